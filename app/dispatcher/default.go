@@ -13,10 +13,12 @@ import (
 	"v2ray.com/core"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/bytespool"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/session"
+	"v2ray.com/core/features/dns"
 	"v2ray.com/core/features/outbound"
 	"v2ray.com/core/features/policy"
 	"v2ray.com/core/features/routing"
@@ -30,23 +32,29 @@ var (
 	errSniffingTimeout = newError("timeout on sniffing")
 )
 
+const (
+	// maxSniffingSize is the number of bytes buffered for sniffing. It is large enough for ClientHellos that span
+	// several segments or records.
+	maxSniffingSize = 16 * 1024
+	// sniffingTimeout is the time to wait for the rest of content that sniffers need more bytes of.
+	sniffingTimeout = 500 * time.Millisecond
+)
+
 type cachedReader struct {
 	sync.Mutex
 	reader *pipe.Reader
 	cache  buf.MultiBuffer
 }
 
-func (r *cachedReader) Cache(b *buf.Buffer) {
+// Cache waits for more data into the cache, and copies the cached data into b. It returns the number of bytes copied.
+func (r *cachedReader) Cache(b []byte) int {
 	mb, _ := r.reader.ReadMultiBufferTimeout(time.Millisecond * 100)
 	r.Lock()
+	defer r.Unlock()
 	if !mb.IsEmpty() {
 		r.cache, _ = buf.MergeMulti(r.cache, mb)
 	}
-	b.Clear()
-	rawBytes := b.Extend(buf.Size)
-	n := r.cache.Copy(rawBytes)
-	b.Resize(0, int32(n))
-	r.Unlock()
+	return r.cache.Copy(b)
 }
 
 func (r *cachedReader) readInternal() buf.MultiBuffer {
@@ -95,13 +103,14 @@ type DefaultDispatcher struct {
 	router routing.Router
 	policy policy.Manager
 	stats  stats.Manager
+	dns    dns.Client
 }
 
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		d := new(DefaultDispatcher)
-		if err := core.RequireFeatures(ctx, func(om outbound.Manager, router routing.Router, pm policy.Manager, sm stats.Manager) error {
-			return d.Init(config.(*Config), om, router, pm, sm)
+		if err := core.RequireFeatures(ctx, func(om outbound.Manager, router routing.Router, pm policy.Manager, sm stats.Manager, dc dns.Client) error {
+			return d.Init(config.(*Config), om, router, pm, sm, dc)
 		}); err != nil {
 			return nil, err
 		}
@@ -110,11 +119,12 @@ func init() {
 }
 
 // Init initializes DefaultDispatcher.
-func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router routing.Router, pm policy.Manager, sm stats.Manager) error {
+func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router routing.Router, pm policy.Manager, sm stats.Manager, dc dns.Client) error {
 	d.ohm = om
 	d.router = router
 	d.policy = pm
 	d.stats = sm
+	d.dns = dc
 	return nil
 }
 
@@ -186,6 +196,46 @@ func shouldOverride(result SniffResult, domainOverride []string) bool {
 	return false
 }
 
+func shouldSniff(request session.SniffingRequest, destination net.Destination) bool {
+	if (destination.Network != net.Network_TCP && destination.Network != net.Network_UDP) || !request.Enabled {
+		return false
+	}
+	return len(request.Ports) == 0 || request.Ports.Contains(destination.Port)
+}
+
+// isExcluded returns true if the destination must not be overridden by the sniffed domain.
+func isExcluded(request session.SniffingRequest, destination net.Destination, domain string) bool {
+	if request.Excluded == nil {
+		return false
+	}
+	return request.Excluded(destination.Address) || request.Excluded(net.ParseAddress(domain))
+}
+
+// overrideDestination applies the sniffed domain to the connection, and returns the new destination.
+func overrideDestination(ctx context.Context, request session.SniffingRequest, content *session.Content, ob *session.Outbound, destination net.Destination, domain string) net.Destination {
+	switch {
+	case isExcluded(request, destination, domain):
+		newError("sniffed domain is excluded, keeping destination ", destination).WriteToLog(session.ExportIDToError(ctx))
+	case request.RouteOnly:
+		content.Domain = domain
+	default:
+		destination.Address = net.ParseAddress(domain)
+		ob.Target = destination
+	}
+	return destination
+}
+
+// metadataDomain returns the domain found from the metadata of the connection, without its payload.
+func (d *DefaultDispatcher) metadataDomain(destination net.Destination) string {
+	if !destination.Address.Family().IsIP() {
+		return ""
+	}
+	if lookup, ok := d.dns.(dns.DomainLookup); ok {
+		return lookup.LookupDomain(destination.Address.IP())
+	}
+	return ""
+}
+
 // Dispatch implements routing.Dispatcher.
 func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destination) (*transport.Link, error) {
 	if !destination.IsValid() {
@@ -203,23 +253,29 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 		ctx = session.ContextWithContent(ctx, content)
 	}
 	sniffingRequest := content.SniffingRequest
-	if destination.Network != net.Network_TCP || !sniffingRequest.Enabled {
+	switch {
+	case !shouldSniff(sniffingRequest, destination):
 		go d.routedDispatch(ctx, outbound, destination)
-	} else {
+	case sniffingRequest.MetadataOnly:
+		if domain := d.metadataDomain(destination); domain != "" {
+			newError("domain from metadata: ", domain).WriteToLog(session.ExportIDToError(ctx))
+			destination = overrideDestination(ctx, sniffingRequest, content, ob, destination, domain)
+		}
+		go d.routedDispatch(ctx, outbound, destination)
+	default:
 		go func() {
 			cReader := &cachedReader{
 				reader: outbound.Reader.(*pipe.Reader),
 			}
 			outbound.Reader = cReader
-			result, err := sniffer(ctx, cReader)
+			result, err := sniffer(ctx, cReader, destination.Network)
 			if err == nil {
 				content.Protocol = result.Protocol()
 			}
 			if err == nil && shouldOverride(result, sniffingRequest.OverrideDestinationForProtocol) {
 				domain := result.Domain()
 				newError("sniffed domain: ", domain).WriteToLog(session.ExportIDToError(ctx))
-				destination.Address = net.ParseAddress(domain)
-				ob.Target = destination
+				destination = overrideDestination(ctx, sniffingRequest, content, ob, destination, domain)
 			}
 			d.routedDispatch(ctx, outbound, destination)
 		}()
@@ -227,11 +283,12 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 	return inbound, nil
 }
 
-func sniffer(ctx context.Context, cReader *cachedReader) (SniffResult, error) {
-	payload := buf.New()
-	defer payload.Release()
+func sniffer(ctx context.Context, cReader *cachedReader, network net.Network) (SniffResult, error) {
+	payload := bytespool.Alloc(maxSniffingSize)[:maxSniffingSize]
+	defer bytespool.Free(payload)
 
 	sniffer := NewSniffer()
+	start := time.Now()
 	totalAttempt := 0
 	for {
 		select {
@@ -239,20 +296,21 @@ func sniffer(ctx context.Context, cReader *cachedReader) (SniffResult, error) {
 			return nil, ctx.Err()
 		default:
 			totalAttempt++
-			if totalAttempt > 2 {
-				return nil, errSniffingTimeout
-			}
 
-			cReader.Cache(payload)
-			if !payload.IsEmpty() {
-				result, err := sniffer.Sniff(payload.Bytes())
+			n := cReader.Cache(payload)
+			if n > 0 {
+				result, err := sniffer.Sniff(payload[:n], network)
 				if err != common.ErrNoClue {
 					return result, err
 				}
 			}
-			if payload.IsFull() {
+			if n == len(payload) {
 				return nil, errUnknownContent
 			}
+			// Only wait longer for content that is incomplete, such as a ClientHello in several segments.
+			if totalAttempt >= 2 && (n == 0 || time.Since(start) > sniffingTimeout) {
+				return nil, errSniffingTimeout
+			}
 		}
 	}
 }
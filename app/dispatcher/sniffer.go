@@ -4,8 +4,10 @@ package dispatcher
 
 import (
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol/bittorrent"
 	"v2ray.com/core/common/protocol/http"
+	"v2ray.com/core/common/protocol/quic"
 	"v2ray.com/core/common/protocol/tls"
 )
 
@@ -16,26 +18,36 @@ type SniffResult interface {
 
 type protocolSniffer func([]byte) (SniffResult, error)
 
+type protocolSnifferWithNetwork struct {
+	protocolSniffer protocolSniffer
+	network         net.Network
+}
+
 type Sniffer struct {
-	sniffer []protocolSniffer
+	sniffer []protocolSnifferWithNetwork
 }
 
 func NewSniffer() *Sniffer {
 	return &Sniffer{
-		sniffer: []protocolSniffer{
-			func(b []byte) (SniffResult, error) { return http.SniffHTTP(b) },
-			func(b []byte) (SniffResult, error) { return tls.SniffTLS(b) },
-			func(b []byte) (SniffResult, error) { return bittorrent.SniffBittorrent(b) },
+		sniffer: []protocolSnifferWithNetwork{
+			{func(b []byte) (SniffResult, error) { return http.SniffHTTP(b) }, net.Network_TCP},
+			{func(b []byte) (SniffResult, error) { return tls.SniffTLS(b) }, net.Network_TCP},
+			{func(b []byte) (SniffResult, error) { return bittorrent.SniffBittorrent(b) }, net.Network_TCP},
+			{func(b []byte) (SniffResult, error) { return quic.SniffQUIC(b) }, net.Network_UDP},
 		},
 	}
 }
 
 var errUnknownContent = newError("unknown content")
 
-func (s *Sniffer) Sniff(payload []byte) (SniffResult, error) {
-	var pendingSniffer []protocolSniffer
+// Sniff detects the protocol of the payload, with the sniffers for the network.
+func (s *Sniffer) Sniff(payload []byte, network net.Network) (SniffResult, error) {
+	var pendingSniffer []protocolSnifferWithNetwork
 	for _, s := range s.sniffer {
-		result, err := s(payload)
+		if s.network != network {
+			continue
+		}
+		result, err := s.protocolSniffer(payload)
 		if err == common.ErrNoClue {
 			pendingSniffer = append(pendingSniffer, s)
 			continue
@@ -0,0 +1,182 @@
+package dispatcher
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	gonet "net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/features/dns"
+	"v2ray.com/core/features/outbound"
+	"v2ray.com/core/transport"
+)
+
+type sniffingResult struct {
+	target net.Destination
+	domain string
+}
+
+type sniffingHandler struct {
+	results chan sniffingResult
+}
+
+func (h *sniffingHandler) Start() error { return nil }
+func (h *sniffingHandler) Close() error { return nil }
+func (h *sniffingHandler) Tag() string  { return "" }
+
+func (h *sniffingHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	h.results <- sniffingResult{
+		target: session.OutboundFromContext(ctx).Target,
+		domain: session.ContentFromContext(ctx).Domain,
+	}
+}
+
+type sniffingManager struct {
+	outbound.Manager
+	handler *sniffingHandler
+}
+
+func (m *sniffingManager) GetDefaultHandler() outbound.Handler {
+	return m.handler
+}
+
+type sniffingDNS struct {
+	dns.Client
+	domains map[string]string
+}
+
+func (s *sniffingDNS) LookupDomain(ip net.IP) string {
+	return s.domains[ip.String()]
+}
+
+func TestSniffing(t *testing.T) {
+	ip := net.TCPDestination(net.ParseAddress("1.2.3.4"), 80)
+	domain := net.TCPDestination(net.DomainAddress("v2fly.org"), 80)
+	udp := net.UDPDestination(net.ParseAddress("1.2.3.4"), 80)
+	unknown := net.TCPDestination(net.ParseAddress("5.6.7.8"), 80)
+
+	testCases := []struct {
+		config *proxyman.SniffingConfig
+		dest   net.Destination
+		result sniffingResult
+	}{
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}},
+			dest:   ip,
+			result: sniffingResult{target: domain},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"tls"}},
+			dest:   ip,
+			result: sniffingResult{target: ip},
+		},
+		{
+			// HTTP is not sniffed on UDP.
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}},
+			dest:   udp,
+			result: sniffingResult{target: udp},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}, RouteOnly: true},
+			dest:   ip,
+			result: sniffingResult{target: ip, domain: "v2fly.org"},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}, Ports: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(443)}}},
+			dest:   ip,
+			result: sniffingResult{target: ip},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}, DomainsExcluded: []string{"domain:v2fly.org"}},
+			dest:   ip,
+			result: sniffingResult{target: ip},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}, DomainsExcluded: []string{"1.2.3.0/24"}},
+			dest:   ip,
+			result: sniffingResult{target: ip},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, MetadataOnly: true},
+			dest:   ip,
+			result: sniffingResult{target: net.TCPDestination(net.DomainAddress("example.com"), 80)},
+		},
+		{
+			config: &proxyman.SniffingConfig{Enabled: true, MetadataOnly: true, RouteOnly: true},
+			dest:   ip,
+			result: sniffingResult{target: ip, domain: "example.com"},
+		},
+		{
+			// The payload is not inspected.
+			config: &proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"http"}, MetadataOnly: true},
+			dest:   unknown,
+			result: sniffingResult{target: unknown},
+		},
+	}
+
+	for i, testCase := range testCases {
+		handler := &sniffingHandler{results: make(chan sniffingResult, 1)}
+		d := &DefaultDispatcher{
+			ohm: &sniffingManager{handler: handler},
+			dns: &sniffingDNS{domains: map[string]string{"1.2.3.4": "example.com"}},
+		}
+
+		request, err := testCase.config.BuildRequest()
+		common.Must(err)
+		ctx := session.ContextWithContent(context.Background(), &session.Content{SniffingRequest: request})
+		link, err := d.Dispatch(ctx, testCase.dest)
+		common.Must(err)
+		common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("GET / HTTP/1.1\r\nHost: v2fly.org\r\n\r\n"))))
+
+		select {
+		case result := <-handler.results:
+			if result != testCase.result {
+				t.Error("case ", i, ": expect ", testCase.result, ", but got ", result)
+			}
+		case <-time.After(time.Second * 2):
+			t.Fatal("case ", i, ": timeout")
+		}
+	}
+}
+
+func TestSniffingSegmentedClientHello(t *testing.T) {
+	client, server := gonet.Pipe()
+	defer server.Close()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: "v2fly.org"}).Handshake()
+		client.Close()
+	}()
+	record := make([]byte, 5)
+	common.Must2(io.ReadFull(server, record))
+	record = append(record, make([]byte, int(record[3])<<8|int(record[4]))...)
+	common.Must2(io.ReadFull(server, record[5:]))
+
+	handler := &sniffingHandler{results: make(chan sniffingResult, 1)}
+	d := &DefaultDispatcher{ohm: &sniffingManager{handler: handler}}
+	request, err := (&proxyman.SniffingConfig{Enabled: true, DestinationOverride: []string{"tls"}}).BuildRequest()
+	common.Must(err)
+	ctx := session.ContextWithContent(context.Background(), &session.Content{SniffingRequest: request})
+	link, err := d.Dispatch(ctx, net.TCPDestination(net.ParseAddress("1.2.3.4"), 443))
+	common.Must(err)
+
+	// The second segment arrives later than a single wait of the sniffer.
+	common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, record[:100])))
+	time.Sleep(time.Millisecond * 250)
+	common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, record[100:])))
+
+	select {
+	case result := <-handler.results:
+		if expected := net.TCPDestination(net.DomainAddress("v2fly.org"), 443); result.target != expected {
+			t.Error("expect ", expected, ", but got ", result.target)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("timeout")
+	}
+}
@@ -167,6 +167,13 @@ func (s *DoHNameServer) Cleanup() error {
 	return nil
 }
 
+// FlushCache removes all cached records.
+func (s *DoHNameServer) FlushCache() {
+	s.Lock()
+	s.ips = make(map[string]record)
+	s.Unlock()
+}
+
 func (s *DoHNameServer) updateIP(req *dnsRequest, ipRec *IPRecord) {
 	elapsed := time.Since(req.start)
 
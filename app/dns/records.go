@@ -0,0 +1,70 @@
+// +build !confonly
+
+package dns
+
+import (
+	"sync"
+
+	"v2ray.com/core/common/net"
+)
+
+// maxDomainRecords is the number of IPs whose domains are remembered.
+const maxDomainRecords = 8192
+
+// domainRecords remembers the domains that IPs are resolved from, so that the domain of a connection to one of the
+// IPs can be found without inspecting its payload. The oldest records are dropped first.
+type domainRecords struct {
+	sync.Mutex
+	domains map[string]string
+	ips     []string
+	next    int
+}
+
+func newDomainRecords() *domainRecords {
+	return &domainRecords{
+		domains: make(map[string]string),
+	}
+}
+
+func (r *domainRecords) add(domain string, ips []net.IP) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, ip := range ips {
+		key := string(ip)
+		if len(ip) == net.IPv6len && ip.To4() != nil {
+			key = string(ip.To4())
+		}
+		if _, found := r.domains[key]; found {
+			r.domains[key] = domain
+			continue
+		}
+		if len(r.ips) < maxDomainRecords {
+			r.ips = append(r.ips, key)
+		} else {
+			delete(r.domains, r.ips[r.next])
+			r.ips[r.next] = key
+			r.next = (r.next + 1) % maxDomainRecords
+		}
+		r.domains[key] = domain
+	}
+}
+
+func (r *domainRecords) lookup(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	return r.domains[string(ip)]
+}
+
+func (r *domainRecords) clear() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.domains = make(map[string]string)
+	r.ips = nil
+	r.next = 0
+}
@@ -0,0 +1,57 @@
+// +build !confonly
+
+package dns
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/net"
+)
+
+func TestDomainRecords(t *testing.T) {
+	records := newDomainRecords()
+	records.add("v2fly.org", []net.IP{net.ParseIP("1.2.3.4"), net.ParseIP("2001:db8::1")})
+	records.add("example.com", []net.IP{net.IP{5, 6, 7, 8}})
+
+	testCases := []struct {
+		ip     net.IP
+		domain string
+	}{
+		{net.ParseIP("1.2.3.4"), "v2fly.org"},
+		{net.IP{1, 2, 3, 4}, "v2fly.org"},
+		{net.ParseIP("2001:db8::1"), "v2fly.org"},
+		{net.ParseIP("5.6.7.8"), "example.com"},
+		{net.ParseIP("5.6.7.9"), ""},
+	}
+	for _, testCase := range testCases {
+		if domain := records.lookup(testCase.ip); domain != testCase.domain {
+			t.Error("expect domain ", testCase.domain, " for ", testCase.ip, ", but got ", domain)
+		}
+	}
+
+	records.add("v2ray.com", []net.IP{net.ParseIP("1.2.3.4")})
+	if domain := records.lookup(net.ParseIP("1.2.3.4")); domain != "v2ray.com" {
+		t.Error("expect the latest domain, but got ", domain)
+	}
+
+	records.clear()
+	if domain := records.lookup(net.ParseIP("1.2.3.4")); domain != "" {
+		t.Error("expect no domain after clear, but got ", domain)
+	}
+}
+
+func TestDomainRecordsLimit(t *testing.T) {
+	records := newDomainRecords()
+	for i := 0; i < maxDomainRecords+10; i++ {
+		records.add("v2fly.org", []net.IP{{10, 0, byte(i >> 8), byte(i)}})
+	}
+	if len(records.domains) != maxDomainRecords {
+		t.Error("expect ", maxDomainRecords, " records, but got ", len(records.domains))
+	}
+	if domain := records.lookup(net.IP{10, 0, 0, 0}); domain != "" {
+		t.Error("expect the oldest record to be dropped, but got ", domain)
+	}
+	if domain := records.lookup(net.IP{10, 0, 0x20, 9}); domain != "v2fly.org" {
+		t.Error("expect the latest record, but got ", domain)
+	}
+}
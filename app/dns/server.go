@@ -37,6 +37,7 @@ type Server struct {
 	domainMatcher strmatcher.IndexMatcher
 	matcherInfos  []DomainMatcherInfo // matcherIdx -> DomainMatcherInfo
 	tag           string
+	records       *domainRecords
 }
 
 // DomainMatcherInfo contains information attached to index returned by Server.domainMatcher
@@ -77,6 +78,7 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 	server := &Server{
 		clients: make([]Client, 0, len(config.NameServers)+len(config.NameServer)),
 		tag:     config.Tag,
+		records: newDomainRecords(),
 	}
 	if server.tag == "" {
 		server.tag = generateRandomTag()
@@ -249,6 +251,16 @@ func (s *Server) Close() error {
 	return nil
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *Server) FlushCache() {
+	s.records.clear()
+	for _, client := range s.clients {
+		if f, ok := client.(dns.CacheFlusher); ok {
+			f.FlushCache()
+		}
+	}
+}
+
 func (s *Server) IsOwnLink(ctx context.Context) bool {
 	inbound := session.InboundFromContext(ctx)
 	return inbound != nil && inbound.Tag == s.tag
@@ -302,7 +314,7 @@ func (s *Server) queryIPTimeout(idx int, client Client, domain string, option IP
 
 // LookupIP implements dns.Client.
 func (s *Server) LookupIP(domain string) ([]net.IP, error) {
-	return s.lookupIPInternal(domain, IPOption{
+	return s.lookupIP(domain, IPOption{
 		IPv4Enable: true,
 		IPv6Enable: true,
 	})
@@ -310,7 +322,7 @@ func (s *Server) LookupIP(domain string) ([]net.IP, error) {
 
 // LookupIPv4 implements dns.IPv4Lookup.
 func (s *Server) LookupIPv4(domain string) ([]net.IP, error) {
-	return s.lookupIPInternal(domain, IPOption{
+	return s.lookupIP(domain, IPOption{
 		IPv4Enable: true,
 		IPv6Enable: false,
 	})
@@ -318,12 +330,26 @@ func (s *Server) LookupIPv4(domain string) ([]net.IP, error) {
 
 // LookupIPv6 implements dns.IPv6Lookup.
 func (s *Server) LookupIPv6(domain string) ([]net.IP, error) {
-	return s.lookupIPInternal(domain, IPOption{
+	return s.lookupIP(domain, IPOption{
 		IPv4Enable: false,
 		IPv6Enable: true,
 	})
 }
 
+// LookupDomain implements dns.DomainLookup.
+func (s *Server) LookupDomain(ip net.IP) string {
+	return s.records.lookup(ip)
+}
+
+// lookupIP looks up the domain, and remembers the domain of the returned IPs.
+func (s *Server) lookupIP(domain string, option IPOption) ([]net.IP, error) {
+	ips, err := s.lookupIPInternal(domain, option)
+	if err == nil {
+		s.records.add(strings.TrimSuffix(domain, "."), ips)
+	}
+	return ips, err
+}
+
 func (s *Server) lookupStatic(domain string, option IPOption, depth int32) []net.Address {
 	ips := s.hosts.LookupIP(domain, option)
 	if ips == nil {
@@ -140,6 +140,13 @@ func (s *ClassicNameServer) HandleResponse(ctx context.Context, packet *udp_prot
 	}
 }
 
+// FlushCache removes all cached records.
+func (s *ClassicNameServer) FlushCache() {
+	s.Lock()
+	s.ips = make(map[string]record)
+	s.Unlock()
+}
+
 func (s *ClassicNameServer) updateIP(domain string, newRec record) {
 	s.Lock()
 
@@ -0,0 +1,260 @@
+// +build !confonly
+
+// Package netmon watches the local network, and recovers from changes such as switching Wi-Fi networks or waking up
+// from sleep, by flushing cached DNS records and closing outbound connections which are broken by the change, including
+// mux, mKCP and pooled transport connections, so that they are dialed again on the new path.
+package netmon
+
+//go:generate errorgen
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/signal/done"
+	"v2ray.com/core/features/dns"
+	"v2ray.com/core/features/outbound"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	defaultPollInterval = time.Second * 5
+	debounceInterval    = time.Millisecond * 500
+)
+
+// probeAddresses are used to find out the preferred local addresses. No packet is sent to them.
+var probeAddresses = []string{"198.18.0.1:53", "[2001:2::1]:53"}
+
+// snapshot is the state of the network that matters to open connections.
+type snapshot struct {
+	addresses map[string]bool
+	// preferred are the local addresses of the default routes.
+	preferred []net.IP
+}
+
+func takeSnapshot() (*snapshot, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, newError("failed to list interface addresses").Base(err)
+	}
+	s := &snapshot{
+		addresses: make(map[string]bool, len(addrs)),
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			s.addresses[ipNet.IP.String()] = true
+		}
+	}
+
+	for _, probe := range probeAddresses {
+		conn, err := net.Dial("udp", probe)
+		if err != nil {
+			continue
+		}
+		s.preferred = append(s.preferred, conn.LocalAddr().(*net.UDPAddr).IP)
+		conn.Close()
+	}
+	return s, nil
+}
+
+func (s *snapshot) String() string {
+	addresses := make([]string, 0, len(s.addresses))
+	for addr := range s.addresses {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+	preferred := make([]string, 0, len(s.preferred))
+	for _, ip := range s.preferred {
+		preferred = append(preferred, ip.String())
+	}
+	return "[" + strings.Join(addresses, " ") + "] via [" + strings.Join(preferred, " ") + "]"
+}
+
+func (s *snapshot) samePreferred(other *snapshot) bool {
+	if len(s.preferred) != len(other.preferred) {
+		return false
+	}
+	for i, ip := range s.preferred {
+		if !ip.Equal(other.preferred[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *snapshot) equal(other *snapshot) bool {
+	if !s.samePreferred(other) || len(s.addresses) != len(other.addresses) {
+		return false
+	}
+	for addr := range s.addresses {
+		if !other.addresses[addr] {
+			return false
+		}
+	}
+	return true
+}
+
+// staleFunc returns a function to check whether a connection from the given local address is broken, after the
+// network has changed from previous to s. A connection is broken if its local address no longer belongs to this host.
+// If the default routes have changed, e.g., from Wi-Fi to cellular, connections not from the new preferred address of
+// the same family are also broken, as their packets are routed to the new path.
+func (s *snapshot) staleFunc(previous *snapshot) func(net.Addr) bool {
+	followPreferred := !s.samePreferred(previous)
+	return func(local net.Addr) bool {
+		ip := localIP(local)
+		if ip == nil || ip.IsUnspecified() || ip.IsLoopback() {
+			return false
+		}
+		if !s.addresses[ip.String()] {
+			return true
+		}
+		if !followPreferred || ip.IsLinkLocalUnicast() {
+			return false
+		}
+		for _, preferred := range s.preferred {
+			if (preferred.To4() == nil) == (ip.To4() == nil) {
+				if !preferred.Equal(ip) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func localIP(local net.Addr) net.IP {
+	switch addr := local.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	default:
+		return nil
+	}
+}
+
+// Monitor is the network monitor.
+type Monitor struct {
+	dns          dns.Client
+	ohm          outbound.Manager
+	pollInterval time.Duration
+
+	access  sync.Mutex
+	current *snapshot
+
+	trigger chan struct{}
+	done    *done.Instance
+}
+
+// New creates a new Monitor.
+func New(ctx context.Context, config *Config) (*Monitor, error) {
+	m := &Monitor{
+		pollInterval: defaultPollInterval,
+		trigger:      make(chan struct{}, 1),
+		done:         done.New(),
+	}
+	if config.PollInterval > 0 {
+		m.pollInterval = time.Duration(config.PollInterval) * time.Second
+	}
+	if err := core.RequireFeatures(ctx, func(d dns.Client, om outbound.Manager) {
+		m.dns = d
+		m.ohm = om
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Type implements common.HasType.
+func (m *Monitor) Type() interface{} {
+	return (*Monitor)(nil)
+}
+
+// Start implements common.Runnable.
+func (m *Monitor) Start() error {
+	s, err := takeSnapshot()
+	if err != nil {
+		return err
+	}
+	m.current = s
+
+	if err := watch(m.done, m.pollInterval, m.notify); err != nil {
+		return newError("failed to watch network changes").Base(err)
+	}
+	go m.run()
+	return nil
+}
+
+// Close implements common.Closable.
+func (m *Monitor) Close() error {
+	return m.done.Close()
+}
+
+// notify signals a possible change of the network.
+func (m *Monitor) notify() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Monitor) run() {
+	for {
+		select {
+		case <-m.trigger:
+		case <-m.done.Wait():
+			return
+		}
+
+		// Changes usually come in bursts, e.g. an interface going down and up with new addresses.
+		select {
+		case <-time.After(debounceInterval):
+		case <-m.done.Wait():
+			return
+		}
+		select {
+		case <-m.trigger:
+		default:
+		}
+
+		m.check()
+	}
+}
+
+func (m *Monitor) check() {
+	s, err := takeSnapshot()
+	if err != nil {
+		newError("failed to check network").Base(err).AtWarning().WriteToLog()
+		return
+	}
+
+	m.access.Lock()
+	previous := m.current
+	m.current = s
+	m.access.Unlock()
+
+	if previous.equal(s) {
+		return
+	}
+	newError("network changed to ", s).AtInfo().WriteToLog()
+
+	if f, ok := m.dns.(dns.CacheFlusher); ok {
+		f.FlushCache()
+	}
+	if r, ok := m.ohm.(outbound.ConnectionResetter); ok {
+		r.ResetConnections(s.staleFunc(previous))
+	}
+	internet.ResetConnectionPools()
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
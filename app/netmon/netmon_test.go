@@ -0,0 +1,91 @@
+// +build !confonly
+
+package netmon
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/net"
+)
+
+func TestSnapshot(t *testing.T) {
+	previous := &snapshot{
+		addresses: map[string]bool{
+			"192.168.1.2": true,
+			"10.0.0.2":    true,
+			"fe80::1":     true,
+		},
+		preferred: []net.IP{net.ParseIP("192.168.1.2")},
+	}
+	s := &snapshot{
+		addresses: map[string]bool{
+			"192.168.1.2": true,
+			"fe80::1":     true,
+		},
+		preferred: []net.IP{net.ParseIP("192.168.1.2")},
+	}
+	if s.equal(previous) {
+		t.Error("expect different snapshots")
+	}
+	if !s.equal(&snapshot{
+		addresses: map[string]bool{"fe80::1": true, "192.168.1.2": true},
+		preferred: []net.IP{net.ParseIP("192.168.1.2")},
+	}) {
+		t.Error("expect equal snapshots")
+	}
+
+	cases := []struct {
+		local net.Addr
+		stale bool
+	}{
+		{local: &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 40000}},
+		{local: &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 40000}},
+		{local: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 40000}, stale: true},
+		{local: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40000}},
+		{local: &net.UDPAddr{IP: net.ParseIP("0.0.0.0"), Port: 40000}},
+		{local: &net.UnixAddr{Name: "/tmp/v2ray.sock", Net: "unix"}},
+		{local: nil},
+	}
+	stale := s.staleFunc(previous)
+	for _, c := range cases {
+		if r := stale(c.local); r != c.stale {
+			t.Error("stale(", c.local, ") = ", r, ", want ", c.stale)
+		}
+	}
+}
+
+func TestSnapshotPreferredChange(t *testing.T) {
+	// Switching from Wi-Fi to cellular, while Wi-Fi is still associated.
+	previous := &snapshot{
+		addresses: map[string]bool{"192.168.1.2": true, "100.64.0.2": true, "2001:db8::2": true},
+		preferred: []net.IP{net.ParseIP("192.168.1.2"), net.ParseIP("2001:db8::2")},
+	}
+	s := &snapshot{
+		addresses: map[string]bool{"192.168.1.2": true, "100.64.0.2": true, "2001:db8::2": true},
+		preferred: []net.IP{net.ParseIP("100.64.0.2"), net.ParseIP("2001:db8::2")},
+	}
+	if s.equal(previous) {
+		t.Error("expect different snapshots")
+	}
+
+	stale := s.staleFunc(previous)
+	if !stale(&net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 40000}) {
+		t.Error("expect connection over Wi-Fi to be stale")
+	}
+	if stale(&net.TCPAddr{IP: net.ParseIP("100.64.0.2"), Port: 40000}) {
+		t.Error("expect connection over cellular not to be stale")
+	}
+	if stale(&net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 40000}) {
+		t.Error("expect IPv6 connection not to be stale")
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	s, err := takeSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.equal(s) {
+		t.Error("snapshot is not equal to itself")
+	}
+}
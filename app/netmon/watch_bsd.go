@@ -0,0 +1,53 @@
+// +build !confonly
+// +build darwin freebsd openbsd netbsd dragonfly
+
+package netmon
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"v2ray.com/core/common/signal/done"
+)
+
+// watch listens on a routing socket, which receives a message on every change of routes, addresses and interfaces.
+func watch(d *done.Instance, interval time.Duration, notify func()) error {
+	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
+	if err != nil {
+		return newError("failed to open routing socket").Base(err)
+	}
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd) // nolint: errcheck
+		return newError("failed to set routing socket non-blocking").Base(err)
+	}
+	f := os.NewFile(uintptr(fd), "route")
+
+	go func() {
+		<-d.Wait()
+		f.Close()
+	}()
+
+	go func() {
+		b := make([]byte, os.Getpagesize())
+		for {
+			n, err := f.Read(b)
+			if err != nil {
+				if !d.Done() {
+					newError("failed to read routing socket").Base(err).AtWarning().WriteToLog()
+				}
+				return
+			}
+			// Header of routing messages: u_short msglen, u_char version, u_char type.
+			if n < 4 {
+				continue
+			}
+			switch int(b[3]) {
+			case unix.RTM_ADD, unix.RTM_DELETE, unix.RTM_CHANGE, unix.RTM_NEWADDR, unix.RTM_DELADDR, unix.RTM_IFINFO:
+				notify()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,69 @@
+// +build !confonly
+
+package netmon
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"v2ray.com/core/common/signal/done"
+)
+
+// watch subscribes to changes of links, addresses and routes with netlink. On systems where netlink is not
+// permitted, such as Android for apps, it falls back to polling.
+func watch(d *done.Instance, interval time.Duration, notify func()) error {
+	f, err := openNetlink()
+	if err != nil {
+		newError("failed to subscribe to netlink, polling network changes instead").Base(err).AtInfo().WriteToLog()
+		poll(d, interval, notify)
+		return nil
+	}
+
+	go func() {
+		<-d.Wait()
+		f.Close()
+	}()
+
+	go func() {
+		b := make([]byte, os.Getpagesize())
+		for {
+			n, err := f.Read(b)
+			if err != nil {
+				if !d.Done() {
+					newError("failed to read netlink socket").Base(err).AtWarning().WriteToLog()
+				}
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(b[:n])
+			if err != nil {
+				continue
+			}
+			for _, msg := range msgs {
+				switch msg.Header.Type {
+				case unix.RTM_NEWLINK, unix.RTM_DELLINK, unix.RTM_NEWADDR, unix.RTM_DELADDR, unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+					notify()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func openNetlink() (*os.File, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	addr := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE,
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd) // nolint: errcheck
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "netlink"), nil
+}
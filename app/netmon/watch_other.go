@@ -0,0 +1,15 @@
+// +build !confonly
+// +build !darwin,!freebsd,!openbsd,!netbsd,!dragonfly,!linux
+
+package netmon
+
+import (
+	"time"
+
+	"v2ray.com/core/common/signal/done"
+)
+
+func watch(d *done.Instance, interval time.Duration, notify func()) error {
+	poll(d, interval, notify)
+	return nil
+}
@@ -0,0 +1,26 @@
+// +build !confonly
+
+package netmon
+
+import (
+	"time"
+
+	"v2ray.com/core/common/signal/done"
+)
+
+// poll checks the network periodically, on systems without change notifications.
+func poll(d *done.Instance, interval time.Duration, notify func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				notify()
+			case <-d.Wait():
+				return
+			}
+		}
+	}()
+}
@@ -4,6 +4,7 @@ package command
 
 import (
 	"context"
+	"sort"
 
 	grpc "google.golang.org/grpc"
 
@@ -125,6 +126,37 @@ func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboun
 	return &AlterOutboundResponse{}, operation.ApplyOutbound(ctx, handler)
 }
 
+func (s *handlerServer) ListConnections(ctx context.Context, request *ListConnectionsRequest) (*ListConnectionsResponse, error) {
+	lister, ok := s.ohm.(outbound.ConnectionLister)
+	if !ok {
+		return nil, newError("outbound manager doesn't support listing connections")
+	}
+	connections := lister.ListConnections()
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].Start.Before(connections[j].Start)
+	})
+
+	response := &ListConnectionsResponse{
+		Connections: make([]*Connection, 0, len(connections)),
+	}
+	for _, c := range connections {
+		conn := &Connection{
+			Id:          c.ID,
+			InboundTag:  c.InboundTag,
+			OutboundTag: c.OutboundTag,
+			Start:       c.Start.Unix(),
+		}
+		if c.Source.IsValid() {
+			conn.Source = c.Source.String()
+		}
+		if c.Target.IsValid() {
+			conn.Target = c.Target.String()
+		}
+		response.Connections = append(response.Connections, conn)
+	}
+	return response, nil
+}
+
 func (s *handlerServer) mustEmbedUnimplementedHandlerServiceServer() {}
 
 type service struct {
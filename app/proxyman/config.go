@@ -1,5 +1,11 @@
 package proxyman
 
+import (
+	"sort"
+
+	"v2ray.com/core/common/net"
+)
+
 func (s *AllocationStrategy) GetConcurrencyValue() uint32 {
 	if s == nil || s.Concurrency == nil {
 		return 3
@@ -37,3 +43,43 @@ func (c *ReceiverConfig) GetEffectiveSniffingSettings() *SniffingConfig {
 
 	return nil
 }
+
+// GetEffectivePorts returns the ports that the Receiver listens on, in ascending order without duplicates.
+func (c *ReceiverConfig) GetEffectivePorts() []net.Port {
+	ranges := c.GetPortList().GetRange()
+	if len(ranges) == 0 && c.PortRange != nil {
+		ranges = []*net.PortRange{c.PortRange}
+	}
+
+	seen := make(map[net.Port]bool)
+	var ports []net.Port
+	for _, r := range ranges {
+		for port := r.From; port <= r.To && port <= 65535; port++ {
+			if !seen[net.Port(port)] {
+				seen[net.Port(port)] = true
+				ports = append(ports, net.Port(port))
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// GetEffectiveListenAddresses returns the addresses that the Receiver listens on. It is the any IP if none is
+// specified.
+func (c *ReceiverConfig) GetEffectiveListenAddresses() []net.Address {
+	var addresses []net.Address
+	for _, address := range c.ListenList {
+		if a := address.AsAddress(); a != nil {
+			addresses = append(addresses, a)
+		}
+	}
+	if len(addresses) == 0 {
+		address := c.Listen.AsAddress()
+		if address == nil {
+			address = net.AnyIP
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
@@ -0,0 +1,73 @@
+package proxyman_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/net"
+)
+
+func TestReceiverConfigPorts(t *testing.T) {
+	testCases := []struct {
+		config *ReceiverConfig
+		ports  []net.Port
+	}{
+		{
+			config: &ReceiverConfig{},
+		},
+		{
+			config: &ReceiverConfig{PortRange: &net.PortRange{From: 1080, To: 1082}},
+			ports:  []net.Port{1080, 1081, 1082},
+		},
+		{
+			config: &ReceiverConfig{
+				PortRange: &net.PortRange{From: 1080, To: 1080},
+				PortList: &net.PortList{Range: []*net.PortRange{
+					{From: 8443, To: 8444},
+					{From: 80, To: 80},
+					{From: 443, To: 443},
+					{From: 8444, To: 8444},
+				}},
+			},
+			ports: []net.Port{80, 443, 8443, 8444},
+		},
+	}
+	for _, testCase := range testCases {
+		if r := cmp.Diff(testCase.config.GetEffectivePorts(), testCase.ports); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestReceiverConfigListenAddresses(t *testing.T) {
+	testCases := []struct {
+		config    *ReceiverConfig
+		addresses []net.Address
+	}{
+		{
+			config:    &ReceiverConfig{},
+			addresses: []net.Address{net.AnyIP},
+		},
+		{
+			config:    &ReceiverConfig{Listen: net.NewIPOrDomain(net.LocalHostIP)},
+			addresses: []net.Address{net.LocalHostIP},
+		},
+		{
+			config: &ReceiverConfig{
+				Listen: net.NewIPOrDomain(net.AnyIP),
+				ListenList: []*net.IPOrDomain{
+					net.NewIPOrDomain(net.LocalHostIP),
+					net.NewIPOrDomain(net.LocalHostIPv6),
+				},
+			},
+			addresses: []net.Address{net.LocalHostIP, net.LocalHostIPv6},
+		},
+	}
+	for _, testCase := range testCases {
+		if r := cmp.Diff(testCase.config.GetEffectiveListenAddresses(), testCase.addresses); r != "" {
+			t.Error(r)
+		}
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"v2ray.com/core/common/errors"
 	"v2ray.com/core/common/mux"
 	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/record"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/task"
 	"v2ray.com/core/features/policy"
 	"v2ray.com/core/features/stats"
 	"v2ray.com/core/proxy"
@@ -41,6 +44,21 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 	return uplinkCounter, downlinkCounter
 }
 
+func getRecorder(config *proxyman.RecordConfig) *record.Recorder {
+	if config == nil || config.Directory == "" {
+		return nil
+	}
+	return record.New(config.Directory, config.MaxSize)
+}
+
+func getSniffingRequest(receiverConfig *proxyman.ReceiverConfig) (session.SniffingRequest, error) {
+	config := receiverConfig.GetEffectiveSniffingSettings()
+	if config == nil {
+		return session.SniffingRequest{}, nil
+	}
+	return config.BuildRequest()
+}
+
 type AlwaysOnInboundHandler struct {
 	proxy   proxy.Inbound
 	workers []worker
@@ -66,13 +84,25 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 
 	uplinkCounter, downlinkCounter := getStatCounter(core.MustFromContext(ctx), tag)
 
-	nl := p.Network()
-	pr := receiverConfig.PortRange
-	address := receiverConfig.Listen.AsAddress()
-	if address == nil {
-		address = net.AnyIP
+	sniffing, err := getSniffingRequest(receiverConfig)
+	if err != nil {
+		return nil, newError("failed to parse sniffing settings").Base(err).AtWarning()
+	}
+
+	if dp, ok := p.(proxy.DeviceInbound); ok {
+		h.workers = append(h.workers, &deviceWorker{
+			proxy:           dp,
+			tag:             tag,
+			dispatcher:      h.mux,
+			sniffing:        sniffing,
+			uplinkCounter:   uplinkCounter,
+			downlinkCounter: downlinkCounter,
+			ctx:             ctx,
+		})
+		return h, nil
 	}
 
+	nl := p.Network()
 	mss, err := internet.ToMemoryStreamConfig(receiverConfig.StreamSettings)
 	if err != nil {
 		return nil, newError("failed to parse stream config").Base(err).AtWarning()
@@ -88,46 +118,71 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 		mss.SocketSettings.ReceiveOriginalDestAddress = true
 	}
 
-	for port := pr.From; port <= pr.To; port++ {
-		if net.HasNetwork(nl, net.Network_TCP) {
-			newError("creating stream worker on ", address, ":", port).AtDebug().WriteToLog()
-
-			worker := &tcpWorker{
-				address:         address,
-				port:            net.Port(port),
-				proxy:           p,
-				stream:          mss,
-				recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
-				tag:             tag,
-				dispatcher:      h.mux,
-				sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
-				uplinkCounter:   uplinkCounter,
-				downlinkCounter: downlinkCounter,
-				ctx:             ctx,
-			}
-			h.workers = append(h.workers, worker)
-		}
-
-		if net.HasNetwork(nl, net.Network_UDP) {
-			worker := &udpWorker{
-				tag:             tag,
-				proxy:           p,
-				address:         address,
-				port:            net.Port(port),
-				dispatcher:      h.mux,
-				uplinkCounter:   uplinkCounter,
-				downlinkCounter: downlinkCounter,
-				stream:          mss,
+	recorder := getRecorder(receiverConfig.RecordSettings)
+	ports := receiverConfig.GetEffectivePorts()
+	for _, address := range receiverConfig.GetEffectiveListenAddresses() {
+		listeners := effectiveListeners(address, receiverConfig.Listeners)
+		for _, port := range ports {
+			for i := 0; i < listeners; i++ {
+				if net.HasNetwork(nl, net.Network_TCP) {
+					newError("creating stream worker on ", address, ":", port).AtDebug().WriteToLog()
+
+					worker := &tcpWorker{
+						address:         address,
+						port:            port,
+						proxy:           p,
+						stream:          mss,
+						recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
+						tag:             tag,
+						dispatcher:      h.mux,
+						sniffing:        sniffing,
+						recorder:        recorder,
+						uplinkCounter:   uplinkCounter,
+						downlinkCounter: downlinkCounter,
+						ctx:             ctx,
+					}
+					h.workers = append(h.workers, worker)
+				}
+
+				if net.HasNetwork(nl, net.Network_UDP) && !internet.IsUnixSocketPath(address) {
+					worker := &udpWorker{
+						tag:             tag,
+						proxy:           p,
+						address:         address,
+						port:            port,
+						dispatcher:      h.mux,
+						uplinkCounter:   uplinkCounter,
+						downlinkCounter: downlinkCounter,
+						stream:          mss,
+					}
+					h.workers = append(h.workers, worker)
+				}
 			}
-			h.workers = append(h.workers, worker)
 		}
 	}
 
 	return h, nil
 }
 
+// effectiveListeners returns the number of sockets opened on each port of address.
+func effectiveListeners(address net.Address, listeners uint32) int {
+	if listeners > 1 && (address.Family().IsDomain() || !internet.ReusePortBalanced()) {
+		newError("multiple listeners are not supported on ", address, ", using one").AtWarning().WriteToLog()
+		return 1
+	}
+	if listeners < 1 {
+		return 1
+	}
+	return int(listeners)
+}
+
 // Start implements common.Runnable.
 func (h *AlwaysOnInboundHandler) Start() error {
+	if p, ok := h.proxy.(common.Runnable); ok {
+		if err := p.Start(); err != nil {
+			return newError("failed to start proxy").Base(err)
+		}
+	}
 	for _, worker := range h.workers {
 		if err := worker.Start(); err != nil {
 			return err
@@ -143,12 +198,30 @@ func (h *AlwaysOnInboundHandler) Close() error {
 		errs = append(errs, worker.Close())
 	}
 	errs = append(errs, h.mux.Close())
+	if p, ok := h.proxy.(common.Runnable); ok {
+		errs = append(errs, p.Close())
+	}
 	if err := errors.Combine(errs...); err != nil {
 		return newError("failed to close all resources").Base(err)
 	}
 	return nil
 }
 
+// Drain implements common.Drainable.
+func (h *AlwaysOnInboundHandler) Drain(ctx context.Context) error {
+	tasks := []func() error{
+		func() error { return h.mux.Drain(ctx) },
+	}
+	for _, w := range h.workers {
+		w := w
+		tasks = append(tasks, func() error { return w.Drain(ctx) })
+	}
+	if err := task.Run(ctx, tasks...); err != nil {
+		return newError("failed to drain all workers").Base(err)
+	}
+	return nil
+}
+
 func (h *AlwaysOnInboundHandler) GetRandomInboundProxy() (interface{}, net.Port, int) {
 	if len(h.workers) == 0 {
 		return nil, 0, 0
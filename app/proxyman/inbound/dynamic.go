@@ -7,9 +7,11 @@ import (
 
 	"v2ray.com/core"
 	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common"
 	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/mux"
 	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
 	"v2ray.com/core/common/task"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
@@ -21,6 +23,8 @@ type DynamicInboundHandler struct {
 	proxyConfig    interface{}
 	receiverConfig *proxyman.ReceiverConfig
 	streamSettings *internet.MemoryStreamConfig
+	sniffing       session.SniffingRequest
+	ports          []net.Port
 	portMutex      sync.Mutex
 	portsInUse     map[net.Port]bool
 	workerMutex    sync.RWMutex
@@ -29,6 +33,10 @@ type DynamicInboundHandler struct {
 	mux            *mux.Server
 	task           *task.Periodic
 
+	// Allocations that are not closed yet, including the expiring ones. They are guarded by workerMutex.
+	allocations []*allocation
+	closed      bool
+
 	ctx context.Context
 }
 
@@ -38,12 +46,17 @@ func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *p
 		tag:            tag,
 		proxyConfig:    proxyConfig,
 		receiverConfig: receiverConfig,
+		ports:          receiverConfig.GetEffectivePorts(),
 		portsInUse:     make(map[net.Port]bool),
 		mux:            mux.NewServer(ctx),
 		v:              v,
 		ctx:            ctx,
 	}
 
+	if concurrency := receiverConfig.AllocationStrategy.GetConcurrencyValue(); uint32(len(h.ports)) <= concurrency {
+		return nil, newError("not enough ports. concurrency = ", concurrency, " ports: ", len(h.ports)).AtWarning()
+	}
+
 	mss, err := internet.ToMemoryStreamConfig(receiverConfig.StreamSettings)
 	if err != nil {
 		return nil, newError("failed to parse stream settings").Base(err).AtWarning()
@@ -60,6 +73,11 @@ func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *p
 
 	h.streamSettings = mss
 
+	h.sniffing, err = getSniffingRequest(receiverConfig)
+	if err != nil {
+		return nil, newError("failed to parse sniffing settings").Base(err).AtWarning()
+	}
+
 	h.task = &task.Periodic{
 		Interval: time.Minute * time.Duration(h.receiverConfig.AllocationStrategy.GetRefreshValue()),
 		Execute:  h.refresh,
@@ -69,15 +87,11 @@ func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *p
 }
 
 func (h *DynamicInboundHandler) allocatePort() net.Port {
-	from := int(h.receiverConfig.PortRange.From)
-	delta := int(h.receiverConfig.PortRange.To) - from + 1
-
 	h.portMutex.Lock()
 	defer h.portMutex.Unlock()
 
 	for {
-		r := dice.Roll(delta)
-		port := net.Port(from + r)
+		port := h.ports[dice.Roll(len(h.ports))]
 		_, used := h.portsInUse[port]
 		if !used {
 			h.portsInUse[port] = true
@@ -86,6 +100,12 @@ func (h *DynamicInboundHandler) allocatePort() net.Port {
 	}
 }
 
+// allocation is the workers and proxy instances allocated by a refresh.
+type allocation struct {
+	workers []worker
+	proxies []common.Runnable
+}
+
 func (h *DynamicInboundHandler) closeWorkers(workers []worker) {
 	ports2Del := make([]net.Port, len(workers))
 	for idx, worker := range workers {
@@ -108,12 +128,9 @@ func (h *DynamicInboundHandler) refresh() error {
 	timeout := time.Minute * time.Duration(h.receiverConfig.AllocationStrategy.GetRefreshValue()) * 2
 	concurrency := h.receiverConfig.AllocationStrategy.GetConcurrencyValue()
 	workers := make([]worker, 0, concurrency)
+	var proxies []common.Runnable
 
-	address := h.receiverConfig.Listen.AsAddress()
-	if address == nil {
-		address = net.AnyIP
-	}
-
+	addresses := h.receiverConfig.GetEffectiveListenAddresses()
 	uplinkCounter, downlinkCounter := getStatCounter(h.v, h.tag)
 
 	for i := uint32(0); i < concurrency; i++ {
@@ -124,64 +141,139 @@ func (h *DynamicInboundHandler) refresh() error {
 			continue
 		}
 		p := rawProxy.(proxy.Inbound)
-		nl := p.Network()
-		if net.HasNetwork(nl, net.Network_TCP) {
-			worker := &tcpWorker{
-				tag:             h.tag,
-				address:         address,
-				port:            port,
-				proxy:           p,
-				stream:          h.streamSettings,
-				recvOrigDest:    h.receiverConfig.ReceiveOriginalDestination,
-				dispatcher:      h.mux,
-				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
-				uplinkCounter:   uplinkCounter,
-				downlinkCounter: downlinkCounter,
-				ctx:             h.ctx,
-			}
-			if err := worker.Start(); err != nil {
-				newError("failed to create TCP worker").Base(err).AtWarning().WriteToLog()
+		if runnable, ok := p.(common.Runnable); ok {
+			if err := runnable.Start(); err != nil {
+				newError("failed to start proxy instance").Base(err).AtWarning().WriteToLog()
 				continue
 			}
-			workers = append(workers, worker)
+			proxies = append(proxies, runnable)
 		}
-
-		if net.HasNetwork(nl, net.Network_UDP) {
-			worker := &udpWorker{
-				tag:             h.tag,
-				proxy:           p,
-				address:         address,
-				port:            port,
-				dispatcher:      h.mux,
-				uplinkCounter:   uplinkCounter,
-				downlinkCounter: downlinkCounter,
-				stream:          h.streamSettings,
+		nl := p.Network()
+		for _, address := range addresses {
+			if net.HasNetwork(nl, net.Network_TCP) {
+				worker := &tcpWorker{
+					tag:             h.tag,
+					address:         address,
+					port:            port,
+					proxy:           p,
+					stream:          h.streamSettings,
+					recvOrigDest:    h.receiverConfig.ReceiveOriginalDestination,
+					dispatcher:      h.mux,
+					sniffing:        h.sniffing,
+					recorder:        getRecorder(h.receiverConfig.RecordSettings),
+					uplinkCounter:   uplinkCounter,
+					downlinkCounter: downlinkCounter,
+					ctx:             h.ctx,
+				}
+				if err := worker.Start(); err != nil {
+					newError("failed to create TCP worker").Base(err).AtWarning().WriteToLog()
+					continue
+				}
+				workers = append(workers, worker)
 			}
-			if err := worker.Start(); err != nil {
-				newError("failed to create UDP worker").Base(err).AtWarning().WriteToLog()
-				continue
+
+			if net.HasNetwork(nl, net.Network_UDP) {
+				worker := &udpWorker{
+					tag:             h.tag,
+					proxy:           p,
+					address:         address,
+					port:            port,
+					dispatcher:      h.mux,
+					uplinkCounter:   uplinkCounter,
+					downlinkCounter: downlinkCounter,
+					stream:          h.streamSettings,
+				}
+				if err := worker.Start(); err != nil {
+					newError("failed to create UDP worker").Base(err).AtWarning().WriteToLog()
+					continue
+				}
+				workers = append(workers, worker)
 			}
-			workers = append(workers, worker)
 		}
 	}
 
+	a := &allocation{workers: workers, proxies: proxies}
 	h.workerMutex.Lock()
+	if h.closed {
+		h.workerMutex.Unlock()
+		h.release(a)
+		return nil
+	}
 	h.worker = workers
+	h.allocations = append(h.allocations, a)
 	h.workerMutex.Unlock()
 
 	time.AfterFunc(timeout, func() {
-		h.closeWorkers(workers)
+		h.workerMutex.Lock()
+		for i, x := range h.allocations {
+			if x == a {
+				h.allocations = append(h.allocations[:i], h.allocations[i+1:]...)
+				h.workerMutex.Unlock()
+				h.release(a)
+				return
+			}
+		}
+		// Already closed by Close.
+		h.workerMutex.Unlock()
 	})
 
 	return nil
 }
 
+// release closes the workers and proxy instances of a.
+func (h *DynamicInboundHandler) release(a *allocation) {
+	h.closeWorkers(a.workers)
+	for _, p := range a.proxies {
+		if err := p.Close(); err != nil {
+			newError("failed to close proxy instance").Base(err).WriteToLog()
+		}
+	}
+}
+
 func (h *DynamicInboundHandler) Start() error {
 	return h.task.Start()
 }
 
+// Close implements common.Closable. It closes all workers and proxy instances, including those not expired yet.
 func (h *DynamicInboundHandler) Close() error {
-	return h.task.Close()
+	if err := h.task.Close(); err != nil {
+		return err
+	}
+
+	h.workerMutex.Lock()
+	allocations := h.allocations
+	h.allocations = nil
+	h.worker = nil
+	h.closed = true
+	h.workerMutex.Unlock()
+
+	for _, a := range allocations {
+		h.release(a)
+	}
+	return nil
+}
+
+// Drain implements common.Drainable. No more workers are allocated after Drain is called.
+func (h *DynamicInboundHandler) Drain(ctx context.Context) error {
+	if err := h.task.Close(); err != nil {
+		return err
+	}
+
+	h.workerMutex.RLock()
+	workers := h.worker
+	h.workerMutex.RUnlock()
+
+	tasks := []func() error{
+		func() error { return h.mux.Drain(ctx) },
+	}
+	for _, w := range workers {
+		w := w
+		tasks = append(tasks, func() error { return w.Drain(ctx) })
+	}
+	if err := task.Run(ctx, tasks...); err != nil {
+		return newError("failed to drain all workers").Base(err)
+	}
+	return nil
 }
 
 func (h *DynamicInboundHandler) GetRandomInboundProxy() (interface{}, net.Port, int) {
@@ -11,6 +11,7 @@ import (
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/serial"
 	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/task"
 	"v2ray.com/core/features/inbound"
 )
 
@@ -107,6 +108,23 @@ func (m *Manager) Start() error {
 	return nil
 }
 
+// Drain implements common.Drainable. All handlers stop accepting new connections, and existing connections are given until ctx is done to finish.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.access.RLock()
+	var tasks []func() error
+	for _, handler := range m.taggedHandlers {
+		handler := handler
+		tasks = append(tasks, func() error { return common.Drain(ctx, handler) })
+	}
+	for _, handler := range m.untaggedHandler {
+		handler := handler
+		tasks = append(tasks, func() error { return common.Drain(ctx, handler) })
+	}
+	m.access.RUnlock()
+
+	return task.Run(ctx, tasks...)
+}
+
 // Close implements common.Closable.
 func (m *Manager) Close() error {
 	m.access.Lock()
@@ -6,10 +6,10 @@ import (
 	"sync/atomic"
 	"time"
 
-	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/record"
 	"v2ray.com/core/common/serial"
 	"v2ray.com/core/common/session"
 	"v2ray.com/core/common/signal/done"
@@ -19,6 +19,7 @@ import (
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/trace"
 	"v2ray.com/core/transport/internet/udp"
 	"v2ray.com/core/transport/pipe"
 )
@@ -26,10 +27,54 @@ import (
 type worker interface {
 	Start() error
 	Close() error
+	Drain(ctx context.Context) error
 	Port() net.Port
 	Proxy() proxy.Inbound
 }
 
+// connTracker keeps count of the connections in progress on a worker, so that the worker can be drained.
+type connTracker struct {
+	access   sync.Mutex
+	conns    sync.WaitGroup
+	draining bool
+}
+
+// add registers a new connection. It returns false if the worker is being drained and the connection should be rejected.
+func (t *connTracker) add() bool {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	if t.draining {
+		return false
+	}
+	t.conns.Add(1)
+	return true
+}
+
+func (t *connTracker) done() {
+	t.conns.Done()
+}
+
+// drain rejects all further connections and waits for existing ones to finish.
+func (t *connTracker) drain(ctx context.Context) error {
+	t.access.Lock()
+	t.draining = true
+	t.access.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.conns.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type tcpWorker struct {
 	address         net.Address
 	port            net.Port
@@ -38,15 +83,22 @@ type tcpWorker struct {
 	recvOrigDest    bool
 	tag             string
 	dispatcher      routing.Dispatcher
-	sniffingConfig  *proxyman.SniffingConfig
+	sniffing        session.SniffingRequest
+	recorder        *record.Recorder
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
 
-	hub internet.Listener
+	hub       internet.Listener
+	hubClosed bool
+	conns     connTracker
 
 	ctx context.Context
 }
 
+type hasNegotiatedProtocol interface {
+	NegotiatedProtocol() string
+}
+
 func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyMode {
 	if s == nil || s.SocketSettings == nil {
 		return internet.SocketConfig_Off
@@ -69,6 +121,9 @@ func (w *tcpWorker) callback(conn internet.Connection) {
 			} else {
 				dest = d
 			}
+			if records := internet.RedirectRecords(conn); len(records) > 0 {
+				ctx = internet.ContextWithRedirectRecords(ctx, records)
+			}
 		case internet.SocketConfig_TProxy:
 			dest = net.DestinationFromAddr(conn.LocalAddr())
 		}
@@ -83,12 +138,25 @@ func (w *tcpWorker) callback(conn internet.Connection) {
 		Gateway: net.TCPDestination(w.address, w.port),
 		Tag:     w.tag,
 	})
-	content := new(session.Content)
-	if w.sniffingConfig != nil {
-		content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
-		content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
+	content := &session.Content{
+		SniffingRequest: w.sniffing,
+	}
+	if negotiated, ok := conn.(hasNegotiatedProtocol); ok {
+		if protocol := negotiated.NegotiatedProtocol(); len(protocol) > 0 {
+			content.SetAttribute(":alpn", protocol)
+		}
 	}
 	ctx = session.ContextWithContent(ctx, content)
+	if carrier, ok := conn.(trace.Carrier); ok {
+		ctx = trace.ContextWithSession(ctx, carrier.TraceSession())
+	} else if s := w.stream.Tracer.Sample(); s != nil {
+		ctx = trace.ContextWithSession(ctx, s)
+	}
+	recorded := w.recorder.NewSession(w.tag, net.DestinationFromAddr(conn.RemoteAddr()))
+	if recorded != nil {
+		ctx = record.ContextWithSession(ctx, recorded)
+		conn = recorded.Conn(conn)
+	}
 	if w.uplinkCounter != nil || w.downlinkCounter != nil {
 		conn = &internet.StatCouterConnection{
 			Connection:   conn,
@@ -96,9 +164,15 @@ func (w *tcpWorker) callback(conn internet.Connection) {
 			WriteCounter: w.downlinkCounter,
 		}
 	}
-	if err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher); err != nil {
+	err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher)
+	if err != nil {
 		newError("connection ends").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
+	if path, err := recorded.Finish(err); err != nil {
+		newError("failed to record session").Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+	} else if path != "" {
+		newError("session recorded in ", path).AtInfo().WriteToLog(session.ExportIDToError(ctx))
+	}
 	cancel()
 	if err := conn.Close(); err != nil {
 		newError("failed to close connection").Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -110,22 +184,59 @@ func (w *tcpWorker) Proxy() proxy.Inbound {
 }
 
 func (w *tcpWorker) Start() error {
-	ctx := context.Background()
-	hub, err := internet.ListenTCP(ctx, w.address, w.port, w.stream, func(conn internet.Connection) {
-		go w.callback(conn)
+	address, port := w.address, w.port
+	forwarder, forwarded := w.proxy.(proxy.ForwardedInbound)
+	if forwarded {
+		if address.Family().IsDomain() {
+			return newError("connections to ", address, " can't be forwarded").AtWarning()
+		}
+		// The forwarder listens on the port instead.
+		address, port = net.LocalHostIP, 0
+	}
+
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: w.tag})
+	hub, err := internet.ListenTCP(ctx, address, port, w.stream, func(conn internet.Connection) {
+		if !w.conns.add() {
+			conn.Close() // nolint: errcheck
+			return
+		}
+		go func() {
+			defer w.conns.done()
+			w.callback(conn)
+		}()
 	})
 	if err != nil {
 		return newError("failed to listen TCP on ", w.port).AtWarning().Base(err)
 	}
+	if forwarded {
+		if err := forwarder.StartForwarder(net.TCPDestination(w.address, w.port), net.DestinationFromAddr(hub.Addr())); err != nil {
+			hub.Close() // nolint: errcheck
+			return newError("failed to forward TCP on ", w.port).AtWarning().Base(err)
+		}
+	}
 	w.hub = hub
 	return nil
 }
 
+// Drain implements common.Drainable. It closes the listener, and waits for all accepted connections to finish.
+func (w *tcpWorker) Drain(ctx context.Context) error {
+	if w.hub == nil {
+		return nil
+	}
+	if err := common.Close(w.hub); err != nil {
+		return newError("failed to close listener on ", w.port).Base(err)
+	}
+	w.hubClosed = true
+	return w.conns.drain(ctx)
+}
+
 func (w *tcpWorker) Close() error {
 	var errors []interface{}
 	if w.hub != nil {
-		if err := common.Close(w.hub); err != nil {
-			errors = append(errors, err)
+		if !w.hubClosed {
+			if err := common.Close(w.hub); err != nil {
+				errors = append(errors, err)
+			}
 		}
 		if err := common.Close(w.proxy); err != nil {
 			errors = append(errors, err)
@@ -235,6 +346,7 @@ type udpWorker struct {
 
 	checker    *task.Periodic
 	activeConn map[connID]*udpConn
+	conns      connTracker
 }
 
 func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
@@ -245,6 +357,10 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 		return conn, true
 	}
 
+	if !w.conns.add() {
+		return nil, false
+	}
+
 	pReader, pWriter := pipe.New(pipe.DiscardOverflow(), pipe.WithSizeLimit(16*1024))
 	conn := &udpConn{
 		reader: pReader,
@@ -278,6 +394,11 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 		id.dest = originalDest
 	}
 	conn, existing := w.getConnection(id)
+	if conn == nil {
+		// The worker is draining. No new session is accepted.
+		b.Release()
+		return
+	}
 
 	// payload will be discarded in pipe is full.
 	conn.writer.WriteMultiBuffer(buf.MultiBuffer{b}) // nolint: errcheck
@@ -286,6 +407,8 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 		common.Must(w.checker.Start())
 
 		go func() {
+			defer w.conns.done()
+
 			ctx := context.Background()
 			sid := session.NewID()
 			ctx = session.ContextWithID(ctx, sid)
@@ -347,7 +470,7 @@ func (w *udpWorker) clean() error {
 
 func (w *udpWorker) Start() error {
 	w.activeConn = make(map[connID]*udpConn, 16)
-	ctx := context.Background()
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: w.tag})
 	h, err := udp.ListenUDP(ctx, w.address, w.port, w.stream, udp.HubCapacity(256))
 	if err != nil {
 		return err
@@ -363,6 +486,11 @@ func (w *udpWorker) Start() error {
 	return nil
 }
 
+// Drain implements common.Drainable. Packets from unknown sources are dropped, while existing sessions continue until they time out.
+func (w *udpWorker) Drain(ctx context.Context) error {
+	return w.conns.drain(ctx)
+}
+
 func (w *udpWorker) Close() error {
 	w.Lock()
 	defer w.Unlock()
@@ -398,3 +526,81 @@ func (w *udpWorker) Port() net.Port {
 func (w *udpWorker) Proxy() proxy.Inbound {
 	return w.proxy
 }
+
+// deviceWorker serves a proxy.DeviceInbound, which captures connections from a network device by itself.
+type deviceWorker struct {
+	proxy           proxy.DeviceInbound
+	tag             string
+	dispatcher      routing.Dispatcher
+	sniffing        session.SniffingRequest
+	uplinkCounter   stats.Counter
+	downlinkCounter stats.Counter
+	conns           connTracker
+
+	ctx context.Context
+}
+
+func (w *deviceWorker) callback(network net.Network, conn internet.Connection) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	sid := session.NewID()
+	ctx = session.ContextWithID(ctx, sid)
+
+	dest := net.DestinationFromAddr(conn.LocalAddr())
+	dest.Network = network
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: dest,
+	})
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Source: net.DestinationFromAddr(conn.RemoteAddr()),
+		Tag:    w.tag,
+	})
+	content := new(session.Content)
+	if network == net.Network_TCP {
+		content.SniffingRequest = w.sniffing
+	}
+	ctx = session.ContextWithContent(ctx, content)
+	if w.uplinkCounter != nil || w.downlinkCounter != nil {
+		conn = &internet.StatCouterConnection{
+			Connection:   conn,
+			ReadCounter:  w.uplinkCounter,
+			WriteCounter: w.downlinkCounter,
+		}
+	}
+	if err := w.proxy.Process(ctx, network, conn, w.dispatcher); err != nil {
+		newError("connection ends").Base(err).WriteToLog(session.ExportIDToError(ctx))
+	}
+	cancel()
+	if err := conn.Close(); err != nil {
+		newError("failed to close connection").Base(err).WriteToLog(session.ExportIDToError(ctx))
+	}
+}
+
+func (w *deviceWorker) Start() error {
+	return w.proxy.StartDevice(func(network net.Network, conn internet.Connection) {
+		if !w.conns.add() {
+			conn.Close() // nolint: errcheck
+			return
+		}
+		go func() {
+			defer w.conns.done()
+			w.callback(network, conn)
+		}()
+	})
+}
+
+// Drain implements common.Drainable. New connections are rejected, and existing ones continue until they finish.
+func (w *deviceWorker) Drain(ctx context.Context) error {
+	return w.conns.drain(ctx)
+}
+
+func (w *deviceWorker) Close() error {
+	return common.Close(w.proxy)
+}
+
+func (w *deviceWorker) Port() net.Port {
+	return 0
+}
+
+func (w *deviceWorker) Proxy() proxy.Inbound {
+	return w.proxy
+}
@@ -2,6 +2,8 @@ package outbound
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"v2ray.com/core"
 	"v2ray.com/core/app/proxyman"
@@ -16,6 +18,7 @@ import (
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/trace"
 	"v2ray.com/core/transport/pipe"
 )
 
@@ -54,6 +57,10 @@ type Handler struct {
 	mux             *mux.ClientManager
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+
+	access      sync.Mutex
+	conns       map[internet.Connection]struct{}
+	connections map[*outbound.Connection]struct{}
 }
 
 // NewHandler create a new Handler based on the given configuration.
@@ -65,6 +72,8 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		outboundManager: v.GetFeature(outbound.ManagerType()).(outbound.Manager),
 		uplinkCounter:   uplinkCounter,
 		downlinkCounter: downlinkCounter,
+		conns:           make(map[internet.Connection]struct{}),
+		connections:     make(map[*outbound.Connection]struct{}),
 	}
 
 	if config.SenderSettings != nil {
@@ -131,6 +140,27 @@ func (h *Handler) Tag() string {
 
 // Dispatch implements proxy.Outbound.Dispatch.
 func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
+	// Connections dialed for this dispatch are no longer tracked once it returns.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Frames of the inbound connection are traced by the settings of the inbound.
+	var traceSession *trace.Session
+	if h.streamSettings != nil {
+		traceSession = h.streamSettings.Tracer.Sample()
+	}
+	ctx = trace.ContextWithSession(ctx, traceSession)
+
+	c := h.newConnection(ctx)
+	h.access.Lock()
+	h.connections[c] = struct{}{}
+	h.access.Unlock()
+	defer func() {
+		h.access.Lock()
+		delete(h.connections, c)
+		h.access.Unlock()
+	}()
+
 	if h.mux != nil && (h.mux.Enabled || session.MuxPreferedFromContext(ctx)) {
 		if err := h.mux.Dispatch(ctx, link); err != nil {
 			newError("failed to process mux outbound traffic").Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -139,7 +169,12 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 	} else {
 		if err := h.proxy.Process(ctx, link, h); err != nil {
 			// Ensure outbound ray is properly closed.
-			newError("failed to process outbound traffic").Base(err).WriteToLog(session.ExportIDToError(ctx))
+			if hops := session.HopsFromContext(ctx); hops != nil {
+				// The handler is a hop of a chain, dispatched with the hops before it.
+				newError("hop ", len(hops)+1, " (", h.tag, ") of chain failed").Base(err).WriteToLog(session.ExportIDToError(ctx))
+			} else {
+				newError("failed to process outbound traffic").Base(err).WriteToLog(session.ExportIDToError(ctx))
+			}
 			common.Interrupt(link.Writer)
 		} else {
 			common.Must(common.Close(link.Writer))
@@ -148,6 +183,34 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 	}
 }
 
+func (h *Handler) newConnection(ctx context.Context) *outbound.Connection {
+	c := &outbound.Connection{
+		ID:          uint32(session.IDFromContext(ctx)),
+		OutboundTag: h.tag,
+		Start:       time.Now(),
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		c.InboundTag = inbound.Tag
+		c.Source = inbound.Source
+	}
+	if outbound := session.OutboundFromContext(ctx); outbound != nil {
+		c.Target = outbound.Target
+	}
+	return c
+}
+
+// ListConnections implements outbound.ConnectionLister.
+func (h *Handler) ListConnections() []*outbound.Connection {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	connections := make([]*outbound.Connection, 0, len(h.connections))
+	for c := range h.connections {
+		connections = append(connections, c)
+	}
+	return connections
+}
+
 // Address implements internet.Dialer.
 func (h *Handler) Address() net.Address {
 	if h.senderSettings == nil || h.senderSettings.Via == nil {
@@ -158,29 +221,24 @@ func (h *Handler) Address() net.Address {
 
 // Dial implements internet.Dialer.
 func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Connection, error) {
+	// In a chain, the handler dials through the hop before it, regardless of its own proxy settings.
+	if hops := session.HopsFromContext(ctx); len(hops) > 0 {
+		tag := hops[len(hops)-1]
+		handler := h.outboundManager.GetHandler(tag)
+		if handler == nil {
+			return nil, newError("hop ", len(hops), " (", tag, ") of chain not found")
+		}
+		newError("dialing ", dest, " through hop ", len(hops), " (", tag, ")").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+		return h.dialThrough(session.ContextWithHops(ctx, hops[:len(hops)-1]), handler, dest), nil
+	}
+
 	if h.senderSettings != nil {
 		if h.senderSettings.ProxySettings.HasTag() {
 			tag := h.senderSettings.ProxySettings.Tag
 			handler := h.outboundManager.GetHandler(tag)
 			if handler != nil {
 				newError("proxying to ", tag, " for dest ", dest).AtDebug().WriteToLog(session.ExportIDToError(ctx))
-				ctx = session.ContextWithOutbound(ctx, &session.Outbound{
-					Target: dest,
-				})
-
-				opts := pipe.OptionsFromContext(ctx)
-				uplinkReader, uplinkWriter := pipe.New(opts...)
-				downlinkReader, downlinkWriter := pipe.New(opts...)
-
-				go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
-				conn := net.NewConnection(net.ConnectionInputMulti(uplinkWriter), net.ConnectionOutputMulti(downlinkReader))
-
-				if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
-					tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
-					conn = tls.Client(conn, tlsConfig)
-				}
-
-				return h.getStatCouterConnection(conn), nil
+				return h.dialThrough(ctx, handler, dest), nil
 			}
 
 			newError("failed to get outbound handler with tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
@@ -196,10 +254,95 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Conn
 		}
 	}
 
+	if h.streamSettings != nil && h.streamSettings.SocketSettings != nil && len(h.streamSettings.SocketSettings.DialerProxy) > 0 {
+		ctx = internet.ContextWithProxyDialer(ctx, h.dialProxy)
+	}
+
 	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	if err == nil {
+		h.track(ctx, conn)
+	}
 	return h.getStatCouterConnection(conn), err
 }
 
+// track keeps conn in the set of open connections until ctx is done.
+// dialThrough returns a connection to dest, whose traffic is sent by the given handler. Only the TLS settings in the
+// stream settings of h apply.
+func (h *Handler) dialThrough(ctx context.Context, handler outbound.Handler, dest net.Destination) internet.Connection {
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: dest,
+	})
+
+	opts := pipe.OptionsFromContext(ctx)
+	uplinkReader, uplinkWriter := pipe.New(opts...)
+	downlinkReader, downlinkWriter := pipe.New(opts...)
+
+	go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+	conn := net.NewConnection(net.ConnectionInputMulti(uplinkWriter), net.ConnectionOutputMulti(downlinkReader))
+
+	if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
+		tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	return h.getStatCouterConnection(conn)
+}
+
+// dialProxy implements internet.ProxyDialer. The underlying connections of the transport of h are dialed through the
+// handler with the given tag, without the TLS settings of h, which are applied by the transport itself.
+func (h *Handler) dialProxy(ctx context.Context, tag string, dest net.Destination) (net.Conn, error) {
+	if tag == h.tag {
+		return nil, newError("dialer proxy of ", tag, " refers to itself")
+	}
+	handler := h.outboundManager.GetHandler(tag)
+	if handler == nil {
+		return nil, newError("dialer proxy ", tag, " not found")
+	}
+	newError("dialing ", dest, " through dialer proxy ", tag).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: dest,
+	})
+
+	opts := pipe.OptionsFromContext(ctx)
+	uplinkReader, uplinkWriter := pipe.New(opts...)
+	downlinkReader, downlinkWriter := pipe.New(opts...)
+
+	go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+	return net.NewConnection(net.ConnectionInputMulti(uplinkWriter), net.ConnectionOutputMulti(downlinkReader)), nil
+}
+
+func (h *Handler) track(ctx context.Context, conn internet.Connection) {
+	h.access.Lock()
+	h.conns[conn] = struct{}{}
+	h.access.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.access.Lock()
+		delete(h.conns, conn)
+		h.access.Unlock()
+	}()
+}
+
+// ResetConnections implements outbound.ConnectionResetter.
+func (h *Handler) ResetConnections(stale func(local net.Addr) bool) {
+	h.access.Lock()
+	var conns []internet.Connection
+	for conn := range h.conns {
+		if stale(conn.LocalAddr()) {
+			conns = append(conns, conn)
+			delete(h.conns, conn)
+		}
+	}
+	h.access.Unlock()
+
+	for _, conn := range conns {
+		newError("closing connection from stale address ", conn.LocalAddr()).AtInfo().WriteToLog()
+		conn.Close()
+	}
+}
+
 func (h *Handler) getStatCouterConnection(conn internet.Connection) internet.Connection {
 	if h.uplinkCounter != nil || h.downlinkCounter != nil {
 		return &internet.StatCouterConnection{
@@ -218,11 +361,17 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if p, ok := h.proxy.(common.Runnable); ok {
+		return p.Start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
 	common.Close(h.mux)
+	if p, ok := h.proxy.(common.Runnable); ok {
+		return p.Close()
+	}
 	return nil
 }
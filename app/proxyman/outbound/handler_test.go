@@ -3,6 +3,7 @@ package outbound_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"v2ray.com/core"
 	"v2ray.com/core/app/policy"
@@ -10,9 +11,13 @@ import (
 	"v2ray.com/core/app/stats"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/serial"
+	"v2ray.com/core/common/session"
 	"v2ray.com/core/features/outbound"
 	"v2ray.com/core/proxy/freedom"
+	"v2ray.com/core/testing/servers/tcp"
+	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/pipe"
 )
 
 func TestInterfaces(t *testing.T) {
@@ -78,3 +83,58 @@ func TestOutboundWithStatCounter(t *testing.T) {
 		t.Errorf("Expected conn to be StatCouterConnection")
 	}
 }
+
+func TestListConnections(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpServer.Close()
+
+	v, _ := core.New(&core.Config{})
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := context.WithValue(context.Background(), v2rayKey, v)
+	h, _ := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag:           "direct",
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	})
+
+	ctx = session.ContextWithID(ctx, session.NewID())
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Tag:    "in",
+		Source: net.TCPDestination(net.LocalHostIP, 10000),
+	})
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: dest,
+	})
+	uplinkReader, uplinkWriter := pipe.New()
+	_, downlinkWriter := pipe.New()
+	done := make(chan struct{})
+	go func() {
+		h.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+		close(done)
+	}()
+
+	time.Sleep(time.Millisecond * 500)
+	connections := h.(outbound.ConnectionLister).ListConnections()
+	if len(connections) != 1 {
+		t.Fatal("expect 1 connection, but got ", len(connections))
+	}
+	c := connections[0]
+	if c.InboundTag != "in" || c.OutboundTag != "direct" || c.Target != dest || c.Source.Port != 10000 {
+		t.Error("unexpected connection: ", c)
+	}
+
+	uplinkWriter.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("dispatch doesn't finish")
+	}
+	if n := len(h.(outbound.ConnectionLister).ListConnections()); n != 0 {
+		t.Error("expect no connection, but got ", n)
+	}
+}
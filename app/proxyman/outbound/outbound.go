@@ -11,6 +11,7 @@ import (
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/errors"
+	"v2ray.com/core/common/net"
 	"v2ray.com/core/features/outbound"
 )
 
@@ -137,6 +138,42 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	return nil
 }
 
+// ResetConnections implements outbound.ConnectionResetter.
+func (m *Manager) ResetConnections(stale func(local net.Addr) bool) {
+	m.access.RLock()
+	handlers := make([]outbound.Handler, 0, len(m.taggedHandler)+len(m.untaggedHandlers))
+	for _, h := range m.taggedHandler {
+		handlers = append(handlers, h)
+	}
+	handlers = append(handlers, m.untaggedHandlers...)
+	m.access.RUnlock()
+
+	for _, h := range handlers {
+		if r, ok := h.(outbound.ConnectionResetter); ok {
+			r.ResetConnections(stale)
+		}
+	}
+}
+
+// ListConnections implements outbound.ConnectionLister.
+func (m *Manager) ListConnections() []*outbound.Connection {
+	m.access.RLock()
+	handlers := make([]outbound.Handler, 0, len(m.taggedHandler)+len(m.untaggedHandlers))
+	for _, h := range m.taggedHandler {
+		handlers = append(handlers, h)
+	}
+	handlers = append(handlers, m.untaggedHandlers...)
+	m.access.RUnlock()
+
+	var connections []*outbound.Connection
+	for _, h := range handlers {
+		if l, ok := h.(outbound.ConnectionLister); ok {
+			connections = append(connections, l.ListConnections()...)
+		}
+	}
+	return connections
+}
+
 // Select implements outbound.HandlerSelector.
 func (m *Manager) Select(selectors []string) []string {
 	m.access.RLock()
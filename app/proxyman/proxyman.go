@@ -1,6 +1,8 @@
 // Package proxyman defines applications for managing inbound and outbound proxies.
 package proxyman
 
+//go:generate errorgen
+
 import (
 	"context"
 
@@ -15,7 +17,10 @@ func ContextWithSniffingConfig(ctx context.Context, c *SniffingConfig) context.C
 		content = new(session.Content)
 		ctx = session.ContextWithContent(ctx, content)
 	}
-	content.SniffingRequest.Enabled = c.Enabled
-	content.SniffingRequest.OverrideDestinationForProtocol = c.DestinationOverride
+	request, err := c.BuildRequest()
+	if err != nil {
+		newError("invalid sniffing config").Base(err).AtWarning().WriteToLog()
+	}
+	content.SniffingRequest = request
 	return ctx
 }
@@ -0,0 +1,92 @@
+package proxyman
+
+import (
+	"strings"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/strmatcher"
+)
+
+// SniffingExclusion matches destinations and sniffed domains that are never overridden by content sniffing.
+type SniffingExclusion struct {
+	domains *strmatcher.MatcherGroup
+	ips     []*net.IPNet
+}
+
+// NewSniffingExclusion creates a SniffingExclusion from a list of domains and IPs. Domains may be prefixed by
+// "domain:", "full:", "regexp:" or "keyword:" as in routing rules, and plain domains are matched as keywords. IPs may
+// be in CIDR.
+func NewSniffingExclusion(entries []string) (*SniffingExclusion, error) {
+	e := &SniffingExclusion{
+		domains: new(strmatcher.MatcherGroup),
+	}
+	for _, entry := range entries {
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			e.ips = append(e.ips, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			e.ips = append(e.ips, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		matcherType := strmatcher.Substr
+		pattern := entry
+		switch {
+		case strings.HasPrefix(entry, "domain:"):
+			matcherType, pattern = strmatcher.Domain, entry[7:]
+		case strings.HasPrefix(entry, "full:"):
+			matcherType, pattern = strmatcher.Full, entry[5:]
+		case strings.HasPrefix(entry, "regexp:"):
+			matcherType, pattern = strmatcher.Regex, entry[7:]
+		case strings.HasPrefix(entry, "keyword:"):
+			pattern = entry[8:]
+		}
+		matcher, err := matcherType.New(strings.ToLower(pattern))
+		if err != nil {
+			return nil, newError("invalid excluded domain ", entry).Base(err)
+		}
+		e.domains.Add(matcher)
+	}
+	return e, nil
+}
+
+// Match reports whether the address is excluded.
+func (e *SniffingExclusion) Match(address net.Address) bool {
+	if address.Family().IsDomain() {
+		return len(e.domains.Match(strings.ToLower(address.Domain()))) > 0
+	}
+	ip := address.IP()
+	for _, ipNet := range e.ips {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildRequest returns the sniffing request for connections of the inbound.
+func (c *SniffingConfig) BuildRequest() (session.SniffingRequest, error) {
+	request := session.SniffingRequest{
+		Enabled:                        c.Enabled,
+		OverrideDestinationForProtocol: c.DestinationOverride,
+		RouteOnly:                      c.RouteOnly,
+		MetadataOnly:                   c.MetadataOnly,
+	}
+	if c.Ports != nil {
+		request.Ports = net.PortListFromProto(c.Ports)
+	}
+	if len(c.DomainsExcluded) > 0 {
+		exclusion, err := NewSniffingExclusion(c.DomainsExcluded)
+		if err != nil {
+			return request, err
+		}
+		request.Excluded = exclusion.Match
+	}
+	return request, nil
+}
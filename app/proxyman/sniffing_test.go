@@ -0,0 +1,51 @@
+package proxyman_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+)
+
+func TestSniffingExclusion(t *testing.T) {
+	exclusion, err := NewSniffingExclusion([]string{
+		"domain:apple.com",
+		"full:www.v2fly.org",
+		"regexp:^cdn[0-9]+\\.example\\.com$",
+		"keyword:local",
+		"10.0.0.0/8",
+		"2001:db8::1",
+		"192.168.1.1",
+	})
+	common.Must(err)
+
+	testCases := []struct {
+		address net.Address
+		match   bool
+	}{
+		{net.DomainAddress("apple.com"), true},
+		{net.DomainAddress("www.Apple.com"), true},
+		{net.DomainAddress("notapple.com"), false},
+		{net.DomainAddress("www.v2fly.org"), true},
+		{net.DomainAddress("v2fly.org"), false},
+		{net.DomainAddress("cdn12.example.com"), true},
+		{net.DomainAddress("cdn.example.com"), false},
+		{net.DomainAddress("printer.local"), true},
+		{net.ParseAddress("10.1.2.3"), true},
+		{net.ParseAddress("11.1.2.3"), false},
+		{net.ParseAddress("2001:db8::1"), true},
+		{net.ParseAddress("2001:db8::2"), false},
+		{net.ParseAddress("192.168.1.1"), true},
+		{net.ParseAddress("192.168.1.2"), false},
+	}
+	for _, testCase := range testCases {
+		if actual := exclusion.Match(testCase.address); actual != testCase.match {
+			t.Error("expect ", testCase.address, " to match ", testCase.match, ", but got ", actual)
+		}
+	}
+
+	if _, err := NewSniffingExclusion([]string{"regexp:("}); err == nil {
+		t.Error("expect error for invalid regexp")
+	}
+}
@@ -0,0 +1,60 @@
+// +build !confonly
+
+// Package command provides the API to reload V2Ray with its config files.
+package command
+
+//go:generate errorgen
+
+import (
+	"context"
+	"sync"
+
+	grpc "google.golang.org/grpc"
+
+	"v2ray.com/core/common"
+)
+
+var (
+	reloaderAccess sync.Mutex
+	reloader       func() error
+)
+
+// SetReloader sets the function to reload V2Ray. It is set by the launcher, which owns the config files. The function
+// should validate the new config before returning, and restart V2Ray asynchronously, as the API server itself is
+// closed in the restart.
+func SetReloader(f func() error) {
+	reloaderAccess.Lock()
+	reloader = f
+	reloaderAccess.Unlock()
+}
+
+type ReloadServer struct{}
+
+// Reload implements ReloadService.
+func (s *ReloadServer) Reload(ctx context.Context, request *ReloadRequest) (*ReloadResponse, error) {
+	reloaderAccess.Lock()
+	f := reloader
+	reloaderAccess.Unlock()
+
+	if f == nil {
+		return nil, newError("reload is not supported by this launcher")
+	}
+	if err := f(); err != nil {
+		return nil, newError("failed to reload").Base(err)
+	}
+	return &ReloadResponse{}, nil
+}
+
+func (s *ReloadServer) mustEmbedUnimplementedReloadServiceServer() {}
+
+type service struct{}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterReloadServiceServer(server, &ReloadServer{})
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		return &service{}, nil
+	}))
+}
@@ -159,6 +159,23 @@ func (v *PortMatcher) Apply(ctx routing.Context) bool {
 	}
 }
 
+// LocalPortMatcher matches the local port that the inbound accepted the connection on.
+type LocalPortMatcher struct {
+	port net.MemoryPortList
+}
+
+// NewLocalPortMatcher creates a new LocalPortMatcher.
+func NewLocalPortMatcher(list *net.PortList) *LocalPortMatcher {
+	return &LocalPortMatcher{
+		port: net.PortListFromProto(list),
+	}
+}
+
+// Apply implements Condition.
+func (v *LocalPortMatcher) Apply(ctx routing.Context) bool {
+	return v.port.Contains(ctx.GetLocalPort())
+}
+
 type NetworkMatcher struct {
 	list [8]bool
 }
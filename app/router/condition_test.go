@@ -306,6 +306,34 @@ func TestRoutingRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			rule: &RoutingRule{
+				LocalPortList: &net.PortList{
+					Range: []*net.PortRange{
+						{From: 443, To: 443},
+						{From: 10000, To: 10100},
+					},
+				},
+			},
+			test: []ruleTest{
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.TCPDestination(net.AnyIP, 443)}),
+					output: true,
+				},
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.UDPDestination(net.AnyIP, 10050)}),
+					output: true,
+				},
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.TCPDestination(net.AnyIP, 80), Source: net.TCPDestination(net.LocalHostIP, 443)}),
+					output: false,
+				},
+				{
+					input:  withInbound(&session.Inbound{}),
+					output: false,
+				},
+			},
+		},
 		{
 			rule: &RoutingRule{
 				Protocol:   []string{"http"},
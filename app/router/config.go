@@ -51,6 +51,8 @@ type Rule struct {
 	Tag       string
 	Balancer  *Balancer
 	Condition Condition
+	// Index is the position of the rule in Config.
+	Index int
 }
 
 func (r *Rule) GetTag() (string, error) {
@@ -94,6 +96,10 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(NewPortMatcher(rr.SourcePortList, true))
 	}
 
+	if rr.LocalPortList != nil {
+		conds.Add(NewLocalPortMatcher(rr.LocalPortList))
+	}
+
 	if len(rr.Networks) > 0 {
 		conds.Add(NewNetworkMatcher(rr.Networks))
 	} else if rr.NetworkList != nil {
@@ -50,7 +50,7 @@ func (r *Router) Init(config *Config, d dns.Client, ohm outbound.Manager) error
 	}
 
 	r.rules = make([]*Rule, 0, len(config.Rule))
-	for _, rule := range config.Rule {
+	for idx, rule := range config.Rule {
 		cond, err := rule.BuildCondition()
 		if err != nil {
 			return err
@@ -58,6 +58,7 @@ func (r *Router) Init(config *Config, d dns.Client, ohm outbound.Manager) error
 		rr := &Rule{
 			Condition: cond,
 			Tag:       rule.GetTag(),
+			Index:     idx,
 		}
 		btag := rule.GetBalancingTag()
 		if len(btag) > 0 {
@@ -82,6 +83,11 @@ func (r *Router) PickRoute(ctx routing.Context) (string, error) {
 	return rule.GetTag()
 }
 
+// PickRule returns the first rule that matches the routing context, or common.ErrNoClue if no rule matches.
+func (r *Router) PickRule(ctx routing.Context) (*Rule, error) {
+	return r.pickRouteInternal(ctx)
+}
+
 func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, error) {
 	if r.domainStrategy == Config_IpOnDemand {
 		ctx = ContextWithDNSClient(ctx, r.dns)
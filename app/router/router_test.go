@@ -196,3 +196,37 @@ func TestIPIfNonMatchIP(t *testing.T) {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
 }
+
+func TestPickRule(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "udp",
+				},
+				Networks: []net.Network{net.Network_UDP},
+			},
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "tcp",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+		},
+	}
+
+	r := new(Router)
+	common.Must(r.Init(config, nil, nil))
+
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.TCPDestination(net.DomainAddress("v2ray.com"), 80)})
+	rule, err := r.PickRule(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if rule.Index != 1 || rule.Tag != "tcp" {
+		t.Error("expect rule 1 with tag 'tcp', but actually ", rule.Index, " ", rule.Tag)
+	}
+
+	ctx = session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.Destination{Network: net.Network_Unknown}})
+	if _, err := r.PickRule(routing_session.AsRoutingContext(ctx)); err != common.ErrNoClue {
+		t.Error("expect no rule matches, but got ", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"v2ray.com/core"
 	"v2ray.com/core/app/stats"
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/platform"
 	"v2ray.com/core/common/strmatcher"
 	feature_stats "v2ray.com/core/features/stats"
 )
@@ -99,6 +100,7 @@ func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest)
 		LiveObjects:  rtm.Mallocs - rtm.Frees,
 		NumGC:        rtm.NumGC,
 		PauseTotalNs: rtm.PauseTotalNs,
+		MaxOpenFiles: platform.FileLimit(),
 	}
 
 	return response, nil
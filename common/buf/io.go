@@ -28,6 +28,13 @@ type Writer interface {
 	WriteMultiBuffer(MultiBuffer) error
 }
 
+// MultiBufferWriter is a writer that writes all Buffers of a MultiBuffer in one operation, e.g., by a single writev(2) call,
+// instead of concatenating them beforehand or writing them one by one. For packet connections, the whole MultiBuffer is sent as one packet.
+type MultiBufferWriter interface {
+	// WriteVectored writes all Buffers in the MultiBuffer in one operation. It takes ownership of the given MultiBuffer.
+	WriteVectored(MultiBuffer) error
+}
+
 // WriteAllBytes ensures all bytes are written into the given writer.
 func WriteAllBytes(writer io.Writer, payload []byte) error {
 	for len(payload) > 0 {
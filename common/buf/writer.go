@@ -57,6 +57,11 @@ func (w *BufferToBytesWriter) WriteMultiBuffer(mb MultiBuffer) error {
 	return nil
 }
 
+// WriteVectored implements MultiBufferWriter.
+func (w *BufferToBytesWriter) WriteVectored(mb MultiBuffer) error {
+	return writeVectored(w.Writer, mb)
+}
+
 // ReadFrom implements io.ReaderFrom.
 func (w *BufferToBytesWriter) ReadFrom(reader io.Reader) (int64, error) {
 	var sc SizeCounter
@@ -64,11 +69,62 @@ func (w *BufferToBytesWriter) ReadFrom(reader io.Reader) (int64, error) {
 	return sc.Size, err
 }
 
+// isVectoredConn returns true if the given writer supports writev(2) through net.Buffers.
+func isVectoredConn(writer io.Writer) bool {
+	switch c := writer.(type) {
+	case *net.TCPConn, *net.UnixConn:
+		return true
+	case *net.UDPConn:
+		// Only connected UDP sockets are writable without a destination.
+		return c.RemoteAddr() != nil
+	default:
+		return false
+	}
+}
+
+// writeVectored writes all Buffers in mb into writer with one write operation. It takes ownership of mb.
+func writeVectored(writer io.Writer, mb MultiBuffer) error {
+	defer ReleaseMulti(mb)
+
+	size := mb.Len()
+	if size == 0 {
+		return nil
+	}
+
+	if len(mb) == 1 {
+		return WriteAllBytes(writer, mb[0].Bytes())
+	}
+
+	if isVectoredConn(writer) {
+		bs := make(net.Buffers, 0, len(mb))
+		for _, b := range mb {
+			bs = append(bs, b.Bytes())
+		}
+		_, err := bs.WriteTo(writer)
+		return err
+	}
+
+	if size <= Size {
+		b := StackNew()
+		defer b.Release()
+
+		mb.Copy(b.Extend(size))
+		return WriteAllBytes(writer, b.Bytes())
+	}
+
+	payload := make([]byte, size)
+	mb.Copy(payload)
+	return WriteAllBytes(writer, payload)
+}
+
 // BufferedWriter is a Writer with internal buffer.
+// If the underlying Writer is a MultiBufferWriter, MultiBuffers written into BufferedWriter are kept as they are instead of being copied,
+// and all buffered content is written with one vectored write on flush.
 type BufferedWriter struct {
 	sync.Mutex
 	writer   Writer
 	buffer   *Buffer
+	pending  MultiBuffer
 	buffered bool
 }
 
@@ -136,6 +192,18 @@ func (w *BufferedWriter) WriteMultiBuffer(b MultiBuffer) error {
 		return w.writer.WriteMultiBuffer(b)
 	}
 
+	if _, ok := w.writer.(MultiBufferWriter); ok {
+		if !w.buffer.IsEmpty() {
+			w.pending = append(w.pending, w.buffer)
+			w.buffer = nil
+		}
+		w.pending = append(w.pending, b...)
+		if w.pending.Len() >= Size {
+			return w.flushInternal()
+		}
+		return nil
+	}
+
 	reader := MultiBufferContainer{
 		MultiBuffer: b,
 	}
@@ -165,6 +233,16 @@ func (w *BufferedWriter) Flush() error {
 }
 
 func (w *BufferedWriter) flushInternal() error {
+	if len(w.pending) > 0 {
+		mb := w.pending
+		w.pending = nil
+		if !w.buffer.IsEmpty() {
+			mb = append(mb, w.buffer)
+			w.buffer = nil
+		}
+		return w.writer.(MultiBufferWriter).WriteVectored(mb)
+	}
+
 	if w.buffer.IsEmpty() {
 		return nil
 	}
@@ -224,6 +302,11 @@ func (w *SequentialWriter) WriteMultiBuffer(mb MultiBuffer) error {
 	return err
 }
 
+// WriteVectored implements MultiBufferWriter.
+func (w *SequentialWriter) WriteVectored(mb MultiBuffer) error {
+	return writeVectored(w.Writer, mb)
+}
+
 type noOpWriter byte
 
 func (noOpWriter) WriteMultiBuffer(b MultiBuffer) error {
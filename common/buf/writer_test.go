@@ -96,3 +96,46 @@ func TestWriterInterface(t *testing.T) {
 		}
 	}
 }
+
+type vectoredRecorder struct {
+	bytes.Buffer
+	calls int
+}
+
+func (r *vectoredRecorder) Write(b []byte) (int, error) {
+	r.calls++
+	return r.Buffer.Write(b)
+}
+
+func TestBufferedWriterVectored(t *testing.T) {
+	recorder := new(vectoredRecorder)
+	writer := NewBufferedWriter(&SequentialWriter{Writer: recorder})
+
+	common.Must2(writer.Write([]byte("header")))
+	common.Must(writer.WriteMultiBuffer(MergeBytes(nil, []byte("payload"))))
+	common.Must2(writer.Write([]byte("padding")))
+	common.Must(writer.SetBuffered(false))
+
+	if recorder.calls != 1 {
+		t.Error("expect 1 write, but got ", recorder.calls)
+	}
+	if r := cmp.Diff(recorder.String(), "headerpayloadpadding"); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestWriteVectoredLargeMultiBuffer(t *testing.T) {
+	data := make([]byte, Size*3)
+	common.Must2(rand.Read(data))
+
+	recorder := new(vectoredRecorder)
+	writer := &SequentialWriter{Writer: recorder}
+	common.Must(writer.WriteVectored(MergeBytes(nil, data)))
+
+	if recorder.calls != 1 {
+		t.Error("expect 1 write, but got ", recorder.calls)
+	}
+	if r := cmp.Diff(recorder.Bytes(), data); r != "" {
+		t.Error(r)
+	}
+}
@@ -0,0 +1,22 @@
+package crypto
+
+import (
+	"v2ray.com/core/common/crypto/internal"
+)
+
+// Blake3Sum256 returns the 256-bit BLAKE3 hash of data.
+func Blake3Sum256(data []byte) [32]byte {
+	h := internal.NewBlake3()
+	h.Write(data)
+	var sum [32]byte
+	h.Sum(sum[:])
+	return sum
+}
+
+// Blake3DeriveKey fills out with the key derived from material by BLAKE3 in the key derivation mode. The context
+// string should be hardcoded, globally unique, and application-specific.
+func Blake3DeriveKey(out []byte, context string, material []byte) {
+	h := internal.NewBlake3DeriveKey(context)
+	h.Write(material)
+	h.Sum(out)
+}
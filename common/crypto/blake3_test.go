@@ -0,0 +1,45 @@
+package crypto_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	. "v2ray.com/core/common/crypto"
+)
+
+// Cases are from the official test vectors, whose input is the repeating sequence of 0 to 250.
+func blake3Input(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestBlake3Sum256(t *testing.T) {
+	cases := []struct {
+		input []byte
+		hash  string
+	}{
+		{blake3Input(0), "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{blake3Input(1), "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{blake3Input(1024), "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{blake3Input(1025), "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{blake3Input(2048), "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+		{[]byte("abc"), "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+	}
+	for _, c := range cases {
+		sum := Blake3Sum256(c.input)
+		if r := hex.EncodeToString(sum[:]); r != c.hash {
+			t.Error("hash of ", len(c.input), " bytes: ", r)
+		}
+	}
+}
+
+func TestBlake3DeriveKey(t *testing.T) {
+	out := make([]byte, 32)
+	Blake3DeriveKey(out, "BLAKE3 2019-12-27 16:29:52 test vectors context", nil)
+	if r := hex.EncodeToString(out); r != "2cc39783c223154fea8dfb7c1b1660f2ac2dcbd1c1de8277b0b0dd39b7e50d7d" {
+		t.Error("derived key: ", r)
+	}
+}
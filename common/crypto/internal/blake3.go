@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// An implementation of BLAKE3, following the reference implementation at https://github.com/BLAKE3-team/BLAKE3.
+
+const (
+	blake3BlockLen = 64
+	blake3ChunkLen = 1024
+
+	blake3ChunkStart        = 1 << 0
+	blake3ChunkEnd          = 1 << 1
+	blake3Parent            = 1 << 2
+	blake3Root              = 1 << 3
+	blake3DeriveKeyContext  = 1 << 5
+	blake3DeriveKeyMaterial = 1 << 6
+)
+
+var blake3IV = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A, 0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+var blake3MsgPermutation = [16]int{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8}
+
+func blake3G(state *[16]uint32, a, b, c, d int, mx, my uint32) {
+	state[a] = state[a] + state[b] + mx
+	state[d] = bits.RotateLeft32(state[d]^state[a], -16)
+	state[c] = state[c] + state[d]
+	state[b] = bits.RotateLeft32(state[b]^state[c], -12)
+	state[a] = state[a] + state[b] + my
+	state[d] = bits.RotateLeft32(state[d]^state[a], -8)
+	state[c] = state[c] + state[d]
+	state[b] = bits.RotateLeft32(state[b]^state[c], -7)
+}
+
+func blake3Round(state *[16]uint32, m *[16]uint32) {
+	// Mix the columns.
+	blake3G(state, 0, 4, 8, 12, m[0], m[1])
+	blake3G(state, 1, 5, 9, 13, m[2], m[3])
+	blake3G(state, 2, 6, 10, 14, m[4], m[5])
+	blake3G(state, 3, 7, 11, 15, m[6], m[7])
+	// Mix the diagonals.
+	blake3G(state, 0, 5, 10, 15, m[8], m[9])
+	blake3G(state, 1, 6, 11, 12, m[10], m[11])
+	blake3G(state, 2, 7, 8, 13, m[12], m[13])
+	blake3G(state, 3, 4, 9, 14, m[14], m[15])
+}
+
+func blake3Compress(cv *[8]uint32, block *[16]uint32, counter uint64, blockLen uint32, flags uint32) [16]uint32 {
+	state := [16]uint32{
+		cv[0], cv[1], cv[2], cv[3], cv[4], cv[5], cv[6], cv[7],
+		blake3IV[0], blake3IV[1], blake3IV[2], blake3IV[3],
+		uint32(counter), uint32(counter >> 32), blockLen, flags,
+	}
+	m := *block
+	for i := 0; i < 7; i++ {
+		blake3Round(&state, &m)
+		if i < 6 {
+			var permuted [16]uint32
+			for j := range permuted {
+				permuted[j] = m[blake3MsgPermutation[j]]
+			}
+			m = permuted
+		}
+	}
+	for i := 0; i < 8; i++ {
+		state[i] ^= state[i+8]
+		state[i+8] ^= cv[i]
+	}
+	return state
+}
+
+func blake3Words(b []byte) [16]uint32 {
+	var block [blake3BlockLen]byte
+	copy(block[:], b)
+	var words [16]uint32
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(block[i*4:])
+	}
+	return words
+}
+
+func blake3First8(words [16]uint32) [8]uint32 {
+	var cv [8]uint32
+	copy(cv[:], words[:8])
+	return cv
+}
+
+// blake3Output is the state just before the last compression of a chunk or a parent node.
+type blake3Output struct {
+	inputCV  [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+func (o *blake3Output) chainingValue() [8]uint32 {
+	return blake3First8(blake3Compress(&o.inputCV, &o.block, o.counter, o.blockLen, o.flags))
+}
+
+func (o *blake3Output) rootBytes(out []byte) {
+	var counter uint64
+	for len(out) > 0 {
+		words := blake3Compress(&o.inputCV, &o.block, counter, o.blockLen, o.flags|blake3Root)
+		var block [blake3BlockLen]byte
+		for i, w := range words {
+			binary.LittleEndian.PutUint32(block[i*4:], w)
+		}
+		out = out[copy(out, block[:]):]
+		counter++
+	}
+}
+
+type blake3ChunkState struct {
+	cv               [8]uint32
+	counter          uint64
+	block            [blake3BlockLen]byte
+	blockLen         int
+	blocksCompressed int
+	flags            uint32
+}
+
+func newBlake3ChunkState(key [8]uint32, counter uint64, flags uint32) *blake3ChunkState {
+	return &blake3ChunkState{
+		cv:      key,
+		counter: counter,
+		flags:   flags,
+	}
+}
+
+func (s *blake3ChunkState) len() int {
+	return blake3BlockLen*s.blocksCompressed + s.blockLen
+}
+
+func (s *blake3ChunkState) startFlag() uint32 {
+	if s.blocksCompressed == 0 {
+		return blake3ChunkStart
+	}
+	return 0
+}
+
+func (s *blake3ChunkState) update(input []byte) {
+	for len(input) > 0 {
+		// The last block of a chunk is compressed in output(), so a full block is only compressed when more input
+		// follows.
+		if s.blockLen == blake3BlockLen {
+			words := blake3Words(s.block[:])
+			s.cv = blake3First8(blake3Compress(&s.cv, &words, s.counter, blake3BlockLen, s.flags|s.startFlag()))
+			s.blocksCompressed++
+			s.block = [blake3BlockLen]byte{}
+			s.blockLen = 0
+		}
+		n := copy(s.block[s.blockLen:], input)
+		s.blockLen += n
+		input = input[n:]
+	}
+}
+
+func (s *blake3ChunkState) output() *blake3Output {
+	return &blake3Output{
+		inputCV:  s.cv,
+		block:    blake3Words(s.block[:s.blockLen]),
+		counter:  s.counter,
+		blockLen: uint32(s.blockLen),
+		flags:    s.flags | s.startFlag() | blake3ChunkEnd,
+	}
+}
+
+func blake3ParentOutput(left, right [8]uint32, key [8]uint32, flags uint32) *blake3Output {
+	o := &blake3Output{
+		inputCV:  key,
+		blockLen: blake3BlockLen,
+		flags:    blake3Parent | flags,
+	}
+	copy(o.block[:8], left[:])
+	copy(o.block[8:], right[:])
+	return o
+}
+
+// Blake3Hasher is an incremental BLAKE3 hasher.
+type Blake3Hasher struct {
+	chunk   *blake3ChunkState
+	key     [8]uint32
+	cvStack [][8]uint32
+	flags   uint32
+}
+
+func newBlake3Hasher(key [8]uint32, flags uint32) *Blake3Hasher {
+	return &Blake3Hasher{
+		chunk: newBlake3ChunkState(key, 0, flags),
+		key:   key,
+		flags: flags,
+	}
+}
+
+// NewBlake3 returns a hasher in the default hash mode.
+func NewBlake3() *Blake3Hasher {
+	return newBlake3Hasher(blake3IV, 0)
+}
+
+// NewBlake3DeriveKey returns a hasher in the key derivation mode. Key material is written to the hasher.
+func NewBlake3DeriveKey(context string) *Blake3Hasher {
+	h := newBlake3Hasher(blake3IV, blake3DeriveKeyContext)
+	h.Write([]byte(context))
+	var contextKey [32]byte
+	h.Sum(contextKey[:])
+	return newBlake3Hasher(blake3First8(blake3Words(contextKey[:])), blake3DeriveKeyMaterial)
+}
+
+func (h *Blake3Hasher) addChunkChainingValue(cv [8]uint32, totalChunks uint64) {
+	// Each trailing zero bit of the total number of chunks completes a subtree, which is merged into its parent.
+	for totalChunks&1 == 0 {
+		top := h.cvStack[len(h.cvStack)-1]
+		h.cvStack = h.cvStack[:len(h.cvStack)-1]
+		cv = blake3ParentOutput(top, cv, h.key, h.flags).chainingValue()
+		totalChunks >>= 1
+	}
+	h.cvStack = append(h.cvStack, cv)
+}
+
+// Write implements io.Writer. It never fails.
+func (h *Blake3Hasher) Write(input []byte) (int, error) {
+	n := len(input)
+	for len(input) > 0 {
+		if h.chunk.len() == blake3ChunkLen {
+			cv := h.chunk.output().chainingValue()
+			totalChunks := h.chunk.counter + 1
+			h.addChunkChainingValue(cv, totalChunks)
+			h.chunk = newBlake3ChunkState(h.key, totalChunks, h.flags)
+		}
+		want := blake3ChunkLen - h.chunk.len()
+		if want > len(input) {
+			want = len(input)
+		}
+		h.chunk.update(input[:want])
+		input = input[want:]
+	}
+	return n, nil
+}
+
+// Sum fills out with the output of the hasher, of any length. It doesn't change the state of the hasher.
+func (h *Blake3Hasher) Sum(out []byte) {
+	output := h.chunk.output()
+	for i := len(h.cvStack) - 1; i >= 0; i-- {
+		output = blake3ParentOutput(h.cvStack[i], output.chainingValue(), h.key, h.flags)
+	}
+	output.rootBytes(out)
+}
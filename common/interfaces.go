@@ -1,6 +1,10 @@
 package common
 
-import "v2ray.com/core/common/errors"
+import (
+	"context"
+
+	"v2ray.com/core/common/errors"
+)
 
 // Closable is the interface for objects that can release its resources.
 //
@@ -38,6 +42,24 @@ func Interrupt(obj interface{}) error {
 	return Close(obj)
 }
 
+// Drainable is the interface for objects that can stop taking new work while letting existing work finish.
+//
+// v2ray:api:beta
+type Drainable interface {
+	// Drain stops accepting new work, and blocks until all existing work finishes or ctx is done.
+	Drain(ctx context.Context) error
+}
+
+// Drain calls Drain() if the object implements Drainable interface. It does nothing otherwise.
+//
+// v2ray:api:beta
+func Drain(ctx context.Context, obj interface{}) error {
+	if d, ok := obj.(Drainable); ok {
+		return d.Drain(ctx)
+	}
+	return nil
+}
+
 // Runnable is the interface for objects that can start to work and stop on demand.
 type Runnable interface {
 	// Start starts the runnable object. Upon the method returning nil, the object begins to function properly.
@@ -12,6 +12,7 @@ import (
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/signal/done"
 	"v2ray.com/core/features/routing"
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/pipe"
@@ -19,11 +20,14 @@ import (
 
 type Server struct {
 	dispatcher routing.Dispatcher
+	draining   *done.Instance
 }
 
 // NewServer creates a new mux.Server.
 func NewServer(ctx context.Context) *Server {
-	s := &Server{}
+	s := &Server{
+		draining: done.New(),
+	}
 	core.RequireFeatures(ctx, func(d routing.Dispatcher) {
 		s.dispatcher = d
 	})
@@ -45,10 +49,10 @@ func (s *Server) Dispatch(ctx context.Context, dest net.Destination) (*transport
 	uplinkReader, uplinkWriter := pipe.New(opts...)
 	downlinkReader, downlinkWriter := pipe.New(opts...)
 
-	_, err := NewServerWorker(ctx, s.dispatcher, &transport.Link{
+	_, err := newServerWorker(ctx, s.dispatcher, &transport.Link{
 		Reader: uplinkReader,
 		Writer: downlinkWriter,
-	})
+	}, s.draining)
 	if err != nil {
 		return nil, err
 	}
@@ -66,17 +70,29 @@ func (s *Server) Close() error {
 	return nil
 }
 
+// Drain implements common.Drainable. After Drain, new sub-connections on existing Mux connections are rejected,
+// so that the peer opens them on a new connection. Existing sub-connections are not affected.
+func (s *Server) Drain(ctx context.Context) error {
+	return s.draining.Close()
+}
+
 type ServerWorker struct {
 	dispatcher     routing.Dispatcher
 	link           *transport.Link
 	sessionManager *SessionManager
+	draining       *done.Instance
 }
 
 func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.Link) (*ServerWorker, error) {
+	return newServerWorker(ctx, d, link, nil)
+}
+
+func newServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.Link, draining *done.Instance) (*ServerWorker, error) {
 	worker := &ServerWorker{
 		dispatcher:     d,
 		link:           link,
 		sessionManager: NewSessionManager(),
+		draining:       draining,
 	}
 	go worker.run(ctx)
 	return worker, nil
@@ -109,6 +125,17 @@ func (w *ServerWorker) handleStatusKeepAlive(meta *FrameMetadata, reader *buf.Bu
 }
 
 func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata, reader *buf.BufferedReader) error {
+	if w.draining != nil && w.draining.Done() {
+		newError("rejecting request for ", meta.Target, " as server is draining").WriteToLog(session.ExportIDToError(ctx))
+		closingWriter := NewResponseWriter(meta.SessionID, w.link.Writer, protocol.TransferTypeStream)
+		closingWriter.hasError = true
+		closingWriter.Close()
+		if meta.Option.Has(OptionData) {
+			return buf.Copy(NewStreamReader(reader), buf.Discard)
+		}
+		return nil
+	}
+
 	newError("received request for ", meta.Target).WriteToLog(session.ExportIDToError(ctx))
 	{
 		msg := &log.AccessMessage{
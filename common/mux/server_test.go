@@ -0,0 +1,58 @@
+package mux_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	. "v2ray.com/core/common/mux"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/features/routing"
+	"v2ray.com/core/transport"
+)
+
+type rejectDispatcher struct {
+	t *testing.T
+}
+
+func (*rejectDispatcher) Type() interface{} { return routing.DispatcherType() }
+func (*rejectDispatcher) Start() error      { return nil }
+func (*rejectDispatcher) Close() error      { return nil }
+
+func (d *rejectDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	d.t.Error("unexpected dispatch to ", dest)
+	return nil, common.ErrNoClue
+}
+
+func TestServerDrainRejectsNewSession(t *testing.T) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	common.Must(instance.AddFeature(&rejectDispatcher{t: t}))
+	ctx := context.WithValue(context.Background(), core.V2rayKey(1), instance)
+
+	server := NewServer(ctx)
+	link, err := server.Dispatch(ctx, net.TCPDestination(net.DomainAddress("v1.mux.cool"), 9527))
+	common.Must(err)
+	common.Must(server.Drain(ctx))
+
+	dest := net.TCPDestination(net.DomainAddress("v2ray.com"), 80)
+	writer := NewWriter(1, dest, link.Writer, protocol.TransferTypeStream)
+	b := buf.New()
+	b.WriteString("abcd")
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+
+	var meta FrameMetadata
+	common.Must(meta.Unmarshal(&buf.BufferedReader{Reader: link.Reader}))
+	if r := cmp.Diff(meta, FrameMetadata{
+		SessionID:     1,
+		SessionStatus: SessionStatusEnd,
+		Option:        OptionError,
+	}); r != "" {
+		t.Error("metadata: ", r)
+	}
+}
@@ -17,13 +17,19 @@ var ListenUnix = net.ListenUnix
 
 var LookupIP = net.LookupIP
 
+var InterfaceAddrs = net.InterfaceAddrs
+
 var FileConn = net.FileConn
+var FileListener = net.FileListener
+var FilePacketConn = net.FilePacketConn
 
 // ParseIP is an alias of net.ParseIP
 var ParseIP = net.ParseIP
 
 var SplitHostPort = net.SplitHostPort
 
+var ParseCIDR = net.ParseCIDR
+
 var CIDRMask = net.CIDRMask
 
 type Addr = net.Addr
@@ -39,6 +45,8 @@ type UDPConn = net.UDPConn
 type UnixAddr = net.UnixAddr
 type UnixConn = net.UnixConn
 
+type IPAddr = net.IPAddr
+
 // IP is an alias for net.IP.
 type IP = net.IP
 type IPMask = net.IPMask
@@ -59,3 +67,5 @@ var ResolveUnixAddr = net.ResolveUnixAddr
 var ResolveUDPAddr = net.ResolveUDPAddr
 
 type Resolver = net.Resolver
+
+var DefaultResolver = net.DefaultResolver
@@ -63,3 +63,27 @@ func TestGetAssetLocation(t *testing.T) {
 		}
 	}
 }
+
+func TestRaiseFileLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no file limit on Windows")
+	}
+
+	before := FileLimit()
+	if before == 0 {
+		t.Fatal("unknown file limit")
+	}
+
+	// The limit is never lowered.
+	limit, err := RaiseFileLimit(1)
+	common.Must(err)
+	if limit != before {
+		t.Error("file limit changed from ", before, " to ", limit)
+	}
+
+	limit, err = RaiseFileLimit(0)
+	common.Must(err)
+	if limit < before || limit != FileLimit() {
+		t.Error("unexpected file limit ", limit, ", before ", before)
+	}
+}
@@ -0,0 +1,6 @@
+// +build dragonfly freebsd
+
+package platform
+
+// rlim is the type of values in syscall.Rlimit.
+type rlim = int64
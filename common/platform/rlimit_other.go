@@ -0,0 +1,13 @@
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package platform
+
+// RaiseFileLimit does nothing on systems without a limit of open files.
+func RaiseFileLimit(target uint64) (uint64, error) {
+	return 0, nil
+}
+
+// FileLimit returns 0, as the limit of open files is unknown on this system.
+func FileLimit() uint64 {
+	return 0
+}
@@ -0,0 +1,6 @@
+// +build aix darwin linux netbsd openbsd solaris
+
+package platform
+
+// rlim is the type of values in syscall.Rlimit.
+type rlim = uint64
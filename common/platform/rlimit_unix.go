@@ -0,0 +1,47 @@
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package platform
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// openMax is the per-process limit of open files on macOS, which is lower than an unlimited hard limit.
+const openMax = 10240
+
+// RaiseFileLimit raises the soft limit of open files to target, or to the hard limit if target is 0 or above
+// the hard limit. The limit is never lowered. It returns the resulting soft limit.
+func RaiseFileLimit(target uint64) (uint64, error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, err
+	}
+	max := uint64(limit.Max)
+	if target == 0 || target > max {
+		target = max
+	}
+	if target <= uint64(limit.Cur) {
+		return uint64(limit.Cur), nil
+	}
+
+	limit.Cur = rlim(target)
+	err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit)
+	if err != nil && runtime.GOOS == "darwin" && target > openMax {
+		limit.Cur = rlim(openMax)
+		err = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit)
+	}
+	if err != nil {
+		return FileLimit(), err
+	}
+	return FileLimit(), nil
+}
+
+// FileLimit returns the soft limit of open files, or 0 if it is unknown.
+func FileLimit() uint64 {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0
+	}
+	return uint64(limit.Cur)
+}
@@ -0,0 +1,331 @@
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/protocol/tls"
+)
+
+type SniffHeader struct {
+	domain string
+}
+
+func (h *SniffHeader) Protocol() string {
+	return "quic"
+}
+
+func (h *SniffHeader) Domain() string {
+	return h.domain
+}
+
+var errNotQUIC = errors.New("not QUIC initial packet")
+var errNotClientHello = errors.New("not client hello")
+
+// version holds the parameters to protect Initial packets of a QUIC version.
+type version struct {
+	salt       []byte
+	keyLabel   string
+	ivLabel    string
+	hpLabel    string
+	packetType byte
+}
+
+// https://www.rfc-editor.org/rfc/rfc9001.html#section-5.2
+var versionV1 = &version{
+	salt:       []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a},
+	keyLabel:   "quic key",
+	ivLabel:    "quic iv",
+	hpLabel:    "quic hp",
+	packetType: 0,
+}
+
+// https://www.rfc-editor.org/rfc/rfc9369.html#section-3.3
+var versionV2 = &version{
+	salt:       []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9},
+	keyLabel:   "quicv2 key",
+	ivLabel:    "quicv2 iv",
+	hpLabel:    "quicv2 hp",
+	packetType: 1,
+}
+
+// Draft 29 is still sent by some clients.
+var versionDraft29 = &version{
+	salt:       []byte{0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97, 0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99},
+	keyLabel:   "quic key",
+	ivLabel:    "quic iv",
+	hpLabel:    "quic hp",
+	packetType: 0,
+}
+
+var versions = map[uint32]*version{
+	0x00000001: versionV1,
+	0x6b3343cf: versionV2,
+	0xff00001d: versionDraft29,
+}
+
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	label = "tls13 " + label
+	info := make([]byte, 0, 4+len(label))
+	info = append(info, byte(length>>8), byte(length), byte(len(label)))
+	info = append(info, label...)
+	info = append(info, 0)
+
+	out := make([]byte, length)
+	common.Must2(io.ReadFull(hkdf.Expand(sha256.New, secret, info), out))
+	return out
+}
+
+// initialKeys derives the key, IV and header protection key of the client Initial packets.
+func (v *version) initialKeys(dcid []byte) (key []byte, iv []byte, hp []byte) {
+	initialSecret := hkdf.Extract(sha256.New, dcid, v.salt)
+	clientSecret := hkdfExpandLabel(initialSecret, "client in", sha256.Size)
+	return hkdfExpandLabel(clientSecret, v.keyLabel, 16), hkdfExpandLabel(clientSecret, v.ivLabel, 12), hkdfExpandLabel(clientSecret, v.hpLabel, 16)
+}
+
+// readVarInt reads a variable-length integer, and returns the number of bytes it takes. It returns 0 if b is too short.
+func readVarInt(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0
+	}
+	v := uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+// cryptoStream reassembles the data of CRYPTO frames, which may arrive out of order.
+type cryptoStream struct {
+	data   []byte
+	filled []bool
+}
+
+func (s *cryptoStream) write(offset uint64, data []byte) error {
+	end := offset + uint64(len(data))
+	if end > 64*1024 {
+		return errNotClientHello
+	}
+	if int(end) > len(s.data) {
+		s.data = append(s.data, make([]byte, int(end)-len(s.data))...)
+		s.filled = append(s.filled, make([]bool, int(end)-len(s.filled))...)
+	}
+	copy(s.data[offset:end], data)
+	for i := offset; i < end; i++ {
+		s.filled[i] = true
+	}
+	return nil
+}
+
+// contiguous returns the data received from offset 0 without gaps.
+func (s *cryptoStream) contiguous() []byte {
+	for i, filled := range s.filled {
+		if !filled {
+			return s.data[:i]
+		}
+	}
+	return s.data
+}
+
+// decryptInitial removes the protection of the Initial packet at the beginning of b. It returns the frames in the
+// packet, and the rest of b after the packet.
+func decryptInitial(b []byte) ([]byte, []byte, error) {
+	if len(b) < 7 {
+		return nil, nil, common.ErrNoClue
+	}
+	// Long header with fixed bit.
+	if b[0]&0xc0 != 0xc0 {
+		return nil, nil, errNotQUIC
+	}
+	v, found := versions[binary.BigEndian.Uint32(b[1:5])]
+	if !found {
+		return nil, nil, errNotQUIC
+	}
+
+	offset := 5
+	dcidLen := int(b[offset])
+	if dcidLen > 20 {
+		return nil, nil, errNotQUIC
+	}
+	offset++
+	if len(b) < offset+dcidLen+1 {
+		return nil, nil, common.ErrNoClue
+	}
+	dcid := b[offset : offset+dcidLen]
+	offset += dcidLen
+	scidLen := int(b[offset])
+	if scidLen > 20 {
+		return nil, nil, errNotQUIC
+	}
+	offset += 1 + scidLen
+
+	if (b[0]>>4)&0x03 != v.packetType {
+		// Coalesced 0-RTT packets carry no ClientHello. Skip them by their length.
+		if len(b) < offset {
+			return nil, nil, common.ErrNoClue
+		}
+		length, n := readVarInt(b[offset:])
+		if n == 0 || uint64(len(b)) < uint64(offset+n)+length {
+			return nil, nil, common.ErrNoClue
+		}
+		return nil, b[offset+n+int(length):], nil
+	}
+
+	if len(b) < offset {
+		return nil, nil, common.ErrNoClue
+	}
+	tokenLen, n := readVarInt(b[offset:])
+	if n == 0 || uint64(len(b)) < uint64(offset+n)+tokenLen {
+		return nil, nil, common.ErrNoClue
+	}
+	offset += n + int(tokenLen)
+	length, n := readVarInt(b[offset:])
+	if n == 0 {
+		return nil, nil, common.ErrNoClue
+	}
+	offset += n
+	if uint64(len(b)) < uint64(offset)+length {
+		return nil, nil, common.ErrNoClue
+	}
+	// The packet number takes 1 to 4 bytes, and the header protection samples 16 bytes after 4 bytes of it.
+	if length < 20 {
+		return nil, nil, errNotQUIC
+	}
+	pnOffset := offset
+	end := pnOffset + int(length)
+
+	key, iv, hpKey := v.initialKeys(dcid)
+	hp, err := aes.NewCipher(hpKey)
+	common.Must(err)
+	mask := make([]byte, aes.BlockSize)
+	hp.Encrypt(mask, b[pnOffset+4:pnOffset+4+16])
+
+	header := append([]byte(nil), b[:pnOffset+4]...)
+	header[0] ^= mask[0] & 0x0f
+	pnLen := int(header[0]&0x03) + 1
+	header = header[:pnOffset+pnLen]
+	var packetNumber uint64
+	for i := 0; i < pnLen; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		packetNumber = packetNumber<<8 | uint64(header[pnOffset+i])
+	}
+
+	block, err := aes.NewCipher(key)
+	common.Must(err)
+	aead, err := cipher.NewGCM(block)
+	common.Must(err)
+	nonce := iv
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(packetNumber >> (8 * i))
+	}
+	frames, err := aead.Open(nil, nonce, b[pnOffset+pnLen:end], header)
+	if err != nil {
+		return nil, nil, errNotQUIC
+	}
+	return frames, b[end:], nil
+}
+
+// readFrames collects the CRYPTO frames into the stream.
+func readFrames(frames []byte, stream *cryptoStream) error {
+	for len(frames) > 0 {
+		frameType, n := readVarInt(frames)
+		if n == 0 {
+			return errNotQUIC
+		}
+		frames = frames[n:]
+
+		switch frameType {
+		case 0x00, 0x01: // PADDING, PING
+		case 0x02, 0x03: // ACK
+			fields := 4
+			for i := 0; i < fields; i++ {
+				v, n := readVarInt(frames)
+				if n == 0 {
+					return errNotQUIC
+				}
+				frames = frames[n:]
+				if i == 2 {
+					// Each ACK range has a gap and a length.
+					fields += 2 * int(v)
+				}
+			}
+			if frameType == 0x03 {
+				// ECN counts.
+				for i := 0; i < 3; i++ {
+					_, n := readVarInt(frames)
+					if n == 0 {
+						return errNotQUIC
+					}
+					frames = frames[n:]
+				}
+			}
+		case 0x06: // CRYPTO
+			offset, n := readVarInt(frames)
+			if n == 0 {
+				return errNotQUIC
+			}
+			frames = frames[n:]
+			length, n := readVarInt(frames)
+			if n == 0 || uint64(len(frames)-n) < length {
+				return errNotQUIC
+			}
+			frames = frames[n:]
+			if err := stream.write(offset, frames[:length]); err != nil {
+				return err
+			}
+			frames = frames[length:]
+		default:
+			return errNotClientHello
+		}
+	}
+	return nil
+}
+
+// SniffQUIC returns the server name in the ClientHello of QUIC Initial packets. The ClientHello may span several
+// packets, which are concatenated in b.
+func SniffQUIC(b []byte) (*SniffHeader, error) {
+	stream := new(cryptoStream)
+	for len(b) > 0 {
+		frames, rest, err := decryptInitial(b)
+		if err != nil && len(stream.data) > 0 {
+			// The rest of the datagrams is truncated or not an Initial packet.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := readFrames(frames, stream); err != nil {
+			return nil, err
+		}
+		b = rest
+	}
+
+	data := stream.contiguous()
+	if len(data) < 4 {
+		return nil, common.ErrNoClue
+	}
+	if data[0] != 0x01 /* client_hello */ {
+		return nil, errNotClientHello
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data) < 4+length {
+		return nil, common.ErrNoClue
+	}
+
+	h := &tls.SniffHeader{}
+	if err := tls.ReadClientHello(data[:4+length], h); err != nil {
+		return nil, err
+	}
+	return &SniffHeader{domain: h.Domain()}, nil
+}
@@ -0,0 +1,139 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	gotls "crypto/tls"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"v2ray.com/core/common"
+)
+
+func TestInitialKeys(t *testing.T) {
+	// https://www.rfc-editor.org/rfc/rfc9001.html#appendix-A.1
+	// https://www.rfc-editor.org/rfc/rfc9369.html#appendix-A.1
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	testCases := []struct {
+		version *version
+		key     string
+		iv      string
+		hp      string
+	}{
+		{versionV1, "1f369613dd76d5467730efcbe3b1a22d", "fa044b2f42a3fd3b46fb255c", "9f50449e04a0e810283a1e9933adedd2"},
+		{versionV2, "8b1a0bc121284290a29e0971b5cd045d", "91f73e2351d8fa91660e909f", "45b95e15235d6f45a6b19cbcb0294ba9"},
+	}
+	for _, testCase := range testCases {
+		key, iv, hp := testCase.version.initialKeys(dcid)
+		if actual := hex.EncodeToString(key); actual != testCase.key {
+			t.Error("expect key ", testCase.key, ", but got ", actual)
+		}
+		if actual := hex.EncodeToString(iv); actual != testCase.iv {
+			t.Error("expect iv ", testCase.iv, ", but got ", actual)
+		}
+		if actual := hex.EncodeToString(hp); actual != testCase.hp {
+			t.Error("expect hp ", testCase.hp, ", but got ", actual)
+		}
+	}
+}
+
+// clientHello returns a ClientHello handshake message generated by crypto/tls, with its handshake header.
+func clientHello(t *testing.T, serverName string) []byte {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		gotls.Client(client, &gotls.Config{ServerName: serverName}).Handshake()
+		client.Close()
+	}()
+
+	header := make([]byte, 5)
+	if _, err := server.Read(header); err != nil {
+		t.Fatal(err)
+	}
+	message := make([]byte, int(header[3])<<8|int(header[4]))
+	for n := 0; n < len(message); {
+		m, err := server.Read(message[n:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += m
+	}
+	return message
+}
+
+func cryptoFrame(offset int, data []byte) []byte {
+	frame := []byte{0x06, 0x80 | byte(offset>>24), byte(offset >> 16), byte(offset >> 8), byte(offset), 0x40 | byte(len(data)>>8), byte(len(data))}
+	return append(frame, data...)
+}
+
+// protectInitial builds a client Initial packet with the frames, as a QUIC client does.
+func protectInitial(v *version, versionNumber uint32, dcid []byte, packetNumber byte, frames []byte) []byte {
+	key, iv, hpKey := v.initialKeys(dcid)
+	length := 1 + len(frames) + 16
+	header := []byte{0xc0 | v.packetType<<4, byte(versionNumber >> 24), byte(versionNumber >> 16), byte(versionNumber >> 8), byte(versionNumber), byte(len(dcid))}
+	header = append(header, dcid...)
+	header = append(header, 0, 0, 0x40|byte(length>>8), byte(length), packetNumber)
+	pnOffset := len(header) - 1
+
+	block, err := aes.NewCipher(key)
+	common.Must(err)
+	aead, err := cipher.NewGCM(block)
+	common.Must(err)
+	iv[len(iv)-1] ^= packetNumber
+	packet := aead.Seal(append([]byte(nil), header...), iv, frames, header)
+
+	hp, err := aes.NewCipher(hpKey)
+	common.Must(err)
+	mask := make([]byte, aes.BlockSize)
+	hp.Encrypt(mask, packet[pnOffset+4:pnOffset+20])
+	packet[0] ^= mask[0] & 0x0f
+	packet[pnOffset] ^= mask[1]
+	return packet
+}
+
+func TestSniffQUIC(t *testing.T) {
+	hello := clientHello(t, "v2fly.org")
+	dcid := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	padding := make([]byte, 64)
+
+	oneInitial := protectInitial(versionV1, 1, dcid, 0, append(cryptoFrame(0, hello), padding...))
+	// Crypto data in reverse order, split into two packets.
+	half := len(hello) / 2
+	twoInitials := append(
+		protectInitial(versionV2, 0x6b3343cf, dcid, 0, cryptoFrame(half, hello[half:])),
+		protectInitial(versionV2, 0x6b3343cf, dcid, 1, cryptoFrame(0, hello[:half]))...)
+	draft := protectInitial(versionDraft29, 0xff00001d, dcid, 0, append([]byte{0x01}, cryptoFrame(0, hello)...))
+
+	testCases := []struct {
+		input  []byte
+		domain string
+		err    error
+	}{
+		{input: oneInitial, domain: "v2fly.org"},
+		{input: twoInitials, domain: "v2fly.org"},
+		{input: draft, domain: "v2fly.org"},
+		{input: oneInitial[:100], err: common.ErrNoClue},
+		{input: twoInitials[:len(twoInitials)-10], err: common.ErrNoClue},
+		{input: []byte{0x40, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, err: errNotQUIC},
+		{input: append([]byte{0xc0, 0x0a, 0x0a, 0x0a, 0x0a}, oneInitial[5:]...), err: errNotQUIC},
+	}
+	for i, testCase := range testCases {
+		header, err := SniffQUIC(testCase.input)
+		if err != testCase.err {
+			t.Error("case ", i, ": expect error ", testCase.err, ", but got ", err)
+			continue
+		}
+		if err == nil && header.Domain() != testCase.domain {
+			t.Error("case ", i, ": expect domain ", testCase.domain, ", but got ", header.Domain())
+		}
+	}
+
+	// The input must not be modified.
+	copied := append([]byte(nil), oneInitial...)
+	common.Must2(SniffQUIC(oneInitial))
+	if !bytes.Equal(copied, oneInitial) {
+		t.Error("input is modified")
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/pem"
+	"io/ioutil"
 	"math/big"
+	"net"
 	"time"
 
 	"v2ray.com/core/common"
@@ -39,9 +41,22 @@ func ParseCertificate(certPEM []byte, keyPEM []byte) (*Certificate, error) {
 	}, nil
 }
 
+// Load reads a certificate and its private key from PEM files.
+func Load(certFile, keyFile string) (*Certificate, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, newError("failed to read certificate file ", certFile).Base(err)
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, newError("failed to read key file ", keyFile).Base(err)
+	}
+	return ParseCertificate(certPEM, keyPEM)
+}
+
 func (c *Certificate) ToPEM() ([]byte, []byte) {
 	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate}),
-		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: c.PrivateKey})
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: c.PrivateKey})
 }
 
 type Option func(*x509.Certificate)
@@ -70,6 +85,12 @@ func DNSNames(names ...string) Option {
 	}
 }
 
+func IPAddresses(ips ...net.IP) Option {
+	return func(c *x509.Certificate) {
+		c.IPAddresses = ips
+	}
+}
+
 func CommonName(name string) Option {
 	return func(c *x509.Certificate) {
 		c.Subject.CommonName = name
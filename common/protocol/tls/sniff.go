@@ -121,24 +121,48 @@ func ReadClientHello(data []byte, h *SniffHeader) error {
 	return errNotTLS
 }
 
+// maxClientHelloSize is the largest ClientHello that SniffTLS reassembles.
+const maxClientHelloSize = 64 * 1024
+
+// SniffTLS returns the server name in the ClientHello at the beginning of b. The ClientHello may be fragmented into
+// several handshake records, which are reassembled. It returns common.ErrNoClue if b ends before the ClientHello.
 func SniffTLS(b []byte) (*SniffHeader, error) {
-	if len(b) < 5 {
-		return nil, common.ErrNoClue
-	}
+	var handshake []byte
+	for {
+		if len(b) < 5 {
+			return nil, common.ErrNoClue
+		}
+		if b[0] != 0x16 /* TLS Handshake */ {
+			return nil, errNotTLS
+		}
+		if !IsValidTLSVersion(b[1], b[2]) {
+			return nil, errNotTLS
+		}
+		recordLen := int(binary.BigEndian.Uint16(b[3:5]))
+		if 5+recordLen > len(b) {
+			return nil, common.ErrNoClue
+		}
+		handshake = append(handshake, b[5:5+recordLen]...)
+		b = b[5+recordLen:]
 
-	if b[0] != 0x16 /* TLS Handshake */ {
-		return nil, errNotTLS
-	}
-	if !IsValidTLSVersion(b[1], b[2]) {
-		return nil, errNotTLS
-	}
-	headerLen := int(binary.BigEndian.Uint16(b[3:5]))
-	if 5+headerLen > len(b) {
-		return nil, common.ErrNoClue
+		if len(handshake) < 4 {
+			continue
+		}
+		if handshake[0] != 0x01 /* client_hello */ {
+			return nil, errNotClientHello
+		}
+		messageLen := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+		if messageLen > maxClientHelloSize {
+			return nil, errNotClientHello
+		}
+		if len(handshake) >= 4+messageLen {
+			handshake = handshake[:4+messageLen]
+			break
+		}
 	}
 
 	h := &SniffHeader{}
-	err := ReadClientHello(b[5:5+headerLen], h)
+	err := ReadClientHello(handshake, h)
 	if err == nil {
 		return h, nil
 	}
@@ -1,8 +1,12 @@
 package tls_test
 
 import (
+	"crypto/tls"
+	"io"
+	"net"
 	"testing"
 
+	"v2ray.com/core/common"
 	. "v2ray.com/core/common/protocol/tls"
 )
 
@@ -159,3 +163,61 @@ func TestTLSHeaders(t *testing.T) {
 		}
 	}
 }
+
+// clientHello returns a ClientHello record generated by crypto/tls.
+func clientHello(t *testing.T, serverName string) []byte {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: serverName}).Handshake()
+		client.Close()
+	}()
+
+	record := make([]byte, 5)
+	if _, err := io.ReadFull(server, record); err != nil {
+		t.Fatal(err)
+	}
+	record = append(record, make([]byte, int(record[3])<<8|int(record[4]))...)
+	if _, err := io.ReadFull(server, record[5:]); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// fragment splits the handshake messages in a record into records of at most size bytes.
+func fragment(record []byte, size int) []byte {
+	var records []byte
+	for data := record[5:]; len(data) > 0; {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		records = append(records, record[0], record[1], record[2], byte(n>>8), byte(n))
+		records = append(records, data[:n]...)
+		data = data[n:]
+	}
+	return records
+}
+
+func TestTLSFragmentedClientHello(t *testing.T) {
+	record := clientHello(t, "v2fly.org")
+	for _, size := range []int{1, 3, 100, 1000} {
+		records := fragment(record, size)
+		header, err := SniffTLS(records)
+		if err != nil {
+			t.Error("size ", size, ": ", err)
+		} else if header.Domain() != "v2fly.org" {
+			t.Error("size ", size, ": expect domain v2fly.org, but got ", header.Domain())
+		}
+
+		if _, err := SniffTLS(records[:len(records)-1]); err != common.ErrNoClue {
+			t.Error("size ", size, ": expect no clue for incomplete ClientHello, but got ", err)
+		}
+	}
+
+	// Application data after a partial ClientHello.
+	records := append(fragment(record, 100)[:105], 0x17, 0x03, 0x03, 0x00, 0x01, 0x00)
+	if _, err := SniffTLS(records); err == nil || err == common.ErrNoClue {
+		t.Error("expect error for non-handshake record, but got ", err)
+	}
+}
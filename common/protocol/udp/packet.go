@@ -10,4 +10,6 @@ type Packet struct {
 	Payload *buf.Buffer
 	Source  net.Destination
 	Target  net.Destination
+	// ECN is the ECN codepoint in the IP header of a received packet, if ECN is enabled on the socket.
+	ECN byte
 }
@@ -0,0 +1,271 @@
+// Package record records the byte streams of inbound sessions that fail, so that they can be fed back through the
+// protocol parsers offline with "v2ctl replay".
+//
+// Each failing session is saved as a JSON file in the configured directory. It contains the raw bytes on the
+// connection, and the plaintext that proxies decrypt with local keys. Sessions that succeed are discarded.
+package record
+
+//go:generate errorgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+)
+
+// Direction of recorded bytes, from the view of the inbound.
+const (
+	Send    = "send"
+	Receive = "recv"
+)
+
+// Layers of recorded bytes.
+const (
+	// Raw is the layer of bytes on the connection.
+	Raw = "raw"
+	// Plain is the layer of payload decrypted or to be encrypted by the proxy.
+	Plain = "plain"
+)
+
+// DefaultMaxSize is the number of bytes recorded in a session, if not configured.
+const DefaultMaxSize = 64 * 1024
+
+// Chunk is a run of bytes in one direction and layer.
+type Chunk struct {
+	Direction string `json:"dir"`
+	Layer     string `json:"layer"`
+	Data      []byte `json:"data"`
+}
+
+// Session is the recording of an inbound session.
+type Session struct {
+	Time    time.Time `json:"time"`
+	Inbound string    `json:"inbound"`
+	Source  string    `json:"source,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	// Truncated is true if the session has more bytes than the limit of the Recorder.
+	Truncated bool     `json:"truncated,omitempty"`
+	Chunks    []*Chunk `json:"chunks"`
+
+	recorder *Recorder
+	access   sync.Mutex
+	size     int
+}
+
+// Recorder saves failing sessions into a directory.
+type Recorder struct {
+	directory string
+	maxSize   int
+}
+
+// savedSessions numbers the saved files, so that sessions that fail in the same second have different names.
+var savedSessions uint32
+
+// New creates a Recorder that saves sessions into the directory. At most maxSize bytes are recorded in each session,
+// or DefaultMaxSize if it is 0.
+func New(directory string, maxSize uint32) *Recorder {
+	r := &Recorder{
+		directory: directory,
+		maxSize:   int(maxSize),
+	}
+	if r.maxSize == 0 {
+		r.maxSize = DefaultMaxSize
+	}
+	return r
+}
+
+// NewSession starts recording a session from the source. It returns nil if the Recorder is nil.
+func (r *Recorder) NewSession(inbound string, source net.Destination) *Session {
+	if r == nil {
+		return nil
+	}
+	s := &Session{
+		Time:     time.Now(),
+		Inbound:  inbound,
+		recorder: r,
+	}
+	if source.IsValid() {
+		s.Source = source.String()
+	}
+	return s
+}
+
+// Record appends bytes to the session. It is a no-op on nil Session.
+func (s *Session) Record(direction string, layer string, data []byte) {
+	if s == nil || len(data) == 0 {
+		return
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if s.Truncated {
+		return
+	}
+	if remaining := s.recorder.maxSize - s.size; len(data) > remaining {
+		data = data[:remaining]
+		s.Truncated = true
+	}
+	s.size += len(data)
+
+	if n := len(s.Chunks); n > 0 && s.Chunks[n-1].Direction == direction && s.Chunks[n-1].Layer == layer {
+		s.Chunks[n-1].Data = append(s.Chunks[n-1].Data, data...)
+		return
+	}
+	s.Chunks = append(s.Chunks, &Chunk{
+		Direction: direction,
+		Layer:     layer,
+		Data:      append([]byte(nil), data...),
+	})
+}
+
+// Data returns all bytes recorded in the direction and layer.
+func (s *Session) Data(direction string, layer string) []byte {
+	var data []byte
+	for _, chunk := range s.Chunks {
+		if chunk.Direction == direction && chunk.Layer == layer {
+			data = append(data, chunk.Data...)
+		}
+	}
+	return data
+}
+
+// Finish ends the session. The session is saved if err is not nil, and discarded otherwise. It returns the path of the
+// saved file, if any.
+func (s *Session) Finish(err error) (string, error) {
+	if s == nil || err == nil {
+		return "", nil
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	s.Error = err.Error()
+	content, err := json.Marshal(s)
+	if err != nil {
+		return "", newError("failed to encode session").Base(err)
+	}
+	if err := os.MkdirAll(s.recorder.directory, 0700); err != nil {
+		return "", newError("failed to create directory ", s.recorder.directory).Base(err)
+	}
+	name := fmt.Sprintf("%s-%d.json", s.Time.Format("20060102-150405"), atomic.AddUint32(&savedSessions, 1))
+	path := filepath.Join(s.recorder.directory, name)
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return "", newError("failed to write session into ", path).Base(err)
+	}
+	return path, nil
+}
+
+// Load reads a session saved by a Recorder.
+func Load(path string) (*Session, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError("failed to read ", path).Base(err)
+	}
+	s := new(Session)
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, newError("invalid session in ", path).Base(err)
+	}
+	return s, nil
+}
+
+type recordConn struct {
+	net.Conn
+	session *Session
+}
+
+func (c *recordConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.session.Record(Receive, Raw, b[:n])
+	return n, err
+}
+
+func (c *recordConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.session.Record(Send, Raw, b[:n])
+	return n, err
+}
+
+// Conn returns a connection that records the bytes read from and written to conn at the Raw layer.
+func (s *Session) Conn(conn net.Conn) net.Conn {
+	if s == nil {
+		return conn
+	}
+	return &recordConn{
+		Conn:    conn,
+		session: s,
+	}
+}
+
+type recordReader struct {
+	buf.Reader
+	session *Session
+}
+
+func (r *recordReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	for _, b := range mb {
+		r.session.Record(Receive, Plain, b.Bytes())
+	}
+	return mb, err
+}
+
+// Reader returns a reader that records the payload read from reader at the Plain layer.
+func (s *Session) Reader(reader buf.Reader) buf.Reader {
+	if s == nil {
+		return reader
+	}
+	return &recordReader{
+		Reader:  reader,
+		session: s,
+	}
+}
+
+type recordWriter struct {
+	buf.Writer
+	session *Session
+}
+
+func (w *recordWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	for _, b := range mb {
+		w.session.Record(Send, Plain, b.Bytes())
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Writer returns a writer that records the payload written to writer at the Plain layer.
+func (s *Session) Writer(writer buf.Writer) buf.Writer {
+	if s == nil {
+		return writer
+	}
+	return &recordWriter{
+		Writer:  writer,
+		session: s,
+	}
+}
+
+type recordKey int
+
+const sessionKey recordKey = 0
+
+// ContextWithSession returns a new context with the recorded session.
+func ContextWithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
+
+// SessionFromContext returns the recorded session in the context, or nil if the session is not recorded.
+func SessionFromContext(ctx context.Context) *Session {
+	if s, ok := ctx.Value(sessionKey).(*Session); ok {
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package record_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	gonet "net"
+	"path/filepath"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/errors"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/common/record"
+)
+
+func TestRecordSession(t *testing.T) {
+	dir := t.TempDir()
+	r := New(dir, 10)
+	s := r.NewSession("in", net.TCPDestination(net.LocalHostIP, 1234))
+
+	s.Record(Receive, Raw, []byte("abc"))
+	s.Record(Receive, Raw, []byte("de"))
+	s.Record(Send, Raw, []byte("f"))
+	s.Record(Receive, Plain, []byte("ghijkl"))
+	s.Record(Receive, Raw, []byte("m"))
+
+	if len(s.Chunks) != 3 {
+		t.Fatal("expect 3 chunks, but got ", len(s.Chunks))
+	}
+	if !s.Truncated {
+		t.Error("expect session to be truncated")
+	}
+	if data := s.Data(Receive, Raw); string(data) != "abcde" {
+		t.Error("unexpected raw data: ", string(data))
+	}
+	if data := s.Data(Receive, Plain); string(data) != "ghij" {
+		t.Error("unexpected plain data: ", string(data))
+	}
+
+	path, err := s.Finish(errors.New("failed"))
+	common.Must(err)
+	if filepath.Dir(path) != dir {
+		t.Error("unexpected path ", path)
+	}
+	loaded, err := Load(path)
+	common.Must(err)
+	if loaded.Inbound != "in" || loaded.Source != "tcp:127.0.0.1:1234" || loaded.Error != "failed" {
+		t.Error("unexpected session ", loaded.Inbound, " ", loaded.Source, " ", loaded.Error)
+	}
+	if !bytes.Equal(loaded.Data(Receive, Raw), s.Data(Receive, Raw)) || !loaded.Truncated {
+		t.Error("unexpected data in loaded session")
+	}
+}
+
+func TestDiscardSucceededSession(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir, 0).NewSession("in", net.Destination{})
+	s.Record(Receive, Raw, []byte("abc"))
+
+	path, err := s.Finish(nil)
+	common.Must(err)
+	if path != "" {
+		t.Error("expect no saved session, but got ", path)
+	}
+	files, err := ioutil.ReadDir(dir)
+	common.Must(err)
+	if len(files) != 0 {
+		t.Error("expect empty directory, but got ", len(files), " files")
+	}
+}
+
+func TestNilSession(t *testing.T) {
+	var r *Recorder
+	s := r.NewSession("in", net.Destination{})
+	if s != nil {
+		t.Fatal("expect nil session")
+	}
+	s.Record(Receive, Raw, []byte("abc"))
+	if _, err := s.Finish(errors.New("failed")); err != nil {
+		t.Error(err)
+	}
+	if SessionFromContext(context.Background()) != nil {
+		t.Error("expect no session in context")
+	}
+	reader := buf.NewReader(bytes.NewReader(nil))
+	if s.Reader(reader) != reader {
+		t.Error("expect reader to be unwrapped")
+	}
+}
+
+func TestRecordConn(t *testing.T) {
+	s := New(t.TempDir(), 0).NewSession("in", net.Destination{})
+	ctx := ContextWithSession(context.Background(), s)
+	if SessionFromContext(ctx) != s {
+		t.Fatal("expect session in context")
+	}
+
+	client, server := gonet.Pipe()
+	conn := s.Conn(server)
+	go func() {
+		common.Must2(client.Write([]byte("request")))
+		b := make([]byte, 8)
+		common.Must2(client.Read(b))
+		client.Close()
+	}()
+
+	b := make([]byte, 7)
+	common.Must2(conn.Read(b))
+	common.Must2(conn.Write([]byte("response")))
+
+	writer := s.Writer(buf.Discard)
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("plain"))))
+
+	if data := s.Data(Receive, Raw); string(data) != "request" {
+		t.Error("unexpected received data: ", string(data))
+	}
+	if data := s.Data(Send, Raw); string(data) != "response" {
+		t.Error("unexpected sent data: ", string(data))
+	}
+	if data := s.Data(Send, Plain); string(data) != "plain" {
+		t.Error("unexpected plain data: ", string(data))
+	}
+}
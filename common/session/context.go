@@ -11,6 +11,7 @@ const (
 	contentSessionKey
 	muxPreferedSessionKey
 	sockoptSessionKey
+	hopsSessionKey
 )
 
 // ContextWithID returns a new context with the given ID.
@@ -84,3 +85,17 @@ func SockoptFromContext(ctx context.Context) *Sockopt {
 	}
 	return nil
 }
+
+// ContextWithHops returns a new context with the tags of outbounds, through which the outbound in the context dials.
+// The last hop is dialed through the one before it, and the first hop dials directly.
+func ContextWithHops(ctx context.Context, hops []string) context.Context {
+	return context.WithValue(ctx, hopsSessionKey, hops)
+}
+
+// HopsFromContext returns the hops in this context, or nil if the outbound is not in a chain.
+func HopsFromContext(ctx context.Context) []string {
+	if hops, ok := ctx.Value(hopsSessionKey).([]string); ok {
+		return hops
+	}
+	return nil
+}
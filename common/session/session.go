@@ -57,6 +57,15 @@ type Outbound struct {
 type SniffingRequest struct {
 	OverrideDestinationForProtocol []string
 	Enabled                        bool
+	// Ports are the destination ports to sniff. All ports are sniffed if it is empty.
+	Ports net.MemoryPortList
+	// RouteOnly makes the sniffed domain used for routing only, without overriding the destination.
+	RouteOnly bool
+	// Excluded reports whether an address, either the destination or the sniffed domain, must not be overridden.
+	// It may be nil.
+	Excluded func(net.Address) bool
+	// MetadataOnly makes the domain found from the metadata of the connection only, without waiting for the payload.
+	MetadataOnly bool
 }
 
 // Content is the metadata of the connection content.
@@ -64,6 +73,9 @@ type Content struct {
 	// Protocol of current content.
 	Protocol string
 
+	// Domain sniffed from the content, for routing. It is only set if the destination is not overridden by it.
+	Domain string
+
 	SniffingRequest SniffingRequest
 
 	Attributes map[string]string
@@ -45,10 +45,12 @@ func (u *UUID) Equals(another *UUID) bool {
 	return bytes.Equal(u.Bytes(), another.Bytes())
 }
 
-// New creates a UUID with random value.
+// New creates a UUID with random value, as a version 4 UUID in RFC 4122.
 func New() UUID {
 	var uuid UUID
 	common.Must2(rand.Read(uuid.Bytes()))
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
 	return uuid
 }
 
@@ -57,6 +57,12 @@ func TestNewUUID(t *testing.T) {
 	if r := cmp.Diff(uuid.Bytes(), uuid2.Bytes()); r != "" {
 		t.Error(r)
 	}
+	if version := uuid[6] >> 4; version != 4 {
+		t.Error("unexpected version: ", version)
+	}
+	if variant := uuid[8] >> 6; variant != 2 {
+		t.Error("unexpected variant: ", variant)
+	}
 }
 
 func TestRandom(t *testing.T) {
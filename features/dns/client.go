@@ -31,6 +31,21 @@ type IPv6Lookup interface {
 	LookupIPv6(domain string) ([]net.IP, error)
 }
 
+// DomainLookup is an optional feature for finding the domain that an IP was resolved from.
+//
+// v2ray:api:beta
+type DomainLookup interface {
+	// LookupDomain returns the domain that ip was last returned for, or an empty string if it is unknown.
+	LookupDomain(ip net.IP) string
+}
+
+// CacheFlusher is an optional feature for clearing cached DNS records, for example after the network has changed.
+//
+// v2ray:api:beta
+type CacheFlusher interface {
+	FlushCache()
+}
+
 // ClientType returns the type of Client interface. Can be used for implementing common.HasType.
 //
 // v2ray:api:beta
@@ -2,8 +2,10 @@ package outbound
 
 import (
 	"context"
+	"time"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
 	"v2ray.com/core/features"
 	"v2ray.com/core/transport"
 )
@@ -37,6 +39,39 @@ type Manager interface {
 	RemoveHandler(ctx context.Context, tag string) error
 }
 
+// ConnectionResetter is an optional interface for outbound.Handlers and outbound.Manager to close open connections,
+// for example after the network has changed.
+//
+// v2ray:api:beta
+type ConnectionResetter interface {
+	// ResetConnections closes all open connections whose local address is considered stale.
+	ResetConnections(stale func(local net.Addr) bool)
+}
+
+// Connection is an active connection relayed by an outbound.Handler.
+type Connection struct {
+	// ID of the session.
+	ID uint32
+	// InboundTag is the tag of the inbound handler that accepts the connection.
+	InboundTag string
+	// OutboundTag is the tag of the outbound handler that relays the connection.
+	OutboundTag string
+	// Source address of the connection.
+	Source net.Destination
+	// Target address of the connection.
+	Target net.Destination
+	// Start is the time when the outbound handler starts relaying the connection.
+	Start time.Time
+}
+
+// ConnectionLister is an optional interface for outbound.Handlers and outbound.Manager to list active connections.
+//
+// v2ray:api:beta
+type ConnectionLister interface {
+	// ListConnections returns the connections being relayed.
+	ListConnections() []*Connection
+}
+
 // ManagerType returns the type of Manager interface. Can be used to implement common.HasType.
 //
 // v2ray:api:stable
@@ -17,6 +17,9 @@ type Context interface {
 	// GetSourcePort returns the source port of the connection.
 	GetSourcePort() net.Port
 
+	// GetLocalPort returns the local port that the inbound accepted the connection on.
+	GetLocalPort() net.Port
+
 	// GetTargetIPs returns the target IP of the connection or resolved IPs of target domain.
 	GetTargetIPs() []net.IP
 
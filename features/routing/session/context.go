@@ -44,6 +44,14 @@ func (ctx *Context) GetSourcePort() net.Port {
 	return ctx.Inbound.Source.Port
 }
 
+// GetLocalPort implements routing.Context.
+func (ctx *Context) GetLocalPort() net.Port {
+	if ctx.Inbound == nil || !ctx.Inbound.Gateway.IsValid() {
+		return 0
+	}
+	return ctx.Inbound.Gateway.Port
+}
+
 // GetTargetIPs implements routing.Context.
 func (ctx *Context) GetTargetIPs() []net.IP {
 	if ctx.Outbound == nil || !ctx.Outbound.Target.IsValid() {
@@ -67,6 +75,9 @@ func (ctx *Context) GetTargetPort() net.Port {
 
 // GetTargetDomain implements routing.Context.
 func (ctx *Context) GetTargetDomain() string {
+	if ctx.Content != nil && ctx.Content.Domain != "" {
+		return ctx.Content.Domain
+	}
 	if ctx.Outbound == nil || !ctx.Outbound.Target.IsValid() {
 		return ""
 	}
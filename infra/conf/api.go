@@ -6,6 +6,7 @@ import (
 	"v2ray.com/core/app/commander"
 	loggerservice "v2ray.com/core/app/log/command"
 	handlerservice "v2ray.com/core/app/proxyman/command"
+	reloadservice "v2ray.com/core/app/reload/command"
 	statsservice "v2ray.com/core/app/stats/command"
 	"v2ray.com/core/common/serial"
 )
@@ -27,6 +28,8 @@ func (c *ApiConfig) Build() (*commander.Config, error) {
 			services = append(services, serial.ToTypedMessage(&handlerservice.Config{}))
 		case "loggerservice":
 			services = append(services, serial.ToTypedMessage(&loggerservice.Config{}))
+		case "reloadservice":
+			services = append(services, serial.ToTypedMessage(&reloadservice.Config{}))
 		case "statsservice":
 			services = append(services, serial.ToTypedMessage(&statsservice.Config{}))
 		}
@@ -0,0 +1,32 @@
+package conf
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/proxy/chain"
+)
+
+// ChainConfig is the config of the chain outbound. Traffic goes through the outbounds in order.
+type ChainConfig struct {
+	Outbounds []string `json:"outbounds"`
+}
+
+// Build implements Buildable.
+func (c *ChainConfig) Build() (proto.Message, error) {
+	if len(c.Outbounds) < 2 {
+		return nil, newError("chain needs at least 2 outbounds")
+	}
+	seen := make(map[string]bool, len(c.Outbounds))
+	for _, tag := range c.Outbounds {
+		if tag == "" {
+			return nil, newError("empty outbound tag in chain")
+		}
+		if seen[tag] {
+			return nil, newError("outbound ", tag, " appears more than once in chain")
+		}
+		seen[tag] = true
+	}
+	return &chain.Config{
+		OutboundTag: c.Outbounds,
+	}, nil
+}
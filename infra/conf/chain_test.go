@@ -0,0 +1,39 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/infra/conf"
+	"v2ray.com/core/proxy/chain"
+)
+
+func TestChainConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(ChainConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"outbounds": ["hop1", "hop2", "exit"]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &chain.Config{
+				OutboundTag: []string{"hop1", "hop2", "exit"},
+			},
+		},
+	})
+}
+
+func TestChainConfigError(t *testing.T) {
+	for _, input := range []string{
+		`{}`,
+		`{"outbounds": ["exit"]}`,
+		`{"outbounds": ["hop1", ""]}`,
+		`{"outbounds": ["hop1", "hop1"]}`,
+	} {
+		if _, err := loadJSON(func() Buildable { return new(ChainConfig) })(input); err == nil {
+			t.Error("expected error for ", input)
+		}
+	}
+}
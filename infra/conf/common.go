@@ -54,6 +54,24 @@ func (v *Address) Build() *net.IPOrDomain {
 	return net.NewIPOrDomain(v.Address)
 }
 
+// AddressList is a list of addresses, in JSON either a single address or an array of addresses.
+type AddressList []*Address
+
+func (v *AddressList) UnmarshalJSON(data []byte) error {
+	var list []*Address
+	if err := json.Unmarshal(data, &list); err == nil {
+		*v = list
+		return nil
+	}
+
+	address := new(Address)
+	if err := address.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*v = AddressList{address}
+	return nil
+}
+
 type Network string
 
 func (v Network) Build() net.Network {
@@ -205,19 +223,14 @@ func (list *PortList) UnmarshalJSON(data []byte) error {
 	for _, rangeStr := range rangelist {
 		trimmed := strings.TrimSpace(rangeStr)
 		if len(trimmed) > 0 {
-			if strings.Contains(trimmed, "-") {
-				from, to, err := parseStringPort(trimmed)
-				if err != nil {
-					return newError("invalid port range: ", trimmed).Base(err)
-				}
-				list.Range = append(list.Range, PortRange{From: uint32(from), To: uint32(to)})
-			} else {
-				port, err := parseIntPort([]byte(trimmed))
-				if err != nil {
-					return newError("invalid port: ", trimmed).Base(err)
-				}
-				list.Range = append(list.Range, PortRange{From: uint32(port), To: uint32(port)})
+			from, to, err := parseStringPort(trimmed)
+			if err != nil {
+				return newError("invalid port range: ", trimmed).Base(err)
+			}
+			if from > to {
+				return newError("invalid port range ", from, " -> ", to)
 			}
+			list.Range = append(list.Range, PortRange{From: uint32(from), To: uint32(to)})
 		}
 	}
 	if number != 0 {
@@ -229,3 +229,30 @@ func TestInvalidUserJson(t *testing.T) {
 		t.Error("nil error")
 	}
 }
+
+func TestPortList(t *testing.T) {
+	common.Must(os.Setenv("PORT", "1234"))
+
+	var portList PortList
+	common.Must(json.Unmarshal([]byte("\"80, 443,8000-8010,env:PORT\""), &portList))
+	if r := cmp.Diff(portList, PortList{Range: []PortRange{
+		{From: 80, To: 80},
+		{From: 443, To: 443},
+		{From: 8000, To: 8010},
+		{From: 1234, To: 1234},
+	}}); r != "" {
+		t.Error(r)
+	}
+
+	portList = PortList{}
+	common.Must(json.Unmarshal([]byte("443"), &portList))
+	if r := cmp.Diff(portList, PortList{Range: []PortRange{{From: 443, To: 443}}}); r != "" {
+		t.Error(r)
+	}
+
+	for _, invalid := range []string{"\"700-600\"", "\"80,http\"", "\"80,70000\""} {
+		if err := json.Unmarshal([]byte(invalid), new(PortList)); err == nil {
+			t.Error("expect error for ", invalid)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package convert
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type clashConfig struct {
+	Proxies []*clashProxy `yaml:"proxies"`
+}
+
+type clashProxy struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Server   string `yaml:"server"`
+	Port     uint16 `yaml:"port"`
+	UUID     string `yaml:"uuid"`
+	AlterID  uint16 `yaml:"alterId"`
+	Cipher   string `yaml:"cipher"`
+	Password string `yaml:"password"`
+	Username string `yaml:"username"`
+	Flow     string `yaml:"flow"`
+	Plugin   string `yaml:"plugin"`
+
+	TLS            bool   `yaml:"tls"`
+	SkipCertVerify bool   `yaml:"skip-cert-verify"`
+	ServerName     string `yaml:"servername"`
+	SNI            string `yaml:"sni"`
+
+	Network   string            `yaml:"network"`
+	WSPath    string            `yaml:"ws-path"`
+	WSHeaders map[string]string `yaml:"ws-headers"`
+	WSOpts    struct {
+		Path    string            `yaml:"path"`
+		Headers map[string]string `yaml:"headers"`
+	} `yaml:"ws-opts"`
+	H2Opts struct {
+		Host []string `yaml:"host"`
+		Path string   `yaml:"path"`
+	} `yaml:"h2-opts"`
+}
+
+// FromClash converts the proxies in a Clash config into V2Ray outbounds, tagged by the names of the proxies.
+// Proxies that V2Ray doesn't support are rejected, instead of being dropped silently.
+func FromClash(data []byte) ([]byte, error) {
+	var config clashConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, newError("failed to parse Clash config").Base(err)
+	}
+
+	outbounds := make([]*outbound, 0, len(config.Proxies))
+	for _, proxy := range config.Proxies {
+		o, err := proxy.build()
+		if err != nil {
+			return nil, newError("failed to convert proxy ", proxy.Name).Base(err)
+		}
+		outbounds = append(outbounds, o)
+	}
+	return marshalOutbounds(outbounds)
+}
+
+func (p *clashProxy) build() (*outbound, error) {
+	if p.Name == "" {
+		return nil, newError("proxy name is not specified")
+	}
+	o := &outbound{
+		Tag: p.Name,
+	}
+
+	switch strings.ToLower(p.Type) {
+	case "vmess":
+		security := p.Cipher
+		if security == "" {
+			security = "auto"
+		}
+		o.Protocol = "vmess"
+		o.Settings = p.vnext(&vmessUser{
+			ID:       p.UUID,
+			AlterID:  p.AlterID,
+			Security: security,
+		})
+	case "vless":
+		o.Protocol = "vless"
+		o.Settings = p.vnext(&vlessUser{
+			ID:         p.UUID,
+			Encryption: "none",
+			Flow:       p.Flow,
+		})
+	case "ss":
+		if p.Plugin != "" {
+			return nil, newError("Shadowsocks plugin ", p.Plugin, " is not supported")
+		}
+		o.Protocol = "shadowsocks"
+		o.Settings = &shadowsocksSettings{
+			Servers: []*shadowsocksServer{{
+				Address:  p.Server,
+				Port:     p.Port,
+				Method:   p.Cipher,
+				Password: p.Password,
+			}},
+		}
+	case "socks5":
+		o.Protocol = "socks"
+		o.Settings = p.servers()
+	case "http":
+		o.Protocol = "http"
+		o.Settings = p.servers()
+	default:
+		return nil, newError("proxy type ", p.Type, " is not supported")
+	}
+
+	stream, err := p.streamSettings()
+	if err != nil {
+		return nil, err
+	}
+	o.StreamSettings = stream
+	return o, nil
+}
+
+func (p *clashProxy) vnext(user interface{}) *vnextSettings {
+	return &vnextSettings{
+		Vnext: []*vnext{{
+			Address: p.Server,
+			Port:    p.Port,
+			Users:   []interface{}{user},
+		}},
+	}
+}
+
+func (p *clashProxy) servers() *serversSettings {
+	s := &server{
+		Address: p.Server,
+		Port:    p.Port,
+	}
+	if p.Username != "" || p.Password != "" {
+		s.Users = []interface{}{&account{
+			User: p.Username,
+			Pass: p.Password,
+		}}
+	}
+	return &serversSettings{
+		Servers: []*server{s},
+	}
+}
+
+func (p *clashProxy) streamSettings() (*streamSettings, error) {
+	s := &streamSettings{}
+	switch p.Network {
+	case "", "tcp":
+	case "ws":
+		path, headers := p.WSOpts.Path, p.WSOpts.Headers
+		if path == "" {
+			path = p.WSPath
+		}
+		if headers == nil {
+			headers = p.WSHeaders
+		}
+		s.Network = "ws"
+		s.WSSettings = &wsSettings{
+			Path:    path,
+			Headers: headers,
+		}
+	case "h2":
+		s.Network = "http"
+		s.HTTPSettings = &httpSettings{
+			Host: p.H2Opts.Host,
+			Path: p.H2Opts.Path,
+		}
+	default:
+		return nil, newError("network ", p.Network, " is not supported")
+	}
+
+	if p.TLS {
+		serverName := p.ServerName
+		if serverName == "" {
+			serverName = p.SNI
+		}
+		s.Security = "tls"
+		if serverName != "" || p.SkipCertVerify {
+			s.TLSSettings = &tlsSettings{
+				ServerName:    serverName,
+				AllowInsecure: p.SkipCertVerify,
+			}
+		}
+	}
+
+	if s.Network == "" && s.Security == "" {
+		return nil, nil
+	}
+	return s, nil
+}
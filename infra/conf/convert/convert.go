@@ -0,0 +1,41 @@
+// Package convert converts V2Ray configs between JSON, YAML and protobuf, and converts configs of other clients into
+// V2Ray outbounds.
+package convert
+
+//go:generate errorgen
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"v2ray.com/core"
+	"v2ray.com/core/infra/conf"
+	json_reader "v2ray.com/core/infra/conf/json"
+)
+
+// ParseJSON parses a V2Ray JSON config strictly. Unlike the loader of V2Ray, unknown keys are rejected, so that typos
+// are caught during conversion.
+func ParseJSON(data []byte) (*conf.Config, error) {
+	stripped, err := ioutil.ReadAll(&json_reader.Reader{Reader: bytes.NewReader(data)})
+	if err != nil {
+		return nil, newError("failed to read JSON").Base(err)
+	}
+	config := new(conf.Config)
+	if err := decodeStrict(stripped, config); err != nil {
+		return nil, newError("invalid JSON config").Base(err)
+	}
+	return config, nil
+}
+
+// Validate checks that the V2Ray JSON config is accepted by V2Ray, and returns the protobuf config built from it.
+func Validate(data []byte) (*core.Config, error) {
+	config, err := ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	pbConfig, err := config.Build()
+	if err != nil {
+		return nil, newError("invalid config").Base(err)
+	}
+	return pbConfig, nil
+}
@@ -0,0 +1,184 @@
+package convert_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	. "v2ray.com/core/infra/conf/convert"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	input := `
+log:
+  loglevel: warning
+outbounds:
+  - &direct
+    protocol: freedom
+    tag: direct
+  - <<: *direct
+    tag: direct2
+    settings:
+      domainStrategy: UseIP
+      redirect: "127.0.0.1:53"
+  - {protocol: blackhole, tag: "null"}
+`
+	expected := `{
+  "log": {
+    "loglevel": "warning"
+  },
+  "outbounds": [
+    {
+      "protocol": "freedom",
+      "tag": "direct"
+    },
+    {
+      "protocol": "freedom",
+      "tag": "direct2",
+      "settings": {
+        "domainStrategy": "UseIP",
+        "redirect": "127.0.0.1:53"
+      }
+    },
+    {
+      "protocol": "blackhole",
+      "tag": "null"
+    }
+  ]
+}
+`
+	output, err := YAMLToJSON([]byte(input))
+	common.Must(err)
+	if r := cmp.Diff(string(output), expected); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestJSONToYAMLRoundTrip(t *testing.T) {
+	input := `{
+  // comments are allowed.
+  "inbounds": [{"port": 1080, "listen": "127.0.0.1", "protocol": "socks", "settings": {"udp": true, "auth": "noauth"}}],
+  "outbounds": [{"protocol": "freedom", "tag": "true"}]
+}`
+	output, err := JSONToYAML([]byte(input))
+	common.Must(err)
+	if !strings.HasPrefix(string(output), "inbounds:\n") {
+		t.Error("unexpected YAML: ", string(output))
+	}
+
+	back, err := YAMLToJSON(output)
+	common.Must(err)
+	expected := `{"inbounds":[{"port":1080,"listen":"127.0.0.1","protocol":"socks","settings":{"udp":true,"auth":"noauth"}}],"outbounds":[{"protocol":"freedom","tag":"true"}]}`
+	if r := cmp.Diff(compact(back), expected); r != "" {
+		t.Error(r)
+	}
+}
+
+func compact(data []byte) string {
+	var b bytes.Buffer
+	common.Must(json.Compact(&b, data))
+	return b.String()
+}
+
+func TestValidate(t *testing.T) {
+	if _, err := Validate([]byte(`{"outbounds": [{"protocol": "freedom"}]}`)); err != nil {
+		t.Error(err)
+	}
+	if _, err := Validate([]byte(`{"outbound": [{"protocol": "freedom"}]}`)); err == nil {
+		t.Error("expect error for unknown key")
+	}
+	if _, err := Validate([]byte(`{"outbounds": [{"protocol": "unknown"}]}`)); err == nil {
+		t.Error("expect error for unknown protocol")
+	}
+}
+
+func TestFromClash(t *testing.T) {
+	input := `
+proxies:
+  - name: vmess
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: 27848739-7e62-4138-9fd3-098a63964b6b
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: example.com
+    network: ws
+    ws-opts:
+      path: /ray
+      headers:
+        Host: example.com
+  - name: ss
+    type: ss
+    server: 1.2.3.4
+    port: 8388
+    cipher: aes-128-gcm
+    password: password
+  - name: socks
+    type: socks5
+    server: 1.2.3.4
+    port: 1080
+`
+	output, err := FromClash([]byte(input))
+	common.Must(err)
+	expected := `{"outbounds":[{"tag":"vmess","protocol":"vmess","settings":{"vnext":[{"address":"example.com","port":443,"users":[{"id":"27848739-7e62-4138-9fd3-098a63964b6b","alterId":0,"security":"auto"}]}]},"streamSettings":{"network":"ws","security":"tls","tlsSettings":{"serverName":"example.com"},"wsSettings":{"path":"/ray","headers":{"Host":"example.com"}}}},{"tag":"ss","protocol":"shadowsocks","settings":{"servers":[{"address":"1.2.3.4","port":8388,"method":"aes-128-gcm","password":"password"}]}},{"tag":"socks","protocol":"socks","settings":{"servers":[{"address":"1.2.3.4","port":1080}]}}]}`
+	if r := cmp.Diff(compact(output), expected); r != "" {
+		t.Error(r)
+	}
+	if _, err := Validate(output); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromClashUnsupported(t *testing.T) {
+	for _, input := range []string{
+		"proxies:\n  - {name: a, type: snell, server: 1.2.3.4, port: 1}",
+		"proxies:\n  - {name: a, type: ss, server: 1.2.3.4, port: 1, cipher: aes-128-gcm, password: p, plugin: obfs}",
+		"proxies:\n  - {name: a, type: ss, server: 1.2.3.4, port: 1, cipher: rc4, password: p}",
+		"proxies:\n  - {name: a, type: socks5, server: 1.2.3.4, port: 1}\n  - {name: a, type: http, server: 1.2.3.4, port: 2}",
+		"proxies: []",
+	} {
+		if _, err := FromClash([]byte(input)); err == nil {
+			t.Error("expect error for ", input)
+		}
+	}
+}
+
+func TestFromSIP008(t *testing.T) {
+	input := `{
+  "version": 1,
+  "servers": [{
+    "id": "27b8a625-4f4b-4428-9f0f-8a2317db7c79",
+    "remarks": "Server 1",
+    "server": "example.com",
+    "server_port": 8388,
+    "password": "password",
+    "method": "chacha20-ietf-poly1305"
+  }]
+}`
+	output, err := FromSIP008([]byte(input))
+	common.Must(err)
+	expected := `{"outbounds":[{"tag":"Server 1","protocol":"shadowsocks","settings":{"servers":[{"address":"example.com","port":8388,"method":"chacha20-ietf-poly1305","password":"password"}]}}]}`
+	if r := cmp.Diff(compact(output), expected); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestProtobufToJSON(t *testing.T) {
+	config, err := Validate([]byte(`{"outbounds": [{"protocol": "freedom", "tag": "direct"}]}`))
+	common.Must(err)
+	data, err := proto.Marshal(config)
+	common.Must(err)
+
+	output, err := ProtobufToJSON(data)
+	common.Must(err)
+	if !strings.Contains(string(output), `"type": "v2ray.core.proxy.freedom.Config"`) {
+		t.Error("typed message is not expanded: ", string(output))
+	}
+}
@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"v2ray.com/core/infra/conf"
+)
+
+// The following types describe outbounds in V2Ray JSON config, with the keys in the usual order.
+
+type outbound struct {
+	Tag            string          `json:"tag"`
+	Protocol       string          `json:"protocol"`
+	Settings       interface{}     `json:"settings"`
+	StreamSettings *streamSettings `json:"streamSettings,omitempty"`
+}
+
+type vnextSettings struct {
+	Vnext []*vnext `json:"vnext"`
+}
+
+type vnext struct {
+	Address string        `json:"address"`
+	Port    uint16        `json:"port"`
+	Users   []interface{} `json:"users"`
+}
+
+type vmessUser struct {
+	ID       string `json:"id"`
+	AlterID  uint16 `json:"alterId"`
+	Security string `json:"security,omitempty"`
+}
+
+type vlessUser struct {
+	ID         string `json:"id"`
+	Encryption string `json:"encryption"`
+	Flow       string `json:"flow,omitempty"`
+}
+
+type shadowsocksSettings struct {
+	Servers []*shadowsocksServer `json:"servers"`
+}
+
+type shadowsocksServer struct {
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+	Method   string `json:"method"`
+	Password string `json:"password"`
+}
+
+type serversSettings struct {
+	Servers []*server `json:"servers"`
+}
+
+type server struct {
+	Address string        `json:"address"`
+	Port    uint16        `json:"port"`
+	Users   []interface{} `json:"users,omitempty"`
+}
+
+type account struct {
+	User string `json:"user"`
+	Pass string `json:"pass"`
+}
+
+type streamSettings struct {
+	Network      string        `json:"network,omitempty"`
+	Security     string        `json:"security,omitempty"`
+	TLSSettings  *tlsSettings  `json:"tlsSettings,omitempty"`
+	WSSettings   *wsSettings   `json:"wsSettings,omitempty"`
+	HTTPSettings *httpSettings `json:"httpSettings,omitempty"`
+}
+
+type tlsSettings struct {
+	ServerName    string `json:"serverName,omitempty"`
+	AllowInsecure bool   `json:"allowInsecure,omitempty"`
+}
+
+type wsSettings struct {
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type httpSettings struct {
+	Host []string `json:"host,omitempty"`
+	Path string   `json:"path,omitempty"`
+}
+
+// decodeStrict decodes JSON into v, and fails on unknown keys.
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// validateOutbound builds the outbound with the JSON config loader, to make sure that V2Ray accepts it.
+func validateOutbound(o *outbound) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	var c conf.OutboundDetourConfig
+	if err := decodeStrict(data, &c); err != nil {
+		return err
+	}
+	_, err = c.Build()
+	return err
+}
+
+// marshalOutbounds validates the outbounds, and returns them as a V2Ray JSON config.
+func marshalOutbounds(outbounds []*outbound) ([]byte, error) {
+	if len(outbounds) == 0 {
+		return nil, newError("no outbound is converted")
+	}
+	tags := make(map[string]bool, len(outbounds))
+	for _, o := range outbounds {
+		if tags[o.Tag] {
+			return nil, newError("duplicated tag: ", o.Tag)
+		}
+		tags[o.Tag] = true
+		if err := validateOutbound(o); err != nil {
+			return nil, newError("invalid outbound ", o.Tag).Base(err)
+		}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Outbounds []*outbound `json:"outbounds"`
+	}{outbounds}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
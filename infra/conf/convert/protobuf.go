@@ -0,0 +1,120 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common/serial"
+)
+
+// ProtobufToJSON dumps a protobuf config as JSON, for inspection. The fields are named as in the .proto files, and
+// typed messages are expanded into their actual content. The output is not a V2Ray JSON config, and can't be loaded.
+func ProtobufToJSON(data []byte) ([]byte, error) {
+	config := new(core.Config)
+	if err := proto.Unmarshal(data, config); err != nil {
+		return nil, newError("failed to parse protobuf config").Base(err)
+	}
+
+	var b bytes.Buffer
+	writeMessage(&b, proto.MessageReflect(config))
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return nil, newError("failed to format JSON").Base(err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func writeMessage(b *bytes.Buffer, m protoreflect.Message) {
+	if tm, ok := m.Interface().(*serial.TypedMessage); ok {
+		if instance, err := tm.GetInstance(); err == nil {
+			b.WriteString(`{"type":`)
+			writeString(b, tm.Type)
+			b.WriteString(`,"value":`)
+			writeMessage(b, proto.MessageReflect(instance))
+			b.WriteByte('}')
+			return
+		}
+	}
+
+	b.WriteByte('{')
+	fields := m.Descriptor().Fields()
+	first := true
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		writeString(b, string(fd.Name()))
+		b.WriteByte(':')
+		writeField(b, fd, m.Get(fd))
+	}
+	b.WriteByte('}')
+}
+
+func writeField(b *bytes.Buffer, fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		b.WriteByte('[')
+		for i := 0; i < list.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeValue(b, fd, list.Get(i))
+		}
+		b.WriteByte(']')
+	case fd.IsMap():
+		b.WriteByte('{')
+		first := true
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			writeString(b, k.String())
+			b.WriteByte(':')
+			writeValue(b, fd.MapValue(), v)
+			return true
+		})
+		b.WriteByte('}')
+	default:
+		writeValue(b, fd, v)
+	}
+}
+
+func writeValue(b *bytes.Buffer, fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		writeMessage(b, v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			writeString(b, string(ev.Name()))
+		} else {
+			b.WriteString(strconv.Itoa(int(v.Enum())))
+		}
+	case protoreflect.BytesKind:
+		writeString(b, base64.StdEncoding.EncodeToString(v.Bytes()))
+	case protoreflect.StringKind:
+		writeString(b, v.String())
+	default:
+		value, _ := json.Marshal(v.Interface())
+		b.Write(value)
+	}
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	value, _ := json.Marshal(s)
+	b.Write(value)
+}
@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"encoding/json"
+)
+
+type sip008Config struct {
+	Version int             `json:"version"`
+	Servers []*sip008Server `json:"servers"`
+}
+
+type sip008Server struct {
+	ID         string `json:"id"`
+	Remarks    string `json:"remarks"`
+	Server     string `json:"server"`
+	ServerPort uint16 `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin"`
+	PluginOpts string `json:"plugin_opts"`
+}
+
+// FromSIP008 converts a Shadowsocks online config, as defined in SIP008, into V2Ray outbounds. Outbounds are tagged
+// by the remarks of the servers, or by their IDs if remarks are absent.
+func FromSIP008(data []byte) ([]byte, error) {
+	var config sip008Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, newError("failed to parse SIP008 config").Base(err)
+	}
+	if config.Version != 0 && config.Version != 1 {
+		return nil, newError("unsupported SIP008 version: ", config.Version)
+	}
+
+	outbounds := make([]*outbound, 0, len(config.Servers))
+	for i, s := range config.Servers {
+		tag := s.Remarks
+		if tag == "" {
+			tag = s.ID
+		}
+		if tag == "" {
+			return nil, newError("server ", i, " has neither remarks nor id")
+		}
+		if s.Plugin != "" {
+			return nil, newError("Shadowsocks plugin ", s.Plugin, " of server ", tag, " is not supported")
+		}
+		outbounds = append(outbounds, &outbound{
+			Tag:      tag,
+			Protocol: "shadowsocks",
+			Settings: &shadowsocksSettings{
+				Servers: []*shadowsocksServer{{
+					Address:  s.Server,
+					Port:     s.ServerPort,
+					Method:   s.Method,
+					Password: s.Password,
+				}},
+			},
+		})
+	}
+	return marshalOutbounds(outbounds)
+}
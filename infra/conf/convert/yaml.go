@@ -0,0 +1,207 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	json_reader "v2ray.com/core/infra/conf/json"
+)
+
+// YAMLToJSON converts a YAML document into JSON. The order of keys is kept.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, newError("failed to parse YAML").Base(err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, newError("empty YAML document")
+	}
+
+	var b bytes.Buffer
+	if err := writeJSON(&b, doc.Content[0]); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return nil, newError("failed to format JSON").Base(err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func writeJSON(b *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeJSON(b, node.Alias)
+	case yaml.DocumentNode:
+		return writeJSON(b, node.Content[0])
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		m := &mapping{index: make(map[string]int)}
+		if err := m.collect(node, true); err != nil {
+			return err
+		}
+		b.WriteByte('{')
+		for i, key := range m.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			b.Write(k)
+			b.WriteByte(':')
+			if err := writeJSON(b, m.values[i]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+		return nil
+	case yaml.ScalarNode:
+		return writeScalar(b, node)
+	default:
+		return newError("unsupported YAML node at line ", node.Line)
+	}
+}
+
+// mapping is a YAML mapping with merge keys "<<" resolved.
+type mapping struct {
+	keys   []string
+	values []*yaml.Node
+	index  map[string]int
+}
+
+// collect adds the entries of node. Explicit entries override merged ones, regardless of their order.
+func (m *mapping) collect(node *yaml.Node, explicit bool) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			if err := m.merge(value); err != nil {
+				return err
+			}
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return newError("unsupported key at line ", key.Line, ": keys must be scalars")
+		}
+		if j, found := m.index[key.Value]; found {
+			if explicit {
+				m.values[j] = value
+			}
+			continue
+		}
+		m.index[key.Value] = len(m.keys)
+		m.keys = append(m.keys, key.Value)
+		m.values = append(m.values, value)
+	}
+	return nil
+}
+
+func (m *mapping) merge(value *yaml.Node) error {
+	if value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+	switch value.Kind {
+	case yaml.MappingNode:
+		return m.collect(value, false)
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if err := m.merge(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return newError("invalid merge at line ", value.Line)
+	}
+}
+
+func writeScalar(b *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		b.WriteString("null")
+	case "!!bool":
+		var v bool
+		if err := node.Decode(&v); err != nil {
+			return newError("invalid boolean at line ", node.Line).Base(err)
+		}
+		b.WriteString(strconv.FormatBool(v))
+	case "!!int":
+		var v int64
+		if err := node.Decode(&v); err != nil {
+			var u uint64
+			if err := node.Decode(&u); err != nil {
+				return newError("invalid integer at line ", node.Line).Base(err)
+			}
+			b.WriteString(strconv.FormatUint(u, 10))
+			return nil
+		}
+		b.WriteString(strconv.FormatInt(v, 10))
+	case "!!float":
+		var v float64
+		if err := node.Decode(&v); err != nil {
+			return newError("invalid number at line ", node.Line).Base(err)
+		}
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return newError("number at line ", node.Line, " is not representable in JSON")
+		}
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		s, _ := json.Marshal(node.Value)
+		b.Write(s)
+	}
+	return nil
+}
+
+// JSONToYAML converts a JSON document, possibly with comments, into YAML. The order of keys is kept.
+func JSONToYAML(data []byte) ([]byte, error) {
+	content, err := ioutil.ReadAll(&json_reader.Reader{Reader: bytes.NewReader(data)})
+	if err != nil {
+		return nil, newError("failed to read JSON").Base(err)
+	}
+	if !json.Valid(content) {
+		var v interface{}
+		return nil, newError("failed to parse JSON").Base(json.Unmarshal(content, &v))
+	}
+
+	// JSON is a subset of YAML, so the parsed tree only needs to be re-formatted in block style.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, newError("failed to parse JSON").Base(err)
+	}
+	resetStyle(&doc)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, newError("failed to encode YAML").Base(err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, newError("failed to encode YAML").Base(err)
+	}
+	return out.Bytes(), nil
+}
+
+// resetStyle switches all nodes to block style. Strings which look like other types are still quoted by the encoder.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
@@ -12,6 +12,7 @@ type DokodemoConfig struct {
 	TimeoutValue uint32       `json:"timeout"`
 	Redirect     bool         `json:"followRedirect"`
 	UserLevel    uint32       `json:"userLevel"`
+	Sockmap      bool         `json:"sockmap"`
 }
 
 func (v *DokodemoConfig) Build() (proto.Message, error) {
@@ -24,5 +25,6 @@ func (v *DokodemoConfig) Build() (proto.Message, error) {
 	config.Timeout = v.TimeoutValue
 	config.FollowRedirect = v.Redirect
 	config.UserLevel = v.UserLevel
+	config.Sockmap = v.Sockmap
 	return config, nil
 }
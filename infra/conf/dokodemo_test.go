@@ -21,7 +21,8 @@ func TestDokodemoConfig(t *testing.T) {
 				"network": "tcp",
 				"timeout": 10,
 				"followRedirect": true,
-				"userLevel": 1
+				"userLevel": 1,
+				"sockmap": true
 			}`,
 			Parser: loadJSON(creator),
 			Output: &dokodemo.Config{
@@ -35,6 +36,7 @@ func TestDokodemoConfig(t *testing.T) {
 				Timeout:        10,
 				FollowRedirect: true,
 				UserLevel:      1,
+				Sockmap:        true,
 			},
 		},
 	})
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/golang/protobuf/proto"
@@ -10,11 +11,67 @@ import (
 	"v2ray.com/core/proxy/freedom"
 )
 
+type FreedomFragmentConfig struct {
+	Packets  string `json:"packets"`
+	Length   string `json:"length"`
+	Interval string `json:"interval"`
+}
+
+// parseUint32Range parses a number or a range in the form of "from-to".
+func parseUint32Range(s string) (uint32, uint32, error) {
+	pair := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	from, err := strconv.ParseUint(strings.TrimSpace(pair[0]), 10, 32)
+	if err != nil {
+		return 0, 0, newError("invalid range: ", s).Base(err)
+	}
+	to := from
+	if len(pair) == 2 {
+		if to, err = strconv.ParseUint(strings.TrimSpace(pair[1]), 10, 32); err != nil {
+			return 0, 0, newError("invalid range: ", s).Base(err)
+		}
+	}
+	if from > to {
+		return 0, 0, newError("invalid range: ", s)
+	}
+	return uint32(from), uint32(to), nil
+}
+
+// Build implements Buildable.
+func (c *FreedomFragmentConfig) Build() (*freedom.Fragment, error) {
+	fragment := new(freedom.Fragment)
+	if !strings.EqualFold(c.Packets, "tlshello") {
+		from, to, err := parseUint32Range(c.Packets)
+		if err != nil {
+			return nil, newError("invalid packets to fragment").Base(err)
+		}
+		if from == 0 {
+			return nil, newError("packets to fragment are counted from 1")
+		}
+		fragment.PacketsFrom, fragment.PacketsTo = from, to
+	}
+
+	var err error
+	if fragment.LengthMin, fragment.LengthMax, err = parseUint32Range(c.Length); err != nil {
+		return nil, newError("invalid length of fragments").Base(err)
+	}
+	if fragment.LengthMin == 0 {
+		return nil, newError("length of fragments must be positive")
+	}
+	if c.Interval != "" {
+		if fragment.IntervalMin, fragment.IntervalMax, err = parseUint32Range(c.Interval); err != nil {
+			return nil, newError("invalid interval between fragments").Base(err)
+		}
+	}
+	return fragment, nil
+}
+
 type FreedomConfig struct {
-	DomainStrategy string  `json:"domainStrategy"`
-	Timeout        *uint32 `json:"timeout"`
-	Redirect       string  `json:"redirect"`
-	UserLevel      uint32  `json:"userLevel"`
+	DomainStrategy string                 `json:"domainStrategy"`
+	Timeout        *uint32                `json:"timeout"`
+	Redirect       string                 `json:"redirect"`
+	UserLevel      uint32                 `json:"userLevel"`
+	NAT64          string                 `json:"nat64"`
+	Fragment       *FreedomFragmentConfig `json:"fragment"`
 }
 
 // Build implements Buildable
@@ -53,5 +110,34 @@ func (c *FreedomConfig) Build() (proto.Message, error) {
 			config.DestinationOverride.Server.Address = v2net.NewIPOrDomain(v2net.ParseAddress(host))
 		}
 	}
+
+	switch nat64 := strings.ToLower(c.NAT64); nat64 {
+	case "":
+	case "auto":
+		config.Nat64 = &freedom.Nat64{}
+	default:
+		_, prefix, err := net.ParseCIDR(nat64)
+		if err != nil || prefix.IP.To4() != nil {
+			return nil, newError("invalid NAT64 prefix: ", c.NAT64)
+		}
+		length, _ := prefix.Mask.Size()
+		switch length {
+		case 32, 40, 48, 56, 64, 96:
+		default:
+			return nil, newError("invalid length of NAT64 prefix: ", c.NAT64)
+		}
+		config.Nat64 = &freedom.Nat64{
+			Prefix:       []byte(prefix.IP.To16()),
+			PrefixLength: uint32(length),
+		}
+	}
+
+	if c.Fragment != nil {
+		fragment, err := c.Fragment.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fragment = fragment
+	}
 	return config, nil
 }
@@ -39,5 +39,76 @@ func TestFreedomConfig(t *testing.T) {
 				UserLevel: 1,
 			},
 		},
+		{
+			Input: `{
+				"nat64": "64:ff9b::/96"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				Nat64: &freedom.Nat64{
+					Prefix:       []byte{0, 0x64, 0xff, 0x9b, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+					PrefixLength: 96,
+				},
+			},
+		},
+		{
+			Input: `{
+				"nat64": "auto"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				Nat64: &freedom.Nat64{},
+			},
+		},
+		{
+			Input: `{
+				"fragment": {
+					"packets": "tlshello",
+					"length": "100-200",
+					"interval": "10-20"
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				Fragment: &freedom.Fragment{
+					LengthMin:   100,
+					LengthMax:   200,
+					IntervalMin: 10,
+					IntervalMax: 20,
+				},
+			},
+		},
+		{
+			Input: `{
+				"fragment": {
+					"packets": "1-3",
+					"length": "5"
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				Fragment: &freedom.Fragment{
+					PacketsFrom: 1,
+					PacketsTo:   3,
+					LengthMin:   5,
+					LengthMax:   5,
+				},
+			},
+		},
 	})
 }
+
+func TestFreedomFragmentConfigError(t *testing.T) {
+	for _, input := range []string{
+		`{"packets": "0-3", "length": "10"}`,
+		`{"packets": "3-1", "length": "10"}`,
+		`{"packets": "tlshello", "length": "0-10"}`,
+		`{"packets": "tlshello", "length": "20-10"}`,
+		`{"packets": "tlshello"}`,
+		`{"packets": "tlshello", "length": "10", "interval": "a"}`,
+	} {
+		if _, err := loadJSON(func() Buildable { return new(FreedomConfig) })(`{"fragment": ` + input + `}`); err == nil {
+			t.Error("expected error for ", input)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package geodata compiles plaintext domain and CIDR lists into the geosite and geoip formats used by the router.
+package geodata
+
+//go:generate errorgen
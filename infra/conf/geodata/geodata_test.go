@@ -0,0 +1,129 @@
+package geodata_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/app/router"
+	. "v2ray.com/core/infra/conf/geodata"
+)
+
+func readers(lists map[string]string) map[string]io.Reader {
+	r := make(map[string]io.Reader, len(lists))
+	for name, list := range lists {
+		r[name] = strings.NewReader(list)
+	}
+	return r
+}
+
+func TestCompileSite(t *testing.T) {
+	siteList, err := CompileSite(readers(map[string]string{
+		"example": `
+# Example domains
+example.com
+www.example.com            # covered by example.com
+full:example.com @ads      # attribute not on example.com
+Example.org @cn @CN
+example.org
+keyword:Google
+regexp:^ad[0-9]+\.
+include:ads
+`,
+		"ads": `
+domain:ads.example.net @ads
+full:ads.example.net @ads
+`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &router.GeoSiteList{
+		Entry: []*router.GeoSite{
+			{
+				CountryCode: "ADS",
+				Domain: []*router.Domain{
+					{Type: router.Domain_Domain, Value: "ads.example.net", Attribute: []*router.Domain_Attribute{
+						{Key: "ads", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}},
+					}},
+				},
+			},
+			{
+				CountryCode: "EXAMPLE",
+				Domain: []*router.Domain{
+					{Type: router.Domain_Domain, Value: "example.com"},
+					{Type: router.Domain_Full, Value: "example.com", Attribute: []*router.Domain_Attribute{
+						{Key: "ads", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}},
+					}},
+					{Type: router.Domain_Domain, Value: "example.org"},
+					{Type: router.Domain_Plain, Value: "google"},
+					{Type: router.Domain_Regex, Value: `^ad[0-9]+\.`},
+					{Type: router.Domain_Domain, Value: "ads.example.net", Attribute: []*router.Domain_Attribute{
+						{Key: "ads", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}},
+					}},
+				},
+			},
+		},
+	}
+	if !proto.Equal(siteList, expected) {
+		t.Error("unexpected site list: ", siteList)
+	}
+}
+
+func TestCompileSiteErrors(t *testing.T) {
+	for _, lists := range []map[string]string{
+		{"a": "bad domain.com/path"},
+		{"a": "unknown:example.com"},
+		{"a": "regexp:(["},
+		{"a": "example.com ads"},
+		{"a": "include:b"},
+		{"a": "include:b", "b": "include:a"},
+		{"a": "example.com", "A": "example.org"},
+	} {
+		if _, err := CompileSite(readers(lists)); err == nil {
+			t.Error("expect error for ", lists)
+		}
+	}
+}
+
+func TestCompileIP(t *testing.T) {
+	ipList, err := CompileIP(readers(map[string]string{
+		"private": `
+10.0.0.0/8
+10.1.2.0/24   # covered by 10.0.0.0/8
+192.168.1.7/16
+127.0.0.1
+::ffff:172.16.0.0/108
+fc00::/7
+fd00::1
+`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &router.GeoIPList{
+		Entry: []*router.GeoIP{
+			{
+				CountryCode: "PRIVATE",
+				Cidr: []*router.CIDR{
+					{Ip: []byte{10, 0, 0, 0}, Prefix: 8},
+					{Ip: []byte{127, 0, 0, 1}, Prefix: 32},
+					{Ip: []byte{172, 16, 0, 0}, Prefix: 12},
+					{Ip: []byte{192, 168, 0, 0}, Prefix: 16},
+					{Ip: []byte{0xfc, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, Prefix: 7},
+				},
+			},
+		},
+	}
+	if !proto.Equal(ipList, expected) {
+		t.Error("unexpected ip list: ", ipList)
+	}
+
+	if _, err := CompileIP(readers(map[string]string{"bad": "10.0.0.0/33"})); err == nil {
+		t.Error("expect error for invalid CIDR")
+	}
+}
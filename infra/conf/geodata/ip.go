@@ -0,0 +1,123 @@
+package geodata
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/app/router"
+)
+
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, newError("invalid IP ", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, newError("invalid CIDR ", s).Base(err)
+	}
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		ones, _ := ipNet.Mask.Size()
+		if len(ipNet.Mask) == net.IPv6len {
+			ones -= 96
+		}
+		ipNet = &net.IPNet{IP: ip4, Mask: net.CIDRMask(ones, 32)}
+	}
+	return ipNet, nil
+}
+
+func parseIPList(reader io.Reader) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	scanner := bufio.NewScanner(reader)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		ipNet, err := parseCIDR(line)
+		if err != nil {
+			return nil, newError("line ", lineNum).Base(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nets, nil
+}
+
+// dedupCIDRs sorts the networks, and drops those covered by another network.
+func dedupCIDRs(nets []*net.IPNet) []*net.IPNet {
+	sort.Slice(nets, func(i, j int) bool {
+		if len(nets[i].IP) != len(nets[j].IP) {
+			return len(nets[i].IP) < len(nets[j].IP)
+		}
+		if c := bytes.Compare(nets[i].IP, nets[j].IP); c != 0 {
+			return c < 0
+		}
+		oi, _ := nets[i].Mask.Size()
+		oj, _ := nets[j].Mask.Size()
+		return oi < oj
+	})
+
+	// After sorting, a network can only be covered by the last kept network of the same family.
+	result := make([]*net.IPNet, 0, len(nets))
+	for _, n := range nets {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if len(last.IP) == len(n.IP) && last.Contains(n.IP) {
+				continue
+			}
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// CompileIP compiles CIDR lists into a GeoIPList. Each list is a country or category, keyed by its name.
+//
+// Each line of a list is a CIDR, or a single IP address. Comments start with #.
+func CompileIP(lists map[string]io.Reader) (*router.GeoIPList, error) {
+	ipList := new(router.GeoIPList)
+	seen := make(map[string]bool, len(lists))
+	for name, reader := range lists {
+		nets, err := parseIPList(reader)
+		if err != nil {
+			return nil, newError("failed to parse CIDR list ", name).Base(err)
+		}
+		code := strings.ToUpper(name)
+		if seen[code] {
+			return nil, newError("duplicated category ", code)
+		}
+		seen[code] = true
+		geoip := &router.GeoIP{CountryCode: code}
+		for _, n := range dedupCIDRs(nets) {
+			ones, _ := n.Mask.Size()
+			geoip.Cidr = append(geoip.Cidr, &router.CIDR{
+				Ip:     []byte(n.IP),
+				Prefix: uint32(ones),
+			})
+		}
+		ipList.Entry = append(ipList.Entry, geoip)
+	}
+	sort.Slice(ipList.Entry, func(i, j int) bool {
+		return ipList.Entry[i].CountryCode < ipList.Entry[j].CountryCode
+	})
+	return ipList, nil
+}
@@ -0,0 +1,253 @@
+package geodata
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/app/router"
+)
+
+type domainEntry struct {
+	Type       router.Domain_Type
+	Value      string
+	Attributes []string
+}
+
+func (e *domainEntry) key() string {
+	return e.Type.String() + ":" + e.Value
+}
+
+// hasAttributes returns true if the entry has all the given attributes. An entry matches rules with any subset of
+// its attributes, so another entry may only be dropped for it if this returns true.
+func (e *domainEntry) hasAttributes(attrs []string) bool {
+	for _, attr := range attrs {
+		found := false
+		for _, a := range e.Attributes {
+			if a == attr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type siteList struct {
+	entries  []*domainEntry
+	includes []string
+}
+
+var domainPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+func parseDomainLine(line string) (*domainEntry, error) {
+	parts := strings.Fields(line)
+	entry := &domainEntry{Type: router.Domain_Domain}
+
+	value := parts[0]
+	if idx := strings.Index(value, ":"); idx > 0 {
+		switch value[:idx] {
+		case "domain":
+			entry.Type = router.Domain_Domain
+		case "full":
+			entry.Type = router.Domain_Full
+		case "keyword":
+			entry.Type = router.Domain_Plain
+		case "regexp":
+			entry.Type = router.Domain_Regex
+		default:
+			return nil, newError("unknown type ", value[:idx])
+		}
+		value = value[idx+1:]
+	}
+	if value == "" {
+		return nil, newError("empty value")
+	}
+
+	switch entry.Type {
+	case router.Domain_Regex:
+		if _, err := regexp.Compile(value); err != nil {
+			return nil, newError("invalid regexp ", value).Base(err)
+		}
+	case router.Domain_Plain:
+		value = strings.ToLower(value)
+	default:
+		value = strings.ToLower(value)
+		if !domainPattern.MatchString(value) {
+			return nil, newError("invalid domain ", value)
+		}
+	}
+	entry.Value = value
+
+	for _, attr := range parts[1:] {
+		if !strings.HasPrefix(attr, "@") || len(attr) == 1 {
+			return nil, newError("invalid attribute ", attr)
+		}
+		attr = strings.ToLower(attr[1:])
+		if !entry.hasAttributes([]string{attr}) {
+			entry.Attributes = append(entry.Attributes, attr)
+		}
+	}
+	return entry, nil
+}
+
+func parseSiteList(reader io.Reader) (*siteList, error) {
+	list := new(siteList)
+	scanner := bufio.NewScanner(reader)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "include:") {
+			category := strings.TrimSpace(line[len("include:"):])
+			if category == "" {
+				return nil, newError("line ", lineNum, ": empty include")
+			}
+			list.includes = append(list.includes, strings.ToUpper(category))
+			continue
+		}
+
+		entry, err := parseDomainLine(line)
+		if err != nil {
+			return nil, newError("line ", lineNum).Base(err)
+		}
+		list.entries = append(list.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// resolve returns the entries of the category, with all included categories expanded.
+func resolve(lists map[string]*siteList, category string, visiting map[string]bool) ([]*domainEntry, error) {
+	list, found := lists[category]
+	if !found {
+		return nil, newError("category ", category, " not found")
+	}
+	if visiting[category] {
+		return nil, newError("circular include of ", category)
+	}
+	visiting[category] = true
+	defer delete(visiting, category)
+
+	entries := append([]*domainEntry(nil), list.entries...)
+	for _, include := range list.includes {
+		included, err := resolve(lists, include, visiting)
+		if err != nil {
+			return nil, newError("failed to include ", include, " in ", category).Base(err)
+		}
+		entries = append(entries, included...)
+	}
+	return entries, nil
+}
+
+// dedupDomains merges duplicated entries, and drops domain and full entries that are covered by a domain entry with
+// the same attributes.
+func dedupDomains(entries []*domainEntry) []*domainEntry {
+	merged := make(map[string]*domainEntry, len(entries))
+	var unique []*domainEntry
+	for _, e := range entries {
+		if existing, found := merged[e.key()]; found {
+			// An entry without attributes matches more rules, so attributes are only kept if all duplicates have them.
+			var attrs []string
+			for _, attr := range existing.Attributes {
+				if e.hasAttributes([]string{attr}) {
+					attrs = append(attrs, attr)
+				}
+			}
+			existing.Attributes = attrs
+			continue
+		}
+		c := *e
+		merged[e.key()] = &c
+		unique = append(unique, &c)
+	}
+
+	result := make([]*domainEntry, 0, len(unique))
+	for _, e := range unique {
+		if (e.Type == router.Domain_Domain || e.Type == router.Domain_Full) && coveredByParent(merged, e) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func coveredByParent(merged map[string]*domainEntry, e *domainEntry) bool {
+	value := e.Value
+	if e.Type == router.Domain_Full {
+		if parent, found := merged[router.Domain_Domain.String()+":"+value]; found && parent.hasAttributes(e.Attributes) {
+			return true
+		}
+	}
+	for {
+		idx := strings.Index(value, ".")
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+1:]
+		if parent, found := merged[router.Domain_Domain.String()+":"+value]; found && parent.hasAttributes(e.Attributes) {
+			return true
+		}
+	}
+}
+
+// CompileSite compiles domain lists into a GeoSiteList. Each list is a category, keyed by its name.
+//
+// Lists are in the format of domain-list-community. Each line is a domain, optionally prefixed by its type, which
+// is one of domain, full, keyword and regexp, followed by attributes like @ads. A line include:name includes all
+// domains of another category. Comments start with #.
+func CompileSite(lists map[string]io.Reader) (*router.GeoSiteList, error) {
+	parsed := make(map[string]*siteList, len(lists))
+	for name, reader := range lists {
+		list, err := parseSiteList(reader)
+		if err != nil {
+			return nil, newError("failed to parse domain list ", name).Base(err)
+		}
+		category := strings.ToUpper(name)
+		if _, found := parsed[category]; found {
+			return nil, newError("duplicated category ", category)
+		}
+		parsed[category] = list
+	}
+
+	siteList := new(router.GeoSiteList)
+	for category := range parsed {
+		entries, err := resolve(parsed, category, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		site := &router.GeoSite{CountryCode: category}
+		for _, e := range dedupDomains(entries) {
+			domain := &router.Domain{
+				Type:  e.Type,
+				Value: e.Value,
+			}
+			sort.Strings(e.Attributes)
+			for _, attr := range e.Attributes {
+				domain.Attribute = append(domain.Attribute, &router.Domain_Attribute{
+					Key:        attr,
+					TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true},
+				})
+			}
+			site.Domain = append(site.Domain, domain)
+		}
+		siteList.Entry = append(siteList.Entry, site)
+	}
+	sort.Slice(siteList.Entry, func(i, j int) bool {
+		return siteList.Entry[i].CountryCode < siteList.Entry[j].CountryCode
+	})
+	return siteList, nil
+}
@@ -0,0 +1,17 @@
+package conf
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/app/netmon"
+)
+
+type NetMonConfig struct {
+	PollInterval uint32 `json:"pollInterval"`
+}
+
+func (c *NetMonConfig) Build() (proto.Message, error) {
+	return &netmon.Config{
+		PollInterval: c.PollInterval,
+	}, nil
+}
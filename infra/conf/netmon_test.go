@@ -0,0 +1,31 @@
+package conf_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/app/netmon"
+	. "v2ray.com/core/infra/conf"
+)
+
+func TestNetMonConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(NetMonConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input:  `{}`,
+			Parser: loadJSON(creator),
+			Output: &netmon.Config{},
+		},
+		{
+			Input: `{
+				"pollInterval": 10
+			}`,
+			Parser: loadJSON(creator),
+			Output: &netmon.Config{
+				PollInterval: 10,
+			},
+		},
+	})
+}
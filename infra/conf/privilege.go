@@ -0,0 +1,24 @@
+package conf
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/main/privilege"
+)
+
+type PrivilegeConfig struct {
+	User         string     `json:"user"`
+	Group        string     `json:"group"`
+	Capabilities StringList `json:"capabilities"`
+}
+
+func (c *PrivilegeConfig) Build() (proto.Message, error) {
+	if len(c.User) == 0 {
+		return nil, newError("user is not specified in privilege settings")
+	}
+	return &privilege.Config{
+		User:       c.User,
+		Group:      c.Group,
+		Capability: []string(c.Capabilities),
+	}, nil
+}
@@ -0,0 +1,30 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/infra/conf"
+	"v2ray.com/core/main/privilege"
+)
+
+func TestPrivilegeConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(PrivilegeConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"user": "v2ray",
+				"group": "nogroup",
+				"capabilities": ["net_admin"]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &privilege.Config{
+				User:       "v2ray",
+				Group:      "nogroup",
+				Capability: []string{"net_admin"},
+			},
+		},
+	})
+}
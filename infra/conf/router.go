@@ -391,6 +391,7 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 		Network    *NetworkList `json:"network"`
 		SourceIP   *StringList  `json:"source"`
 		SourcePort *PortList    `json:"sourcePort"`
+		LocalPort  *PortList    `json:"localPort"`
 		User       *StringList  `json:"user"`
 		InboundTag *StringList  `json:"inboundTag"`
 		Protocols  *StringList  `json:"protocol"`
@@ -453,6 +454,10 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 		rule.SourcePortList = rawFieldRule.SourcePort.Build()
 	}
 
+	if rawFieldRule.LocalPort != nil {
+		rule.LocalPortList = rawFieldRule.LocalPort.Build()
+	}
+
 	if rawFieldRule.User != nil {
 		for _, s := range *rawFieldRule.User {
 			rule.UserEmail = append(rule.UserEmail, s)
@@ -28,49 +28,117 @@ func cipherFromString(c string) shadowsocks.CipherType {
 		return shadowsocks.CipherType_CHACHA20_POLY1305
 	case "none", "plain":
 		return shadowsocks.CipherType_NONE
+	case "2022-blake3-aes-128-gcm":
+		return shadowsocks.CipherType_BLAKE3_AES_128_GCM
+	case "2022-blake3-aes-256-gcm":
+		return shadowsocks.CipherType_BLAKE3_AES_256_GCM
 	default:
 		return shadowsocks.CipherType_UNKNOWN
 	}
 }
 
+func isAEADCipher(c shadowsocks.CipherType) bool {
+	switch c {
+	case shadowsocks.CipherType_AES_128_GCM, shadowsocks.CipherType_AES_256_GCM, shadowsocks.CipherType_CHACHA20_POLY1305:
+		return true
+	default:
+		return false
+	}
+}
+
+func isAEAD2022Cipher(c shadowsocks.CipherType) bool {
+	return c == shadowsocks.CipherType_BLAKE3_AES_128_GCM || c == shadowsocks.CipherType_BLAKE3_AES_256_GCM
+}
+
+type ShadowsocksUserConfig struct {
+	Cipher   string `json:"method"`
+	Password string `json:"password"`
+	Level    byte   `json:"level"`
+	Email    string `json:"email"`
+}
+
 type ShadowsocksServerConfig struct {
-	Cipher      string       `json:"method"`
-	Password    string       `json:"password"`
-	UDP         bool         `json:"udp"`
-	Level       byte         `json:"level"`
-	Email       string       `json:"email"`
-	OTA         *bool        `json:"ota"`
-	NetworkList *NetworkList `json:"network"`
+	Cipher      string                   `json:"method"`
+	Password    string                   `json:"password"`
+	UDP         bool                     `json:"udp"`
+	Level       byte                     `json:"level"`
+	Email       string                   `json:"email"`
+	OTA         *bool                    `json:"ota"`
+	NetworkList *NetworkList             `json:"network"`
+	Clients     []*ShadowsocksUserConfig `json:"clients"`
+	Plugin      string                   `json:"plugin"`
+	PluginOpts  string                   `json:"pluginOpts"`
 }
 
 func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 	config := new(shadowsocks.ServerConfig)
 	config.UdpEnabled = v.UDP
 	config.Network = v.NetworkList.Build()
+	config.Plugin = v.Plugin
+	config.PluginOpts = v.PluginOpts
 
-	if v.Password == "" {
+	if v.Password == "" && len(v.Clients) == 0 {
 		return nil, newError("Shadowsocks password is not specified.")
 	}
-	account := &shadowsocks.Account{
-		Password: v.Password,
-		Ota:      shadowsocks.Account_Auto,
-	}
+	ota := shadowsocks.Account_Auto
 	if v.OTA != nil {
 		if *v.OTA {
-			account.Ota = shadowsocks.Account_Enabled
+			ota = shadowsocks.Account_Enabled
 		} else {
-			account.Ota = shadowsocks.Account_Disabled
+			ota = shadowsocks.Account_Disabled
 		}
 	}
-	account.CipherType = cipherFromString(v.Cipher)
-	if account.CipherType == shadowsocks.CipherType_UNKNOWN {
-		return nil, newError("unknown cipher method: ", v.Cipher)
+
+	if v.Password != "" {
+		account := &shadowsocks.Account{
+			Password: v.Password,
+			Ota:      ota,
+		}
+		account.CipherType = cipherFromString(v.Cipher)
+		if account.CipherType == shadowsocks.CipherType_UNKNOWN {
+			return nil, newError("unknown cipher method: ", v.Cipher)
+		}
+
+		config.User = &protocol.User{
+			Email:   v.Email,
+			Level:   uint32(v.Level),
+			Account: serial.ToTypedMessage(account),
+		}
 	}
 
-	config.User = &protocol.User{
-		Email:   v.Email,
-		Level:   uint32(v.Level),
-		Account: serial.ToTypedMessage(account),
+	// With Shadowsocks 2022 ciphers, the password of the server is its identity key, and clients are identified by
+	// identity headers.
+	identified := config.User != nil && isAEAD2022Cipher(cipherFromString(v.Cipher))
+	allAEAD := config.User == nil || isAEADCipher(cipherFromString(v.Cipher))
+	for _, client := range v.Clients {
+		if client.Password == "" {
+			return nil, newError("Shadowsocks password is not specified.")
+		}
+		cipher := client.Cipher
+		if cipher == "" {
+			cipher = v.Cipher
+		}
+		account := &shadowsocks.Account{
+			Password:   client.Password,
+			CipherType: cipherFromString(cipher),
+			Ota:        ota,
+		}
+		if account.CipherType == shadowsocks.CipherType_UNKNOWN {
+			return nil, newError("unknown cipher method: ", cipher)
+		}
+		if identified && account.CipherType != cipherFromString(v.Cipher) {
+			return nil, newError("Shadowsocks: clients must use the cipher of the server with Shadowsocks 2022")
+		}
+		allAEAD = allAEAD && isAEADCipher(account.CipherType)
+
+		config.Users = append(config.Users, &protocol.User{
+			Email:   client.Email,
+			Level:   uint32(client.Level),
+			Account: serial.ToTypedMessage(account),
+		})
+	}
+	if len(config.AllUsers()) > 1 && !allAEAD && !identified {
+		return nil, newError("Shadowsocks: multiple users are only supported with AEAD ciphers")
 	}
 
 	return config, nil
@@ -87,7 +155,9 @@ type ShadowsocksServerTarget struct {
 }
 
 type ShadowsocksClientConfig struct {
-	Servers []*ShadowsocksServerTarget `json:"servers"`
+	Servers    []*ShadowsocksServerTarget `json:"servers"`
+	Plugin     string                     `json:"plugin"`
+	PluginOpts string                     `json:"pluginOpts"`
 }
 
 func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
@@ -136,6 +206,8 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 	}
 
 	config.Server = serverSpecs
+	config.Plugin = v.Plugin
+	config.PluginOpts = v.PluginOpts
 
 	return config, nil
 }
@@ -32,5 +32,139 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 				Network: []net.Network{net.Network_TCP},
 			},
 		},
+		{
+			Input: `{
+				"method": "aes-128-gcm",
+				"clients": [
+					{
+						"password": "password-a",
+						"email": "a@v2fly.org"
+					},
+					{
+						"method": "chacha20-poly1305",
+						"password": "password-b",
+						"email": "b@v2fly.org",
+						"level": 1
+					}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				Users: []*protocol.User{
+					{
+						Email: "a@v2fly.org",
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_AES_128_GCM,
+							Password:   "password-a",
+						}),
+					},
+					{
+						Email: "b@v2fly.org",
+						Level: 1,
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_CHACHA20_POLY1305,
+							Password:   "password-b",
+						}),
+					},
+				},
+				Network: []net.Network{net.Network_TCP},
+			},
+		},
+		{
+			Input: `{
+				"method": "2022-blake3-aes-128-gcm",
+				"password": "AAECAwQFBgcICQoLDA0ODw==",
+				"clients": [
+					{
+						"password": "EBESExQVFhcYGRobHB0eHw==",
+						"email": "a@v2fly.org"
+					},
+					{
+						"password": "ICEiIyQlJicoKSorLC0uLw==",
+						"email": "b@v2fly.org"
+					}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				User: &protocol.User{
+					Account: serial.ToTypedMessage(&shadowsocks.Account{
+						CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM,
+						Password:   "AAECAwQFBgcICQoLDA0ODw==",
+					}),
+				},
+				Users: []*protocol.User{
+					{
+						Email: "a@v2fly.org",
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM,
+							Password:   "EBESExQVFhcYGRobHB0eHw==",
+						}),
+					},
+					{
+						Email: "b@v2fly.org",
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM,
+							Password:   "ICEiIyQlJicoKSorLC0uLw==",
+						}),
+					},
+				},
+				Network: []net.Network{net.Network_TCP},
+			},
+		},
+		{
+			Input: `{
+				"method": "aes-256-gcm",
+				"password": "v2ray-password",
+				"plugin": "obfs-server",
+				"pluginOpts": "obfs=http"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				User: &protocol.User{
+					Account: serial.ToTypedMessage(&shadowsocks.Account{
+						CipherType: shadowsocks.CipherType_AES_256_GCM,
+						Password:   "v2ray-password",
+					}),
+				},
+				Network:    []net.Network{net.Network_TCP},
+				Plugin:     "obfs-server",
+				PluginOpts: "obfs=http",
+			},
+		},
 	})
 }
+
+func TestShadowsocksServerConfigMultipleStreamCiphers(t *testing.T) {
+	_, err := loadJSON(func() Buildable { return new(ShadowsocksServerConfig) })(`{
+		"method": "aes-128-cfb",
+		"password": "password",
+		"clients": [
+			{
+				"method": "aes-128-gcm",
+				"password": "password-a",
+				"email": "a@v2fly.org"
+			}
+		]
+	}`)
+	if err == nil {
+		t.Error("expected error for multiple users with stream cipher")
+	}
+}
+
+func TestShadowsocksServerConfig2022MixedCiphers(t *testing.T) {
+	_, err := loadJSON(func() Buildable { return new(ShadowsocksServerConfig) })(`{
+		"method": "2022-blake3-aes-128-gcm",
+		"password": "AAECAwQFBgcICQoLDA0ODw==",
+		"clients": [
+			{
+				"method": "aes-128-gcm",
+				"password": "password-a",
+				"email": "a@v2fly.org"
+			}
+		]
+	}`)
+	if err == nil {
+		t.Error("expected error for clients of another cipher")
+	}
+}
@@ -0,0 +1,201 @@
+package share
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// Version 1-M symbol of "HELLO WORLD" in alphanumeric mode.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if ecc := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(ecc, expected) {
+		t.Error("unexpected error correction codewords: ", ecc)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	expected := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	q, err := encodeQR([]byte("v2ray"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mask, bits := range expected {
+		q.drawFormatBits(mask)
+		if actual := readFormatBits(q); actual != bits {
+			t.Error("mask ", mask, ": expect format bits ", bits, ", but got ", actual)
+		}
+	}
+}
+
+// readFormatBits reads the copy of format bits around the top left finder pattern, from the most significant bit.
+func readFormatBits(q *qrCode) string {
+	var positions [][2]int
+	for i := 0; i <= 5; i++ {
+		positions = append(positions, [2]int{8, i})
+	}
+	positions = append(positions, [2]int{8, 7}, [2]int{8, 8}, [2]int{7, 8})
+	for i := 9; i < 15; i++ {
+		positions = append(positions, [2]int{14 - i, 8})
+	}
+
+	bits := make([]byte, 15)
+	for i, p := range positions {
+		bits[14-i] = '0'
+		if q.modules[p[1]][p[0]] {
+			bits[14-i] = '1'
+		}
+	}
+	return string(bits)
+}
+
+// decodeQR decodes a symbol, and verifies error correction codewords by their syndromes.
+func decodeQR(t *testing.T, q *qrCode) []byte {
+	bits := 0
+	for _, c := range readFormatBits(q) {
+		bits = bits<<1 | int(c-'0')
+	}
+	bits ^= 0x5412
+	if bits>>13 != eccFormatBits {
+		t.Fatal("unexpected error correction level")
+	}
+	mask := (bits >> 10) & 7
+
+	// Recover the data modules, by removing the mask from a copy.
+	clean := &qrCode{version: q.version, size: q.size}
+	clean.modules = make([][]bool, q.size)
+	clean.isFunction = make([][]bool, q.size)
+	for i := range q.modules {
+		clean.modules[i] = make([]bool, q.size)
+		clean.isFunction[i] = make([]bool, q.size)
+	}
+	clean.drawFunctionPatterns()
+	for y := range q.modules {
+		for x := range q.modules[y] {
+			if !clean.isFunction[y][x] {
+				clean.modules[y][x] = q.modules[y][x]
+			}
+		}
+	}
+	clean.applyMask(mask)
+
+	var codewords []byte
+	var current byte
+	numBits := 0
+	for col := q.size - 1; col > 0; col -= 2 {
+		if col == 6 {
+			col--
+		}
+		for row := 0; row < q.size; row++ {
+			y := row
+			if ((q.size-1-col)/2)%2 == 0 && col > 6 || ((q.size-2-col)/2)%2 == 0 && col < 6 {
+				y = q.size - 1 - row
+			}
+			for _, x := range []int{col, col - 1} {
+				if clean.isFunction[y][x] {
+					continue
+				}
+				current <<= 1
+				if clean.modules[y][x] {
+					current |= 1
+				}
+				numBits++
+				if numBits%8 == 0 {
+					codewords = append(codewords, current)
+					current = 0
+				}
+			}
+		}
+	}
+	rawCodewords := numRawDataModules(q.version) / 8
+	if len(codewords) != rawCodewords {
+		t.Fatal("expect ", rawCodewords, " codewords, but got ", len(codewords))
+	}
+
+	numBlocks := eccBlocks[q.version]
+	eccLen := eccCodewordsPerBlock[q.version]
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortDataLen := rawCodewords/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortDataLen; i++ {
+		for j := range blocks {
+			if i == shortDataLen && j < numShortBlocks {
+				continue
+			}
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for j, block := range blocks {
+		root := byte(1)
+		for i := 0; i < eccLen; i++ {
+			syndrome := byte(0)
+			for _, c := range block {
+				syndrome = gfMultiply(syndrome, root) ^ c
+			}
+			if syndrome != 0 {
+				t.Fatal("block ", j, ": non-zero syndrome ", i)
+			}
+			root = gfMultiply(root, 2)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	var bb bitBuffer
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v <<= 1
+			if bb[i] {
+				v |= 1
+			}
+		}
+		bb = bb[n:]
+		return v
+	}
+	if mode := read(4); mode != 4 {
+		t.Fatal("unexpected mode ", mode)
+	}
+	length := read(8)
+	if q.version >= 10 {
+		length = length<<8 | read(8)
+	}
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = byte(read(8))
+	}
+	return result
+}
+
+func TestEncodeQR(t *testing.T) {
+	for _, length := range []int{1, 14, 100, 213, 500, 1000, 2331} {
+		text := strings.Repeat("vmess://v2ray.com/", length/18+1)[:length]
+		q, err := encodeQR([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded := decodeQR(t, q); string(decoded) != text {
+			t.Error("version ", q.version, ": expect ", text, ", but got ", string(decoded))
+		}
+	}
+
+	if _, err := encodeQR(make([]byte, 2332)); err == nil {
+		t.Error("expect error for data too long")
+	}
+}
@@ -0,0 +1,364 @@
+// Package share builds share links of inbound users, for clients to import, and renders them as QR codes.
+//
+// Links are derived from the built config of the inbound, so that they always match what the server accepts.
+// Supported schemes are vmess:// in the format of V2RayN, vless:// and ss:// in the format of SIP002.
+package share
+
+//go:generate errorgen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/proxy/vless"
+	vlessInbound "v2ray.com/core/proxy/vless/inbound"
+	"v2ray.com/core/proxy/vmess"
+	vmessInbound "v2ray.com/core/proxy/vmess/inbound"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/http"
+	"v2ray.com/core/transport/internet/httpupgrade"
+	"v2ray.com/core/transport/internet/kcp"
+	"v2ray.com/core/transport/internet/quic"
+	"v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/websocket"
+)
+
+// headerTypes maps messages of packet and TCP headers to their names in share links.
+var headerTypes = map[string]string{
+	"v2ray.core.transport.internet.headers.noop.Config":               "none",
+	"v2ray.core.transport.internet.headers.noop.ConnectionConfig":     "none",
+	"v2ray.core.transport.internet.headers.http.Config":               "http",
+	"v2ray.core.transport.internet.headers.srtp.Config":               "srtp",
+	"v2ray.core.transport.internet.headers.utp.Config":                "utp",
+	"v2ray.core.transport.internet.headers.wechat.VideoConfig":        "wechat-video",
+	"v2ray.core.transport.internet.headers.tls.PacketConfig":          "dtls",
+	"v2ray.core.transport.internet.headers.wireguard.WireguardConfig": "wireguard",
+}
+
+// transport is the stream settings of an inbound, in the terms of share links.
+type transport struct {
+	network    string
+	headerType string
+	host       string
+	path       string
+	// Seed of mKCP, or key of QUIC.
+	key string
+	// Encryption of QUIC.
+	security string
+
+	tls  bool
+	sni  string
+	alpn []string
+}
+
+func headerType(header interface{ GetType() string }) string {
+	if header == nil || header.GetType() == "" {
+		return "none"
+	}
+	if name, found := headerTypes[header.GetType()]; found {
+		return name
+	}
+	return "none"
+}
+
+// normalizedPath returns the path of WebSocket and HTTP/2 as the transports send it.
+func normalizedPath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
+
+func newTransport(stream *internet.StreamConfig) (*transport, error) {
+	t := &transport{
+		network:    "tcp",
+		headerType: "none",
+	}
+	settings, err := stream.GetEffectiveTransportSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	switch s := settings.(type) {
+	case *tcp.Config:
+		if h := s.GetHeaderSettings(); h != nil {
+			t.headerType = headerType(h)
+		}
+	case *kcp.Config:
+		t.network = "kcp"
+		if h := s.GetHeaderConfig(); h != nil {
+			t.headerType = headerType(h)
+		}
+		t.key = s.GetSeed().GetSeed()
+	case *websocket.Config:
+		t.network = "ws"
+		t.path = normalizedPath(s.Path)
+		for _, h := range s.Header {
+			if strings.EqualFold(h.Key, "Host") {
+				t.host = h.Value
+			}
+		}
+	case *httpupgrade.Config:
+		t.network = "httpupgrade"
+		t.host = s.Host
+		t.path = normalizedPath(s.Path)
+	case *http.Config:
+		t.network = "h2"
+		t.host = strings.Join(s.Host, ",")
+		t.path = normalizedPath(s.Path)
+	case *quic.Config:
+		t.network = "quic"
+		if h := s.GetHeader(); h != nil {
+			t.headerType = headerType(h)
+		}
+		t.key = s.Key
+		t.security = strings.ToLower(s.GetSecurity().GetType().String())
+		if t.security == "" || t.security == "unknown" {
+			t.security = "none"
+		}
+		t.security = strings.Replace(t.security, "aes128_gcm", "aes-128-gcm", 1)
+		t.security = strings.Replace(t.security, "chacha20_poly1305", "chacha20-poly1305", 1)
+	default:
+		return nil, newError("transport ", stream.GetEffectiveProtocol(), " can't be shared")
+	}
+
+	if stream != nil && stream.HasSecuritySettings() {
+		security, err := stream.GetEffectiveSecuritySettings()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, ok := security.(*tls.Config)
+		if !ok {
+			return nil, newError("security ", stream.SecurityType, " can't be shared")
+		}
+		t.tls = true
+		t.sni = tlsConfig.ServerName
+		t.alpn = tlsConfig.NextProtocol
+	}
+	return t, nil
+}
+
+// Options are the settings of share links that are not in the config of the inbound.
+type Options struct {
+	// Address of the server that clients connect to. Defaults to the server name in TLS settings.
+	Address string
+	// Remark of the link. Defaults to the email of the user.
+	Remark string
+}
+
+// Link returns the share link of a user in the inbound. The user is selected by email. The email may be empty, if the
+// inbound has only one user.
+func Link(inbound *core.InboundHandlerConfig, email string, options Options) (string, error) {
+	rawReceiver, err := inbound.ReceiverSettings.GetInstance()
+	if err != nil {
+		return "", err
+	}
+	receiver, ok := rawReceiver.(*proxyman.ReceiverConfig)
+	if !ok {
+		return "", newError("inbound ", inbound.Tag, " doesn't listen on a port")
+	}
+	ports := receiver.GetEffectivePorts()
+	if len(ports) == 0 {
+		return "", newError("inbound ", inbound.Tag, " has no port")
+	}
+	port := uint32(ports[0])
+
+	t, err := newTransport(receiver.StreamSettings)
+	if err != nil {
+		return "", err
+	}
+	address := options.Address
+	if address == "" {
+		address = t.sni
+	}
+	if address == "" {
+		return "", newError("server address must be specified")
+	}
+
+	rawProxy, err := inbound.ProxySettings.GetInstance()
+	if err != nil {
+		return "", err
+	}
+	var users []*protocol.User
+	switch config := rawProxy.(type) {
+	case *vmessInbound.Config:
+		users = config.User
+	case *vlessInbound.Config:
+		users = config.Clients
+	case *shadowsocks.ServerConfig:
+		users = config.AllUsers()
+	default:
+		return "", newError("inbound ", inbound.Tag, " has no user to share")
+	}
+	user, err := findUser(users, email)
+	if err != nil {
+		return "", newError("inbound ", inbound.Tag).Base(err)
+	}
+	remark := options.Remark
+	if remark == "" {
+		remark = user.Email
+	}
+
+	account, err := user.Account.GetInstance()
+	if err != nil {
+		return "", err
+	}
+	switch account := account.(type) {
+	case *vmess.Account:
+		return vmessLink(account, address, port, t, remark)
+	case *vless.Account:
+		return vlessLink(account, address, port, t, remark), nil
+	case *shadowsocks.Account:
+		return shadowsocksLink(account, address, port, t, remark)
+	default:
+		return "", newError("account of ", user.Email, " can't be shared")
+	}
+}
+
+func findUser(users []*protocol.User, email string) (*protocol.User, error) {
+	if email == "" {
+		if len(users) != 1 {
+			return nil, newError("email must be specified, as there are ", len(users), " users")
+		}
+		return users[0], nil
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, newError("user ", email, " not found")
+}
+
+func vmessLink(account *vmess.Account, address string, port uint32, t *transport, remark string) (string, error) {
+	link := map[string]string{
+		"v":    "2",
+		"ps":   remark,
+		"add":  address,
+		"port": strconv.Itoa(int(port)),
+		"id":   account.Id,
+		"aid":  strconv.Itoa(int(account.AlterId)),
+		"scy":  "auto",
+		"net":  t.network,
+		"type": t.headerType,
+		"host": t.host,
+		"path": t.path,
+		"tls":  "",
+		"sni":  t.sni,
+		"alpn": strings.Join(t.alpn, ","),
+	}
+	switch t.network {
+	case "kcp":
+		link["path"] = t.key
+	case "quic":
+		link["host"] = t.security
+		link["path"] = t.key
+	}
+	if t.tls {
+		link["tls"] = "tls"
+	}
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// query returns the standard query parameters of a transport, shared by vless:// and other URL schemes.
+func (t *transport) query() url.Values {
+	q := url.Values{}
+	q.Set("type", t.network)
+	switch t.network {
+	case "tcp":
+		if t.headerType != "none" {
+			q.Set("headerType", t.headerType)
+		}
+	case "kcp":
+		q.Set("headerType", t.headerType)
+		if t.key != "" {
+			q.Set("seed", t.key)
+		}
+	case "ws", "h2", "httpupgrade":
+		if t.host != "" {
+			q.Set("host", t.host)
+		}
+		q.Set("path", t.path)
+	case "quic":
+		q.Set("headerType", t.headerType)
+		q.Set("quicSecurity", t.security)
+		if t.key != "" {
+			q.Set("key", t.key)
+		}
+	}
+	if t.tls {
+		q.Set("security", "tls")
+		if t.sni != "" {
+			q.Set("sni", t.sni)
+		}
+		if len(t.alpn) > 0 {
+			q.Set("alpn", strings.Join(t.alpn, ","))
+		}
+	}
+	return q
+}
+
+func vlessLink(account *vless.Account, address string, port uint32, t *transport, remark string) string {
+	q := t.query()
+	q.Set("encryption", "none")
+	if account.Flow != "" {
+		q.Set("flow", account.Flow)
+	}
+	u := url.URL{
+		Scheme:   "vless",
+		User:     url.User(account.Id),
+		Host:     hostPort(address, port),
+		RawQuery: q.Encode(),
+		Fragment: remark,
+	}
+	return u.String()
+}
+
+// shadowsocksMethods are the names of ciphers in SIP002.
+var shadowsocksMethods = map[shadowsocks.CipherType]string{
+	shadowsocks.CipherType_AES_128_CFB:       "aes-128-cfb",
+	shadowsocks.CipherType_AES_256_CFB:       "aes-256-cfb",
+	shadowsocks.CipherType_CHACHA20:          "chacha20",
+	shadowsocks.CipherType_CHACHA20_IETF:     "chacha20-ietf",
+	shadowsocks.CipherType_AES_128_GCM:       "aes-128-gcm",
+	shadowsocks.CipherType_AES_256_GCM:       "aes-256-gcm",
+	shadowsocks.CipherType_CHACHA20_POLY1305: "chacha20-ietf-poly1305",
+	shadowsocks.CipherType_NONE:              "none",
+}
+
+func shadowsocksLink(account *shadowsocks.Account, address string, port uint32, t *transport, remark string) (string, error) {
+	if t.network != "tcp" || t.headerType != "none" || t.tls {
+		return "", newError("Shadowsocks over transport ", t.network, " can't be shared")
+	}
+	method, found := shadowsocksMethods[account.CipherType]
+	if !found {
+		return "", newError("unknown cipher ", account.CipherType)
+	}
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(method + ":" + account.Password))
+	u := url.URL{
+		Scheme:   "ss",
+		User:     url.User(userInfo),
+		Host:     hostPort(address, port),
+		Fragment: remark,
+	}
+	return u.String(), nil
+}
+
+func hostPort(address string, port uint32) string {
+	if strings.Contains(address, ":") && !strings.HasPrefix(address, "[") {
+		address = "[" + address + "]"
+	}
+	return address + ":" + strconv.Itoa(int(port))
+}
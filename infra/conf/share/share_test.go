@@ -0,0 +1,149 @@
+package share_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/infra/conf"
+	. "v2ray.com/core/infra/conf/share"
+)
+
+func buildInbound(s string) *conf.InboundDetourConfig {
+	config := new(conf.InboundDetourConfig)
+	common.Must(json.Unmarshal([]byte(s), config))
+	return config
+}
+
+func TestVMessLink(t *testing.T) {
+	config, err := buildInbound(`{
+		"protocol": "vmess",
+		"port": 443,
+		"settings": {
+			"clients": [
+				{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "alterId": 4, "email": "love@v2fly.org"},
+				{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "email": "other@v2fly.org"}
+			]
+		},
+		"streamSettings": {
+			"network": "ws",
+			"wsSettings": {"path": "/ws", "headers": {"Host": "cdn.v2fly.org"}},
+			"security": "tls",
+			"tlsSettings": {"serverName": "v2fly.org"}
+		}
+	}`).Build()
+	common.Must(err)
+
+	link, err := Link(config, "love@v2fly.org", Options{})
+	common.Must(err)
+	if !strings.HasPrefix(link, "vmess://") {
+		t.Fatal("unexpected link ", link)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(link, "vmess://"))
+	common.Must(err)
+	var fields map[string]string
+	common.Must(json.Unmarshal(data, &fields))
+	expected := map[string]string{
+		"ps":   "love@v2fly.org",
+		"add":  "v2fly.org",
+		"port": "443",
+		"id":   "27848739-7e62-4138-9fd3-098a63964b6b",
+		"aid":  "4",
+		"net":  "ws",
+		"host": "cdn.v2fly.org",
+		"path": "/ws",
+		"tls":  "tls",
+		"sni":  "v2fly.org",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Error("expect ", key, " to be ", value, ", but got ", fields[key])
+		}
+	}
+
+	if _, err := Link(config, "", Options{}); err == nil {
+		t.Error("expect error for unspecified user of multiple")
+	}
+	if _, err := Link(config, "nobody@v2fly.org", Options{}); err == nil {
+		t.Error("expect error for unknown user")
+	}
+}
+
+func TestVLessLink(t *testing.T) {
+	config, err := buildInbound(`{
+		"protocol": "vless",
+		"port": 10086,
+		"settings": {
+			"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "email": "love@v2fly.org"}],
+			"decryption": "none"
+		},
+		"streamSettings": {
+			"network": "kcp",
+			"kcpSettings": {"header": {"type": "wechat-video"}, "seed": "v2ray"}
+		}
+	}`).Build()
+	common.Must(err)
+
+	link, err := Link(config, "", Options{Address: "1.2.3.4", Remark: "my server"})
+	common.Must(err)
+	const expected = "vless://27848739-7e62-4138-9fd3-098a63964b6b@1.2.3.4:10086?encryption=none&headerType=wechat-video&seed=v2ray&type=kcp#my%20server"
+	if link != expected {
+		t.Error("expect ", expected, ", but got ", link)
+	}
+
+	if _, err := Link(config, "", Options{}); err == nil {
+		t.Error("expect error for unknown address")
+	}
+}
+
+func TestVLessLinkWithHTTPUpgrade(t *testing.T) {
+	config, err := buildInbound(`{
+		"protocol": "vless",
+		"port": 443,
+		"settings": {
+			"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b"}],
+			"decryption": "none"
+		},
+		"streamSettings": {
+			"network": "httpupgrade",
+			"httpupgradeSettings": {"path": "up", "host": "www.v2ray.com"}
+		}
+	}`).Build()
+	common.Must(err)
+
+	link, err := Link(config, "", Options{Address: "1.2.3.4"})
+	common.Must(err)
+	const expected = "vless://27848739-7e62-4138-9fd3-098a63964b6b@1.2.3.4:443?encryption=none&host=www.v2ray.com&path=%2Fup&type=httpupgrade"
+	if link != expected {
+		t.Error("expect ", expected, ", but got ", link)
+	}
+}
+
+func TestShadowsocksLink(t *testing.T) {
+	config, err := buildInbound(`{
+		"protocol": "shadowsocks",
+		"port": 8388,
+		"settings": {"method": "aes-256-gcm", "password": "v2ray-password", "email": "love@v2fly.org"}
+	}`).Build()
+	common.Must(err)
+
+	link, err := Link(config, "", Options{Address: "::1"})
+	common.Must(err)
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte("aes-256-gcm:v2ray-password"))
+	expected := "ss://" + userInfo + "@[::1]:8388#love@v2fly.org"
+	if link != expected {
+		t.Error("expect ", expected, ", but got ", link)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	code, err := QRCode("v2fly.org")
+	common.Must(err)
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	// A version 1 symbol with 21 modules and quiet zones of 2 modules, rendered 2 rows per line.
+	if len(lines) != 13 {
+		t.Error("expect 13 lines, but got ", len(lines))
+	}
+}
@@ -7,12 +7,14 @@ import (
 )
 
 type TransportConfig struct {
-	TCPConfig  *TCPConfig          `json:"tcpSettings"`
-	KCPConfig  *KCPConfig          `json:"kcpSettings"`
-	WSConfig   *WebSocketConfig    `json:"wsSettings"`
-	HTTPConfig *HTTPConfig         `json:"httpSettings"`
-	DSConfig   *DomainSocketConfig `json:"dsSettings"`
-	QUICConfig *QUICConfig         `json:"quicSettings"`
+	TCPConfig         *TCPConfig          `json:"tcpSettings"`
+	KCPConfig         *KCPConfig          `json:"kcpSettings"`
+	WSConfig          *WebSocketConfig    `json:"wsSettings"`
+	HTTPUpgradeConfig *HTTPUpgradeConfig  `json:"httpupgradeSettings"`
+	ICMPConfig        *ICMPConfig         `json:"icmpSettings"`
+	HTTPConfig        *HTTPConfig         `json:"httpSettings"`
+	DSConfig          *DomainSocketConfig `json:"dsSettings"`
+	QUICConfig        *QUICConfig         `json:"quicSettings"`
 }
 
 // Build implements Buildable.
@@ -52,6 +54,28 @@ func (c *TransportConfig) Build() (*transport.Config, error) {
 		})
 	}
 
+	if c.HTTPUpgradeConfig != nil {
+		ts, err := c.HTTPUpgradeConfig.Build()
+		if err != nil {
+			return nil, newError("failed to build HTTP Upgrade config").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "httpupgrade",
+			Settings:     serial.ToTypedMessage(ts),
+		})
+	}
+
+	if c.ICMPConfig != nil {
+		ts, err := c.ICMPConfig.Build()
+		if err != nil {
+			return nil, newError("failed to build ICMP config").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "icmp",
+			Settings:     serial.ToTypedMessage(ts),
+		})
+	}
+
 	if c.HTTPConfig != nil {
 		ts, err := c.HTTPConfig.Build()
 		if err != nil {
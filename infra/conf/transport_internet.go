@@ -1,7 +1,11 @@
 package conf
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/golang/protobuf/proto"
@@ -11,6 +15,8 @@ import (
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/domainsocket"
 	"v2ray.com/core/transport/internet/http"
+	"v2ray.com/core/transport/internet/httpupgrade"
+	"v2ray.com/core/transport/internet/icmptunnel"
 	"v2ray.com/core/transport/internet/kcp"
 	"v2ray.com/core/transport/internet/quic"
 	"v2ray.com/core/transport/internet/tcp"
@@ -34,16 +40,87 @@ var (
 	}, "type", "")
 )
 
+type KCPFECConfig struct {
+	DataShards   uint32 `json:"dataShards"`
+	ParityShards uint32 `json:"parityShards"`
+}
+
+type KCPLossControlConfig struct {
+	Decrease  uint32 `json:"decrease"`
+	Increase  uint32 `json:"increase"`
+	MinWindow uint32 `json:"minWindow"`
+	MaxWindow uint32 `json:"maxWindow"`
+}
+
+// Build implements Buildable.
+func (c *KCPLossControlConfig) Build() (*kcp.LossControl, error) {
+	if c.Decrease >= 100 {
+		return nil, newError("invalid mKCP loss control decrease: ", c.Decrease).AtError()
+	}
+	if c.Increase > 100 {
+		return nil, newError("invalid mKCP loss control increase: ", c.Increase).AtError()
+	}
+	if c.MaxWindow > 0 && c.MinWindow > c.MaxWindow {
+		return nil, newError("mKCP loss control min window ", c.MinWindow, " is larger than max ", c.MaxWindow).AtError()
+	}
+	return &kcp.LossControl{
+		Decrease:  c.Decrease,
+		Increase:  c.Increase,
+		MinWindow: c.MinWindow,
+		MaxWindow: c.MaxWindow,
+	}, nil
+}
+
+type KCPBackoffConfig struct {
+	Factor     uint32 `json:"factor"`
+	MaxTimeout uint32 `json:"maxTimeout"`
+}
+
+// Build implements Buildable.
+func (c *KCPBackoffConfig) Build() (*kcp.RetransmitBackoff, error) {
+	if c.Factor != 0 && (c.Factor < 100 || c.Factor > 1000) {
+		return nil, newError("invalid mKCP retransmit backoff factor: ", c.Factor).AtError()
+	}
+	return &kcp.RetransmitBackoff{
+		Factor:     c.Factor,
+		MaxTimeout: c.MaxTimeout,
+	}, nil
+}
+
 type KCPConfig struct {
-	Mtu             *uint32         `json:"mtu"`
-	Tti             *uint32         `json:"tti"`
-	UpCap           *uint32         `json:"uplinkCapacity"`
-	DownCap         *uint32         `json:"downlinkCapacity"`
-	Congestion      *bool           `json:"congestion"`
-	ReadBufferSize  *uint32         `json:"readBufferSize"`
-	WriteBufferSize *uint32         `json:"writeBufferSize"`
-	HeaderConfig    json.RawMessage `json:"header"`
-	Seed            *string         `json:"seed"`
+	Mtu               *uint32               `json:"mtu"`
+	Tti               *uint32               `json:"tti"`
+	UpCap             *uint32               `json:"uplinkCapacity"`
+	DownCap           *uint32               `json:"downlinkCapacity"`
+	Congestion        *bool                 `json:"congestion"`
+	CongestionControl string                `json:"congestionControl"`
+	AckRange          bool                  `json:"ackRange"`
+	FEC               *KCPFECConfig         `json:"fec"`
+	Pacing            bool                  `json:"pacing"`
+	MTUDiscovery      bool                  `json:"mtuDiscovery"`
+	Metrics           bool                  `json:"metrics"`
+	FastResend        uint32                `json:"fastResend"`
+	FastAckLimit      uint32                `json:"fastAckLimit"`
+	RTOMin            uint32                `json:"rtoMin"`
+	RTOMax            uint32                `json:"rtoMax"`
+	AutoTuneWindow    bool                  `json:"autoTuneWindow"`
+	MaxRetransmit     uint32                `json:"maxRetransmit"`
+	Migration         bool                  `json:"migration"`
+	ECN               bool                  `json:"ecn"`
+	Obfuscation       string                `json:"obfuscation"`
+	AckDelay          uint32                `json:"ackDelay"`
+	AckNoDelay        uint32                `json:"ackNoDelayThreshold"`
+	Multiplex         bool                  `json:"multiplex"`
+	Profile           string                `json:"profile"`
+	LossControl       *KCPLossControlConfig `json:"lossControl"`
+	IdleTimeout       uint32                `json:"idleTimeout"`
+	Backoff           *KCPBackoffConfig     `json:"retransmitBackoff"`
+	KeepAlive         uint32                `json:"keepAliveInterval"`
+	KeepAliveJitter   uint32                `json:"keepAliveJitter"`
+	ReadBufferSize    *uint32               `json:"readBufferSize"`
+	WriteBufferSize   *uint32               `json:"writeBufferSize"`
+	HeaderConfig      json.RawMessage       `json:"header"`
+	Seed              *string               `json:"seed"`
 }
 
 // Build implements Buildable.
@@ -73,6 +150,83 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 	if c.Congestion != nil {
 		config.Congestion = *c.Congestion
 	}
+	switch cc := strings.ToLower(c.CongestionControl); cc {
+	case "":
+	case "loss", "bbr":
+		config.CongestionControl = cc
+		// Selecting a congestion controller implies congestion control, unless it is disabled explicitly.
+		if c.Congestion == nil {
+			config.Congestion = true
+		}
+	default:
+		return nil, newError("unknown mKCP congestion control: ", c.CongestionControl).AtError()
+	}
+	config.AckRange = c.AckRange
+	config.Pacing = c.Pacing
+	config.PathMtuDiscovery = c.MTUDiscovery
+	config.Metrics = c.Metrics
+	if c.FastResend > 100 {
+		return nil, newError("invalid mKCP fast resend: ", c.FastResend).AtError()
+	}
+	config.FastResend = c.FastResend
+	config.FastAckLimit = c.FastAckLimit
+	if c.RTOMax > 0 && c.RTOMin > c.RTOMax {
+		return nil, newError("mKCP RTO min ", c.RTOMin, " is larger than max ", c.RTOMax).AtError()
+	}
+	config.RtoMin = c.RTOMin
+	config.RtoMax = c.RTOMax
+	config.AutoTuneWindow = c.AutoTuneWindow
+	config.MaxRetransmit = c.MaxRetransmit
+	if c.Migration && c.Seed == nil {
+		return nil, newError("mKCP migration requires a seed").AtError()
+	}
+	config.Migration = c.Migration
+	config.Ecn = c.ECN
+	switch obfs := strings.ToLower(c.Obfuscation); obfs {
+	case "":
+	case "xor", "padding":
+		config.Obfuscation = obfs
+	default:
+		return nil, newError("unknown mKCP obfuscation: ", c.Obfuscation).AtError()
+	}
+	if c.AckDelay > 500 {
+		return nil, newError("invalid mKCP ACK delay: ", c.AckDelay).AtError()
+	}
+	config.AckDelay = c.AckDelay
+	config.AckNoDelayThreshold = c.AckNoDelay
+	config.Multiplex = c.Multiplex
+	if c.LossControl != nil {
+		lossControl, err := c.LossControl.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.LossControl = lossControl
+	}
+	config.IdleTimeout = c.IdleTimeout
+	if c.Backoff != nil {
+		backoff, err := c.Backoff.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.RetransmitBackoff = backoff
+	}
+	if c.KeepAlive > 20000 {
+		return nil, newError("invalid mKCP keep-alive interval: ", c.KeepAlive).AtError()
+	}
+	config.KeepAliveInterval = c.KeepAlive
+	config.KeepAliveJitter = c.KeepAliveJitter
+	if c.FEC != nil {
+		if c.FEC.DataShards < 1 || c.FEC.DataShards > 32 {
+			return nil, newError("invalid mKCP FEC data shards: ", c.FEC.DataShards).AtError()
+		}
+		if c.FEC.ParityShards < 1 || c.FEC.ParityShards > 16 {
+			return nil, newError("invalid mKCP FEC parity shards: ", c.FEC.ParityShards).AtError()
+		}
+		config.Fec = &kcp.FEC{
+			DataShards:   c.FEC.DataShards,
+			ParityShards: c.FEC.ParityShards,
+		}
+	}
 	if c.ReadBufferSize != nil {
 		size := *c.ReadBufferSize
 		if size > 0 {
@@ -105,12 +259,45 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
 	}
 
+	if c.Profile != "" {
+		if err := kcp.ApplyProfile(config, strings.ToLower(c.Profile)); err != nil {
+			return nil, newError("invalid mKCP profile").Base(err).AtError()
+		}
+		if c.Congestion != nil {
+			config.Congestion = *c.Congestion
+		}
+	}
+
 	return config, nil
 }
 
+type TCPFragmentConfig struct {
+	MinLength   uint32 `json:"minLength"`
+	MaxLength   uint32 `json:"maxLength"`
+	MinInterval uint32 `json:"minInterval"`
+	MaxInterval uint32 `json:"maxInterval"`
+}
+
+// Build implements Buildable.
+func (c *TCPFragmentConfig) Build() (*tcp.Fragment, error) {
+	if c.MaxLength > 0 && c.MinLength > c.MaxLength {
+		return nil, newError("TCP fragment min length ", c.MinLength, " is larger than max ", c.MaxLength).AtError()
+	}
+	if c.MaxInterval > 0 && c.MinInterval > c.MaxInterval {
+		return nil, newError("TCP fragment min interval ", c.MinInterval, " is larger than max ", c.MaxInterval).AtError()
+	}
+	return &tcp.Fragment{
+		MinLength:   c.MinLength,
+		MaxLength:   c.MaxLength,
+		MinInterval: c.MinInterval,
+		MaxInterval: c.MaxInterval,
+	}, nil
+}
+
 type TCPConfig struct {
-	HeaderConfig        json.RawMessage `json:"header"`
-	AcceptProxyProtocol bool            `json:"acceptProxyProtocol"`
+	HeaderConfig        json.RawMessage    `json:"header"`
+	AcceptProxyProtocol bool               `json:"acceptProxyProtocol"`
+	Fragment            *TCPFragmentConfig `json:"fragment"`
 }
 
 // Build implements Buildable.
@@ -130,6 +317,13 @@ func (c *TCPConfig) Build() (proto.Message, error) {
 	if c.AcceptProxyProtocol {
 		config.AcceptProxyProtocol = c.AcceptProxyProtocol
 	}
+	if c.Fragment != nil {
+		fragment, err := c.Fragment.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fragment = fragment
+	}
 	return config, nil
 }
 
@@ -137,7 +331,11 @@ type WebSocketConfig struct {
 	Path                string            `json:"path"`
 	Path2               string            `json:"Path"` // The key was misspelled. For backward compatibility, we have to keep track the old key.
 	Headers             map[string]string `json:"headers"`
+	Host                string            `json:"host"`
 	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+	Compression         bool              `json:"compression"`
+	CompressionLevel    int32             `json:"compressionLevel"`
+	MaxMessageSize      uint32            `json:"maxMessageSize"`
 }
 
 // Build implements Buildable.
@@ -153,9 +351,16 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 			Value: value,
 		})
 	}
+	if c.CompressionLevel < 0 || c.CompressionLevel > 9 {
+		return nil, newError("invalid WebSocket compression level: ", c.CompressionLevel).AtError()
+	}
 	config := &websocket.Config{
-		Path:   path,
-		Header: header,
+		Path:             path,
+		Header:           header,
+		Host:             c.Host,
+		Compression:      c.Compression,
+		CompressionLevel: c.CompressionLevel,
+		MaxMessageSize:   c.MaxMessageSize,
 	}
 	if c.AcceptProxyProtocol {
 		config.AcceptProxyProtocol = c.AcceptProxyProtocol
@@ -163,6 +368,46 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+type HTTPUpgradeConfig struct {
+	Path                string            `json:"path"`
+	Host                string            `json:"host"`
+	Headers             map[string]string `json:"headers"`
+	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+}
+
+// Build implements Buildable.
+func (c *HTTPUpgradeConfig) Build() (proto.Message, error) {
+	config := &httpupgrade.Config{
+		Path:                c.Path,
+		Host:                c.Host,
+		AcceptProxyProtocol: c.AcceptProxyProtocol,
+	}
+	for key, value := range c.Headers {
+		config.Header = append(config.Header, &httpupgrade.Header{
+			Key:   key,
+			Value: value,
+		})
+	}
+	return config, nil
+}
+
+type ICMPConfig struct {
+	KCPSettings *KCPConfig `json:"kcpSettings"`
+}
+
+// Build implements Buildable.
+func (c *ICMPConfig) Build() (proto.Message, error) {
+	config := new(icmptunnel.Config)
+	if c.KCPSettings != nil {
+		kcpConfig, err := c.KCPSettings.Build()
+		if err != nil {
+			return nil, newError("invalid mKCP settings of ICMP tunnel").Base(err)
+		}
+		config.KcpSettings = kcpConfig.(*kcp.Config)
+	}
+	return config, nil
+}
+
 type HTTPConfig struct {
 	Host *StringList `json:"host"`
 	Path string      `json:"path"`
@@ -284,13 +529,20 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 }
 
 type TLSConfig struct {
-	Insecure                 bool             `json:"allowInsecure"`
-	InsecureCiphers          bool             `json:"allowInsecureCiphers"`
-	Certs                    []*TLSCertConfig `json:"certificates"`
-	ServerName               string           `json:"serverName"`
-	ALPN                     *StringList      `json:"alpn"`
-	DisableSessionResumption bool             `json:"disableSessionResumption"`
-	DisableSystemRoot        bool             `json:"disableSystemRoot"`
+	Insecure                 bool                 `json:"allowInsecure"`
+	InsecureCiphers          bool                 `json:"allowInsecureCiphers"`
+	Certs                    []*TLSCertConfig     `json:"certificates"`
+	ServerName               string               `json:"serverName"`
+	ALPN                     *StringList          `json:"alpn"`
+	DisableSessionResumption bool                 `json:"disableSessionResumption"`
+	DisableSystemRoot        bool                 `json:"disableSystemRoot"`
+	Camouflage               *TLSCamouflageConfig `json:"camouflage"`
+	PinnedPeerCertSha256     *StringList          `json:"pinnedPeerCertSha256"`
+	CAFile                   string               `json:"caFile"`
+	ECHConfigList            string               `json:"echConfigList"`
+	ECHDNSServer             string               `json:"echDnsServer"`
+	ECHKeys                  []*TLSECHKeyConfig   `json:"echKeys"`
+	KeyLogFile               string               `json:"keyLogFile"`
 }
 
 // Build implements Buildable.
@@ -315,6 +567,96 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 	}
 	config.DisableSessionResumption = c.DisableSessionResumption
 	config.DisableSystemRoot = c.DisableSystemRoot
+	if c.PinnedPeerCertSha256 != nil {
+		for _, s := range *c.PinnedPeerCertSha256 {
+			hash, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+			if err != nil || len(hash) != sha256.Size {
+				return nil, newError("invalid pinned certificate hash: ", s).AtError()
+			}
+			config.PinnedPeerCertificateSha256 = append(config.PinnedPeerCertificateSha256, hash)
+		}
+	}
+	if len(c.CAFile) > 0 {
+		ca, err := filesystem.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, newError("failed to read CA file ", c.CAFile).Base(err).AtError()
+		}
+		config.RootCa = ca
+	}
+	if len(c.ECHConfigList) > 0 {
+		configList, err := base64.StdEncoding.DecodeString(c.ECHConfigList)
+		if err != nil {
+			return nil, newError("invalid ECH config list").Base(err).AtError()
+		}
+		config.EchConfigList = configList
+	}
+	config.EchDnsServer = c.ECHDNSServer
+	config.KeyLogFile = c.KeyLogFile
+	for _, key := range c.ECHKeys {
+		echKey, err := key.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.EchKey = append(config.EchKey, echKey)
+	}
+	if c.Camouflage != nil {
+		camouflage, err := c.Camouflage.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Camouflage = camouflage
+	}
+	return config, nil
+}
+
+type TLSECHKeyConfig struct {
+	Config     string `json:"config"`
+	PrivateKey string `json:"privateKey"`
+}
+
+// Build implements Buildable.
+func (c *TLSECHKeyConfig) Build() (*tls.ECHKey, error) {
+	config, err := base64.StdEncoding.DecodeString(c.Config)
+	if err != nil || len(config) == 0 {
+		return nil, newError("invalid ECH config: ", c.Config).AtError()
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(c.PrivateKey)
+	if err != nil || len(privateKey) == 0 {
+		return nil, newError("invalid ECH private key").AtError()
+	}
+	return &tls.ECHKey{
+		Config:     config,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+type TLSCamouflageConfig struct {
+	Key         string      `json:"key"`
+	Dest        string      `json:"dest"`
+	ServerNames *StringList `json:"serverNames"`
+	MaxTimeDiff uint32      `json:"maxTimeDiff"`
+}
+
+// Build implements Buildable.
+func (c *TLSCamouflageConfig) Build() (*tls.Camouflage, error) {
+	key, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, newError("invalid camouflage key").Base(err).AtError()
+	}
+	if len(key) != 32 {
+		return nil, newError("camouflage key must be 32 bytes, but got ", len(key)).AtError()
+	}
+	if len(c.Dest) == 0 {
+		return nil, newError("camouflage dest is not specified").AtError()
+	}
+	config := &tls.Camouflage{
+		Key:         key,
+		Dest:        c.Dest,
+		MaxTimeDiff: c.MaxTimeDiff,
+	}
+	if c.ServerNames != nil {
+		config.ServerNames = []string(*c.ServerNames)
+	}
 	return config, nil
 }
 
@@ -331,7 +673,11 @@ func (p TransportProtocol) Build() (string, error) {
 		return "websocket", nil
 	case "h2", "http":
 		return "http", nil
-	case "ds", "domainsocket":
+	case "httpupgrade":
+		return "httpupgrade", nil
+	case "icmp":
+		return "icmp", nil
+	case "ds", "domainsocket", "unix":
 		return "domainsocket", nil
 	case "quic":
 		return "quic", nil
@@ -340,10 +686,53 @@ func (p TransportProtocol) Build() (string, error) {
 	}
 }
 
+type HappyEyeballsConfig struct {
+	TryDelay         uint32 `json:"tryDelay"`
+	PreferIPv4       bool   `json:"preferIPv4"`
+	FirstFamilyCount uint32 `json:"firstFamilyCount"`
+}
+
+// Build implements Buildable.
+func (c *HappyEyeballsConfig) Build() (*internet.SocketConfig_HappyEyeballs, error) {
+	if c.TryDelay > 2000 {
+		return nil, newError("invalid Happy Eyeballs try delay: ", c.TryDelay).AtError()
+	}
+	return &internet.SocketConfig_HappyEyeballs{
+		TryDelay:         c.TryDelay,
+		PreferIpv4:       c.PreferIPv4,
+		FirstFamilyCount: c.FirstFamilyCount,
+	}, nil
+}
+
+type TCPKeepAliveConfig struct {
+	Idle     uint32 `json:"idle"`
+	Interval uint32 `json:"interval"`
+	Count    uint32 `json:"count"`
+}
+
+// Build implements Buildable.
+func (c *TCPKeepAliveConfig) Build() (*internet.SocketConfig_TCPKeepAlive, error) {
+	return &internet.SocketConfig_TCPKeepAlive{
+		Idle:     c.Idle,
+		Interval: c.Interval,
+		Count:    c.Count,
+	}, nil
+}
+
 type SocketConfig struct {
-	Mark   int32  `json:"mark"`
-	TFO    *bool  `json:"tcpFastOpen"`
-	TProxy string `json:"tproxy"`
+	Mark              int32                `json:"mark"`
+	TFO               *bool                `json:"tcpFastOpen"`
+	TProxy            string               `json:"tproxy"`
+	UnixSocketMode    string               `json:"unixSocketMode"`
+	UnixSocketOwner   string               `json:"unixSocketOwner"`
+	UDPOffload        bool                 `json:"udpOffload"`
+	HappyEyeballs     *HappyEyeballsConfig `json:"happyEyeballs"`
+	Interface         string               `json:"interface"`
+	MPTCP             bool                 `json:"mptcp"`
+	TCPKeepAlive      *TCPKeepAliveConfig  `json:"tcpKeepAlive"`
+	DialerProxy       string               `json:"dialerProxy"`
+	ReceiveBufferSize uint32               `json:"receiveBufferSize"`
+	SendBufferSize    uint32               `json:"sendBufferSize"`
 }
 
 func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
@@ -365,24 +754,64 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		tproxy = internet.SocketConfig_Off
 	}
 
+	var unixSocketMode uint64
+	if c.UnixSocketMode != "" {
+		mode, err := strconv.ParseUint(c.UnixSocketMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, newError("invalid unix socket mode: ", c.UnixSocketMode)
+		}
+		unixSocketMode = mode
+	}
+
+	var happyEyeballs *internet.SocketConfig_HappyEyeballs
+	if c.HappyEyeballs != nil {
+		config, err := c.HappyEyeballs.Build()
+		if err != nil {
+			return nil, err
+		}
+		happyEyeballs = config
+	}
+
+	var tcpKeepAlive *internet.SocketConfig_TCPKeepAlive
+	if c.TCPKeepAlive != nil {
+		config, err := c.TCPKeepAlive.Build()
+		if err != nil {
+			return nil, err
+		}
+		tcpKeepAlive = config
+	}
+
 	return &internet.SocketConfig{
-		Mark:   c.Mark,
-		Tfo:    tfoSettings,
-		Tproxy: tproxy,
+		Mark:              c.Mark,
+		Tfo:               tfoSettings,
+		Tproxy:            tproxy,
+		UnixSocketMode:    uint32(unixSocketMode),
+		UnixSocketOwner:   c.UnixSocketOwner,
+		UdpOffload:        c.UDPOffload,
+		HappyEyeballs:     happyEyeballs,
+		Interface:         c.Interface,
+		Mptcp:             c.MPTCP,
+		TcpKeepAlive:      tcpKeepAlive,
+		DialerProxy:       c.DialerProxy,
+		ReceiveBufferSize: c.ReceiveBufferSize,
+		SendBufferSize:    c.SendBufferSize,
 	}, nil
 }
 
 type StreamConfig struct {
-	Network        *TransportProtocol  `json:"network"`
-	Security       string              `json:"security"`
-	TLSSettings    *TLSConfig          `json:"tlsSettings"`
-	TCPSettings    *TCPConfig          `json:"tcpSettings"`
-	KCPSettings    *KCPConfig          `json:"kcpSettings"`
-	WSSettings     *WebSocketConfig    `json:"wsSettings"`
-	HTTPSettings   *HTTPConfig         `json:"httpSettings"`
-	DSSettings     *DomainSocketConfig `json:"dsSettings"`
-	QUICSettings   *QUICConfig         `json:"quicSettings"`
-	SocketSettings *SocketConfig       `json:"sockopt"`
+	Network             *TransportProtocol  `json:"network"`
+	Security            string              `json:"security"`
+	TLSSettings         *TLSConfig          `json:"tlsSettings"`
+	TCPSettings         *TCPConfig          `json:"tcpSettings"`
+	KCPSettings         *KCPConfig          `json:"kcpSettings"`
+	WSSettings          *WebSocketConfig    `json:"wsSettings"`
+	HTTPUpgradeSettings *HTTPUpgradeConfig  `json:"httpupgradeSettings"`
+	ICMPSettings        *ICMPConfig         `json:"icmpSettings"`
+	HTTPSettings        *HTTPConfig         `json:"httpSettings"`
+	DSSettings          *DomainSocketConfig `json:"dsSettings"`
+	QUICSettings        *QUICConfig         `json:"quicSettings"`
+	SocketSettings      *SocketConfig       `json:"sockopt"`
+	TraceSettings       *TraceConfig        `json:"trace"`
 }
 
 // Build implements Buildable.
@@ -440,6 +869,26 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 			Settings:     serial.ToTypedMessage(ts),
 		})
 	}
+	if c.HTTPUpgradeSettings != nil {
+		ts, err := c.HTTPUpgradeSettings.Build()
+		if err != nil {
+			return nil, newError("Failed to build HTTP Upgrade config.").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "httpupgrade",
+			Settings:     serial.ToTypedMessage(ts),
+		})
+	}
+	if c.ICMPSettings != nil {
+		ts, err := c.ICMPSettings.Build()
+		if err != nil {
+			return nil, newError("Failed to build ICMP config.").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "icmp",
+			Settings:     serial.ToTypedMessage(ts),
+		})
+	}
 	if c.HTTPSettings != nil {
 		ts, err := c.HTTPSettings.Build()
 		if err != nil {
@@ -477,9 +926,33 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 		}
 		config.SocketSettings = ss
 	}
+	if c.TraceSettings != nil {
+		ts, err := c.TraceSettings.Build()
+		if err != nil {
+			return nil, newError("failed to build trace settings").Base(err)
+		}
+		config.TraceSettings = ts
+	}
 	return config, nil
 }
 
+// TraceConfig is the JSON config of frame traces for debugging.
+type TraceConfig struct {
+	Path   string `json:"path"`
+	Sample uint32 `json:"sample"`
+}
+
+// Build implements Buildable.
+func (c *TraceConfig) Build() (*internet.TraceConfig, error) {
+	if c.Path == "" {
+		return nil, newError("trace path is not specified")
+	}
+	return &internet.TraceConfig{
+		Path:   c.Path,
+		Sample: c.Sample,
+	}, nil
+}
+
 type ProxyConfig struct {
 	Tag string `json:"tag"`
 }
@@ -10,12 +10,16 @@ import (
 	. "v2ray.com/core/infra/conf"
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/domainsocket"
 	"v2ray.com/core/transport/internet/headers/http"
 	"v2ray.com/core/transport/internet/headers/noop"
 	"v2ray.com/core/transport/internet/headers/tls"
+	"v2ray.com/core/transport/internet/httpupgrade"
+	"v2ray.com/core/transport/internet/icmptunnel"
 	"v2ray.com/core/transport/internet/kcp"
 	"v2ray.com/core/transport/internet/quic"
 	"v2ray.com/core/transport/internet/tcp"
+	v2tls "v2ray.com/core/transport/internet/tls"
 	"v2ray.com/core/transport/internet/websocket"
 )
 
@@ -34,12 +38,138 @@ func TestSocketConfig(t *testing.T) {
 		{
 			Input: `{
 				"mark": 1,
-				"tcpFastOpen": true
+				"tcpFastOpen": true,
+				"interface": "eth0"
 			}`,
 			Parser: createParser(),
 			Output: &internet.SocketConfig{
-				Mark: 1,
-				Tfo:  internet.SocketConfig_Enable,
+				Mark:      1,
+				Tfo:       internet.SocketConfig_Enable,
+				Interface: "eth0",
+			},
+		},
+		{
+			Input: `{
+				"udpOffload": true,
+				"mptcp": true,
+				"tcpKeepAlive": {
+					"idle": 30,
+					"interval": 5,
+					"count": 3
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				UdpOffload: true,
+				Mptcp:      true,
+				TcpKeepAlive: &internet.SocketConfig_TCPKeepAlive{
+					Idle:     30,
+					Interval: 5,
+					Count:    3,
+				},
+			},
+		},
+		{
+			Input: `{
+				"dialerProxy": "relay",
+				"receiveBufferSize": 4194304,
+				"sendBufferSize": 1048576
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				DialerProxy:       "relay",
+				ReceiveBufferSize: 4194304,
+				SendBufferSize:    1048576,
+			},
+		},
+		{
+			Input: `{
+				"happyEyeballs": {
+					"tryDelay": 100,
+					"preferIPv4": true,
+					"firstFamilyCount": 2
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				HappyEyeballs: &internet.SocketConfig_HappyEyeballs{
+					TryDelay:         100,
+					PreferIpv4:       true,
+					FirstFamilyCount: 2,
+				},
+			},
+		},
+	})
+}
+
+func TestStreamTraceConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(StreamConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"network": "kcp",
+				"trace": {
+					"path": "/tmp/kcp.jsonl",
+					"sample": 10
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.StreamConfig{
+				ProtocolName: "mkcp",
+				TraceSettings: &internet.TraceConfig{
+					Path:   "/tmp/kcp.jsonl",
+					Sample: 10,
+				},
+			},
+		},
+	})
+}
+
+func TestStreamUnixConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(StreamConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"network": "unix",
+				"dsSettings": {
+					"path": "/var/run/v2ray.sock"
+				},
+				"sockopt": {
+					"unixSocketMode": "0660"
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.StreamConfig{
+				ProtocolName: "domainsocket",
+				TransportSettings: []*internet.TransportConfig{
+					{
+						ProtocolName: "domainsocket",
+						Settings: serial.ToTypedMessage(&domainsocket.Config{
+							Path: "/var/run/v2ray.sock",
+						}),
+					},
+				},
+				SocketSettings: &internet.SocketConfig{
+					UnixSocketMode: 0660,
+				},
 			},
 		},
 	})
@@ -85,7 +215,10 @@ func TestTransportConfig(t *testing.T) {
 					}
 				},
 				"wsSettings": {
-					"path": "/t"
+					"path": "/t",
+					"host": "example.com",
+					"compression": true,
+					"compressionLevel": 6
 				},
 				"quicSettings": {
 					"key": "abcd",
@@ -149,7 +282,10 @@ func TestTransportConfig(t *testing.T) {
 					{
 						ProtocolName: "websocket",
 						Settings: serial.ToTypedMessage(&websocket.Config{
-							Path: "/t",
+							Path:             "/t",
+							Host:             "example.com",
+							Compression:      true,
+							CompressionLevel: 6,
 						}),
 					},
 					{
@@ -167,3 +303,391 @@ func TestTransportConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestKCPConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(KCPConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"congestionControl": "BBR",
+				"fec": {
+					"dataShards": 10,
+					"parityShards": 3
+				}
+			}`,
+			Parser: createParser(),
+			Output: &kcp.Config{
+				Congestion:        true,
+				CongestionControl: "bbr",
+				Fec: &kcp.FEC{
+					DataShards:   10,
+					ParityShards: 3,
+				},
+			},
+		},
+		{
+			Input: `{
+				"congestion": false,
+				"congestionControl": "loss",
+				"ackRange": true,
+				"pacing": true,
+				"mtuDiscovery": true,
+				"metrics": true,
+				"fastResend": 2,
+				"fastAckLimit": 5,
+				"rtoMin": 30,
+				"rtoMax": 3000,
+				"autoTuneWindow": true,
+				"maxRetransmit": 10,
+				"migration": true,
+				"seed": "kcp",
+				"ecn": true,
+				"obfuscation": "padding",
+				"ackDelay": 20,
+				"ackNoDelayThreshold": 8,
+				"multiplex": true,
+				"lossControl": {"decrease": 50, "increase": 10, "minWindow": 8, "maxWindow": 512},
+				"idleTimeout": 300,
+				"retransmitBackoff": {"factor": 200, "maxTimeout": 5000},
+				"keepAliveInterval": 10000,
+				"keepAliveJitter": 2000
+			}`,
+			Parser: createParser(),
+			Output: &kcp.Config{
+				CongestionControl:   "loss",
+				AckRange:            true,
+				Pacing:              true,
+				PathMtuDiscovery:    true,
+				Metrics:             true,
+				FastResend:          2,
+				FastAckLimit:        5,
+				RtoMin:              30,
+				RtoMax:              3000,
+				AutoTuneWindow:      true,
+				MaxRetransmit:       10,
+				Migration:           true,
+				Seed:                &kcp.EncryptionSeed{Seed: "kcp"},
+				Ecn:                 true,
+				Obfuscation:         "padding",
+				AckDelay:            20,
+				AckNoDelayThreshold: 8,
+				Multiplex:           true,
+				LossControl:         &kcp.LossControl{Decrease: 50, Increase: 10, MinWindow: 8, MaxWindow: 512},
+				IdleTimeout:         300,
+				RetransmitBackoff:   &kcp.RetransmitBackoff{Factor: 200, MaxTimeout: 5000},
+				KeepAliveInterval:   10000,
+				KeepAliveJitter:     2000,
+			},
+		},
+		{
+			Input: `{
+				"profile": "throughput",
+				"tti": 20,
+				"congestion": false
+			}`,
+			Parser: createParser(),
+			Output: &kcp.Config{
+				Tti:               &kcp.TTI{Value: 20},
+				UplinkCapacity:    &kcp.UplinkCapacity{Value: 100},
+				DownlinkCapacity:  &kcp.DownlinkCapacity{Value: 100},
+				CongestionControl: "bbr",
+				ReadBuffer:        &kcp.ReadBuffer{Size: 8 * 1024 * 1024},
+				WriteBuffer:       &kcp.WriteBuffer{Size: 8 * 1024 * 1024},
+			},
+		},
+		{
+			Input: `{
+				"profile": "Latency",
+				"fastResend": 5
+			}`,
+			Parser: createParser(),
+			Output: &kcp.Config{
+				Tti:              &kcp.TTI{Value: 10},
+				UplinkCapacity:   &kcp.UplinkCapacity{Value: 20},
+				DownlinkCapacity: &kcp.DownlinkCapacity{Value: 50},
+				FastResend:       5,
+				RtoMin:           30,
+			},
+		},
+	})
+
+	for _, input := range []string{
+		`{"congestionControl": "cubic"}`,
+		`{"fastResend": 101}`,
+		`{"rtoMin": 3000, "rtoMax": 1000}`,
+		`{"obfuscation": "rot13"}`,
+		`{"ackDelay": 501}`,
+		`{"migration": true}`,
+		`{"profile": "fastest"}`,
+		`{"lossControl": {"decrease": 100}}`,
+		`{"lossControl": {"increase": 101}}`,
+		`{"lossControl": {"minWindow": 64, "maxWindow": 32}}`,
+		`{"retransmitBackoff": {"factor": 50}}`,
+		`{"keepAliveInterval": 30000}`,
+		`{"fec": {"dataShards": 0, "parityShards": 3}}`,
+		`{"fec": {"dataShards": 33, "parityShards": 3}}`,
+		`{"fec": {"dataShards": 10, "parityShards": 17}}`,
+	} {
+		if _, err := createParser()(input); err == nil {
+			t.Error("expected error for ", input)
+		}
+	}
+}
+
+func TestTLSCamouflageConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(TLSConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"camouflage": {
+					"key": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=",
+					"dest": "www.example.com:443",
+					"serverNames": ["www.example.com", "example.com"],
+					"maxTimeDiff": 60
+				}
+			}`,
+			Parser: createParser(),
+			Output: &v2tls.Config{
+				Certificate: []*v2tls.Certificate{},
+				Camouflage: &v2tls.Camouflage{
+					Key:         key,
+					Dest:        "www.example.com:443",
+					ServerNames: []string{"www.example.com", "example.com"},
+					MaxTimeDiff: 60,
+				},
+			},
+		},
+	})
+
+	for _, input := range []string{
+		`{"camouflage": {"key": "AAEC", "dest": "www.example.com:443"}}`,
+		`{"camouflage": {"key": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="}}`,
+	} {
+		if _, err := createParser()(input); err == nil {
+			t.Error("expected error for ", input)
+		}
+	}
+}
+
+func TestTLSPinningConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(TLSConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	hash := make([]byte, 32)
+	hash[0] = 0xab
+	hash[31] = 0xcd
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"pinnedPeerCertSha256": [
+					"ab000000000000000000000000000000000000000000000000000000000000cd",
+					"AB:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:CD"
+				]
+			}`,
+			Parser: createParser(),
+			Output: &v2tls.Config{
+				Certificate:                 []*v2tls.Certificate{},
+				PinnedPeerCertificateSha256: [][]byte{hash, hash},
+			},
+		},
+	})
+
+	if _, err := createParser()(`{"pinnedPeerCertSha256": ["abcd"]}`); err == nil {
+		t.Error("expected error for short hash")
+	}
+	if _, err := createParser()(`{"caFile": "/non/existing/ca.pem"}`); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
+
+func TestTLSECHConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(TLSConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"echConfigList": "AAECAw==",
+				"echDnsServer": "1.1.1.1:53",
+				"keyLogFile": "/tmp/keys.log",
+				"echKeys": [{
+					"config": "BAUG",
+					"privateKey": "BwgJ"
+				}]
+			}`,
+			Parser: createParser(),
+			Output: &v2tls.Config{
+				Certificate:   []*v2tls.Certificate{},
+				EchConfigList: []byte{0, 1, 2, 3},
+				EchDnsServer:  "1.1.1.1:53",
+				KeyLogFile:    "/tmp/keys.log",
+				EchKey: []*v2tls.ECHKey{
+					{
+						Config:     []byte{4, 5, 6},
+						PrivateKey: []byte{7, 8, 9},
+					},
+				},
+			},
+		},
+	})
+
+	if _, err := createParser()(`{"echKeys": [{"config": "BAUG"}]}`); err == nil {
+		t.Error("expected error for missing ECH private key")
+	}
+}
+
+func TestTCPFragmentConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(TCPConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"fragment": {
+					"minLength": 10,
+					"maxLength": 20,
+					"minInterval": 5,
+					"maxInterval": 10
+				}
+			}`,
+			Parser: createParser(),
+			Output: &tcp.Config{
+				Fragment: &tcp.Fragment{
+					MinLength:   10,
+					MaxLength:   20,
+					MinInterval: 5,
+					MaxInterval: 10,
+				},
+			},
+		},
+	})
+
+	if _, err := createParser()(`{"fragment": {"minLength": 20, "maxLength": 10}}`); err == nil {
+		t.Error("expected error for invalid length range")
+	}
+}
+
+func TestStreamHTTPUpgradeConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(StreamConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"network": "httpupgrade",
+				"httpupgradeSettings": {
+					"path": "/up",
+					"host": "www.v2ray.com",
+					"headers": {
+						"User-Agent": "v2ray"
+					}
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.StreamConfig{
+				ProtocolName: "httpupgrade",
+				TransportSettings: []*internet.TransportConfig{
+					{
+						ProtocolName: "httpupgrade",
+						Settings: serial.ToTypedMessage(&httpupgrade.Config{
+							Path: "/up",
+							Host: "www.v2ray.com",
+							Header: []*httpupgrade.Header{
+								{Key: "User-Agent", Value: "v2ray"},
+							},
+						}),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestStreamICMPConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(StreamConfig)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"network": "icmp",
+				"icmpSettings": {
+					"kcpSettings": {
+						"mtu": 1200
+					}
+				}
+			}`,
+			Parser: createParser(),
+			Output: &internet.StreamConfig{
+				ProtocolName: "icmp",
+				TransportSettings: []*internet.TransportConfig{
+					{
+						ProtocolName: "icmp",
+						Settings: serial.ToTypedMessage(&icmptunnel.Config{
+							KcpSettings: &kcp.Config{
+								Mtu: &kcp.MTU{Value: 1200},
+							},
+						}),
+					},
+				},
+			},
+		},
+	})
+}
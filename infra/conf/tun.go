@@ -0,0 +1,33 @@
+package conf
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/proxy/tun"
+)
+
+type TunConfig struct {
+	Name      string     `json:"name"`
+	MTU       uint32     `json:"mtu"`
+	Address   StringList `json:"address"`
+	UserLevel uint32     `json:"userLevel"`
+}
+
+func (c *TunConfig) Build() (proto.Message, error) {
+	for _, address := range c.Address {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return nil, newError("invalid address of TUN device: ", address).Base(err)
+		}
+	}
+	if c.MTU != 0 && c.MTU < 576 {
+		return nil, newError("MTU of TUN device is too small: ", c.MTU)
+	}
+
+	return &tun.Config{
+		Name:      c.Name,
+		Mtu:       c.MTU,
+		Address:   []string(c.Address),
+		UserLevel: c.UserLevel,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/infra/conf"
+	"v2ray.com/core/proxy/tun"
+)
+
+func TestTunConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(TunConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"name": "tun0",
+				"mtu": 9000,
+				"address": ["10.0.0.1/24", "fd00::1/64"],
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &tun.Config{
+				Name:      "tun0",
+				Mtu:       9000,
+				Address:   []string{"10.0.0.1/24", "fd00::1/64"},
+				UserLevel: 1,
+			},
+		},
+	})
+}
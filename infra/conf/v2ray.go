@@ -10,7 +10,9 @@ import (
 	"v2ray.com/core/app/dispatcher"
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/serial"
+	"v2ray.com/core/transport/internet"
 )
 
 var (
@@ -22,10 +24,12 @@ var (
 		"vless":         func() interface{} { return new(VLessInboundConfig) },
 		"vmess":         func() interface{} { return new(VMessInboundConfig) },
 		"mtproto":       func() interface{} { return new(MTProtoServerConfig) },
+		"tun":           func() interface{} { return new(TunConfig) },
 	}, "protocol", "settings")
 
 	outboundConfigLoader = NewJSONConfigLoader(ConfigCreatorCache{
 		"blackhole":   func() interface{} { return new(BlackholeConfig) },
+		"chain":       func() interface{} { return new(ChainConfig) },
 		"freedom":     func() interface{} { return new(FreedomConfig) },
 		"http":        func() interface{} { return new(HttpClientConfig) },
 		"shadowsocks": func() interface{} { return new(ShadowsocksClientConfig) },
@@ -55,8 +59,12 @@ func toProtocolList(s []string) ([]proxyman.KnownProtocols, error) {
 }
 
 type SniffingConfig struct {
-	Enabled      bool        `json:"enabled"`
-	DestOverride *StringList `json:"destOverride"`
+	Enabled         bool        `json:"enabled"`
+	DestOverride    *StringList `json:"destOverride"`
+	Ports           *PortList   `json:"ports"`
+	RouteOnly       bool        `json:"routeOnly"`
+	DomainsExcluded *StringList `json:"domainsExcluded"`
+	MetadataOnly    bool        `json:"metadataOnly"`
 }
 
 func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
@@ -68,15 +76,44 @@ func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
 				p = append(p, "http")
 			case "tls", "https", "ssl":
 				p = append(p, "tls")
+			case "quic":
+				p = append(p, "quic")
 			default:
 				return nil, newError("unknown protocol: ", domainOverride)
 			}
 		}
 	}
 
-	return &proxyman.SniffingConfig{
+	config := &proxyman.SniffingConfig{
 		Enabled:             c.Enabled,
 		DestinationOverride: p,
+		RouteOnly:           c.RouteOnly,
+		MetadataOnly:        c.MetadataOnly,
+	}
+	if c.Ports != nil {
+		config.Ports = c.Ports.Build()
+	}
+	if c.DomainsExcluded != nil {
+		if _, err := proxyman.NewSniffingExclusion(*c.DomainsExcluded); err != nil {
+			return nil, err
+		}
+		config.DomainsExcluded = *c.DomainsExcluded
+	}
+	return config, nil
+}
+
+type RecordConfig struct {
+	Directory string `json:"directory"`
+	MaxSize   uint32 `json:"maxSize"`
+}
+
+func (c *RecordConfig) Build() (*proxyman.RecordConfig, error) {
+	if c.Directory == "" {
+		return nil, newError("directory of recorded sessions is not specified")
+	}
+	return &proxyman.RecordConfig{
+		Directory: c.Directory,
+		MaxSize:   c.MaxSize,
 	}, nil
 }
 
@@ -138,39 +175,69 @@ func (c *InboundDetourAllocationConfig) Build() (*proxyman.AllocationStrategy, e
 
 type InboundDetourConfig struct {
 	Protocol       string                         `json:"protocol"`
-	PortRange      *PortRange                     `json:"port"`
-	ListenOn       *Address                       `json:"listen"`
+	PortList       *PortList                      `json:"port"`
+	ListenOn       *AddressList                   `json:"listen"`
 	Settings       *json.RawMessage               `json:"settings"`
 	Tag            string                         `json:"tag"`
 	Allocation     *InboundDetourAllocationConfig `json:"allocate"`
 	StreamSetting  *StreamConfig                  `json:"streamSettings"`
 	DomainOverride *StringList                    `json:"domainOverride"`
 	SniffingConfig *SniffingConfig                `json:"sniffing"`
+	Listeners      uint32                         `json:"listeners"`
+	RecordConfig   *RecordConfig                  `json:"record"`
 }
 
 // Build implements Buildable.
 func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 	receiverSettings := &proxyman.ReceiverConfig{}
 
-	if c.PortRange == nil {
+	var listenOn AddressList
+	if c.ListenOn != nil {
+		listenOn = *c.ListenOn
+	}
+
+	unixSocket := len(listenOn) > 0 && internet.IsUnixSocketPath(listenOn[0].Address)
+	if unixSocket {
+		// Unix domain sockets have no port.
+		receiverSettings.PortRange = &net.PortRange{From: 0, To: 0}
+		if len(listenOn) > 1 {
+			return nil, newError("multiple listen addresses are not supported on unix domain socket ", listenOn[0].Domain())
+		}
+		if c.Allocation != nil && c.Allocation.Strategy == "random" {
+			return nil, newError("random allocation is not supported on unix domain socket ", listenOn[0].Domain())
+		}
+		if c.Listeners > 1 {
+			return nil, newError("multiple listeners are not supported on unix domain socket ", listenOn[0].Domain())
+		}
+	} else if c.PortList != nil && len(c.PortList.Range) == 1 {
+		receiverSettings.PortRange = c.PortList.Range[0].Build()
+	} else if c.PortList != nil && len(c.PortList.Range) > 1 {
+		receiverSettings.PortList = c.PortList.Build()
+	} else if !strings.EqualFold(c.Protocol, "tun") {
+		// TUN inbound captures traffic from a device, and listens on no port.
 		return nil, newError("port range not specified in InboundDetour.")
 	}
-	receiverSettings.PortRange = c.PortRange.Build()
 
-	if c.ListenOn != nil {
-		if c.ListenOn.Family().IsDomain() {
-			return nil, newError("unable to listen on domain address: ", c.ListenOn.Domain())
+	for _, address := range listenOn {
+		if address.Family().IsDomain() && !unixSocket {
+			return nil, newError("unable to listen on domain address: ", address.Domain())
 		}
-		receiverSettings.Listen = c.ListenOn.Build()
 	}
-	if c.Allocation != nil {
+	if len(listenOn) == 1 {
+		receiverSettings.Listen = listenOn[0].Build()
+	} else {
+		for _, address := range listenOn {
+			receiverSettings.ListenList = append(receiverSettings.ListenList, address.Build())
+		}
+	}
+	if c.Allocation != nil && c.PortList != nil {
 		concurrency := -1
 		if c.Allocation.Concurrency != nil && c.Allocation.Strategy == "random" {
 			concurrency = int(*c.Allocation.Concurrency)
 		}
-		portRange := int(c.PortRange.To - c.PortRange.From + 1)
-		if concurrency >= 0 && concurrency >= portRange {
-			return nil, newError("not enough ports. concurrency = ", concurrency, " ports: ", c.PortRange.From, " - ", c.PortRange.To)
+		ports := len(receiverSettings.GetEffectivePorts())
+		if concurrency >= 0 && concurrency >= ports {
+			return nil, newError("not enough ports. concurrency = ", concurrency, " ports: ", ports)
 		}
 
 		as, err := c.Allocation.Build()
@@ -179,6 +246,15 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.AllocationStrategy = as
 	}
+	if c.Listeners > 1 {
+		if c.PortList == nil {
+			return nil, newError("multiple listeners require a port")
+		}
+		if as := receiverSettings.AllocationStrategy; as != nil && as.Type != proxyman.AllocationStrategy_Always {
+			return nil, newError("multiple listeners are only supported with \"always\" allocation strategy")
+		}
+		receiverSettings.Listeners = c.Listeners
+	}
 	if c.StreamSetting != nil {
 		ss, err := c.StreamSetting.Build()
 		if err != nil {
@@ -193,6 +269,13 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.SniffingSettings = s
 	}
+	if c.RecordConfig != nil {
+		r, err := c.RecordConfig.Build()
+		if err != nil {
+			return nil, newError("failed to build record config").Base(err)
+		}
+		receiverSettings.RecordSettings = r
+	}
 	if c.DomainOverride != nil {
 		kp, err := toProtocolList(*c.DomainOverride)
 		if err != nil {
@@ -308,6 +391,8 @@ type Config struct {
 	Api             *ApiConfig             `json:"api"`
 	Stats           *StatsConfig           `json:"stats"`
 	Reverse         *ReverseConfig         `json:"reverse"`
+	Privilege       *PrivilegeConfig       `json:"privilege"`
+	NetMon          *NetMonConfig          `json:"netmon"`
 }
 
 func (c *Config) findInboundTag(tag string) int {
@@ -361,6 +446,12 @@ func (c *Config) Override(o *Config, fn string) {
 	if o.Reverse != nil {
 		c.Reverse = o.Reverse
 	}
+	if o.Privilege != nil {
+		c.Privilege = o.Privilege
+	}
+	if o.NetMon != nil {
+		c.NetMon = o.NetMon
+	}
 
 	// deprecated attrs... keep them for now
 	if o.InboundConfig != nil {
@@ -499,6 +590,22 @@ func (c *Config) Build() (*core.Config, error) {
 		config.App = append(config.App, serial.ToTypedMessage(r))
 	}
 
+	if c.NetMon != nil {
+		nc, err := c.NetMon.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(nc))
+	}
+
+	if c.Privilege != nil {
+		pc, err := c.Privilege.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Extension = append(config.Extension, serial.ToTypedMessage(pc))
+	}
+
 	var inbounds []InboundDetourConfig
 
 	if c.InboundConfig != nil {
@@ -514,10 +621,9 @@ func (c *Config) Build() (*core.Config, error) {
 	}
 
 	// Backward compatibility.
-	if len(inbounds) > 0 && inbounds[0].PortRange == nil && c.Port > 0 {
-		inbounds[0].PortRange = &PortRange{
-			From: uint32(c.Port),
-			To:   uint32(c.Port),
+	if len(inbounds) > 0 && inbounds[0].PortList == nil && c.Port > 0 {
+		inbounds[0].PortList = &PortList{
+			Range: []PortRange{{From: uint32(c.Port), To: uint32(c.Port)}},
 		}
 	}
 
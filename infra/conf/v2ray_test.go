@@ -447,3 +447,137 @@ func TestConfig_Override(t *testing.T) {
 		})
 	}
 }
+
+func TestInboundListeners(t *testing.T) {
+	build := func(s string) (*proxyman.ReceiverConfig, error) {
+		config := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(s), config))
+		handler, err := config.Build()
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := handler.ReceiverSettings.GetInstance()
+		common.Must(err)
+		return receiver.(*proxyman.ReceiverConfig), nil
+	}
+
+	receiver, err := build(`{"protocol": "dokodemo-door", "port": 1080, "listeners": 4}`)
+	common.Must(err)
+	if receiver.Listeners != 4 {
+		t.Error("unexpected listeners: ", receiver.Listeners)
+	}
+
+	if _, err := build(`{"protocol": "dokodemo-door", "port": "1080-1090", "listeners": 4, "allocate": {"strategy": "random"}}`); err == nil {
+		t.Error("expect error for random allocation with multiple listeners")
+	}
+}
+
+func TestInboundPortList(t *testing.T) {
+	build := func(s string) (*proxyman.ReceiverConfig, error) {
+		config := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(s), config))
+		handler, err := config.Build()
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := handler.ReceiverSettings.GetInstance()
+		common.Must(err)
+		return receiver.(*proxyman.ReceiverConfig), nil
+	}
+
+	receiver, err := build(`{"protocol": "dokodemo-door", "port": "10000-10002"}`)
+	common.Must(err)
+	if r := cmp.Diff(receiver.PortRange, &net.PortRange{From: 10000, To: 10002}, cmp.Comparer(proto.Equal)); r != "" {
+		t.Error(r)
+	}
+	if receiver.PortList != nil {
+		t.Error("unexpected port list: ", receiver.PortList)
+	}
+
+	receiver, err = build(`{"protocol": "dokodemo-door", "port": "80,443,8443-8444"}`)
+	common.Must(err)
+	if r := cmp.Diff(receiver.GetEffectivePorts(), []net.Port{80, 443, 8443, 8444}); r != "" {
+		t.Error(r)
+	}
+
+	if _, err := build(`{"protocol": "dokodemo-door", "port": "80,443", "allocate": {"strategy": "random", "concurrency": 2}}`); err == nil {
+		t.Error("expect error for not enough ports")
+	}
+	if _, err := build(`{"protocol": "dokodemo-door", "port": "80,443,8443", "allocate": {"strategy": "random", "concurrency": 2}}`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInboundUnixSocket(t *testing.T) {
+	config := new(InboundDetourConfig)
+	common.Must(json.Unmarshal([]byte(`{
+		"listen": "/run/v2ray/socks.sock",
+		"protocol": "socks",
+		"streamSettings": {"sockopt": {"unixSocketMode": "0660", "unixSocketOwner": "nobody:nogroup"}}
+	}`), config))
+	handler, err := config.Build()
+	common.Must(err)
+	rawReceiver, err := handler.ReceiverSettings.GetInstance()
+	common.Must(err)
+	receiver := rawReceiver.(*proxyman.ReceiverConfig)
+	if listen := receiver.Listen.AsAddress(); listen.Domain() != "/run/v2ray/socks.sock" {
+		t.Error("unexpected listen address ", listen)
+	}
+	if r := cmp.Diff(receiver.GetEffectivePorts(), []net.Port{0}); r != "" {
+		t.Error(r)
+	}
+	sockopt := receiver.StreamSettings.SocketSettings
+	if sockopt.UnixSocketMode != 0660 || sockopt.UnixSocketOwner != "nobody:nogroup" {
+		t.Error("unexpected socket settings ", sockopt)
+	}
+
+	for _, invalid := range []string{
+		`{"listen": "v2fly.org", "port": 1080, "protocol": "socks"}`,
+		`{"listen": "/run/v2ray.sock", "protocol": "socks", "allocate": {"strategy": "random"}}`,
+		`{"listen": "/run/v2ray.sock", "protocol": "socks", "streamSettings": {"sockopt": {"unixSocketMode": "0999"}}}`,
+	} {
+		config := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(invalid), config))
+		if _, err := config.Build(); err == nil {
+			t.Error("expect error for ", invalid)
+		}
+	}
+}
+
+func TestInboundListenList(t *testing.T) {
+	build := func(s string) (*proxyman.ReceiverConfig, error) {
+		config := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(s), config))
+		handler, err := config.Build()
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := handler.ReceiverSettings.GetInstance()
+		common.Must(err)
+		return receiver.(*proxyman.ReceiverConfig), nil
+	}
+
+	receiver, err := build(`{"protocol": "dokodemo-door", "port": 1080, "listen": ["127.0.0.1", "::1"]}`)
+	common.Must(err)
+	if receiver.Listen != nil {
+		t.Error("unexpected listen address: ", receiver.Listen)
+	}
+	if r := cmp.Diff(receiver.GetEffectiveListenAddresses(), []net.Address{net.LocalHostIP, net.LocalHostIPv6}); r != "" {
+		t.Error(r)
+	}
+
+	receiver, err = build(`{"protocol": "dokodemo-door", "port": 1080, "listen": ["127.0.0.1"]}`)
+	common.Must(err)
+	if listen := receiver.Listen.AsAddress(); listen != net.LocalHostIP {
+		t.Error("unexpected listen address: ", listen)
+	}
+
+	for _, invalid := range []string{
+		`{"protocol": "dokodemo-door", "port": 1080, "listen": ["127.0.0.1", "v2fly.org"]}`,
+		`{"protocol": "socks", "listen": ["/run/v2ray.sock", "127.0.0.1"]}`,
+	} {
+		if _, err := build(invalid); err == nil {
+			t.Error("expect error for ", invalid)
+		}
+	}
+}
@@ -54,6 +54,9 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		if account.Encryption != "" {
 			return nil, newError(`VLESS clients: "encryption" should not in inbound settings`)
 		}
+		if account.Padding != nil {
+			return nil, newError(`VLESS clients: "padding" should not in inbound settings, it is requested by clients`)
+		}
 
 		user.Account = serial.ToTypedMessage(account)
 		config.Clients[idx] = user
@@ -120,6 +123,24 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+type VLessPaddingConfig struct {
+	Sizes        []uint32 `json:"sizes"`
+	DummyPercent uint32   `json:"dummyPercent"`
+	Frames       uint32   `json:"frames"`
+}
+
+func (c *VLessPaddingConfig) Build() (*vless.PaddingScheme, error) {
+	scheme := &vless.PaddingScheme{
+		Sizes:        c.Sizes,
+		DummyPercent: c.DummyPercent,
+		Frames:       c.Frames,
+	}
+	if err := scheme.Validate(); err != nil {
+		return nil, newError(`VLESS users: invalid "padding"`).Base(err)
+	}
+	return scheme, nil
+}
+
 type VLessOutboundVnext struct {
 	Address *Address          `json:"address"`
 	Port    uint16            `json:"port"`
@@ -168,6 +189,20 @@ func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 				return nil, newError(`VLESS users: please add/set "encryption":"none" for every user`)
 			}
 
+			padding := new(struct {
+				Padding *VLessPaddingConfig `json:"padding"`
+			})
+			if err := json.Unmarshal(rawUser, padding); err != nil {
+				return nil, newError(`VLESS users: invalid user`).Base(err)
+			}
+			account.Padding = nil
+			if padding.Padding != nil {
+				var err error
+				if account.Padding, err = padding.Padding.Build(); err != nil {
+					return nil, err
+				}
+			}
+
 			user.Account = serial.ToTypedMessage(account)
 			spec.User[idx] = user
 		}
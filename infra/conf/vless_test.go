@@ -55,9 +55,74 @@ func TestVLessOutbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"vnext": [{
+					"address": "example.com",
+					"port": 443,
+					"users": [
+						{
+							"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+							"encryption": "none",
+							"padding": {
+								"sizes": [600, 1200],
+								"dummyPercent": 20,
+								"frames": 16
+							}
+						}
+					]
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &outbound.Config{
+				Vnext: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Domain{
+								Domain: "example.com",
+							},
+						},
+						Port: 443,
+						User: []*protocol.User{
+							{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id:         "27848739-7e62-4138-9fd3-098a63964b6b",
+									Encryption: "none",
+									Padding: &vless.PaddingScheme{
+										Sizes:        []uint32{600, 1200},
+										DummyPercent: 20,
+										Frames:       16,
+									},
+								}),
+							},
+						},
+					},
+				},
+			},
+		},
 	})
 }
 
+func TestVLessOutboundInvalidPadding(t *testing.T) {
+	for _, padding := range []string{
+		`{}`,
+		`{"sizes": [32]}`,
+		`{"sizes": [4096]}`,
+		`{"sizes": [600], "dummyPercent": 101}`,
+	} {
+		_, err := loadJSON(func() Buildable { return new(VLessOutboundConfig) })(`{
+			"vnext": [{
+				"address": "example.com",
+				"port": 443,
+				"users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none", "padding": ` + padding + `}]
+			}]
+		}`)
+		if err == nil {
+			t.Error("expected error for padding ", padding)
+		}
+	}
+}
+
 func TestVLessInbound(t *testing.T) {
 	creator := func() Buildable {
 		return new(VLessInboundConfig)
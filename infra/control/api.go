@@ -27,17 +27,29 @@ func (c *ApiCommand) Description() Description {
 		Short: "Call V2Ray API",
 		Usage: []string{
 			"v2ctl api [--server=127.0.0.1:8080] Service.Method Request",
+			"v2ctl api [--server=127.0.0.1:8080] <subcommand> [arguments]",
 			"Call an API in an V2Ray process.",
 			"The following methods are currently supported:",
 			"\tLoggerService.RestartLogger",
 			"\tStatsService.GetStats",
 			"\tStatsService.QueryStats",
+			"\tStatsService.GetSysStats",
+			"The following subcommands are available:",
+			"\tstats [--reset] [pattern]: Query statistics whose names contain the pattern.",
+			"\tadduser --inbound=tag [--protocol=vmess] <user>: Add a user in JSON, as in the clients of VMess, VLESS or Shadowsocks inbound.",
+			"\trmuser --inbound=tag <email>: Remove a user by email.",
+			"\taddinbound <config>: Add the inbounds in a JSON config file.",
+			"\trminbound <tag>...: Remove inbounds by tags.",
+			"\tconns: List active connections.",
+			"\treload: Reload V2Ray with its config files.",
 			"API calls in this command have a timeout to the server of 3 seconds.",
 			"Examples:",
 			"v2ctl api --server=127.0.0.1:8080 LoggerService.RestartLogger '' ",
 			"v2ctl api --server=127.0.0.1:8080 StatsService.QueryStats 'pattern: \"\" reset: false'",
 			"v2ctl api --server=127.0.0.1:8080 StatsService.GetStats 'name: \"inbound>>>statin>>>traffic>>>downlink\" reset: false'",
 			"v2ctl api --server=127.0.0.1:8080 StatsService.GetSysStats ''",
+			"v2ctl api --server=127.0.0.1:8080 adduser --inbound=vmess-in '{\"id\": \"27848739-7e62-4138-9fd3-098a63964b6b\", \"email\": \"love@v2fly.org\"}'",
+			"v2ctl api --server=127.0.0.1:8080 stats --reset user>>>",
 		},
 	}
 }
@@ -52,6 +64,20 @@ func (c *ApiCommand) Execute(args []string) error {
 	}
 
 	unnamedArgs := fs.Args()
+	if len(unnamedArgs) < 1 {
+		return newError("service name or request not specified.")
+	}
+
+	if !strings.Contains(unnamedArgs[0], ".") {
+		subcommand, found := apiSubcommands[strings.ToLower(unnamedArgs[0])]
+		if !found {
+			return newError("unknown subcommand: ", unnamedArgs[0])
+		}
+		return callAPI(*serverAddrPtr, func(ctx context.Context, conn *grpc.ClientConn) error {
+			return subcommand(ctx, conn, unnamedArgs[1:])
+		})
+	}
+
 	if len(unnamedArgs) < 2 {
 		return newError("service name or request not specified.")
 	}
@@ -62,22 +88,28 @@ func (c *ApiCommand) Execute(args []string) error {
 		return newError("unknown service: ", service)
 	}
 
+	return callAPI(*serverAddrPtr, func(ctx context.Context, conn *grpc.ClientConn) error {
+		response, err := handler(ctx, conn, method, unnamedArgs[1])
+		if err != nil {
+			return newError("failed to call service ", unnamedArgs[0]).Base(err)
+		}
+		fmt.Println(response)
+		return nil
+	})
+}
+
+// callAPI runs f with a connection to the API server.
+func callAPI(server string, f func(ctx context.Context, conn *grpc.ClientConn) error) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, *serverAddrPtr, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := grpc.DialContext(ctx, server, grpc.WithInsecure(), grpc.WithBlock())
 	if err != nil {
-		return newError("failed to dial ", *serverAddrPtr).Base(err)
+		return newError("failed to dial ", server).Base(err)
 	}
 	defer conn.Close()
 
-	response, err := handler(ctx, conn, method, unnamedArgs[1])
-	if err != nil {
-		return newError("failed to call service ", unnamedArgs[0]).Base(err)
-	}
-
-	fmt.Println(response)
-	return nil
+	return f(ctx, conn)
 }
 
 func getServiceMethod(s string) (string, string) {
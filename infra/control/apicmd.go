@@ -0,0 +1,207 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+
+	handlerService "v2ray.com/core/app/proxyman/command"
+	reloadService "v2ray.com/core/app/reload/command"
+	statsService "v2ray.com/core/app/stats/command"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/infra/conf"
+	jsonConf "v2ray.com/core/infra/conf/serial"
+	"v2ray.com/core/proxy/shadowsocks"
+	vlessInbound "v2ray.com/core/proxy/vless/inbound"
+	vmessInbound "v2ray.com/core/proxy/vmess/inbound"
+)
+
+type apiSubcommand func(ctx context.Context, conn *grpc.ClientConn, args []string) error
+
+var apiSubcommands = map[string]apiSubcommand{
+	"stats":      queryStats,
+	"adduser":    addUser,
+	"rmuser":     removeUser,
+	"addinbound": addInbound,
+	"rminbound":  removeInbound,
+	"conns":      listConnections,
+	"reload":     reload,
+}
+
+func queryStats(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	reset := fs.Bool("reset", false, "Reset the counters after query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := statsService.NewStatsServiceClient(conn).QueryStats(ctx, &statsService.QueryStatsRequest{
+		Pattern: fs.Arg(0),
+		Reset_:  *reset,
+	})
+	if err != nil {
+		return newError("failed to query stats").Base(err)
+	}
+
+	stats := resp.Stat
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, stat := range stats {
+		fmt.Fprintf(w, "%s\t%d\n", stat.Name, stat.Value)
+	}
+	return w.Flush()
+}
+
+// buildUser builds a user from JSON, in the same format as the clients in inbound settings.
+func buildUser(proxyProtocol string, user []byte) (*protocol.User, error) {
+	switch proxyProtocol {
+	case "vmess":
+		config, err := (&conf.VMessInboundConfig{Users: []json.RawMessage{user}}).Build()
+		if err != nil {
+			return nil, err
+		}
+		return config.(*vmessInbound.Config).User[0], nil
+	case "vless":
+		config, err := (&conf.VLessInboundConfig{Clients: []json.RawMessage{user}, Decryption: "none"}).Build()
+		if err != nil {
+			return nil, err
+		}
+		return config.(*vlessInbound.Config).Clients[0], nil
+	case "shadowsocks":
+		client := new(conf.ShadowsocksUserConfig)
+		if err := json.Unmarshal(user, client); err != nil {
+			return nil, err
+		}
+		config, err := (&conf.ShadowsocksServerConfig{Clients: []*conf.ShadowsocksUserConfig{client}}).Build()
+		if err != nil {
+			return nil, err
+		}
+		return config.(*shadowsocks.ServerConfig).Users[0], nil
+	default:
+		return nil, newError("users of ", proxyProtocol, " can't be managed")
+	}
+}
+
+func addUser(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("adduser", flag.ContinueOnError)
+	tag := fs.String("inbound", "", "Tag of the inbound")
+	proxyProtocol := fs.String("protocol", "vmess", "Protocol of the inbound, vmess, vless or shadowsocks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tag == "" || fs.NArg() != 1 {
+		return newError("inbound tag and user must be specified")
+	}
+
+	user, err := buildUser(*proxyProtocol, []byte(fs.Arg(0)))
+	if err != nil {
+		return newError("invalid user").Base(err)
+	}
+	if _, err := handlerService.NewHandlerServiceClient(conn).AlterInbound(ctx, &handlerService.AlterInboundRequest{
+		Tag:       *tag,
+		Operation: serial.ToTypedMessage(&handlerService.AddUserOperation{User: user}),
+	}); err != nil {
+		return newError("failed to add user").Base(err)
+	}
+	fmt.Println("Added user", user.Email, "to", *tag)
+	return nil
+}
+
+func removeUser(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("rmuser", flag.ContinueOnError)
+	tag := fs.String("inbound", "", "Tag of the inbound")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tag == "" || fs.NArg() != 1 {
+		return newError("inbound tag and email must be specified")
+	}
+
+	if _, err := handlerService.NewHandlerServiceClient(conn).AlterInbound(ctx, &handlerService.AlterInboundRequest{
+		Tag:       *tag,
+		Operation: serial.ToTypedMessage(&handlerService.RemoveUserOperation{Email: fs.Arg(0)}),
+	}); err != nil {
+		return newError("failed to remove user").Base(err)
+	}
+	fmt.Println("Removed user", fs.Arg(0), "from", *tag)
+	return nil
+}
+
+func addInbound(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	if len(args) != 1 {
+		return newError("config file must be specified")
+	}
+	r, err := (&ConfigCommand{}).LoadArg(args[0])
+	if err != nil {
+		return newError("failed to read ", args[0]).Base(err)
+	}
+	config, err := jsonConf.DecodeJSONConfig(r)
+	if err != nil {
+		return err
+	}
+	if len(config.InboundConfigs) == 0 {
+		return newError("no inbound in ", args[0])
+	}
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, ic := range config.InboundConfigs {
+		inbound, err := ic.Build()
+		if err != nil {
+			return newError("failed to build inbound ", ic.Tag).Base(err)
+		}
+		if _, err := client.AddInbound(ctx, &handlerService.AddInboundRequest{Inbound: inbound}); err != nil {
+			return newError("failed to add inbound ", ic.Tag).Base(err)
+		}
+		fmt.Println("Added inbound", ic.Tag)
+	}
+	return nil
+}
+
+func removeInbound(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	if len(args) == 0 {
+		return newError("inbound tags must be specified")
+	}
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, tag := range args {
+		if _, err := client.RemoveInbound(ctx, &handlerService.RemoveInboundRequest{Tag: tag}); err != nil {
+			return newError("failed to remove inbound ", tag).Base(err)
+		}
+		fmt.Println("Removed inbound", tag)
+	}
+	return nil
+}
+
+func listConnections(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	resp, err := handlerService.NewHandlerServiceClient(conn).ListConnections(ctx, &handlerService.ListConnectionsRequest{})
+	if err != nil {
+		return newError("failed to list connections").Base(err)
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tINBOUND\tOUTBOUND\tSOURCE\tTARGET\tDURATION")
+	for _, c := range resp.Connections {
+		duration := now.Sub(time.Unix(c.Start, 0)).Round(time.Second)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", c.Id, c.InboundTag, c.OutboundTag, c.Source, c.Target, duration)
+	}
+	return w.Flush()
+}
+
+func reload(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	if _, err := reloadService.NewReloadServiceClient(conn).Reload(ctx, &reloadService.ReloadRequest{}); err != nil {
+		return newError("failed to reload").Base(err)
+	}
+	fmt.Println("Reloading")
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"flag"
+	"net"
 	"os"
 	"strings"
 	"time"
@@ -29,6 +30,7 @@ func (l *stringList) Set(v string) error {
 }
 
 type jsonCert struct {
+	Usage       string   `json:"usage,omitempty"`
 	Certificate []string `json:"certificate"`
 	Key         []string `json:"key"`
 }
@@ -44,21 +46,26 @@ func (c *CertificateCommand) Description() Description {
 	return Description{
 		Short: "Generate TLS certificates.",
 		Usage: []string{
-			"v2ctl cert [--ca] [--domain=v2ray.com] [--expire=240h]",
-			"Generate new TLS certificate",
+			"v2ctl cert [--ca] [--domain=v2ray.com] [--expire=240h] [--ca-cert=ca_cert.pem --ca-key=ca_key.pem] [--file=name]",
+			"Generate new TLS certificate, and print it as a certificate object in TLS settings.",
 			"--ca The new certificate is a CA certificate",
-			"--domain Common name for the certificate",
+			"--domain Domain name or IP address for the certificate. Multiple assign is accepted.",
 			"--expire Time until certificate expires. 240h = 10 days.",
+			"--ca-cert, --ca-key Sign the certificate with the given CA, instead of self-signing.",
+			"--file Save certificate in name_cert.pem and name_key.pem.",
 		},
 	}
 }
 
-func (c *CertificateCommand) printJson(certificate *cert.Certificate) {
+func (c *CertificateCommand) printJson(certificate *cert.Certificate, isCA bool) {
 	certPEM, keyPEM := certificate.ToPEM()
 	jCert := &jsonCert{
 		Certificate: strings.Split(strings.TrimSpace(string(certPEM)), "\n"),
 		Key:         strings.Split(strings.TrimSpace(string(keyPEM)), "\n"),
 	}
+	if isCA {
+		jCert.Usage = "issue"
+	}
 	content, err := json.MarshalIndent(jCert, "", "  ")
 	common.Must(err)
 	os.Stdout.Write(content)
@@ -99,10 +106,39 @@ func (c *CertificateCommand) Execute(args []string) error {
 
 	expire := fs.Duration("expire", time.Hour*24*90 /* 90 days */, "Time until the certificate expires. Default value 3 months.")
 
+	caCertFile := fs.String("ca-cert", "", "CA certificate to sign the certificate.")
+	caKeyFile := fs.String("ca-key", "", "Private key of the CA certificate.")
+
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *expire <= 0 {
+		return newError("invalid expire: ", *expire)
+	}
+
+	var parent *cert.Certificate
+	if len(*caCertFile) > 0 || len(*caKeyFile) > 0 {
+		if len(*caCertFile) == 0 || len(*caKeyFile) == 0 {
+			return newError("both --ca-cert and --ca-key are required to sign the certificate")
+		}
+		caCert, err := cert.Load(*caCertFile, *caKeyFile)
+		if err != nil {
+			return newError("failed to load CA certificate").Base(err)
+		}
+		parent = caCert
+	}
+
+	var dnsNames []string
+	var ips []net.IP
+	for _, name := range domainNames {
+		if ip := net.ParseIP(name); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+
 	var opts []cert.Option
 	if *isCA {
 		opts = append(opts, cert.Authority(*isCA))
@@ -110,19 +146,32 @@ func (c *CertificateCommand) Execute(args []string) error {
 	}
 
 	opts = append(opts, cert.NotAfter(time.Now().Add(*expire)))
+	// Use the domain name as common name, unless specified, so that the certificate is not mistaken for its CA.
+	nameSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "name" {
+			nameSet = true
+		}
+	})
+	if !nameSet && len(domainNames) > 0 {
+		*commonName = domainNames[0]
+	}
 	opts = append(opts, cert.CommonName(*commonName))
-	if len(domainNames) > 0 {
-		opts = append(opts, cert.DNSNames(domainNames...))
+	if len(dnsNames) > 0 {
+		opts = append(opts, cert.DNSNames(dnsNames...))
+	}
+	if len(ips) > 0 {
+		opts = append(opts, cert.IPAddresses(ips...))
 	}
 	opts = append(opts, cert.Organization(*organization))
 
-	cert, err := cert.Generate(nil, opts...)
+	cert, err := cert.Generate(parent, opts...)
 	if err != nil {
 		return newError("failed to generate TLS certificate").Base(err)
 	}
 
 	if *jsonOutput {
-		c.printJson(cert)
+		c.printJson(cert, *isCA)
 	}
 
 	if len(*fileOutput) > 0 {
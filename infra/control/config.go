@@ -83,6 +83,23 @@ func (c *ConfigCommand) LoadArg(arg string) (out io.Reader, err error) {
 	return
 }
 
+// loadJSONConfig loads and merges JSON configs, from files, URLs or stdin:.
+func loadJSONConfig(args []string) (*conf.Config, error) {
+	config := &conf.Config{}
+	for _, arg := range args {
+		r, err := (&ConfigCommand{}).LoadArg(arg)
+		if err != nil {
+			return nil, newError("failed to read ", arg).Base(err)
+		}
+		c, err := serial.DecodeJSONConfig(r)
+		if err != nil {
+			return nil, err
+		}
+		config.Override(c, arg)
+	}
+	return config, nil
+}
+
 func init() {
 	common.Must(RegisterCommand(&ConfigCommand{}))
 }
@@ -0,0 +1,155 @@
+package control
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/infra/conf"
+	"v2ray.com/core/infra/conf/convert"
+)
+
+type ConvertCommand struct{}
+
+func (c *ConvertCommand) Name() string {
+	return "convert"
+}
+
+func (c *ConvertCommand) Description() Description {
+	return Description{
+		Short: "Convert configs between formats",
+		Usage: []string{
+			"v2ctl convert [--from=format] [--to=format] <file>|<url>|stdin: ...",
+			"Convert configs between formats, after validating them. Output is written to stdout.",
+			"--from Format of the inputs: json, yaml, protobuf, clash or sip008. Detected from file extensions by default.",
+			"--to Format of the output: json, yaml or protobuf. Default json.",
+			"Proxies in Clash configs and servers in SIP008 configs are converted into outbounds.",
+			"Multiple inputs are merged, only if the output is protobuf.",
+			"Protobuf inputs are dumped for inspection. The dump can't be loaded as a JSON config.",
+		},
+	}
+}
+
+// formatOf returns the format of an input by its extension.
+func formatOf(arg string) string {
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".pb":
+		return "protobuf"
+	default:
+		return "json"
+	}
+}
+
+// toJSON converts an input into V2Ray JSON config.
+func toJSON(data []byte, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return data, nil
+	case "yaml":
+		return convert.YAMLToJSON(data)
+	case "clash":
+		return convert.FromClash(data)
+	case "sip008":
+		return convert.FromSIP008(data)
+	default:
+		return nil, newError("unknown input format: ", format)
+	}
+}
+
+func (c *ConvertCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	from := fs.String("from", "", "")
+	to := fs.String("to", "json", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		return newError("no input is specified")
+	}
+	switch *to {
+	case "json", "yaml":
+		if len(inputs) > 1 {
+			return newError("multiple inputs can only be merged into protobuf")
+		}
+	case "protobuf":
+	default:
+		return newError("unknown output format: ", *to)
+	}
+
+	merged := &conf.Config{}
+	var output []byte
+	for _, arg := range inputs {
+		r, err := (&ConfigCommand{}).LoadArg(arg)
+		if err != nil {
+			return newError("failed to read ", arg).Base(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return newError("failed to read ", arg).Base(err)
+		}
+		format := *from
+		if format == "" {
+			format = formatOf(arg)
+		}
+
+		if format == "protobuf" {
+			if *to == "protobuf" {
+				return newError("protobuf inputs can only be dumped into json or yaml")
+			}
+			output, err = convert.ProtobufToJSON(data)
+			if err != nil {
+				return newError("failed to convert ", arg).Base(err)
+			}
+			continue
+		}
+
+		jsonConfig, err := toJSON(data, format)
+		if err != nil {
+			return newError("failed to convert ", arg).Base(err)
+		}
+		if _, err := convert.Validate(jsonConfig); err != nil {
+			return newError("failed to validate ", arg).Base(err)
+		}
+		if *to == "protobuf" {
+			config, err := convert.ParseJSON(jsonConfig)
+			common.Must(err)
+			merged.Override(config, arg)
+		}
+		output = jsonConfig
+	}
+
+	switch *to {
+	case "yaml":
+		yamlConfig, err := convert.JSONToYAML(output)
+		if err != nil {
+			return err
+		}
+		output = yamlConfig
+	case "protobuf":
+		pbConfig, err := merged.Build()
+		if err != nil {
+			return err
+		}
+		output, err = proto.Marshal(pbConfig)
+		if err != nil {
+			return newError("failed to marshal proto config").Base(err)
+		}
+	}
+
+	if _, err := os.Stdout.Write(output); err != nil {
+		return newError("failed to write output").Base(err)
+	}
+	return nil
+}
+
+func init() {
+	common.Must(RegisterCommand(&ConvertCommand{}))
+}
@@ -0,0 +1,151 @@
+package control
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/infra/conf/geodata"
+)
+
+type GeoDataCommand struct{}
+
+func (c *GeoDataCommand) Name() string {
+	return "geodata"
+}
+
+func (c *GeoDataCommand) Description() Description {
+	return Description{
+		Short: "Compile domain and CIDR lists into geosite and geoip files",
+		Usage: []string{
+			"v2ctl geodata [--type=site] [--output=file] <dir>|<file>...",
+			"Compile plaintext lists into a file for geosite: or geoip: rules. Each file is a category named after the file, without extension.",
+			"Files in a directory are all compiled. Duplicated entries are merged.",
+			"--type Type of the lists, site or ip. Default site.",
+			"--output Output file. Default geosite.dat or geoip.dat.",
+			"Each line of a site list is a domain, optionally prefixed by domain:, full:, keyword: or regexp:, and followed by attributes like @ads.",
+			"include:name includes all domains of another category.",
+			"Each line of an ip list is a CIDR or an IP address. Comments start with #.",
+		},
+	}
+}
+
+// listFiles returns the files of the arguments, keyed by category.
+func listFiles(args []string) (map[string]string, error) {
+	files := make(map[string]string)
+	add := func(path string) error {
+		base := filepath.Base(path)
+		category := strings.TrimSuffix(base, filepath.Ext(base))
+		if existing, found := files[category]; found {
+			return newError("category ", category, " in both ", existing, " and ", path)
+		}
+		files[category] = path
+		return nil
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := add(arg); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if err := add(filepath.Join(arg, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+func (c *GeoDataCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	dataType := fs.String("type", "site", "Type of the lists, site or ip")
+	output := fs.String("output", "", "Output file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return newError("no list specified")
+	}
+
+	files, err := listFiles(fs.Args())
+	if err != nil {
+		return newError("failed to list files").Base(err)
+	}
+	lists := make(map[string]io.Reader, len(files))
+	for category, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		lists[category] = f
+	}
+
+	var message proto.Message
+	var summary []string
+	switch strings.ToLower(*dataType) {
+	case "site":
+		siteList, err := geodata.CompileSite(lists)
+		if err != nil {
+			return err
+		}
+		for _, site := range siteList.Entry {
+			summary = append(summary, fmt.Sprint(site.CountryCode, ": ", len(site.Domain), " domains"))
+		}
+		message = siteList
+	case "ip":
+		ipList, err := geodata.CompileIP(lists)
+		if err != nil {
+			return err
+		}
+		for _, geoip := range ipList.Entry {
+			summary = append(summary, fmt.Sprint(geoip.CountryCode, ": ", len(geoip.Cidr), " CIDRs"))
+		}
+		message = ipList
+	default:
+		return newError("unknown type ", *dataType)
+	}
+
+	if *output == "" {
+		*output = "geo" + strings.ToLower(*dataType) + ".dat"
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return newError("failed to marshal geo data").Base(err)
+	}
+	if err := ioutil.WriteFile(*output, data, 0644); err != nil {
+		return newError("failed to write ", *output).Base(err)
+	}
+
+	for _, line := range summary {
+		fmt.Println(line)
+	}
+	fmt.Println("Written to", *output)
+	return nil
+}
+
+func init() {
+	common.Must(RegisterCommand(&GeoDataCommand{}))
+}
@@ -0,0 +1,117 @@
+package control
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+
+	"v2ray.com/core/common"
+)
+
+type KeyPairCommand struct{}
+
+func (c *KeyPairCommand) Name() string {
+	return "keypair"
+}
+
+func (c *KeyPairCommand) Description() Description {
+	return Description{
+		Short: "Generate key pairs",
+		Usage: []string{
+			"v2ctl keypair [--type=x25519] [--private=key]",
+			"Generate a new key pair, with keys encoded in URL-safe base64 without padding.",
+			"--type Type of the key pair, x25519 or ed25519.",
+			"--private Derive the public key from this private key, instead of generating a new one.",
+		},
+	}
+}
+
+// decodeKey accepts keys in standard or URL-safe base64, with or without padding.
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if strings.ContainsAny(s, "+/") {
+		return base64.RawStdEncoding.DecodeString(s)
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func generateX25519(private []byte) ([]byte, []byte, error) {
+	if private == nil {
+		private = make([]byte, curve25519.ScalarSize)
+		if _, err := rand.Read(private); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(private) != curve25519.ScalarSize {
+		return nil, nil, newError("invalid length of x25519 private key: ", len(private))
+	}
+	// Clamp the scalar as in RFC 7748, so that the private key is accepted by all implementations.
+	private[0] &= 248
+	private[31] &= 127
+	private[31] |= 64
+
+	public, err := curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, public, nil
+}
+
+func generateEd25519(private []byte) ([]byte, []byte, error) {
+	if private == nil {
+		private = make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(private); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(private) != ed25519.SeedSize {
+		return nil, nil, newError("invalid length of ed25519 private key: ", len(private))
+	}
+	key := ed25519.NewKeyFromSeed(private)
+	return private, []byte(key.Public().(ed25519.PublicKey)), nil
+}
+
+func (c *KeyPairCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	keyType := fs.String("type", "x25519", "Type of the key pair, x25519 or ed25519")
+	privateKey := fs.String("private", "", "Private key to derive the public key from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var private []byte
+	if len(*privateKey) > 0 {
+		key, err := decodeKey(*privateKey)
+		if err != nil {
+			return newError("invalid private key").Base(err)
+		}
+		private = key
+	}
+
+	var public []byte
+	var err error
+	switch strings.ToLower(*keyType) {
+	case "x25519":
+		private, public, err = generateX25519(private)
+	case "ed25519":
+		private, public, err = generateEd25519(private)
+	default:
+		return newError("unknown key type: ", *keyType)
+	}
+	if err != nil {
+		return newError("failed to generate key pair").Base(err)
+	}
+
+	fmt.Println("Private key:", base64.RawURLEncoding.EncodeToString(private))
+	fmt.Println("Public key:", base64.RawURLEncoding.EncodeToString(public))
+	return nil
+}
+
+func init() {
+	common.Must(RegisterCommand(&KeyPairCommand{}))
+}
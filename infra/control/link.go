@@ -0,0 +1,89 @@
+package control
+
+import (
+	"flag"
+	"fmt"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/cmdarg"
+	"v2ray.com/core/infra/conf/share"
+)
+
+type LinkCommand struct{}
+
+func (c *LinkCommand) Name() string {
+	return "link"
+}
+
+func (c *LinkCommand) Description() Description {
+	return Description{
+		Short: "Export share links of inbound users",
+		Usage: []string{
+			"v2ctl link -config <file> --inbound tag [--user email] [--address host] [--remark name] [--qr]",
+			"Print the share link of a user, derived from the inbound in the server config.",
+			"Links of VMess (in the format of V2RayN), VLESS and Shadowsocks (SIP002) are supported.",
+			"-config Config file, URL or stdin:. Multiple configs are merged.",
+			"--user Email of the user. It may be omitted if the inbound has only one user.",
+			"--address Address that clients connect to. Default to the server name in TLS settings.",
+			"--remark Remark of the link. Default to the email of the user.",
+			"--qr Print the link as a QR code as well.",
+		},
+	}
+}
+
+func (c *LinkCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+
+	var configFiles cmdarg.Arg
+	fs.Var(&configFiles, "config", "Config file, URL or stdin:")
+	inboundTag := fs.String("inbound", "", "Tag of the inbound")
+	user := fs.String("user", "", "Email of the user")
+	address := fs.String("address", "", "Address that clients connect to")
+	remark := fs.String("remark", "", "Remark of the link")
+	qr := fs.Bool("qr", false, "Print the link as a QR code")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(configFiles) == 0 {
+		return newError("config must be specified")
+	}
+	if *inboundTag == "" {
+		return newError("inbound tag must be specified")
+	}
+
+	jsonConfig, err := loadJSONConfig(configFiles)
+	if err != nil {
+		return err
+	}
+	config, err := jsonConfig.Build()
+	if err != nil {
+		return newError("invalid config").Base(err)
+	}
+
+	for _, inbound := range config.Inbound {
+		if inbound.Tag != *inboundTag {
+			continue
+		}
+		link, err := share.Link(inbound, *user, share.Options{
+			Address: *address,
+			Remark:  *remark,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(link)
+		if *qr {
+			code, err := share.QRCode(link)
+			if err != nil {
+				return err
+			}
+			fmt.Print(code)
+		}
+		return nil
+	}
+	return newError("inbound ", *inboundTag, " not found")
+}
+
+func init() {
+	common.Must(RegisterCommand(&LinkCommand{}))
+}
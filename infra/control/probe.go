@@ -0,0 +1,231 @@
+// +build !confonly
+
+package control
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	gonet "net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/cmdarg"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/features/outbound"
+	"v2ray.com/core/infra/conf"
+	// The outbound runs in a V2Ray instance, which needs all features that the config may use.
+	_ "v2ray.com/core/main/distro/all"
+	"v2ray.com/core/transport"
+	"v2ray.com/core/transport/pipe"
+)
+
+type ProbeCommand struct{}
+
+func (c *ProbeCommand) Name() string {
+	return "probe"
+}
+
+func (c *ProbeCommand) Description() Description {
+	return Description{
+		Short: "Test the reachability of an outbound",
+		Usage: []string{
+			"v2ctl probe -config <file> --outbound tag [--url https://...] [--timeout 10s]",
+			"Send an HTTP request through an outbound in the config, and print the timing, TLS details and HTTP status.",
+			"Inbounds in the config are not started, so the config of a running V2Ray instance can be probed as is.",
+			"-config Config file, URL or stdin:. Multiple configs are merged.",
+			"--url URL to request. Default to https://www.google.com/generate_204.",
+			"--timeout Timeout of the whole request. Default to 10s.",
+		},
+	}
+}
+
+// probeLog keeps the log messages of the V2Ray instance, to explain failed probes.
+type probeLog struct {
+	access   sync.Mutex
+	messages []string
+}
+
+func (l *probeLog) Handle(msg log.Message) {
+	if m, ok := msg.(*log.GeneralMessage); ok && m.Severity > log.Severity_Info {
+		return
+	}
+	l.access.Lock()
+	l.messages = append(l.messages, msg.String())
+	l.access.Unlock()
+}
+
+func (l *probeLog) Messages() []string {
+	l.access.Lock()
+	defer l.access.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+func (c *ProbeCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+
+	var configFiles cmdarg.Arg
+	fs.Var(&configFiles, "config", "Config file, URL or stdin:")
+	outboundTag := fs.String("outbound", "", "Tag of the outbound")
+	target := fs.String("url", "https://www.google.com/generate_204", "URL to request")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of the request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(configFiles) == 0 {
+		return newError("config must be specified")
+	}
+	if *outboundTag == "" {
+		return newError("outbound tag must be specified")
+	}
+	u, err := url.Parse(*target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return newError("invalid URL ", *target)
+	}
+
+	jsonConfig, err := loadJSONConfig(configFiles)
+	if err != nil {
+		return err
+	}
+	// Logs of the instance are only printed when the probe fails.
+	jsonConfig.LogConfig = &conf.LogConfig{
+		AccessLog: "none",
+		ErrorLog:  "none",
+	}
+	config, err := jsonConfig.Build()
+	if err != nil {
+		return newError("invalid config").Base(err)
+	}
+	config.Inbound = nil
+
+	server, err := core.New(config)
+	if err != nil {
+		return newError("failed to create V2Ray instance").Base(err)
+	}
+	if err := server.Start(); err != nil {
+		return newError("failed to start V2Ray instance").Base(err)
+	}
+	defer server.Close()
+
+	handler := server.GetFeature(outbound.ManagerType()).(outbound.Manager).GetHandler(*outboundTag)
+	if handler == nil {
+		return newError("outbound ", *outboundTag, " not found")
+	}
+	logs := new(probeLog)
+	log.RegisterHandler(logs)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, addr string) (gonet.Conn, error) {
+				dest, err := net.ParseDestination(network + ":" + addr)
+				if err != nil {
+					return nil, err
+				}
+				ctx = session.ContextWithID(ctx, session.NewID())
+				ctx = session.ContextWithOutbound(ctx, &session.Outbound{Target: dest})
+
+				opt := pipe.OptionsFromContext(ctx)
+				uplinkReader, uplinkWriter := pipe.New(opt...)
+				downlinkReader, downlinkWriter := pipe.New(opt...)
+				go handler.Dispatch(ctx, &transport.Link{
+					Reader: uplinkReader,
+					Writer: downlinkWriter,
+				})
+				return net.NewConnection(net.ConnectionInputMulti(uplinkWriter), net.ConnectionOutputMulti(downlinkReader)), nil
+			},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+		Timeout: *timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var tlsStart, tlsDone, firstByte time.Time
+	var tlsState *tls.ConnectionState
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			tlsDone = time.Now()
+			if err == nil {
+				tlsState = &state
+			}
+		},
+		GotFirstResponseByte: func() {
+			firstByte = time.Now()
+		},
+	})
+	request, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return newError("failed to create request").Base(err)
+	}
+
+	fmt.Println("Outbound:", *outboundTag)
+	fmt.Println("URL:", u.String())
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		if messages := logs.Messages(); len(messages) > 0 {
+			fmt.Println("Log:")
+			fmt.Println(strings.Join(messages, "\n"))
+		}
+		return newError("failed to request ", u.String(), " through outbound ", *outboundTag).Base(err)
+	}
+	defer response.Body.Close()
+	size, err := io.Copy(ioutil.Discard, response.Body)
+	end := time.Now()
+
+	if tlsState != nil {
+		fmt.Println("TLS handshake:", tlsDone.Sub(tlsStart).Round(time.Millisecond))
+		fmt.Println("TLS version:", tlsVersionName(tlsState.Version))
+		fmt.Println("TLS cipher suite:", tls.CipherSuiteName(tlsState.CipherSuite))
+		if tlsState.NegotiatedProtocol != "" {
+			fmt.Println("TLS ALPN:", tlsState.NegotiatedProtocol)
+		}
+		if len(tlsState.PeerCertificates) > 0 {
+			cert := tlsState.PeerCertificates[0]
+			fmt.Println("TLS certificate:", cert.Subject.CommonName, "issued by", cert.Issuer.CommonName, "expires", cert.NotAfter.Format("2006-01-02"))
+		}
+	}
+	fmt.Println("First byte:", firstByte.Sub(start).Round(time.Millisecond))
+	fmt.Println("HTTP status:", response.Proto, response.Status)
+	fmt.Println("Body:", size, "bytes")
+	fmt.Println("Total:", end.Sub(start).Round(time.Millisecond))
+	if err != nil {
+		return newError("failed to read response body").Base(err)
+	}
+	return nil
+}
+
+func init() {
+	common.Must(RegisterCommand(&ProbeCommand{}))
+}
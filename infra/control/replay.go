@@ -0,0 +1,234 @@
+// +build !confonly
+
+package control
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/cmdarg"
+	"v2ray.com/core/common/errors"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/record"
+	"v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/proxy/vless"
+	vlessEncoding "v2ray.com/core/proxy/vless/encoding"
+	vlessInbound "v2ray.com/core/proxy/vless/inbound"
+	"v2ray.com/core/proxy/vmess"
+	vmessEncoding "v2ray.com/core/proxy/vmess/encoding"
+	vmessInbound "v2ray.com/core/proxy/vmess/inbound"
+)
+
+type ReplayCommand struct{}
+
+func (c *ReplayCommand) Name() string {
+	return "replay"
+}
+
+func (c *ReplayCommand) Description() Description {
+	return Description{
+		Short: "Replay a recorded session through protocol parsers",
+		Usage: []string{
+			"v2ctl replay -config <file> [--inbound tag] [--dump] <session.json>",
+			"Decode the bytes of a session recorded by the \"record\" settings of an inbound, with the users in the config.",
+			"The request header and payload are printed, and the payload is compared with the recorded plaintext.",
+			"VMess, VLESS and Shadowsocks are supported. VMess requests are validated at the time they were recorded.",
+			"-config Config file, URL or stdin:. Multiple configs are merged.",
+			"--inbound Tag of the inbound. Default to the inbound that the session is recorded on.",
+			"--dump Print the hex dump of the payload.",
+		},
+	}
+}
+
+// replayResult is the outcome of decoding a recorded session.
+type replayResult struct {
+	request *protocol.RequestHeader
+	payload []byte
+}
+
+func (c *ReplayCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+
+	var configFiles cmdarg.Arg
+	fs.Var(&configFiles, "config", "Config file, URL or stdin:")
+	inboundTag := fs.String("inbound", "", "Tag of the inbound")
+	dump := fs.Bool("dump", false, "Print the hex dump of the payload")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(configFiles) == 0 {
+		return newError("config must be specified")
+	}
+	if fs.NArg() != 1 {
+		return newError("a recorded session must be specified")
+	}
+
+	s, err := record.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *inboundTag == "" {
+		*inboundTag = s.Inbound
+	}
+
+	jsonConfig, err := loadJSONConfig(configFiles)
+	if err != nil {
+		return err
+	}
+	config, err := jsonConfig.Build()
+	if err != nil {
+		return newError("invalid config").Base(err)
+	}
+	var inbound *core.InboundHandlerConfig
+	for _, ib := range config.Inbound {
+		if ib.Tag == *inboundTag {
+			inbound = ib
+			break
+		}
+	}
+	if inbound == nil {
+		return newError("inbound ", *inboundTag, " not found")
+	}
+
+	fmt.Println("Recorded:", s.Time.Format("2006-01-02 15:04:05"), "from", s.Source)
+	if s.Error != "" {
+		fmt.Println("Recorded error:", s.Error)
+	}
+	if s.Truncated {
+		fmt.Println("The session is truncated, the payload may be incomplete.")
+	}
+
+	result, err := replay(s, inbound)
+	if result != nil && result.request != nil {
+		if user := result.request.User; user != nil && user.Email != "" {
+			fmt.Println("User:", user.Email)
+		}
+		fmt.Println("Request:", result.request.Destination())
+		if result.request.Security != protocol.SecurityType_UNKNOWN {
+			fmt.Println("Security:", result.request.Security)
+		}
+		fmt.Println("Payload:", len(result.payload), "bytes")
+		if plain := s.Data(record.Receive, record.Plain); len(plain) > 0 {
+			if bytes.Equal(plain, result.payload) {
+				fmt.Println("Payload matches the recorded plaintext.")
+			} else {
+				fmt.Println("Payload differs from the recorded plaintext of", len(plain), "bytes, at byte", mismatch(plain, result.payload))
+			}
+		}
+		if *dump && len(result.payload) > 0 {
+			fmt.Print(hex.Dump(result.payload))
+		}
+	}
+	if err != nil {
+		return newError("failed to replay session").Base(err)
+	}
+	return nil
+}
+
+func mismatch(a []byte, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) < len(b) {
+		return len(a)
+	}
+	return len(b)
+}
+
+// replay decodes the bytes received in the session with the proxy of the inbound. The result is returned as far as it
+// is decoded, along with the error that stops decoding.
+func replay(s *record.Session, inbound *core.InboundHandlerConfig) (*replayResult, error) {
+	rawProxy, err := inbound.ProxySettings.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	reader := &buf.BufferedReader{Reader: buf.NewReader(bytes.NewReader(s.Data(record.Receive, record.Raw)))}
+	result := new(replayResult)
+
+	var body buf.Reader
+	switch config := rawProxy.(type) {
+	case *vmessInbound.Config:
+		fmt.Println("Protocol: VMess")
+		validator := vmess.NewTimedUserValidatorAt(protocol.DefaultIDHash, s.Time)
+		defer validator.Close()
+		for _, user := range config.User {
+			u, err := user.ToMemoryUser()
+			if err != nil {
+				return nil, newError("failed to get VMess user").Base(err)
+			}
+			common.Must(validator.Add(u))
+		}
+		sessionHistory := vmessEncoding.NewSessionHistory()
+		defer sessionHistory.Close()
+
+		session := vmessEncoding.NewServerSession(validator, sessionHistory)
+		request, err := session.DecodeRequestHeader(reader)
+		if err != nil {
+			return result, err
+		}
+		result.request = request
+		body = session.DecodeRequestBody(request, reader)
+	case *vlessInbound.Config:
+		fmt.Println("Protocol: VLESS")
+		validator := new(vless.Validator)
+		for _, user := range config.Clients {
+			u, err := user.ToMemoryUser()
+			if err != nil {
+				return nil, newError("failed to get VLESS user").Base(err)
+			}
+			common.Must(validator.Add(u))
+		}
+		request, addons, err, _ := vlessEncoding.DecodeRequestHeader(reader, validator)
+		if err != nil {
+			return result, err
+		}
+		result.request = request
+		body = vlessEncoding.DecodeBodyAddons(reader, request, addons)
+	case *shadowsocks.ServerConfig:
+		fmt.Println("Protocol: Shadowsocks")
+		validator := new(shadowsocks.Validator)
+		for _, user := range config.AllUsers() {
+			u, err := user.ToMemoryUser()
+			if err != nil {
+				return nil, newError("failed to get Shadowsocks user").Base(err)
+			}
+			if err := validator.Add(u); err != nil {
+				return nil, newError("failed to add Shadowsocks user").Base(err)
+			}
+		}
+		request, bodyReader, err := validator.ReadTCPSession(reader)
+		if err != nil {
+			return result, err
+		}
+		result.request = request
+		body = bodyReader
+	default:
+		return nil, newError("inbound ", inbound.Tag, " can't be replayed")
+	}
+
+	for {
+		mb, err := body.ReadMultiBuffer()
+		for _, b := range mb {
+			result.payload = append(result.payload, b.Bytes()...)
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				return result, nil
+			}
+			return result, err
+		}
+	}
+}
+
+func init() {
+	common.Must(RegisterCommand(&ReplayCommand{}))
+}
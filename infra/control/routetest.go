@@ -0,0 +1,200 @@
+// +build !confonly
+
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/cmdarg"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/features/dns/localdns"
+	"v2ray.com/core/features/outbound"
+	routing_session "v2ray.com/core/features/routing/session"
+	"v2ray.com/core/infra/conf"
+)
+
+type RouteTestCommand struct{}
+
+func (c *RouteTestCommand) Name() string {
+	return "route-test"
+}
+
+func (c *RouteTestCommand) Description() Description {
+	return Description{
+		Short: "Test which outbound a connection is routed to",
+		Usage: []string{
+			"v2ctl route-test -config <file> --dest <host:port> [--inbound tag] [--user email] [--source ip:port] [--protocol name] [--expect tag]",
+			"Load the config offline, and print the routing rule that matches the connection and the outbound it is sent to.",
+			"-config Config file, URL or stdin:. Multiple configs are merged.",
+			"--dest Destination of the connection. Prefix it with udp: for UDP, for example udp:8.8.8.8:53.",
+			"--protocol Sniffed protocol of the connection, for example http, tls or bittorrent.",
+			"--expect Exit with an error if the connection is not routed to the outbound, for checks in CI.",
+			"Domain names are resolved by the system resolver, only if the domain strategy of the routing config requires IPs.",
+		},
+	}
+}
+
+// routeTestOutbounds is an outbound.Manager that only knows the tags of outbounds, so that balancers can be built
+// without creating the outbounds.
+type routeTestOutbounds struct {
+	outbound.Manager
+	tags     []string
+	selected []string
+}
+
+func (o *routeTestOutbounds) Select(selectors []string) []string {
+	var tags []string
+	for _, tag := range o.tags {
+		for _, selector := range selectors {
+			if strings.HasPrefix(tag, selector) {
+				tags = append(tags, tag)
+				break
+			}
+		}
+	}
+	o.selected = tags
+	return tags
+}
+
+func (c *RouteTestCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+
+	var configFiles cmdarg.Arg
+	fs.Var(&configFiles, "config", "Config file, URL or stdin:")
+	dest := fs.String("dest", "", "Destination of the connection")
+	inboundTag := fs.String("inbound", "", "Tag of the inbound that accepts the connection")
+	user := fs.String("user", "", "Email of the user")
+	source := fs.String("source", "", "Source address of the connection")
+	sniffed := fs.String("protocol", "", "Sniffed protocol of the connection")
+	expect := fs.String("expect", "", "Expected outbound tag")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(configFiles) == 0 {
+		return newError("config must be specified")
+	}
+	if *dest == "" {
+		return newError("destination must be specified")
+	}
+
+	jsonConfig, err := loadJSONConfig(configFiles)
+	if err != nil {
+		return err
+	}
+	config, err := jsonConfig.Build()
+	if err != nil {
+		return newError("invalid config").Base(err)
+	}
+
+	target, err := net.ParseDestination(*dest)
+	if err != nil {
+		return newError("invalid destination ", *dest).Base(err)
+	}
+	if target.Network == net.Network_Unknown {
+		target.Network = net.Network_TCP
+	}
+	inbound := &session.Inbound{Tag: *inboundTag}
+	if *user != "" {
+		inbound.User = &protocol.MemoryUser{Email: *user}
+	}
+	if *source != "" {
+		src, err := net.ParseDestination(*source)
+		if err != nil {
+			return newError("invalid source ", *source).Base(err)
+		}
+		src.Network = target.Network
+		inbound.Source = src
+	}
+	ctx := session.ContextWithInbound(context.Background(), inbound)
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{Target: target})
+	if *sniffed != "" {
+		ctx = session.ContextWithContent(ctx, &session.Content{Protocol: *sniffed})
+	}
+
+	outbounds := &routeTestOutbounds{}
+	for _, ob := range config.Outbound {
+		outbounds.tags = append(outbounds.tags, ob.Tag)
+	}
+	if len(outbounds.tags) == 0 {
+		return newError("no outbound in config")
+	}
+
+	var rule *router.Rule
+	if routerConfig := findRouterConfig(config); routerConfig != nil {
+		r := new(router.Router)
+		if err := r.Init(routerConfig, localdns.New(), outbounds); err != nil {
+			return newError("failed to create router").Base(err)
+		}
+		rule, err = r.PickRule(routing_session.AsRoutingContext(ctx))
+		if err != nil && err != common.ErrNoClue {
+			return err
+		}
+	}
+
+	var tag string
+	if rule == nil {
+		tag = outbounds.tags[0]
+		fmt.Println("Rule: none, routed to the default outbound")
+	} else {
+		tag, err = rule.GetTag()
+		if err != nil {
+			return newError("failed to pick outbound of rule ", rule.Index).Base(err)
+		}
+		fmt.Printf("Rule: #%d %s\n", rule.Index, ruleJSON(jsonConfig.RouterConfig, rule.Index))
+		if rule.Balancer != nil {
+			fmt.Println("Balancer candidates:", strings.Join(outbounds.selected, ", "))
+		}
+	}
+	fmt.Println("Outbound:", tag)
+
+	if *expect != "" && *expect != tag {
+		return newError("expect outbound ", *expect, ", but routed to ", tag)
+	}
+	return nil
+}
+
+func findRouterConfig(config *core.Config) *router.Config {
+	for _, app := range config.App {
+		instance, err := app.GetInstance()
+		if err != nil {
+			continue
+		}
+		if routerConfig, ok := instance.(*router.Config); ok {
+			return routerConfig
+		}
+	}
+	return nil
+}
+
+// ruleJSON returns the compact JSON of a routing rule, in the order that RouterConfig builds them.
+func ruleJSON(c *conf.RouterConfig, index int) string {
+	if c == nil {
+		return ""
+	}
+	rules := c.RuleList
+	if c.Settings != nil {
+		rules = append(rules, c.Settings.RuleList...)
+	}
+	if index >= len(rules) {
+		return ""
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, rules[index]); err != nil {
+		return string(rules[index])
+	}
+	return b.String()
+}
+
+func init() {
+	common.Must(RegisterCommand(&RouteTestCommand{}))
+}
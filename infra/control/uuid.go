@@ -1,6 +1,7 @@
 package control
 
 import (
+	"flag"
 	"fmt"
 
 	"v2ray.com/core/common"
@@ -16,13 +17,25 @@ func (c *UUIDCommand) Name() string {
 func (c *UUIDCommand) Description() Description {
 	return Description{
 		Short: "Generate new UUIDs",
-		Usage: []string{"v2ctl uuid"},
+		Usage: []string{
+			"v2ctl uuid [-n 1]",
+			"Generate new random UUIDs, one per line.",
+			"-n Number of UUIDs to generate.",
+		},
 	}
 }
 
-func (c *UUIDCommand) Execute([]string) error {
-	u := uuid.New()
-	fmt.Println(u.String())
+func (c *UUIDCommand) Execute(args []string) error {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	count := fs.Uint("n", 1, "Number of UUIDs to generate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	for i := uint(0); i < *count; i++ {
+		u := uuid.New()
+		fmt.Println(u.String())
+	}
 	return nil
 }
 
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"v2ray.com/core/main/bench"
+)
+
+// runBench handles "v2ray bench". It relays traffic through the chosen transport on loopback, and reports the
+// performance, so that changes to transports can be compared on the same machine.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	transport := fs.String("transport", "tcp", "Transport to benchmark: tcp, kcp or ws.")
+	size := fs.Int64("size", 64, "Megabytes to transfer in the throughput test.")
+	connections := fs.Int("conns", 4, "Number of concurrent connections in the throughput test.")
+	pings := fs.Int("pings", 200, "Number of round trips in the latency test.")
+	timeout := fs.Duration("timeout", time.Minute, "Time limit of each test.")
+	jsonOutput := fs.Bool("json", false, "Print the result in JSON.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := bench.Run(&bench.Options{
+		Transport:   *transport,
+		Size:        *size * 1024 * 1024,
+		Connections: *connections,
+		Pings:       *pings,
+		Timeout:     *timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Println("Transport: ", result.Transport)
+	fmt.Printf("Throughput: %.2f MB/s (%d MB in %v, each direction)\n", result.Throughput/1024/1024, result.Bytes/1024/1024, result.Duration.Round(time.Millisecond))
+	fmt.Printf("Latency:    p50 %v, p90 %v, p99 %v, max %v\n", result.LatencyP50, result.LatencyP90, result.LatencyP99, result.LatencyMax)
+	if result.CPU >= 0 {
+		fmt.Printf("CPU:        %.0f%%\n", result.CPU*100)
+	}
+	fmt.Printf("Allocation: %.2f MB/s, %.0f objects/s\n", result.AllocBytes/1024/1024, result.Allocs)
+	return nil
+}
@@ -0,0 +1,371 @@
+// Package bench measures the performance of transports, by relaying traffic through a pair of in-process V2Ray
+// instances on loopback.
+package bench
+
+//go:generate errorgen
+
+import (
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
+	_ "v2ray.com/core/app/proxyman/inbound"
+	_ "v2ray.com/core/app/proxyman/outbound"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/proxy/dokodemo"
+	"v2ray.com/core/proxy/freedom"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/kcp"
+	"v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/websocket"
+)
+
+const (
+	chunkSize   = 32 * 1024
+	pingSize    = 64
+	warmupPings = 10
+)
+
+// Options are the parameters of a benchmark.
+type Options struct {
+	// Transport is one of tcp, kcp and ws.
+	Transport string
+	// Size is the total number of bytes to send in the throughput test.
+	Size int64
+	// Connections is the number of concurrent connections in the throughput test.
+	Connections int
+	// Pings is the number of round trips in the latency test.
+	Pings int
+	// Timeout limits the duration of each test.
+	Timeout time.Duration
+}
+
+// Result is the outcome of a benchmark.
+// Durations are in nanoseconds in JSON.
+type Result struct {
+	Transport string `json:"transport"`
+
+	// Bytes are sent and echoed back in the throughput test. Throughput is in bytes per second, in each direction.
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"duration"`
+	Throughput float64       `json:"throughput"`
+
+	// Round trip times of small messages on an established connection.
+	LatencyP50 time.Duration `json:"latencyP50"`
+	LatencyP90 time.Duration `json:"latencyP90"`
+	LatencyP99 time.Duration `json:"latencyP99"`
+	LatencyMax time.Duration `json:"latencyMax"`
+
+	// CPU is the CPU time of the process during the throughput test, divided by the wall time, so 1 means one core
+	// is busy. It is negative if CPU time is not available on this platform. Load generator and echo server are
+	// included.
+	CPU float64 `json:"cpu"`
+	// Allocations during the throughput test, per second.
+	AllocBytes float64 `json:"allocBytes"`
+	Allocs     float64 `json:"allocs"`
+}
+
+func streamConfig(transport string) (*internet.StreamConfig, net.Network, error) {
+	var name string
+	var settings *serial.TypedMessage
+	network := net.Network_TCP
+	switch transport {
+	case "tcp":
+		name, settings = "tcp", serial.ToTypedMessage(&tcp.Config{})
+	case "kcp":
+		name, settings = "mkcp", serial.ToTypedMessage(&kcp.Config{})
+		network = net.Network_UDP
+	case "ws":
+		name, settings = "websocket", serial.ToTypedMessage(&websocket.Config{Path: "/bench"})
+	default:
+		return nil, 0, newError("unsupported transport: ", transport)
+	}
+	return &internet.StreamConfig{
+		ProtocolName: name,
+		TransportSettings: []*internet.TransportConfig{
+			{
+				ProtocolName: name,
+				Settings:     settings,
+			},
+		},
+	}, network, nil
+}
+
+// pickPort returns a free port on loopback.
+func pickPort(network net.Network) (net.Port, error) {
+	if network == net.Network_UDP {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return net.Port(conn.LocalAddr().(*net.UDPAddr).Port), nil
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return net.Port(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+func dokodemoInbound(port net.Port, target net.Destination, stream *internet.StreamConfig) *core.InboundHandlerConfig {
+	return &core.InboundHandlerConfig{
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			PortRange:      net.SinglePortRange(port),
+			Listen:         net.NewIPOrDomain(net.LocalHostIP),
+			StreamSettings: stream,
+		}),
+		ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+			Address: net.NewIPOrDomain(target.Address),
+			Port:    uint32(target.Port),
+			NetworkList: &net.NetworkList{
+				Network: []net.Network{net.Network_TCP},
+			},
+		}),
+	}
+}
+
+// startInstances starts a client instance listening on the returned port, which relays to the echo server through
+// a server instance over the transport.
+func startInstances(transport string, echo net.Destination) (net.Port, []*core.Instance, error) {
+	stream, network, err := streamConfig(transport)
+	if err != nil {
+		return 0, nil, err
+	}
+	serverPort, err := pickPort(network)
+	if err != nil {
+		return 0, nil, err
+	}
+	clientPort, err := pickPort(net.Network_TCP)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{dokodemoInbound(serverPort, echo, stream)},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{dokodemoInbound(clientPort, echo, nil)},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: stream,
+				}),
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{
+					DestinationOverride: &freedom.DestinationOverride{
+						Server: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	var instances []*core.Instance
+	for _, config := range []*core.Config{serverConfig, clientConfig} {
+		config.App = []*serial.TypedMessage{
+			serial.ToTypedMessage(&log.Config{
+				ErrorLogType:  log.LogType_None,
+				AccessLogType: log.LogType_None,
+			}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		}
+		instance, err := core.New(config)
+		if err == nil {
+			err = instance.Start()
+		}
+		if err != nil {
+			closeAll(instances)
+			return 0, nil, newError("failed to start V2Ray").Base(err)
+		}
+		instances = append(instances, instance)
+	}
+	return clientPort, instances, nil
+}
+
+func closeAll(instances []*core.Instance) {
+	for _, instance := range instances {
+		instance.Close()
+	}
+}
+
+// startEcho starts a TCP server on loopback, which echoes everything it receives.
+func startEcho() (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn) // nolint: errcheck
+			}()
+		}
+	}()
+	return listener, nil
+}
+
+// Run runs the latency test and then the throughput test.
+func Run(options *Options) (*Result, error) {
+	echo, err := startEcho()
+	if err != nil {
+		return nil, newError("failed to start echo server").Base(err)
+	}
+	defer echo.Close()
+
+	port, instances, err := startInstances(options.Transport, net.DestinationFromAddr(echo.Addr()))
+	if err != nil {
+		return nil, err
+	}
+	defer closeAll(instances)
+	address := net.TCPDestination(net.LocalHostIP, port).NetAddr()
+
+	result := &Result{
+		Transport: options.Transport,
+	}
+	if err := measureLatency(address, options, result); err != nil {
+		return nil, newError("latency test failed").Base(err)
+	}
+	if err := measureThroughput(address, options, result); err != nil {
+		return nil, newError("throughput test failed").Base(err)
+	}
+	return result, nil
+}
+
+func measureLatency(address string, options *Options, result *Result) error {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(options.Timeout)); err != nil {
+		return err
+	}
+
+	b := make([]byte, pingSize)
+	rtts := make([]time.Duration, 0, options.Pings)
+	for i := 0; i < warmupPings+options.Pings; i++ {
+		start := time.Now()
+		if _, err := conn.Write(b); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return err
+		}
+		if i >= warmupPings {
+			rtts = append(rtts, time.Since(start))
+		}
+	}
+	if len(rtts) == 0 {
+		return nil
+	}
+
+	sort.Slice(rtts, func(i, j int) bool {
+		return rtts[i] < rtts[j]
+	})
+	percentile := func(p int) time.Duration {
+		return rtts[(len(rtts)-1)*p/100]
+	}
+	result.LatencyP50 = percentile(50)
+	result.LatencyP90 = percentile(90)
+	result.LatencyP99 = percentile(99)
+	result.LatencyMax = rtts[len(rtts)-1]
+	return nil
+}
+
+func measureThroughput(address string, options *Options, result *Result) error {
+	connections := options.Connections
+	if connections < 1 {
+		connections = 1
+	}
+	size := options.Size / int64(connections)
+
+	conns := make([]net.Conn, 0, connections)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < connections; i++ {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := conn.SetDeadline(time.Now().Add(options.Timeout)); err != nil {
+			return err
+		}
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cpuBefore, hasCPU := cpuTime()
+	start := time.Now()
+
+	var group errgroup.Group
+	for _, conn := range conns {
+		conn := conn
+		group.Go(func() error {
+			b := make([]byte, chunkSize)
+			for sent := int64(0); sent < size; {
+				n := int64(len(b))
+				if size-sent < n {
+					n = size - sent
+				}
+				if _, err := conn.Write(b[:n]); err != nil {
+					return err
+				}
+				sent += n
+			}
+			return nil
+		})
+		group.Go(func() error {
+			_, err := io.CopyN(ioutil.Discard, conn, size)
+			return err
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+
+	result.Duration = time.Since(start)
+	cpuAfter, _ := cpuTime()
+	runtime.ReadMemStats(&after)
+
+	seconds := result.Duration.Seconds()
+	result.Bytes = size * int64(connections)
+	result.Throughput = float64(result.Bytes) / seconds
+	result.CPU = -1
+	if hasCPU {
+		result.CPU = (cpuAfter - cpuBefore).Seconds() / seconds
+	}
+	result.AllocBytes = float64(after.TotalAlloc-before.TotalAlloc) / seconds
+	result.Allocs = float64(after.Mallocs-before.Mallocs) / seconds
+	return nil
+}
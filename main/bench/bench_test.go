@@ -0,0 +1,34 @@
+package bench_test
+
+import (
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	. "v2ray.com/core/main/bench"
+)
+
+func TestRun(t *testing.T) {
+	for _, transport := range []string{"tcp", "ws", "kcp"} {
+		result, err := Run(&Options{
+			Transport:   transport,
+			Size:        256 * 1024,
+			Connections: 2,
+			Pings:       5,
+			Timeout:     time.Second * 30,
+		})
+		common.Must(err)
+		if result.Bytes != 256*1024 || result.Throughput <= 0 {
+			t.Error(transport, ": unexpected throughput result: ", result.Bytes, " ", result.Throughput)
+		}
+		if result.LatencyP50 <= 0 || result.LatencyMax < result.LatencyP99 || result.LatencyP99 < result.LatencyP50 {
+			t.Error(transport, ": unexpected latency result: ", result.LatencyP50, " ", result.LatencyP99, " ", result.LatencyMax)
+		}
+	}
+}
+
+func TestRunUnsupportedTransport(t *testing.T) {
+	if _, err := Run(&Options{Transport: "carrier-pigeon"}); err == nil {
+		t.Error("expect error for unsupported transport")
+	}
+}
@@ -0,0 +1,11 @@
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package bench
+
+import (
+	"time"
+)
+
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,17 @@
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package bench
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTime returns the user and system CPU time of this process.
+func cpuTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
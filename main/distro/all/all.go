@@ -12,11 +12,13 @@ import (
 	_ "v2ray.com/core/app/commander"
 	_ "v2ray.com/core/app/log/command"
 	_ "v2ray.com/core/app/proxyman/command"
+	_ "v2ray.com/core/app/reload/command"
 	_ "v2ray.com/core/app/stats/command"
 
 	// Other optional features.
 	_ "v2ray.com/core/app/dns"
 	_ "v2ray.com/core/app/log"
+	_ "v2ray.com/core/app/netmon"
 	_ "v2ray.com/core/app/policy"
 	_ "v2ray.com/core/app/reverse"
 	_ "v2ray.com/core/app/router"
@@ -24,6 +26,7 @@ import (
 
 	// Inbound and outbound proxies.
 	_ "v2ray.com/core/proxy/blackhole"
+	_ "v2ray.com/core/proxy/chain"
 	_ "v2ray.com/core/proxy/dns"
 	_ "v2ray.com/core/proxy/dokodemo"
 	_ "v2ray.com/core/proxy/freedom"
@@ -31,6 +34,7 @@ import (
 	_ "v2ray.com/core/proxy/mtproto"
 	_ "v2ray.com/core/proxy/shadowsocks"
 	_ "v2ray.com/core/proxy/socks"
+	_ "v2ray.com/core/proxy/tun"
 	_ "v2ray.com/core/proxy/vless/inbound"
 	_ "v2ray.com/core/proxy/vless/outbound"
 	_ "v2ray.com/core/proxy/vmess/inbound"
@@ -39,6 +43,8 @@ import (
 	// Transports
 	_ "v2ray.com/core/transport/internet/domainsocket"
 	_ "v2ray.com/core/transport/internet/http"
+	_ "v2ray.com/core/transport/internet/httpupgrade"
+	_ "v2ray.com/core/transport/internet/icmptunnel"
 	_ "v2ray.com/core/transport/internet/kcp"
 	_ "v2ray.com/core/transport/internet/quic"
 	_ "v2ray.com/core/transport/internet/tcp"
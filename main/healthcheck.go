@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/main/healthcheck"
+)
+
+// runHealthCheck handles "v2ray healthcheck". It pings the API of a running instance, whose address is
+// discovered from the same config files as the instance, unless specified by -server.
+func runHealthCheck(args []string) error {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	server := fs.String("server", "", "Address of V2Ray API, e.g. 127.0.0.1:10085. Discovered from config if empty.")
+	timeout := fs.Duration("timeout", 3*time.Second, "Time to wait for the API to respond.")
+	fs.Var(&configFiles, "config", "Config file for V2Ray. Multiple assign is accepted (only json). Latter ones overrides the former ones.")
+	fs.Var(&configFiles, "c", "Short alias of -config")
+	fs.StringVar(&configDir, "confdir", "", "A dir with multiple json config")
+	fs.StringVar(format, "format", "json", "Format of input file.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	address := *server
+	if len(address) == 0 {
+		configFiles, err := getConfigFilePath()
+		if err != nil {
+			return err
+		}
+		config, err := core.LoadConfig(GetConfigFormat(), configFiles[0], configFiles)
+		if err != nil {
+			return newError("failed to read config files: [", configFiles.String(), "]").Base(err)
+		}
+		dest, err := healthcheck.FindAPIAddress(config)
+		if err != nil {
+			return newError("failed to find API address. Use -server to specify one.").Base(err)
+		}
+		address = dest.NetAddr()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := healthcheck.Ping(ctx, address); err != nil {
+		return err
+	}
+
+	fmt.Println("V2Ray is healthy.")
+	return nil
+}
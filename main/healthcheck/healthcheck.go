@@ -0,0 +1,96 @@
+// Package healthcheck probes a running V2Ray instance through its API, for use in container health checks.
+package healthcheck
+
+//go:generate errorgen
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/commander"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	statsService "v2ray.com/core/app/stats/command"
+	"v2ray.com/core/common/net"
+)
+
+// FindAPIAddress returns the address of the API inbound in config. The API inbound is the one routed to
+// the outbound tag of Commander. An inbound listening on all interfaces is reached through loopback.
+func FindAPIAddress(config *core.Config) (net.Destination, error) {
+	var commanderConfig *commander.Config
+	var routerConfig *router.Config
+	for _, app := range config.App {
+		instance, err := app.GetInstance()
+		if err != nil {
+			continue
+		}
+		switch c := instance.(type) {
+		case *commander.Config:
+			commanderConfig = c
+		case *router.Config:
+			routerConfig = c
+		}
+	}
+	if commanderConfig == nil {
+		return net.Destination{}, newError("API is not enabled in config")
+	}
+
+	inboundTags := make(map[string]bool)
+	if routerConfig != nil {
+		for _, rule := range routerConfig.Rule {
+			if rule.GetTag() == commanderConfig.Tag {
+				for _, tag := range rule.InboundTag {
+					inboundTags[tag] = true
+				}
+			}
+		}
+	}
+	if len(inboundTags) == 0 {
+		return net.Destination{}, newError("no inbound is routed to API outbound [", commanderConfig.Tag, "]")
+	}
+
+	for _, inbound := range config.Inbound {
+		if !inboundTags[inbound.Tag] || inbound.ReceiverSettings == nil {
+			continue
+		}
+		rawSettings, err := inbound.ReceiverSettings.GetInstance()
+		if err != nil {
+			continue
+		}
+		receiver, ok := rawSettings.(*proxyman.ReceiverConfig)
+		if !ok || receiver.PortRange == nil {
+			continue
+		}
+
+		address := receiver.GetEffectiveListenAddresses()[0]
+		switch {
+		case address == net.AnyIP:
+			address = net.LocalHostIP
+		case address == net.AnyIPv6:
+			address = net.LocalHostIPv6
+		}
+		return net.TCPDestination(address, net.Port(receiver.PortRange.From)), nil
+	}
+
+	return net.Destination{}, newError("API inbound not found")
+}
+
+// Ping calls the API at the given address. The instance is considered healthy as long as the API responds,
+// even if the stats service is not enabled.
+func Ping(ctx context.Context, address string) error {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return newError("failed to dial API at ", address).Base(err)
+	}
+	defer conn.Close()
+
+	client := statsService.NewStatsServiceClient(conn)
+	if _, err := client.GetSysStats(ctx, &statsService.SysStatsRequest{}); err != nil && status.Code(err) != codes.Unimplemented {
+		return newError("API at ", address, " is not responding").Base(err)
+	}
+	return nil
+}
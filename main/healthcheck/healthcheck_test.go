@@ -0,0 +1,90 @@
+package healthcheck_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/commander"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	. "v2ray.com/core/main/healthcheck"
+	"v2ray.com/core/proxy/dokodemo"
+	"v2ray.com/core/testing/servers/tcp"
+)
+
+func TestFindAPIAddress(t *testing.T) {
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{Tag: "api"}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						TargetTag:  &router.RoutingRule_Tag{Tag: "direct"},
+						InboundTag: []string{"socks"},
+					},
+					{
+						TargetTag:  &router.RoutingRule_Tag{Tag: "api"},
+						InboundTag: []string{"api-in"},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "socks",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(1080),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{}),
+			},
+			{
+				Tag: "api-in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(10085),
+					Listen:    net.NewIPOrDomain(net.AnyIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{}),
+			},
+		},
+	}
+
+	dest, err := FindAPIAddress(config)
+	common.Must(err)
+	if dest.NetAddr() != "127.0.0.1:10085" {
+		t.Error("unexpected API address: ", dest.NetAddr())
+	}
+
+	config.App = config.App[1:]
+	if _, err := FindAPIAddress(config); err == nil {
+		t.Error("expect error when API is not enabled")
+	}
+}
+
+func TestPing(t *testing.T) {
+	port := tcp.PickPort()
+	listener, err := net.Listen("tcp", net.TCPDestination(net.LocalHostIP, port).NetAddr())
+	common.Must(err)
+
+	// A server with no service responds with Unimplemented, which is still healthy.
+	server := grpc.NewServer()
+	go server.Serve(listener) // nolint: errcheck
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	common.Must(Ping(ctx, listener.Addr().String()))
+
+	server.Stop()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Ping(ctx, listener.Addr().String()); err == nil {
+		t.Error("expect error after API is stopped")
+	}
+}
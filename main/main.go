@@ -3,6 +3,7 @@ package main
 //go:generate errorgen
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -14,11 +15,16 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"v2ray.com/core"
+	"v2ray.com/core/app/reload/command"
 	"v2ray.com/core/common/cmdarg"
 	"v2ray.com/core/common/platform"
 	_ "v2ray.com/core/main/distro/all"
+	"v2ray.com/core/main/preflight"
+	"v2ray.com/core/main/privilege"
+	"v2ray.com/core/main/sandbox"
 )
 
 var (
@@ -27,6 +33,10 @@ var (
 	version     = flag.Bool("version", false, "Show current version of V2Ray.")
 	test        = flag.Bool("test", false, "Test config file only, without launching V2Ray server.")
 	format      = flag.String("format", "json", "Format of input file.")
+	drain       = flag.Duration("drain", 0, "Time to wait for existing connections to finish on shutdown. A second signal stops waiting.")
+	checkEnv    = flag.Bool("preflight", false, "Check ports, certificates, geo data, DNS servers and log paths before starting V2Ray.")
+	hardened    = flag.Bool("sandbox", false, "Restrict system calls after V2Ray is started. Supported on Linux (amd64 and arm64) and OpenBSD.")
+	fileLimit   = flag.Uint64("nofile", 0, "Raise the limit of open files to this value at startup. Default to the hard limit.")
 
 	/* We have to do this here because Golang's Test will also need to parse flag, before
 	 * main func in this file is run.
@@ -107,23 +117,38 @@ func GetConfigFormat() string {
 	}
 }
 
-func startV2Ray() (core.Server, error) {
-	configFiles, err := getConfigFilePath()
+func loadConfig(configFiles cmdarg.Arg) (*core.Config, error) {
+	config, err := core.LoadConfig(GetConfigFormat(), configFiles[0], configFiles)
 	if err != nil {
-		return nil, err
+		return nil, newError("failed to read config files: [", configFiles.String(), "]").Base(err)
 	}
 
-	config, err := core.LoadConfig(GetConfigFormat(), configFiles[0], configFiles)
+	if *checkEnv {
+		if problems := preflight.Run(config); len(problems) > 0 {
+			var sb strings.Builder
+			for _, p := range problems {
+				sb.WriteString("\n  - ")
+				sb.WriteString(p.Error())
+			}
+			return nil, newError("preflight checks found ", len(problems), " problem(s):", sb.String())
+		}
+	}
+
+	return config, nil
+}
+
+func startV2Ray(configFiles cmdarg.Arg) (*core.Instance, *core.Config, error) {
+	config, err := loadConfig(configFiles)
 	if err != nil {
-		return nil, newError("failed to read config files: [", configFiles.String(), "]").Base(err)
+		return nil, nil, err
 	}
 
 	server, err := core.New(config)
 	if err != nil {
-		return nil, newError("failed to create server").Base(err)
+		return nil, nil, newError("failed to create server").Base(err)
 	}
 
-	return server, nil
+	return server, config, nil
 }
 
 func printVersion() {
@@ -134,6 +159,29 @@ func printVersion() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := runServiceCommand(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := runHealthCheck(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		if err := runBench(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	flag.Parse()
 
@@ -143,13 +191,36 @@ func main() {
 		return
 	}
 
-	server, err := startV2Ray()
+	raiseFileLimit(*fileLimit)
+
+	if isService, err := runAsService(); isService || err != nil {
+		if err != nil {
+			fmt.Println("Failed to run as service", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configFiles, err := getConfigFilePath()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(23)
+	}
+
+	server, config, err := startV2Ray(configFiles)
 	if err != nil {
 		fmt.Println(err)
 		// Configuration error. Exit with a special value to prevent systemd from restarting.
 		os.Exit(23)
 	}
 
+	if *hardened {
+		if err := sandbox.CheckConfig(config); err != nil {
+			fmt.Println(err)
+			os.Exit(23)
+		}
+	}
+
 	if *test {
 		fmt.Println("Configuration OK.")
 		os.Exit(0)
@@ -159,14 +230,103 @@ func main() {
 		fmt.Println("Failed to start", err)
 		os.Exit(-1)
 	}
-	defer server.Close()
+
+	// Switch user after all inbounds are listening.
+	dropped, err := dropPrivilege(config)
+	if err != nil {
+		fmt.Println("Failed to drop privilege", err)
+		server.Close()
+		os.Exit(-1)
+	}
 
 	// Explicitly triggering GC to remove garbage from config loading.
 	runtime.GC()
 
-	{
-		osSignals := make(chan os.Signal, 1)
-		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-		<-osSignals
+	if *hardened {
+		if err := sandbox.Apply(); err != nil {
+			fmt.Println("Failed to apply sandbox", err)
+			server.Close()
+			os.Exit(-1)
+		}
+	}
+
+	l := &launcher{
+		configFiles: configFiles,
+		server:      server,
+		config:      config,
+	}
+	// Restarting in the sandbox is not possible, as system calls to bind ports are no longer allowed. Neither is it
+	// after switching user, as privileged ports can't be bound again.
+	reloadable := !*hardened && !dropped
+	if reloadable {
+		command.SetReloader(l.reload)
+	}
+
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-reloadSignals:
+			if !reloadable {
+				log.Println("Reload is not supported in sandbox or after switching user")
+				continue
+			}
+			if err := l.reload(); err != nil {
+				log.Println("Failed to reload", err)
+			}
+		case <-osSignals:
+			shutdown(l.current(), osSignals, *drain)
+			return
+		}
+	}
+}
+
+// raiseFileLimit raises the limit of open files, as each connection takes one or more file descriptors.
+func raiseFileLimit(target uint64) {
+	limit, err := platform.RaiseFileLimit(target)
+	if err != nil {
+		log.Println("Failed to raise open file limit:", err)
+	}
+	if limit > 0 {
+		log.Println("Open file limit:", limit)
+	}
+}
+
+// dropPrivilege switches user as the privilege settings in config say, and returns whether it did.
+func dropPrivilege(config *core.Config) (bool, error) {
+	privilegeConfig, err := privilege.FromConfig(config)
+	if err != nil || privilegeConfig == nil {
+		return false, err
+	}
+	if err := privilege.Drop(privilegeConfig); err != nil {
+		return false, err
+	}
+	log.Println("Switched to user", privilegeConfig.User)
+	return true, nil
+}
+
+func shutdown(server *core.Instance, osSignals <-chan os.Signal, timeout time.Duration) {
+	if timeout <= 0 {
+		server.Close()
+		return
+	}
+
+	log.Println("Draining connections for up to", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-osSignals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Println("Failed to shutdown", err)
 	}
 }
@@ -0,0 +1,72 @@
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"v2ray.com/core"
+	v2tls "v2ray.com/core/transport/internet/tls"
+)
+
+// checkCertificates verifies that all TLS certificates match their keys, and are valid at the moment.
+func checkCertificates(config *core.Config) []error {
+	// Invalid inbound settings are already reported by checkPorts.
+	inbounds, _ := inboundStreams(config)
+	var problems []error
+	for _, s := range inbounds {
+		if tlsConfig := v2tls.ConfigFromStreamSettings(s.stream); tlsConfig != nil {
+			problems = append(problems, checkTLSConfig("inbound ["+s.tag+"]", tlsConfig)...)
+		}
+	}
+
+	outbounds, outboundProblems := outboundStreams(config)
+	problems = append(problems, outboundProblems...)
+	for _, s := range outbounds {
+		if tlsConfig := v2tls.ConfigFromStreamSettings(s.stream); tlsConfig != nil {
+			problems = append(problems, checkTLSConfig("outbound ["+s.tag+"]", tlsConfig)...)
+		}
+	}
+	return problems
+}
+
+func checkTLSConfig(owner string, config *v2tls.Config) []error {
+	var problems []error
+	now := time.Now()
+	for idx, entry := range config.Certificate {
+		var leaf *x509.Certificate
+		if entry.Usage == v2tls.Certificate_AUTHORITY_VERIFY {
+			block, _ := pem.Decode(entry.Certificate)
+			if block == nil {
+				problems = append(problems, newError(owner, ": certificate #", idx, " is not in PEM format."))
+				continue
+			}
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				problems = append(problems, newError(owner, ": failed to parse certificate #", idx).Base(err))
+				continue
+			}
+			leaf = c
+		} else {
+			pair, err := tls.X509KeyPair(entry.Certificate, entry.Key)
+			if err != nil {
+				problems = append(problems, newError(owner, ": certificate #", idx, " doesn't match its key. Make sure both files are from the same issuance.").Base(err))
+				continue
+			}
+			c, err := x509.ParseCertificate(pair.Certificate[0])
+			if err != nil {
+				problems = append(problems, newError(owner, ": failed to parse certificate #", idx).Base(err))
+				continue
+			}
+			leaf = c
+		}
+
+		if now.After(leaf.NotAfter) {
+			problems = append(problems, newError(owner, ": certificate #", idx, " (", leaf.Subject.CommonName, ") expired at ", leaf.NotAfter.Format(time.RFC3339), ". Renew the certificate."))
+		} else if now.Before(leaf.NotBefore) {
+			problems = append(problems, newError(owner, ": certificate #", idx, " (", leaf.Subject.CommonName, ") is not valid until ", leaf.NotBefore.Format(time.RFC3339), ". Check the system clock."))
+		}
+	}
+	return problems
+}
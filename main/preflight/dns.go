@@ -0,0 +1,143 @@
+package preflight
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common/net"
+)
+
+const dnsTimeout = 3 * time.Second
+
+// checkNameServers verifies that all DNS servers are reachable.
+func checkNameServers(config *core.Config) []error {
+	_, _, dnsConfig := apps(config)
+
+	var endpoints []*net.Endpoint
+	for _, ns := range dnsConfig.GetNameServer() {
+		endpoints = append(endpoints, ns.Address)
+	}
+	endpoints = append(endpoints, dnsConfig.GetNameServers()...)
+
+	results := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for idx, endpoint := range endpoints {
+		wg.Add(1)
+		go func(idx int, endpoint *net.Endpoint) {
+			defer wg.Done()
+			results[idx] = probeNameServer(endpoint)
+		}(idx, endpoint)
+	}
+	wg.Wait()
+
+	var problems []error
+	for _, err := range results {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+func probeNameServer(endpoint *net.Endpoint) error {
+	if endpoint == nil {
+		return nil
+	}
+	address := endpoint.Address.AsAddress()
+	if address.Family().IsDomain() {
+		domain := address.Domain()
+		switch {
+		case domain == "localhost":
+			return nil
+		case strings.HasPrefix(domain, "https+local://"), strings.HasPrefix(domain, "https://"):
+			return probeDOH(domain)
+		}
+	}
+
+	dest := endpoint.AsDestination()
+	if dest.Network == net.Network_Unknown {
+		dest.Network = net.Network_UDP
+	}
+	if dest.Port == 0 {
+		dest.Port = net.Port(53)
+	}
+	if dest.Network != net.Network_UDP {
+		return nil
+	}
+	if err := probeUDPNameServer(dest); err != nil {
+		return newError("DNS server ", dest.NetAddr(), " doesn't respond. Check network connectivity and firewall rules.").Base(err)
+	}
+	return nil
+}
+
+func probeDOH(rawURL string) error {
+	u, err := url.Parse(strings.Replace(rawURL, "https+local://", "https://", 1))
+	if err != nil {
+		return newError("DNS server ", rawURL, " has an invalid URL.").Base(err)
+	}
+	port := net.Port(443)
+	if len(u.Port()) > 0 {
+		if port, err = net.PortFromString(u.Port()); err != nil {
+			return newError("DNS server ", rawURL, " has an invalid port.").Base(err)
+		}
+	}
+	dialer := &net.Dialer{Timeout: dnsTimeout}
+	conn, err := dialer.Dial("tcp", net.TCPDestination(net.ParseAddress(u.Hostname()), port).NetAddr())
+	if err != nil {
+		return newError("DNS server ", rawURL, " is not reachable. Check network connectivity and firewall rules.").Base(err)
+	}
+	return conn.Close()
+}
+
+func probeUDPNameServer(dest net.Destination) error {
+	const queryID = 0x5632
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: queryID, RecursionDesired: true})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return err
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("."),
+		Type:  dnsmessage.TypeNS,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return err
+	}
+	query, err := builder.Finish()
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: dnsTimeout}
+	conn, err := dialer.Dial("udp", dest.NetAddr())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(dnsTimeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return err
+	}
+
+	response := make([]byte, 512)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(response[:n])
+		if err == nil && header.ID == queryID {
+			return nil
+		}
+	}
+}
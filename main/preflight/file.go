@@ -0,0 +1,65 @@
+package preflight
+
+import (
+	"os"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/log"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/platform"
+)
+
+// checkGeoData verifies that all GeoIP lists referenced by routing rules and DNS servers are loaded with data.
+func checkGeoData(config *core.Config) []error {
+	_, routerConfig, dnsConfig := apps(config)
+
+	var geoips []*router.GeoIP
+	for _, rule := range routerConfig.GetRule() {
+		geoips = append(geoips, rule.Geoip...)
+		geoips = append(geoips, rule.SourceGeoip...)
+	}
+	for _, ns := range dnsConfig.GetNameServer() {
+		geoips = append(geoips, ns.Geoip...)
+	}
+
+	var problems []error
+	reported := make(map[string]bool)
+	for _, geoip := range geoips {
+		cc := geoip.CountryCode
+		if len(cc) == 0 || len(geoip.Cidr) > 0 || reported[cc] {
+			continue
+		}
+		reported[cc] = true
+		problems = append(problems, newError("geoip:", cc, " contains no IP. Make sure ", platform.GetAssetLocation("geoip.dat"), " is complete and up to date."))
+	}
+	return problems
+}
+
+// checkLogPaths verifies that all log files are writable.
+func checkLogPaths(config *core.Config) []error {
+	logConfig, _, _ := apps(config)
+	if logConfig == nil {
+		return nil
+	}
+
+	var problems []error
+	if logConfig.ErrorLogType == log.LogType_File {
+		if err := tryOpenLog(logConfig.ErrorLogPath); err != nil {
+			problems = append(problems, newError("error log ", logConfig.ErrorLogPath, " is not writable.").Base(err))
+		}
+	}
+	if logConfig.AccessLogType == log.LogType_File {
+		if err := tryOpenLog(logConfig.AccessLogPath); err != nil {
+			problems = append(problems, newError("access log ", logConfig.AccessLogPath, " is not writable.").Base(err))
+		}
+	}
+	return problems
+}
+
+func tryOpenLog(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
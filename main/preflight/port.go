@@ -0,0 +1,61 @@
+package preflight
+
+import (
+	"v2ray.com/core"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/net"
+)
+
+func isPacketTransport(protocolName string) bool {
+	switch protocolName {
+	case "mkcp", "quic":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkPorts verifies that all ports of inbounds are available for listening.
+func checkPorts(config *core.Config) []error {
+	streams, problems := inboundStreams(config)
+	for _, s := range streams {
+		// Ports of dynamic inbounds are picked at runtime.
+		if strategy := s.receiver.AllocationStrategy; strategy != nil && strategy.Type != proxyman.AllocationStrategy_Always {
+			continue
+		}
+		if s.stream.ProtocolName == "domainsocket" {
+			continue
+		}
+
+		for _, address := range s.receiver.GetEffectiveListenAddresses() {
+			for _, port := range s.receiver.GetEffectivePorts() {
+				var err error
+				if isPacketTransport(s.stream.ProtocolName) {
+					err = tryListenUDP(address, port)
+				} else {
+					err = tryListenTCP(address, port)
+				}
+				if err != nil {
+					problems = append(problems, newError("inbound [", s.tag, "]: port ", port, " on ", address, " is not available. Stop the program using it, or choose another port.").Base(err))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func tryListenTCP(address net.Address, port net.Port) error {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: address.IP(), Port: int(port)})
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
+
+func tryListenUDP(address net.Address, port net.Port) error {
+	l, err := net.ListenUDP("udp", &net.UDPAddr{IP: address.IP(), Port: int(port)})
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
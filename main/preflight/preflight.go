@@ -0,0 +1,114 @@
+// Package preflight verifies the environment V2Ray is about to run in, before the instance is started.
+package preflight
+
+//go:generate errorgen
+
+import (
+	"v2ray.com/core"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/transport/internet"
+)
+
+// Check inspects part of a V2Ray config, and returns all problems found.
+type Check func(config *core.Config) []error
+
+var checks = []Check{
+	checkPorts,
+	checkCertificates,
+	checkGeoData,
+	checkNameServers,
+	checkLogPaths,
+}
+
+// Run runs all preflight checks against the given config. It returns all problems found, or nil if there is none.
+func Run(config *core.Config) []error {
+	var problems []error
+	for _, check := range checks {
+		problems = append(problems, check(config)...)
+	}
+	return problems
+}
+
+type inboundStream struct {
+	tag      string
+	receiver *proxyman.ReceiverConfig
+	stream   *internet.MemoryStreamConfig
+}
+
+type outboundStream struct {
+	tag    string
+	stream *internet.MemoryStreamConfig
+}
+
+// inboundStreams returns stream settings of all inbounds. Inbounds with invalid settings are reported as problems.
+func inboundStreams(config *core.Config) ([]inboundStream, []error) {
+	var streams []inboundStream
+	var problems []error
+	for _, inbound := range config.Inbound {
+		rawSettings, err := inbound.ReceiverSettings.GetInstance()
+		if err != nil {
+			problems = append(problems, newError("inbound [", inbound.Tag, "]: invalid receiver settings").Base(err))
+			continue
+		}
+		receiver, ok := rawSettings.(*proxyman.ReceiverConfig)
+		if !ok {
+			continue
+		}
+		mss, err := internet.ToMemoryStreamConfig(receiver.StreamSettings)
+		if err != nil {
+			problems = append(problems, newError("inbound [", inbound.Tag, "]: invalid stream settings").Base(err))
+			continue
+		}
+		streams = append(streams, inboundStream{tag: inbound.Tag, receiver: receiver, stream: mss})
+	}
+	return streams, problems
+}
+
+// outboundStreams returns stream settings of all outbounds. Outbounds with invalid settings are reported as problems.
+func outboundStreams(config *core.Config) ([]outboundStream, []error) {
+	var streams []outboundStream
+	var problems []error
+	for _, outbound := range config.Outbound {
+		if outbound.SenderSettings == nil {
+			continue
+		}
+		rawSettings, err := outbound.SenderSettings.GetInstance()
+		if err != nil {
+			problems = append(problems, newError("outbound [", outbound.Tag, "]: invalid sender settings").Base(err))
+			continue
+		}
+		sender, ok := rawSettings.(*proxyman.SenderConfig)
+		if !ok {
+			continue
+		}
+		mss, err := internet.ToMemoryStreamConfig(sender.StreamSettings)
+		if err != nil {
+			problems = append(problems, newError("outbound [", outbound.Tag, "]: invalid stream settings").Base(err))
+			continue
+		}
+		streams = append(streams, outboundStream{tag: outbound.Tag, stream: mss})
+	}
+	return streams, problems
+}
+
+// apps returns the app settings of the given types in config.
+func apps(config *core.Config) (logConfig *log.Config, routerConfig *router.Config, dnsConfig *dns.Config) {
+	for _, app := range config.App {
+		instance, err := app.GetInstance()
+		if err != nil {
+			continue
+		}
+		switch c := instance.(type) {
+		case *log.Config:
+			logConfig = c
+		case *router.Config:
+			routerConfig = c
+		case *dns.Config:
+			dnsConfig = c
+		}
+	}
+	return
+}
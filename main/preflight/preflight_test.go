@@ -0,0 +1,102 @@
+package preflight_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol/tls/cert"
+	"v2ray.com/core/common/serial"
+	. "v2ray.com/core/main/preflight"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/tls"
+)
+
+func expectProblem(t *testing.T, problems []error, substr string) {
+	t.Helper()
+	if len(problems) != 1 {
+		t.Fatal("expect 1 problem, but got ", problems)
+	}
+	if !strings.Contains(problems[0].Error(), substr) {
+		t.Error("expect problem about '", substr, "', but got ", problems[0])
+	}
+}
+
+func TestPortInUse(t *testing.T) {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer l.Close()
+
+	port := net.Port(l.Addr().(*net.TCPAddr).Port)
+	problems := Run(&core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(port),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+			},
+		},
+	})
+	expectProblem(t, problems, "port "+port.String())
+}
+
+func tlsInbound(certificate *tls.Certificate) *core.InboundHandlerConfig {
+	return &core.InboundHandlerConfig{
+		Tag: "in",
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			StreamSettings: &internet.StreamConfig{
+				SecurityType: serial.GetMessageType(&tls.Config{}),
+				SecuritySettings: []*serial.TypedMessage{
+					serial.ToTypedMessage(&tls.Config{
+						Certificate: []*tls.Certificate{certificate},
+					}),
+				},
+			},
+		}),
+	}
+}
+
+func TestCertificateKeyMismatch(t *testing.T) {
+	c1 := tls.ParseCertificate(cert.MustGenerate(nil))
+	c2 := tls.ParseCertificate(cert.MustGenerate(nil))
+	c1.Key = c2.Key
+
+	problems := Run(&core.Config{
+		Inbound: []*core.InboundHandlerConfig{tlsInbound(c1)},
+	})
+	expectProblem(t, problems, "doesn't match its key")
+}
+
+func TestCertificateExpired(t *testing.T) {
+	c := tls.ParseCertificate(cert.MustGenerate(nil,
+		cert.NotBefore(time.Now().Add(-48*time.Hour)),
+		cert.NotAfter(time.Now().Add(-24*time.Hour))))
+
+	problems := Run(&core.Config{
+		Inbound: []*core.InboundHandlerConfig{tlsInbound(c)},
+	})
+	expectProblem(t, problems, "expired")
+}
+
+func TestLogPathNotWritable(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "v2ray-preflight-not-exist", "error.log")
+	problems := Run(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&log.Config{
+				ErrorLogType: log.LogType_File,
+				ErrorLogPath: path,
+			}),
+		},
+	})
+	expectProblem(t, problems, "error log")
+}
@@ -0,0 +1,57 @@
+// Package privilege switches a V2Ray process started as root to an unprivileged account,
+// once the ports and devices which need root are opened.
+package privilege
+
+//go:generate errorgen
+
+import (
+	"os/user"
+	"strconv"
+
+	"v2ray.com/core"
+)
+
+// FromConfig returns the privilege settings in the extensions of config, or nil if there is none.
+func FromConfig(config *core.Config) (*Config, error) {
+	for _, ext := range config.Extension {
+		instance, err := ext.GetInstance()
+		if err != nil {
+			continue
+		}
+		if c, ok := instance.(*Config); ok {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// lookupIDs resolves the user and group in config into numeric IDs.
+func lookupIDs(config *Config) (int, int, error) {
+	u, err := user.Lookup(config.User)
+	if err != nil {
+		if u, err = user.LookupId(config.User); err != nil {
+			return 0, 0, newError("unknown user ", config.User).Base(err)
+		}
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, 0, newError("user ", config.User, " has no numeric ID").Base(err)
+	}
+
+	gidString := u.Gid
+	if len(config.Group) > 0 {
+		g, err := user.LookupGroup(config.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(config.Group); err != nil {
+				return 0, 0, newError("unknown group ", config.Group).Base(err)
+			}
+		}
+		gidString = g.Gid
+	}
+	gid, err := strconv.Atoi(gidString)
+	if err != nil {
+		return 0, 0, newError("group ", config.Group, " has no numeric ID").Base(err)
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,88 @@
+package privilege
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var capabilities = map[string]uint{
+	"net_admin":        unix.CAP_NET_ADMIN,
+	"net_bind_service": unix.CAP_NET_BIND_SERVICE,
+	"net_raw":          unix.CAP_NET_RAW,
+	"sys_resource":     unix.CAP_SYS_RESOURCE,
+}
+
+func parseCapabilities(names []string) (uint64, error) {
+	var mask uint64
+	for _, name := range names {
+		c, found := capabilities[strings.TrimPrefix(strings.ToLower(name), "cap_")]
+		if !found {
+			return 0, newError("unsupported capability ", name)
+		}
+		mask |= 1 << c
+	}
+	return mask, nil
+}
+
+// allThreadsSyscall calls a per-thread syscall on all threads of the process.
+func allThreadsSyscall(trap, a1, a2, a3 uintptr) error {
+	if _, _, errno := syscall.AllThreadsSyscall(trap, a1, a2, a3); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return newError("keeping capabilities is not supported in builds with cgo")
+		}
+		return errno
+	}
+	return nil
+}
+
+// Drop switches the process to the user and group in config. Capabilities in config are kept, and all others are dropped.
+func Drop(config *Config) error {
+	uid, gid, err := lookupIDs(config)
+	if err != nil {
+		return err
+	}
+	mask, err := parseCapabilities(config.Capability)
+	if err != nil {
+		return err
+	}
+
+	if mask != 0 {
+		// Permitted capabilities survive setuid with keepcaps.
+		if err := allThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_KEEPCAPS, 1, 0); err != nil {
+			return newError("failed to keep capabilities").Base(err)
+		}
+	}
+
+	// Since Go 1.16, these apply to all threads of the process.
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return newError("failed to set supplementary groups").Base(err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return newError("failed to set group to ", gid).Base(err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return newError("failed to set user to ", uid).Base(err)
+	}
+
+	if mask != 0 {
+		header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+		data := [2]unix.CapUserData{
+			{Effective: uint32(mask), Permitted: uint32(mask)},
+			{Effective: uint32(mask >> 32), Permitted: uint32(mask >> 32)},
+		}
+		if err := allThreadsSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); err != nil {
+			return newError("failed to set capabilities").Base(err)
+		}
+		if err := allThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_KEEPCAPS, 0, 0); err != nil {
+			return newError("failed to reset keepcaps").Base(err)
+		}
+	}
+
+	if uid != 0 && syscall.Setuid(0) == nil {
+		return newError("privilege is not dropped")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package privilege_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	. "v2ray.com/core/main/privilege"
+)
+
+// droppedMain runs in a child process, as privilege can't be regained once dropped.
+func droppedMain() {
+	common.Must(Drop(&Config{User: "nobody"}))
+
+	if os.Getuid() == 0 || os.Getgid() == 0 {
+		os.Exit(2)
+	}
+	// Binding to ports below 1024 requires CAP_NET_BIND_SERVICE, which is dropped.
+	if l, err := net.Listen("tcp", "127.0.0.1:80"); err == nil {
+		l.Close()
+		os.Exit(3)
+	}
+	os.Exit(0)
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv("V2RAY_PRIVILEGE_TEST") == "1" {
+		droppedMain()
+	}
+	os.Exit(m.Run())
+}
+
+func TestDrop(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("not running as root")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "V2RAY_PRIVILEGE_TEST=1")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal("child process failed: ", err, "\n", string(output))
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	config := &core.Config{
+		Extension: []*serial.TypedMessage{
+			serial.ToTypedMessage(&Config{User: "nobody", Capability: []string{"net_admin"}}),
+		},
+	}
+	c, err := FromConfig(config)
+	common.Must(err)
+	if c == nil || c.User != "nobody" {
+		t.Error("unexpected privilege config: ", c)
+	}
+
+	c, err = FromConfig(&core.Config{})
+	common.Must(err)
+	if c != nil {
+		t.Error("expect no privilege config, but got ", c)
+	}
+}
+
+func TestDropUnknownUser(t *testing.T) {
+	if err := Drop(&Config{User: "v2ray-no-such-user"}); err == nil {
+		t.Error("expect error for unknown user")
+	}
+}
@@ -0,0 +1,10 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package privilege
+
+import "runtime"
+
+// Drop is not supported on this platform.
+func Drop(config *Config) error {
+	return newError("switching user is not supported on ", runtime.GOOS)
+}
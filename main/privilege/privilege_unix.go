@@ -0,0 +1,33 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package privilege
+
+import (
+	"syscall"
+)
+
+// Drop switches the process to the user and group in config.
+func Drop(config *Config) error {
+	if len(config.Capability) > 0 {
+		return newError("capabilities are only supported on Linux")
+	}
+	uid, gid, err := lookupIDs(config)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return newError("failed to set supplementary groups").Base(err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return newError("failed to set group to ", gid).Base(err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return newError("failed to set user to ", uid).Base(err)
+	}
+
+	if uid != 0 && syscall.Setuid(0) == nil {
+		return newError("privilege is not dropped")
+	}
+	return nil
+}
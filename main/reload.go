@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common/cmdarg"
+)
+
+const restartDelay = time.Millisecond * 200
+
+// launcher keeps the running V2Ray instance, and replaces it when the config files are reloaded.
+type launcher struct {
+	configFiles cmdarg.Arg
+
+	access sync.Mutex
+	server *core.Instance
+	config *core.Config
+}
+
+func (l *launcher) current() *core.Instance {
+	l.access.Lock()
+	defer l.access.Unlock()
+	return l.server
+}
+
+// reload loads the config files again. If the config is valid, the running instance is replaced asynchronously, so
+// that reload may be called by the API server of the instance.
+func (l *launcher) reload() error {
+	for _, file := range l.configFiles {
+		if file == "stdin:" {
+			return newError("config from STDIN can't be reloaded")
+		}
+	}
+
+	config, err := loadConfig(l.configFiles)
+	if err != nil {
+		return err
+	}
+	server, err := core.New(config)
+	if err != nil {
+		return newError("failed to create server").Base(err)
+	}
+
+	// Give the API server a moment to respond, before it is closed.
+	time.AfterFunc(restartDelay, func() {
+		l.restart(server, config)
+	})
+	return nil
+}
+
+func (l *launcher) restart(server *core.Instance, config *core.Config) {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	// Ports of the running instance must be released before the new one listens on them.
+	l.server.Close()
+	if err := server.Start(); err != nil {
+		log.Println("Failed to start with reloaded config, restoring the previous one:", err)
+		server.Close()
+
+		// A closed instance can't be started again.
+		previous, err := core.New(l.config)
+		if err == nil {
+			err = previous.Start()
+		}
+		if err != nil {
+			// Nothing is listening any more.
+			log.Println("Failed to restore the previous config:", err)
+			os.Exit(-1)
+		}
+		l.server = previous
+		return
+	}
+
+	l.server = server
+	l.config = config
+	log.Println("Config reloaded")
+	runtime.GC()
+}
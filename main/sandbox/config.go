@@ -0,0 +1,30 @@
+package sandbox
+
+import (
+	"v2ray.com/core"
+	"v2ray.com/core/proxy/shadowsocks"
+)
+
+// CheckConfig returns an error if config needs what the sandbox forbids. Shadowsocks plugins are programs executed,
+// waited for and killed by V2Ray, none of which is allowed in the sandbox.
+func CheckConfig(config *core.Config) error {
+	for _, inbound := range config.Inbound {
+		settings, err := inbound.ProxySettings.GetInstance()
+		if err != nil {
+			continue
+		}
+		if server, ok := settings.(*shadowsocks.ServerConfig); ok && server.Plugin != "" {
+			return newError("inbound [", inbound.Tag, "]: Shadowsocks plugin is not supported in sandbox")
+		}
+	}
+	for _, outbound := range config.Outbound {
+		settings, err := outbound.ProxySettings.GetInstance()
+		if err != nil {
+			continue
+		}
+		if client, ok := settings.(*shadowsocks.ClientConfig); ok && client.Plugin != "" {
+			return newError("outbound [", outbound.Tag, "]: Shadowsocks plugin is not supported in sandbox")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package sandbox_test
+
+import (
+	"testing"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common/serial"
+	. "v2ray.com/core/main/sandbox"
+	"v2ray.com/core/proxy/freedom"
+	"v2ray.com/core/proxy/shadowsocks"
+)
+
+func TestCheckConfig(t *testing.T) {
+	cases := []struct {
+		config *core.Config
+		ok     bool
+	}{
+		{
+			config: &core.Config{
+				Inbound: []*core.InboundHandlerConfig{{ProxySettings: serial.ToTypedMessage(&shadowsocks.ServerConfig{})}},
+				Outbound: []*core.OutboundHandlerConfig{
+					{ProxySettings: serial.ToTypedMessage(&freedom.Config{})},
+					{ProxySettings: serial.ToTypedMessage(&shadowsocks.ClientConfig{})},
+				},
+			},
+			ok: true,
+		},
+		{
+			config: &core.Config{
+				Inbound: []*core.InboundHandlerConfig{{ProxySettings: serial.ToTypedMessage(&shadowsocks.ServerConfig{Plugin: "v2ray-plugin"})}},
+			},
+		},
+		{
+			config: &core.Config{
+				Outbound: []*core.OutboundHandlerConfig{{ProxySettings: serial.ToTypedMessage(&shadowsocks.ClientConfig{Plugin: "v2ray-plugin"})}},
+			},
+		},
+	}
+
+	for i, c := range cases {
+		if err := CheckConfig(c.config); (err == nil) != c.ok {
+			t.Error("case ", i, ": unexpected result ", err)
+		}
+	}
+}
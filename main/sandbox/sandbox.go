@@ -0,0 +1,5 @@
+// Package sandbox restricts what a running V2Ray process is able to do, so that a compromise in
+// protocol parsing can do little harm to the system.
+package sandbox
+
+//go:generate errorgen
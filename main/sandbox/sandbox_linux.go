@@ -0,0 +1,111 @@
+// +build linux,amd64 linux,arm64
+
+package sandbox
+
+import (
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTSync = 1
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	// Offsets in struct seccomp_data.
+	seccompDataNr   = 0
+	seccompDataArch = 4
+)
+
+// allowedSyscalls are the system calls made by the Go runtime and V2Ray after it is started.
+// Opening files is allowed for logs and DNS configs. Executing programs and tracing processes are not.
+var allowedSyscalls = append([]uint32{
+	unix.SYS_READ, unix.SYS_WRITE, unix.SYS_READV, unix.SYS_WRITEV, unix.SYS_PREAD64, unix.SYS_PWRITE64,
+	unix.SYS_CLOSE, unix.SYS_FSTAT, unix.SYS_LSEEK, unix.SYS_OPENAT, unix.SYS_GETDENTS64, unix.SYS_READLINKAT,
+	unix.SYS_STATX, unix.SYS_FACCESSAT, unix.SYS_FSTATFS, unix.SYS_GETCWD, unix.SYS_UMASK,
+	unix.SYS_FSYNC, unix.SYS_FDATASYNC, unix.SYS_FTRUNCATE, unix.SYS_FCHMOD,
+	unix.SYS_UNLINKAT, unix.SYS_RENAMEAT, unix.SYS_MKDIRAT,
+	unix.SYS_IOCTL, unix.SYS_FCNTL, unix.SYS_DUP, unix.SYS_DUP3, unix.SYS_PIPE2,
+	unix.SYS_SPLICE, unix.SYS_SENDFILE, unix.SYS_COPY_FILE_RANGE,
+
+	unix.SYS_SOCKET, unix.SYS_SOCKETPAIR, unix.SYS_CONNECT, unix.SYS_ACCEPT, unix.SYS_ACCEPT4,
+	unix.SYS_BIND, unix.SYS_LISTEN, unix.SYS_SHUTDOWN, unix.SYS_GETSOCKNAME, unix.SYS_GETPEERNAME,
+	unix.SYS_SETSOCKOPT, unix.SYS_GETSOCKOPT, unix.SYS_SENDTO, unix.SYS_RECVFROM,
+	unix.SYS_SENDMSG, unix.SYS_RECVMSG, unix.SYS_SENDMMSG, unix.SYS_RECVMMSG,
+	unix.SYS_EPOLL_CREATE1, unix.SYS_EPOLL_CTL, unix.SYS_EPOLL_PWAIT, unix.SYS_EVENTFD2,
+	unix.SYS_PPOLL, unix.SYS_PSELECT6,
+
+	unix.SYS_MMAP, unix.SYS_MUNMAP, unix.SYS_MREMAP, unix.SYS_MPROTECT, unix.SYS_MADVISE, unix.SYS_MINCORE, unix.SYS_BRK,
+	unix.SYS_RT_SIGACTION, unix.SYS_RT_SIGPROCMASK, unix.SYS_RT_SIGRETURN, unix.SYS_SIGALTSTACK, unix.SYS_TGKILL,
+	unix.SYS_CLONE, unix.SYS_FUTEX, unix.SYS_SET_ROBUST_LIST, unix.SYS_RSEQ, unix.SYS_SET_TID_ADDRESS,
+	unix.SYS_SCHED_YIELD, unix.SYS_SCHED_GETAFFINITY, unix.SYS_NANOSLEEP, unix.SYS_CLOCK_GETTIME,
+	unix.SYS_CLOCK_NANOSLEEP, unix.SYS_GETTIMEOFDAY, unix.SYS_TIMER_CREATE, unix.SYS_TIMER_SETTIME, unix.SYS_TIMER_DELETE,
+	unix.SYS_GETPID, unix.SYS_GETTID, unix.SYS_GETUID, unix.SYS_GETEUID, unix.SYS_GETGID, unix.SYS_GETEGID,
+	unix.SYS_UNAME, unix.SYS_PRLIMIT64, unix.SYS_PRCTL, unix.SYS_GETRANDOM, unix.SYS_RESTART_SYSCALL,
+	unix.SYS_EXIT, unix.SYS_EXIT_GROUP,
+}, archSyscalls...)
+
+// buildFilter returns a seccomp filter that allows the given system calls only.
+func buildFilter(allowed []uint32) ([]bpf.RawInstruction, error) {
+	n := len(allowed)
+	if n+2 > 255 {
+		return nil, newError("too many system calls")
+	}
+
+	program := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: seccompDataArch, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: auditArch, SkipTrue: 1},
+		bpf.RetConstant{Val: seccompRetKillProcess},
+		bpf.LoadAbsolute{Off: seccompDataNr, Size: 4},
+		// clone3 fails with ENOSYS instead of EPERM, so that libc falls back to clone.
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: unix.SYS_CLONE3, SkipTrue: uint8(n + 2)},
+	}
+	for i, nr := range allowed {
+		program = append(program, bpf.JumpIf{Cond: bpf.JumpEqual, Val: nr, SkipTrue: uint8(n - i)})
+	}
+	program = append(program,
+		bpf.RetConstant{Val: seccompRetErrno | uint32(unix.EPERM)},
+		bpf.RetConstant{Val: seccompRetAllow},
+		bpf.RetConstant{Val: seccompRetErrno | uint32(unix.ENOSYS)},
+	)
+	return bpf.Assemble(program)
+}
+
+// Apply installs a seccomp filter on all threads of the process. System calls not in the allowlist fail with EPERM.
+// The filter can't be removed once applied.
+func Apply() error {
+	raw, err := buildFilter(allowedSyscalls)
+	if err != nil {
+		return err
+	}
+	filter := make([]unix.SockFilter, len(raw))
+	for i, ins := range raw {
+		filter[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	program := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// no_new_privs is set on the current thread, and then synchronized to the others with the filter.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return newError("failed to set no_new_privs").Base(err)
+	}
+	r, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTSync, uintptr(unsafe.Pointer(&program)))
+	if errno != 0 {
+		return newError("failed to install seccomp filter").Base(errno)
+	}
+	if r != 0 {
+		return newError("failed to synchronize seccomp filter to thread ", r)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = 0xc00000b7 // AUDIT_ARCH_AARCH64
+
+var archSyscalls = []uint32{
+	unix.SYS_FSTATAT,
+}
@@ -0,0 +1,58 @@
+// +build linux,amd64 linux,arm64
+
+package sandbox_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/main/sandbox"
+)
+
+// sandboxedMain runs in a child process, as the sandbox can't be removed once applied.
+func sandboxedMain() {
+	common.Must(Apply())
+
+	// Networking still works.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		conn.Write([]byte("ok")) // nolint: errcheck
+		conn.Close()
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	b, err := ioutil.ReadAll(conn)
+	common.Must(err)
+	if string(b) != "ok" {
+		os.Exit(2)
+	}
+
+	// Executing programs does not.
+	if err := exec.Command("/bin/true").Run(); err == nil || !os.IsPermission(err) {
+		os.Exit(3)
+	}
+	os.Exit(0)
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv("V2RAY_SANDBOX_TEST") == "1" {
+		sandboxedMain()
+	}
+	os.Exit(m.Run())
+}
+
+func TestApply(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "V2RAY_SANDBOX_TEST=1")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal("sandboxed process failed: ", err, "\n", string(output))
+	}
+}
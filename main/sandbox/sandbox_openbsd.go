@@ -0,0 +1,22 @@
+package sandbox
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Apply hides the file system except for the files needed by DNS resolution and TLS verification,
+// and pledges the process to networking and reading files. Log files opened before are still writable.
+func Apply() error {
+	for _, path := range []string{"/etc/resolv.conf", "/etc/hosts", "/etc/ssl"} {
+		if err := unix.Unveil(path, "r"); err != nil {
+			return newError("failed to unveil ", path).Base(err)
+		}
+	}
+	if err := unix.UnveilBlock(); err != nil {
+		return newError("failed to lock unveil").Base(err)
+	}
+	if err := unix.PledgePromises("stdio rpath inet dns unix"); err != nil {
+		return newError("failed to pledge").Base(err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+// +build !linux,!openbsd linux,!amd64,!arm64
+
+package sandbox
+
+import "runtime"
+
+// Apply is not supported on this platform.
+func Apply() error {
+	return newError("sandbox is not supported on ", runtime.GOOS, "/", runtime.GOARCH)
+}
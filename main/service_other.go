@@ -0,0 +1,11 @@
+// +build !windows
+
+package main
+
+func runAsService() (bool, error) {
+	return false, nil
+}
+
+func runServiceCommand(args []string) error {
+	return newError("service mode is only available on Windows. Use systemd or launchd instead.")
+}
@@ -0,0 +1,233 @@
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/reload/command"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+)
+
+const serviceName = "v2ray"
+
+// eventLogHandler is a log.Handler that writes logs into Windows Event Log.
+type eventLogHandler struct {
+	elog *eventlog.Log
+}
+
+// Handle implements log.Handler.
+func (h *eventLogHandler) Handle(msg log.Message) {
+	const eventID = 1
+	if m, ok := msg.(*log.GeneralMessage); ok {
+		switch m.Severity {
+		case log.Severity_Error:
+			h.elog.Error(eventID, msg.String()) // nolint: errcheck
+			return
+		case log.Severity_Warning:
+			h.elog.Warning(eventID, msg.String()) // nolint: errcheck
+			return
+		}
+	}
+	h.elog.Info(eventID, msg.String()) // nolint: errcheck
+}
+
+// Close implements common.Closable.
+func (h *eventLogHandler) Close() error {
+	return h.elog.Close()
+}
+
+type service struct{}
+
+// Execute implements svc.Handler.
+func (service) Execute(args []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	configFiles, err := getConfigFilePath()
+	if err != nil {
+		newError("failed to start service").Base(err).AtError().WriteToLog()
+		return true, 23
+	}
+	server, config, err := startV2Ray(configFiles)
+	if err != nil {
+		newError("failed to start service").Base(err).AtError().WriteToLog()
+		return true, 23
+	}
+	if err := server.Start(); err != nil {
+		newError("failed to start service").Base(err).AtError().WriteToLog()
+		return true, 1
+	}
+
+	l := &launcher{
+		configFiles: configFiles,
+		server:      server,
+		config:      config,
+	}
+	command.SetReloader(l.reload)
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			status <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			shutdown(l.current(), nil, *drain)
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// runAsService runs V2Ray as a Windows service, if the process is started by the service control manager.
+// It returns false if the process is started interactively.
+func runAsService() (bool, error) {
+	interactive, err := svc.IsAnInteractiveSession()
+	if err != nil || interactive {
+		return false, err
+	}
+
+	// Services have no console. Logs to console go to Event Log instead.
+	common.Must(applog.RegisterHandlerCreator(applog.LogType_Console, func(applog.LogType, applog.HandlerCreatorOptions) (log.Handler, error) {
+		elog, err := eventlog.Open(serviceName)
+		if err != nil {
+			return nil, err
+		}
+		return &eventLogHandler{elog: elog}, nil
+	}))
+
+	return true, svc.Run(serviceName, service{})
+}
+
+// absArgs returns args with paths of config files and directories made absolute,
+// as services run in the system directory.
+func absArgs(args []string) []string {
+	isPathFlag := func(name string) bool {
+		name = strings.TrimLeft(name, "-")
+		return name == "config" || name == "c" || name == "confdir"
+	}
+
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if idx := strings.Index(arg, "="); idx > 0 && isPathFlag(arg[:idx]) {
+			if p, err := filepath.Abs(arg[idx+1:]); err == nil {
+				arg = arg[:idx+1] + p
+			}
+		} else if isPathFlag(arg) && i+1 < len(args) {
+			result = append(result, arg)
+			i++
+			arg = args[i]
+			if p, err := filepath.Abs(arg); err == nil {
+				arg = p
+			}
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+func installService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return newError("failed to connect to service control manager").Base(err)
+	}
+	defer m.Disconnect() // nolint: errcheck
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return newError("service ", serviceName, " already exists")
+	}
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "V2Ray",
+		Description: "A platform for building proxies to bypass network restrictions.",
+		StartType:   mgr.StartAutomatic,
+	}, absArgs(args)...)
+	if err != nil {
+		return newError("failed to create service").Base(err)
+	}
+	defer s.Close()
+
+	// Restart on crashes. Configuration errors are not retried, as they are not counted as crashes.
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+	}, uint32((24 * time.Hour).Seconds())); err != nil {
+		s.Delete() // nolint: errcheck
+		return newError("failed to set recovery actions").Base(err)
+	}
+
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete() // nolint: errcheck
+		return newError("failed to register event source").Base(err)
+	}
+
+	return nil
+}
+
+func controlService(action string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return newError("failed to connect to service control manager").Base(err)
+	}
+	defer m.Disconnect() // nolint: errcheck
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return newError("failed to open service ", serviceName).Base(err)
+	}
+	defer s.Close()
+
+	switch action {
+	case "start":
+		return s.Start()
+	case "stop":
+		_, err := s.Control(svc.Stop)
+		return err
+	case "uninstall":
+		if err := s.Delete(); err != nil {
+			return err
+		}
+		return eventlog.Remove(serviceName)
+	default:
+		return newError("unknown service command: ", action)
+	}
+}
+
+// runServiceCommand handles "v2ray service <install|uninstall|start|stop>".
+// Arguments after "install" are passed to V2Ray when the service starts.
+func runServiceCommand(args []string) error {
+	if len(args) == 0 {
+		return newError("usage: v2ray service <install|uninstall|start|stop> [arguments for install]")
+	}
+
+	action := args[0]
+	if action == "install" {
+		if err := installService(args[1:]); err != nil {
+			return err
+		}
+	} else if err := controlService(action); err != nil {
+		return err
+	}
+
+	fmt.Println("Service", serviceName, action, "OK.")
+	return nil
+}
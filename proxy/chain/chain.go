@@ -0,0 +1,90 @@
+// +build !confonly
+
+// Package chain is an outbound handler that sends traffic through a chain of other outbounds.
+package chain
+
+//go:generate errorgen
+
+import (
+	"context"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/features/outbound"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport"
+	"v2ray.com/core/transport/internet"
+)
+
+// Handler is an outbound handler that dials each outbound in the chain through the one before it.
+type Handler struct {
+	tags            []string
+	outboundManager outbound.Manager
+}
+
+// New creates a new chain handler.
+func New(ctx context.Context, config *Config) (*Handler, error) {
+	if len(config.OutboundTag) == 0 {
+		return nil, newError("no outbound in chain")
+	}
+	h := &Handler{
+		tags: config.OutboundTag,
+	}
+	if err := core.RequireFeatures(ctx, func(om outbound.Manager) error {
+		h.outboundManager = om
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// getHop returns the outbound of the i-th hop, counting from 1.
+func (h *Handler) getHop(i int) (proxy.Outbound, internet.Dialer, error) {
+	tag := h.tags[i-1]
+	handler := h.outboundManager.GetHandler(tag)
+	if handler == nil {
+		return nil, nil, newError("hop ", i, " (", tag, ") not found")
+	}
+	getOutbound, ok := handler.(proxy.GetOutbound)
+	if !ok {
+		return nil, nil, newError("hop ", i, " (", tag, ") is not a proxy")
+	}
+	p := getOutbound.GetOutbound()
+	if _, ok := p.(*Handler); ok {
+		return nil, nil, newError("hop ", i, " (", tag, ") is a chain")
+	}
+	dialer, ok := handler.(internet.Dialer)
+	if !ok {
+		return nil, nil, newError("hop ", i, " (", tag, ") is not a dialer")
+	}
+	return p, dialer, nil
+}
+
+// Process implements proxy.Outbound.
+func (h *Handler) Process(ctx context.Context, link *transport.Link, _ internet.Dialer) error {
+	// All hops are checked beforehand, so that a broken chain fails fast instead of in the middle of dialing.
+	for i := 1; i < len(h.tags); i++ {
+		if _, _, err := h.getHop(i); err != nil {
+			return err
+		}
+	}
+	n := len(h.tags)
+	p, dialer, err := h.getHop(n)
+	if err != nil {
+		return err
+	}
+
+	ctx = session.ContextWithHops(ctx, h.tags[:n-1])
+	if err := p.Process(ctx, link, dialer); err != nil {
+		return newError("hop ", n, " (", h.tags[n-1], ") of chain failed").Base(err)
+	}
+	return nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
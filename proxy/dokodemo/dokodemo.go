@@ -21,6 +21,7 @@ import (
 	"v2ray.com/core/features/policy"
 	"v2ray.com/core/features/routing"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/sockmap"
 )
 
 func init() {
@@ -122,6 +123,19 @@ func (d *DokodemoDoor) Process(ctx context.Context, network net.Network, conn in
 	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
 
 	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
+
+	var relay *sockmap.Relay
+	if d.config.Sockmap && dest.Network == net.Network_TCP {
+		if r, err := sockmap.NewRelay(conn); err != nil {
+			newError("unable to relay in kernel").Base(err).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+		} else {
+			relay = r
+			ctx = sockmap.ContextWithRelay(ctx, relay)
+			go relay.KeepAlive(ctx, timer)
+			defer relay.Drain()
+		}
+	}
+
 	link, err := dispatcher.Dispatch(ctx, dest)
 	if err != nil {
 		return newError("failed to dispatch request").Base(err)
@@ -138,6 +152,8 @@ func (d *DokodemoDoor) Process(ctx context.Context, network net.Network, conn in
 		var reader buf.Reader
 		if dest.Network == net.Network_UDP {
 			reader = buf.NewPacketReader(conn)
+		} else if relay != nil {
+			reader = buf.NewReader(relay)
 		} else {
 			reader = buf.NewReader(conn)
 		}
@@ -0,0 +1,114 @@
+// +build !confonly
+
+package freedom
+
+import (
+	"io"
+	"time"
+
+	"v2ray.com/core/common/dice"
+)
+
+// fragmentWriter splits the first packets written to a TCP connection into fragments of random lengths, written with
+// random intervals.
+type fragmentWriter struct {
+	fragment *Fragment
+	writer   io.Writer
+	count    uint32
+	sleep    func(time.Duration)
+}
+
+func newFragmentWriter(fragment *Fragment, writer io.Writer) *fragmentWriter {
+	return &fragmentWriter{
+		fragment: fragment,
+		writer:   writer,
+		sleep:    time.Sleep,
+	}
+}
+
+func rollRange(min, max uint32) int {
+	if max <= min {
+		return int(min)
+	}
+	return int(min) + dice.Roll(int(max-min)+1)
+}
+
+func (w *fragmentWriter) nextLength() int {
+	if n := rollRange(w.fragment.LengthMin, w.fragment.LengthMax); n > 0 {
+		return n
+	}
+	return 1
+}
+
+func (w *fragmentWriter) wait() {
+	if interval := rollRange(w.fragment.IntervalMin, w.fragment.IntervalMax); interval > 0 {
+		w.sleep(time.Duration(interval) * time.Millisecond)
+	}
+}
+
+// Write implements io.Writer.
+func (w *fragmentWriter) Write(b []byte) (int, error) {
+	w.count++
+	if w.fragment.PacketsFrom == 0 && w.fragment.PacketsTo == 0 {
+		if w.count == 1 {
+			return w.writeTLSHello(b)
+		}
+		return w.writer.Write(b)
+	}
+	if w.count < w.fragment.PacketsFrom || w.count > w.fragment.PacketsTo {
+		return w.writer.Write(b)
+	}
+
+	for written := 0; written < len(b); {
+		if written > 0 {
+			w.wait()
+		}
+		end := written + w.nextLength()
+		if end > len(b) {
+			end = len(b)
+		}
+		n, err := w.writer.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return len(b), nil
+}
+
+// writeTLSHello splits the handshake record at the beginning of b into several records, each in its own write. Other
+// data is written as is.
+func (w *fragmentWriter) writeTLSHello(b []byte) (int, error) {
+	if len(b) < 5 || b[0] != 0x16 /* TLS Handshake */ {
+		return w.writer.Write(b)
+	}
+	recordLen := int(b[3])<<8 | int(b[4])
+	if len(b) < 5+recordLen {
+		return w.writer.Write(b)
+	}
+
+	data := b[5 : 5+recordLen]
+	record := make([]byte, 0, 5+len(data))
+	for len(data) > 0 {
+		n := w.nextLength()
+		if n > len(data) {
+			n = len(data)
+		}
+		record = append(record[:0], b[0], b[1], b[2], byte(n>>8), byte(n))
+		record = append(record, data[:n]...)
+		if _, err := w.writer.Write(record); err != nil {
+			return 0, err
+		}
+		data = data[n:]
+		if len(data) > 0 {
+			w.wait()
+		}
+	}
+
+	if rest := b[5+recordLen:]; len(rest) > 0 {
+		if _, err := w.writer.Write(rest); err != nil {
+			return 5 + recordLen, err
+		}
+	}
+	return len(b), nil
+}
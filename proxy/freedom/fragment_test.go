@@ -0,0 +1,121 @@
+// +build !confonly
+
+package freedom
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeRecorder keeps each write separately.
+type writeRecorder struct {
+	writes [][]byte
+}
+
+func (r *writeRecorder) Write(b []byte) (int, error) {
+	r.writes = append(r.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func newTestFragmentWriter(fragment *Fragment) (*fragmentWriter, *writeRecorder, *int) {
+	recorder := new(writeRecorder)
+	waits := new(int)
+	w := newFragmentWriter(fragment, recorder)
+	w.sleep = func(time.Duration) { *waits++ }
+	return w, recorder, waits
+}
+
+func TestFragmentTLSHello(t *testing.T) {
+	w, recorder, waits := newTestFragmentWriter(&Fragment{
+		LengthMin:   3,
+		LengthMax:   7,
+		IntervalMin: 10,
+		IntervalMax: 20,
+	})
+
+	hello := make([]byte, 100)
+	for i := range hello {
+		hello[i] = byte(i)
+	}
+	record := append([]byte{0x16, 0x03, 0x01, 0x00, byte(len(hello))}, hello...)
+	extra := []byte("extra")
+	n, err := w.Write(append(append([]byte(nil), record...), extra...))
+	if err != nil || n != len(record)+len(extra) {
+		t.Fatal("unexpected write result: ", n, err)
+	}
+
+	var reassembled []byte
+	for i, b := range recorder.writes[:len(recorder.writes)-1] {
+		if b[0] != 0x16 || b[1] != 0x03 || b[2] != 0x01 {
+			t.Fatal("invalid record header in write ", i)
+		}
+		length := int(b[3])<<8 | int(b[4])
+		if length != len(b)-5 || length > 7 || (length < 3 && i != len(recorder.writes)-2) {
+			t.Error("unexpected record length ", length, " in write ", i)
+		}
+		reassembled = append(reassembled, b[5:]...)
+	}
+	if r := cmp.Diff(reassembled, hello); r != "" {
+		t.Error(r)
+	}
+	if r := cmp.Diff(recorder.writes[len(recorder.writes)-1], extra); r != "" {
+		t.Error(r)
+	}
+	if *waits != len(recorder.writes)-2 {
+		t.Error("unexpected waits: ", *waits)
+	}
+
+	// Only the first write is fragmented.
+	recorder.writes = nil
+	next := []byte{0x16, 0x03, 0x03, 0x00, 0x01, 0x00}
+	if _, err := w.Write(next); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.writes) != 1 || !bytes.Equal(recorder.writes[0], next) {
+		t.Error("unexpected writes: ", recorder.writes)
+	}
+}
+
+func TestFragmentTLSHelloPassthrough(t *testing.T) {
+	w, recorder, _ := newTestFragmentWriter(&Fragment{LengthMin: 1, LengthMax: 1})
+	payload := []byte("GET / HTTP/1.1\r\n\r\n")
+	if _, err := w.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.writes) != 1 || !bytes.Equal(recorder.writes[0], payload) {
+		t.Error("unexpected writes: ", recorder.writes)
+	}
+}
+
+func TestFragmentPackets(t *testing.T) {
+	w, recorder, waits := newTestFragmentWriter(&Fragment{
+		PacketsFrom: 2,
+		PacketsTo:   3,
+		LengthMin:   4,
+		LengthMax:   4,
+	})
+
+	payload := []byte("0123456789")
+	for i := 0; i < 4; i++ {
+		n, err := w.Write(payload)
+		if err != nil || n != len(payload) {
+			t.Fatal("unexpected write result: ", n, err)
+		}
+	}
+
+	expected := [][]byte{
+		[]byte("0123456789"),
+		[]byte("0123"), []byte("4567"), []byte("89"),
+		[]byte("0123"), []byte("4567"), []byte("89"),
+		[]byte("0123456789"),
+	}
+	if r := cmp.Diff(recorder.writes, expected); r != "" {
+		t.Error(r)
+	}
+	if *waits != 0 {
+		t.Error("unexpected waits without interval: ", *waits)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"v2ray.com/core/features/policy"
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/sockmap"
 )
 
 func init() {
@@ -40,6 +41,7 @@ type Handler struct {
 	policyManager policy.Manager
 	dns           dns.Client
 	config        *Config
+	nat64         *nat64
 }
 
 // Init initializes the Handler with necessary parameters.
@@ -47,6 +49,12 @@ func (h *Handler) Init(config *Config, pm policy.Manager, d dns.Client) error {
 	h.config = config
 	h.policyManager = pm
 	h.dns = d
+	if config.Nat64 != nil {
+		if len(config.Nat64.Prefix) > 0 && (len(config.Nat64.Prefix) != net.IPv6len || !validNAT64PrefixLength(config.Nat64.PrefixLength)) {
+			return newError("invalid NAT64 prefix")
+		}
+		h.nat64 = &nat64{config: config.Nat64}
+	}
 
 	return nil
 }
@@ -82,6 +90,41 @@ func (h *Handler) resolveIP(ctx context.Context, domain string, localAddr net.Ad
 	return net.IPAddress(ips[dice.Roll(len(ips))])
 }
 
+// nat64Translator returns the NAT64 translation in use, or nil if IPv4 destinations are dialed as is.
+func (h *Handler) nat64Translator(ctx context.Context, dialer internet.Dialer) func(net.IP) net.IP {
+	if via := dialer.Address(); via != nil && via.Family().IsIPv4() {
+		return nil
+	}
+	return h.nat64.translator(ctx)
+}
+
+// resolveNAT64 resolves the domain into an IPv6 address, or an IPv4 address translated by NAT64.
+func (h *Handler) resolveNAT64(ctx context.Context, domain string, translate func(net.IP) net.IP) net.Address {
+	var ips []net.IP
+	if lookupIPv6, ok := h.dns.(dns.IPv6Lookup); ok {
+		ips, _ = lookupIPv6.LookupIPv6(domain)
+	}
+	if len(ips) == 0 {
+		var err error
+		if lookupIPv4, ok := h.dns.(dns.IPv4Lookup); ok {
+			ips, err = lookupIPv4.LookupIPv4(domain)
+		} else {
+			ips, err = h.dns.LookupIP(domain)
+		}
+		if err != nil {
+			newError("failed to get IP address for domain ", domain).Base(err).WriteToLog(session.ExportIDToError(ctx))
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	ip := ips[dice.Roll(len(ips))]
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = translate(ip4)
+	}
+	return net.IPAddress(ip)
+}
+
 func isValidAddress(addr *net.IPOrDomain) bool {
 	if addr == nil {
 		return false
@@ -115,6 +158,18 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	var conn internet.Connection
 	err := retry.ExponentialBackoff(5, 100).On(func() error {
 		dialDest := destination
+		if translate := h.nat64Translator(ctx, dialer); translate != nil {
+			if dialDest.Address.Family().IsDomain() {
+				if ip := h.resolveNAT64(ctx, dialDest.Address.Domain(), translate); ip != nil {
+					dialDest.Address = ip
+				}
+			} else if dialDest.Address.Family().IsIPv4() {
+				dialDest.Address = net.IPAddress(translate(dialDest.Address.IP()))
+			}
+			if dialDest.Address != destination.Address {
+				newError("dialing to ", dialDest, " through NAT64").WriteToLog(session.ExportIDToError(ctx))
+			}
+		}
 		if h.config.useIP() && dialDest.Address.Family().IsDomain() {
 			ip := h.resolveIP(ctx, dialDest.Address.Domain(), dialer.Address())
 			if ip != nil {
@@ -143,11 +198,26 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
 
+	// Fragments must be written by the writer below, instead of being relayed in kernel.
+	var relay *sockmap.Relay
+	if r := sockmap.RelayFromContext(ctx); r != nil && destination.Network == net.Network_TCP && h.config.Fragment == nil {
+		if err := r.Splice(ctx, conn, input); err != nil {
+			newError("unable to relay in kernel").Base(err).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+		} else {
+			newError("relaying in kernel").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+			relay = r
+			go relay.KeepAlive(ctx, timer)
+			defer relay.Drain()
+		}
+	}
+
 	requestDone := func() error {
 		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
 
 		var writer buf.Writer
-		if destination.Network == net.Network_TCP {
+		if destination.Network == net.Network_TCP && h.config.Fragment != nil {
+			writer = buf.NewWriter(newFragmentWriter(h.config.Fragment, conn))
+		} else if destination.Network == net.Network_TCP {
 			writer = buf.NewWriter(conn)
 		} else {
 			writer = &buf.SequentialWriter{Writer: conn}
@@ -164,7 +234,9 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
 
 		var reader buf.Reader
-		if destination.Network == net.Network_TCP {
+		if relay != nil {
+			reader = buf.NewReader(relay.Outbound())
+		} else if destination.Network == net.Network_TCP {
 			reader = buf.NewReader(conn)
 		} else {
 			reader = buf.NewPacketReader(conn)
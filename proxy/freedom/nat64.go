@@ -0,0 +1,154 @@
+// +build !confonly
+
+package freedom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/net"
+)
+
+const (
+	nat64DetectInterval = time.Minute * 5
+	nat64DetectTimeout  = time.Second * 5
+)
+
+// ipv4OnlyDomain has only A records of the well-known addresses. DNS64 resolvers synthesize AAAA records for it,
+// which reveal the NAT64 prefix. See RFC 7050.
+const ipv4OnlyDomain = "ipv4only.arpa"
+
+var ipv4OnlyAddresses = []net.IP{
+	{192, 0, 0, 170},
+	{192, 0, 0, 171},
+}
+
+// validNAT64PrefixLength returns true if length is a prefix length defined in RFC 6052.
+func validNAT64PrefixLength(length uint32) bool {
+	switch length {
+	case 32, 40, 48, 56, 64, 96:
+		return true
+	default:
+		return false
+	}
+}
+
+// nat64Positions returns the indices in an IPv6 address of the embedded IPv4 address. Bits 64 to 71 are always zero.
+func nat64Positions(length int) []int {
+	positions := make([]int, 0, net.IPv4len)
+	for i := length / 8; len(positions) < net.IPv4len; i++ {
+		if i != 8 {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// synthesizeIPv6 embeds an IPv4 address into the NAT64 prefix, as in RFC 6052.
+func synthesizeIPv6(prefix net.IP, length int, ip net.IP) net.IP {
+	ip6 := make(net.IP, net.IPv6len)
+	copy(ip6, prefix[:length/8])
+	for i, pos := range nat64Positions(length) {
+		ip6[pos] = ip[i]
+	}
+	return ip6
+}
+
+// extractIPv4 is the reverse of synthesizeIPv6.
+func extractIPv4(ip6 net.IP, length int) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	for i, pos := range nat64Positions(length) {
+		ip[i] = ip6[pos]
+	}
+	return ip
+}
+
+// detectNAT64Prefix finds the prefix in the given AAAA records of ipv4OnlyDomain.
+func detectNAT64Prefix(ips []net.IP) (net.IP, int, bool) {
+	for _, ip := range ips {
+		if len(ip) != net.IPv6len || ip.To4() != nil {
+			continue
+		}
+		for _, length := range []int{96, 64, 56, 48, 40, 32} {
+			if length < 96 && ip[8] != 0 {
+				continue
+			}
+			extracted := extractIPv4(ip, length)
+			for _, known := range ipv4OnlyAddresses {
+				if extracted.Equal(known) {
+					prefix := make(net.IP, net.IPv6len)
+					copy(prefix, ip[:length/8])
+					return prefix, length, true
+				}
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// hasIPv4Route returns true if the system has a route to the IPv4 Internet. No packet is sent.
+func hasIPv4Route() bool {
+	conn, err := net.Dial("udp4", "198.18.0.1:53")
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// nat64 translates IPv4 destinations into IPv6 addresses of a NAT64 gateway.
+type nat64 struct {
+	config *Nat64
+
+	access   sync.Mutex
+	prefix   net.IP
+	length   int
+	detected time.Time
+}
+
+// translator returns a function to translate IPv4 addresses, or nil if NAT64 is not in use.
+func (n *nat64) translator(ctx context.Context) func(net.IP) net.IP {
+	if n == nil {
+		return nil
+	}
+	if len(n.config.Prefix) == net.IPv6len {
+		return n.translate(n.config.Prefix, int(n.config.PrefixLength))
+	}
+
+	n.access.Lock()
+	defer n.access.Unlock()
+
+	if time.Since(n.detected) > nat64DetectInterval {
+		n.detected = time.Now()
+		n.prefix, n.length = nil, 0
+		if !hasIPv4Route() {
+			n.detect(ctx)
+		}
+	}
+	if n.prefix == nil {
+		return nil
+	}
+	return n.translate(n.prefix, n.length)
+}
+
+func (n *nat64) detect(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, nat64DetectTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip6", ipv4OnlyDomain)
+	if err != nil {
+		newError("failed to discover NAT64 prefix").Base(err).AtDebug().WriteToLog()
+		return
+	}
+	if prefix, length, ok := detectNAT64Prefix(ips); ok {
+		n.prefix, n.length = prefix, length
+		newError("discovered NAT64 prefix ", prefix, "/", length).AtInfo().WriteToLog()
+	}
+}
+
+func (n *nat64) translate(prefix net.IP, length int) func(net.IP) net.IP {
+	return func(ip net.IP) net.IP {
+		return synthesizeIPv6(prefix, length, ip)
+	}
+}
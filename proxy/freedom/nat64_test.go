@@ -0,0 +1,64 @@
+// +build !confonly
+
+package freedom
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/net"
+)
+
+func TestSynthesizeIPv6(t *testing.T) {
+	// Examples in RFC 6052, section 2.4.
+	ip := net.ParseIP("192.0.2.33").To4()
+	cases := []struct {
+		prefix string
+		length int
+		output string
+	}{
+		{prefix: "2001:db8::", length: 32, output: "2001:db8:c000:221::"},
+		{prefix: "2001:db8:100::", length: 40, output: "2001:db8:1c0:2:21::"},
+		{prefix: "2001:db8:122::", length: 48, output: "2001:db8:122:c000:2:2100::"},
+		{prefix: "2001:db8:122:300::", length: 56, output: "2001:db8:122:3c0:0:221::"},
+		{prefix: "2001:db8:122:344::", length: 64, output: "2001:db8:122:344:c0:2:2100:0"},
+		{prefix: "64:ff9b::", length: 96, output: "64:ff9b::c000:221"},
+	}
+	for _, c := range cases {
+		ip6 := synthesizeIPv6(net.ParseIP(c.prefix), c.length, ip)
+		if !ip6.Equal(net.ParseIP(c.output)) {
+			t.Error("synthesize ", ip, " with ", c.prefix, "/", c.length, ": got ", ip6, ", want ", c.output)
+		}
+		if extracted := extractIPv4(ip6, c.length); !extracted.Equal(ip) {
+			t.Error("extract from ", ip6, ": got ", extracted)
+		}
+	}
+}
+
+func TestDetectNAT64Prefix(t *testing.T) {
+	cases := []struct {
+		records []string
+		prefix  string
+		length  int
+	}{
+		{records: []string{"64:ff9b::c000:aa", "64:ff9b::c000:ab"}, prefix: "64:ff9b::", length: 96},
+		{records: []string{"2001:db8:122:344:c0:0:aa00:0"}, prefix: "2001:db8:122:344::", length: 64},
+		{records: []string{"2001:db8:c000:aa::"}, prefix: "2001:db8::", length: 32},
+		{records: []string{"2001:db8::1"}},
+	}
+	for _, c := range cases {
+		var ips []net.IP
+		for _, r := range c.records {
+			ips = append(ips, net.ParseIP(r))
+		}
+		prefix, length, ok := detectNAT64Prefix(ips)
+		if c.length == 0 {
+			if ok {
+				t.Error("unexpected prefix ", prefix, "/", length, " in ", c.records)
+			}
+			continue
+		}
+		if !ok || !prefix.Equal(net.ParseIP(c.prefix)) || length != c.length {
+			t.Error("detect ", c.records, ": got ", prefix, "/", length, ", want ", c.prefix, "/", c.length)
+		}
+	}
+}
@@ -24,6 +24,24 @@ type Inbound interface {
 	Process(context.Context, net.Network, internet.Connection, routing.Dispatcher) error
 }
 
+// DeviceInbound is an Inbound that captures connections from a network device, such as a TUN device, instead of listening on ports.
+type DeviceInbound interface {
+	Inbound
+
+	// StartDevice opens the device, and calls handler for each captured connection.
+	// The LocalAddr() of the connection is its original destination.
+	StartDevice(handler func(net.Network, internet.Connection)) error
+}
+
+// ForwardedInbound is an Inbound whose TCP ports are listened on by another process, such as a SIP003 plugin. The
+// process forwards connections to the Inbound, which listens on the loopback address instead.
+type ForwardedInbound interface {
+	Inbound
+
+	// StartForwarder starts forwarding connections on the public destination to the local one.
+	StartForwarder(public net.Destination, local net.Destination) error
+}
+
 // An Outbound process outbound connections.
 type Outbound interface {
 	// Process processes the given connection. The given dialer may be used to dial a system outbound connection.
@@ -23,17 +23,27 @@ import (
 type Client struct {
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
+	plugins       map[*protocol.ServerSpec]*pluginProcess
 }
 
 // NewClient create a new Shadowsocks client.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	serverList := protocol.NewServerList()
+	var plugins map[*protocol.ServerSpec]*pluginProcess
 	for _, rec := range config.Server {
 		s, err := protocol.NewServerSpecFromPB(rec)
 		if err != nil {
 			return nil, newError("failed to parse server spec").Base(err)
 		}
 		serverList.AddServer(s)
+		if config.Plugin != "" {
+			if plugins == nil {
+				plugins = make(map[*protocol.ServerSpec]*pluginProcess)
+			}
+			plugin := newPluginProcess(config.Plugin, config.PluginOpts)
+			plugin.remote = s.Destination()
+			plugins[s] = plugin
+		}
 	}
 	if serverList.Size() == 0 {
 		return nil, newError("0 server")
@@ -43,10 +53,34 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	client := &Client{
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		plugins:       plugins,
 	}
 	return client, nil
 }
 
+// Start implements common.Runnable. It starts the plugins of all servers, if any.
+func (c *Client) Start() error {
+	for _, plugin := range c.plugins {
+		local, err := pickLocalPort()
+		if err != nil {
+			return newError("failed to pick a port for plugin").Base(err)
+		}
+		plugin.local = local
+		if err := plugin.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (c *Client) Close() error {
+	for _, plugin := range c.plugins {
+		plugin.Close() // nolint: errcheck
+	}
+	return nil
+}
+
 // Process implements OutboundHandler.Process().
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbound := session.OutboundFromContext(ctx)
@@ -63,6 +97,9 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		server = c.serverPicker.PickServer()
 		dest := server.Destination()
 		dest.Network = network
+		if plugin := c.plugins[server]; plugin != nil && network == net.Network_TCP {
+			dest = plugin.local
+		}
 		rawConn, err := dialer.Dial(ctx, dest)
 		if err != nil {
 			return err
@@ -123,7 +160,13 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		responseDone := func() error {
 			defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
-			responseReader, err := ReadTCPResponse(user, conn)
+			var responseReader buf.Reader
+			var err error
+			if writer, ok := bodyWriter.(*tcpWriter2022); ok {
+				responseReader, err = readTCPResponse2022(user, writer.salt, conn)
+			} else {
+				responseReader, err = ReadTCPResponse(user, conn)
+			}
 			if err != nil {
 				return err
 			}
@@ -140,10 +183,15 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	}
 
 	if request.Command == protocol.RequestCommandUDP {
+		var udpSession *udpSession2022
+		if isAEAD2022(account) {
+			udpSession = newUDPSession2022(false)
+		}
 
 		writer := &buf.SequentialWriter{Writer: &UDPWriter{
 			Writer:  conn,
 			Request: request,
+			session: udpSession,
 		}}
 
 		requestDone := func() error {
@@ -159,8 +207,9 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 			defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
 			reader := &UDPReader{
-				Reader: conn,
-				User:   user,
+				Reader:  conn,
+				User:    user,
+				session: udpSession,
 			}
 
 			if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
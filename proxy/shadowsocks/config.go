@@ -6,7 +6,9 @@ import (
 	"crypto/cipher"
 	"crypto/md5"
 	"crypto/sha1"
+	"encoding/base64"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
@@ -22,6 +24,8 @@ type MemoryAccount struct {
 	Cipher      Cipher
 	Key         []byte
 	OneTimeAuth Account_OneTimeAuth
+	// IdentityKeys are the keys of the servers in front of the user, with Shadowsocks 2022 ciphers.
+	IdentityKeys [][]byte
 }
 
 // Equals implements protocol.Account.Equals().
@@ -76,6 +80,10 @@ func (a *Account) getCipher() (Cipher, error) {
 		}, nil
 	case CipherType_NONE:
 		return NoneCipher{}, nil
+	case CipherType_BLAKE3_AES_128_GCM:
+		return &AEAD2022Cipher{KeyBytes: 16}, nil
+	case CipherType_BLAKE3_AES_256_GCM:
+		return &AEAD2022Cipher{KeyBytes: 32}, nil
 	default:
 		return nil, newError("Unsupported cipher.")
 	}
@@ -87,6 +95,17 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	if err != nil {
 		return nil, newError("failed to get cipher").Base(err)
 	}
+	if _, ok := cipher.(*AEAD2022Cipher); ok {
+		keys, err := parse2022Keys(a.Password, cipher.KeySize())
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryAccount{
+			Cipher:       cipher,
+			Key:          keys[len(keys)-1],
+			IdentityKeys: keys[:len(keys)-1],
+		}, nil
+	}
 	return &MemoryAccount{
 		Cipher:      cipher,
 		Key:         passwordToCipherKey([]byte(a.Password), cipher.KeySize()),
@@ -94,6 +113,14 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	}, nil
 }
 
+// AllUsers returns 'user' and 'users' of the server.
+func (c *ServerConfig) AllUsers() []*protocol.User {
+	if c.User == nil {
+		return c.Users
+	}
+	return append([]*protocol.User{c.User}, c.Users...)
+}
+
 // Cipher is an interface for all Shadowsocks ciphers.
 type Cipher interface {
 	KeySize() int32
@@ -220,6 +247,65 @@ func (c *AEADCipher) DecodePacket(key []byte, b *buf.Buffer) error {
 	return nil
 }
 
+// AEAD2022Cipher represents the AES-GCM ciphers of Shadowsocks 2022, whose keys are derived with BLAKE3.
+type AEAD2022Cipher struct {
+	KeyBytes int32
+}
+
+func (*AEAD2022Cipher) IsAEAD() bool {
+	return true
+}
+
+func (c *AEAD2022Cipher) KeySize() int32 {
+	return c.KeyBytes
+}
+
+// IVSize returns the size of salts, which is the same as keys.
+func (c *AEAD2022Cipher) IVSize() int32 {
+	return c.KeyBytes
+}
+
+// createAEAD returns the AEAD of a session. Salt is the salt of TCP sessions, or the session ID of UDP.
+func (c *AEAD2022Cipher) createAEAD(key []byte, salt []byte) cipher.AEAD {
+	material := make([]byte, 0, len(key)+len(salt))
+	material = append(append(material, key...), salt...)
+	subkey := make([]byte, c.KeyBytes)
+	crypto.Blake3DeriveKey(subkey, "shadowsocks 2022 session subkey", material)
+	return createAesGcm(subkey)
+}
+
+func (c *AEAD2022Cipher) createAuthenticator(key []byte, salt []byte) *crypto.AEADAuthenticator {
+	return &crypto.AEADAuthenticator{
+		AEAD:           c.createAEAD(key, salt),
+		NonceGenerator: crypto.GenerateInitialAEADNonce(),
+	}
+}
+
+func (c *AEAD2022Cipher) NewEncryptionWriter(key []byte, iv []byte, writer io.Writer) (buf.Writer, error) {
+	auth := c.createAuthenticator(key, iv)
+	return crypto.NewAuthenticationWriter(auth, &crypto.AEADChunkSizeParser{
+		Auth: auth,
+	}, writer, protocol.TransferTypeStream, nil), nil
+}
+
+func (c *AEAD2022Cipher) NewDecryptionReader(key []byte, iv []byte, reader io.Reader) (buf.Reader, error) {
+	auth := c.createAuthenticator(key, iv)
+	return crypto.NewAuthenticationReader(auth, &crypto.AEADChunkSizeParser{
+		Auth: auth,
+	}, reader, protocol.TransferTypeStream, nil), nil
+}
+
+// EncodePacket implements Cipher.EncodePacket(). Packets of Shadowsocks 2022 belong to sessions, which are not known
+// to the cipher.
+func (c *AEAD2022Cipher) EncodePacket(key []byte, b *buf.Buffer) error {
+	return newError("packets of Shadowsocks 2022 are encoded with sessions")
+}
+
+// DecodePacket implements Cipher.DecodePacket().
+func (c *AEAD2022Cipher) DecodePacket(key []byte, b *buf.Buffer) error {
+	return newError("packets of Shadowsocks 2022 are decoded with sessions")
+}
+
 type ChaCha20 struct {
 	IVBytes int32
 }
@@ -305,6 +391,26 @@ func passwordToCipherKey(password []byte, keySize int32) []byte {
 	return key
 }
 
+// parse2022Keys parses the password of Shadowsocks 2022, which is a list of base64 encoded keys separated by colons.
+// The last key is of the user, and the others are identity keys of servers in order.
+func parse2022Keys(password string, keySize int32) ([][]byte, error) {
+	if password == "" {
+		return nil, newError("empty key")
+	}
+	var keys [][]byte
+	for _, s := range strings.Split(password, ":") {
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, newError("failed to decode key ", s).Base(err)
+		}
+		if int32(len(key)) != keySize {
+			return nil, newError("key ", s, " is ", len(key), " bytes, but ", keySize, " bytes are required by the cipher")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 func hkdfSHA1(secret, salt, outkey []byte) {
 	r := hkdf.New(sha1.New, secret, salt, []byte("ss-subkey"))
 	common.Must2(io.ReadFull(r, outkey))
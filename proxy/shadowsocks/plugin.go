@@ -0,0 +1,167 @@
+// +build !confonly
+
+package shadowsocks
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/signal/done"
+)
+
+// pluginRestartDelay is the time to wait before restarting a plugin that exited.
+const pluginRestartDelay = time.Second
+
+// pluginProcess is a SIP003 plugin. The plugin listens on one of local and remote, and forwards connections to the
+// other. It is restarted whenever it exits, until it is closed.
+type pluginProcess struct {
+	name    string
+	options string
+	local   net.Destination
+	remote  net.Destination
+	delay   time.Duration
+
+	access sync.Mutex
+	cmd    *exec.Cmd
+	done   *done.Instance
+}
+
+func newPluginProcess(name string, options string) *pluginProcess {
+	return &pluginProcess{
+		name:    name,
+		options: options,
+		delay:   pluginRestartDelay,
+		done:    done.New(),
+	}
+}
+
+// pickLocalPort returns a free TCP port on the loopback address.
+func pickLocalPort() (net.Destination, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return net.Destination{}, err
+	}
+	defer listener.Close()
+	return net.DestinationFromAddr(listener.Addr()), nil
+}
+
+func (p *pluginProcess) run() (*exec.Cmd, error) {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	if p.done.Done() {
+		return nil, newError("plugin ", p.name, " closed")
+	}
+
+	cmd := exec.Command(p.name)
+	cmd.Env = append(os.Environ(),
+		"SS_REMOTE_HOST="+p.remote.Address.String(),
+		"SS_REMOTE_PORT="+p.remote.Port.String(),
+		"SS_LOCAL_HOST="+p.local.Address.String(),
+		"SS_LOCAL_PORT="+p.local.Port.String(),
+		"SS_PLUGIN_OPTIONS="+p.options,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setPluginAttr(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, newError("failed to start plugin ", p.name).Base(err)
+	}
+	p.cmd = cmd
+	return cmd, nil
+}
+
+func (p *pluginProcess) supervise(cmd *exec.Cmd) {
+	for {
+		err := cmd.Wait()
+		if p.done.Done() {
+			return
+		}
+		newError("plugin ", p.name, " exited").Base(err).AtWarning().WriteToLog()
+
+		for {
+			select {
+			case <-p.done.Wait():
+				return
+			case <-time.After(p.delay):
+			}
+			if cmd, err = p.run(); err == nil {
+				break
+			}
+			newError("failed to restart plugin ", p.name).Base(err).AtWarning().WriteToLog()
+		}
+	}
+}
+
+// Start starts the plugin between local and remote, which must be set beforehand.
+func (p *pluginProcess) Start() error {
+	cmd, err := p.run()
+	if err != nil {
+		return err
+	}
+	newError("plugin ", p.name, " started between ", p.local.NetAddr(), " and ", p.remote.NetAddr()).AtInfo().WriteToLog()
+	go p.supervise(cmd)
+	return nil
+}
+
+// Close implements common.Closable. It kills the plugin, which is not restarted afterwards.
+func (p *pluginProcess) Close() error {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	if p.done.Done() {
+		return nil
+	}
+	p.done.Close()
+	if p.cmd != nil {
+		// The plugin may have exited already.
+		p.cmd.Process.Kill() // nolint: errcheck
+	}
+	return nil
+}
+
+// pluginServer is a Server with a SIP003 plugin. As ss-server does, the plugin listens on each port of the inbound, and
+// forwards connections to the Server on the loopback address. The source of these connections is lost.
+type pluginServer struct {
+	*Server
+	plugin  string
+	options string
+
+	access  sync.Mutex
+	plugins []*pluginProcess
+}
+
+// StartForwarder implements proxy.ForwardedInbound.
+func (s *pluginServer) StartForwarder(public net.Destination, local net.Destination) error {
+	plugin := newPluginProcess(s.plugin, s.options)
+	plugin.remote = public
+	plugin.local = local
+	if err := plugin.Start(); err != nil {
+		return err
+	}
+
+	s.access.Lock()
+	s.plugins = append(s.plugins, plugin)
+	s.access.Unlock()
+	return nil
+}
+
+// Start implements common.Runnable. Plugins are started along with the listeners of the inbound.
+func (s *pluginServer) Start() error {
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *pluginServer) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	for _, plugin := range s.plugins {
+		plugin.Close() // nolint: errcheck
+	}
+	s.plugins = nil
+	return nil
+}
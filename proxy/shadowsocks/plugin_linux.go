@@ -0,0 +1,13 @@
+// +build !confonly
+
+package shadowsocks
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setPluginAttr makes the plugin killed when V2Ray exits, even if V2Ray is killed before it closes the plugin.
+func setPluginAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
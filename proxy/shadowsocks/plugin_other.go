@@ -0,0 +1,11 @@
+// +build !confonly
+// +build !linux
+
+package shadowsocks
+
+import (
+	"os/exec"
+)
+
+// setPluginAttr does nothing on this platform. The plugin keeps running if V2Ray is killed before it closes the plugin.
+func setPluginAttr(cmd *exec.Cmd) {}
@@ -0,0 +1,111 @@
+package shadowsocks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+)
+
+func TestPluginProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "v2ray-plugin")
+	common.Must(err)
+	defer os.RemoveAll(dir)
+
+	// The plugin records its environment and exits, so that it is restarted.
+	output := filepath.Join(dir, "output")
+	script := filepath.Join(dir, "plugin")
+	common.Must(ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$SS_REMOTE_HOST:$SS_REMOTE_PORT $SS_LOCAL_HOST:$SS_LOCAL_PORT $SS_PLUGIN_OPTIONS\" >> "+output+"\n"), 0700))
+
+	plugin := newPluginProcess(script, "obfs=http;obfs-host=v2fly.org")
+	plugin.delay = 50 * time.Millisecond
+	plugin.remote = net.TCPDestination(net.DomainAddress("v2fly.org"), 8388)
+	plugin.local = net.TCPDestination(net.LocalHostIP, 1080)
+	common.Must(plugin.Start())
+
+	readLines := func() []string {
+		content, err := ioutil.ReadFile(output)
+		if err != nil {
+			return nil
+		}
+		return strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(readLines()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	lines := readLines()
+	if len(lines) < 2 {
+		t.Fatal("plugin is not restarted: ", lines)
+	}
+	if lines[0] != "v2fly.org:8388 127.0.0.1:1080 obfs=http;obfs-host=v2fly.org" {
+		t.Error("unexpected environment: ", lines[0])
+	}
+
+	common.Must(plugin.Close())
+	time.Sleep(200 * time.Millisecond)
+	count := len(readLines())
+	time.Sleep(200 * time.Millisecond)
+	if len(readLines()) != count {
+		t.Error("plugin is restarted after close")
+	}
+}
+
+func TestPluginServer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "v2ray-plugin")
+	common.Must(err)
+	defer os.RemoveAll(dir)
+
+	// The plugin records its environment, and runs until it is killed.
+	output := filepath.Join(dir, "output")
+	script := filepath.Join(dir, "plugin")
+	common.Must(ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$SS_REMOTE_HOST:$SS_REMOTE_PORT $SS_LOCAL_HOST:$SS_LOCAL_PORT\" >> "+output+"\nexec sleep 60\n"), 0700))
+
+	server := &pluginServer{plugin: script}
+	common.Must(server.StartForwarder(net.TCPDestination(net.AnyIP, 8388), net.TCPDestination(net.LocalHostIP, 40001)))
+	common.Must(server.StartForwarder(net.TCPDestination(net.AnyIP, 8389), net.TCPDestination(net.LocalHostIP, 40002)))
+
+	readLines := func() []string {
+		content, err := ioutil.ReadFile(output)
+		if err != nil {
+			return nil
+		}
+		return strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(readLines()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	lines := readLines()
+	sort.Strings(lines)
+	if r := cmp.Diff(lines, []string{"0.0.0.0:8388 127.0.0.1:40001", "0.0.0.0:8389 127.0.0.1:40002"}); r != "" {
+		t.Error(r)
+	}
+
+	plugins := server.plugins
+	common.Must(server.Close())
+	for _, plugin := range plugins {
+		if !plugin.done.Done() {
+			t.Error("plugin is not closed")
+		}
+	}
+}
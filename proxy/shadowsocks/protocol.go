@@ -38,6 +38,14 @@ var addrParser = protocol.NewAddressParser(
 func ReadTCPSession(user *protocol.MemoryUser, reader io.Reader) (*protocol.RequestHeader, buf.Reader, error) {
 	account := user.Account.(*MemoryAccount)
 
+	if cipher, ok := account.Cipher.(*AEAD2022Cipher); ok {
+		salt := make([]byte, cipher.IVSize())
+		if _, err := io.ReadFull(reader, salt); err != nil {
+			return nil, nil, newError("failed to read salt").Base(err)
+		}
+		return readTCPSession2022(user, salt, reader)
+	}
+
 	hashkdf := hmac.New(func() hash.Hash { return sha256.New() }, []byte("SSBSKDF"))
 	hashkdf.Write(account.Key)
 
@@ -155,6 +163,10 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (buf.Wri
 	user := request.User
 	account := user.Account.(*MemoryAccount)
 
+	if _, ok := account.Cipher.(*AEAD2022Cipher); ok {
+		return writeTCPRequest2022(request, writer)
+	}
+
 	if account.Cipher.IsAEAD() {
 		request.Option.Clear(RequestOptionOneTimeAuth)
 	}
@@ -202,9 +214,15 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (buf.Wri
 	return chunkWriter, nil
 }
 
+// ReadTCPResponse reads the response of a TCP session. Responses of Shadowsocks 2022 are read with the request
+// instead.
 func ReadTCPResponse(user *protocol.MemoryUser, reader io.Reader) (buf.Reader, error) {
 	account := user.Account.(*MemoryAccount)
 
+	if _, ok := account.Cipher.(*AEAD2022Cipher); ok {
+		return nil, newError("response of Shadowsocks 2022 requires the salt of request")
+	}
+
 	var iv []byte
 	if account.Cipher.IVSize() > 0 {
 		iv = make([]byte, account.Cipher.IVSize())
@@ -216,10 +234,16 @@ func ReadTCPResponse(user *protocol.MemoryUser, reader io.Reader) (buf.Reader, e
 	return account.Cipher.NewDecryptionReader(account.Key, iv, reader)
 }
 
+// WriteTCPResponse writes the response of a TCP session. Responses of Shadowsocks 2022 are written with the request
+// instead.
 func WriteTCPResponse(request *protocol.RequestHeader, writer io.Writer) (buf.Writer, error) {
 	user := request.User
 	account := user.Account.(*MemoryAccount)
 
+	if _, ok := account.Cipher.(*AEAD2022Cipher); ok {
+		return nil, newError("response of Shadowsocks 2022 requires the salt of request")
+	}
+
 	var iv []byte
 	if account.Cipher.IVSize() > 0 {
 		iv = make([]byte, account.Cipher.IVSize())
@@ -267,6 +291,13 @@ func EncodeUDPPacket(request *protocol.RequestHeader, payload []byte) (*buf.Buff
 func DecodeUDPPacket(user *protocol.MemoryUser, payload *buf.Buffer) (*protocol.RequestHeader, *buf.Buffer, error) {
 	account := user.Account.(*MemoryAccount)
 
+	if _, ok := account.Cipher.(*AEAD2022Cipher); ok {
+		if err := openUDPHeader2022(account.Key, payload); err != nil {
+			return nil, nil, err
+		}
+		return decodeUDPPacket2022(user, payload, nil)
+	}
+
 	var iv []byte
 	if !account.Cipher.IsAEAD() && account.Cipher.IVSize() > 0 {
 		// Keep track of IV as it gets removed from payload in DecodePacket.
@@ -328,6 +359,8 @@ func DecodeUDPPacket(user *protocol.MemoryUser, payload *buf.Buffer) (*protocol.
 type UDPReader struct {
 	Reader io.Reader
 	User   *protocol.MemoryUser
+	// session is the UDP session of Shadowsocks 2022, shared with the UDPWriter.
+	session *udpSession2022
 }
 
 func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
@@ -337,7 +370,14 @@ func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 		buffer.Release()
 		return nil, err
 	}
-	_, payload, err := DecodeUDPPacket(v.User, buffer)
+	var payload *buf.Buffer
+	if v.session != nil {
+		if err = openUDPHeader2022(v.User.Account.(*MemoryAccount).Key, buffer); err == nil {
+			_, payload, err = decodeUDPPacket2022(v.User, buffer, v.session)
+		}
+	} else {
+		_, payload, err = DecodeUDPPacket(v.User, buffer)
+	}
 	if err != nil {
 		buffer.Release()
 		return nil, err
@@ -348,11 +388,22 @@ func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 type UDPWriter struct {
 	Writer  io.Writer
 	Request *protocol.RequestHeader
+	// session is the UDP session of Shadowsocks 2022. It is created on the first packet, if not set.
+	session *udpSession2022
 }
 
 // Write implements io.Writer.
 func (w *UDPWriter) Write(payload []byte) (int, error) {
-	packet, err := EncodeUDPPacket(w.Request, payload)
+	var packet *buf.Buffer
+	var err error
+	if _, ok := w.Request.User.Account.(*MemoryAccount).Cipher.(*AEAD2022Cipher); ok {
+		if w.session == nil {
+			w.session = newUDPSession2022(false)
+		}
+		packet, err = encodeUDPPacket2022(w.Request, w.session, payload)
+	} else {
+		packet, err = EncodeUDPPacket(w.Request, payload)
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,532 @@
+// +build !confonly
+
+package shadowsocks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/antireplay"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/crypto"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/protocol"
+)
+
+// Shadowsocks 2022, as in SIP022. Sessions start with headers of a type and a timestamp, and are identified by salts
+// for TCP, or by session IDs for UDP.
+
+const (
+	headerTypeClient = 0
+	headerTypeServer = 1
+
+	// maxTimeDiff is the largest difference in seconds between the timestamp of a header and the local clock.
+	maxTimeDiff = 30
+	// maxPaddingLength is the largest length of padding in requests.
+	maxPaddingLength = 900
+
+	identityHeaderSize = aes.BlockSize
+	udpHeaderSize      = aes.BlockSize
+)
+
+// saltFilter holds the salts of TCP requests, which are accepted only once. Salts are kept for at least 60 seconds,
+// which covers the timestamps of valid requests.
+var saltFilter = antireplay.NewAntiReplayWindow(60)
+
+func isAEAD2022(account *MemoryAccount) bool {
+	_, ok := account.Cipher.(*AEAD2022Cipher)
+	return ok
+}
+
+func checkTimestamp(timestamp uint64) error {
+	diff := time.Now().Unix() - int64(timestamp)
+	if diff > maxTimeDiff || diff < -maxTimeDiff {
+		return newError("timestamp is off by ", diff, " seconds")
+	}
+	return nil
+}
+
+// keyHash returns the hash of a key, which identifies the user in identity headers.
+func keyHash(key []byte) [16]byte {
+	sum := crypto.Blake3Sum256(key)
+	var hash [16]byte
+	copy(hash[:], sum[:16])
+	return hash
+}
+
+func newAESBlock(key []byte) cipher.Block {
+	block, err := aes.NewCipher(key)
+	common.Must(err)
+	return block
+}
+
+// identitySubkey returns the cipher of the identity header of a TCP session.
+func identitySubkey(key []byte, salt []byte) cipher.Block {
+	material := make([]byte, 0, len(key)+len(salt))
+	material = append(append(material, key...), salt...)
+	subkey := make([]byte, len(key))
+	crypto.Blake3DeriveKey(subkey, "shadowsocks 2022 identity subkey", material)
+	return newAESBlock(subkey)
+}
+
+// tcpReader2022 is the reader of request body with Shadowsocks 2022 ciphers. It keeps the salt of the request, as
+// the response refers to it.
+type tcpReader2022 struct {
+	*buf.BufferedReader
+	salt []byte
+}
+
+// tcpWriter2022 is the writer of request body with Shadowsocks 2022 ciphers.
+type tcpWriter2022 struct {
+	buf.Writer
+	salt []byte
+}
+
+func newChunkReader2022(auth *crypto.AEADAuthenticator, reader io.Reader) buf.Reader {
+	return crypto.NewAuthenticationReader(auth, &crypto.AEADChunkSizeParser{
+		Auth: auth,
+	}, reader, protocol.TransferTypeStream, nil)
+}
+
+func newChunkWriter2022(auth *crypto.AEADAuthenticator, writer io.Writer) buf.Writer {
+	return crypto.NewAuthenticationWriter(auth, &crypto.AEADChunkSizeParser{
+		Auth: auth,
+	}, writer, protocol.TransferTypeStream, nil)
+}
+
+// readTCPSession2022 reads a TCP request after its salt and identity headers.
+func readTCPSession2022(user *protocol.MemoryUser, salt []byte, reader io.Reader) (*protocol.RequestHeader, buf.Reader, error) {
+	account := user.Account.(*MemoryAccount)
+	auth := account.Cipher.(*AEAD2022Cipher).createAuthenticator(account.Key, salt)
+	overhead := auth.Overhead()
+
+	fixedHeader := make([]byte, 1+8+2+overhead)
+	if _, err := io.ReadFull(reader, fixedHeader); err != nil {
+		return nil, nil, newError("failed to read request header").Base(err)
+	}
+	fixedHeader, err := auth.Open(fixedHeader[:0], fixedHeader)
+	if err != nil {
+		return nil, nil, newError("failed to decrypt request header").Base(err)
+	}
+	if fixedHeader[0] != headerTypeClient {
+		return nil, nil, newError("unexpected header type: ", fixedHeader[0])
+	}
+	if err := checkTimestamp(binary.BigEndian.Uint64(fixedHeader[1:])); err != nil {
+		return nil, nil, err
+	}
+	if !saltFilter.Check(salt) {
+		return nil, nil, newError("replayed request")
+	}
+
+	header := make([]byte, int(binary.BigEndian.Uint16(fixedHeader[9:]))+overhead)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, nil, newError("failed to read request header").Base(err)
+	}
+	header, err = auth.Open(header[:0], header)
+	if err != nil {
+		return nil, nil, newError("failed to decrypt request header").Base(err)
+	}
+	headerReader := bytes.NewReader(header)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandTCP,
+	}
+	addr, port, err := addrParser.ReadAddressPort(nil, headerReader)
+	if err != nil {
+		return nil, nil, newError("failed to read address").Base(err)
+	}
+	request.Address = addr
+	request.Port = port
+
+	var paddingLength uint16
+	if err := binary.Read(headerReader, binary.BigEndian, &paddingLength); err != nil {
+		return nil, nil, newError("failed to read padding length").Base(err)
+	}
+	if int(paddingLength) > headerReader.Len() {
+		return nil, nil, newError("invalid padding length: ", paddingLength)
+	}
+	common.Must2(headerReader.Seek(int64(paddingLength), io.SeekCurrent))
+
+	payload := buf.MergeBytes(nil, header[len(header)-headerReader.Len():])
+	return request, &tcpReader2022{
+		BufferedReader: &buf.BufferedReader{Reader: newChunkReader2022(auth, reader), Buffer: payload},
+		salt:           salt,
+	}, nil
+}
+
+// writeTCPRequest2022 writes a TCP request, with padding in place of initial payload.
+func writeTCPRequest2022(request *protocol.RequestHeader, writer io.Writer) (buf.Writer, error) {
+	account := request.User.Account.(*MemoryAccount)
+	c := account.Cipher.(*AEAD2022Cipher)
+
+	salt := make([]byte, c.IVSize())
+	common.Must2(rand.Read(salt))
+
+	buffer := buf.New()
+	defer buffer.Release()
+	buffer.Write(salt)
+	for i, key := range account.IdentityKeys {
+		next := account.Key
+		if i+1 < len(account.IdentityKeys) {
+			next = account.IdentityKeys[i+1]
+		}
+		hash := keyHash(next)
+		identitySubkey(key, salt).Encrypt(buffer.Extend(identityHeaderSize), hash[:])
+	}
+
+	header := buf.New()
+	defer header.Release()
+	if err := addrParser.WriteAddressPort(header, request.Address, request.Port); err != nil {
+		return nil, newError("failed to write address").Base(err)
+	}
+	paddingLength := dice.Roll(maxPaddingLength) + 1
+	binary.BigEndian.PutUint16(header.Extend(2), uint16(paddingLength))
+	common.Must2(header.ReadFullFrom(rand.Reader, int32(paddingLength)))
+
+	auth := c.createAuthenticator(account.Key, salt)
+	overhead := int32(auth.Overhead())
+
+	fixedHeader := buffer.Extend(1 + 8 + 2 + overhead)
+	fixedHeader[0] = headerTypeClient
+	binary.BigEndian.PutUint64(fixedHeader[1:], uint64(time.Now().Unix()))
+	binary.BigEndian.PutUint16(fixedHeader[9:], uint16(header.Len()))
+	common.Must2(auth.Seal(fixedHeader[:0], fixedHeader[:11]))
+
+	sealedHeader := buffer.Extend(header.Len() + overhead)
+	copy(sealedHeader, header.Bytes())
+	common.Must2(auth.Seal(sealedHeader[:0], sealedHeader[:header.Len()]))
+
+	if err := buf.WriteAllBytes(writer, buffer.Bytes()); err != nil {
+		return nil, newError("failed to write request header").Base(err)
+	}
+	return &tcpWriter2022{
+		Writer: newChunkWriter2022(auth, writer),
+		salt:   salt,
+	}, nil
+}
+
+// tcpResponseWriter2022 writes the response header along with the first chunk of payload, as the header includes
+// the length of it.
+type tcpResponseWriter2022 struct {
+	account     *MemoryAccount
+	requestSalt []byte
+	writer      io.Writer
+	body        buf.Writer
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *tcpResponseWriter2022) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if w.body != nil {
+		return w.body.WriteMultiBuffer(mb)
+	}
+
+	c := w.account.Cipher.(*AEAD2022Cipher)
+	salt := make([]byte, c.IVSize())
+	common.Must2(rand.Read(salt))
+	auth := c.createAuthenticator(w.account.Key, salt)
+	overhead := auth.Overhead()
+
+	mb, first := buf.SplitFirst(mb)
+	defer first.Release()
+	var payload []byte
+	if first != nil {
+		payload = first.Bytes()
+	}
+
+	fixedHeader := make([]byte, 1+8+len(w.requestSalt)+2)
+	fixedHeader[0] = headerTypeServer
+	binary.BigEndian.PutUint64(fixedHeader[1:], uint64(time.Now().Unix()))
+	copy(fixedHeader[9:], w.requestSalt)
+	binary.BigEndian.PutUint16(fixedHeader[9+len(w.requestSalt):], uint16(len(payload)))
+
+	header := make([]byte, 0, len(salt)+len(fixedHeader)+len(payload)+overhead*2)
+	header = append(header, salt...)
+	header, err := auth.Seal(header, fixedHeader)
+	if err == nil {
+		header, err = auth.Seal(header, payload)
+	}
+	if err != nil {
+		buf.ReleaseMulti(mb)
+		return newError("failed to encrypt response header").Base(err)
+	}
+
+	if err := buf.WriteAllBytes(w.writer, header); err != nil {
+		buf.ReleaseMulti(mb)
+		return newError("failed to write response header").Base(err)
+	}
+	w.body = newChunkWriter2022(auth, w.writer)
+	if mb.IsEmpty() {
+		return nil
+	}
+	return w.body.WriteMultiBuffer(mb)
+}
+
+func writeTCPResponse2022(request *protocol.RequestHeader, requestSalt []byte, writer io.Writer) (buf.Writer, error) {
+	return &tcpResponseWriter2022{
+		account:     request.User.Account.(*MemoryAccount),
+		requestSalt: requestSalt,
+		writer:      writer,
+	}, nil
+}
+
+func readTCPResponse2022(user *protocol.MemoryUser, requestSalt []byte, reader io.Reader) (buf.Reader, error) {
+	account := user.Account.(*MemoryAccount)
+	c := account.Cipher.(*AEAD2022Cipher)
+
+	salt := make([]byte, c.IVSize())
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return nil, newError("failed to read salt").Base(err)
+	}
+	auth := c.createAuthenticator(account.Key, salt)
+	overhead := auth.Overhead()
+
+	fixedHeader := make([]byte, 1+8+len(requestSalt)+2+overhead)
+	if _, err := io.ReadFull(reader, fixedHeader); err != nil {
+		return nil, newError("failed to read response header").Base(err)
+	}
+	fixedHeader, err := auth.Open(fixedHeader[:0], fixedHeader)
+	if err != nil {
+		return nil, newError("failed to decrypt response header").Base(err)
+	}
+	if fixedHeader[0] != headerTypeServer {
+		return nil, newError("unexpected header type: ", fixedHeader[0])
+	}
+	if err := checkTimestamp(binary.BigEndian.Uint64(fixedHeader[1:])); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(fixedHeader[9:9+len(requestSalt)], requestSalt) {
+		return nil, newError("response is not for the request")
+	}
+
+	payload := make([]byte, int(binary.BigEndian.Uint16(fixedHeader[9+len(requestSalt):]))+overhead)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, newError("failed to read response payload").Base(err)
+	}
+	payload, err = auth.Open(payload[:0], payload)
+	if err != nil {
+		return nil, newError("failed to decrypt response payload").Base(err)
+	}
+
+	return &buf.BufferedReader{
+		Reader: newChunkReader2022(auth, reader),
+		Buffer: buf.MergeBytes(nil, payload),
+	}, nil
+}
+
+// packetWindow is a sliding window of the latest packet IDs of a UDP session, for replay protection.
+type packetWindow struct {
+	started bool
+	// last is the largest packet ID seen.
+	last uint64
+	// Bit i of bitmap is set if packet ID last-i is seen.
+	bitmap uint64
+}
+
+func (w *packetWindow) check(id uint64) bool {
+	switch {
+	case !w.started:
+		w.started = true
+		w.last = id
+		w.bitmap = 1
+		return true
+	case id > w.last:
+		if shift := id - w.last; shift < 64 {
+			w.bitmap = w.bitmap<<shift | 1
+		} else {
+			w.bitmap = 1
+		}
+		w.last = id
+		return true
+	}
+	diff := w.last - id
+	if diff >= 64 || w.bitmap&(1<<diff) != 0 {
+		return false
+	}
+	w.bitmap |= 1 << diff
+	return true
+}
+
+// udpSession2022 is a UDP session of Shadowsocks 2022, on either side. It tracks the latest session of the other
+// side.
+type udpSession2022 struct {
+	sync.Mutex
+	server   bool
+	id       uint64
+	packetID uint64
+	remoteID uint64
+	window   packetWindow
+}
+
+func newUDPSession2022(server bool) *udpSession2022 {
+	return &udpSession2022{
+		server: server,
+		id:     dice.RollUint64(),
+	}
+}
+
+// accept checks whether the packet of the other side is new. A new session ID of the other side resets the window.
+func (s *udpSession2022) accept(remoteID uint64, packetID uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.window.started || s.remoteID != remoteID {
+		s.remoteID = remoteID
+		s.window = packetWindow{}
+	}
+	return s.window.check(packetID)
+}
+
+func (s *udpSession2022) remote() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.remoteID
+}
+
+// encodeUDPPacket2022 encodes a UDP packet of the session. Packets of clients carry identity headers for all identity
+// keys, while packets of servers are encrypted with the key of the user only.
+func encodeUDPPacket2022(request *protocol.RequestHeader, session *udpSession2022, payload []byte) (*buf.Buffer, error) {
+	account := request.User.Account.(*MemoryAccount)
+	c := account.Cipher.(*AEAD2022Cipher)
+
+	buffer := buf.New()
+	header := buffer.Extend(udpHeaderSize)
+	binary.BigEndian.PutUint64(header, session.id)
+	binary.BigEndian.PutUint64(header[8:], atomic.AddUint64(&session.packetID, 1)-1)
+	var plainHeader [udpHeaderSize]byte
+	copy(plainHeader[:], header)
+
+	headerKey := account.Key
+	if !session.server && len(account.IdentityKeys) > 0 {
+		headerKey = account.IdentityKeys[0]
+		for i, key := range account.IdentityKeys {
+			next := account.Key
+			if i+1 < len(account.IdentityKeys) {
+				next = account.IdentityKeys[i+1]
+			}
+			hash := keyHash(next)
+			identityHeader := buffer.Extend(identityHeaderSize)
+			for j := range identityHeader {
+				identityHeader[j] = hash[j] ^ plainHeader[j]
+			}
+			newAESBlock(key).Encrypt(identityHeader, identityHeader)
+		}
+	}
+	newAESBlock(headerKey).Encrypt(header, header)
+
+	bodyStart := buffer.Len()
+	if session.server {
+		buffer.WriteByte(headerTypeServer)
+		binary.BigEndian.PutUint64(buffer.Extend(8), uint64(time.Now().Unix()))
+		binary.BigEndian.PutUint64(buffer.Extend(8), session.remote())
+	} else {
+		buffer.WriteByte(headerTypeClient)
+		binary.BigEndian.PutUint64(buffer.Extend(8), uint64(time.Now().Unix()))
+	}
+	// No padding.
+	binary.BigEndian.PutUint16(buffer.Extend(2), 0)
+	if err := addrParser.WriteAddressPort(buffer, request.Address, request.Port); err != nil {
+		buffer.Release()
+		return nil, newError("failed to write address").Base(err)
+	}
+	buffer.Write(payload)
+
+	aead := c.createAEAD(account.Key, plainHeader[:8])
+	bodyLen := buffer.Len() - bodyStart
+	buffer.Extend(int32(aead.Overhead()))
+	body := buffer.BytesFrom(bodyStart)
+	aead.Seal(body[:0], plainHeader[4:], body[:bodyLen], nil)
+	return buffer, nil
+}
+
+// openUDPHeader2022 decrypts the separate header of a UDP packet in place.
+func openUDPHeader2022(key []byte, payload *buf.Buffer) error {
+	if payload.Len() < udpHeaderSize {
+		return newError("insufficient data: ", payload.Len())
+	}
+	header := payload.BytesTo(udpHeaderSize)
+	newAESBlock(key).Decrypt(header, header)
+	return nil
+}
+
+// decodeUDPPacket2022 decodes a UDP packet, whose separate header is decrypted and identity headers are removed.
+// Packets are checked for replay if session is not nil.
+func decodeUDPPacket2022(user *protocol.MemoryUser, payload *buf.Buffer, session *udpSession2022) (*protocol.RequestHeader, *buf.Buffer, error) {
+	account := user.Account.(*MemoryAccount)
+	c := account.Cipher.(*AEAD2022Cipher)
+
+	var header [udpHeaderSize]byte
+	copy(header[:], payload.BytesTo(udpHeaderSize))
+	aead := c.createAEAD(account.Key, header[:8])
+	if payload.Len() < udpHeaderSize+int32(aead.Overhead()) {
+		return nil, nil, newError("insufficient data: ", payload.Len())
+	}
+	body, err := aead.Open(payload.BytesFrom(udpHeaderSize)[:0], header[4:], payload.BytesFrom(udpHeaderSize), nil)
+	if err != nil {
+		return nil, nil, newError("failed to decrypt UDP payload").Base(err)
+	}
+	payload.Resize(udpHeaderSize, udpHeaderSize+int32(len(body)))
+
+	if payload.Len() < 1+8 {
+		return nil, nil, newError("insufficient data: ", payload.Len())
+	}
+	headerType := payload.Byte(0)
+	expectedType := headerType
+	if session != nil {
+		// Servers receive packets of clients, and vice versa.
+		expectedType = headerTypeClient
+		if !session.server {
+			expectedType = headerTypeServer
+		}
+	}
+	if headerType != expectedType || headerType > headerTypeServer {
+		return nil, nil, newError("unexpected header type: ", headerType)
+	}
+	if err := checkTimestamp(binary.BigEndian.Uint64(payload.BytesRange(1, 9))); err != nil {
+		return nil, nil, err
+	}
+	payload.Advance(9)
+	if headerType == headerTypeServer {
+		if payload.Len() < 8 {
+			return nil, nil, newError("insufficient data: ", payload.Len())
+		}
+		if session != nil && binary.BigEndian.Uint64(payload.BytesTo(8)) != session.id {
+			return nil, nil, newError("packet is not for the session")
+		}
+		payload.Advance(8)
+	}
+	if payload.Len() < 2 {
+		return nil, nil, newError("insufficient data: ", payload.Len())
+	}
+	paddingLength := int32(binary.BigEndian.Uint16(payload.BytesTo(2)))
+	if payload.Len() < 2+paddingLength {
+		return nil, nil, newError("invalid padding length: ", paddingLength)
+	}
+	payload.Advance(2 + paddingLength)
+
+	addr, port, err := addrParser.ReadAddressPort(nil, payload)
+	if err != nil {
+		return nil, nil, newError("failed to parse address").Base(err)
+	}
+
+	if session != nil && !session.accept(binary.BigEndian.Uint64(header[:8]), binary.BigEndian.Uint64(header[8:])) {
+		return nil, nil, newError("replayed packet")
+	}
+
+	return &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandUDP,
+		Address: addr,
+		Port:    port,
+	}, payload, nil
+}
@@ -0,0 +1,169 @@
+package shadowsocks
+
+import (
+	"bytes"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	testIdentityKey = "AAECAwQFBgcICQoLDA0ODw=="
+	testUserKey     = "EBESExQVFhcYGRobHB0eHw=="
+)
+
+func new2022User(email string, password string) *protocol.MemoryUser {
+	account, err := (&Account{
+		Password:   password,
+		CipherType: CipherType_BLAKE3_AES_128_GCM,
+	}).AsAccount()
+	common.Must(err)
+	return &protocol.MemoryUser{
+		Email:   email,
+		Account: account,
+	}
+}
+
+// new2022Validator returns a validator of the user key, with the identity key if identity is true.
+func new2022Validator(identity bool) *Validator {
+	validator := new(Validator)
+	if identity {
+		common.Must(validator.SetIdentity(new2022User("", testIdentityKey).Account.(*MemoryAccount)))
+		common.Must(validator.Add(new2022User("other@v2fly.org", "ICEiIyQlJicoKSorLC0uLw==")))
+	}
+	common.Must(validator.Add(new2022User("user@v2fly.org", testUserKey)))
+	return validator
+}
+
+func TestAccount2022(t *testing.T) {
+	account := new2022User("", testIdentityKey+":"+testUserKey).Account.(*MemoryAccount)
+	if len(account.IdentityKeys) != 1 || account.Key[0] != 0x10 {
+		t.Error("unexpected keys: ", account.IdentityKeys, " ", account.Key)
+	}
+
+	for _, password := range []string{"", "password", testUserKey + ":", "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="} {
+		if _, err := (&Account{Password: password, CipherType: CipherType_BLAKE3_AES_128_GCM}).AsAccount(); err == nil {
+			t.Error("expected error for password ", password)
+		}
+	}
+}
+
+func TestTCPSession2022(t *testing.T) {
+	for _, identity := range []bool{false, true} {
+		password := testUserKey
+		if identity {
+			password = testIdentityKey + ":" + testUserKey
+		}
+		client := new2022User("", password)
+		validator := new2022Validator(identity)
+
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandTCP,
+			Address: net.DomainAddress("v2fly.org"),
+			Port:    443,
+			User:    client,
+		}
+		var requestData bytes.Buffer
+		writer, err := WriteTCPRequest(request, &requestData)
+		common.Must(err)
+		common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("request"))))
+		replayed := append([]byte(nil), requestData.Bytes()...)
+
+		decodedRequest, reader, err := validator.ReadTCPSession(&buf.BufferedReader{Reader: buf.NewReader(&requestData)})
+		common.Must(err)
+		if decodedRequest.User.Email != "user@v2fly.org" || decodedRequest.Destination() != request.Destination() {
+			t.Error("unexpected request: ", decodedRequest.User.Email, " ", decodedRequest.Destination())
+		}
+		payload, err := buf.ReadAllToBytes(&buf.BufferedReader{Reader: reader})
+		common.Must(err)
+		if string(payload) != "request" {
+			t.Error("unexpected request payload: ", string(payload))
+		}
+
+		if _, _, err := validator.ReadTCPSession(&buf.BufferedReader{Reader: buf.NewReader(bytes.NewReader(replayed))}); err == nil {
+			t.Error("expected error for replayed request")
+		}
+
+		var responseData bytes.Buffer
+		responseWriter, err := writeTCPResponse2022(decodedRequest, reader.(*tcpReader2022).salt, &responseData)
+		common.Must(err)
+		common.Must(responseWriter.WriteMultiBuffer(buf.MergeBytes(nil, []byte("response"))))
+		common.Must(responseWriter.WriteMultiBuffer(buf.MergeBytes(nil, []byte(" more"))))
+
+		responseReader, err := readTCPResponse2022(client, writer.(*tcpWriter2022).salt, &responseData)
+		common.Must(err)
+		payload, err = buf.ReadAllToBytes(&buf.BufferedReader{Reader: responseReader})
+		common.Must(err)
+		if string(payload) != "response more" {
+			t.Error("unexpected response payload: ", string(payload))
+		}
+	}
+}
+
+func TestUDPSession2022(t *testing.T) {
+	for _, identity := range []bool{false, true} {
+		password := testUserKey
+		if identity {
+			password = testIdentityKey + ":" + testUserKey
+		}
+		client := new2022User("", password)
+		validator := new2022Validator(identity)
+		clientSession := newUDPSession2022(false)
+		serverSession := newUDPSession2022(true)
+
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandUDP,
+			Address: net.LocalHostIP,
+			Port:    53,
+			User:    client,
+		}
+		packet, err := encodeUDPPacket2022(request, clientSession, []byte("request"))
+		common.Must(err)
+		replayed := buf.New()
+		replayed.Write(packet.Bytes())
+
+		decodedRequest, payload, err := validator.decodeUDPPacket(packet, serverSession)
+		common.Must(err)
+		if decodedRequest.User.Email != "user@v2fly.org" || decodedRequest.Destination() != request.Destination() {
+			t.Error("unexpected request: ", decodedRequest.User.Email, " ", decodedRequest.Destination())
+		}
+		if payload.String() != "request" {
+			t.Error("unexpected request payload: ", payload.String())
+		}
+		if _, _, err := validator.decodeUDPPacket(replayed, serverSession); err == nil {
+			t.Error("expected error for replayed packet")
+		}
+
+		response, err := encodeUDPPacket2022(decodedRequest, serverSession, []byte("response"))
+		common.Must(err)
+		reader := &UDPReader{
+			Reader:  bytes.NewReader(response.Bytes()),
+			User:    client,
+			session: clientSession,
+		}
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if mb.String() != "response" {
+			t.Error("unexpected response payload: ", mb.String())
+		}
+	}
+}
+
+func TestPacketWindow(t *testing.T) {
+	var window packetWindow
+	for _, c := range []struct {
+		id     uint64
+		accept bool
+	}{
+		{1, true}, {1, false}, {3, true}, {2, true}, {2, false}, {100, true}, {36, false}, {37, true}, {37, false},
+	} {
+		if window.check(c.id) != c.accept {
+			t.Error("packet ", c.id, ": expected ", c.accept)
+		}
+	}
+}
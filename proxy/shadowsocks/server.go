@@ -13,6 +13,7 @@ import (
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	udp_proto "v2ray.com/core/common/protocol/udp"
+	"v2ray.com/core/common/record"
 	"v2ray.com/core/common/session"
 	"v2ray.com/core/common/signal"
 	"v2ray.com/core/common/task"
@@ -24,31 +25,60 @@ import (
 
 type Server struct {
 	config        *ServerConfig
-	user          *protocol.MemoryUser
+	validator     *Validator
 	policyManager policy.Manager
 }
 
 // NewServer create a new Shadowsocks server.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
-	if config.GetUser() == nil {
+	users := config.AllUsers()
+	if len(users) == 0 {
 		return nil, newError("user is not specified")
 	}
 
-	mUser, err := config.User.ToMemoryUser()
-	if err != nil {
-		return nil, newError("failed to parse user account").Base(err)
+	validator := new(Validator)
+	if config.User != nil && len(config.Users) > 0 {
+		identity, err := config.User.ToMemoryUser()
+		if err != nil {
+			return nil, newError("failed to parse user account").Base(err)
+		}
+		if account := identity.Account.(*MemoryAccount); isAEAD2022(account) {
+			if err := validator.SetIdentity(account); err != nil {
+				return nil, err
+			}
+			users = config.Users
+		}
+	}
+	for _, user := range users {
+		mUser, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, newError("failed to parse user account").Base(err)
+		}
+		if err := validator.Add(mUser); err != nil {
+			return nil, newError("failed to add user").Base(err)
+		}
 	}
 
 	v := core.MustFromContext(ctx)
 	s := &Server{
 		config:        config,
-		user:          mUser,
+		validator:     validator,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 	}
 
 	return s, nil
 }
 
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, user *protocol.MemoryUser) error {
+	return s.validator.Add(user)
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, email string) error {
+	return s.validator.Del(email)
+}
+
 func (s *Server) Network() []net.Network {
 	list := s.config.Network
 	if len(list) == 0 {
@@ -72,6 +102,10 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 }
 
 func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	// udpSession is the UDP session of the server with Shadowsocks 2022, which responds to the latest session of
+	// client.
+	udpSession := newUDPSession2022(true)
+
 	udpServer := udp.NewDispatcher(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
 		request := protocol.RequestHeaderFromContext(ctx)
 		if request == nil {
@@ -79,7 +113,13 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 		}
 
 		payload := packet.Payload
-		data, err := EncodeUDPPacket(request, payload.Bytes())
+		var data *buf.Buffer
+		var err error
+		if isAEAD2022(request.User.Account.(*MemoryAccount)) {
+			data, err = encodeUDPPacket2022(request, udpSession, payload.Bytes())
+		} else {
+			data, err = EncodeUDPPacket(request, payload.Bytes())
+		}
 		payload.Release()
 		if err != nil {
 			newError("failed to encode UDP packet").Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
@@ -90,12 +130,10 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 		conn.Write(data.Bytes())
 	})
 
-	account := s.user.Account.(*MemoryAccount)
 	inbound := session.InboundFromContext(ctx)
 	if inbound == nil {
 		panic("no inbound metadata")
 	}
-	inbound.User = s.user
 
 	reader := buf.NewPacketReader(conn)
 	for {
@@ -105,7 +143,7 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 		}
 
 		for _, payload := range mpayload {
-			request, data, err := DecodeUDPPacket(s.user, payload)
+			request, data, err := s.validator.decodeUDPPacket(payload, udpSession)
 			if err != nil {
 				if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
 					newError("dropping invalid UDP packet from: ", inbound.Source).Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -120,17 +158,19 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 				continue
 			}
 
+			account := request.User.Account.(*MemoryAccount)
 			if request.Option.Has(RequestOptionOneTimeAuth) && account.OneTimeAuth == Account_Disabled {
 				newError("client payload enables OTA but server doesn't allow it").WriteToLog(session.ExportIDToError(ctx))
-				payload.Release()
+				data.Release()
 				continue
 			}
 
 			if !request.Option.Has(RequestOptionOneTimeAuth) && account.OneTimeAuth == Account_Enabled {
 				newError("client payload disables OTA but server forces it").WriteToLog(session.ExportIDToError(ctx))
-				payload.Release()
+				data.Release()
 				continue
 			}
+			inbound.User = request.User
 
 			currentPacketCtx := ctx
 			dest := request.Destination()
@@ -154,11 +194,15 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 }
 
 func (s *Server) handleConnection(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
-	sessionPolicy := s.policyManager.ForLevel(s.user.Level)
+	sessionPolicy := s.policyManager.ForLevel(0)
+	if users := s.validator.Users(); len(users) == 1 {
+		sessionPolicy = s.policyManager.ForLevel(users[0].Level)
+	}
 	conn.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake))
 
+	recorded := record.SessionFromContext(ctx)
 	bufferedReader := buf.BufferedReader{Reader: buf.NewReader(conn)}
-	request, bodyReader, err := ReadTCPSession(s.user, &bufferedReader)
+	request, bodyReader, err := s.validator.ReadTCPSession(&bufferedReader)
 	if err != nil {
 		log.Record(&log.AccessMessage{
 			From:   conn.RemoteAddr(),
@@ -169,12 +213,13 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 		return newError("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
 	conn.SetReadDeadline(time.Time{})
+	sessionPolicy = s.policyManager.ForLevel(request.User.Level)
 
 	inbound := session.InboundFromContext(ctx)
 	if inbound == nil {
 		panic("no inbound metadata")
 	}
-	inbound.User = s.user
+	inbound.User = request.User
 
 	dest := request.Destination()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
@@ -199,10 +244,17 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
 		bufferedWriter := buf.NewBufferedWriter(buf.NewWriter(conn))
-		responseWriter, err := WriteTCPResponse(request, bufferedWriter)
+		var responseWriter buf.Writer
+		var err error
+		if reader, ok := bodyReader.(*tcpReader2022); ok {
+			responseWriter, err = writeTCPResponse2022(request, reader.salt, bufferedWriter)
+		} else {
+			responseWriter, err = WriteTCPResponse(request, bufferedWriter)
+		}
 		if err != nil {
 			return newError("failed to write response").Base(err)
 		}
+		responseWriter = recorded.Writer(responseWriter)
 
 		{
 			payload, err := link.Reader.ReadMultiBuffer()
@@ -228,7 +280,7 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 	requestDone := func() error {
 		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
 
-		if err := buf.Copy(bodyReader, link.Writer, buf.UpdateActivity(timer)); err != nil {
+		if err := buf.Copy(recorded.Reader(bodyReader), link.Writer, buf.UpdateActivity(timer)); err != nil {
 			return newError("failed to transport all TCP request").Base(err)
 		}
 
@@ -247,6 +299,15 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 
 func init() {
 	common.Must(common.RegisterConfig((*ServerConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
-		return NewServer(ctx, config.(*ServerConfig))
+		serverConfig := config.(*ServerConfig)
+		server, err := NewServer(ctx, serverConfig)
+		if err != nil || serverConfig.Plugin == "" {
+			return server, err
+		}
+		return &pluginServer{
+			Server:  server,
+			plugin:  serverConfig.Plugin,
+			options: serverConfig.PluginOpts,
+		}, nil
 	}))
 }
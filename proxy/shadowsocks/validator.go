@@ -0,0 +1,253 @@
+// +build !confonly
+
+package shadowsocks
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/protocol"
+)
+
+// maxIVSize is the largest IV size of AEAD ciphers.
+const maxIVSize = 32
+
+// Validator holds the users of a Shadowsocks server. With more than one user, the user of a request is found by the
+// key that decrypts it, which is only possible with AEAD ciphers. With Shadowsocks 2022 ciphers, the user is found by
+// the identity header of the request, which is decrypted with the identity key of the server.
+type Validator struct {
+	sync.RWMutex
+	// users is replaced instead of modified, so that a snapshot of it can be used without lock.
+	users []*protocol.MemoryUser
+	// identity is the identity key of the server, and keys maps the key hashes in identity headers to users.
+	identity *MemoryAccount
+	keys     map[[16]byte]*protocol.MemoryUser
+}
+
+func identifiable(u *protocol.MemoryUser) bool {
+	_, ok := u.Account.(*MemoryAccount).Cipher.(*AEADCipher)
+	return ok
+}
+
+// SetIdentity sets the identity key of the server, which must be of a Shadowsocks 2022 cipher. It must be set before
+// any user is added.
+func (v *Validator) SetIdentity(account *MemoryAccount) error {
+	v.Lock()
+	defer v.Unlock()
+
+	if _, ok := account.Cipher.(*AEAD2022Cipher); !ok {
+		return newError("identity key requires Shadowsocks 2022 ciphers")
+	}
+	if len(v.users) > 0 {
+		return newError("identity key must be set before users")
+	}
+	v.identity = account
+	return nil
+}
+
+// Add adds a user to the validator.
+func (v *Validator) Add(u *protocol.MemoryUser) error {
+	v.Lock()
+	defer v.Unlock()
+
+	if u.Email != "" {
+		for _, user := range v.users {
+			if strings.EqualFold(user.Email, u.Email) {
+				return newError("User ", u.Email, " already exists.")
+			}
+		}
+	}
+	if v.identity != nil {
+		if cipher, ok := u.Account.(*MemoryAccount).Cipher.(*AEAD2022Cipher); !ok || cipher.KeySize() != v.identity.Cipher.KeySize() {
+			return newError("users must use the same Shadowsocks 2022 cipher as the identity key")
+		}
+	} else if len(v.users) > 0 && (!identifiable(u) || !identifiable(v.users[0])) {
+		return newError("multiple users are only supported with AEAD ciphers, or Shadowsocks 2022 ciphers with identity key")
+	}
+	users := make([]*protocol.MemoryUser, len(v.users), len(v.users)+1)
+	copy(users, v.users)
+	v.users = append(users, u)
+	v.updateKeys()
+	return nil
+}
+
+func (v *Validator) updateKeys() {
+	if v.identity == nil {
+		return
+	}
+	keys := make(map[[16]byte]*protocol.MemoryUser, len(v.users))
+	for _, user := range v.users {
+		keys[keyHash(user.Account.(*MemoryAccount).Key)] = user
+	}
+	v.keys = keys
+}
+
+// Del removes the user with the email from the validator.
+func (v *Validator) Del(email string) error {
+	if email == "" {
+		return newError("Email must not be empty.")
+	}
+	v.Lock()
+	defer v.Unlock()
+
+	for i, user := range v.users {
+		if strings.EqualFold(user.Email, email) {
+			users := make([]*protocol.MemoryUser, 0, len(v.users)-1)
+			users = append(users, v.users[:i]...)
+			v.users = append(users, v.users[i+1:]...)
+			v.updateKeys()
+			return nil
+		}
+	}
+	return newError("User ", email, " not found.")
+}
+
+// Users returns the users in the validator.
+func (v *Validator) Users() []*protocol.MemoryUser {
+	v.RLock()
+	defer v.RUnlock()
+	return v.users
+}
+
+// get returns the user whose key decrypts the length of the first chunk of a TCP session. b holds the beginning of
+// the session.
+func get(users []*protocol.MemoryUser, b []byte) *protocol.MemoryUser {
+	for _, user := range users {
+		account := user.Account.(*MemoryAccount)
+		cipher := account.Cipher.(*AEADCipher)
+		ivLen := cipher.IVSize()
+		auth := cipher.createAuthenticator(account.Key, b[:ivLen])
+		end := int(ivLen) + 2 + auth.Overhead()
+		if len(b) < end {
+			continue
+		}
+		if _, err := auth.Open(nil, b[ivLen:end]); err == nil {
+			return user
+		}
+	}
+	return nil
+}
+
+// identified returns the identity key and the users by key hashes, if the identity key is set.
+func (v *Validator) identified() (*MemoryAccount, map[[16]byte]*protocol.MemoryUser) {
+	v.RLock()
+	defer v.RUnlock()
+	return v.identity, v.keys
+}
+
+// ReadTCPSession reads a Shadowsocks TCP session of any user in the validator.
+func (v *Validator) ReadTCPSession(reader *buf.BufferedReader) (*protocol.RequestHeader, buf.Reader, error) {
+	if identity, keys := v.identified(); identity != nil {
+		return readIdentifiedTCPSession(identity, keys, reader)
+	}
+
+	users := v.Users()
+	switch len(users) {
+	case 0:
+		return nil, nil, newError("no user")
+	case 1:
+		return ReadTCPSession(users[0], reader)
+	}
+
+	// A valid session of any AEAD cipher is longer than this.
+	header := buf.New()
+	if _, err := header.ReadFullFrom(reader, maxIVSize+2+16); err != nil {
+		header.Release()
+		return nil, nil, newError("failed to read request header").Base(err)
+	}
+	user := get(users, header.Bytes())
+	if user == nil {
+		// The session fails to decrypt as with a wrong key of a single user.
+		user = users[0]
+	}
+	reader.Buffer = append(buf.MultiBuffer{header}, reader.Buffer...)
+	return ReadTCPSession(user, reader)
+}
+
+// readIdentifiedTCPSession reads a TCP session of Shadowsocks 2022, whose user is found by the identity header.
+func readIdentifiedTCPSession(identity *MemoryAccount, keys map[[16]byte]*protocol.MemoryUser, reader io.Reader) (*protocol.RequestHeader, buf.Reader, error) {
+	saltLen := identity.Cipher.IVSize()
+	header := make([]byte, saltLen+identityHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, nil, newError("failed to read request header").Base(err)
+	}
+	salt, identityHeader := header[:saltLen], header[saltLen:]
+	identitySubkey(identity.Key, salt).Decrypt(identityHeader, identityHeader)
+
+	var hash [16]byte
+	copy(hash[:], identityHeader)
+	user, found := keys[hash]
+	if !found {
+		return nil, nil, newError("unknown user in identity header")
+	}
+	return readTCPSession2022(user, salt, reader)
+}
+
+// DecodeUDPPacket decodes a Shadowsocks UDP packet of any user in the validator.
+func (v *Validator) DecodeUDPPacket(payload *buf.Buffer) (*protocol.RequestHeader, *buf.Buffer, error) {
+	return v.decodeUDPPacket(payload, nil)
+}
+
+// decodeUDPPacket decodes a UDP packet of any user. Packets of Shadowsocks 2022 are checked against the session, if
+// it is not nil.
+func (v *Validator) decodeUDPPacket(payload *buf.Buffer, session *udpSession2022) (*protocol.RequestHeader, *buf.Buffer, error) {
+	if identity, keys := v.identified(); identity != nil {
+		return decodeIdentifiedUDPPacket(identity, keys, payload, session)
+	}
+
+	users := v.Users()
+	switch len(users) {
+	case 0:
+		return nil, nil, newError("no user")
+	case 1:
+		account := users[0].Account.(*MemoryAccount)
+		if _, ok := account.Cipher.(*AEAD2022Cipher); ok && session != nil {
+			if err := openUDPHeader2022(account.Key, payload); err != nil {
+				return nil, nil, err
+			}
+			return decodeUDPPacket2022(users[0], payload, session)
+		}
+		return DecodeUDPPacket(users[0], payload)
+	}
+
+	for _, user := range users {
+		// Decoding is in place, so each user works on a copy.
+		b := buf.New()
+		b.Write(payload.Bytes())
+		request, data, err := DecodeUDPPacket(user, b)
+		if err == nil {
+			payload.Release()
+			return request, data, nil
+		}
+		b.Release()
+	}
+	return nil, nil, newError("no user decrypts the packet")
+}
+
+// decodeIdentifiedUDPPacket decodes a UDP packet of Shadowsocks 2022, whose user is found by the identity header.
+func decodeIdentifiedUDPPacket(identity *MemoryAccount, keys map[[16]byte]*protocol.MemoryUser, payload *buf.Buffer, session *udpSession2022) (*protocol.RequestHeader, *buf.Buffer, error) {
+	if payload.Len() < udpHeaderSize+identityHeaderSize {
+		return nil, nil, newError("insufficient data: ", payload.Len())
+	}
+	block := newAESBlock(identity.Key)
+	header := payload.BytesTo(udpHeaderSize)
+	block.Decrypt(header, header)
+	identityHeader := payload.BytesRange(udpHeaderSize, udpHeaderSize+identityHeaderSize)
+	block.Decrypt(identityHeader, identityHeader)
+
+	var hash [16]byte
+	for i := range hash {
+		hash[i] = identityHeader[i] ^ header[i]
+	}
+	user, found := keys[hash]
+	if !found {
+		return nil, nil, newError("unknown user in identity header")
+	}
+
+	// Move the separate header over the identity header.
+	copy(identityHeader, header)
+	payload.Advance(identityHeaderSize)
+	return decodeUDPPacket2022(user, payload, session)
+}
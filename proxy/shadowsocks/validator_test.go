@@ -0,0 +1,141 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+)
+
+func newUser(email string, password string, cipher CipherType) *protocol.MemoryUser {
+	return &protocol.MemoryUser{
+		Email: email,
+		Account: toAccount(&Account{
+			Password:   password,
+			CipherType: cipher,
+		}),
+	}
+}
+
+func TestValidatorUsers(t *testing.T) {
+	validator := new(Validator)
+	common.Must(validator.Add(newUser("a@v2fly.org", "a", CipherType_AES_128_GCM)))
+	common.Must(validator.Add(newUser("b@v2fly.org", "b", CipherType_CHACHA20_POLY1305)))
+
+	if err := validator.Add(newUser("A@v2fly.org", "c", CipherType_AES_256_GCM)); err == nil {
+		t.Error("expected error for duplicated email")
+	}
+	if err := validator.Add(newUser("c@v2fly.org", "c", CipherType_AES_256_CFB)); err == nil {
+		t.Error("expected error for multiple users with stream cipher")
+	}
+
+	users := validator.Users()
+	common.Must(validator.Del("a@v2fly.org"))
+	if err := validator.Del("a@v2fly.org"); err == nil {
+		t.Error("expected error for removed user")
+	}
+	if len(users) != 2 || len(validator.Users()) != 1 {
+		t.Error("unexpected users: ", len(users), " ", len(validator.Users()))
+	}
+
+	stream := new(Validator)
+	common.Must(stream.Add(newUser("c@v2fly.org", "c", CipherType_AES_256_CFB)))
+	if err := stream.Add(newUser("d@v2fly.org", "d", CipherType_AES_128_GCM)); err == nil {
+		t.Error("expected error for multiple users with stream cipher")
+	}
+}
+
+func TestValidatorTCPSession(t *testing.T) {
+	users := []*protocol.MemoryUser{
+		newUser("a@v2fly.org", "a", CipherType_AES_128_GCM),
+		newUser("b@v2fly.org", "b", CipherType_AES_256_GCM),
+		newUser("c@v2fly.org", "c", CipherType_CHACHA20_POLY1305),
+	}
+	validator := new(Validator)
+	for _, user := range users {
+		common.Must(validator.Add(user))
+	}
+
+	for _, user := range users {
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandTCP,
+			Address: net.DomainAddress("v2fly.org"),
+			Port:    443,
+			User:    user,
+		}
+		cache := buf.New()
+		writer, err := WriteTCPRequest(request, cache)
+		common.Must(err)
+		common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("payload"))))
+
+		decodedRequest, reader, err := validator.ReadTCPSession(&buf.BufferedReader{Reader: buf.NewReader(cache)})
+		common.Must(err)
+		if decodedRequest.User != user {
+			t.Error("unexpected user: ", decodedRequest.User.Email, ", expected ", user.Email)
+		}
+		if r := cmp.Diff(decodedRequest.Destination(), request.Destination()); r != "" {
+			t.Error(r)
+		}
+		payload, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if r := cmp.Diff(payload.String(), "payload"); r != "" {
+			t.Error(r)
+		}
+		cache.Release()
+	}
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: net.DomainAddress("v2fly.org"),
+		Port:    443,
+		User:    newUser("d@v2fly.org", "d", CipherType_AES_128_GCM),
+	}
+	cache := buf.New()
+	defer cache.Release()
+	writer, err := WriteTCPRequest(request, cache)
+	common.Must(err)
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("payload"))))
+	if _, _, err := validator.ReadTCPSession(&buf.BufferedReader{Reader: buf.NewReader(cache)}); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
+
+func TestValidatorUDPPacket(t *testing.T) {
+	users := []*protocol.MemoryUser{
+		newUser("a@v2fly.org", "a", CipherType_AES_128_GCM),
+		newUser("b@v2fly.org", "b", CipherType_CHACHA20_POLY1305),
+	}
+	validator := new(Validator)
+	for _, user := range users {
+		common.Must(validator.Add(user))
+	}
+
+	for _, user := range users {
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandUDP,
+			Address: net.LocalHostIP,
+			Port:    53,
+			User:    user,
+		}
+		packet, err := EncodeUDPPacket(request, []byte("payload"))
+		common.Must(err)
+
+		decodedRequest, data, err := validator.DecodeUDPPacket(packet)
+		common.Must(err)
+		if decodedRequest.User != user {
+			t.Error("unexpected user: ", decodedRequest.User.Email, ", expected ", user.Email)
+		}
+		if r := cmp.Diff(data.String(), "payload"); r != "" {
+			t.Error(r)
+		}
+		data.Release()
+	}
+}
@@ -42,7 +42,12 @@ var addrParser = protocol.NewAddressParser(
 
 type ServerSession struct {
 	config *ServerConfig
-	port   net.Port
+	// address and port of the UDP relay, where address is used unless the config specifies one.
+	address net.Address
+	port    net.Port
+	// associate is called on UDP ASSOCIATE before the response, so that the UDP relay accepts packets from the client
+	// as soon as the client learns about it.
+	associate func()
 }
 
 func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
@@ -191,11 +196,17 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 	responsePort := net.Port(1717)
 	if request.Command == protocol.RequestCommandUDP {
 		addr := s.config.Address.AsAddress()
+		if addr == nil {
+			addr = s.address
+		}
 		if addr == nil {
 			addr = net.LocalHostIP
 		}
 		responseAddress = addr
 		responsePort = s.port
+		if s.associate != nil {
+			s.associate()
+		}
 	}
 	if err := writeSocks5Response(writer, statusSuccess, responseAddress, responsePort); err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ package socks
 import (
 	"context"
 	"io"
+	"sync"
 	"time"
 
 	"v2ray.com/core"
@@ -16,6 +17,7 @@ import (
 	udp_proto "v2ray.com/core/common/protocol/udp"
 	"v2ray.com/core/common/session"
 	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/signal/done"
 	"v2ray.com/core/common/task"
 	"v2ray.com/core/features"
 	"v2ray.com/core/features/policy"
@@ -28,6 +30,66 @@ import (
 type Server struct {
 	config        *ServerConfig
 	policyManager policy.Manager
+	associations  udpAssociations
+}
+
+// udpAssociation is a UDP ASSOCIATE request of a client, which lasts as long as the TCP connection of the request.
+type udpAssociation struct {
+	client net.Address
+	done   *done.Instance
+}
+
+// udpAssociations keeps active UDP associations by the address of their clients. The UDP relay only accepts packets
+// from clients with an association.
+type udpAssociations struct {
+	access   sync.Mutex
+	byClient map[net.Address][]*udpAssociation
+}
+
+func (a *udpAssociations) add(client net.Address) *udpAssociation {
+	a.access.Lock()
+	defer a.access.Unlock()
+
+	association := &udpAssociation{
+		client: client,
+		done:   done.New(),
+	}
+	if a.byClient == nil {
+		a.byClient = make(map[net.Address][]*udpAssociation)
+	}
+	a.byClient[client] = append(a.byClient[client], association)
+	return association
+}
+
+func (a *udpAssociations) remove(association *udpAssociation) {
+	a.access.Lock()
+	defer a.access.Unlock()
+
+	associations := a.byClient[association.client]
+	for i, x := range associations {
+		if x == association {
+			associations = append(associations[:i], associations[i+1:]...)
+			break
+		}
+	}
+	if len(associations) == 0 {
+		delete(a.byClient, association.client)
+	} else {
+		a.byClient[association.client] = associations
+	}
+	association.done.Close() // nolint: errcheck
+}
+
+// get returns the latest association of the client, or nil if there is none.
+func (a *udpAssociations) get(client net.Address) *udpAssociation {
+	a.access.Lock()
+	defer a.access.Unlock()
+
+	associations := a.byClient[client]
+	if len(associations) == 0 {
+		return nil
+	}
+	return associations[len(associations)-1]
 }
 
 // NewServer creates a new Server object.
@@ -90,13 +152,24 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 		return newError("inbound gateway not specified")
 	}
 
+	var association *udpAssociation
 	svrSession := &ServerSession{
 		config: s.config,
 		port:   inbound.Gateway.Port,
+		associate: func() {
+			association = s.associations.add(inbound.Source.Address)
+		},
+	}
+	// The UDP relay is reachable at the address that the client connects to.
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok && !addr.IP.IsUnspecified() {
+		svrSession.address = net.IPAddress(addr.IP)
 	}
 
 	reader := &buf.BufferedReader{Reader: buf.NewReader(conn)}
 	request, err := svrSession.Handshake(reader, conn)
+	if association != nil {
+		defer s.associations.remove(association)
+	}
 	if err != nil {
 		if inbound != nil && inbound.Source.IsValid() {
 			log.Record(&log.AccessMessage{
@@ -139,7 +212,7 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 }
 
 func (*Server) handleUDP(c io.Reader) error {
-	// The TCP connection closes after this method returns. We need to wait until
+	// The TCP connection closes after this method returns, which ends the UDP association. We need to wait until
 	// the client closes it.
 	return common.Error2(io.Copy(buf.DiscardBytes, c))
 }
@@ -186,6 +259,26 @@ func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writ
 }
 
 func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || !inbound.Source.IsValid() {
+		return newError("client address not specified")
+	}
+	association := s.associations.get(inbound.Source.Address)
+	if association == nil {
+		return newError("no UDP association for ", inbound.Source)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-association.done.Wait():
+			// The client closed the TCP connection of the association, so the UDP session ends as well.
+			conn.Close() // nolint: errcheck
+		case <-ctx.Done():
+		}
+	}()
+
 	udpServer := udp.NewDispatcher(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
 		payload := packet.Payload
 		newError("writing back UDP response with ", payload.Len(), " bytes").AtDebug().WriteToLog(session.ExportIDToError(ctx))
@@ -205,9 +298,7 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 		conn.Write(udpMessage.Bytes()) // nolint: errcheck
 	})
 
-	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
-		newError("client UDP connection from ", inbound.Source).WriteToLog(session.ExportIDToError(ctx))
-	}
+	newError("client UDP connection from ", inbound.Source).WriteToLog(session.ExportIDToError(ctx))
 
 	reader := buf.NewPacketReader(conn)
 	for {
@@ -231,14 +322,12 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 			}
 			currentPacketCtx := ctx
 			newError("send packet to ", request.Destination(), " with ", payload.Len(), " bytes").AtDebug().WriteToLog(session.ExportIDToError(ctx))
-			if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
-				currentPacketCtx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
-					From:   inbound.Source,
-					To:     request.Destination(),
-					Status: log.AccessAccepted,
-					Reason: "",
-				})
-			}
+			currentPacketCtx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+				From:   inbound.Source,
+				To:     request.Destination(),
+				Status: log.AccessAccepted,
+				Reason: "",
+			})
 
 			currentPacketCtx = protocol.ContextWithRequestHeader(currentPacketCtx, request)
 			udpServer.Dispatch(currentPacketCtx, request.Destination(), payload)
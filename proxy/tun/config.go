@@ -0,0 +1,10 @@
+package tun
+
+const defaultMTU = 1500
+
+func (c *Config) getMTU() int {
+	if c.Mtu == 0 {
+		return defaultMTU
+	}
+	return int(c.Mtu)
+}
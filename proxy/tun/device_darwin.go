@@ -0,0 +1,171 @@
+// +build darwin
+
+package tun
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	utunControlName = "com.apple.net.utun_control"
+	utunOptIfName   = 2
+	sysProtoControl = 2
+	afSysControl    = 2
+	ctlIOCGInfo     = 0xc0644e03
+)
+
+// ctlInfo is struct ctl_info in <sys/kern_control.h>.
+type ctlInfo struct {
+	id   uint32
+	name [96]byte
+}
+
+// sockaddrCtl is struct sockaddr_ctl in <sys/kern_control.h>.
+type sockaddrCtl struct {
+	len      uint8
+	family   uint8
+	sysaddr  uint16
+	id       uint32
+	unit     uint32
+	reserved [5]uint32
+}
+
+// ifreqMTU is struct ifreq in <net/if.h>, with ifr_mtu in the union.
+type ifreqMTU struct {
+	name [unix.IFNAMSIZ]byte
+	mtu  int32
+	_    [12]byte
+}
+
+// utunDevice is a utun device. Each packet on it is prefixed with its address family in 4 bytes.
+type utunDevice struct {
+	*os.File
+
+	readAccess  sync.Mutex
+	readBuffer  []byte
+	writeAccess sync.Mutex
+	writeBuffer []byte
+}
+
+func (d *utunDevice) Read(b []byte) (int, error) {
+	d.readAccess.Lock()
+	defer d.readAccess.Unlock()
+
+	if len(d.readBuffer) < len(b)+4 {
+		d.readBuffer = make([]byte, len(b)+4)
+	}
+	n, err := d.File.Read(d.readBuffer[:len(b)+4])
+	if err != nil {
+		return 0, err
+	}
+	if n < 4 {
+		return 0, nil
+	}
+	return copy(b, d.readBuffer[4:n]), nil
+}
+
+func (d *utunDevice) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	d.writeAccess.Lock()
+	defer d.writeAccess.Unlock()
+
+	if len(d.writeBuffer) < len(b)+4 {
+		d.writeBuffer = make([]byte, len(b)+4)
+	}
+	family := byte(unix.AF_INET)
+	if b[0]>>4 == 6 {
+		family = unix.AF_INET6
+	}
+	d.writeBuffer[0], d.writeBuffer[1], d.writeBuffer[2], d.writeBuffer[3] = 0, 0, 0, family
+	copy(d.writeBuffer[4:], b)
+	if _, err := d.File.Write(d.writeBuffer[:len(b)+4]); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func ioctl(fd int, request uintptr, req unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(req)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func openDevice(config *Config) (Device, string, error) {
+	unit := uint32(0)
+	if len(config.Name) > 0 {
+		n, err := strconv.Atoi(strings.TrimPrefix(config.Name, "utun"))
+		if err != nil || !strings.HasPrefix(config.Name, "utun") {
+			return nil, "", newError("invalid device name ", config.Name, ", must be utunN")
+		}
+		unit = uint32(n) + 1
+	}
+
+	fd, err := unix.Socket(unix.AF_SYSTEM, unix.SOCK_DGRAM, sysProtoControl)
+	if err != nil {
+		return nil, "", newError("failed to create control socket").Base(err)
+	}
+	unix.CloseOnExec(fd)
+
+	info := &ctlInfo{}
+	copy(info.name[:], utunControlName)
+	if err := ioctl(fd, ctlIOCGInfo, unsafe.Pointer(info)); err != nil {
+		unix.Close(fd)
+		return nil, "", newError("failed to find utun control").Base(err)
+	}
+
+	addr := &sockaddrCtl{
+		len:     uint8(unsafe.Sizeof(sockaddrCtl{})),
+		family:  unix.AF_SYSTEM,
+		sysaddr: afSysControl,
+		id:      info.id,
+		unit:    unit,
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_CONNECT, uintptr(fd), uintptr(unsafe.Pointer(addr)), uintptr(addr.len)); errno != 0 {
+		unix.Close(fd)
+		return nil, "", newError("failed to create utun device").Base(errno)
+	}
+
+	name, err := unix.GetsockoptString(fd, sysProtoControl, utunOptIfName)
+	if err != nil {
+		unix.Close(fd)
+		return nil, "", newError("failed to get utun device name").Base(err)
+	}
+	name = strings.TrimRight(name, "\x00")
+
+	if err := setMTU(name, config.getMTU()); err != nil {
+		unix.Close(fd)
+		return nil, "", newError("failed to set MTU of ", name).Base(err)
+	}
+	if len(config.Address) > 0 {
+		newError("addresses of ", name, " have to be configured by ifconfig").AtWarning().WriteToLog()
+	}
+
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, "", err
+	}
+	return &utunDevice{File: os.NewFile(uintptr(fd), name)}, name, nil
+}
+
+func setMTU(name string, mtu int) error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	req := &ifreqMTU{mtu: int32(mtu)}
+	copy(req.name[:unix.IFNAMSIZ-1], name)
+	return ioctl(fd, unix.SIOCSIFMTU, unsafe.Pointer(req))
+}
@@ -0,0 +1,163 @@
+// +build linux
+
+package tun
+
+import (
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"v2ray.com/core/common/net"
+)
+
+// ifreq is struct ifreq in <linux/if.h>, with the union as raw bytes.
+type ifreq struct {
+	name [unix.IFNAMSIZ]byte
+	data [24]byte
+}
+
+func newIfreq(name string) *ifreq {
+	req := new(ifreq)
+	copy(req.name[:unix.IFNAMSIZ-1], name)
+	return req
+}
+
+// The union is in native byte order.
+func (r *ifreq) uint16() uint16 {
+	return *(*uint16)(unsafe.Pointer(&r.data[0]))
+}
+
+func (r *ifreq) setUint16(v uint16) {
+	*(*uint16)(unsafe.Pointer(&r.data[0])) = v
+}
+
+func (r *ifreq) uint32() uint32 {
+	return *(*uint32)(unsafe.Pointer(&r.data[0]))
+}
+
+func (r *ifreq) setUint32(v uint32) {
+	*(*uint32)(unsafe.Pointer(&r.data[0])) = v
+}
+
+// setSockaddrInet4 sets the union to a struct sockaddr_in.
+func (r *ifreq) setSockaddrInet4(ip net.IP) {
+	r.setUint16(unix.AF_INET)
+	copy(r.data[4:8], ip)
+}
+
+func ioctl(fd int, request uintptr, req unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), request, uintptr(req)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func openDevice(config *Config) (Device, string, error) {
+	fd, err := unix.Open("/dev/net/tun", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, "", newError("failed to open /dev/net/tun").Base(err)
+	}
+
+	req := newIfreq(config.Name)
+	req.setUint16(unix.IFF_TUN | unix.IFF_NO_PI)
+	if err := ioctl(fd, unix.TUNSETIFF, unsafe.Pointer(req)); err != nil {
+		unix.Close(fd)
+		return nil, "", newError("failed to create TUN device ", config.Name).Base(err)
+	}
+	name := strings.TrimRight(string(req.name[:]), "\x00")
+
+	// Non-blocking mode lets the runtime poller interrupt pending reads on Close.
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, "", err
+	}
+	file := os.NewFile(uintptr(fd), "/dev/net/tun")
+
+	if err := configureDevice(name, config); err != nil {
+		file.Close()
+		return nil, "", newError("failed to configure TUN device ", name).Base(err)
+	}
+	return file, name, nil
+}
+
+// configureDevice sets MTU and addresses of the device, and brings it up.
+func configureDevice(name string, config *Config) error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	req := newIfreq(name)
+	req.setUint32(uint32(config.getMTU()))
+	if err := ioctl(fd, unix.SIOCSIFMTU, unsafe.Pointer(req)); err != nil {
+		return newError("failed to set MTU").Base(err)
+	}
+
+	for _, address := range config.Address {
+		ip, ipNet, err := net.ParseCIDR(address)
+		if err != nil {
+			return newError("invalid address ", address).Base(err)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			err = setIPv4Address(fd, name, ip4, ipNet.Mask)
+		} else {
+			err = setIPv6Address(name, ip, ipNet.Mask)
+		}
+		if err != nil {
+			return newError("failed to set address ", address).Base(err)
+		}
+	}
+
+	req = newIfreq(name)
+	if err := ioctl(fd, unix.SIOCGIFFLAGS, unsafe.Pointer(req)); err != nil {
+		return err
+	}
+	req.setUint16(req.uint16() | unix.IFF_UP | unix.IFF_RUNNING)
+	if err := ioctl(fd, unix.SIOCSIFFLAGS, unsafe.Pointer(req)); err != nil {
+		return newError("failed to bring up device").Base(err)
+	}
+	return nil
+}
+
+func setIPv4Address(fd int, name string, ip net.IP, mask net.IPMask) error {
+	req := newIfreq(name)
+	req.setSockaddrInet4(ip)
+	if err := ioctl(fd, unix.SIOCSIFADDR, unsafe.Pointer(req)); err != nil {
+		return err
+	}
+
+	req = newIfreq(name)
+	req.setSockaddrInet4(net.IP(mask))
+	return ioctl(fd, unix.SIOCSIFNETMASK, unsafe.Pointer(req))
+}
+
+// in6Ifreq is struct in6_ifreq in <linux/ipv6.h>.
+type in6Ifreq struct {
+	addr      [16]byte
+	prefixLen uint32
+	ifIndex   int32
+}
+
+func setIPv6Address(name string, ip net.IP, mask net.IPMask) error {
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	req := newIfreq(name)
+	if err := ioctl(fd, unix.SIOCGIFINDEX, unsafe.Pointer(req)); err != nil {
+		return err
+	}
+
+	prefixLen, _ := mask.Size()
+	req6 := &in6Ifreq{
+		prefixLen: uint32(prefixLen),
+		ifIndex:   int32(req.uint32()),
+	}
+	copy(req6.addr[:], ip.To16())
+	return ioctl(fd, unix.SIOCSIFADDR, unsafe.Pointer(req6))
+}
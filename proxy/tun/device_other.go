@@ -0,0 +1,7 @@
+// +build !linux,!darwin,!windows
+
+package tun
+
+func openDevice(config *Config) (Device, string, error) {
+	return nil, "", newError("TUN device is not supported on this platform")
+}
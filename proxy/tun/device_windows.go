@@ -0,0 +1,154 @@
+// +build windows
+
+package tun
+
+import (
+	"reflect"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Wintun (https://www.wintun.net/) provides TUN devices on Windows. wintun.dll has to be placed
+// along with the executable.
+var (
+	wintun                     = windows.NewLazyDLL("wintun.dll")
+	wintunCreateAdapter        = wintun.NewProc("WintunCreateAdapter")
+	wintunCloseAdapter         = wintun.NewProc("WintunCloseAdapter")
+	wintunStartSession         = wintun.NewProc("WintunStartSession")
+	wintunEndSession           = wintun.NewProc("WintunEndSession")
+	wintunGetReadWaitEvent     = wintun.NewProc("WintunGetReadWaitEvent")
+	wintunReceivePacket        = wintun.NewProc("WintunReceivePacket")
+	wintunReleaseReceivePacket = wintun.NewProc("WintunReleaseReceivePacket")
+	wintunAllocateSendPacket   = wintun.NewProc("WintunAllocateSendPacket")
+	wintunSendPacket           = wintun.NewProc("WintunSendPacket")
+)
+
+const wintunRingCapacity = 0x800000
+
+type wintunDevice struct {
+	adapter   uintptr
+	session   uintptr
+	readEvent windows.Handle
+	closed    windows.Handle
+
+	readAccess  sync.Mutex
+	writeAccess sync.Mutex
+	closeOnce   sync.Once
+}
+
+// bytesAt returns the memory of n bytes at p, which is allocated by wintun.
+func bytesAt(p uintptr, n int) []byte {
+	var b []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data, h.Len, h.Cap = p, n, n
+	return b
+}
+
+func (d *wintunDevice) Read(b []byte) (int, error) {
+	d.readAccess.Lock()
+	defer d.readAccess.Unlock()
+
+	for {
+		var size uint32
+		packet, _, err := wintunReceivePacket.Call(d.session, uintptr(unsafe.Pointer(&size)))
+		if packet != 0 {
+			n := copy(b, bytesAt(packet, int(size)))
+			wintunReleaseReceivePacket.Call(d.session, packet) // nolint: errcheck
+			return n, nil
+		}
+		if err != windows.ERROR_NO_MORE_ITEMS {
+			return 0, newError("failed to receive packet").Base(err)
+		}
+
+		event, err := windows.WaitForMultipleObjects([]windows.Handle{d.readEvent, d.closed}, false, windows.INFINITE)
+		if err != nil {
+			return 0, err
+		}
+		if event == windows.WAIT_OBJECT_0+1 {
+			return 0, newError("device closed")
+		}
+	}
+}
+
+func (d *wintunDevice) Write(b []byte) (int, error) {
+	d.writeAccess.Lock()
+	defer d.writeAccess.Unlock()
+
+	if event, _ := windows.WaitForSingleObject(d.closed, 0); event == windows.WAIT_OBJECT_0 {
+		return 0, newError("device closed")
+	}
+	packet, _, err := wintunAllocateSendPacket.Call(d.session, uintptr(len(b)))
+	if packet == 0 {
+		return 0, newError("failed to allocate packet").Base(err)
+	}
+	copy(bytesAt(packet, len(b)), b)
+	wintunSendPacket.Call(d.session, packet) // nolint: errcheck
+	return len(b), nil
+}
+
+func (d *wintunDevice) Close() error {
+	d.closeOnce.Do(func() {
+		windows.SetEvent(d.closed) // nolint: errcheck
+
+		d.readAccess.Lock()
+		d.writeAccess.Lock()
+		wintunEndSession.Call(d.session)   // nolint: errcheck
+		wintunCloseAdapter.Call(d.adapter) // nolint: errcheck
+		windows.CloseHandle(d.closed)      // nolint: errcheck
+		d.writeAccess.Unlock()
+		d.readAccess.Unlock()
+	})
+	return nil
+}
+
+func openDevice(config *Config) (Device, string, error) {
+	if err := wintun.Load(); err != nil {
+		return nil, "", newError("failed to load wintun.dll").Base(err)
+	}
+
+	name := config.Name
+	if len(name) == 0 {
+		name = "v2ray"
+	}
+	name16, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, "", err
+	}
+	tunnelType16, err := syscall.UTF16PtrFromString("V2Ray")
+	if err != nil {
+		return nil, "", err
+	}
+
+	adapter, _, err := wintunCreateAdapter.Call(uintptr(unsafe.Pointer(name16)), uintptr(unsafe.Pointer(tunnelType16)), 0)
+	if adapter == 0 {
+		return nil, "", newError("failed to create adapter ", name).Base(err)
+	}
+
+	session, _, err := wintunStartSession.Call(adapter, wintunRingCapacity)
+	if session == 0 {
+		wintunCloseAdapter.Call(adapter) // nolint: errcheck
+		return nil, "", newError("failed to start session on ", name).Base(err)
+	}
+
+	readEvent, _, _ := wintunGetReadWaitEvent.Call(session)
+	closed, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		wintunEndSession.Call(session)   // nolint: errcheck
+		wintunCloseAdapter.Call(adapter) // nolint: errcheck
+		return nil, "", err
+	}
+
+	if len(config.Address) > 0 {
+		newError("addresses of ", name, " have to be configured by netsh").AtWarning().WriteToLog()
+	}
+
+	return &wintunDevice{
+		adapter:   adapter,
+		session:   session,
+		readEvent: windows.Handle(readEvent),
+		closed:    closed,
+	}, name, nil
+}
@@ -0,0 +1,239 @@
+package tun
+
+import (
+	"encoding/binary"
+
+	"v2ray.com/core/common/net"
+)
+
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	tcpHeaderSize  = 20
+	udpHeaderSize  = 8
+)
+
+const (
+	tcpFlagFIN = 1 << iota
+	tcpFlagSYN
+	tcpFlagRST
+	tcpFlagPSH
+	tcpFlagACK
+)
+
+// ipPacket is a parsed IPv4 or IPv6 packet.
+type ipPacket struct {
+	src      net.IP
+	dst      net.IP
+	protocol byte
+	payload  []byte
+}
+
+// parseIPPacket parses an IP packet. Fragments and IPv6 extension headers are not supported.
+func parseIPPacket(b []byte) (ipPacket, bool) {
+	var p ipPacket
+	if len(b) < 1 {
+		return p, false
+	}
+
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < ipv4HeaderSize {
+			return p, false
+		}
+		headerLen := int(b[0]&0x0f) * 4
+		totalLen := int(binary.BigEndian.Uint16(b[2:]))
+		if headerLen < ipv4HeaderSize || totalLen < headerLen || totalLen > len(b) {
+			return p, false
+		}
+		// More fragments, or a non-first fragment.
+		if binary.BigEndian.Uint16(b[6:])&0x3fff != 0 {
+			return p, false
+		}
+		p.protocol = b[9]
+		p.src = net.IP(b[12:16])
+		p.dst = net.IP(b[16:20])
+		p.payload = b[headerLen:totalLen]
+	case 6:
+		if len(b) < ipv6HeaderSize {
+			return p, false
+		}
+		payloadLen := int(binary.BigEndian.Uint16(b[4:]))
+		if ipv6HeaderSize+payloadLen > len(b) {
+			return p, false
+		}
+		p.protocol = b[6]
+		p.src = net.IP(b[8:24])
+		p.dst = net.IP(b[24:40])
+		p.payload = b[ipv6HeaderSize : ipv6HeaderSize+payloadLen]
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+func ipHeaderSize(ip net.IP) int {
+	if ip.To4() != nil {
+		return ipv4HeaderSize
+	}
+	return ipv6HeaderSize
+}
+
+// writeIPHeader writes an IP header into b, and returns the size of the header.
+func writeIPHeader(b []byte, src, dst net.IP, protocol byte, payloadLen int) int {
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		b[0] = 0x45
+		b[1] = 0
+		binary.BigEndian.PutUint16(b[2:], uint16(ipv4HeaderSize+payloadLen))
+		binary.BigEndian.PutUint32(b[4:], 0x00004000) // ID 0, don't fragment
+		b[8] = 64
+		b[9] = protocol
+		b[10], b[11] = 0, 0
+		copy(b[12:16], src4)
+		copy(b[16:20], dst4)
+		binary.BigEndian.PutUint16(b[10:], finishChecksum(checksum(0, b[:ipv4HeaderSize])))
+		return ipv4HeaderSize
+	}
+
+	binary.BigEndian.PutUint32(b[0:], 0x60000000)
+	binary.BigEndian.PutUint16(b[4:], uint16(payloadLen))
+	b[6] = protocol
+	b[7] = 64
+	copy(b[8:24], src.To16())
+	copy(b[24:40], dst.To16())
+	return ipv6HeaderSize
+}
+
+// checksum adds b into the one's complement sum.
+func checksum(sum uint32, b []byte) uint32 {
+	for len(b) >= 2 {
+		sum += uint32(b[0])<<8 | uint32(b[1])
+		b = b[2:]
+	}
+	if len(b) == 1 {
+		sum += uint32(b[0]) << 8
+	}
+	return sum
+}
+
+func finishChecksum(sum uint32) uint16 {
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+func pseudoHeaderChecksum(src, dst net.IP, protocol byte, length int) uint32 {
+	var sum uint32
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		sum = checksum(sum, src4)
+		sum = checksum(sum, dst4)
+	} else {
+		sum = checksum(sum, src.To16())
+		sum = checksum(sum, dst.To16())
+	}
+	return sum + uint32(protocol) + uint32(length)
+}
+
+// tcpSegment is a parsed TCP segment.
+type tcpSegment struct {
+	srcPort uint16
+	dstPort uint16
+	seq     uint32
+	ack     uint32
+	flags   byte
+	window  uint16
+	// mss is the value of MSS option, or 0 if absent.
+	mss     uint16
+	payload []byte
+}
+
+func parseTCPSegment(b []byte) (tcpSegment, bool) {
+	var s tcpSegment
+	if len(b) < tcpHeaderSize {
+		return s, false
+	}
+	dataOffset := int(b[12]>>4) * 4
+	if dataOffset < tcpHeaderSize || dataOffset > len(b) {
+		return s, false
+	}
+	s.srcPort = binary.BigEndian.Uint16(b[0:])
+	s.dstPort = binary.BigEndian.Uint16(b[2:])
+	s.seq = binary.BigEndian.Uint32(b[4:])
+	s.ack = binary.BigEndian.Uint32(b[8:])
+	s.flags = b[13]
+	s.window = binary.BigEndian.Uint16(b[14:])
+	s.payload = b[dataOffset:]
+
+	options := b[tcpHeaderSize:dataOffset]
+	for len(options) > 0 {
+		kind := options[0]
+		if kind == 0 {
+			break
+		}
+		if kind == 1 {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || int(options[1]) < 2 || int(options[1]) > len(options) {
+			break
+		}
+		if kind == 2 && options[1] == 4 {
+			s.mss = binary.BigEndian.Uint16(options[2:])
+		}
+		options = options[options[1]:]
+	}
+	return s, true
+}
+
+// writeTCPSegment writes an IP packet carrying s into b, and returns the size of the packet.
+func writeTCPSegment(b []byte, src, dst net.IP, s *tcpSegment) int {
+	headerLen := tcpHeaderSize
+	if s.mss > 0 {
+		headerLen += 4
+	}
+	tcpLen := headerLen + len(s.payload)
+	ipLen := writeIPHeader(b, src, dst, protocolTCP, tcpLen)
+
+	t := b[ipLen : ipLen+tcpLen]
+	binary.BigEndian.PutUint16(t[0:], s.srcPort)
+	binary.BigEndian.PutUint16(t[2:], s.dstPort)
+	binary.BigEndian.PutUint32(t[4:], s.seq)
+	binary.BigEndian.PutUint32(t[8:], s.ack)
+	t[12] = byte(headerLen/4) << 4
+	t[13] = s.flags
+	binary.BigEndian.PutUint16(t[14:], s.window)
+	t[16], t[17], t[18], t[19] = 0, 0, 0, 0
+	if s.mss > 0 {
+		t[20], t[21] = 2, 4
+		binary.BigEndian.PutUint16(t[22:], s.mss)
+	}
+	copy(t[headerLen:], s.payload)
+
+	sum := pseudoHeaderChecksum(src, dst, protocolTCP, tcpLen)
+	binary.BigEndian.PutUint16(t[16:], finishChecksum(checksum(sum, t)))
+	return ipLen + tcpLen
+}
+
+// writeUDPDatagram writes an IP packet carrying a UDP datagram into b, and returns the size of the packet.
+func writeUDPDatagram(b []byte, src net.IP, srcPort uint16, dst net.IP, dstPort uint16, payload []byte) int {
+	udpLen := udpHeaderSize + len(payload)
+	ipLen := writeIPHeader(b, src, dst, protocolUDP, udpLen)
+
+	u := b[ipLen : ipLen+udpLen]
+	binary.BigEndian.PutUint16(u[0:], srcPort)
+	binary.BigEndian.PutUint16(u[2:], dstPort)
+	binary.BigEndian.PutUint16(u[4:], uint16(udpLen))
+	u[6], u[7] = 0, 0
+	copy(u[udpHeaderSize:], payload)
+
+	sum := finishChecksum(checksum(pseudoHeaderChecksum(src, dst, protocolUDP, udpLen), u))
+	if sum == 0 {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(u[6:], sum)
+	return ipLen + udpLen
+}
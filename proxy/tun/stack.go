@@ -0,0 +1,265 @@
+package tun
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/task"
+	"v2ray.com/core/transport/internet"
+)
+
+// Device is a TUN device. Each Read returns one IP packet, and each Write sends one IP packet.
+type Device interface {
+	io.ReadWriteCloser
+}
+
+type flowID struct {
+	src net.Destination
+	dst net.Destination
+}
+
+// Stack terminates TCP connections and UDP flows of the IP packets read from a Device.
+// Each connection is passed to the handler with its original destination as LocalAddr().
+type Stack struct {
+	device  Device
+	mtu     int
+	handler func(net.Network, internet.Connection)
+
+	access   sync.Mutex
+	tcpConns map[flowID]*tcpConn
+	udpConns map[flowID]*udpConn
+	cleaner  *task.Periodic
+
+	writeAccess sync.Mutex
+	writeBuffer []byte
+}
+
+// NewStack creates a new Stack on the given device. The handler must not block.
+func NewStack(device Device, mtu int, handler func(net.Network, internet.Connection)) *Stack {
+	s := &Stack{
+		device:      device,
+		mtu:         mtu,
+		handler:     handler,
+		tcpConns:    make(map[flowID]*tcpConn),
+		udpConns:    make(map[flowID]*udpConn),
+		writeBuffer: make([]byte, mtu),
+	}
+	s.cleaner = &task.Periodic{
+		Interval: time.Second * 16,
+		Execute:  s.cleanUDP,
+	}
+	return s
+}
+
+// Start starts reading packets from the device, until the device is closed.
+func (s *Stack) Start() error {
+	if err := s.cleaner.Start(); err != nil {
+		return err
+	}
+	go s.readPackets()
+	return nil
+}
+
+// Close closes the device and all connections on it.
+func (s *Stack) Close() error {
+	err := s.device.Close()
+	s.cleaner.Close() // nolint: errcheck
+
+	s.access.Lock()
+	tcpConns := make([]*tcpConn, 0, len(s.tcpConns))
+	for _, c := range s.tcpConns {
+		tcpConns = append(tcpConns, c)
+	}
+	udpConns := make([]*udpConn, 0, len(s.udpConns))
+	for _, c := range s.udpConns {
+		udpConns = append(udpConns, c)
+	}
+	s.access.Unlock()
+
+	for _, c := range tcpConns {
+		c.access.Lock()
+		c.terminate(newError("device closed"))
+		c.access.Unlock()
+	}
+	for _, c := range udpConns {
+		c.Close() // nolint: errcheck
+	}
+	return err
+}
+
+func (s *Stack) readPackets() {
+	// Some devices report packets larger than MTU, such as the ones with offloading enabled.
+	b := make([]byte, s.mtu+64*1024)
+	for {
+		n, err := s.device.Read(b)
+		if err != nil {
+			newError("failed to read from device").Base(err).AtInfo().WriteToLog()
+			return
+		}
+		s.handlePacket(b[:n])
+	}
+}
+
+func (s *Stack) handlePacket(b []byte) {
+	p, ok := parseIPPacket(b)
+	if !ok {
+		return
+	}
+	src := net.IPAddress(p.src)
+	dst := net.IPAddress(p.dst)
+
+	switch p.protocol {
+	case protocolTCP:
+		seg, ok := parseTCPSegment(p.payload)
+		if !ok {
+			return
+		}
+		s.handleTCP(flowID{
+			src: net.TCPDestination(src, net.Port(seg.srcPort)),
+			dst: net.TCPDestination(dst, net.Port(seg.dstPort)),
+		}, &seg)
+	case protocolUDP:
+		if len(p.payload) < udpHeaderSize {
+			return
+		}
+		udpLen := int(binary.BigEndian.Uint16(p.payload[4:]))
+		if udpLen < udpHeaderSize || udpLen > len(p.payload) {
+			return
+		}
+		s.handleUDP(flowID{
+			src: net.UDPDestination(src, net.Port(binary.BigEndian.Uint16(p.payload[0:]))),
+			dst: net.UDPDestination(dst, net.Port(binary.BigEndian.Uint16(p.payload[2:]))),
+		}, p.payload[udpHeaderSize:udpLen])
+	}
+}
+
+func (s *Stack) handleTCP(id flowID, seg *tcpSegment) {
+	s.access.Lock()
+	conn, found := s.tcpConns[id]
+	if !found {
+		if seg.flags&(tcpFlagSYN|tcpFlagACK|tcpFlagRST) != tcpFlagSYN {
+			s.access.Unlock()
+			if seg.flags&tcpFlagRST == 0 {
+				s.resetTCP(id, seg)
+			}
+			return
+		}
+		conn = newTCPConn(s, id, seg)
+		s.tcpConns[id] = conn
+		s.access.Unlock()
+
+		conn.access.Lock()
+		conn.sendSynAck()
+		conn.access.Unlock()
+		return
+	}
+	s.access.Unlock()
+
+	conn.handleSegment(seg)
+}
+
+// resetTCP replies a RST to a segment that belongs to no connection.
+func (s *Stack) resetTCP(id flowID, seg *tcpSegment) {
+	rst := tcpSegment{
+		srcPort: seg.dstPort,
+		dstPort: seg.srcPort,
+		flags:   tcpFlagRST,
+	}
+	if seg.flags&tcpFlagACK != 0 {
+		rst.seq = seg.ack
+	} else {
+		rst.flags |= tcpFlagACK
+		rst.ack = seg.seq + uint32(len(seg.payload))
+		if seg.flags&(tcpFlagSYN|tcpFlagFIN) != 0 {
+			rst.ack++
+		}
+	}
+	s.writeTCP(id.dst.Address.IP(), id.src.Address.IP(), &rst)
+}
+
+func (s *Stack) removeTCP(id flowID) {
+	s.access.Lock()
+	delete(s.tcpConns, id)
+	s.access.Unlock()
+}
+
+func (s *Stack) handleUDP(id flowID, payload []byte) {
+	if len(payload) > buf.Size {
+		newError("dropping UDP packet larger than ", buf.Size, " bytes").AtDebug().WriteToLog()
+		return
+	}
+
+	s.access.Lock()
+	conn, found := s.udpConns[id]
+	if !found {
+		conn = newUDPConn(s, id)
+		s.udpConns[id] = conn
+	}
+	s.access.Unlock()
+
+	b := buf.New()
+	b.Write(payload) // nolint: errcheck
+	// Payload is discarded if the pipe is full.
+	conn.writer.WriteMultiBuffer(buf.MultiBuffer{b}) // nolint: errcheck
+	conn.updateActivity()
+
+	if !found {
+		s.handler(net.Network_UDP, conn)
+	}
+}
+
+func (s *Stack) removeUDP(id flowID, conn *udpConn) {
+	s.access.Lock()
+	if s.udpConns[id] == conn {
+		delete(s.udpConns, id)
+	}
+	s.access.Unlock()
+}
+
+func (s *Stack) cleanUDP() error {
+	nowSec := time.Now().Unix()
+	var expired []*udpConn
+
+	s.access.Lock()
+	for _, conn := range s.udpConns {
+		if conn.idle(nowSec) {
+			expired = append(expired, conn)
+		}
+	}
+	s.access.Unlock()
+
+	for _, conn := range expired {
+		conn.Close() // nolint: errcheck
+	}
+	return nil
+}
+
+func (s *Stack) writeTCP(src, dst net.IP, seg *tcpSegment) {
+	s.writeAccess.Lock()
+	defer s.writeAccess.Unlock()
+
+	n := writeTCPSegment(s.writeBuffer, src, dst, seg)
+	if _, err := s.device.Write(s.writeBuffer[:n]); err != nil {
+		newError("failed to write to device").Base(err).AtDebug().WriteToLog()
+	}
+}
+
+func (s *Stack) writeUDP(src, dst net.Destination, payload []byte) error {
+	srcIP := src.Address.IP()
+	if ipHeaderSize(srcIP)+udpHeaderSize+len(payload) > s.mtu {
+		return newError("UDP payload too large: ", len(payload))
+	}
+
+	s.writeAccess.Lock()
+	defer s.writeAccess.Unlock()
+
+	n := writeUDPDatagram(s.writeBuffer, srcIP, uint16(src.Port), dst.Address.IP(), uint16(dst.Port), payload)
+	if _, err := s.device.Write(s.writeBuffer[:n]); err != nil {
+		return newError("failed to write to device").Base(err)
+	}
+	return nil
+}
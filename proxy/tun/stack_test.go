@@ -0,0 +1,302 @@
+package tun
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+type fakeDevice struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+}
+
+func newFakeDevice() *fakeDevice {
+	return &fakeDevice{
+		in:     make(chan []byte, 16),
+		out:    make(chan []byte, 16),
+		closed: make(chan struct{}),
+	}
+}
+
+func (d *fakeDevice) Read(b []byte) (int, error) {
+	select {
+	case p := <-d.in:
+		return copy(b, p), nil
+	case <-d.closed:
+		return 0, io.EOF
+	}
+}
+
+func (d *fakeDevice) Write(b []byte) (int, error) {
+	d.out <- append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (d *fakeDevice) Close() error {
+	close(d.closed)
+	return nil
+}
+
+// receive returns the next packet written by the stack, after verifying its checksums.
+func (d *fakeDevice) receive(t *testing.T) ipPacket {
+	t.Helper()
+
+	select {
+	case b := <-d.out:
+		p, ok := parseIPPacket(b)
+		if !ok {
+			t.Fatal("invalid IP packet: ", b)
+		}
+		if finishChecksum(checksum(0, b[:ipv4HeaderSize])) != 0 {
+			t.Fatal("bad IP checksum")
+		}
+		if finishChecksum(checksum(pseudoHeaderChecksum(p.src, p.dst, p.protocol, len(p.payload)), p.payload)) != 0 {
+			t.Fatal("bad checksum of protocol ", p.protocol)
+		}
+		return p
+	case <-time.After(time.Second * 2):
+		t.Fatal("timeout waiting for packet")
+	}
+	panic("unreachable")
+}
+
+var (
+	clientIP = net.IP{10, 0, 0, 2}
+	serverIP = net.IP{1, 2, 3, 4}
+)
+
+func (d *fakeDevice) sendTCP(s *tcpSegment) {
+	b := make([]byte, 1500)
+	n := writeTCPSegment(b, clientIP, serverIP, s)
+	d.in <- b[:n]
+}
+
+func (d *fakeDevice) receiveTCP(t *testing.T) tcpSegment {
+	t.Helper()
+
+	p := d.receive(t)
+	if p.protocol != protocolTCP {
+		t.Fatal("expect TCP, but got protocol ", p.protocol)
+	}
+	s, ok := parseTCPSegment(p.payload)
+	if !ok {
+		t.Fatal("invalid TCP segment")
+	}
+	return s
+}
+
+func TestStackTCP(t *testing.T) {
+	device := newFakeDevice()
+	conns := make(chan internet.Connection, 1)
+	stack := NewStack(device, 1500, func(network net.Network, conn internet.Connection) {
+		if network != net.Network_TCP {
+			t.Error("unexpected network ", network)
+		}
+		conns <- conn
+	})
+	common.Must(stack.Start())
+	defer stack.Close()
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1000, flags: tcpFlagSYN, window: 65535, mss: 1000})
+	synAck := device.receiveTCP(t)
+	if synAck.flags != tcpFlagSYN|tcpFlagACK || synAck.ack != 1001 || synAck.mss != 1000 {
+		t.Fatal("unexpected SYN-ACK: ", synAck)
+	}
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1001, ack: synAck.seq + 1, flags: tcpFlagACK, window: 65535, payload: []byte("hello")})
+	var conn internet.Connection
+	select {
+	case conn = <-conns:
+	case <-time.After(time.Second * 2):
+		t.Fatal("connection is not established")
+	}
+	if dest := net.DestinationFromAddr(conn.LocalAddr()); dest.String() != "tcp:1.2.3.4:80" {
+		t.Error("unexpected destination ", dest)
+	}
+
+	b := make([]byte, 16)
+	n, err := conn.Read(b)
+	common.Must(err)
+	if string(b[:n]) != "hello" {
+		t.Error("unexpected data ", string(b[:n]))
+	}
+	if ack := device.receiveTCP(t); ack.ack != 1006 {
+		t.Error("unexpected ack ", ack.ack)
+	}
+
+	_, err = conn.Write([]byte("world"))
+	common.Must(err)
+	data := device.receiveTCP(t)
+	if string(data.payload) != "world" || data.seq != synAck.seq+1 {
+		t.Fatal("unexpected data segment: ", data)
+	}
+
+	// Lost segment is retransmitted.
+	data = device.receiveTCP(t)
+	if string(data.payload) != "world" {
+		t.Fatal("unexpected retransmission: ", data)
+	}
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1006, ack: data.seq + 5, flags: tcpFlagACK | tcpFlagFIN, window: 65535})
+	if ack := device.receiveTCP(t); ack.ack != 1007 {
+		t.Error("FIN is not acknowledged: ", ack)
+	}
+	if _, err := conn.Read(b); err != io.EOF {
+		t.Error("expect EOF, but got ", err)
+	}
+
+	common.Must(conn.Close())
+	fin := device.receiveTCP(t)
+	if fin.flags&tcpFlagFIN == 0 {
+		t.Fatal("expect FIN, but got ", fin)
+	}
+}
+
+// establishTCP completes a handshake from port 50000 with initial sequence number 1000, and returns the accepted
+// connection and the SYN-ACK.
+func establishTCP(t *testing.T, device *fakeDevice, conns chan internet.Connection, window uint16) (*tcpConn, tcpSegment) {
+	t.Helper()
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1000, flags: tcpFlagSYN, window: 65535, mss: 1000})
+	synAck := device.receiveTCP(t)
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1001, ack: synAck.seq + 1, flags: tcpFlagACK, window: window})
+	select {
+	case conn := <-conns:
+		return conn.(*tcpConn), synAck
+	case <-time.After(time.Second * 2):
+		t.Fatal("connection is not established")
+	}
+	panic("unreachable")
+}
+
+func TestStackTCPOutOfOrder(t *testing.T) {
+	device := newFakeDevice()
+	conns := make(chan internet.Connection, 1)
+	stack := NewStack(device, 1500, func(network net.Network, conn internet.Connection) {
+		conns <- conn
+	})
+	common.Must(stack.Start())
+	defer stack.Close()
+
+	conn, synAck := establishTCP(t, device, conns, 65535)
+	ack := synAck.seq + 1
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1011, ack: ack, flags: tcpFlagACK | tcpFlagFIN, window: 65535, payload: []byte("!")})
+	if s := device.receiveTCP(t); s.ack != 1001 {
+		t.Error("unexpected ack of out of order segment ", s.ack)
+	}
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1006, ack: ack, flags: tcpFlagACK, window: 65535, payload: []byte("world")})
+	if s := device.receiveTCP(t); s.ack != 1001 {
+		t.Error("unexpected ack of out of order segment ", s.ack)
+	}
+	// Beyond the receive window.
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1001 + 65535, ack: ack, flags: tcpFlagACK, window: 65535, payload: []byte("x")})
+	if s := device.receiveTCP(t); s.ack != 1001 {
+		t.Error("unexpected ack of segment beyond window ", s.ack)
+	}
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1001, ack: ack, flags: tcpFlagACK, window: 65535, payload: []byte("hello")})
+	if s := device.receiveTCP(t); s.ack != 1013 {
+		t.Error("queued segments are not acknowledged: ", s.ack)
+	}
+
+	b, err := ioutil.ReadAll(conn)
+	common.Must(err)
+	if string(b) != "helloworld!" {
+		t.Error("unexpected data ", string(b))
+	}
+}
+
+func TestStackTCPDeadline(t *testing.T) {
+	device := newFakeDevice()
+	conns := make(chan internet.Connection, 1)
+	stack := NewStack(device, 1500, func(network net.Network, conn internet.Connection) {
+		conns <- conn
+	})
+	common.Must(stack.Start())
+	defer stack.Close()
+
+	// The peer window is closed, so that writes are buffered.
+	conn, _ := establishTCP(t, device, conns, 0)
+
+	common.Must(conn.SetDeadline(time.Now().Add(time.Millisecond * 100)))
+	if _, err := conn.Read(make([]byte, 16)); !os.IsTimeout(err) {
+		t.Error("expect timeout of read, but got ", err)
+	}
+	n, err := conn.Write(make([]byte, tcpSendBufferSize+1))
+	if !os.IsTimeout(err) || n != tcpSendBufferSize {
+		t.Error("expect timeout of write after ", tcpSendBufferSize, " bytes, but got ", n, " ", err)
+	}
+
+	// Reads are woken up when the deadline is reached.
+	common.Must(conn.SetReadDeadline(time.Time{}))
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		conn.SetReadDeadline(time.Now())
+	}()
+	if _, err := conn.Read(make([]byte, 16)); !os.IsTimeout(err) {
+		t.Error("expect timeout of read, but got ", err)
+	}
+}
+
+func TestStackTCPReset(t *testing.T) {
+	device := newFakeDevice()
+	stack := NewStack(device, 1500, func(net.Network, internet.Connection) {})
+	common.Must(stack.Start())
+	defer stack.Close()
+
+	device.sendTCP(&tcpSegment{srcPort: 50000, dstPort: 80, seq: 1000, ack: 2000, flags: tcpFlagACK, window: 65535})
+	rst := device.receiveTCP(t)
+	if rst.flags&tcpFlagRST == 0 || rst.seq != 2000 {
+		t.Error("unexpected reply to unknown segment: ", rst)
+	}
+}
+
+func TestStackUDP(t *testing.T) {
+	device := newFakeDevice()
+	conns := make(chan internet.Connection, 1)
+	stack := NewStack(device, 1500, func(network net.Network, conn internet.Connection) {
+		if network != net.Network_UDP {
+			t.Error("unexpected network ", network)
+		}
+		conns <- conn
+	})
+	common.Must(stack.Start())
+	defer stack.Close()
+
+	b := make([]byte, 1500)
+	n := writeUDPDatagram(b, clientIP, 5353, serverIP, 53, []byte("query"))
+	device.in <- b[:n]
+
+	var conn internet.Connection
+	select {
+	case conn = <-conns:
+	case <-time.After(time.Second * 2):
+		t.Fatal("no UDP connection")
+	}
+	if dest := net.DestinationFromAddr(conn.LocalAddr()); dest.String() != "udp:1.2.3.4:53" {
+		t.Error("unexpected destination ", dest)
+	}
+
+	mb, err := conn.(*udpConn).ReadMultiBuffer()
+	common.Must(err)
+	if mb.String() != "query" {
+		t.Error("unexpected payload ", mb.String())
+	}
+
+	_, err = conn.Write([]byte("answer"))
+	common.Must(err)
+	p := device.receive(t)
+	if p.protocol != protocolUDP || !p.src.Equal(serverIP) || !p.dst.Equal(clientIP) || string(p.payload[udpHeaderSize:]) != "answer" {
+		t.Error("unexpected reply ", p)
+	}
+	common.Must(conn.Close())
+}
@@ -0,0 +1,609 @@
+package tun
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/net"
+)
+
+const (
+	tcpReceiveWindow  = 65535
+	tcpSendBufferSize = 256 * 1024
+	tcpInitialRTO     = 200 * time.Millisecond
+	tcpMaxRTO         = 10 * time.Second
+	tcpMaxRetries     = 10
+)
+
+type tcpState int
+
+const (
+	tcpStateSynReceived tcpState = iota
+	tcpStateEstablished
+	tcpStateClosed
+)
+
+// tcpConn is a minimal TCP endpoint terminating a connection captured from the device.
+// It acts as the original destination of the connection. Segments received out of order are queued within the receive
+// window until the missing data arrives, and lost segments are recovered by go-back-N retransmission. This is
+// sufficient for a link as reliable as a local device.
+type tcpConn struct {
+	stack  *Stack
+	id     flowID
+	local  *net.TCPAddr
+	remote *net.TCPAddr
+	mss    int
+
+	access sync.Mutex
+	cond   *sync.Cond
+	state  tcpState
+	err    error
+
+	rcvNxt     uint32
+	recvBuf    buf.MultiBuffer
+	recvClosed bool
+	// Segments after rcvNxt, sorted by their sequence numbers.
+	outOfOrder     []tcpOutOfOrder
+	outOfOrderSize int
+	// windowClosed is set when the advertised window is too small, so that a window update is sent after reading.
+	windowClosed bool
+
+	sndUna    uint32
+	sndNxt    uint32
+	sndWnd    uint32
+	sendBuf   []byte
+	finQueued bool
+	finSent   bool
+	closed    bool
+
+	rto     time.Duration
+	retries int
+	timer   *time.Timer
+
+	readDeadline  tcpDeadline
+	writeDeadline tcpDeadline
+}
+
+// tcpOutOfOrder is a segment received before some data preceding it.
+type tcpOutOfOrder struct {
+	seq     uint32
+	payload []byte
+	fin     bool
+}
+
+// tcpDeadline is the deadline of reads or writes of a tcpConn.
+type tcpDeadline struct {
+	t     time.Time
+	timer *time.Timer
+}
+
+// set sets the deadline to t, and wakes up the goroutines waiting on cond when it is reached. A zero t disables the
+// deadline. Caller must hold the lock of cond.
+func (d *tcpDeadline) set(t time.Time, cond *sync.Cond) {
+	d.stop()
+	d.t = t
+	if !t.IsZero() {
+		d.timer = time.AfterFunc(time.Until(t), func() {
+			cond.L.Lock()
+			cond.Broadcast()
+			cond.L.Unlock()
+		})
+	}
+	cond.Broadcast()
+}
+
+func (d *tcpDeadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+func (d *tcpDeadline) exceeded() bool {
+	return !d.t.IsZero() && !time.Now().Before(d.t)
+}
+
+func newTCPConn(stack *Stack, id flowID, syn *tcpSegment) *tcpConn {
+	iss := uint32(dice.RollUint64())
+	mss := stack.mtu - ipHeaderSize(id.src.Address.IP()) - tcpHeaderSize
+	if syn.mss > 0 && int(syn.mss) < mss {
+		mss = int(syn.mss)
+	}
+
+	c := &tcpConn{
+		stack:  stack,
+		id:     id,
+		local:  &net.TCPAddr{IP: id.dst.Address.IP(), Port: int(id.dst.Port)},
+		remote: &net.TCPAddr{IP: id.src.Address.IP(), Port: int(id.src.Port)},
+		mss:    mss,
+		state:  tcpStateSynReceived,
+		rcvNxt: syn.seq + 1,
+		sndUna: iss,
+		sndNxt: iss + 1,
+		sndWnd: uint32(syn.window),
+		rto:    tcpInitialRTO,
+	}
+	c.cond = sync.NewCond(&c.access)
+	return c
+}
+
+func seqBefore(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// window returns the receive window to advertise. Caller must hold the lock.
+func (c *tcpConn) window() uint16 {
+	w := tcpReceiveWindow - int(c.recvBuf.Len())
+	if w < 0 {
+		w = 0
+	}
+	return uint16(w)
+}
+
+// send writes a segment to the device. Caller must hold the lock.
+func (c *tcpConn) send(flags byte, seq uint32, payload []byte) {
+	s := tcpSegment{
+		srcPort: uint16(c.id.dst.Port),
+		dstPort: uint16(c.id.src.Port),
+		seq:     seq,
+		ack:     c.rcvNxt,
+		flags:   flags | tcpFlagACK,
+		window:  c.window(),
+		payload: payload,
+	}
+	if flags&tcpFlagSYN != 0 {
+		s.mss = uint16(c.mss)
+	}
+	c.windowClosed = int(s.window) < c.mss
+	c.stack.writeTCP(c.id.dst.Address.IP(), c.id.src.Address.IP(), &s)
+}
+
+func (c *tcpConn) sendSynAck() {
+	c.send(tcpFlagSYN, c.sndUna, nil)
+	c.armTimer()
+}
+
+// output sends as much pending data as the peer window allows, followed by a FIN if the connection is closed locally.
+// Caller must hold the lock.
+func (c *tcpConn) output() {
+	if c.state != tcpStateEstablished {
+		return
+	}
+	for {
+		offset := int(c.sndNxt - c.sndUna)
+		if offset >= len(c.sendBuf) {
+			break
+		}
+		inflight := uint32(offset)
+		if inflight >= c.sndWnd {
+			break
+		}
+		n := len(c.sendBuf) - offset
+		if n > c.mss {
+			n = c.mss
+		}
+		if uint32(n) > c.sndWnd-inflight {
+			n = int(c.sndWnd - inflight)
+		}
+		c.send(tcpFlagPSH, c.sndNxt, c.sendBuf[offset:offset+n])
+		c.sndNxt += uint32(n)
+	}
+
+	if c.finQueued && !c.finSent && int(c.sndNxt-c.sndUna) == len(c.sendBuf) {
+		c.send(tcpFlagFIN, c.sndNxt, nil)
+		c.sndNxt++
+		c.finSent = true
+	}
+
+	if c.sndNxt != c.sndUna || len(c.sendBuf) > 0 {
+		c.armTimer()
+	}
+}
+
+// armTimer starts the retransmission timer if it is not running. Caller must hold the lock.
+func (c *tcpConn) armTimer() {
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.rto, c.onTimer)
+	}
+}
+
+func (c *tcpConn) stopTimer() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+func (c *tcpConn) onTimer() {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.timer = nil
+	if c.state == tcpStateClosed {
+		return
+	}
+
+	c.retries++
+	if c.retries > tcpMaxRetries {
+		c.send(tcpFlagRST, c.sndNxt, nil)
+		c.terminate(newError("connection to ", c.remote, " timed out"))
+		return
+	}
+	c.rto *= 2
+	if c.rto > tcpMaxRTO {
+		c.rto = tcpMaxRTO
+	}
+
+	if c.state == tcpStateSynReceived {
+		c.sendSynAck()
+		return
+	}
+
+	if c.sndNxt == c.sndUna && len(c.sendBuf) > 0 {
+		// Zero window probe.
+		c.send(tcpFlagPSH, c.sndNxt, c.sendBuf[:1])
+		c.sndNxt++
+		c.armTimer()
+		return
+	}
+
+	// Go back N.
+	c.sndNxt = c.sndUna
+	c.finSent = false
+	wnd := c.sndWnd
+	if wnd == 0 {
+		c.sndWnd = 1
+	}
+	c.output()
+	c.sndWnd = wnd
+}
+
+// terminate closes the connection immediately. Caller must hold the lock.
+func (c *tcpConn) terminate(err error) {
+	if c.state == tcpStateClosed {
+		return
+	}
+	c.state = tcpStateClosed
+	c.err = err
+	c.stopTimer()
+	c.readDeadline.stop()
+	c.writeDeadline.stop()
+	c.cond.Broadcast()
+	c.stack.removeTCP(c.id)
+}
+
+// handleSegment processes a segment from the peer.
+func (c *tcpConn) handleSegment(s *tcpSegment) {
+	if c.processSegment(s) {
+		c.stack.handler(net.Network_TCP, c)
+	}
+}
+
+// processSegment processes a segment from the peer, and returns true if the connection becomes established.
+func (c *tcpConn) processSegment(s *tcpSegment) bool {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	if c.state == tcpStateClosed {
+		return false
+	}
+
+	if s.flags&tcpFlagRST != 0 {
+		c.terminate(newError("connection reset by ", c.remote))
+		return false
+	}
+
+	if s.flags&tcpFlagSYN != 0 {
+		if c.state == tcpStateSynReceived {
+			// SYN-ACK was lost.
+			c.send(tcpFlagSYN, c.sndUna, nil)
+		}
+		return false
+	}
+
+	if s.flags&tcpFlagACK == 0 {
+		return false
+	}
+
+	established := c.handleAck(s)
+	if c.state == tcpStateClosed {
+		return false
+	}
+	c.handleData(s)
+	c.output()
+	return established
+}
+
+// handleAck processes the acknowledgement in a segment, and returns true if it completes the handshake.
+// Caller must hold the lock.
+func (c *tcpConn) handleAck(s *tcpSegment) bool {
+	established := false
+	if c.state == tcpStateSynReceived {
+		if s.ack != c.sndUna+1 {
+			return false
+		}
+		c.sndUna++
+		c.state = tcpStateEstablished
+		c.stopTimer()
+		c.rto = tcpInitialRTO
+		c.retries = 0
+		established = true
+	}
+
+	c.sndWnd = uint32(s.window)
+	if !seqBefore(c.sndUna, s.ack) || seqBefore(c.sndNxt, s.ack) {
+		c.cond.Broadcast()
+		return established
+	}
+
+	acked := int(s.ack - c.sndUna)
+	c.sndUna = s.ack
+	if acked > len(c.sendBuf) {
+		// FIN is acknowledged.
+		acked = len(c.sendBuf)
+	}
+	c.sendBuf = c.sendBuf[acked:]
+	if len(c.sendBuf) == 0 {
+		c.sendBuf = nil
+	}
+
+	c.stopTimer()
+	c.rto = tcpInitialRTO
+	c.retries = 0
+	c.cond.Broadcast()
+
+	if c.finSent && c.sndUna == c.sndNxt {
+		// All data and the FIN is acknowledged. Data from the peer after this point is not accepted any more.
+		c.terminate(io.EOF)
+	}
+	return established
+}
+
+// handleData processes the payload and FIN in a segment. Caller must hold the lock.
+func (c *tcpConn) handleData(s *tcpSegment) {
+	payload := s.payload
+	seq := s.seq
+	if seqBefore(seq, c.rcvNxt) {
+		// Retransmission that overlaps with received data.
+		skip := c.rcvNxt - seq
+		if uint32(len(payload)) < skip {
+			if len(payload) > 0 || s.flags&tcpFlagFIN != 0 {
+				c.send(0, c.sndNxt, nil)
+			}
+			return
+		}
+		payload = payload[skip:]
+		seq = c.rcvNxt
+	}
+
+	fin := s.flags&tcpFlagFIN != 0
+	if len(payload) == 0 && !fin {
+		return
+	}
+	if len(payload) > 0 && c.recvClosed {
+		c.send(0, c.sndNxt, nil)
+		return
+	}
+	if int(seq-c.rcvNxt)+len(payload) > int(c.window()) {
+		// Beyond the receive window.
+		c.send(0, c.sndNxt, nil)
+		return
+	}
+
+	if seq != c.rcvNxt {
+		// Out of order. Keep it, and ask for the missing segment.
+		c.queueOutOfOrder(seq, payload, fin)
+		c.send(0, c.sndNxt, nil)
+		return
+	}
+
+	c.deliver(payload, fin)
+	for len(c.outOfOrder) > 0 && !seqBefore(c.rcvNxt, c.outOfOrder[0].seq) {
+		q := c.outOfOrder[0]
+		c.outOfOrder = c.outOfOrder[1:]
+		c.outOfOrderSize -= len(q.payload)
+		if skip := c.rcvNxt - q.seq; skip <= uint32(len(q.payload)) && !c.recvClosed {
+			c.deliver(q.payload[skip:], q.fin)
+		}
+	}
+	if len(c.outOfOrder) == 0 {
+		c.outOfOrder = nil
+	}
+	c.send(0, c.sndNxt, nil)
+}
+
+// deliver appends the payload in sequence to the receive buffer, followed by a FIN if fin is set. Caller must hold the
+// lock.
+func (c *tcpConn) deliver(payload []byte, fin bool) {
+	if len(payload) > 0 {
+		if !c.closed {
+			c.recvBuf = buf.MergeBytes(c.recvBuf, payload)
+		}
+		c.rcvNxt += uint32(len(payload))
+		c.cond.Broadcast()
+	}
+
+	if fin && !c.recvClosed {
+		c.rcvNxt++
+		c.recvClosed = true
+		c.outOfOrder = nil
+		c.outOfOrderSize = 0
+		c.cond.Broadcast()
+	}
+}
+
+// queueOutOfOrder keeps a copy of a segment after rcvNxt, unless the same data is queued already. The queued data is
+// limited to the receive window. Caller must hold the lock.
+func (c *tcpConn) queueOutOfOrder(seq uint32, payload []byte, fin bool) {
+	if c.outOfOrderSize+len(payload) > tcpReceiveWindow {
+		return
+	}
+	i := 0
+	for ; i < len(c.outOfOrder); i++ {
+		s := c.outOfOrder[i]
+		if s.seq == seq && len(s.payload) >= len(payload) && (s.fin || !fin) {
+			return
+		}
+		if seqBefore(seq, s.seq) {
+			break
+		}
+	}
+	c.outOfOrder = append(c.outOfOrder, tcpOutOfOrder{})
+	copy(c.outOfOrder[i+1:], c.outOfOrder[i:])
+	c.outOfOrder[i] = tcpOutOfOrder{seq: seq, payload: append([]byte(nil), payload...), fin: fin}
+	c.outOfOrderSize += len(payload)
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (c *tcpConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	for c.recvBuf.IsEmpty() {
+		if c.recvClosed {
+			return nil, io.EOF
+		}
+		if c.state == tcpStateClosed || c.closed {
+			if c.err != nil && c.err != io.EOF {
+				return nil, c.err
+			}
+			return nil, io.EOF
+		}
+		if c.readDeadline.exceeded() {
+			return nil, os.ErrDeadlineExceeded
+		}
+		c.cond.Wait()
+	}
+
+	mb := c.recvBuf
+	c.recvBuf = nil
+	c.updateWindow()
+	return mb, nil
+}
+
+// Read implements io.Reader.
+func (c *tcpConn) Read(b []byte) (int, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	for c.recvBuf.IsEmpty() {
+		if c.recvClosed {
+			return 0, io.EOF
+		}
+		if c.state == tcpStateClosed || c.closed {
+			if c.err != nil && c.err != io.EOF {
+				return 0, c.err
+			}
+			return 0, io.EOF
+		}
+		if c.readDeadline.exceeded() {
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.cond.Wait()
+	}
+
+	mb, n := buf.SplitBytes(c.recvBuf, b)
+	c.recvBuf = mb
+	c.updateWindow()
+	return n, nil
+}
+
+// updateWindow sends a window update if the window was closed before reading. Caller must hold the lock.
+func (c *tcpConn) updateWindow() {
+	if c.windowClosed && c.state == tcpStateEstablished && int(c.window()) >= c.mss {
+		c.send(0, c.sndNxt, nil)
+	}
+}
+
+// Write implements io.Writer. It blocks when the send buffer is full.
+func (c *tcpConn) Write(b []byte) (int, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	written := 0
+	for len(b) > 0 {
+		for len(c.sendBuf) >= tcpSendBufferSize && c.state != tcpStateClosed && !c.finQueued {
+			if c.writeDeadline.exceeded() {
+				return written, os.ErrDeadlineExceeded
+			}
+			c.cond.Wait()
+		}
+		if c.state == tcpStateClosed {
+			if c.err != nil && c.err != io.EOF {
+				return written, c.err
+			}
+			return written, io.ErrClosedPipe
+		}
+		if c.finQueued {
+			return written, io.ErrClosedPipe
+		}
+
+		n := tcpSendBufferSize - len(c.sendBuf)
+		if n > len(b) {
+			n = len(b)
+		}
+		c.sendBuf = append(c.sendBuf, b[:n]...)
+		b = b[n:]
+		written += n
+		c.output()
+	}
+	return written, nil
+}
+
+// Close implements io.Closer. Pending data is still delivered to the peer before a FIN.
+func (c *tcpConn) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.closed = true
+	buf.ReleaseMulti(c.recvBuf)
+	c.recvBuf = nil
+	c.cond.Broadcast()
+
+	if c.state == tcpStateClosed || c.finQueued {
+		return nil
+	}
+	c.finQueued = true
+	c.output()
+	return nil
+}
+
+func (c *tcpConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *tcpConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline implements net.Conn.
+func (c *tcpConn) SetDeadline(t time.Time) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.readDeadline.set(t, c.cond)
+	c.writeDeadline.set(t, c.cond)
+	return nil
+}
+
+// SetReadDeadline implements net.Conn. Reads blocked when it is reached fail with os.ErrDeadlineExceeded.
+func (c *tcpConn) SetReadDeadline(t time.Time) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.readDeadline.set(t, c.cond)
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn. Writes blocked when it is reached fail with os.ErrDeadlineExceeded.
+func (c *tcpConn) SetWriteDeadline(t time.Time) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.writeDeadline.set(t, c.cond)
+	return nil
+}
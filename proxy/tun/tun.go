@@ -0,0 +1,150 @@
+// +build !confonly
+
+// Package tun implements an inbound proxy that captures traffic from a TUN device.
+// TCP connections and UDP flows in the IP packets from the device are terminated by a built-in minimal stack,
+// and dispatched to their original destinations.
+package tun
+
+//go:generate errorgen
+
+import (
+	"context"
+	"sync"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/task"
+	"v2ray.com/core/features/policy"
+	"v2ray.com/core/features/routing"
+	"v2ray.com/core/transport/internet"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		s := &Server{
+			config: config.(*Config),
+		}
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			s.policyManager = pm
+			return nil
+		})
+		return s, err
+	}))
+}
+
+// Server is an inbound proxy on a TUN device.
+type Server struct {
+	config        *Config
+	policyManager policy.Manager
+
+	access sync.Mutex
+	stack  *Stack
+}
+
+// Network implements proxy.Inbound. Server listens on no network, as connections come from the device.
+func (s *Server) Network() []net.Network {
+	return nil
+}
+
+// StartDevice implements proxy.DeviceInbound.
+func (s *Server) StartDevice(handler func(net.Network, internet.Connection)) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	device, name, err := openDevice(s.config)
+	if err != nil {
+		return newError("failed to open TUN device").Base(err).AtWarning()
+	}
+	stack := NewStack(device, s.config.getMTU(), handler)
+	if err := stack.Start(); err != nil {
+		device.Close() // nolint: errcheck
+		return err
+	}
+	s.stack = stack
+	newError("TUN device ", name, " started").AtInfo().WriteToLog()
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *Server) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if s.stack == nil {
+		return nil
+	}
+	err := s.stack.Close()
+	s.stack = nil
+	return err
+}
+
+// Process implements proxy.Inbound.
+func (s *Server) Process(ctx context.Context, network net.Network, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("unable to get destination")
+	}
+	dest := outbound.Target
+
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		inbound.User = &protocol.MemoryUser{
+			Level: s.config.UserLevel,
+		}
+	}
+
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   conn.RemoteAddr(),
+		To:     dest,
+		Status: log.AccessAccepted,
+		Reason: "",
+	})
+	newError("received request for ", dest).WriteToLog(session.ExportIDToError(ctx))
+
+	plcy := s.policyManager.ForLevel(s.config.UserLevel)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
+
+	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
+	link, err := dispatcher.Dispatch(ctx, dest)
+	if err != nil {
+		return newError("failed to dispatch request").Base(err)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+
+		if err := buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to transport request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
+
+		var writer buf.Writer
+		if network == net.Network_TCP {
+			writer = buf.NewWriter(conn)
+		} else {
+			writer = &buf.SequentialWriter{Writer: conn}
+		}
+		if err := buf.Copy(link.Reader, writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to transport response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(link.Writer)), responseDone); err != nil {
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+		return newError("connection ends").Base(err)
+	}
+
+	return nil
+}
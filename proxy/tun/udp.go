@@ -0,0 +1,100 @@
+package tun
+
+import (
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/signal/done"
+	"v2ray.com/core/transport/pipe"
+)
+
+// udpConn is a UDP flow captured from the device. Each Write sends one datagram back to the source of the flow.
+type udpConn struct {
+	lastActivityTime int64 // in seconds
+	stack            *Stack
+	id               flowID
+	reader           *pipe.Reader
+	writer           *pipe.Writer
+	local            *net.UDPAddr
+	remote           *net.UDPAddr
+	done             *done.Instance
+}
+
+func newUDPConn(stack *Stack, id flowID) *udpConn {
+	reader, writer := pipe.New(pipe.DiscardOverflow(), pipe.WithSizeLimit(16*1024))
+	c := &udpConn{
+		stack:  stack,
+		id:     id,
+		reader: reader,
+		writer: writer,
+		local:  &net.UDPAddr{IP: id.dst.Address.IP(), Port: int(id.dst.Port)},
+		remote: &net.UDPAddr{IP: id.src.Address.IP(), Port: int(id.src.Port)},
+		done:   done.New(),
+	}
+	c.updateActivity()
+	return c
+}
+
+func (c *udpConn) updateActivity() {
+	atomic.StoreInt64(&c.lastActivityTime, time.Now().Unix())
+}
+
+func (c *udpConn) idle(nowSec int64) bool {
+	return nowSec-atomic.LoadInt64(&c.lastActivityTime) > 60
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (c *udpConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := c.reader.ReadMultiBuffer()
+	if err != nil {
+		return nil, err
+	}
+	c.updateActivity()
+	return mb, nil
+}
+
+func (c *udpConn) Read(b []byte) (int, error) {
+	panic("not implemented")
+}
+
+// Write implements io.Writer.
+func (c *udpConn) Write(b []byte) (int, error) {
+	if c.done.Done() {
+		return 0, newError("connection closed")
+	}
+	if err := c.stack.writeUDP(c.id.dst, c.id.src, b); err != nil {
+		return 0, err
+	}
+	c.updateActivity()
+	return len(b), nil
+}
+
+func (c *udpConn) Close() error {
+	common.Must(c.done.Close())
+	common.Must(c.writer.Close())
+	c.stack.removeUDP(c.id, c)
+	return nil
+}
+
+func (c *udpConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *udpConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (*udpConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (*udpConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (*udpConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
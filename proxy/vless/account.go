@@ -17,6 +17,7 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 		ID:         protocol.NewID(id),
 		Flow:       a.Flow,       // needs parser here?
 		Encryption: a.Encryption, // needs parser here?
+		Padding:    a.Padding,
 	}, nil
 }
 
@@ -28,6 +29,8 @@ type MemoryAccount struct {
 	Flow string
 	// Encryption of the account. Used for client connections, and only accepts "none" for now.
 	Encryption string
+	// Padding scheme of the account. Used for client connections.
+	Padding *PaddingScheme
 }
 
 // Equals implements protocol.Account.Equals().
@@ -16,9 +16,26 @@ func EncodeHeaderAddons(buffer *buf.Buffer, addons *Addons) error {
 	switch addons.Flow {
 	default:
 
-		if err := buffer.WriteByte(0); err != nil {
+		if addons.Padding == nil {
+			if err := buffer.WriteByte(0); err != nil {
+				return newError("failed to write addons protobuf length").Base(err)
+			}
+			return nil
+		}
+
+		bytes, err := proto.Marshal(addons)
+		if err != nil {
+			return newError("failed to marshal addons protobuf value").Base(err)
+		}
+		if len(bytes) > 255 {
+			return newError("addons protobuf value is too long: ", len(bytes))
+		}
+		if err := buffer.WriteByte(byte(len(bytes))); err != nil {
 			return newError("failed to write addons protobuf length").Base(err)
 		}
+		if _, err := buffer.Write(bytes); err != nil {
+			return newError("failed to write addons protobuf value").Base(err)
+		}
 
 	}
 
@@ -51,6 +68,11 @@ func DecodeHeaderAddons(buffer *buf.Buffer, reader io.Reader) (*Addons, error) {
 		default:
 
 		}
+		if addons.Padding != nil {
+			if err := addons.Padding.Validate(); err != nil {
+				return nil, newError("invalid padding scheme").Base(err)
+			}
+		}
 
 	}
 
@@ -64,6 +86,9 @@ func EncodeBodyAddons(writer io.Writer, request *protocol.RequestHeader, addons
 	switch addons.Flow {
 	default:
 
+		if addons.Padding != nil {
+			return newPaddingWriter(writer, addons.Padding)
+		}
 		return buf.NewWriter(writer)
 
 	}
@@ -76,6 +101,9 @@ func DecodeBodyAddons(reader io.Reader, request *protocol.RequestHeader, addons
 	switch addons.Flow {
 	default:
 
+		if addons.Padding != nil {
+			return newPaddingReader(reader, addons.Padding)
+		}
 		return buf.NewReader(reader)
 
 	}
@@ -9,6 +9,7 @@ import (
 	io "io"
 	math "math"
 	math_bits "math/bits"
+	vless "v2ray.com/core/proxy/vless"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -23,11 +24,12 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Addons struct {
-	Flow                 string   `protobuf:"bytes,1,opt,name=Flow,proto3" json:"Flow,omitempty"`
-	Seed                 []byte   `protobuf:"bytes,2,opt,name=Seed,proto3" json:"Seed,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Flow                 string               `protobuf:"bytes,1,opt,name=Flow,proto3" json:"Flow,omitempty"`
+	Seed                 []byte               `protobuf:"bytes,2,opt,name=Seed,proto3" json:"Seed,omitempty"`
+	Padding              *vless.PaddingScheme `protobuf:"bytes,3,opt,name=Padding,proto3" json:"Padding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *Addons) Reset()         { *m = Addons{} }
@@ -77,6 +79,13 @@ func (m *Addons) GetSeed() []byte {
 	return nil
 }
 
+func (m *Addons) GetPadding() *vless.PaddingScheme {
+	if m != nil {
+		return m.Padding
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Addons)(nil), "v2ray.core.proxy.vless.encoding.Addons")
 }
@@ -84,19 +93,22 @@ func init() {
 func init() { proto.RegisterFile("proxy/vless/encoding/addons.proto", fileDescriptor_75ab671b0ca8b1cc) }
 
 var fileDescriptor_75ab671b0ca8b1cc = []byte{
-	// 186 bytes of a gzipped FileDescriptorProto
+	// 228 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x2c, 0x28, 0xca, 0xaf,
 	0xa8, 0xd4, 0x2f, 0xcb, 0x49, 0x2d, 0x2e, 0xd6, 0x4f, 0xcd, 0x4b, 0xce, 0x4f, 0xc9, 0xcc, 0x4b,
 	0xd7, 0x4f, 0x4c, 0x49, 0xc9, 0xcf, 0x2b, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x92, 0x2f,
 	0x33, 0x2a, 0x4a, 0xac, 0xd4, 0x4b, 0xce, 0x2f, 0x4a, 0xd5, 0x03, 0xab, 0xd6, 0x03, 0xab, 0xd6,
-	0x83, 0xa9, 0x56, 0x32, 0xe0, 0x62, 0x73, 0x04, 0x6b, 0x10, 0x12, 0xe2, 0x62, 0x71, 0xcb, 0xc9,
-	0x2f, 0x97, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x02, 0xb3, 0x41, 0x62, 0xc1, 0xa9, 0xa9, 0x29,
-	0x12, 0x4c, 0x0a, 0x8c, 0x1a, 0x3c, 0x41, 0x60, 0xb6, 0x53, 0xdd, 0x89, 0x47, 0x72, 0x8c, 0x17,
-	0x1e, 0xc9, 0x31, 0x3e, 0x78, 0x24, 0xc7, 0x38, 0xe3, 0xb1, 0x1c, 0x03, 0x97, 0x72, 0x72, 0x7e,
-	0xae, 0x1e, 0x01, 0x8b, 0x02, 0x18, 0xa3, 0x94, 0x61, 0x4a, 0x72, 0xf5, 0x41, 0xca, 0xf4, 0xb1,
-	0xb9, 0x7e, 0x15, 0x93, 0x7c, 0x98, 0x51, 0x50, 0x62, 0xa5, 0x9e, 0x33, 0xc8, 0xa0, 0x00, 0xb0,
-	0x41, 0x61, 0x60, 0x83, 0x5c, 0xa1, 0x2a, 0x92, 0xd8, 0xc0, 0x3e, 0x33, 0x06, 0x04, 0x00, 0x00,
-	0xff, 0xff, 0x36, 0x32, 0x14, 0x7c, 0xfe, 0x00, 0x00, 0x00,
+	0x83, 0xa9, 0x96, 0x92, 0x44, 0x36, 0x23, 0x31, 0x39, 0x39, 0xbf, 0x34, 0xaf, 0x04, 0xa2, 0x57,
+	0xa9, 0x90, 0x8b, 0xcd, 0x11, 0x6c, 0x96, 0x90, 0x10, 0x17, 0x8b, 0x5b, 0x4e, 0x7e, 0xb9, 0x04,
+	0xa3, 0x02, 0xa3, 0x06, 0x67, 0x10, 0x98, 0x0d, 0x12, 0x0b, 0x4e, 0x4d, 0x4d, 0x91, 0x60, 0x52,
+	0x60, 0xd4, 0xe0, 0x09, 0x02, 0xb3, 0x85, 0xec, 0xb9, 0xd8, 0x03, 0x12, 0x53, 0x40, 0xe6, 0x4a,
+	0x30, 0x2b, 0x30, 0x6a, 0x70, 0x1b, 0xa9, 0xea, 0xe1, 0xb0, 0x1f, 0xaa, 0x2c, 0x38, 0x39, 0x23,
+	0x35, 0x37, 0x35, 0x08, 0xa6, 0xcb, 0xa9, 0xee, 0xc4, 0x23, 0x39, 0xc6, 0x0b, 0x8f, 0xe4, 0x18,
+	0x1f, 0x3c, 0x92, 0x63, 0x9c, 0xf1, 0x58, 0x8e, 0x81, 0x4b, 0x39, 0x39, 0x3f, 0x57, 0x8f, 0x80,
+	0x27, 0x02, 0x18, 0xa3, 0x94, 0x61, 0x4a, 0x72, 0xf5, 0x41, 0xca, 0xf4, 0xb1, 0x85, 0xcc, 0x2a,
+	0x26, 0xf9, 0x30, 0xa3, 0xa0, 0xc4, 0x4a, 0x3d, 0x67, 0x90, 0x41, 0x01, 0x60, 0x83, 0xc2, 0xc0,
+	0x06, 0xb9, 0x42, 0x55, 0x24, 0xb1, 0x81, 0x7d, 0x6e, 0x0c, 0x18, 0x00, 0x2e, 0x44, 0xe6, 0x12,
+	0x5a, 0x01, 0x00, 0x00,
 }
 
 func (m *Addons) Marshal() (dAtA []byte, err error) {
@@ -123,6 +135,19 @@ func (m *Addons) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.Padding != nil {
+		{
+			b, err := proto.Marshal(m.Padding)
+			if err != nil {
+				return 0, err
+			}
+			i -= len(b)
+			copy(dAtA[i:], b)
+			i = encodeVarintAddons(dAtA, i, uint64(len(b)))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
 	if len(m.Seed) > 0 {
 		i -= len(m.Seed)
 		copy(dAtA[i:], m.Seed)
@@ -165,6 +190,10 @@ func (m *Addons) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovAddons(uint64(l))
 	}
+	if m.Padding != nil {
+		l = proto.Size(m.Padding)
+		n += 1 + l + sovAddons(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -272,6 +301,42 @@ func (m *Addons) Unmarshal(dAtA []byte) error {
 				m.Seed = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Padding", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAddons
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAddons
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAddons
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Padding == nil {
+				m.Padding = &vless.PaddingScheme{}
+			}
+			if err := proto.Unmarshal(dAtA[iNdEx:postIndex], m.Padding); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAddons(dAtA[iNdEx:])
@@ -7,6 +7,8 @@ package encoding
 import (
 	"io"
 
+	"github.com/golang/protobuf/proto"
+
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
@@ -166,10 +168,11 @@ func DecodeResponseHeader(reader io.Reader, request *protocol.RequestHeader, res
 		return newError("unexpected response version. Expecting ", int(request.Version), " but actually ", int(buffer.Byte(0)))
 	}
 
-	responseAddons, err := DecodeHeaderAddons(&buffer, reader)
+	addons, err := DecodeHeaderAddons(&buffer, reader)
 	if err != nil {
 		return newError("failed to decode response header addons").Base(err)
 	}
+	proto.Merge(responseAddons, addons)
 
 	return nil
 }
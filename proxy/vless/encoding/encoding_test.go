@@ -1,8 +1,11 @@
 package encoding_test
 
 import (
+	"bytes"
+	"crypto/rand"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 
 	"v2ray.com/core/common"
@@ -124,3 +127,148 @@ func TestMuxRequest(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestPaddingSchemeSerialization(t *testing.T) {
+	user := &protocol.MemoryUser{
+		Level: 0,
+		Email: "test@v2fly.org",
+	}
+	id := uuid.New()
+	account := &vless.Account{
+		Id: id.String(),
+	}
+	user.Account = toAccount(account)
+
+	expectedRequest := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandTCP,
+		Address: net.DomainAddress("www.v2fly.org"),
+		Port:    net.Port(443),
+	}
+	expectedAddons := &Addons{
+		Padding: &vless.PaddingScheme{
+			Sizes:        []uint32{512, 1400},
+			DummyPercent: 10,
+			Frames:       8,
+		},
+	}
+
+	buffer := buf.StackNew()
+	common.Must(EncodeRequestHeader(&buffer, expectedRequest, expectedAddons))
+
+	Validator := new(vless.Validator)
+	Validator.Add(user)
+
+	_, actualAddons, err, _ := DecodeRequestHeader(&buffer, Validator)
+	common.Must(err)
+	if !proto.Equal(actualAddons, expectedAddons) {
+		t.Error("unexpected request addons: ", actualAddons)
+	}
+
+	buffer.Clear()
+	common.Must(EncodeResponseHeader(&buffer, expectedRequest, expectedAddons))
+	responseAddons := new(Addons)
+	common.Must(DecodeResponseHeader(&buffer, expectedRequest, responseAddons))
+	if !proto.Equal(responseAddons, expectedAddons) {
+		t.Error("unexpected response addons: ", responseAddons)
+	}
+}
+
+func TestInvalidPaddingScheme(t *testing.T) {
+	user := &protocol.MemoryUser{
+		Level: 0,
+		Email: "test@v2fly.org",
+	}
+	id := uuid.New()
+	account := &vless.Account{
+		Id: id.String(),
+	}
+	user.Account = toAccount(account)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandTCP,
+		Address: net.DomainAddress("www.v2fly.org"),
+		Port:    net.Port(443),
+	}
+	addons := &Addons{
+		Padding: &vless.PaddingScheme{
+			Sizes: []uint32{16},
+		},
+	}
+
+	buffer := buf.StackNew()
+	common.Must(EncodeRequestHeader(&buffer, request, addons))
+
+	Validator := new(vless.Validator)
+	Validator.Add(user)
+
+	if _, _, err, _ := DecodeRequestHeader(&buffer, Validator); err == nil {
+		t.Error("nil error")
+	}
+}
+
+func TestPaddingBody(t *testing.T) {
+	payload := make([]byte, 20000)
+	common.Must2(rand.Read(payload))
+
+	cases := []struct {
+		scheme *vless.PaddingScheme
+		size   int
+	}{
+		{
+			// Content of 2500 bytes takes 3 frames of 996 bytes at most.
+			scheme: &vless.PaddingScheme{Sizes: []uint32{1000}},
+			size:   3000,
+		},
+		{
+			scheme: &vless.PaddingScheme{Sizes: []uint32{1400, 200, 600, 2048}, DummyPercent: 100},
+		},
+		{
+			scheme: &vless.PaddingScheme{Sizes: []uint32{64}, DummyPercent: 50, Frames: 5},
+		},
+	}
+
+	for _, c := range cases {
+		addons := &Addons{Padding: c.scheme}
+		length := 20000
+		if c.size > 0 {
+			length = 2500
+		}
+
+		encoded := new(bytes.Buffer)
+		writer := EncodeBodyAddons(encoded, nil, addons)
+		for written := 0; written < length; {
+			end := written + 1000 + len(c.scheme.Sizes)*700
+			if end > length {
+				end = length
+			}
+			common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, payload[written:end])))
+			written = end
+		}
+		if c.size > 0 && encoded.Len() != c.size {
+			t.Error("unexpected size of padded body: ", encoded.Len())
+		}
+		if encoded.Len() < length {
+			t.Error("padded body is shorter than payload: ", encoded.Len())
+		}
+
+		reader := DecodeBodyAddons(encoded, nil, addons)
+		var decoded []byte
+		for {
+			mb, err := reader.ReadMultiBuffer()
+			if err != nil {
+				break
+			}
+			for _, b := range mb {
+				decoded = append(decoded, b.Bytes()...)
+			}
+			buf.ReleaseMulti(mb)
+		}
+		if r := cmp.Diff(decoded, payload[:length]); r != "" {
+			t.Error(r)
+		}
+	}
+}
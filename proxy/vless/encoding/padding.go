@@ -0,0 +1,154 @@
+// +build !confonly
+
+package encoding
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/proxy/vless"
+)
+
+// paddingWriter sends the body in frames of the target sizes of a padding scheme. Each frame consists of a 2-byte
+// length of content, a 2-byte length of padding, the content and the padding.
+type paddingWriter struct {
+	writer io.Writer
+	raw    buf.Writer
+	scheme *vless.PaddingScheme
+	sizes  []int32
+	frames uint32
+}
+
+func newPaddingWriter(writer io.Writer, scheme *vless.PaddingScheme) *paddingWriter {
+	sizes := make([]int32, 0, len(scheme.Sizes))
+	for _, size := range scheme.Sizes {
+		sizes = append(sizes, int32(size))
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return &paddingWriter{
+		writer: writer,
+		raw:    buf.NewWriter(writer),
+		scheme: scheme,
+		sizes:  sizes,
+	}
+}
+
+func (w *paddingWriter) done() bool {
+	return w.scheme.Frames > 0 && w.frames >= w.scheme.Frames
+}
+
+// writeFrame pads the frame with content to target size, and writes it.
+func (w *paddingWriter) writeFrame(frame *buf.Buffer, target int32) error {
+	content := frame.Len() - vless.PaddingFrameHeaderSize
+	padding := target - frame.Len()
+	if padding < 0 {
+		padding = 0
+	}
+	header := frame.BytesTo(vless.PaddingFrameHeaderSize)
+	binary.BigEndian.PutUint16(header, uint16(content))
+	binary.BigEndian.PutUint16(header[2:], uint16(padding))
+	common.Must2(frame.ReadFullFrom(rand.Reader, padding))
+
+	w.frames++
+	_, err := w.writer.Write(frame.Bytes())
+	return err
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *paddingWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	for !mb.IsEmpty() && !w.done() {
+		frame := buf.New()
+		frame.Extend(vless.PaddingFrameHeaderSize)
+		var n int
+		mb, n = buf.SplitBytes(mb, frame.Extend(w.sizes[len(w.sizes)-1]-vless.PaddingFrameHeaderSize))
+		frame.Resize(0, vless.PaddingFrameHeaderSize+int32(n))
+
+		target := w.sizes[len(w.sizes)-1]
+		for _, size := range w.sizes {
+			if size >= frame.Len() {
+				target = size
+				break
+			}
+		}
+		err := w.writeFrame(frame, target)
+		frame.Release()
+		if err != nil {
+			buf.ReleaseMulti(mb)
+			return err
+		}
+
+		if !w.done() && w.scheme.DummyPercent > 0 && uint32(dice.Roll(100)) < w.scheme.DummyPercent {
+			dummy := buf.New()
+			dummy.Extend(vless.PaddingFrameHeaderSize)
+			err := w.writeFrame(dummy, w.sizes[dice.Roll(len(w.sizes))])
+			dummy.Release()
+			if err != nil {
+				buf.ReleaseMulti(mb)
+				return err
+			}
+		}
+	}
+	if mb.IsEmpty() {
+		return nil
+	}
+	return w.raw.WriteMultiBuffer(mb)
+}
+
+// paddingReader reads the body sent by paddingWriter, and drops the padding.
+type paddingReader struct {
+	reader io.Reader
+	raw    buf.Reader
+	scheme *vless.PaddingScheme
+	frames uint32
+}
+
+func newPaddingReader(reader io.Reader, scheme *vless.PaddingScheme) *paddingReader {
+	return &paddingReader{
+		reader: reader,
+		raw:    buf.NewReader(reader),
+		scheme: scheme,
+	}
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *paddingReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	for {
+		if r.scheme.Frames > 0 && r.frames >= r.scheme.Frames {
+			return r.raw.ReadMultiBuffer()
+		}
+
+		var header [vless.PaddingFrameHeaderSize]byte
+		if _, err := io.ReadFull(r.reader, header[:]); err != nil {
+			return nil, err
+		}
+		content := int32(binary.BigEndian.Uint16(header[:]))
+		padding := int64(binary.BigEndian.Uint16(header[2:]))
+		if vless.PaddingFrameHeaderSize+int64(content)+padding > vless.MaxPaddingSize {
+			return nil, newError("invalid padding frame of ", content, " bytes of content and ", padding, " bytes of padding")
+		}
+		r.frames++
+
+		var mb buf.MultiBuffer
+		if content > 0 {
+			b := buf.New()
+			if _, err := b.ReadFullFrom(r.reader, content); err != nil {
+				b.Release()
+				return nil, err
+			}
+			mb = buf.MultiBuffer{b}
+		}
+		if _, err := io.CopyN(ioutil.Discard, r.reader, padding); err != nil {
+			buf.ReleaseMulti(mb)
+			return nil, err
+		}
+		if !mb.IsEmpty() {
+			return mb, nil
+		}
+	}
+}
@@ -417,7 +417,8 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
 		responseAddons := &encoding.Addons{
-			Flow: requestAddons.Flow,
+			Flow:    requestAddons.Flow,
+			Padding: requestAddons.Padding,
 		}
 
 		bufferWriter := buf.NewBufferedWriter(buf.NewWriter(connection))
@@ -105,7 +105,8 @@ func (v *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	account := request.User.Account.(*vless.MemoryAccount)
 
 	requestAddons := &encoding.Addons{
-		Flow: account.Flow,
+		Flow:    account.Flow,
+		Padding: account.Padding,
 	}
 
 	sessionPolicy := v.policyManager.ForLevel(request.User.Level)
@@ -156,6 +157,9 @@ func (v *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		if err := encoding.DecodeResponseHeader(conn, request, responseAddons); err != nil {
 			return newError("failed to decode response header").Base(err).AtWarning()
 		}
+		if requestAddons.Padding != nil && responseAddons.Padding == nil {
+			return newError("server doesn't accept padding scheme").AtWarning()
+		}
 
 		// default: serverReader := buf.NewReader(conn)
 		serverReader := encoding.DecodeBodyAddons(conn, request, responseAddons)
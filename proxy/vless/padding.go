@@ -0,0 +1,35 @@
+package vless
+
+import (
+	"v2ray.com/core/common/buf"
+)
+
+const (
+	// PaddingFrameHeaderSize is the size of the header of a padding frame, which holds the length of content and the
+	// length of padding.
+	PaddingFrameHeaderSize = 4
+	// MinPaddingSize and MaxPaddingSize bound the target sizes of padding frames. A frame fits in one buffer.
+	MinPaddingSize  = 64
+	MaxPaddingSize  = buf.Size
+	maxPaddingSizes = 16
+)
+
+// Validate returns an error if the scheme can't be applied. A scheme from a client is validated before the server
+// accepts it.
+func (s *PaddingScheme) Validate() error {
+	if len(s.Sizes) == 0 {
+		return newError("no target size")
+	}
+	if len(s.Sizes) > maxPaddingSizes {
+		return newError("too many target sizes: ", len(s.Sizes))
+	}
+	for _, size := range s.Sizes {
+		if size < MinPaddingSize || size > MaxPaddingSize {
+			return newError("target size ", size, " is out of range [", MinPaddingSize, ", ", MaxPaddingSize, "]")
+		}
+	}
+	if s.DummyPercent > 100 {
+		return newError("dummy percent ", s.DummyPercent, " is larger than 100")
+	}
+	return nil
+}
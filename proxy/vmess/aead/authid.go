@@ -60,12 +60,18 @@ func (aidd *AuthIDDecoder) Decode(data [16]byte) (int64, uint32, int32, []byte)
 }
 
 func NewAuthIDDecoderHolder() *AuthIDDecoderHolder {
-	return &AuthIDDecoderHolder{make(map[string]*AuthIDDecoderItem), antiReplayWindow.NewAntiReplayWindow(120)}
+	return &AuthIDDecoderHolder{make(map[string]*AuthIDDecoderItem), antiReplayWindow.NewAntiReplayWindow(120), time.Now}
 }
 
 type AuthIDDecoderHolder struct {
 	aidhi map[string]*AuthIDDecoderItem
 	apw   *antiReplayWindow.AntiReplayWindow
+	now   func() time.Time
+}
+
+// SetClock replaces the clock that timestamps in AuthIDs are checked against, for replaying recorded requests.
+func (a *AuthIDDecoderHolder) SetClock(now func() time.Time) {
+	a.now = now
 }
 
 type AuthIDDecoderItem struct {
@@ -100,7 +106,7 @@ func (a *AuthIDDecoderHolder) Match(AuthID [16]byte) (interface{}, error) {
 			continue
 		}
 
-		if math.Abs(math.Abs(float64(t))-float64(time.Now().Unix())) > 120 {
+		if math.Abs(math.Abs(float64(t))-float64(a.now().Unix())) > 120 {
 			continue
 		}
 
@@ -25,6 +25,7 @@ import (
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/serial"
 	"v2ray.com/core/proxy/vmess"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 func hashTimestamp(h hash.Hash, t protocol.Timestamp) []byte {
@@ -46,6 +47,8 @@ type ClientSession struct {
 	responseHeader  byte
 
 	isAEADRequest bool
+
+	trace *trace.Session
 }
 
 // NewClientSession creates a new ClientSession.
@@ -83,6 +86,7 @@ func NewClientSession(idHash protocol.IDHash, ctx context.Context) *ClientSessio
 	}
 
 	session.idHash = idHash
+	session.trace = trace.SessionFromContext(ctx)
 
 	return session
 }
@@ -151,6 +155,8 @@ func (c *ClientSession) EncodeRequestBody(request *protocol.RequestHeader, write
 		padding = sizeParser.(crypto.PaddingLengthGenerator)
 	}
 
+	sizeParser = traceSizeEncoder(c.trace, sizeParser)
+
 	switch request.Security {
 	case protocol.SecurityType_NONE:
 		if request.Option.Has(protocol.RequestOptionChunkStream) {
@@ -291,6 +297,8 @@ func (c *ClientSession) DecodeResponseBody(request *protocol.RequestHeader, read
 		padding = sizeParser.(crypto.PaddingLengthGenerator)
 	}
 
+	sizeParser = traceSizeDecoder(c.trace, sizeParser)
+
 	switch request.Security {
 	case protocol.SecurityType_NONE:
 		if request.Option.Has(protocol.RequestOptionChunkStream) {
@@ -24,6 +24,7 @@ import (
 	"v2ray.com/core/common/task"
 	"v2ray.com/core/proxy/vmess"
 	vmessaead "v2ray.com/core/proxy/vmess/aead"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 type sessionId struct {
@@ -107,6 +108,8 @@ type ServerSession struct {
 	isAEADRequest bool
 
 	isAEADForced bool
+
+	trace *trace.Session
 }
 
 // NewServerSession creates a new ServerSession, using the given UserValidator.
@@ -118,6 +121,11 @@ func NewServerSession(validator *vmess.TimedUserValidator, sessionHistory *Sessi
 	}
 }
 
+// SetTraceSession sets the session that chunks of the request and response bodies are recorded into.
+func (s *ServerSession) SetTraceSession(session *trace.Session) {
+	s.trace = session
+}
+
 func parseSecurityType(b byte) protocol.SecurityType {
 	if _, f := protocol.SecurityType_name[int32(b)]; f {
 		st := protocol.SecurityType(b)
@@ -313,6 +321,8 @@ func (s *ServerSession) DecodeRequestBody(request *protocol.RequestHeader, reade
 		padding = sizeParser.(crypto.PaddingLengthGenerator)
 	}
 
+	sizeParser = traceSizeDecoder(s.trace, sizeParser)
+
 	switch request.Security {
 	case protocol.SecurityType_NONE:
 		if request.Option.Has(protocol.RequestOptionChunkStream) {
@@ -433,6 +443,8 @@ func (s *ServerSession) EncodeResponseBody(request *protocol.RequestHeader, writ
 		padding = sizeParser.(crypto.PaddingLengthGenerator)
 	}
 
+	sizeParser = traceSizeEncoder(s.trace, sizeParser)
+
 	switch request.Security {
 	case protocol.SecurityType_NONE:
 		if request.Option.Has(protocol.RequestOptionChunkStream) {
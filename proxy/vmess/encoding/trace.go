@@ -0,0 +1,45 @@
+package encoding
+
+import (
+	"v2ray.com/core/common/crypto"
+	"v2ray.com/core/transport/internet/trace"
+)
+
+// tracingSizeEncoder records the size of each chunk written into the body.
+type tracingSizeEncoder struct {
+	crypto.ChunkSizeEncoder
+	session *trace.Session
+}
+
+func (e *tracingSizeEncoder) Encode(size uint16, b []byte) []byte {
+	e.session.Record("vmess", trace.Send, "chunk", map[string]interface{}{"size": size})
+	return e.ChunkSizeEncoder.Encode(size, b)
+}
+
+// tracingSizeDecoder records the size of each chunk read from the body.
+type tracingSizeDecoder struct {
+	crypto.ChunkSizeDecoder
+	session *trace.Session
+}
+
+func (d *tracingSizeDecoder) Decode(b []byte) (uint16, error) {
+	size, err := d.ChunkSizeDecoder.Decode(b)
+	if err == nil {
+		d.session.Record("vmess", trace.Receive, "chunk", map[string]interface{}{"size": size})
+	}
+	return size, err
+}
+
+func traceSizeEncoder(s *trace.Session, encoder crypto.ChunkSizeEncoder) crypto.ChunkSizeEncoder {
+	if s == nil {
+		return encoder
+	}
+	return &tracingSizeEncoder{ChunkSizeEncoder: encoder, session: s}
+}
+
+func traceSizeDecoder(s *trace.Session, decoder crypto.ChunkSizeDecoder) crypto.ChunkSizeDecoder {
+	if s == nil {
+		return decoder
+	}
+	return &tracingSizeDecoder{ChunkSizeDecoder: decoder, session: s}
+}
@@ -43,6 +43,8 @@ type TimedUserValidator struct {
 	behaviorFused bool
 
 	aeadDecoderHolder *aead.AuthIDDecoderHolder
+
+	now func() time.Time
 }
 
 type indexTimePair struct {
@@ -52,15 +54,22 @@ type indexTimePair struct {
 	taintedFuse *uint32
 }
 
-// NewTimedUserValidator creates a new TimedUserValidator.
-func NewTimedUserValidator(hasher protocol.IDHash) *TimedUserValidator {
+func newTimedUserValidator(hasher protocol.IDHash, now func() time.Time) *TimedUserValidator {
 	tuv := &TimedUserValidator{
 		users:             make([]*user, 0, 16),
 		userHash:          make(map[[16]byte]indexTimePair, 1024),
 		hasher:            hasher,
-		baseTime:          protocol.Timestamp(time.Now().Unix() - cacheDurationSec*2),
+		baseTime:          protocol.Timestamp(now().Unix() - cacheDurationSec*2),
 		aeadDecoderHolder: aead.NewAuthIDDecoderHolder(),
+		now:               now,
 	}
+	tuv.aeadDecoderHolder.SetClock(now)
+	return tuv
+}
+
+// NewTimedUserValidator creates a new TimedUserValidator.
+func NewTimedUserValidator(hasher protocol.IDHash) *TimedUserValidator {
+	tuv := newTimedUserValidator(hasher, time.Now)
 	tuv.task = &task.Periodic{
 		Interval: updateInterval,
 		Execute: func() error {
@@ -72,6 +81,14 @@ func NewTimedUserValidator(hasher protocol.IDHash) *TimedUserValidator {
 	return tuv
 }
 
+// NewTimedUserValidatorAt creates a TimedUserValidator that takes t as the current time, for replaying requests recorded
+// at t. Its user hashes are not updated over time.
+func NewTimedUserValidatorAt(hasher protocol.IDHash, t time.Time) *TimedUserValidator {
+	return newTimedUserValidator(hasher, func() time.Time {
+		return t
+	})
+}
+
 func (v *TimedUserValidator) generateNewHashes(nowSec protocol.Timestamp, user *user) {
 	var hashValue [16]byte
 	genEndSec := nowSec + cacheDurationSec
@@ -112,7 +129,7 @@ func (v *TimedUserValidator) removeExpiredHashes(expire uint32) {
 }
 
 func (v *TimedUserValidator) updateUserHash() {
-	now := time.Now()
+	now := v.now()
 	nowSec := protocol.Timestamp(now.Unix())
 	v.Lock()
 	defer v.Unlock()
@@ -131,7 +148,7 @@ func (v *TimedUserValidator) Add(u *protocol.MemoryUser) error {
 	v.Lock()
 	defer v.Unlock()
 
-	nowSec := time.Now().Unix()
+	nowSec := v.now().Unix()
 
 	uu := &user{
 		user:    *u,
@@ -214,6 +231,9 @@ func (v *TimedUserValidator) Remove(email string) bool {
 
 // Close implements common.Closable.
 func (v *TimedUserValidator) Close() error {
+	if v.task == nil {
+		return nil
+	}
 	return v.task.Close()
 }
 
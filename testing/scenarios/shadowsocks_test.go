@@ -826,3 +826,128 @@ func TestShadowsocksNone(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestShadowsocks2022MultipleUsers(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	udpDest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&shadowsocks.ServerConfig{
+					User: &protocol.User{
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							Password:   "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=",
+							CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM,
+						}),
+					},
+					Users: []*protocol.User{
+						{
+							Email: "a@v2fly.org",
+							Account: serial.ToTypedMessage(&shadowsocks.Account{
+								Password:   "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=",
+								CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM,
+							}),
+						},
+						{
+							Email: "b@v2fly.org",
+							Account: serial.ToTypedMessage(&shadowsocks.Account{
+								Password:   "QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8=",
+								CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM,
+							}),
+						},
+					},
+					Network: []net.Network{net.Network_TCP, net.Network_UDP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientOutbound := serial.ToTypedMessage(&shadowsocks.ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: net.NewIPOrDomain(net.LocalHostIP),
+				Port:    uint32(serverPort),
+				User: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							Password:   "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=:QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8=",
+							CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM,
+						}),
+					},
+				},
+			},
+		},
+	})
+	tcpPort := tcp.PickPort()
+	udpPort := udp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(tcpPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(tcpDest.Address),
+					Port:    uint32(tcpDest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(udpPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(udpDest.Address),
+					Port:    uint32(udpDest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_UDP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: clientOutbound,
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	var errg errgroup.Group
+	for i := 0; i < 10; i++ {
+		errg.Go(testTCPConn(tcpPort, 10240*1024, time.Second*20))
+		errg.Go(testUDPConn(udpPort, 1024, time.Second*5))
+	}
+	if err := errg.Wait(); err != nil {
+		t.Error(err)
+	}
+}
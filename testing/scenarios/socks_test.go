@@ -1,9 +1,11 @@
 package scenarios
 
 import (
+	"crypto/rand"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	xproxy "golang.org/x/net/proxy"
 	socks4 "h12.io/socks"
 
@@ -11,6 +13,7 @@ import (
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/router"
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/serial"
@@ -271,6 +274,108 @@ func TestSocksBridageUDPWithRouting(t *testing.T) {
 	}
 }
 
+func TestSocksUDPAssociate(t *testing.T) {
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{
+					AuthType:   socks.AuthType_NO_AUTH,
+					UdpEnabled: true,
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	request := &protocol.RequestHeader{
+		Version: 5,
+		Command: protocol.RequestCommandUDP,
+		Address: dest.Address,
+		Port:    dest.Port,
+	}
+	exchange := func(conn net.Conn, payload []byte) ([]byte, error) {
+		packet, err := socks.EncodeUDPPacket(request, payload)
+		common.Must(err)
+		defer packet.Release()
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			return nil, err
+		}
+
+		response := buf.New()
+		defer response.Release()
+		common.Must(conn.SetReadDeadline(time.Now().Add(time.Second * 2)))
+		if _, err := response.ReadFrom(conn); err != nil {
+			return nil, err
+		}
+		if _, err := socks.DecodeUDPPacket(response); err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), response.Bytes()...), nil
+	}
+
+	// Packets are dropped without an association.
+	relay, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: []byte{127, 0, 0, 1}, Port: int(serverPort)})
+	common.Must(err)
+	if _, err := exchange(relay, []byte("no association")); err == nil {
+		t.Error("expected no response without association")
+	}
+	relay.Close()
+
+	control, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, serverPort).NetAddr())
+	common.Must(err)
+	defer control.Close()
+	relayRequest, err := socks.ClientHandshake(&protocol.RequestHeader{
+		Version: 5,
+		Command: protocol.RequestCommandUDP,
+		Address: net.AnyIP,
+		Port:    0,
+	}, control, control)
+	common.Must(err)
+	if relayRequest.Destination() != net.UDPDestination(net.LocalHostIP, serverPort) {
+		t.Fatal("unexpected relay: ", relayRequest.Destination())
+	}
+
+	relay, err = net.DialUDP("udp", nil, &net.UDPAddr{IP: []byte{127, 0, 0, 1}, Port: int(serverPort)})
+	common.Must(err)
+	defer relay.Close()
+	for i := 0; i < 3; i++ {
+		payload := make([]byte, 1024)
+		rand.Read(payload)
+		response, err := exchange(relay, payload)
+		common.Must(err)
+		if r := cmp.Diff(response, xor(payload)); r != "" {
+			t.Error(r)
+		}
+	}
+
+	// The association ends with the TCP connection.
+	control.Close()
+	time.Sleep(time.Millisecond * 500)
+	if _, err := exchange(relay, []byte("closed association")); err == nil {
+		t.Error("expected no response after the TCP connection closes")
+	}
+}
+
 func TestSocksConformanceMod(t *testing.T) {
 	tcpServer := tcp.Server{
 		MsgProcessor: xor,
@@ -368,4 +473,23 @@ func TestSocksConformanceMod(t *testing.T) {
 			t.Error(err)
 		}
 	}
+
+	{
+		dialer := socks4.Dial("socks4a://" + net.TCPDestination(net.LocalHostIP, noAuthPort).NetAddr())
+		conn, err := dialer("tcp", net.TCPDestination(net.DomainAddress("localhost"), tcpServer.Port).NetAddr())
+		common.Must(err)
+		defer conn.Close()
+
+		if err := testTCPConn2(conn, 1024, time.Second*5)(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	{
+		dialer := socks4.Dial("socks4://" + net.TCPDestination(net.LocalHostIP, authPort).NetAddr())
+		if conn, err := dialer("tcp", dest.NetAddr()); err == nil {
+			conn.Close()
+			t.Error("expected SOCKS 4 to be rejected when auth is required")
+		}
+	}
 }
@@ -29,6 +29,7 @@ import (
 	"v2ray.com/core/transport/internet/headers/wechat"
 	"v2ray.com/core/transport/internet/quic"
 	tcptransport "v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/websocket"
 )
 
 func TestHttpConnectionHeader(t *testing.T) {
@@ -383,3 +384,108 @@ func TestVMessQuic(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDialerProxy(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	streamSettings := &internet.StreamConfig{
+		ProtocolName: "websocket",
+		TransportSettings: []*internet.TransportConfig{
+			{
+				ProtocolName: "websocket",
+				Settings:     serial.ToTypedMessage(&websocket.Config{}),
+			},
+		},
+	}
+
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange:      net.SinglePortRange(serverPort),
+					Listen:         net.NewIPOrDomain(net.LocalHostIP),
+					StreamSettings: streamSettings,
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	// The WebSocket server is only reachable through the relay, which redirects all connections to it.
+	unreachablePort := tcp.PickPort()
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(clientPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{
+					DestinationOverride: &freedom.DestinationOverride{
+						Server: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(unreachablePort),
+						},
+					},
+				}),
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: &internet.StreamConfig{
+						ProtocolName:      streamSettings.ProtocolName,
+						TransportSettings: streamSettings.TransportSettings,
+						SocketSettings: &internet.SocketConfig{
+							DialerProxy: "relay",
+						},
+					},
+				}),
+			},
+			{
+				Tag: "relay",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{
+					DestinationOverride: &freedom.DestinationOverride{
+						Server: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	if err := testTCPConn(clientPort, 10240, time.Second*5)(); err != nil {
+		t.Error(err)
+	}
+}
@@ -21,6 +21,7 @@ type dialFunc func(ctx context.Context, dest net.Destination, streamSettings *Me
 
 var (
 	transportDialerCache = make(map[string]dialFunc)
+	connectionPools      []func()
 )
 
 // RegisterTransportDialer registers a Dialer with given name.
@@ -32,6 +33,19 @@ func RegisterTransportDialer(protocol string, dialer dialFunc) error {
 	return nil
 }
 
+// RegisterConnectionPool registers a function to close the connections pooled by a transport.
+func RegisterConnectionPool(reset func()) {
+	connectionPools = append(connectionPools, reset)
+}
+
+// ResetConnectionPools closes the connections pooled by all transports, so that new connections are dialed on the
+// current network. It is usually called when the network has changed.
+func ResetConnectionPools() {
+	for _, reset := range connectionPools {
+		reset()
+	}
+}
+
 // Dial dials a internet connection towards the given destination.
 func Dial(ctx context.Context, dest net.Destination, streamSettings *MemoryStreamConfig) (Connection, error) {
 	if dest.Network == net.Network_TCP {
@@ -64,6 +78,10 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *MemoryStrea
 
 // DialSystem calls system dialer to create a network connection.
 func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	if sockopt != nil && len(sockopt.DialerProxy) > 0 {
+		return dialThroughProxy(ctx, dest, sockopt)
+	}
+
 	var src net.Address
 	if outbound := session.OutboundFromContext(ctx); outbound != nil {
 		src = outbound.Gateway
@@ -0,0 +1,37 @@
+package internet
+
+import (
+	"context"
+
+	"v2ray.com/core/common/net"
+)
+
+// ProxyDialer dials a connection to dest through the outbound handler with the given tag.
+type ProxyDialer func(ctx context.Context, tag string, dest net.Destination) (net.Conn, error)
+
+type proxyDialerKey int
+
+const proxyDialerContextKey proxyDialerKey = iota
+
+// ContextWithProxyDialer returns a new context with the ProxyDialer, which is used by DialSystem when the socket
+// settings have a dialer proxy.
+func ContextWithProxyDialer(ctx context.Context, dialer ProxyDialer) context.Context {
+	return context.WithValue(ctx, proxyDialerContextKey, dialer)
+}
+
+// ProxyDialerFromContext returns the ProxyDialer in ctx, or nil if there is none.
+func ProxyDialerFromContext(ctx context.Context) ProxyDialer {
+	if dialer, ok := ctx.Value(proxyDialerContextKey).(ProxyDialer); ok {
+		return dialer
+	}
+	return nil
+}
+
+// dialThroughProxy dials dest through the outbound handler named in sockopt.
+func dialThroughProxy(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	dialer := ProxyDialerFromContext(ctx)
+	if dialer == nil {
+		return nil, newError("dialer proxy ", sockopt.DialerProxy, " is not available for ", dest).AtWarning()
+	}
+	return dialer(ctx, sockopt.DialerProxy, dest)
+}
@@ -68,6 +68,10 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 			unixListener.Close()
 			return nil, err
 		}
+		if err := internet.ApplyUnixSocketOptions(settings.Path, streamSettings.SocketSettings); err != nil {
+			ln.Close()
+			return nil, err
+		}
 	}
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
@@ -4,6 +4,7 @@ package domainsocket_test
 
 import (
 	"context"
+	"os"
 	"runtime"
 	"testing"
 
@@ -90,3 +91,27 @@ func TestListenAbstract(t *testing.T) {
 		t.Error("expected response as 'RequestResponse' but got ", b.String())
 	}
 }
+
+func TestListenWithMode(t *testing.T) {
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path: "/tmp/ts4",
+		},
+		SocketSettings: &internet.SocketConfig{
+			UnixSocketMode: 0600,
+		},
+	}
+	listener, err := Listen(ctx, nil, net.Port(0), streamSettings, func(conn internet.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	info, err := os.Stat("/tmp/ts4")
+	common.Must(err)
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Error("mode: ", mode)
+	}
+}
@@ -0,0 +1,134 @@
+package internet
+
+import (
+	"context"
+	"time"
+
+	"v2ray.com/core/common/net"
+)
+
+const defaultTryDelay = time.Millisecond * 250
+
+// sortAddresses orders the addresses for connection attempts, as in section 4 of RFC 8305. The first firstFamilyCount
+// addresses are of the preferred family, followed by the remaining addresses of both families interleaved.
+func sortAddresses(ips []net.IP, preferIPv4 bool, firstFamilyCount int) []net.IP {
+	var preferred, other []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == preferIPv4 {
+			preferred = append(preferred, ip)
+		} else {
+			other = append(other, ip)
+		}
+	}
+	if firstFamilyCount < 1 {
+		firstFamilyCount = 1
+	}
+
+	sorted := make([]net.IP, 0, len(ips))
+	for len(preferred) > 0 && firstFamilyCount > 0 {
+		sorted = append(sorted, preferred[0])
+		preferred = preferred[1:]
+		firstFamilyCount--
+	}
+	for len(preferred) > 0 || len(other) > 0 {
+		if len(other) > 0 {
+			sorted = append(sorted, other[0])
+			other = other[1:]
+		}
+		if len(preferred) > 0 {
+			sorted = append(sorted, preferred[0])
+			preferred = preferred[1:]
+		}
+	}
+	return sorted
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialParallel dials the addresses in order, starting each attempt after the previous one fails or after delay,
+// whichever comes first. It returns the first connection established, and closes the others.
+func dialParallel(ctx context.Context, ips []net.IP, delay time.Duration, dial func(ctx context.Context, ip net.IP) (net.Conn, error)) (net.Conn, error) {
+	if len(ips) == 0 {
+		return nil, newError("no address to dial")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult)
+	started := 0
+	start := func() {
+		ip := ips[started]
+		started++
+		go func() {
+			conn, err := dial(ctx, ip)
+			select {
+			case results <- dialResult{conn: conn, err: err}:
+			case <-ctx.Done():
+				if conn != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	start()
+	pending := 1
+	var firstErr error
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			if started < len(ips) {
+				start()
+				pending++
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if started < len(ips) {
+				start()
+				pending++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, firstErr
+}
+
+// dialHappyEyeballs resolves the domain of dest, and races connections to its addresses.
+func dialHappyEyeballs(ctx context.Context, dialer *net.Dialer, dest net.Destination, config *SocketConfig_HappyEyeballs) (net.Conn, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, dest.Address.Domain())
+	if err != nil {
+		return nil, newError("failed to resolve ", dest.Address).Base(err)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+
+	delay := defaultTryDelay
+	if config.TryDelay > 0 {
+		delay = time.Duration(config.TryDelay) * time.Millisecond
+	}
+	ips = sortAddresses(ips, config.PreferIpv4, int(config.FirstFamilyCount))
+	return dialParallel(ctx, ips, delay, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+		return dialer.DialContext(ctx, dest.Network.SystemString(), net.TCPDestination(net.IPAddress(ip), dest.Port).NetAddr())
+	})
+}
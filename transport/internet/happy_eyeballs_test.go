@@ -0,0 +1,93 @@
+package internet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common/net"
+)
+
+func TestSortAddresses(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("1.1.1.1"),
+		net.ParseIP("2.2.2.2"),
+		net.ParseIP("3.3.3.3"),
+		net.ParseIP("::1"),
+		net.ParseIP("::2"),
+	}
+	cases := []struct {
+		preferIPv4       bool
+		firstFamilyCount int
+		expected         []string
+	}{
+		{false, 0, []string{"::1", "1.1.1.1", "::2", "2.2.2.2", "3.3.3.3"}},
+		{true, 1, []string{"1.1.1.1", "::1", "2.2.2.2", "::2", "3.3.3.3"}},
+		{true, 2, []string{"1.1.1.1", "2.2.2.2", "::1", "3.3.3.3", "::2"}},
+	}
+	for _, c := range cases {
+		var sorted []string
+		for _, ip := range sortAddresses(ips, c.preferIPv4, c.firstFamilyCount) {
+			sorted = append(sorted, ip.String())
+		}
+		if r := cmp.Diff(sorted, c.expected); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+type fakeConn struct {
+	net.Conn
+	ip net.IP
+}
+
+func TestDialParallel(t *testing.T) {
+	ips := []net.IP{net.ParseIP("::1"), net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")}
+
+	// The first address hangs, and the second one connects after the delay.
+	start := time.Now()
+	conn, err := dialParallel(context.Background(), ips, time.Millisecond*100, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+		if ip.Equal(ips[0]) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &fakeConn{ip: ip}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := conn.(*fakeConn).ip; !ip.Equal(ips[1]) {
+		t.Error("connected to ", ip)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*100 {
+		t.Error("next attempt started after ", elapsed)
+	}
+
+	// A failed attempt starts the next one without waiting for the delay.
+	start = time.Now()
+	conn, err = dialParallel(context.Background(), ips, time.Second*10, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+		if !ip.Equal(ips[2]) {
+			return nil, newError("refused")
+		}
+		return &fakeConn{ip: ip}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := conn.(*fakeConn).ip; !ip.Equal(ips[2]) {
+		t.Error("connected to ", ip)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("connected after ", elapsed)
+	}
+
+	// The first error is returned when all attempts fail.
+	if _, err := dialParallel(context.Background(), ips, time.Millisecond*10, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+		return nil, newError("refused ", ip)
+	}); err == nil || !strings.HasSuffix(err.Error(), "refused ::1") {
+		t.Error("error: ", err)
+	}
+}
@@ -84,6 +84,17 @@ func getHTTPClient(ctx context.Context, dest net.Destination, tlsSettings *tls.C
 	return client, nil
 }
 
+// resetHTTPClients drops all cached clients, and closes their idle connections.
+func resetHTTPClients() {
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+
+	for _, client := range globalDialerMap {
+		client.CloseIdleConnections()
+	}
+	globalDialerMap = nil
+}
+
 // Dial dials a new TCP connection to the given destination.
 func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
 	httpSettings := streamSettings.ProtocolSettings.(*Config)
@@ -135,4 +146,5 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 
 func init() {
 	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
+	internet.RegisterConnectionPool(resetHTTPClients)
 }
@@ -0,0 +1,41 @@
+// +build !confonly
+
+package httpupgrade
+
+import (
+	"bufio"
+
+	"v2ray.com/core/common/net"
+)
+
+// connection is a connection after the upgrade, whose data read along with the handshake is read first.
+type connection struct {
+	net.Conn
+	reader     *bufio.Reader
+	remoteAddr net.Addr
+}
+
+func newConnection(conn net.Conn, reader *bufio.Reader, remoteAddr net.Addr) net.Conn {
+	if reader.Buffered() == 0 && remoteAddr == conn.RemoteAddr() {
+		return conn
+	}
+	return &connection{
+		Conn:       conn,
+		reader:     reader,
+		remoteAddr: remoteAddr,
+	}
+}
+
+func (c *connection) Read(b []byte) (int, error) {
+	if c.reader != nil {
+		if c.reader.Buffered() > 0 {
+			return c.reader.Read(b)
+		}
+		c.reader = nil
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *connection) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
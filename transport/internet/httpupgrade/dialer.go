@@ -0,0 +1,93 @@
+// +build !confonly
+
+package httpupgrade
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/tls"
+)
+
+const handshakeTimeout = time.Second * 8
+
+// Dial dials an HTTP Upgrade connection to the given destination.
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
+	newError("creating connection to ", dest).WriteToLog(session.ExportIDToError(ctx))
+
+	conn, err := dialHTTPUpgrade(ctx, dest, streamSettings)
+	if err != nil {
+		return nil, newError("failed to dial HTTP Upgrade").Base(err)
+	}
+	return internet.Connection(conn), nil
+}
+
+func init() {
+	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
+}
+
+func dialHTTPUpgrade(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+
+	conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if tlsConfig := tls.ConfigFromStreamSettings(streamSettings); tlsConfig != nil {
+		scheme = "https"
+		conn = tls.Client(conn, tlsConfig.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1")))
+	}
+
+	host := dest.NetAddr()
+	if (scheme == "http" && dest.Port == 80) || (scheme == "https" && dest.Port == 443) {
+		host = dest.Address.String()
+	}
+	if config.Host != "" {
+		host = config.Host
+	}
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Scheme: scheme, Host: host, Path: config.GetNormalizedPath()},
+		Host:       host,
+		Header:     config.GetRequestHeader(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+
+	if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, newError("failed to write upgrade request").Base(err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		conn.Close()
+		return nil, newError("failed to read upgrade response").Base(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") {
+		conn.Close()
+		return nil, newError("unexpected upgrade response: ", response.Status)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newConnection(conn, reader, conn.RemoteAddr()), nil
+}
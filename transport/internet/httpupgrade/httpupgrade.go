@@ -0,0 +1,8 @@
+/*Package httpupgrade implements HTTP Upgrade transport
+
+HTTP Upgrade transport starts a connection with an HTTP/1.1 Upgrade handshake, same as WebSocket, and sends raw data
+afterwards without WebSocket framing. It passes through reverse proxies that forward WebSocket.
+*/
+package httpupgrade
+
+//go:generate errorgen
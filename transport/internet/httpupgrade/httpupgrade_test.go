@@ -0,0 +1,100 @@
+package httpupgrade_test
+
+import (
+	"context"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol/tls/cert"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/httpupgrade"
+	"v2ray.com/core/transport/internet/tls"
+)
+
+func listenEcho(port net.Port, streamSettings *internet.MemoryStreamConfig) internet.Listener {
+	listen, err := ListenHTTPUpgrade(context.Background(), net.LocalHostIP, port, streamSettings, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			n, err := c.Read(b[:])
+			if err != nil {
+				return
+			}
+			common.Must2(c.Write(append([]byte("Response: "), b[:n]...)))
+		}(conn)
+	})
+	common.Must(err)
+	return listen
+}
+
+func dialEcho(t *testing.T, port net.Port, streamSettings *internet.MemoryStreamConfig) {
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), port), streamSettings)
+	common.Must(err)
+	defer conn.Close()
+
+	common.Must2(conn.Write([]byte("Test")))
+	var b [1024]byte
+	n, err := conn.Read(b[:])
+	common.Must(err)
+	if string(b[:n]) != "Response: Test" {
+		t.Error("response: ", string(b[:n]))
+	}
+}
+
+func TestDial(t *testing.T) {
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "httpupgrade",
+		ProtocolSettings: &Config{
+			Path: "up",
+			Host: "www.v2ray.com",
+		},
+	}
+	listen := listenEcho(13151, streamSettings)
+	defer listen.Close()
+
+	dialEcho(t, 13151, streamSettings)
+
+	_, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), 13151), &internet.MemoryStreamConfig{
+		ProtocolName: "httpupgrade",
+		ProtocolSettings: &Config{
+			Path: "down",
+			Host: "www.v2ray.com",
+		},
+	})
+	if err == nil {
+		t.Error("dialed to wrong path")
+	}
+
+	_, err = Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), 13151), &internet.MemoryStreamConfig{
+		ProtocolName: "httpupgrade",
+		ProtocolSettings: &Config{
+			Path: "up",
+		},
+	})
+	if err == nil {
+		t.Error("dialed to wrong host")
+	}
+}
+
+func TestDialWithTLS(t *testing.T) {
+	listen := listenEcho(13152, &internet.MemoryStreamConfig{
+		ProtocolName:     "httpupgrade",
+		ProtocolSettings: &Config{},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			Certificate: []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+		},
+	})
+	defer listen.Close()
+
+	dialEcho(t, 13152, &internet.MemoryStreamConfig{
+		ProtocolName:     "httpupgrade",
+		ProtocolSettings: &Config{},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			AllowInsecure: true,
+		},
+	})
+}
@@ -0,0 +1,131 @@
+// +build !confonly
+
+package httpupgrade
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	http_proto "v2ray.com/core/common/protocol/http"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/transport/internet"
+	v2tls "v2ray.com/core/transport/internet/tls"
+)
+
+type requestHandler struct {
+	path   string
+	config *Config
+	ln     *Listener
+}
+
+func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != h.path || !strings.EqualFold(request.Header.Get("Upgrade"), "websocket") {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !h.config.IsHostAllowed(request.Host) {
+		newError("rejected request to unexpected host: ", request.Host).AtInfo().WriteToLog()
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		newError("failed to hijack HTTP connection").Base(err).WriteToLog()
+		return
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")); err != nil {
+		newError("failed to write upgrade response").Base(err).WriteToLog()
+		conn.Close()
+		return
+	}
+
+	remoteAddr := conn.RemoteAddr()
+	forwardedAddrs := http_proto.ParseXForwardedFor(request.Header)
+	if tcpAddr, ok := remoteAddr.(*net.TCPAddr); ok && len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
+		remoteAddr = &net.TCPAddr{
+			IP:   forwardedAddrs[0].IP(),
+			Port: tcpAddr.Port,
+		}
+	}
+
+	h.ln.addConn(internet.Connection(newConnection(conn, rw.Reader, remoteAddr)))
+}
+
+type Listener struct {
+	server   http.Server
+	listener net.Listener
+	addConn  internet.ConnHandler
+}
+
+// ListenHTTPUpgrade listens for HTTP Upgrade connections.
+func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
+	listener, err := internet.ListenSystem(ctx, internet.StreamListenAddr(address, port), streamSettings.SocketSettings)
+	if err != nil {
+		return nil, newError("failed to listen TCP(for HTTP Upgrade) on", address, ":", port).Base(err)
+	}
+	newError("listening TCP(for HTTP Upgrade) on ", address, ":", port).WriteToLog(session.ExportIDToError(ctx))
+
+	config := streamSettings.ProtocolSettings.(*Config)
+
+	if config.AcceptProxyProtocol {
+		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return proxyproto.REQUIRE, nil }
+		listener = &proxyproto.Listener{Listener: listener, Policy: policyFunc}
+		newError("accepting PROXY protocol").AtWarning().WriteToLog(session.ExportIDToError(ctx))
+	}
+
+	if tlsConfig := v2tls.ConfigFromStreamSettings(streamSettings); tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig.GetTLSConfig(v2tls.WithNextProto("http/1.1")))
+	}
+
+	l := &Listener{
+		addConn:  addConn,
+		listener: listener,
+	}
+	l.server = http.Server{
+		Handler: &requestHandler{
+			path:   config.GetNormalizedPath(),
+			config: config,
+			ln:     l,
+		},
+		ReadHeaderTimeout: time.Second * 4,
+		MaxHeaderBytes:    2048,
+	}
+
+	go func() {
+		if err := l.server.Serve(l.listener); err != nil {
+			newError("failed to serve http for HTTP Upgrade").Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+		}
+	}()
+
+	return l, nil
+}
+
+// Addr implements net.Listener.Addr().
+func (ln *Listener) Addr() net.Addr {
+	return ln.listener.Addr()
+}
+
+// Close implements net.Listener.Close().
+func (ln *Listener) Close() error {
+	return ln.listener.Close()
+}
+
+func init() {
+	common.Must(internet.RegisterTransportListener(protocolName, ListenHTTPUpgrade))
+}
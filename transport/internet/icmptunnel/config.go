@@ -0,0 +1,20 @@
+package icmptunnel
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"v2ray.com/core/transport/internet/kcp"
+)
+
+const protocolName = "icmp"
+
+// GetKCPConfig returns the mKCP settings, without the header and the obfuscation.
+func (c *Config) GetKCPConfig() *kcp.Config {
+	if c.KcpSettings == nil {
+		return new(kcp.Config)
+	}
+	config := proto.Clone(c.KcpSettings).(*kcp.Config)
+	config.HeaderConfig = nil
+	config.Obfuscation = ""
+	return config
+}
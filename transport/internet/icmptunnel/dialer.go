@@ -0,0 +1,102 @@
+// +build icmptunnel
+// +build !confonly
+
+package icmptunnel
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/kcp"
+)
+
+func resolveIPv4(ctx context.Context, address net.Address) (net.IP, error) {
+	if address.Family().IsDomain() {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, address.Domain())
+		if err != nil {
+			return nil, newError("failed to resolve ", address).Base(err)
+		}
+		for _, addr := range addrs {
+			if ip := addr.IP.To4(); ip != nil {
+				return ip, nil
+			}
+		}
+		return nil, newError("no IPv4 address of ", address)
+	}
+	if ip := address.IP().To4(); ip != nil {
+		return ip, nil
+	}
+	return nil, newError("ICMP tunnel doesn't support IPv6 address ", address)
+}
+
+// Dial dials an mKCP connection over ICMP to the given destination. The port of the destination is ignored.
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
+	newError("dialing ICMP tunnel to ", dest.Address).WriteToLog(session.ExportIDToError(ctx))
+
+	ip, err := resolveIPv4(ctx, dest.Address)
+	if err != nil {
+		return nil, err
+	}
+	kcpConfig := streamSettings.ProtocolSettings.(*Config).GetKCPConfig()
+	security, err := kcpConfig.GetSecurity()
+	if err != nil {
+		return nil, newError("failed to create security").Base(err)
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, newError("failed to open ICMP socket, which requires privilege").Base(err)
+	}
+
+	peer := &net.IPAddr{IP: ip}
+	id := int(dice.RollUint16())
+	var seq uint32
+	kcpConn := kcp.NewConnection(kcp.ConnMetadata{
+		LocalAddr:    conn.LocalAddr(),
+		RemoteAddr:   peer,
+		Conversation: kcp.AllocateConversation(),
+	}, &kcp.KCPPacketWriter{
+		Security: security,
+		Writer: &echoWriter{
+			conn: conn,
+			peer: peer,
+			typ:  ipv4.ICMPTypeEcho,
+			id:   id,
+			seq: func() int {
+				return int(uint16(atomic.AddUint32(&seq, 1)))
+			},
+			magic: clientMagic,
+		},
+	}, conn, kcpConfig)
+
+	go fetchReplies(conn, peer, id, &kcp.KCPPacketReader{Security: security}, kcpConn)
+	return kcpConn, nil
+}
+
+// fetchReplies passes the packets in the echo replies from peer to kcpConn, until conn is closed.
+func fetchReplies(conn *icmp.PacketConn, peer *net.IPAddr, id int, reader *kcp.KCPPacketReader, kcpConn *kcp.Connection) {
+	b := make([]byte, 2048)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		if src, ok := addr.(*net.IPAddr); !ok || !src.IP.Equal(peer.IP) {
+			continue
+		}
+		echo, payload := parseEcho(b[:n], ipv4.ICMPTypeEchoReply, serverMagic)
+		if echo == nil || echo.ID != id {
+			continue
+		}
+		if segments := reader.Read(payload); len(segments) > 0 {
+			kcpConn.Input(segments)
+		}
+	}
+}
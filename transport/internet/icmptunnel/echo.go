@@ -0,0 +1,67 @@
+// +build icmptunnel
+// +build !confonly
+
+package icmptunnel
+
+import (
+	"bytes"
+
+	"golang.org/x/net/icmp"
+
+	"v2ray.com/core/common/net"
+)
+
+// IANA protocol number of ICMP for IPv4.
+const protocolICMP = 1
+
+var (
+	// Packets from clients and servers are marked differently, so that the replies the kernel of the server sends to
+	// echo requests on its own are not taken as packets from the server.
+	clientMagic = []byte{'v', '2', 'i', 'c'}
+	serverMagic = []byte{'v', '2', 'i', 's'}
+)
+
+// echoWriter writes each packet in an ICMP echo message to the peer.
+type echoWriter struct {
+	conn  *icmp.PacketConn
+	peer  net.Addr
+	typ   icmp.Type
+	id    int
+	seq   func() int
+	magic []byte
+}
+
+func (w *echoWriter) Write(b []byte) (int, error) {
+	data := make([]byte, 0, len(w.magic)+len(b))
+	data = append(append(data, w.magic...), b...)
+	msg := &icmp.Message{
+		Type: w.typ,
+		Body: &icmp.Echo{
+			ID:   w.id,
+			Seq:  w.seq(),
+			Data: data,
+		},
+	}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.WriteTo(packet, w.peer); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// parseEcho returns the echo message in b and its payload after magic, or nil if b is not an echo message of typ
+// with magic.
+func parseEcho(b []byte, typ icmp.Type, magic []byte) (*icmp.Echo, []byte) {
+	msg, err := icmp.ParseMessage(protocolICMP, b)
+	if err != nil || msg.Type != typ {
+		return nil, nil
+	}
+	echo, ok := msg.Body.(*icmp.Echo)
+	if !ok || !bytes.HasPrefix(echo.Data, magic) {
+		return nil, nil
+	}
+	return echo, echo.Data[len(magic):]
+}
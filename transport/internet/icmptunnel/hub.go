@@ -0,0 +1,164 @@
+// +build icmptunnel
+// +build !confonly
+
+package icmptunnel
+
+import (
+	"context"
+	"crypto/cipher"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/kcp"
+)
+
+type connectionID struct {
+	ip   string
+	id   int
+	conv uint16
+}
+
+type tunnel struct {
+	conn *kcp.Connection
+	// Sequence number of the last echo request, which the replies answer.
+	seq uint32
+}
+
+// Listener accepts mKCP connections over ICMP.
+type Listener struct {
+	sync.Mutex
+	conn     *icmp.PacketConn
+	config   *kcp.Config
+	security cipher.AEAD
+	reader   *kcp.KCPPacketReader
+	tunnels  map[connectionID]*tunnel
+	addConn  internet.ConnHandler
+}
+
+// ListenICMP listens for ICMP echo requests on address. The port is ignored.
+func ListenICMP(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
+	kcpConfig := streamSettings.ProtocolSettings.(*Config).GetKCPConfig()
+	security, err := kcpConfig.GetSecurity()
+	if err != nil {
+		return nil, newError("failed to create security").Base(err)
+	}
+	conn, err := icmp.ListenPacket("ip4:icmp", address.String())
+	if err != nil {
+		return nil, newError("failed to open ICMP socket, which requires privilege").Base(err)
+	}
+	newError("listening ICMP on ", address).WriteToLog(session.ExportIDToError(ctx))
+
+	l := &Listener{
+		conn:     conn,
+		config:   kcpConfig,
+		security: security,
+		reader:   &kcp.KCPPacketReader{Security: security},
+		tunnels:  make(map[connectionID]*tunnel),
+		addConn:  addConn,
+	}
+	go l.handlePackets()
+	return l, nil
+}
+
+func (l *Listener) handlePackets() {
+	b := make([]byte, 2048)
+	for {
+		n, addr, err := l.conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		src, ok := addr.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		echo, payload := parseEcho(b[:n], ipv4.ICMPTypeEcho, clientMagic)
+		if echo == nil {
+			continue
+		}
+		if segments := l.reader.Read(payload); len(segments) > 0 {
+			l.receive(src, echo, segments)
+		}
+	}
+}
+
+func (l *Listener) receive(src *net.IPAddr, echo *icmp.Echo, segments []kcp.Segment) {
+	id := connectionID{
+		ip:   src.IP.String(),
+		id:   echo.ID,
+		conv: segments[0].Conversation(),
+	}
+
+	l.Lock()
+	t, found := l.tunnels[id]
+	if !found {
+		if segments[0].Command() == kcp.CommandTerminate {
+			l.Unlock()
+			return
+		}
+		t = new(tunnel)
+		closer := &tunnelCloser{listener: l, id: id}
+		t.conn = kcp.NewConnection(kcp.ConnMetadata{
+			LocalAddr:    l.conn.LocalAddr(),
+			RemoteAddr:   &net.IPAddr{IP: src.IP},
+			Conversation: id.conv,
+		}, &kcp.KCPPacketWriter{
+			Security: l.security,
+			Writer: &echoWriter{
+				conn: l.conn,
+				peer: &net.IPAddr{IP: src.IP},
+				typ:  ipv4.ICMPTypeEchoReply,
+				id:   echo.ID,
+				seq: func() int {
+					return int(atomic.LoadUint32(&t.seq))
+				},
+				magic: serverMagic,
+			},
+		}, closer, l.config)
+		l.tunnels[id] = t
+		l.addConn(t.conn)
+	}
+	l.Unlock()
+
+	atomic.StoreUint32(&t.seq, uint32(echo.Seq))
+	t.conn.Input(segments)
+}
+
+func (l *Listener) remove(id connectionID) {
+	l.Lock()
+	delete(l.tunnels, id)
+	l.Unlock()
+}
+
+// ActiveConnections returns the number of connections of the listener.
+func (l *Listener) ActiveConnections() int {
+	l.Lock()
+	defer l.Unlock()
+	return len(l.tunnels)
+}
+
+// Addr implements internet.Listener.Addr.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Close implements internet.Listener.Close.
+func (l *Listener) Close() error {
+	return l.conn.Close()
+}
+
+// tunnelCloser removes a connection from the listener when it is closed.
+type tunnelCloser struct {
+	listener *Listener
+	id       connectionID
+}
+
+func (c *tunnelCloser) Close() error {
+	c.listener.remove(c.id)
+	return nil
+}
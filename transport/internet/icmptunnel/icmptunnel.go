@@ -0,0 +1,9 @@
+/*Package icmptunnel implements an experimental transport over ICMP
+
+ICMP tunnel transport carries mKCP packets in the payload of ICMP echo requests and replies, for networks where only
+ping passes. It needs raw sockets, and so root or CAP_NET_RAW, on both sides. Only IPv4 is supported. The transport
+is only built with the icmptunnel tag, as golang.org/x/net/ipv4 does not link on every platform.
+*/
+package icmptunnel
+
+//go:generate errorgen
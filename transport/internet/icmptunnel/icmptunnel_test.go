@@ -0,0 +1,48 @@
+// +build icmptunnel
+
+package icmptunnel_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/icmptunnel"
+)
+
+func TestDialAndListen(t *testing.T) {
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName:     "icmp",
+		ProtocolSettings: &Config{},
+	}
+	listener, err := ListenICMP(context.Background(), net.LocalHostIP, 0, streamSettings, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	if err != nil {
+		t.Skip("ICMP socket not available: ", err)
+	}
+	defer listener.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, 0), streamSettings)
+	common.Must(err)
+	defer conn.Close()
+
+	payload := make([]byte, 64*1024)
+	common.Must2(rand.Read(payload))
+	go func() {
+		common.Must2(conn.Write(payload))
+	}()
+	response := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, response))
+	if !bytes.Equal(payload, response) {
+		t.Error("corrupted response")
+	}
+}
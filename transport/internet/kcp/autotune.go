@@ -0,0 +1,80 @@
+// +build !confonly
+
+package kcp
+
+const (
+	// The tuned window never shrinks below this number of segments.
+	autoTuneMinWindow = 32
+	// Number of recent rounds, of which the largest delivery is taken as the bandwidth-delay product.
+	autoTuneRounds = 4
+	// The window is this multiple of the bandwidth-delay product, so that it can grow when more bandwidth is available.
+	autoTuneGain = 2
+)
+
+// windowTuner sizes the sending window by the bandwidth-delay product, which is measured as the number of segments
+// delivered in a round trip.
+type windowTuner struct {
+	// Hard upper bound of the window, so that the memory held by the sending buffer is bounded.
+	maxWindow uint32
+	window    uint32
+
+	roundStart uint32
+	roundAcked uint32
+	samples    [autoTuneRounds]uint32
+	round      int
+}
+
+func newWindowTuner(initial uint32, maxWindow uint32) *windowTuner {
+	t := &windowTuner{maxWindow: maxWindow}
+	t.setWindow(initial)
+	return t
+}
+
+func (t *windowTuner) setWindow(window uint32) {
+	if window < autoTuneMinWindow {
+		window = autoTuneMinWindow
+	}
+	if window > t.maxWindow {
+		window = t.maxWindow
+	}
+	t.window = window
+}
+
+// OnAck handles the acknowledgement of segments at current, with the smoothed RTT of the connection.
+func (t *windowTuner) OnAck(current uint32, acked uint32, srtt uint32) {
+	if srtt == 0 {
+		return
+	}
+	if t.roundAcked == 0 {
+		t.roundStart = current
+	}
+	t.roundAcked += acked
+	if current-t.roundStart < srtt {
+		return
+	}
+
+	t.samples[t.round%autoTuneRounds] = t.roundAcked
+	t.round++
+	t.roundAcked = 0
+
+	var bdp uint32
+	for _, sample := range t.samples {
+		if sample > bdp {
+			bdp = sample
+		}
+	}
+	t.setWindow(autoTuneGain * bdp)
+}
+
+// Window returns the number of segments allowed in flight.
+func (t *windowTuner) Window() uint32 {
+	return t.window
+}
+
+// BufferSize returns the number of segments allowed in the sending buffer.
+func (t *windowTuner) BufferSize() uint32 {
+	if size := 2 * t.window; size < t.maxWindow {
+		return size
+	}
+	return t.maxWindow
+}
@@ -0,0 +1,50 @@
+package kcp
+
+import (
+	"testing"
+)
+
+func TestWindowTuner(t *testing.T) {
+	tuner := newWindowTuner(8, 1000)
+	if w := tuner.Window(); w != autoTuneMinWindow {
+		t.Error("initial window: ", w)
+	}
+
+	// 300 segments are delivered in each round trip of 90ms, in 10 acknowledgements.
+	current := uint32(0)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			tuner.OnAck(current, 30, 90)
+			current += 10
+		}
+	}
+	if w := tuner.Window(); w != 600 {
+		t.Error("window: ", w)
+	}
+	if size := tuner.BufferSize(); size != 1000 {
+		t.Error("buffer size: ", size)
+	}
+
+	// The window shrinks after the larger deliveries leave the filter.
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			tuner.OnAck(current, 10, 90)
+			current += 10
+		}
+	}
+	if w := tuner.Window(); w != 200 {
+		t.Error("window: ", w)
+	}
+	if size := tuner.BufferSize(); size != 400 {
+		t.Error("buffer size: ", size)
+	}
+
+	// The window never exceeds the upper bound.
+	for i := 0; i < 100; i++ {
+		tuner.OnAck(current, 100, 90)
+		current += 10
+	}
+	if w := tuner.Window(); w != 1000 {
+		t.Error("window: ", w)
+	}
+}
@@ -0,0 +1,84 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"io"
+	"sync"
+
+	"v2ray.com/core/common/buf"
+)
+
+// Maximum number of packets written in one batch.
+const maxBatchSize = 64
+
+// batchWriter is the io.Writer under the KCPPacketWriter of a Connection. Packets written during a flush of the
+// Connection are collected, and written in batches, to save the system calls of writing them one by one.
+type batchWriter struct {
+	sync.Mutex
+	writer   io.Writer
+	batch    func(packets [][]byte) error
+	batching bool
+	buffers  []*buf.Buffer
+	packets  [][]byte
+}
+
+func newBatchWriter(writer io.Writer, batch func(packets [][]byte) error) *batchWriter {
+	return &batchWriter{
+		writer: writer,
+		batch:  batch,
+	}
+}
+
+func (w *batchWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.batching {
+		return w.writer.Write(b)
+	}
+	buffer := buf.New()
+	if _, err := buffer.Write(b); err != nil {
+		buffer.Release()
+		return 0, err
+	}
+	w.buffers = append(w.buffers, buffer)
+	if len(w.buffers) >= maxBatchSize {
+		w.flush()
+	}
+	return len(b), nil
+}
+
+// Begin starts collecting the packets written.
+func (w *batchWriter) Begin() {
+	w.Lock()
+	w.batching = true
+	w.Unlock()
+}
+
+// End writes the packets collected since Begin, and stops collecting them.
+func (w *batchWriter) End() {
+	w.Lock()
+	w.batching = false
+	w.flush()
+	w.Unlock()
+}
+
+func (w *batchWriter) flush() {
+	if len(w.buffers) == 0 {
+		return
+	}
+	for _, buffer := range w.buffers {
+		w.packets = append(w.packets, buffer.Bytes())
+	}
+	if err := w.batch(w.packets); err != nil {
+		newError("failed to write ", len(w.packets), " packets").Base(err).AtDebug().WriteToLog()
+	}
+	for i, buffer := range w.buffers {
+		buffer.Release()
+		w.buffers[i] = nil
+		w.packets[i] = nil
+	}
+	w.buffers = w.buffers[:0]
+	w.packets = w.packets[:0]
+}
@@ -0,0 +1,58 @@
+package kcp
+
+import (
+	"testing"
+)
+
+type batchRecorder struct {
+	packets []string
+	batches [][]string
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	r.packets = append(r.packets, string(b))
+	return len(b), nil
+}
+
+func (r *batchRecorder) WriteBatch(packets [][]byte) error {
+	var batch []string
+	for _, packet := range packets {
+		batch = append(batch, string(packet))
+	}
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func TestBatchWriter(t *testing.T) {
+	recorder := new(batchRecorder)
+	writer := newBatchWriter(recorder, recorder.WriteBatch)
+
+	writer.Write([]byte("a"))
+	if len(recorder.packets) != 1 {
+		t.Error("expect packet written at once without batching")
+	}
+
+	writer.Begin()
+	b := []byte("b")
+	writer.Write(b)
+	// The packet is copied, as the caller reuses its buffer.
+	b[0] = 'x'
+	writer.Write([]byte("c"))
+	if len(recorder.batches) != 0 {
+		t.Fatal("expect packets held until the end of the batch")
+	}
+	writer.End()
+	if len(recorder.batches) != 1 || len(recorder.batches[0]) != 2 || recorder.batches[0][0] != "b" || recorder.batches[0][1] != "c" {
+		t.Error("unexpected batches: ", recorder.batches)
+	}
+
+	recorder.batches = nil
+	writer.Begin()
+	for i := 0; i < maxBatchSize+1; i++ {
+		writer.Write([]byte("d"))
+	}
+	writer.End()
+	if len(recorder.batches) != 2 || len(recorder.batches[0]) != maxBatchSize || len(recorder.batches[1]) != 1 {
+		t.Error("expect a full batch and a batch of the rest, but got ", len(recorder.batches))
+	}
+}
@@ -0,0 +1,134 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"v2ray.com/core/common"
+)
+
+const (
+	// Number of rounds, in which the maximum delivery rate is taken as the bandwidth.
+	bbrBandwidthRounds = 10
+	// Time in milli-sec, after which the minimum RTT expires.
+	bbrMinRTTExpiry = 10000
+	// Number of rounds without significant bandwidth growth, after which the startup ends.
+	bbrStartupRounds = 3
+	// Gain of the window in startup, in percent.
+	bbrStartupGain = 289
+)
+
+// Gains of the window in each round after startup, in percent. The window probes for more bandwidth in one round, and
+// drains the queue built by the probe in the next.
+var bbrCycleGains = [...]uint32{250, 150, 200, 200, 200, 200, 200, 200}
+
+// bbrController sets the window to the bandwidth-delay product, measured by the delivery rate of the segments and the
+// minimum RTT. Unlike lossController, it doesn't shrink the window on packet loss, which is common on lossy links
+// without congestion.
+type bbrController struct {
+	window    uint32
+	minWindow uint32
+	maxWindow uint32
+
+	minRTT      uint32
+	minRTTStamp uint32
+
+	// Delivery rate sampling of the current round.
+	roundStarted   bool
+	roundStart     uint32
+	roundDelivered uint32
+
+	// Delivery rates of recent rounds, in segments per second.
+	bandwidth [bbrBandwidthRounds]uint32
+	round     uint32
+
+	startup             bool
+	fullBandwidth       uint32
+	fullBandwidthRounds uint32
+	cycle               uint32
+}
+
+func newBBRController(config *Config) CongestionController {
+	return &bbrController{
+		window:    config.GetSendingInFlightSize(),
+		minWindow: 16,
+		maxWindow: 2 * config.GetSendingInFlightSize(),
+		startup:   true,
+	}
+}
+
+func (c *bbrController) OnAck(current uint32, acked uint32, rtt uint32) {
+	if rtt > 0 && (c.minRTT == 0 || rtt <= c.minRTT || current-c.minRTTStamp > bbrMinRTTExpiry) {
+		c.minRTT = rtt
+		c.minRTTStamp = current
+	}
+
+	if !c.roundStarted {
+		c.roundStarted = true
+		c.roundStart = current
+		c.roundDelivered = 0
+	}
+	c.roundDelivered += acked
+
+	elapsed := current - c.roundStart
+	if c.minRTT == 0 || elapsed < c.minRTT || elapsed == 0 {
+		return
+	}
+
+	c.bandwidth[c.round%bbrBandwidthRounds] = c.roundDelivered * 1000 / elapsed
+	c.round++
+	c.roundStart = current
+	c.roundDelivered = 0
+	c.onRoundEnd()
+}
+
+// maxBandwidth returns the maximum delivery rate of recent rounds.
+func (c *bbrController) maxBandwidth() uint32 {
+	var max uint32
+	for _, bw := range c.bandwidth {
+		if bw > max {
+			max = bw
+		}
+	}
+	return max
+}
+
+func (c *bbrController) onRoundEnd() {
+	bandwidth := c.maxBandwidth()
+
+	gain := uint32(bbrStartupGain)
+	if c.startup {
+		// Startup ends when the bandwidth stops growing by 25%.
+		if bandwidth >= c.fullBandwidth*5/4 {
+			c.fullBandwidth = bandwidth
+			c.fullBandwidthRounds = 0
+		} else {
+			c.fullBandwidthRounds++
+			if c.fullBandwidthRounds >= bbrStartupRounds {
+				c.startup = false
+			}
+		}
+	}
+	if !c.startup {
+		gain = bbrCycleGains[c.cycle%uint32(len(bbrCycleGains))]
+		c.cycle++
+	}
+
+	window := uint64(bandwidth) * uint64(c.minRTT) / 1000 * uint64(gain) / 100
+	if window < uint64(c.minWindow) {
+		window = uint64(c.minWindow)
+	}
+	if window > uint64(c.maxWindow) {
+		window = uint64(c.maxWindow)
+	}
+	c.window = uint32(window)
+}
+
+func (c *bbrController) OnPacketLoss(lossRate uint32) {}
+
+func (c *bbrController) Window() uint32 {
+	return c.window
+}
+
+func init() {
+	common.Must(RegisterCongestionController("bbr", newBBRController))
+}
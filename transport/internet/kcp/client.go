@@ -0,0 +1,266 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	udp_proto "v2ray.com/core/common/protocol/udp"
+	"v2ray.com/core/transport/internet"
+	v2tls "v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/trace"
+	"v2ray.com/core/transport/internet/udp"
+)
+
+// AllocateConversation allocates a conversation ID, which is not assigned to other connections, until all other IDs
+// are used. It can be pinned to a connection by Client.DialConversation.
+func AllocateConversation() uint16 {
+	return uint16(atomic.AddUint32(&globalConv, 1))
+}
+
+// Client dials mKCP connections to a destination over a single UDP socket, so that embedders can pool connections.
+// The connections are told apart by their conversation IDs.
+type Client struct {
+	sync.Mutex
+	dest           net.Destination
+	streamSettings *internet.MemoryStreamConfig
+	rawConn        net.Conn
+	reader         PacketReader
+	writer         *KCPPacketWriter
+	connections    map[uint16]*Connection
+	// Whether the socket is closed with the last connection.
+	closeWhenIdle bool
+	// Whether no more connections are dialed. The socket is closed with the last connection then.
+	closed bool
+}
+
+// NewClient creates a Client to dest, and dials its socket.
+func NewClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*Client, error) {
+	dest.Network = net.Network_UDP
+	newError("dialing mKCP to ", dest).WriteToLog()
+
+	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, newError("failed to dial to dest: ", err).AtWarning().Base(err)
+	}
+
+	kcpSettings := streamSettings.ProtocolSettings.(*Config)
+
+	header, err := kcpSettings.GetPackerHeader()
+	if err != nil {
+		rawConn.Close()
+		return nil, newError("failed to create packet header").Base(err)
+	}
+	security, err := kcpSettings.GetSecurity()
+	if err != nil {
+		rawConn.Close()
+		return nil, newError("failed to create security").Base(err)
+	}
+	obfuscator, err := NewObfuscator(kcpSettings)
+	if err != nil {
+		rawConn.Close()
+		return nil, newError("failed to create obfuscator").Base(err)
+	}
+	c := &Client{
+		dest:           dest,
+		streamSettings: streamSettings,
+		rawConn:        rawConn,
+		reader: &KCPPacketReader{
+			Header:     header,
+			Security:   security,
+			Obfuscator: obfuscator,
+		},
+		writer: &KCPPacketWriter{
+			Header:     header,
+			Security:   security,
+			Obfuscator: obfuscator,
+			Writer:     rawConn,
+		},
+		connections: make(map[uint16]*Connection),
+	}
+
+	input := &inputReader{input: rawConn}
+	if udpConn, ok := rawConn.(*net.UDPConn); ok {
+		if kcpSettings.Ecn {
+			if err := udp.EnableECN(udpConn); err != nil {
+				newError("failed to enable ECN").Base(err).AtWarning().WriteToLog()
+			} else {
+				input.conn = udpConn
+			}
+		}
+		offload := streamSettings.SocketSettings.GetUdpOffload()
+		if offload {
+			if err := udp.EnableOffload(udpConn); err != nil {
+				newError("failed to enable UDP offload").Base(err).AtWarning().WriteToLog()
+				offload = false
+			} else {
+				input.conn = udpConn
+				input.large = make([]byte, 65536)
+			}
+		}
+		if input.conn != nil {
+			input.oob = make([]byte, 64)
+		}
+
+		batch := udp.NewBatchWriter(udpConn, offload)
+		c.writer.Writer = newBatchWriter(rawConn, func(packets [][]byte) error {
+			return batch.WriteBatch(packets, nil)
+		})
+	}
+
+	go c.fetchInput(input)
+
+	return c, nil
+}
+
+func (c *Client) fetchInput(input *inputReader) {
+	cache := make(chan *udp_proto.Packet, 1024)
+	go func() {
+		for {
+			packets, err := input.Read()
+			if err != nil {
+				close(cache)
+				return
+			}
+			for _, packet := range packets {
+				select {
+				case cache <- packet:
+				default:
+					packet.Payload.Release()
+				}
+			}
+		}
+	}()
+
+	for packet := range cache {
+		segments := c.reader.Read(packet.Payload.Bytes())
+		packet.Payload.Release()
+		if len(segments) == 0 {
+			continue
+		}
+		c.Lock()
+		conn := c.connections[segments[0].Conversation()]
+		c.Unlock()
+		if conn == nil {
+			for _, seg := range segments {
+				seg.Release()
+			}
+			continue
+		}
+		conn.Input(segments)
+		if packet.ECN == udp.ECNCE {
+			conn.receivingWorker.OnCongestionExperienced()
+		}
+	}
+}
+
+// newMigrationToken returns a random token, which is never 0.
+func newMigrationToken() uint64 {
+	for {
+		var b [8]byte
+		common.Must2(rand.Read(b[:]))
+		if token := binary.BigEndian.Uint64(b[:]); token != 0 {
+			return token
+		}
+	}
+}
+
+// Dial dials a new connection with an allocated conversation ID.
+func (c *Client) Dial(ctx context.Context) (internet.Connection, error) {
+	return c.DialConversation(ctx, AllocateConversation())
+}
+
+// DialConversation dials a new connection with the conversation ID conv. It fails if conv is used by another
+// connection of the client.
+func (c *Client) DialConversation(ctx context.Context, conv uint16) (internet.Connection, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed {
+		return nil, newError("client to ", c.dest, " closed")
+	}
+	if _, found := c.connections[conv]; found {
+		return nil, newError("conversation ", conv, " to ", c.dest, " is in use")
+	}
+
+	kcpSettings := c.streamSettings.ProtocolSettings.(*Config)
+	meta := ConnMetadata{
+		LocalAddr:    c.rawConn.LocalAddr(),
+		RemoteAddr:   c.rawConn.RemoteAddr(),
+		Conversation: conv,
+		Trace:        trace.SessionFromContext(ctx),
+		Stats:        statsManagerFromContext(ctx),
+	}
+	if kcpSettings.Migration {
+		meta.MigrationToken = newMigrationToken()
+	}
+	session := NewConnection(meta, c.writer, &clientCloser{client: c, conv: conv}, kcpSettings)
+	c.connections[conv] = session
+
+	var iConn internet.Connection = session
+
+	if config := v2tls.ConfigFromStreamSettings(c.streamSettings); config != nil {
+		tlsConn := tls.Client(iConn, config.GetTLSConfig(v2tls.WithDestination(c.dest)))
+		iConn = tlsConn
+	}
+
+	return iConn, nil
+}
+
+// remove removes the terminated connection of conv.
+func (c *Client) remove(conv uint16) {
+	c.Lock()
+	if _, found := c.connections[conv]; !found {
+		c.Unlock()
+		return
+	}
+	delete(c.connections, conv)
+	idle := (c.closeWhenIdle || c.closed) && len(c.connections) == 0
+	c.closed = c.closed || idle
+	c.Unlock()
+
+	if idle {
+		c.rawConn.Close()
+	}
+}
+
+// Close closes all connections of the client, and its socket after they are terminated.
+func (c *Client) Close() error {
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return nil
+	}
+	c.closed = true
+	connections := make([]*Connection, 0, len(c.connections))
+	for _, conn := range c.connections {
+		connections = append(connections, conn)
+	}
+	c.Unlock()
+
+	if len(connections) == 0 {
+		return c.rawConn.Close()
+	}
+	for _, conn := range connections {
+		conn.Close()
+	}
+	return nil
+}
+
+// clientCloser removes a connection from its Client, when the connection is terminated.
+type clientCloser struct {
+	client *Client
+	conv   uint16
+}
+
+func (c *clientCloser) Close() error {
+	c.client.remove(c.conv)
+	return nil
+}
@@ -0,0 +1,70 @@
+package kcp_test
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/kcp"
+)
+
+func TestClient(t *testing.T) {
+	config := &Config{}
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	client, err := NewClient(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer client.Close()
+
+	conv := AllocateConversation()
+	pinned, err := client.DialConversation(context.Background(), conv)
+	common.Must(err)
+	defer pinned.Close()
+	if _, err := client.DialConversation(context.Background(), conv); err == nil {
+		t.Error("expect error when the conversation is in use")
+	}
+
+	allocated, err := client.Dial(context.Background())
+	common.Must(err)
+	defer allocated.Close()
+	if pinned.LocalAddr().String() != allocated.LocalAddr().String() {
+		t.Error("connections over different sockets: ", pinned.LocalAddr(), " ", allocated.LocalAddr())
+	}
+
+	for _, conn := range []internet.Connection{pinned, allocated} {
+		send := make([]byte, 256*1024)
+		rand.Read(send)
+		go conn.Write(send)
+
+		received := make([]byte, 256*1024)
+		common.Must2(io.ReadFull(conn, received))
+		if r := cmp.Diff(received, send); r != "" {
+			t.Error(r)
+		}
+	}
+
+	common.Must(client.Close())
+	if _, err := client.Dial(context.Background()); err == nil {
+		t.Error("expect error when the client is closed")
+	}
+}
@@ -4,7 +4,6 @@ package kcp
 
 import (
 	"crypto/cipher"
-	"fmt"
 
 	"v2ray.com/core/common"
 	"v2ray.com/core/transport/internet"
@@ -63,7 +62,6 @@ func (c *Config) GetReadBufferSize() uint32 {
 // GetSecurity returns the security settings.
 func (c *Config) GetSecurity() (cipher.AEAD, error) {
 	if c.Seed != nil {
-		fmt.Println("=========NewAEADAESGCMBasedOnSeed Used============")
 		return NewAEADAESGCMBasedOnSeed(c.Seed.Seed), nil
 	}
 	return NewSimpleAuthenticator(), nil
@@ -81,6 +79,96 @@ func (c *Config) GetPackerHeader() (internet.PacketHeader, error) {
 	return nil, nil
 }
 
+// GetCongestionControlName returns the name of the congestion controller.
+func (c *Config) GetCongestionControlName() string {
+	if c == nil || c.CongestionControl == "" {
+		return "loss"
+	}
+	return c.CongestionControl
+}
+
+// GetFECShards returns the numbers of data shards and parity shards in an FEC block, or zeros if FEC is disabled.
+func (c *Config) GetFECShards() (int, int) {
+	if c == nil || c.Fec == nil {
+		return 0, 0
+	}
+	data, parity := int(c.Fec.DataShards), int(c.Fec.ParityShards)
+	if data == 0 || parity == 0 || data > maxFECDataShards || parity > maxFECParityShards {
+		return 0, 0
+	}
+	return data, parity
+}
+
+// GetFECOverhead returns the size of an FECSegment in bytes, in excess of the largest DataSegment in its block.
+func (c *Config) GetFECOverhead() uint32 {
+	data, _ := c.GetFECShards()
+	if data == 0 {
+		return 0
+	}
+	return 15 + 8*uint32(data)
+}
+
+// GetFastResendValue returns the number of acknowledgements of later segments, after which a segment is resent.
+func (c *Config) GetFastResendValue() uint32 {
+	if c == nil || c.FastResend == 0 {
+		return 3
+	}
+	return c.FastResend
+}
+
+// GetAckNoDelayThresholdValue returns the number of pending acknowledgements, at which they are sent without delay.
+func (c *Config) GetAckNoDelayThresholdValue() uint32 {
+	if c == nil || c.AckNoDelayThreshold == 0 {
+		return 16
+	}
+	return c.AckNoDelayThreshold
+}
+
+// GetKeepAliveIntervalValue returns the interval of keep-alive pings in milli-sec.
+func (c *Config) GetKeepAliveIntervalValue() uint32 {
+	if c == nil || c.KeepAliveInterval == 0 {
+		return 3000
+	}
+	return c.KeepAliveInterval
+}
+
+// GetRTOMinValue returns the lower bound of the retransmission timeout.
+func (c *Config) GetRTOMinValue() uint32 {
+	if c == nil || c.RtoMin == 0 {
+		return c.GetTTIValue()
+	}
+	return c.RtoMin
+}
+
+// GetRTOMaxValue returns the upper bound of the retransmission timeout.
+func (c *Config) GetRTOMaxValue() uint32 {
+	if c == nil || c.RtoMax == 0 {
+		return 10000
+	}
+	if c.RtoMax < c.GetRTOMinValue() {
+		return c.GetRTOMinValue()
+	}
+	return c.RtoMax
+}
+
+// GetRetransmitBackoffValue returns the percentage, by which the timeout of a segment grows on each transmission, and
+// its upper bound. The percentage is 0 if backoff is disabled.
+func (c *Config) GetRetransmitBackoffValue() (uint32, uint32) {
+	backoff := c.GetRetransmitBackoff()
+	if backoff == nil {
+		return 0, 0
+	}
+	factor := backoff.Factor
+	if factor == 0 {
+		factor = 150
+	}
+	maxTimeout := backoff.MaxTimeout
+	if maxTimeout == 0 {
+		maxTimeout = c.GetRTOMaxValue()
+	}
+	return factor, maxTimeout
+}
+
 func (c *Config) GetSendingInFlightSize() uint32 {
 	size := c.GetUplinkCapacityValue() * 1024 * 1024 / c.GetMTUValue() / (1000 / c.GetTTIValue())
 	if size < 8 {
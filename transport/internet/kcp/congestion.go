@@ -0,0 +1,105 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"v2ray.com/core/common"
+)
+
+// CongestionController limits the number of segments in flight when congestion control is enabled.
+type CongestionController interface {
+	// OnAck is called when segments are acknowledged. rtt is the round trip time measured by the latest acknowledged
+	// segment, or 0 if not measured.
+	OnAck(current uint32, acked uint32, rtt uint32)
+	// OnPacketLoss is called after segments are flushed, with the percentage of resent segments among the segments in
+	// flight.
+	OnPacketLoss(lossRate uint32)
+	// Window returns the maximum number of segments in flight.
+	Window() uint32
+}
+
+// CongestionControllerCreator creates a CongestionController for a connection.
+type CongestionControllerCreator func(config *Config) CongestionController
+
+var congestionControllerCache = make(map[string]CongestionControllerCreator)
+
+// RegisterCongestionController registers a congestion controller, which can be selected by its name in Config.
+func RegisterCongestionController(name string, creator CongestionControllerCreator) error {
+	if _, found := congestionControllerCache[name]; found {
+		return newError("congestion controller ", name, " is already registered").AtError()
+	}
+	congestionControllerCache[name] = creator
+	return nil
+}
+
+// NewCongestionController creates the congestion controller selected in the config.
+func NewCongestionController(config *Config) (CongestionController, error) {
+	name := config.GetCongestionControlName()
+	creator, found := congestionControllerCache[name]
+	if !found {
+		return nil, newError("unknown congestion controller: ", name)
+	}
+	return creator(config), nil
+}
+
+// lossController adjusts the window by the loss rate. By default, it shrinks the window by a quarter when more than
+// 15% of the segments are resent, and grows it by a quarter when less than 5% are.
+type lossController struct {
+	window    uint32
+	decrease  uint32
+	increase  uint32
+	minWindow uint32
+	maxWindow uint32
+}
+
+func newLossController(config *Config) CongestionController {
+	c := &lossController{
+		window:    config.GetSendingInFlightSize(),
+		decrease:  75,
+		increase:  25,
+		minWindow: 16,
+		maxWindow: 2 * config.GetSendingInFlightSize(),
+	}
+	if lc := config.GetLossControl(); lc != nil {
+		if lc.Decrease > 0 && lc.Decrease < 100 {
+			c.decrease = lc.Decrease
+		}
+		if lc.Increase > 0 {
+			c.increase = lc.Increase
+		}
+		if lc.MinWindow > 0 {
+			c.minWindow = lc.MinWindow
+		}
+		if lc.MaxWindow > 0 {
+			c.maxWindow = lc.MaxWindow
+		}
+	}
+	if c.maxWindow < c.minWindow {
+		c.maxWindow = c.minWindow
+	}
+	return c
+}
+
+func (c *lossController) OnAck(current uint32, acked uint32, rtt uint32) {}
+
+func (c *lossController) OnPacketLoss(lossRate uint32) {
+	if lossRate >= 15 {
+		c.window = c.window * c.decrease / 100
+	} else if lossRate <= 5 {
+		c.window += c.window * c.increase / 100
+	}
+	if c.window < c.minWindow {
+		c.window = c.minWindow
+	}
+	if c.window > c.maxWindow {
+		c.window = c.maxWindow
+	}
+}
+
+func (c *lossController) Window() uint32 {
+	return c.window
+}
+
+func init() {
+	common.Must(RegisterCongestionController("loss", newLossController))
+}
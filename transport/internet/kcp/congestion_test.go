@@ -0,0 +1,130 @@
+package kcp_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/common"
+	. "v2ray.com/core/transport/internet/kcp"
+)
+
+type fixedController struct {
+	window uint32
+}
+
+func (c *fixedController) OnAck(current uint32, acked uint32, rtt uint32) {}
+func (c *fixedController) OnPacketLoss(lossRate uint32)                   {}
+func (c *fixedController) Window() uint32                                 { return c.window }
+
+func TestRegisterCongestionController(t *testing.T) {
+	common.Must(RegisterCongestionController("test-fixed", func(config *Config) CongestionController {
+		return &fixedController{window: 42}
+	}))
+	if err := RegisterCongestionController("test-fixed", nil); err == nil {
+		t.Error("expected error for duplicate registration")
+	}
+
+	c, err := NewCongestionController(&Config{CongestionControl: "test-fixed"})
+	common.Must(err)
+	if w := c.Window(); w != 42 {
+		t.Error("window: ", w)
+	}
+
+	if _, err := NewCongestionController(&Config{CongestionControl: "unknown"}); err == nil {
+		t.Error("expected error for unknown congestion controller")
+	}
+}
+
+func TestLossCongestionController(t *testing.T) {
+	config := &Config{}
+	c, err := NewCongestionController(config)
+	common.Must(err)
+
+	initial := c.Window()
+	if initial != config.GetSendingInFlightSize() {
+		t.Error("initial window: ", initial)
+	}
+
+	c.OnPacketLoss(20)
+	if w := c.Window(); w != 3*initial/4 {
+		t.Error("window after loss: ", w)
+	}
+
+	for i := 0; i < 100; i++ {
+		c.OnPacketLoss(0)
+	}
+	if w := c.Window(); w != 2*config.GetSendingInFlightSize() {
+		t.Error("window without loss: ", w)
+	}
+
+	for i := 0; i < 100; i++ {
+		c.OnPacketLoss(100)
+	}
+	if w := c.Window(); w != 16 {
+		t.Error("window with full loss: ", w)
+	}
+}
+
+func TestLossCongestionControllerTuned(t *testing.T) {
+	config := &Config{
+		LossControl: &LossControl{
+			Decrease:  50,
+			Increase:  10,
+			MinWindow: 4,
+			MaxWindow: 1000,
+		},
+	}
+	c, err := NewCongestionController(config)
+	common.Must(err)
+
+	initial := c.Window()
+	c.OnPacketLoss(20)
+	if w := c.Window(); w != initial/2 {
+		t.Error("window after loss: ", w)
+	}
+	c.OnPacketLoss(0)
+	if w := c.Window(); w != initial/2+initial/2/10 {
+		t.Error("window after recovery: ", w)
+	}
+
+	for i := 0; i < 200; i++ {
+		c.OnPacketLoss(0)
+	}
+	if w := c.Window(); w != 1000 {
+		t.Error("window without loss: ", w)
+	}
+	for i := 0; i < 100; i++ {
+		c.OnPacketLoss(100)
+	}
+	if w := c.Window(); w != 4 {
+		t.Error("window with full loss: ", w)
+	}
+}
+
+func TestBBRCongestionController(t *testing.T) {
+	c, err := NewCongestionController(&Config{CongestionControl: "bbr"})
+	common.Must(err)
+
+	// 10 segments are delivered every 10 ms, with 100 ms RTT, so the bandwidth-delay product is 100 segments.
+	var current uint32
+	for ; current < 5000; current += 10 {
+		c.OnAck(current, 10, 100)
+	}
+	if w := c.Window(); w < 150 || w > 250 {
+		t.Error("window: ", w)
+	}
+
+	// Loss doesn't shrink the window.
+	w := c.Window()
+	c.OnPacketLoss(50)
+	if c.Window() != w {
+		t.Error("window after loss: ", c.Window())
+	}
+
+	// The window follows a lower bandwidth.
+	for end := current + 20000; current < end; current += 10 {
+		c.OnAck(current, 2, 100)
+	}
+	if w := c.Window(); w < 30 || w > 50 {
+		t.Error("window after bandwidth drop: ", w)
+	}
+}
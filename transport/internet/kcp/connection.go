@@ -12,14 +12,19 @@ import (
 	"time"
 
 	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/signal"
 	"v2ray.com/core/common/signal/semaphore"
+	"v2ray.com/core/features/stats"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 var (
 	ErrIOTimeout        = newError("Read/Write timeout")
 	ErrClosedListener   = newError("Listener closed.")
 	ErrClosedConnection = newError("Connection closed.")
+	ErrDeadLink         = newError("Peer not responding.")
+	ErrIdleTimeout      = newError("Connection idle timeout.")
 )
 
 // State of the connection
@@ -49,15 +54,30 @@ func nowMillisec() int64 {
 	return now.Unix()*1000 + int64(now.Nanosecond()/1000000)
 }
 
+// RoundTripInfo estimates the retransmission timeout of a connection as in RFC 6298.
 type RoundTripInfo struct {
 	sync.RWMutex
-	variation        uint32
-	srtt             uint32
-	rto              uint32
-	minRtt           uint32
+	variation uint32
+	srtt      uint32
+	rto       uint32
+	// Clock granularity, which is the interval of flushes. The RTO is at least this much larger than SRTT.
+	granularity uint32
+	// Bounds of the RTO. maxRto is ignored if 0.
+	minRto           uint32
+	maxRto           uint32
 	updatedTimestamp uint32
 }
 
+func (info *RoundTripInfo) bound(rto uint32) uint32 {
+	if info.maxRto > 0 && rto > info.maxRto {
+		rto = info.maxRto
+	}
+	if rto < info.minRto {
+		rto = info.minRto
+	}
+	return rto
+}
+
 func (info *RoundTripInfo) UpdatePeerRTO(rto uint32, current uint32) {
 	info.Lock()
 	defer info.Unlock()
@@ -67,9 +87,11 @@ func (info *RoundTripInfo) UpdatePeerRTO(rto uint32, current uint32) {
 	}
 
 	info.updatedTimestamp = current
-	info.rto = rto
+	info.rto = info.bound(rto)
 }
 
+// Update updates the estimation with a round trip time measured at current. By Karn's algorithm, rtt must not be
+// measured from retransmitted segments.
 func (info *RoundTripInfo) Update(rtt uint32, current uint32) {
 	if rtt > 0x7FFFFFFF {
 		return
@@ -88,21 +110,21 @@ func (info *RoundTripInfo) Update(rtt uint32, current uint32) {
 		}
 		info.variation = (3*info.variation + delta) / 4
 		info.srtt = (7*info.srtt + rtt) / 8
-		if info.srtt < info.minRtt {
-			info.srtt = info.minRtt
-		}
 	}
-	var rto uint32
-	if info.minRtt < 4*info.variation {
-		rto = info.srtt + 4*info.variation
+	if 4*info.variation > info.granularity {
+		info.rto = info.bound(info.srtt + 4*info.variation)
 	} else {
-		rto = info.srtt + info.variation
+		info.rto = info.bound(info.srtt + info.granularity)
 	}
+	info.updatedTimestamp = current
+}
 
-	if rto > 10000 {
-		rto = 10000
-	}
-	info.rto = rto * 5 / 4
+// Backoff doubles the RTO after a retransmission timeout. The RTO is estimated again on the next measurement.
+func (info *RoundTripInfo) Backoff(current uint32) {
+	info.Lock()
+	defer info.Unlock()
+
+	info.rto = info.bound(2 * info.rto)
 	info.updatedTimestamp = current
 }
 
@@ -173,14 +195,22 @@ type ConnMetadata struct {
 	LocalAddr    net.Addr
 	RemoteAddr   net.Addr
 	Conversation uint16
+	// Trace is the session that segments of the connection are recorded into. It may be nil.
+	Trace *trace.Session
+	// Stats is the manager that metrics of the connection are exported to, if enabled in config. It may be nil.
+	Stats stats.Manager
+	// MigrationToken is sent in every packet of a dialed connection, so that the listener follows the connection to new
+	// addresses of the dialer. It is 0 if the connection doesn't migrate.
+	MigrationToken uint64
 }
 
 // Connection is a KCP connection over UDP.
 type Connection struct {
 	meta       ConnMetadata
 	closer     io.Closer
-	rd         time.Time
-	wd         time.Time // write deadline
+	clock      func() int64
+	rd         deadline
+	wd         deadline
 	since      int64
 	dataInput  *signal.Notifier
 	dataOutput *signal.Notifier
@@ -190,6 +220,10 @@ type Connection struct {
 	stateBeginTime   uint32
 	lastIncomingTime uint32
 	lastPingTime     uint32
+	// The time after the last ping, when the next keep-alive ping is sent.
+	pingInterval uint32
+	// The time data was last sent or received, for the idle timeout.
+	lastActiveTime uint32
 
 	mss       uint32
 	roundTrip *RoundTripInfo
@@ -198,6 +232,16 @@ type Connection struct {
 	sendingWorker   *SendingWorker
 
 	output SegmentWriter
+	fec    *fecDecoder
+	pmtud  *pathMTUDiscovery
+	// The writer that batches the packets of each flush, if supported by the socket.
+	batch *batchWriter
+
+	metrics *metricsRecorder
+	// The error that terminated the connection, returned by further reads and writes.
+	failure atomic.Value
+	// The remote address, which changes if the peer migrates.
+	remoteAddr atomic.Value
 
 	dataUpdater *Updater
 	pingUpdater *Updater
@@ -205,51 +249,103 @@ type Connection struct {
 
 // NewConnection create a new KCP connection between local and remote.
 func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, config *Config) *Connection {
+	conn := newConnection(meta, writer, closer, config, nowMillisec)
+	conn.pingUpdater.WakeUp()
+	return conn
+}
+
+// newConnection creates a connection on the clock, which returns the time in milli-sec. The connection is not updated
+// until its updaters are woken up.
+func newConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, config *Config, clock func() int64) *Connection {
 	newError("#", meta.Conversation, " creating connection to ", meta.RemoteAddr).WriteToLog()
 
+	segmentWriter := &SimpleSegmentWriter{
+		writer: writer,
+		buffer: buf.New(),
+	}
+	overhead := uint32(writer.Overhead())
+	if meta.MigrationToken != 0 {
+		segmentWriter.prefix = &MigrationSegment{Conv: meta.Conversation, Token: meta.MigrationToken}
+		overhead += MigrationSegmentSize
+	}
+
 	conn := &Connection{
 		meta:       meta,
 		closer:     closer,
-		since:      nowMillisec(),
+		clock:      clock,
+		since:      clock(),
 		dataInput:  signal.NewNotifier(),
 		dataOutput: signal.NewNotifier(),
 		Config:     config,
-		output:     NewRetryableWriter(NewSegmentWriter(writer)),
-		mss:        config.GetMTUValue() - uint32(writer.Overhead()) - DataSegmentOverhead,
+		output:     newPriorityWriter(NewRetryableWriter(segmentWriter)),
+		mss:        config.GetMTUValue() - overhead - DataSegmentOverhead - config.GetFECOverhead(),
 		roundTrip: &RoundTripInfo{
-			rto:    100,
-			minRtt: config.GetTTIValue(),
+			granularity: config.GetTTIValue(),
+			minRto:      config.GetRTOMinValue(),
+			maxRto:      config.GetRTOMaxValue(),
 		},
 	}
+	conn.roundTrip.rto = conn.roundTrip.bound(100)
+	conn.pingInterval = conn.nextPingInterval()
+	conn.remoteAddr.Store(&meta.RemoteAddr)
+
+	if w, ok := writer.(*KCPPacketWriter); ok {
+		conn.batch, _ = w.Writer.(*batchWriter)
+	}
+
+	if meta.Trace != nil {
+		conn.output = &tracingWriter{writer: conn.output, session: meta.Trace}
+	}
+
+	if dataShards, _ := config.GetFECShards(); dataShards > 0 {
+		conn.fec = newFECDecoder(dataShards)
+	}
+	if config.PathMtuDiscovery && config.GetMTUValue() > pmtuBase {
+		conn.pmtud = newPathMTUDiscovery(conn, overhead, config.GetFECOverhead(), config.GetMTUValue())
+		conn.mss = conn.pmtud.MSS(pmtuBase)
+	}
 
 	conn.receivingWorker = NewReceivingWorker(conn)
 	conn.sendingWorker = NewSendingWorker(conn)
 
+	if config.Metrics && meta.Stats != nil {
+		conn.metrics = newMetricsRecorder(meta.Stats, meta)
+	}
+
 	isTerminating := func() bool {
 		return conn.State().Is(StateTerminating, StateTerminated)
 	}
 	isTerminated := func() bool {
 		return conn.State() == StateTerminated
 	}
+	interval := config.GetTTIValue()
+	if config.Pacing && interval > pacingInterval {
+		// Paced segments are sent in smaller batches of more frequent flushes.
+		interval = pacingInterval
+	}
 	conn.dataUpdater = NewUpdater(
-		config.GetTTIValue(),
+		interval,
 		func() bool {
 			return !isTerminating() && (conn.sendingWorker.UpdateNecessary() || conn.receivingWorker.UpdateNecessary())
 		},
 		isTerminating,
 		conn.updateTask)
+	pingInterval := uint32(5000) // 5 seconds
+	if keepAlive := config.GetKeepAliveInterval(); keepAlive > 0 && keepAlive < pingInterval {
+		// Wakes up often enough for shorter keep-alive intervals.
+		pingInterval = keepAlive
+	}
 	conn.pingUpdater = NewUpdater(
-		5000, // 5 seconds
+		pingInterval,
 		func() bool { return !isTerminated() },
 		isTerminated,
 		conn.updateTask)
-	conn.pingUpdater.WakeUp()
 
 	return conn
 }
 
 func (c *Connection) Elapsed() uint32 {
-	return uint32(nowMillisec() - c.since)
+	return uint32(c.clock() - c.since)
 }
 
 // ReadMultiBuffer implements buf.Reader.
@@ -260,7 +356,7 @@ func (c *Connection) ReadMultiBuffer() (buf.MultiBuffer, error) {
 
 	for {
 		if c.State().Is(StateReadyToClose, StateTerminating, StateTerminated) {
-			return nil, io.EOF
+			return nil, c.closedError(io.EOF)
 		}
 		mb := c.receivingWorker.ReadMultiBuffer()
 		if !mb.IsEmpty() {
@@ -278,20 +374,53 @@ func (c *Connection) ReadMultiBuffer() (buf.MultiBuffer, error) {
 	}
 }
 
-func (c *Connection) waitForDataInput() error {
+// deadline is the deadline of reads or writes. Changing it wakes up the goroutines waiting for it.
+type deadline struct {
+	sync.Mutex
+	t       time.Time
+	changed chan struct{}
+}
+
+// Set sets the deadline. A zero time value disables the deadline.
+func (d *deadline) Set(t time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.t = t
+	if d.changed != nil {
+		close(d.changed)
+		d.changed = nil
+	}
+}
+
+// Get returns the deadline, and a channel that is closed when it is changed.
+func (d *deadline) Get() (time.Time, <-chan struct{}) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.changed == nil {
+		d.changed = make(chan struct{})
+	}
+	return d.t, d.changed
+}
+
+// waitForSignal waits until n is signaled, the deadline is changed, or 16 seconds pass. It returns ErrIOTimeout if the
+// deadline is exceeded.
+func waitForSignal(n *signal.Notifier, d *deadline) error {
 	for i := 0; i < 16; i++ {
 		select {
-		case <-c.dataInput.Wait():
+		case <-n.Wait():
 			return nil
 		default:
 			runtime.Gosched()
 		}
 	}
 
+	t, changed := d.Get()
 	duration := time.Second * 16
-	if !c.rd.IsZero() {
-		duration = time.Until(c.rd)
-		if duration < 0 {
+	if !t.IsZero() {
+		duration = time.Until(t)
+		if duration <= 0 {
 			return ErrIOTimeout
 		}
 	}
@@ -300,9 +429,10 @@ func (c *Connection) waitForDataInput() error {
 	defer timeout.Stop()
 
 	select {
-	case <-c.dataInput.Wait():
+	case <-n.Wait():
+	case <-changed:
 	case <-timeout.C:
-		if !c.rd.IsZero() && c.rd.Before(time.Now()) {
+		if !t.IsZero() {
 			return ErrIOTimeout
 		}
 	}
@@ -310,6 +440,10 @@ func (c *Connection) waitForDataInput() error {
 	return nil
 }
 
+func (c *Connection) waitForDataInput() error {
+	return waitForSignal(c.dataInput, &c.rd)
+}
+
 // Read implements the Conn Read method.
 func (c *Connection) Read(b []byte) (int, error) {
 	if c == nil {
@@ -318,7 +452,7 @@ func (c *Connection) Read(b []byte) (int, error) {
 
 	for {
 		if c.State().Is(StateReadyToClose, StateTerminating, StateTerminated) {
-			return 0, io.EOF
+			return 0, c.closedError(io.EOF)
 		}
 		nBytes := c.receivingWorker.Read(b)
 		if nBytes > 0 {
@@ -333,57 +467,47 @@ func (c *Connection) Read(b []byte) (int, error) {
 }
 
 func (c *Connection) waitForDataOutput() error {
-	for i := 0; i < 16; i++ {
-		select {
-		case <-c.dataOutput.Wait():
-			return nil
-		default:
-			runtime.Gosched()
-		}
-	}
-
-	duration := time.Second * 16
-	if !c.wd.IsZero() {
-		duration = time.Until(c.wd)
-		if duration < 0 {
-			return ErrIOTimeout
-		}
-	}
-
-	timeout := time.NewTimer(duration)
-	defer timeout.Stop()
-
-	select {
-	case <-c.dataOutput.Wait():
-	case <-timeout.C:
-		if !c.wd.IsZero() && c.wd.Before(time.Now()) {
-			return ErrIOTimeout
-		}
-	}
-
-	return nil
+	return waitForSignal(c.dataOutput, &c.wd)
 }
 
 // Write implements io.Writer.
 func (c *Connection) Write(b []byte) (int, error) {
 	reader := bytes.NewReader(b)
-	if err := c.writeMultiBufferInternal(reader); err != nil {
-		return 0, err
-	}
-	return len(b), nil
+	return c.writeMultiBufferInternal(func() *buf.Buffer {
+		b := buf.New()
+		if _, err := b.ReadFrom(io.LimitReader(reader, int64(atomic.LoadUint32(&c.mss)))); err != nil {
+			b.Release()
+			return nil
+		}
+		return b
+	})
 }
 
-// WriteMultiBuffer implements buf.Writer.
+// WriteMultiBuffer implements buf.Writer. The buffers in mb are sent as they are, without copying them.
 func (c *Connection) WriteMultiBuffer(mb buf.MultiBuffer) error {
-	reader := &buf.MultiBufferContainer{
-		MultiBuffer: mb,
-	}
-	defer reader.Close()
+	defer func() {
+		buf.ReleaseMulti(mb)
+	}()
 
-	return c.writeMultiBufferInternal(reader)
+	_, err := c.writeMultiBufferInternal(func() *buf.Buffer {
+		for len(mb) > 0 {
+			b := mb[0]
+			mb[0] = nil
+			mb = mb[1:]
+			if !b.IsEmpty() {
+				return b
+			}
+			b.Release()
+		}
+		return nil
+	})
+	return err
 }
 
-func (c *Connection) writeMultiBufferInternal(reader io.Reader) error {
+// writeMultiBufferInternal writes the buffers returned by next until it returns nil, and returns the number of bytes
+// written, even if it fails. The buffers are owned by the connection afterwards.
+func (c *Connection) writeMultiBufferInternal(next func() *buf.Buffer) (int, error) {
+	written := 0
 	updatePending := false
 	defer func() {
 		if updatePending {
@@ -396,21 +520,26 @@ func (c *Connection) writeMultiBufferInternal(reader io.Reader) error {
 
 	for {
 		for {
-			if c == nil || c.State() != StateActive {
-				return io.ErrClosedPipe
+			if c == nil {
+				return written, io.ErrClosedPipe
+			}
+			if c.State() != StateActive {
+				return written, c.closedError(io.ErrClosedPipe)
 			}
 
 			if b == nil {
-				b = buf.New()
-				_, err := b.ReadFrom(io.LimitReader(reader, int64(c.mss)))
-				if err != nil {
-					return nil
+				b = next()
+				if b == nil {
+					return written, nil
 				}
 			}
 
+			size := int(b.Len())
 			if !c.sendingWorker.Push(b) {
 				break
 			}
+			atomic.StoreUint32(&c.lastActiveTime, c.Elapsed())
+			written += size
 			updatePending = true
 			b = nil
 		}
@@ -421,7 +550,7 @@ func (c *Connection) writeMultiBufferInternal(reader io.Reader) error {
 		}
 
 		if err := c.waitForDataOutput(); err != nil {
-			return err
+			return written, err
 		}
 	}
 }
@@ -454,6 +583,35 @@ func (c *Connection) SetState(state State) {
 	}
 }
 
+// fail terminates the connection at once, without waiting for the peer. Further reads and writes return err.
+func (c *Connection) fail(err error) {
+	if c.State() == StateTerminated {
+		return
+	}
+	c.failure.Store(&err)
+	newError("#", c.meta.Conversation, " connection to ", c.RemoteAddr(), " failed").Base(err).AtWarning().WriteToLog()
+	c.SetState(StateTerminated)
+	c.dataInput.Signal()
+	c.dataOutput.Signal()
+}
+
+// closeIdle closes the connection after it has been idle for too long. Further reads and writes return
+// ErrIdleTimeout.
+func (c *Connection) closeIdle() {
+	err := error(ErrIdleTimeout)
+	c.failure.Store(&err)
+	newError("#", c.meta.Conversation, " connection to ", c.RemoteAddr(), " is idle").AtInfo().WriteToLog()
+	c.Close()
+}
+
+// closedError returns the error that terminated the connection, or err if it is closed normally.
+func (c *Connection) closedError(err error) error {
+	if failure, ok := c.failure.Load().(*error); ok {
+		return *failure
+	}
+	return err
+}
+
 // Close closes the connection.
 func (c *Connection) Close() error {
 	if c == nil {
@@ -474,7 +632,7 @@ func (c *Connection) Close() error {
 		c.SetState(StateTerminated)
 	}
 
-	newError("#", c.meta.Conversation, " closing connection to ", c.meta.RemoteAddr).WriteToLog()
+	newError("#", c.meta.Conversation, " closing connection to ", c.RemoteAddr()).WriteToLog()
 
 	return nil
 }
@@ -487,12 +645,23 @@ func (c *Connection) LocalAddr() net.Addr {
 	return c.meta.LocalAddr
 }
 
+// TraceSession implements trace.Carrier.
+func (c *Connection) TraceSession() *trace.Session {
+	return c.meta.Trace
+}
+
 // RemoteAddr returns the remote network address. The Addr returned is shared by all invocations of RemoteAddr, so do not modify it.
 func (c *Connection) RemoteAddr() net.Addr {
 	if c == nil {
 		return nil
 	}
-	return c.meta.RemoteAddr
+	return *c.remoteAddr.Load().(*net.Addr)
+}
+
+// migrate changes the remote address of the connection, after the peer moved to addr.
+func (c *Connection) migrate(addr net.Addr) {
+	newError("#", c.meta.Conversation, " migrating connection from ", c.RemoteAddr(), " to ", addr).AtInfo().WriteToLog()
+	c.remoteAddr.Store(&addr)
 }
 
 // SetDeadline sets the deadline associated with the listener. A zero time value disables the deadline.
@@ -503,21 +672,22 @@ func (c *Connection) SetDeadline(t time.Time) error {
 	return c.SetWriteDeadline(t)
 }
 
-// SetReadDeadline implements the Conn SetReadDeadline method.
+// SetReadDeadline implements the Conn SetReadDeadline method. It applies to pending reads too.
 func (c *Connection) SetReadDeadline(t time.Time) error {
-	if c == nil || c.State() != StateActive {
+	if c == nil || c.State() == StateTerminated {
 		return ErrClosedConnection
 	}
-	c.rd = t
+	c.rd.Set(t)
 	return nil
 }
 
-// SetWriteDeadline implements the Conn SetWriteDeadline method.
+// SetWriteDeadline implements the Conn SetWriteDeadline method. It applies to pending writes too, which are blocked
+// by a full sending window.
 func (c *Connection) SetWriteDeadline(t time.Time) error {
-	if c == nil || c.State() != StateActive {
+	if c == nil || c.State() == StateTerminated {
 		return ErrClosedConnection
 	}
-	c.wd = t
+	c.wd.Set(t)
 	return nil
 }
 
@@ -539,6 +709,19 @@ func (c *Connection) Terminate() {
 	c.closer.Close()
 	c.sendingWorker.Release()
 	c.receivingWorker.Release()
+	if c.metrics != nil {
+		c.metrics.Close()
+	}
+}
+
+// Metrics returns the current metrics of the connection.
+func (c *Connection) Metrics() Metrics {
+	m := Metrics{
+		RTT: c.roundTrip.SmoothedTime(),
+		RTO: c.roundTrip.Timeout(),
+	}
+	c.sendingWorker.fillMetrics(&m)
+	return m
 }
 
 func (c *Connection) HandleOption(opt SegmentOption) {
@@ -565,15 +748,44 @@ func (c *Connection) Input(segments []Segment) {
 		if seg.Conversation() != c.meta.Conversation {
 			break
 		}
+		traceSegment(c.meta.Trace, trace.Receive, seg)
 
 		switch seg := seg.(type) {
 		case *DataSegment:
+			atomic.StoreUint32(&c.lastActiveTime, current)
+			if c.fec != nil {
+				c.fec.AddData(seg)
+			}
 			c.HandleOption(seg.Option)
 			c.receivingWorker.ProcessSegment(seg)
 			if c.receivingWorker.IsDataAvailable() {
 				c.dataInput.Signal()
 			}
 			c.dataUpdater.WakeUp()
+		case *FECSegment:
+			if c.fec == nil {
+				continue
+			}
+			for _, dataSeg := range c.fec.AddParity(seg) {
+				c.HandleOption(dataSeg.Option)
+				c.receivingWorker.ProcessSegment(dataSeg)
+			}
+			if c.receivingWorker.IsDataAvailable() {
+				c.dataInput.Signal()
+			}
+			c.dataUpdater.WakeUp()
+		case *ProbeSegment:
+			if seg.Cmd == CommandProbe {
+				// Probes are acknowledged even if path MTU discovery is disabled locally.
+				ack := NewProbeSegment()
+				ack.Conv = c.meta.Conversation
+				ack.Cmd = CommandProbeAck
+				ack.Size = seg.Size
+				c.output.Write(ack)
+				ack.Release()
+			} else if c.pmtud != nil {
+				c.pmtud.OnAck(uint32(seg.Size))
+			}
 		case *AckSegment:
 			c.HandleOption(seg.Option)
 			c.sendingWorker.ProcessSegment(current, seg, c.roundTrip.Timeout())
@@ -607,12 +819,21 @@ func (c *Connection) Input(segments []Segment) {
 func (c *Connection) flush() {
 	current := c.Elapsed()
 
+	if c.batch != nil {
+		c.batch.Begin()
+		defer c.batch.End()
+	}
+
 	if c.State() == StateTerminated {
 		return
 	}
 	if c.State() == StateActive && current-atomic.LoadUint32(&c.lastIncomingTime) >= 30000 {
 		c.Close()
 	}
+	if idleTimeout := c.Config.GetIdleTimeout() * 1000; idleTimeout > 0 && c.State().Is(StateActive, StatePeerClosed) &&
+		current-atomic.LoadUint32(&c.lastActiveTime) >= idleTimeout {
+		c.closeIdle()
+	}
 	if c.State() == StateReadyToClose && c.sendingWorker.IsEmpty() {
 		c.SetState(StateTerminating)
 	}
@@ -634,19 +855,33 @@ func (c *Connection) flush() {
 		c.SetState(StateTerminating)
 	}
 
-	// flush acknowledges
+	// Acknowledgements and pings go ahead of data segments, so that they are not delayed by a full window.
 	c.receivingWorker.Flush(current)
-	c.sendingWorker.Flush(current)
-
-	if current-atomic.LoadUint32(&c.lastPingTime) >= 3000 {
+	if current-atomic.LoadUint32(&c.lastPingTime) >= atomic.LoadUint32(&c.pingInterval) {
 		c.Ping(current, CommandPing)
 	}
+	c.sendingWorker.Flush(current)
+	if c.pmtud != nil {
+		c.pmtud.Flush(current)
+	}
+	if c.metrics != nil {
+		c.metrics.Record(c.Metrics())
+	}
 }
 
 func (c *Connection) State() State {
 	return State(atomic.LoadInt32((*int32)(&c.state)))
 }
 
+// nextPingInterval returns the keep-alive interval until the next ping, with a random jitter.
+func (c *Connection) nextPingInterval() uint32 {
+	interval := c.Config.GetKeepAliveIntervalValue()
+	if jitter := c.Config.GetKeepAliveJitter(); jitter > 0 {
+		interval += uint32(dice.Roll(int(jitter) + 1))
+	}
+	return interval
+}
+
 func (c *Connection) Ping(current uint32, cmd Command) {
 	seg := NewCmdOnlySegment()
 	seg.Conv = c.meta.Conversation
@@ -659,5 +894,6 @@ func (c *Connection) Ping(current uint32, cmd Command) {
 	}
 	c.output.Write(seg)
 	atomic.StoreUint32(&c.lastPingTime, current)
+	atomic.StoreUint32(&c.pingInterval, c.nextPingInterval())
 	seg.Release()
 }
@@ -1,11 +1,16 @@
 package kcp_test
 
 import (
+	"context"
 	"io"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
 	. "v2ray.com/core/transport/internet/kcp"
 )
 
@@ -30,6 +35,170 @@ func TestConnectionReadTimeout(t *testing.T) {
 	conn.Terminate()
 }
 
+func TestConnectionDeadlineWakesUp(t *testing.T) {
+	conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+		Writer: buf.DiscardBytes,
+	}, NoOpCloser(0), &Config{
+		WriteBuffer: &WriteBuffer{Size: 14000},
+	})
+	defer conn.Terminate()
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		conn.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	}()
+
+	start := time.Now()
+	// Segments are never acknowledged, so the write blocks when the sending buffer is full.
+	nBytes, err := conn.Write(make([]byte, 64*1024))
+	if err != ErrIOTimeout {
+		t.Error("unexpected write: ", nBytes, err)
+	}
+	if nBytes == 0 || nBytes >= 64*1024 {
+		t.Error("expect partial write, but got ", nBytes)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Error("write returned after ", d)
+	}
+
+	start = time.Now()
+	if _, err := conn.Read(make([]byte, 1024)); err != ErrIOTimeout {
+		t.Error("unexpected read: ", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Error("read returned after ", d)
+	}
+}
+
+func TestConnectionMetrics(t *testing.T) {
+	manager, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	conn := NewConnection(ConnMetadata{
+		Conversation: 1,
+		RemoteAddr:   &net.UDPAddr{IP: []byte{127, 0, 0, 1}, Port: 1234},
+		Stats:        manager,
+	}, &KCPPacketWriter{
+		Writer: buf.DiscardBytes,
+	}, NoOpCloser(0), &Config{
+		Metrics:     true,
+		WriteBuffer: &WriteBuffer{Size: 14000},
+	})
+
+	common.Must2(conn.Write(make([]byte, 1024)))
+	// Segments are never acknowledged, so they are retransmitted after the initial RTO of 100ms.
+	time.Sleep(500 * time.Millisecond)
+
+	metrics := conn.Metrics()
+	if metrics.InFlight != 1 {
+		t.Error("in flight: ", metrics.InFlight)
+	}
+	if metrics.Retransmits == 0 {
+		t.Error("no retransmits")
+	}
+	if metrics.Window == 0 || metrics.WindowOccupancy == 0 {
+		t.Error("window: ", metrics.Window, " ", metrics.WindowOccupancy)
+	}
+
+	const name = "kcp>>>127.0.0.1:1234>>>1>>>inflight"
+	if c := manager.GetCounter(name); c == nil || c.Value() != 1 {
+		t.Error("unexpected counter: ", c)
+	}
+
+	conn.Terminate()
+	if c := manager.GetCounter(name); c != nil {
+		t.Error("counter not unregistered")
+	}
+}
+
+func TestConnectionRTOBackoff(t *testing.T) {
+	conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+		Writer: buf.DiscardBytes,
+	}, NoOpCloser(0), &Config{
+		RtoMin: 200,
+		RtoMax: 1000,
+	})
+	defer conn.Terminate()
+
+	if rto := conn.Metrics().RTO; rto != 200 {
+		t.Error("initial RTO: ", rto)
+	}
+
+	// The RTO doubles on each timeout of the unacknowledged segment, up to the upper bound.
+	common.Must2(conn.Write(make([]byte, 1024)))
+	time.Sleep(2 * time.Second)
+	if rto := conn.Metrics().RTO; rto != 1000 {
+		t.Error("RTO after timeouts: ", rto)
+	}
+}
+
+func TestConnectionDeadLink(t *testing.T) {
+	conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+		Writer: buf.DiscardBytes,
+	}, NoOpCloser(0), &Config{
+		MaxRetransmit: 2,
+	})
+	defer conn.Terminate()
+
+	common.Must2(conn.Write(make([]byte, 1024)))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1024)); err != ErrDeadLink {
+		t.Error("unexpected read error: ", err)
+	}
+	if _, err := conn.Write(make([]byte, 1024)); err != ErrDeadLink {
+		t.Error("unexpected write error: ", err)
+	}
+}
+
+func TestConnectionIdleTimeout(t *testing.T) {
+	conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+		Writer: buf.DiscardBytes,
+	}, NoOpCloser(0), &Config{
+		IdleTimeout: 1,
+	})
+	defer conn.Terminate()
+
+	// The idle timeout is checked on each ping, every 5 seconds.
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Read(make([]byte, 1024)); err != ErrIdleTimeout {
+		t.Error("unexpected read error: ", err)
+	}
+	if _, err := conn.Write(make([]byte, 1024)); err != ErrIdleTimeout {
+		t.Error("unexpected write error: ", err)
+	}
+}
+
+type packetCounter uint32
+
+func (c *packetCounter) Write(b []byte) (int, error) {
+	atomic.AddUint32((*uint32)(c), 1)
+	return len(b), nil
+}
+
+func TestConnectionKeepAlive(t *testing.T) {
+	countPings := func(config *Config) uint32 {
+		counter := new(packetCounter)
+		conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+			Writer: counter,
+		}, NoOpCloser(0), config)
+		defer conn.Terminate()
+
+		// Without data, only pings are sent.
+		time.Sleep(time.Second)
+		return atomic.LoadUint32((*uint32)(counter))
+	}
+
+	if n := countPings(&Config{}); n != 0 {
+		t.Error("pings in 1 second by default: ", n)
+	}
+	if n := countPings(&Config{KeepAliveInterval: 100}); n < 5 || n > 12 {
+		t.Error("pings in 1 second at 100ms interval: ", n)
+	}
+	if n := countPings(&Config{KeepAliveInterval: 100, KeepAliveJitter: 100}); n < 3 || n > 12 {
+		t.Error("pings in 1 second at 100ms interval and 100ms jitter: ", n)
+	}
+}
+
 func TestConnectionInterface(t *testing.T) {
 	_ = (io.Writer)(new(Connection))
 	_ = (io.Reader)(new(Connection))
@@ -5,9 +5,6 @@ package kcp
 import (
 	"crypto/cipher"
 	"encoding/binary"
-	"hash/fnv"
-
-	"v2ray.com/core/common"
 )
 
 // SimpleAuthenticator is a legacy AEAD used for KCP encryption.
@@ -28,20 +25,21 @@ func (*SimpleAuthenticator) Overhead() int {
 	return 6
 }
 
-// Seal implements cipher.AEAD.Seal().
+// Seal implements cipher.AEAD.Seal(). To encrypt in place, use plain[:0] as dst.
 func (a *SimpleAuthenticator) Seal(dst, nonce, plain, extra []byte) []byte {
-	dst = append(dst, 0, 0, 0, 0, 0, 0) // 4 bytes for hash, and then 2 bytes for length
-	binary.BigEndian.PutUint16(dst[4:], uint16(len(plain)))
-	dst = append(dst, plain...)
-
-	fnvHash := fnv.New32a()
-	common.Must2(fnvHash.Write(dst[4:]))
-	fnvHash.Sum(dst[:0])
+	dst, out := sliceForAppend(dst, len(plain)+6) // 4 bytes for hash, and then 2 bytes for length
+	copy(out[6:], plain)
+	binary.BigEndian.PutUint16(out[4:], uint16(len(plain)))
+	binary.BigEndian.PutUint32(out, fnvHash32a(out[4:]))
 
 	dstLen := len(dst)
 	xtra := 4 - dstLen%4
 	if xtra != 4 {
-		dst = append(dst, make([]byte, xtra)...)
+		if cap(dst)-dstLen >= xtra {
+			dst = dst[:dstLen+xtra]
+		} else {
+			dst = append(dst, make([]byte, xtra)...)
+		}
 	}
 	xorfwd(dst)
 	if xtra != 4 {
@@ -63,9 +61,7 @@ func (a *SimpleAuthenticator) Open(dst, nonce, cipherText, extra []byte) ([]byte
 		dst = dst[:dstLen]
 	}
 
-	fnvHash := fnv.New32a()
-	common.Must2(fnvHash.Write(dst[4:]))
-	if binary.BigEndian.Uint32(dst[:4]) != fnvHash.Sum32() {
+	if binary.BigEndian.Uint32(dst[:4]) != fnvHash32a(dst[4:]) {
 		return nil, newError("invalid auth")
 	}
 
@@ -76,3 +72,30 @@ func (a *SimpleAuthenticator) Open(dst, nonce, cipherText, extra []byte) ([]byte
 
 	return dst[6:], nil
 }
+
+// fnvHash32a returns the FNV-1a hash of b. Unlike hash/fnv, it doesn't allocate.
+func fnvHash32a(b []byte) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= prime32
+	}
+	return h
+}
+
+// sliceForAppend extends in by n bytes, and returns the whole slice as well as the extended part.
+// The underlying array of in is reused if it has enough capacity.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
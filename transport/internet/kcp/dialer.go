@@ -4,96 +4,142 @@ package kcp
 
 import (
 	"context"
-	"crypto/tls"
 	"io"
-	"sync/atomic"
+	"sync"
 
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/net"
+	udp_proto "v2ray.com/core/common/protocol/udp"
 	"v2ray.com/core/transport/internet"
-	v2tls "v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/udp"
 )
 
 var (
 	globalConv = uint32(dice.RollUint16())
 )
 
-func fetchInput(ctx context.Context, input io.Reader, reader PacketReader, conn *Connection) {
-	cache := make(chan *buf.Buffer, 1024)
-	go func() {
-		for {
-			payload := buf.New()
-			if _, err := payload.ReadFrom(input); err != nil {
-				payload.Release()
-				close(cache)
-				return
-			}
-			select {
-			case cache <- payload:
-			default:
-				payload.Release()
-			}
-		}
-	}()
+// inputReader reads the packets of a dialed connection.
+type inputReader struct {
+	input io.Reader
+	// The socket, if packets are read with control messages, for ECN or GRO.
+	conn *net.UDPConn
+	oob  []byte
+	// The buffer, into which packets coalesced by GRO are read, if GRO is enabled.
+	large []byte
+}
 
-	for payload := range cache {
-		segments := reader.Read(payload.Bytes())
-		payload.Release()
-		if len(segments) > 0 {
-			conn.Input(segments)
+// Read reads the next packets. There are multiple of them only if they are coalesced by GRO.
+func (r *inputReader) Read() ([]*udp_proto.Packet, error) {
+	if r.conn == nil {
+		packet := &udp_proto.Packet{Payload: buf.New()}
+		if _, err := packet.Payload.ReadFrom(r.input); err != nil {
+			packet.Payload.Release()
+			return nil, err
 		}
+		return []*udp_proto.Packet{packet}, nil
 	}
-}
 
-// DialKCP dials a new KCP connections to the specific destination.
-func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
-	dest.Network = net.Network_UDP
-	newError("dialing mKCP to ", dest).WriteToLog()
-
-	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
-	if err != nil {
-		return nil, newError("failed to dial to dest: ", err).AtWarning().Base(err)
+	if r.large == nil {
+		packet := &udp_proto.Packet{Payload: buf.New()}
+		n, noob, _, _, err := udp.ReadUDPMsg(r.conn, packet.Payload.Extend(buf.Size), r.oob)
+		if err != nil {
+			packet.Payload.Release()
+			return nil, err
+		}
+		packet.Payload.Resize(0, int32(n))
+		if noob > 0 {
+			packet.ECN = udp.RetrieveECN(r.oob[:noob])
+		}
+		return []*udp_proto.Packet{packet}, nil
 	}
 
-	kcpSettings := streamSettings.ProtocolSettings.(*Config)
-
-	header, err := kcpSettings.GetPackerHeader()
+	n, noob, _, _, err := udp.ReadUDPMsg(r.conn, r.large, r.oob)
 	if err != nil {
-		return nil, newError("failed to create packet header").Base(err)
+		return nil, err
 	}
-	security, err := kcpSettings.GetSecurity()
-	if err != nil {
-		return nil, newError("failed to create security").Base(err)
+	var ecn byte
+	groSize := 0
+	if noob > 0 {
+		ecn = udp.RetrieveECN(r.oob[:noob])
+		groSize = udp.RetrieveGROSize(r.oob[:noob])
 	}
-	reader := &KCPPacketReader{
-		Header:   header,
-		Security: security,
-	}
-	writer := &KCPPacketWriter{
-		Header:   header,
-		Security: security,
-		Writer:   rawConn,
+	var packets []*udp_proto.Packet
+	for _, datagram := range udp.SplitGRO(r.large[:n], groSize) {
+		if len(datagram) > buf.Size {
+			continue
+		}
+		packet := &udp_proto.Packet{Payload: buf.New(), ECN: ecn}
+		common.Must2(packet.Payload.Write(datagram))
+		packets = append(packets, packet)
 	}
+	return packets, nil
+}
+
+type streamSessionKey struct {
+	dest   net.Destination
+	config *Config
+}
 
-	conv := uint16(atomic.AddUint32(&globalConv, 1))
-	session := NewConnection(ConnMetadata{
-		LocalAddr:    rawConn.LocalAddr(),
-		RemoteAddr:   rawConn.RemoteAddr(),
-		Conversation: conv,
-	}, writer, rawConn, kcpSettings)
+var streamSessions = struct {
+	sync.Mutex
+	sessions map[streamSessionKey]*StreamSession
+}{
+	sessions: make(map[streamSessionKey]*StreamSession),
+}
 
-	go fetchInput(ctx, rawConn, reader, session)
+// dialStream opens a stream in the StreamSession to the destination, or in a new one if there is none.
+func dialStream(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
+	key := streamSessionKey{dest: dest, config: streamSettings.ProtocolSettings.(*Config)}
 
-	var iConn internet.Connection = session
+	streamSessions.Lock()
+	defer streamSessions.Unlock()
 
-	if config := v2tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		tlsConn := tls.Client(iConn, config.GetTLSConfig(v2tls.WithDestination(dest)))
-		iConn = tlsConn
+	if session, found := streamSessions.sessions[key]; found {
+		if conn, err := session.OpenStream(); err == nil {
+			return conn, nil
+		}
+		delete(streamSessions.sessions, key)
 	}
 
-	return iConn, nil
+	conn, err := dialConnection(ctx, dest, streamSettings)
+	if err != nil {
+		return nil, err
+	}
+	session := NewStreamSession(conn, nil)
+	session.onClose = func() {
+		streamSessions.Lock()
+		if streamSessions.sessions[key] == session {
+			delete(streamSessions.sessions, key)
+		}
+		streamSessions.Unlock()
+	}
+	streamSessions.sessions[key] = session
+	return session.OpenStream()
+}
+
+// DialKCP dials a new KCP connections to the specific destination.
+func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
+	dest.Network = net.Network_UDP
+	if streamSettings.ProtocolSettings.(*Config).Multiplex {
+		return dialStream(ctx, dest, streamSettings)
+	}
+	return dialConnection(ctx, dest, streamSettings)
+}
+
+func dialConnection(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
+	client, err := NewClient(ctx, dest, streamSettings)
+	if err != nil {
+		return nil, err
+	}
+	client.closeWhenIdle = true
+	conn, err := client.Dial(ctx)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return conn, nil
 }
 
 func init() {
@@ -0,0 +1,321 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+const (
+	// Each FECSegment lists the DataSegments in its block, so the number of them is limited.
+	maxFECDataShards   = 32
+	maxFECParityShards = 16
+	// Number of recent blocks, whose DataSegments are kept for recovery.
+	fecHistoryBlocks = 8
+)
+
+// Arithmetic in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns the multiplicative inverse of a, which must not be 0.
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd adds c * src to dst, which is at least as long as src.
+func gfMulAdd(dst []byte, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	logC := int(gfLog[c])
+	for i, v := range src {
+		if v != 0 {
+			dst[i] ^= gfExp[logC+int(gfLog[v])]
+		}
+	}
+}
+
+// gfInvertMatrix returns the inverse of the square matrix m, which is modified. It returns nil if m is singular.
+func gfInvertMatrix(m [][]byte) [][]byte {
+	n := len(m)
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		c := gfInv(m[col][col])
+		for i := 0; i < n; i++ {
+			m[col][i] = gfMul(m[col][i], c)
+			inv[col][i] = gfMul(inv[col][i], c)
+		}
+		for row := 0; row < n; row++ {
+			if f := m[row][col]; row != col && f != 0 {
+				gfMulAdd(m[row], m[col], f)
+				gfMulAdd(inv[row], inv[col], f)
+			}
+		}
+	}
+	return inv
+}
+
+// fecCoefficient returns the coefficient of the j-th data shard in the i-th parity shard. The coefficients form a
+// Cauchy matrix, of which every square sub-matrix is invertible.
+func fecCoefficient(i int, j int) byte {
+	return gfInv(byte(maxFECDataShards+i) ^ byte(j))
+}
+
+// newFECShard returns the DataSegment serialized with its length, as a data shard of an FEC block.
+func newFECShard(seg *DataSegment) []byte {
+	size := seg.ByteSize()
+	shard := make([]byte, 2+size)
+	binary.BigEndian.PutUint16(shard, uint16(size))
+	seg.Serialize(shard[2:])
+	return shard
+}
+
+// fecEncoder groups sent DataSegments into blocks, and writes the parity shards of each block.
+type fecEncoder struct {
+	writer       SegmentWriter
+	conv         uint16
+	dataShards   int
+	parityShards int
+
+	block  uint32
+	ids    []FECShardID
+	shards [][]byte
+}
+
+func newFECEncoder(writer SegmentWriter, conv uint16, dataShards int, parityShards int) *fecEncoder {
+	return &fecEncoder{
+		writer:       writer,
+		conv:         conv,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+	}
+}
+
+// Add adds a sent DataSegment to the current block, and writes the parity shards when the block is full.
+func (e *fecEncoder) Add(seg *DataSegment) {
+	e.ids = append(e.ids, FECShardID{Number: seg.Number, Timestamp: seg.Timestamp})
+	e.shards = append(e.shards, newFECShard(seg))
+	if len(e.ids) >= e.dataShards {
+		e.Flush()
+	}
+}
+
+// Flush writes the parity shards of the current block, even if it is not full, so that the DataSegments sent before
+// a pause are protected too.
+func (e *fecEncoder) Flush() {
+	if len(e.ids) == 0 {
+		return
+	}
+
+	size := 0
+	for _, shard := range e.shards {
+		if len(shard) > size {
+			size = len(shard)
+		}
+	}
+	for i := 0; i < e.parityShards; i++ {
+		parity := make([]byte, size)
+		for j, shard := range e.shards {
+			gfMulAdd(parity, shard, fecCoefficient(i, j))
+		}
+		e.writer.Write(&FECSegment{
+			Conv:         e.conv,
+			Block:        e.block,
+			Index:        byte(i),
+			ParityShards: byte(e.parityShards),
+			DataShards:   e.ids,
+			Parity:       parity,
+		})
+	}
+
+	e.block++
+	e.ids = nil
+	e.shards = nil
+}
+
+type fecBlock struct {
+	dataShards []FECShardID
+	size       int
+	parity     map[byte][]byte
+	done       bool
+}
+
+// fecDecoder recovers lost DataSegments from the received DataSegments and FECSegments.
+type fecDecoder struct {
+	sync.Mutex
+	historySize int
+	shards      map[FECShardID][]byte
+	shardOrder  []FECShardID
+	blocks      map[uint32]*fecBlock
+	blockOrder  []uint32
+}
+
+func newFECDecoder(dataShards int) *fecDecoder {
+	return &fecDecoder{
+		historySize: dataShards * fecHistoryBlocks,
+		shards:      make(map[FECShardID][]byte),
+		blocks:      make(map[uint32]*fecBlock),
+	}
+}
+
+// AddData keeps a received DataSegment, which may be needed to recover others in its block.
+func (d *fecDecoder) AddData(seg *DataSegment) {
+	d.Lock()
+	defer d.Unlock()
+
+	id := FECShardID{Number: seg.Number, Timestamp: seg.Timestamp}
+	if _, found := d.shards[id]; found {
+		return
+	}
+	d.shards[id] = newFECShard(seg)
+	d.shardOrder = append(d.shardOrder, id)
+	if len(d.shardOrder) > d.historySize {
+		delete(d.shards, d.shardOrder[0])
+		d.shardOrder = d.shardOrder[1:]
+	}
+}
+
+func (d *fecDecoder) getBlock(seg *FECSegment) *fecBlock {
+	if block, found := d.blocks[seg.Block]; found {
+		return block
+	}
+	block := &fecBlock{
+		dataShards: seg.DataShards,
+		size:       len(seg.Parity),
+		parity:     make(map[byte][]byte),
+	}
+	d.blocks[seg.Block] = block
+	d.blockOrder = append(d.blockOrder, seg.Block)
+	if len(d.blockOrder) > fecHistoryBlocks {
+		delete(d.blocks, d.blockOrder[0])
+		d.blockOrder = d.blockOrder[1:]
+	}
+	return block
+}
+
+// AddParity adds a parity shard to its block, and returns the DataSegments of the block recovered by it.
+func (d *fecDecoder) AddParity(seg *FECSegment) []*DataSegment {
+	d.Lock()
+	defer d.Unlock()
+
+	if len(seg.DataShards) > maxFECDataShards || seg.ParityShards > maxFECParityShards {
+		return nil
+	}
+	block := d.getBlock(seg)
+	if block.done || len(block.dataShards) != len(seg.DataShards) || block.size != len(seg.Parity) {
+		return nil
+	}
+	block.parity[seg.Index] = seg.Parity
+
+	var missing []int
+	for j, id := range block.dataShards {
+		shard, found := d.shards[id]
+		if !found {
+			missing = append(missing, j)
+		} else if len(shard) > block.size {
+			block.done = true
+			return nil
+		}
+	}
+	if len(missing) == 0 {
+		block.done = true
+		return nil
+	}
+	if len(block.parity) < len(missing) {
+		return nil
+	}
+	block.done = true
+	return d.recover(block, missing)
+}
+
+func (d *fecDecoder) recover(block *fecBlock, missing []int) []*DataSegment {
+	// Each parity shard minus the known data shards is a linear combination of the missing data shards.
+	var rows []int
+	var residuals [][]byte
+	for i, parity := range block.parity {
+		if len(rows) == len(missing) {
+			break
+		}
+		residual := append([]byte(nil), parity...)
+		for j, id := range block.dataShards {
+			if shard, found := d.shards[id]; found {
+				gfMulAdd(residual, shard, fecCoefficient(int(i), j))
+			}
+		}
+		rows = append(rows, int(i))
+		residuals = append(residuals, residual)
+	}
+
+	matrix := make([][]byte, len(missing))
+	for a, i := range rows {
+		matrix[a] = make([]byte, len(missing))
+		for b, j := range missing {
+			matrix[a][b] = fecCoefficient(i, j)
+		}
+	}
+	inv := gfInvertMatrix(matrix)
+	if inv == nil {
+		return nil
+	}
+
+	var recovered []*DataSegment
+	for b, j := range missing {
+		shard := make([]byte, block.size)
+		for a, residual := range residuals {
+			gfMulAdd(shard, residual, inv[b][a])
+		}
+		size := int(binary.BigEndian.Uint16(shard))
+		if size > len(shard)-2 {
+			continue
+		}
+		seg, _ := ReadSegment(shard[2 : 2+size])
+		dataSeg, ok := seg.(*DataSegment)
+		if !ok {
+			continue
+		}
+		if id := block.dataShards[j]; dataSeg.Number != id.Number || dataSeg.Timestamp != id.Timestamp {
+			dataSeg.Release()
+			continue
+		}
+		recovered = append(recovered, dataSeg)
+	}
+	return recovered
+}
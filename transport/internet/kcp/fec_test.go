@@ -0,0 +1,109 @@
+package kcp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type segmentCollector struct {
+	segments []Segment
+}
+
+func (c *segmentCollector) Write(seg Segment) error {
+	c.segments = append(c.segments, seg)
+	return nil
+}
+
+func newTestDataSegment(number uint32, payload string) *DataSegment {
+	seg := NewDataSegment()
+	seg.Conv = 1
+	seg.Number = number
+	seg.Timestamp = 100 + number
+	seg.Data().WriteString(payload)
+	return seg
+}
+
+func TestGFInvertMatrix(t *testing.T) {
+	m := [][]byte{
+		{fecCoefficient(0, 0), fecCoefficient(0, 1), fecCoefficient(0, 2)},
+		{fecCoefficient(1, 0), fecCoefficient(1, 1), fecCoefficient(1, 2)},
+		{fecCoefficient(2, 0), fecCoefficient(2, 1), fecCoefficient(2, 2)},
+	}
+	original := [][]byte{append([]byte(nil), m[0]...), append([]byte(nil), m[1]...), append([]byte(nil), m[2]...)}
+	inv := gfInvertMatrix(m)
+	if inv == nil {
+		t.Fatal("singular matrix")
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			var v byte
+			for k := 0; k < 3; k++ {
+				v ^= gfMul(original[i][k], inv[k][j])
+			}
+			if (i == j && v != 1) || (i != j && v != 0) {
+				t.Error("product at ", i, ",", j, ": ", v)
+			}
+		}
+	}
+
+	if gfInvertMatrix([][]byte{{1, 2}, {1, 2}}) != nil {
+		t.Error("expected singular matrix")
+	}
+}
+
+func TestFECRecovery(t *testing.T) {
+	payloads := []string{"a", "bb", "ccc", "dddd", "", "ffffff", "ggggggg", "hhhhhhhh"}
+
+	collector := new(segmentCollector)
+	encoder := newFECEncoder(collector, 1, 5, 3)
+	var sent []*DataSegment
+	for i, payload := range payloads {
+		seg := newTestDataSegment(uint32(i), payload)
+		sent = append(sent, seg)
+		encoder.Add(seg)
+	}
+	// The second block has only 3 data shards.
+	encoder.Flush()
+	if len(collector.segments) != 6 {
+		t.Fatal("parity segments: ", len(collector.segments))
+	}
+
+	decoder := newFECDecoder(5)
+	// Data segments 0, 2, 3 of the first block and 6 of the second block are lost.
+	for _, i := range []int{1, 4, 5, 7} {
+		decoder.AddData(sent[i])
+	}
+
+	var recovered []*DataSegment
+	for _, seg := range collector.segments {
+		recovered = append(recovered, decoder.AddParity(seg.(*FECSegment))...)
+	}
+	if len(recovered) != 4 {
+		t.Fatal("recovered: ", len(recovered))
+	}
+	for _, seg := range recovered {
+		expected := sent[seg.Number]
+		if seg.Timestamp != expected.Timestamp {
+			t.Error("timestamp: ", seg.Timestamp)
+		}
+		if r := cmp.Diff(seg.Data().String(), payloads[seg.Number]); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestFECTooManyLosses(t *testing.T) {
+	collector := new(segmentCollector)
+	encoder := newFECEncoder(collector, 1, 4, 1)
+	for i := 0; i < 4; i++ {
+		encoder.Add(newTestDataSegment(uint32(i), "data"))
+	}
+
+	decoder := newFECDecoder(4)
+	decoder.AddData(newTestDataSegment(0, "data"))
+	decoder.AddData(newTestDataSegment(1, "data"))
+	if recovered := decoder.AddParity(collector.segments[0].(*FECSegment)); len(recovered) != 0 {
+		t.Error("recovered: ", len(recovered))
+	}
+}
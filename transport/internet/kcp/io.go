@@ -21,9 +21,19 @@ type PacketWriter interface {
 	io.Writer
 }
 
+// inPlacePacketWriter is a PacketWriter that packs a payload in the Buffer carrying it, so that the payload is not copied.
+type inPlacePacketWriter interface {
+	PacketWriter
+	// reservedSize returns the number of bytes to reserve in the Buffer before the payload.
+	reservedSize() int32
+	// writeBuffer packs the payload in b and writes it as one packet. The payload starts after reservedSize() bytes of b.
+	writeBuffer(b *buf.Buffer) error
+}
+
 type KCPPacketReader struct {
-	Security cipher.AEAD
-	Header   internet.PacketHeader
+	Security   cipher.AEAD
+	Header     internet.PacketHeader
+	Obfuscator Obfuscator
 }
 
 func (r *KCPPacketReader) Read(b []byte) []Segment {
@@ -33,6 +43,12 @@ func (r *KCPPacketReader) Read(b []byte) []Segment {
 		}
 		b = b[r.Header.Size():]
 	}
+	if r.Obfuscator != nil {
+		b = r.Obfuscator.Deobfuscate(b)
+		if b == nil {
+			return nil
+		}
+	}
 	if r.Security != nil {
 		nonceSize := r.Security.NonceSize()
 		overhead := r.Security.Overhead()
@@ -58,9 +74,10 @@ func (r *KCPPacketReader) Read(b []byte) []Segment {
 }
 
 type KCPPacketWriter struct {
-	Header   internet.PacketHeader
-	Security cipher.AEAD
-	Writer   io.Writer
+	Header     internet.PacketHeader
+	Security   cipher.AEAD
+	Obfuscator Obfuscator
+	Writer     io.Writer
 }
 
 func (w *KCPPacketWriter) Overhead() int {
@@ -71,15 +88,56 @@ func (w *KCPPacketWriter) Overhead() int {
 	if w.Security != nil {
 		overhead += w.Security.Overhead()
 	}
+	if w.Obfuscator != nil {
+		overhead += w.Obfuscator.Overhead()
+	}
 	return overhead
 }
 
+func (w *KCPPacketWriter) reservedSize() int32 {
+	size := int32(0)
+	if w.Header != nil {
+		size += w.Header.Size()
+	}
+	if w.Security != nil {
+		size += int32(w.Security.NonceSize())
+	}
+	return size
+}
+
+func (w *KCPPacketWriter) writeBuffer(b *buf.Buffer) error {
+	reserved := w.reservedSize()
+	prefix := b.BytesTo(reserved)
+
+	headerSize := int32(0)
+	if w.Header != nil {
+		headerSize = w.Header.Size()
+		w.Header.Serialize(prefix[:headerSize])
+		prefix = prefix[headerSize:]
+	}
+	if w.Security != nil {
+		common.Must2(io.ReadFull(rand.Reader, prefix))
+		payload := b.BytesFrom(reserved)
+		b.Extend(int32(w.Security.Overhead()))
+		w.Security.Seal(payload[:0], prefix, payload, nil)
+	}
+	if w.Obfuscator != nil {
+		packet := w.Obfuscator.Obfuscate(b.BytesFrom(headerSize))
+		b.Resize(0, headerSize+int32(len(packet)))
+	}
+
+	_, err := w.Writer.Write(b.Bytes())
+	return err
+}
+
 func (w *KCPPacketWriter) Write(b []byte) (int, error) {
 	bb := buf.StackNew()
 	defer bb.Release()
 
+	headerSize := int32(0)
 	if w.Header != nil {
-		w.Header.Serialize(bb.Extend(w.Header.Size()))
+		headerSize = w.Header.Size()
+		w.Header.Serialize(bb.Extend(headerSize))
 	}
 	if w.Security != nil {
 		nonceSize := w.Security.NonceSize()
@@ -91,6 +149,10 @@ func (w *KCPPacketWriter) Write(b []byte) (int, error) {
 	} else {
 		bb.Write(b)
 	}
+	if w.Obfuscator != nil {
+		packet := w.Obfuscator.Obfuscate(bb.BytesFrom(headerSize))
+		bb.Resize(0, headerSize+int32(len(packet)))
+	}
 
 	_, err := w.Writer.Write(bb.Bytes())
 	return len(b), err
@@ -1,8 +1,15 @@
 package kcp_test
 
 import (
+	"context"
+	"crypto/cipher"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/headers/srtp"
 	. "v2ray.com/core/transport/internet/kcp"
 )
 
@@ -35,3 +42,133 @@ func TestKCPPacketReader(t *testing.T) {
 	}
 
 }
+
+type packetRecorder struct {
+	packets [][]byte
+}
+
+func (r *packetRecorder) Write(b []byte) (int, error) {
+	r.packets = append(r.packets, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestKCPPacketWriterInPlace(t *testing.T) {
+	header, err := srtp.New(context.Background(), &srtp.Config{})
+	common.Must(err)
+
+	for _, security := range []cipher.AEAD{NewSimpleAuthenticator(), NewAEADAESGCMBasedOnSeed("test")} {
+		recorder := &packetRecorder{}
+		writer := NewSegmentWriter(&KCPPacketWriter{
+			Header:   header.(internet.PacketHeader),
+			Security: security,
+			Writer:   recorder,
+		})
+
+		seg := NewCmdOnlySegment()
+		seg.Conv = 1
+		seg.Cmd = CommandPing
+		seg.SendingNext = 2
+		seg.ReceivingNext = 3
+		seg.PeerRTO = 4
+		common.Must(writer.Write(seg))
+
+		reader := &KCPPacketReader{
+			Header:   header.(internet.PacketHeader),
+			Security: security,
+		}
+		segs := reader.Read(recorder.packets[0])
+		if len(segs) != 1 {
+			t.Fatal("expect 1 segment, but got ", len(segs))
+		}
+		if r := cmp.Diff(segs[0].(*CmdOnlySegment), seg); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestSegmentWriterNoAlloc(t *testing.T) {
+	writer := NewRetryableWriter(NewSegmentWriter(&KCPPacketWriter{
+		Security: NewSimpleAuthenticator(),
+		Writer:   discardWriter{},
+	}))
+
+	seg := NewDataSegment()
+	seg.Conv = 1
+	common.Must2(seg.Data().Write(make([]byte, 1024)))
+	defer seg.Release()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		common.Must(writer.Write(seg))
+	})
+	if allocs > 0 {
+		t.Error("expect no allocation, but got ", allocs)
+	}
+}
+
+func TestKCPPacketWriterObfuscation(t *testing.T) {
+	header, err := srtp.New(context.Background(), &srtp.Config{})
+	common.Must(err)
+
+	for _, name := range []string{"xor", "padding"} {
+		obfuscator, err := NewObfuscator(&Config{Obfuscation: name})
+		common.Must(err)
+
+		recorder := &packetRecorder{}
+		writer := &KCPPacketWriter{
+			Header:     header.(internet.PacketHeader),
+			Security:   NewSimpleAuthenticator(),
+			Obfuscator: obfuscator,
+			Writer:     recorder,
+		}
+
+		seg := NewCmdOnlySegment()
+		seg.Conv = 1
+		seg.Cmd = CommandPing
+		seg.SendingNext = 2
+		common.Must(NewSegmentWriter(writer).Write(seg))
+		raw := make([]byte, seg.ByteSize())
+		seg.Serialize(raw)
+		common.Must2(writer.Write(raw))
+
+		reader := &KCPPacketReader{
+			Header:     header.(internet.PacketHeader),
+			Security:   NewSimpleAuthenticator(),
+			Obfuscator: obfuscator,
+		}
+		for _, packet := range recorder.packets {
+			if len(packet) > len(raw)+writer.Overhead() {
+				t.Error(name, ": packet of ", len(packet), " bytes exceeds overhead ", writer.Overhead())
+			}
+			segs := reader.Read(packet)
+			if len(segs) != 1 {
+				t.Fatal(name, ": expect 1 segment, but got ", len(segs))
+			}
+			if r := cmp.Diff(segs[0].(*CmdOnlySegment), seg); r != "" {
+				t.Error(r)
+			}
+		}
+
+		plain := &KCPPacketReader{
+			Header:   header.(internet.PacketHeader),
+			Security: NewSimpleAuthenticator(),
+		}
+		if name == "xor" && len(plain.Read(recorder.packets[0])) != 0 {
+			t.Error("expect obfuscated packet to be unreadable without obfuscator")
+		}
+	}
+}
+
+func TestNewObfuscatorUnknown(t *testing.T) {
+	if _, err := NewObfuscator(&Config{Obfuscation: "rot13"}); err == nil {
+		t.Error("expect error for unknown obfuscator")
+	}
+	if obfuscator, err := NewObfuscator(&Config{}); err != nil || obfuscator != nil {
+		t.Error("expect no obfuscator, but got ", obfuscator, err)
+	}
+}
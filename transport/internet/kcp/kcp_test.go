@@ -1,9 +1,14 @@
 package kcp_test
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,10 +16,12 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/errors"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
 	. "v2ray.com/core/transport/internet/kcp"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 func TestDialAndListen(t *testing.T) {
@@ -83,3 +90,468 @@ func TestDialAndListen(t *testing.T) {
 		t.Error("active connections: ", v)
 	}
 }
+
+func testEcho(t *testing.T, config *Config) {
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	clientSend := make([]byte, 1024*1024)
+	rand.Read(clientSend)
+	go clientConn.Write(clientSend)
+
+	clientReceived := make([]byte, 1024*1024)
+	common.Must2(io.ReadFull(clientConn, clientReceived))
+	if r := cmp.Diff(clientReceived, clientSend); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestDialAndListenWithMultiBuffer(t *testing.T) {
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	// Full buffers are larger than the MSS, and split into segments.
+	clientSend := make([]byte, 1024*1024)
+	rand.Read(clientSend)
+	go clientConn.(buf.Writer).WriteMultiBuffer(buf.MergeBytes(nil, clientSend))
+
+	clientReceived := make([]byte, 1024*1024)
+	common.Must2(io.ReadFull(clientConn, clientReceived))
+	if r := cmp.Diff(clientReceived, clientSend); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestDialAndListenWithAckRange(t *testing.T) {
+	testEcho(t, &Config{
+		Congestion:        true,
+		CongestionControl: "bbr",
+		AckRange:          true,
+	})
+}
+
+func TestDialAndListenWithPacing(t *testing.T) {
+	testEcho(t, &Config{
+		Congestion: true,
+		Pacing:     true,
+	})
+}
+
+func TestDialAndListenWithPathMTUDiscovery(t *testing.T) {
+	testEcho(t, &Config{
+		PathMtuDiscovery: true,
+	})
+}
+
+func TestDialAndListenWithAutoTuneWindow(t *testing.T) {
+	testEcho(t, &Config{
+		AutoTuneWindow: true,
+	})
+}
+
+// udpRelay forwards packets between a client and a server, from a source address that can be changed.
+type udpRelay struct {
+	sync.Mutex
+	conn     *net.UDPConn
+	upstream *net.UDPConn
+	server   *net.UDPAddr
+	client   *net.UDPAddr
+}
+
+func newUDPRelay(server *net.UDPAddr) *udpRelay {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	r := &udpRelay{conn: conn, server: server}
+	r.switchUpstream()
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			r.Lock()
+			r.client = addr
+			upstream := r.upstream
+			r.Unlock()
+			upstream.WriteToUDP(b[:n], server)
+		}
+	}()
+	return r
+}
+
+// switchUpstream sends further packets to the server from a new address.
+func (r *udpRelay) switchUpstream() {
+	upstream, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	r.Lock()
+	if r.upstream != nil {
+		r.upstream.Close()
+	}
+	r.upstream = upstream
+	r.Unlock()
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, err := upstream.Read(b)
+			if err != nil {
+				return
+			}
+			r.Lock()
+			client := r.client
+			r.Unlock()
+			r.conn.WriteToUDP(b[:n], client)
+		}
+	}()
+}
+
+func (r *udpRelay) Close() {
+	r.Lock()
+	defer r.Unlock()
+	r.conn.Close()
+	r.upstream.Close()
+}
+
+// TestNoMigration checks that packets from a new address never take over an existing connection, as any client
+// knowing the seed could send them.
+func TestNoMigration(t *testing.T) {
+	config := &Config{
+		Seed: &EncryptionSeed{Seed: "migration"},
+	}
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	relay := newUDPRelay(listerner.Addr().(*net.UDPAddr))
+	defer relay.Close()
+
+	port := net.Port(relay.conn.LocalAddr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	clientSend := make([]byte, 1024)
+	rand.Read(clientSend)
+	common.Must2(clientConn.Write(clientSend))
+	clientReceived := make([]byte, 1024)
+	common.Must2(io.ReadFull(clientConn, clientReceived))
+	if r := cmp.Diff(clientReceived, clientSend); r != "" {
+		t.Error(r)
+	}
+
+	relay.switchUpstream()
+	common.Must2(clientConn.Write(clientSend))
+	deadline := time.Now().Add(time.Second * 5)
+	for listerner.ActiveConnections() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 50)
+	}
+	if v := listerner.ActiveConnections(); v != 2 {
+		t.Error("active connections: ", v)
+	}
+}
+
+func TestMigration(t *testing.T) {
+	config := &Config{
+		Seed:      &EncryptionSeed{Seed: "migration"},
+		Migration: true,
+	}
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	relay := newUDPRelay(listerner.Addr().(*net.UDPAddr))
+	defer relay.Close()
+
+	conv := AllocateConversation()
+	relayClient, err := NewClient(context.Background(), net.DestinationFromAddr(relay.conn.LocalAddr()), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer relayClient.Close()
+	clientConn, err := relayClient.DialConversation(context.Background(), conv)
+	common.Must(err)
+	defer clientConn.Close()
+
+	echo := func(conn internet.Connection) {
+		clientSend := make([]byte, 64*1024)
+		rand.Read(clientSend)
+		go conn.Write(clientSend)
+
+		clientReceived := make([]byte, 64*1024)
+		common.Must2(io.ReadFull(conn, clientReceived))
+		if r := cmp.Diff(clientReceived, clientSend); r != "" {
+			t.Error(r)
+		}
+	}
+
+	echo(clientConn)
+	relay.switchUpstream()
+	echo(clientConn)
+	if v := listerner.ActiveConnections(); v != 1 {
+		t.Error("active connections: ", v)
+	}
+
+	// Another client of the seed can't take over the connection, without its token.
+	client, err := NewClient(context.Background(), net.DestinationFromAddr(listerner.Addr()), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer client.Close()
+	otherConn, err := client.DialConversation(context.Background(), conv)
+	common.Must(err)
+	defer otherConn.Close()
+
+	echo(otherConn)
+	if v := listerner.ActiveConnections(); v != 2 {
+		t.Error("active connections: ", v)
+	}
+	echo(clientConn)
+}
+
+func TestDialAndListenWithECN(t *testing.T) {
+	testEcho(t, &Config{
+		Congestion: true,
+		Ecn:        true,
+	})
+}
+
+func TestDialAndListenWithObfuscation(t *testing.T) {
+	testEcho(t, &Config{
+		Seed:        &EncryptionSeed{Seed: "kcp"},
+		Obfuscation: "xor",
+	})
+	testEcho(t, &Config{
+		Obfuscation: "padding",
+	})
+}
+
+func TestDialAndListenWithAckDelay(t *testing.T) {
+	testEcho(t, &Config{
+		AckDelay:            20,
+		AckNoDelayThreshold: 8,
+	})
+}
+
+func TestDialAndListenWithMultiplex(t *testing.T) {
+	config := &Config{Multiplex: true}
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	var conns []internet.Connection
+	for i := 0; i < 5; i++ {
+		conn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+			ProtocolName:     "mkcp",
+			ProtocolSettings: config,
+		})
+		common.Must(err)
+		conns = append(conns, conn)
+	}
+
+	var errg errgroup.Group
+	for _, conn := range conns {
+		conn := conn
+		errg.Go(func() error {
+			clientSend := make([]byte, 256*1024)
+			rand.Read(clientSend)
+			go conn.Write(clientSend)
+
+			clientReceived := make([]byte, 256*1024)
+			if _, err := io.ReadFull(conn, clientReceived); err != nil {
+				return err
+			}
+			if r := cmp.Diff(clientReceived, clientSend); r != "" {
+				return errors.New(r)
+			}
+			return nil
+		})
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if v := listerner.ActiveConnections(); v != 1 {
+		t.Error("active connections: ", v)
+	}
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	for i := 0; i < 60 && listerner.ActiveConnections() > 0; i++ {
+		time.Sleep(500 * time.Millisecond)
+	}
+	if v := listerner.ActiveConnections(); v != 0 {
+		t.Error("active connections after all streams closed: ", v)
+	}
+}
+
+func TestDialAndListenWithSlowReader(t *testing.T) {
+	// A small receiving window, which is filled while the listener doesn't read. Frequent pings clear the pending
+	// acknowledgements, so that no more of them are sent after the reads.
+	config := &Config{
+		DownlinkCapacity:  &DownlinkCapacity{Value: 1},
+		KeepAliveInterval: 100,
+	}
+	received := make(chan []byte, 1)
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+			time.Sleep(time.Second)
+			b := make([]byte, 256*1024)
+			c.SetReadDeadline(time.Now().Add(10 * time.Second))
+			if _, err := io.ReadFull(c, b); err != nil {
+				b = nil
+			}
+			received <- b
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	clientSend := make([]byte, 256*1024)
+	rand.Read(clientSend)
+	go clientConn.Write(clientSend)
+
+	// The writer is blocked by the full window, until it is updated after the reads.
+	if r := cmp.Diff(<-received, clientSend); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestDialAndListenWithFEC(t *testing.T) {
+	testEcho(t, &Config{
+		Fec: &FEC{
+			DataShards:   10,
+			ParityShards: 3,
+		},
+	})
+}
+
+func TestTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kcp.jsonl")
+	tracer := trace.Open(path, 0)
+
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+		Tracer:           tracer,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			common.Must2(io.Copy(c, c))
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientSession := tracer.Sample()
+	ctx := trace.ContextWithSession(context.Background(), clientSession)
+	clientConn, err := DialKCP(ctx, net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+	})
+	common.Must(err)
+
+	payload := make([]byte, 4096)
+	common.Must2(rand.Read(payload))
+	common.Must2(clientConn.Write(payload))
+	common.Must2(io.ReadFull(clientConn, payload))
+	clientConn.Close()
+
+	file, err := os.Open(path)
+	common.Must(err)
+	defer file.Close()
+
+	events := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event trace.Event
+		common.Must(json.Unmarshal(scanner.Bytes(), &event))
+		if event.Layer != "kcp" {
+			t.Fatal("unexpected layer: ", event.Layer)
+		}
+		if event.Session == clientSession.ID() {
+			events[event.Direction+" "+event.Type]++
+		}
+	}
+	for _, e := range []string{"send data", "recv ack", "recv data"} {
+		if events[e] == 0 {
+			t.Error("no ", e, " event of the client in ", events)
+		}
+	}
+}
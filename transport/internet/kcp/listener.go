@@ -11,8 +11,10 @@ import (
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/net"
+	"v2ray.com/core/features/stats"
 	"v2ray.com/core/transport/internet"
 	v2tls "v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/trace"
 	"v2ray.com/core/transport/internet/udp"
 )
 
@@ -25,14 +27,19 @@ type ConnectionID struct {
 // Listener defines a server listening for connections
 type Listener struct {
 	sync.Mutex
-	sessions  map[ConnectionID]*Connection
-	hub       *udp.Hub
-	tlsConfig *tls.Config
-	config    *Config
-	reader    PacketReader
-	header    internet.PacketHeader
-	security  cipher.AEAD
-	addConn   internet.ConnHandler
+	sessions map[ConnectionID]*Connection
+	// Sessions by their migration tokens, if migration is enabled.
+	migrations map[uint64]*Connection
+	hub        *udp.Hub
+	tlsConfig  *tls.Config
+	config     *Config
+	reader     PacketReader
+	header     internet.PacketHeader
+	security   cipher.AEAD
+	obfuscator Obfuscator
+	addConn    internet.ConnHandler
+	tracer     *trace.Tracer
+	stats      stats.Manager
 }
 
 func NewListener(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (*Listener, error) {
@@ -45,23 +52,32 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 	if err != nil {
 		return nil, newError("failed to create security").Base(err).AtError()
 	}
+	obfuscator, err := NewObfuscator(kcpSettings)
+	if err != nil {
+		return nil, newError("failed to create obfuscator").Base(err).AtError()
+	}
 	l := &Listener{
-		header:   header,
-		security: security,
+		header:     header,
+		security:   security,
+		obfuscator: obfuscator,
 		reader: &KCPPacketReader{
-			Header:   header,
-			Security: security,
+			Header:     header,
+			Security:   security,
+			Obfuscator: obfuscator,
 		},
-		sessions: make(map[ConnectionID]*Connection),
-		config:   kcpSettings,
-		addConn:  addConn,
+		sessions:   make(map[ConnectionID]*Connection),
+		migrations: make(map[uint64]*Connection),
+		config:     kcpSettings,
+		addConn:    addConn,
+		tracer:     streamSettings.Tracer,
+		stats:      statsManagerFromContext(ctx),
 	}
 
 	if config := v2tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.tlsConfig = config.GetTLSConfig()
 	}
 
-	hub, err := udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024))
+	hub, err := udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024), udp.HubECN(kcpSettings.Ecn))
 	if err != nil {
 		return nil, err
 	}
@@ -77,12 +93,17 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 
 func (l *Listener) handlePackets() {
 	receive := l.hub.Receive()
-	for payload := range receive {
-		l.OnReceive(payload.Payload, payload.Source)
+	for packet := range receive {
+		l.receive(packet.Payload, packet.Source, packet.ECN == udp.ECNCE)
 	}
 }
 
 func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
+	l.receive(payload, src, false)
+}
+
+// receive handles a packet from src. ce is whether the packet is marked with Congestion Experienced by ECN.
+func (l *Listener) receive(payload *buf.Buffer, src net.Destination, ce bool) {
 	segments := l.reader.Read(payload.Bytes())
 	payload.Release()
 
@@ -91,6 +112,15 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 		return
 	}
 
+	var token uint64
+	if seg, ok := segments[0].(*MigrationSegment); ok {
+		token = seg.Token
+		segments = segments[1:]
+		if len(segments) == 0 {
+			return
+		}
+	}
+
 	conv := segments[0].Conversation()
 	cmd := segments[0].Command()
 
@@ -105,6 +135,16 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 
 	conn, found := l.sessions[id]
 
+	if l.config.Migration {
+		if found && conn.closer.(*Writer).token != token {
+			newError("#", conv, " discarding packet from ", src, " with another migration token").WriteToLog()
+			return
+		}
+		if !found && token != 0 && cmd != CommandTerminate {
+			conn, found = l.migrate(id, token)
+		}
+	}
+
 	if !found {
 		if cmd == CommandTerminate {
 			return
@@ -115,19 +155,25 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			dest:     src,
 			listener: l,
 		}
-		remoteAddr := &net.UDPAddr{
-			IP:   src.Address.IP(),
-			Port: int(src.Port),
+		if l.config.Migration && token != 0 {
+			if _, used := l.migrations[token]; used {
+				newError("#", conv, " discarding packet from ", src, " with a used migration token").WriteToLog()
+				return
+			}
+			writer.token = token
 		}
 		localAddr := l.hub.Addr()
 		conn = NewConnection(ConnMetadata{
 			LocalAddr:    localAddr,
-			RemoteAddr:   remoteAddr,
+			RemoteAddr:   udpAddr(src),
 			Conversation: conv,
+			Trace:        l.tracer.Sample(),
+			Stats:        l.stats,
 		}, &KCPPacketWriter{
-			Header:   l.header,
-			Security: l.security,
-			Writer:   writer,
+			Header:     l.header,
+			Security:   l.security,
+			Obfuscator: l.obfuscator,
+			Writer:     newBatchWriter(writer, writer.WriteBatch),
 		}, writer, l.config)
 		var netConn internet.Connection = conn
 		if l.tlsConfig != nil {
@@ -135,18 +181,65 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			netConn = tlsConn
 		}
 
-		l.addConn(netConn)
+		if l.config.Multiplex {
+			NewStreamSession(netConn, l.addConn)
+		} else {
+			l.addConn(netConn)
+		}
 		l.sessions[id] = conn
+		if writer.token != 0 {
+			l.migrations[writer.token] = conn
+		}
 	}
 	conn.Input(segments)
+	if ce {
+		conn.receivingWorker.OnCongestionExperienced()
+	}
+}
+
+func udpAddr(dest net.Destination) *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   dest.Address.IP(),
+		Port: int(dest.Port),
+	}
+}
+
+// migrate moves the session of token to id, after its peer moved to a new address, and returns it. The token is the
+// proof that the packet comes from the peer of the session, as it is only sent in the encrypted packets of the
+// session. It must be called with the lock held.
+func (l *Listener) migrate(id ConnectionID, token uint64) (*Connection, bool) {
+	conn, found := l.migrations[token]
+	if !found {
+		return nil, false
+	}
+	writer := conn.closer.(*Writer)
+	if writer.id.Conv != id.Conv {
+		return nil, false
+	}
+
+	delete(l.sessions, writer.id)
+	l.sessions[id] = conn
+	writer.migrate(id)
+	conn.migrate(udpAddr(net.UDPDestination(id.Remote, id.Port)))
+	return conn, true
 }
 
 func (l *Listener) Remove(id ConnectionID) {
 	l.Lock()
-	delete(l.sessions, id)
+	l.remove(id)
 	l.Unlock()
 }
 
+// remove removes the session of id. It must be called with the lock held.
+func (l *Listener) remove(id ConnectionID) {
+	if conn, found := l.sessions[id]; found {
+		if token := conn.closer.(*Writer).token; token != 0 {
+			delete(l.migrations, token)
+		}
+		delete(l.sessions, id)
+	}
+}
+
 // Close stops listening on the UDP address. Already Accepted connections are not closed.
 func (l *Listener) Close() error {
 	l.hub.Close()
@@ -174,18 +267,43 @@ func (l *Listener) Addr() net.Addr {
 }
 
 type Writer struct {
-	id       ConnectionID
+	// The ID and the token are guarded by the lock of the listener.
+	id    ConnectionID
+	token uint64
+
+	access   sync.RWMutex
 	dest     net.Destination
 	hub      *udp.Hub
 	listener *Listener
 }
 
+func (w *Writer) destination() net.Destination {
+	w.access.RLock()
+	defer w.access.RUnlock()
+	return w.dest
+}
+
 func (w *Writer) Write(payload []byte) (int, error) {
-	return w.hub.WriteTo(payload, w.dest)
+	return w.hub.WriteTo(payload, w.destination())
+}
+
+// WriteBatch writes the payloads to the peer in a batch.
+func (w *Writer) WriteBatch(payloads [][]byte) error {
+	return w.hub.WriteBatch(payloads, w.destination())
+}
+
+// migrate sends further packets to the new address of the peer. It must be called with the lock of the listener held.
+func (w *Writer) migrate(id ConnectionID) {
+	w.id = id
+	w.access.Lock()
+	w.dest = net.UDPDestination(id.Remote, id.Port)
+	w.access.Unlock()
 }
 
 func (w *Writer) Close() error {
-	w.listener.Remove(w.id)
+	w.listener.Lock()
+	w.listener.remove(w.id)
+	w.listener.Unlock()
 	return nil
 }
 
@@ -0,0 +1,78 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"context"
+	"strconv"
+
+	"v2ray.com/core"
+	"v2ray.com/core/features/stats"
+)
+
+// Metrics is a snapshot of the state of a Connection, for diagnosing the link it runs over.
+type Metrics struct {
+	// Smoothed round trip time and retransmission timeout, in milli-sec.
+	RTT uint32
+	RTO uint32
+	// Total number of retransmitted segments.
+	Retransmits uint32
+	// Percentage of the segments in flight that were retransmitted in the last flush.
+	LossRate uint32
+	// Number of segments sent but not acknowledged.
+	InFlight uint32
+	// Number of segments allowed in flight, limited by the peer, the config and the congestion controller.
+	Window uint32
+	// Percentage of the sending buffer in use.
+	WindowOccupancy uint32
+}
+
+// statsManagerFromContext returns the stats manager of the V2Ray instance in ctx, or nil if there is none.
+func statsManagerFromContext(ctx context.Context) stats.Manager {
+	if v := core.FromContext(ctx); v != nil {
+		if m, ok := v.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// metricsRecorder exports the Metrics of a Connection as counters named
+// "kcp>>>[remote address]>>>[conversation]>>>[metric]".
+type metricsRecorder struct {
+	manager  stats.Manager
+	names    []string
+	counters []stats.Counter
+}
+
+var metricsNames = []string{"rtt", "rto", "retransmits", "loss", "inflight", "window", "occupancy"}
+
+func newMetricsRecorder(manager stats.Manager, meta ConnMetadata) *metricsRecorder {
+	r := &metricsRecorder{manager: manager}
+	prefix := "kcp>>>" + meta.RemoteAddr.String() + ">>>" + strconv.Itoa(int(meta.Conversation)) + ">>>"
+	for _, name := range metricsNames {
+		c, err := stats.GetOrRegisterCounter(manager, prefix+name)
+		if err != nil {
+			newError("failed to register counter ", prefix+name).Base(err).AtDebug().WriteToLog()
+			r.Close()
+			return nil
+		}
+		r.names = append(r.names, prefix+name)
+		r.counters = append(r.counters, c)
+	}
+	return r
+}
+
+// Record sets the counters to the values of m.
+func (r *metricsRecorder) Record(m Metrics) {
+	for i, v := range []uint32{m.RTT, m.RTO, m.Retransmits, m.LossRate, m.InFlight, m.Window, m.WindowOccupancy} {
+		r.counters[i].Set(int64(v))
+	}
+}
+
+// Close unregisters the counters.
+func (r *metricsRecorder) Close() {
+	for _, name := range r.names {
+		r.manager.UnregisterCounter(name)
+	}
+}
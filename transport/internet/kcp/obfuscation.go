@@ -0,0 +1,140 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/dice"
+)
+
+// Obfuscator transforms packets on the wire, so that they are harder to classify. It is applied to packets after they
+// are sealed, and the packet header stays in front of the obfuscated packet.
+type Obfuscator interface {
+	// Overhead returns the maximum number of bytes added to each packet.
+	Overhead() int
+	// Obfuscate transforms the packet p, and returns the result. The capacity of p is at least len(p) + Overhead(), so
+	// that p can be transformed in place.
+	Obfuscate(p []byte) []byte
+	// Deobfuscate restores the packet p in place, and returns it. It returns nil if p is malformed.
+	Deobfuscate(p []byte) []byte
+}
+
+// ObfuscatorCreator creates an Obfuscator for a connection or a listener.
+type ObfuscatorCreator func(config *Config) (Obfuscator, error)
+
+var obfuscatorCache = make(map[string]ObfuscatorCreator)
+
+// RegisterObfuscator registers an obfuscator, which can be selected by its name in Config.
+func RegisterObfuscator(name string, creator ObfuscatorCreator) error {
+	if _, found := obfuscatorCache[name]; found {
+		return newError("obfuscator ", name, " is already registered").AtError()
+	}
+	obfuscatorCache[name] = creator
+	return nil
+}
+
+// NewObfuscator creates the obfuscator selected in the config, or returns nil if none is selected.
+func NewObfuscator(config *Config) (Obfuscator, error) {
+	name := config.GetObfuscation()
+	if name == "" {
+		return nil, nil
+	}
+	creator, found := obfuscatorCache[name]
+	if !found {
+		return nil, newError("unknown obfuscator: ", name)
+	}
+	return creator(config)
+}
+
+const xorObfuscatorNonceSize = 8
+
+// xorObfuscator XORs packets with a key stream, which is derived from the seed and a random nonce in front of each
+// packet. It hides the fixed fields of packets without a seed, but it is not meant to be secure.
+type xorObfuscator struct {
+	block cipher.Block
+}
+
+func newXorObfuscator(config *Config) (Obfuscator, error) {
+	seed := "mkcp-obfuscation"
+	if config.Seed != nil {
+		seed += config.Seed.Seed
+	}
+	key := sha256.Sum256([]byte(seed))
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, newError("failed to create xor obfuscator").Base(err)
+	}
+	return &xorObfuscator{block: block}, nil
+}
+
+func (o *xorObfuscator) Overhead() int {
+	return xorObfuscatorNonceSize
+}
+
+func (o *xorObfuscator) stream(nonce []byte) cipher.Stream {
+	var iv [aes.BlockSize]byte
+	copy(iv[:], nonce)
+	return cipher.NewCTR(o.block, iv[:])
+}
+
+func (o *xorObfuscator) Obfuscate(p []byte) []byte {
+	out := p[:len(p)+xorObfuscatorNonceSize]
+	copy(out[xorObfuscatorNonceSize:], p)
+	common.Must2(io.ReadFull(rand.Reader, out[:xorObfuscatorNonceSize]))
+	payload := out[xorObfuscatorNonceSize:]
+	o.stream(out[:xorObfuscatorNonceSize]).XORKeyStream(payload, payload)
+	return out
+}
+
+func (o *xorObfuscator) Deobfuscate(p []byte) []byte {
+	if len(p) <= xorObfuscatorNonceSize {
+		return nil
+	}
+	payload := p[xorObfuscatorNonceSize:]
+	o.stream(p[:xorObfuscatorNonceSize]).XORKeyStream(payload, payload)
+	return payload
+}
+
+const maxObfuscationPadding = 63
+
+// paddingObfuscator appends random bytes of random length to packets, so that their sizes don't reveal the segments
+// in them. The last byte of a padded packet is the length of the padding.
+type paddingObfuscator struct{}
+
+func newPaddingObfuscator(config *Config) (Obfuscator, error) {
+	return paddingObfuscator{}, nil
+}
+
+func (paddingObfuscator) Overhead() int {
+	return maxObfuscationPadding + 1
+}
+
+func (paddingObfuscator) Obfuscate(p []byte) []byte {
+	size := dice.Roll(maxObfuscationPadding + 1)
+	out := p[:len(p)+size+1]
+	common.Must2(io.ReadFull(rand.Reader, out[len(p):len(p)+size]))
+	out[len(out)-1] = byte(size)
+	return out
+}
+
+func (paddingObfuscator) Deobfuscate(p []byte) []byte {
+	if len(p) == 0 {
+		return nil
+	}
+	size := int(p[len(p)-1])
+	if size > maxObfuscationPadding || size+1 >= len(p) {
+		return nil
+	}
+	return p[:len(p)-size-1]
+}
+
+func init() {
+	common.Must(RegisterObfuscator("xor", newXorObfuscator))
+	common.Must(RegisterObfuscator("padding", newPaddingObfuscator))
+}
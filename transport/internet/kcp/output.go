@@ -19,6 +19,8 @@ type SimpleSegmentWriter struct {
 	sync.Mutex
 	buffer *buf.Buffer
 	writer io.Writer
+	// The segment written before each segment in the same packet. It may be nil.
+	prefix Segment
 }
 
 func NewSegmentWriter(writer io.Writer) SegmentWriter {
@@ -33,12 +35,68 @@ func (w *SimpleSegmentWriter) Write(seg Segment) error {
 	defer w.Unlock()
 
 	w.buffer.Clear()
-	rawBytes := w.buffer.Extend(seg.ByteSize())
-	seg.Serialize(rawBytes)
+	writer, inPlace := w.writer.(inPlacePacketWriter)
+	if inPlace {
+		w.buffer.Extend(writer.reservedSize())
+	}
+	if w.prefix != nil {
+		w.prefix.Serialize(w.buffer.Extend(w.prefix.ByteSize()))
+	}
+	seg.Serialize(w.buffer.Extend(seg.ByteSize()))
+	if inPlace {
+		return writer.writeBuffer(w.buffer)
+	}
+
 	_, err := w.writer.Write(w.buffer.Bytes())
 	return err
 }
 
+// priorityWriter is a SegmentWriter, in which control segments bypass the data segments waiting to be written. A flush
+// of a full window doesn't delay acknowledgements and pings written meanwhile.
+type priorityWriter struct {
+	sync.Mutex
+	writer SegmentWriter
+	idle   *sync.Cond
+	busy   bool
+	// Number of control segments waiting to be written.
+	control int
+}
+
+func newPriorityWriter(writer SegmentWriter) *priorityWriter {
+	w := &priorityWriter{writer: writer}
+	w.idle = sync.NewCond(&w.Mutex)
+	return w
+}
+
+func isDataCommand(cmd Command) bool {
+	return cmd == CommandData || cmd == CommandFEC || cmd == CommandProbe
+}
+
+func (w *priorityWriter) Write(seg Segment) error {
+	w.Lock()
+	if isDataCommand(seg.Command()) {
+		for w.busy || w.control > 0 {
+			w.idle.Wait()
+		}
+	} else {
+		w.control++
+		for w.busy {
+			w.idle.Wait()
+		}
+		w.control--
+	}
+	w.busy = true
+	w.Unlock()
+
+	err := w.writer.Write(seg)
+
+	w.Lock()
+	w.busy = false
+	w.idle.Broadcast()
+	w.Unlock()
+	return err
+}
+
 type RetryableWriter struct {
 	writer SegmentWriter
 }
@@ -50,7 +108,11 @@ func NewRetryableWriter(writer SegmentWriter) SegmentWriter {
 }
 
 func (w *RetryableWriter) Write(seg Segment) error {
-	return retry.Timed(5, 100).On(func() error {
+	// Most writes succeed at the first attempt. Skip the retry strategy, as it allocates on every call.
+	if err := w.writer.Write(seg); err == nil {
+		return nil
+	}
+	return retry.Timed(4, 100).On(func() error {
 		return w.writer.Write(seg)
 	})
 }
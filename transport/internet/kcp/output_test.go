@@ -0,0 +1,65 @@
+package kcp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type blockingSegmentWriter struct {
+	sync.Mutex
+	started  chan struct{}
+	release  chan struct{}
+	commands []Command
+}
+
+func (w *blockingSegmentWriter) Write(seg Segment) error {
+	w.Lock()
+	first := len(w.commands) == 0
+	w.commands = append(w.commands, seg.Command())
+	w.Unlock()
+	if first {
+		close(w.started)
+		<-w.release
+	}
+	return nil
+}
+
+func TestPriorityWriter(t *testing.T) {
+	underlying := &blockingSegmentWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	writer := newPriorityWriter(underlying)
+
+	write := func(seg Segment, wg *sync.WaitGroup) {
+		wg.Add(1)
+		go func() {
+			writer.Write(seg)
+			wg.Done()
+		}()
+	}
+
+	var wg sync.WaitGroup
+	write(NewDataSegment(), &wg)
+	<-underlying.started
+	write(NewDataSegment(), &wg)
+	time.Sleep(50 * time.Millisecond)
+	ping := NewCmdOnlySegment()
+	ping.Cmd = CommandPing
+	write(ping, &wg)
+	time.Sleep(50 * time.Millisecond)
+
+	close(underlying.release)
+	wg.Wait()
+
+	expected := []Command{CommandData, CommandPing, CommandData}
+	if len(underlying.commands) != len(expected) {
+		t.Fatal("expect ", expected, ", but got ", underlying.commands)
+	}
+	for i, cmd := range underlying.commands {
+		if cmd != expected[i] {
+			t.Fatal("expect ", expected, ", but got ", underlying.commands)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// +build !confonly
+
+package kcp
+
+const (
+	// Interval in milli-sec of flushes when pacing is enabled, if shorter than TTI.
+	pacingInterval = 10
+)
+
+// pacer spreads the segments of a window over a round trip, instead of sending them in a burst at each flush.
+type pacer struct {
+	// Tokens in 1/1000 segments.
+	tokens      uint64
+	lastUpdated uint32
+}
+
+// Budget refills the tokens by a window per round trip, and returns the number of segments allowed to send now.
+func (p *pacer) Budget(current uint32, window uint32, rtt uint32) uint32 {
+	elapsed := current - p.lastUpdated
+	p.lastUpdated = current
+	if rtt == 0 {
+		// Segments are not paced before the round trip is measured.
+		return window
+	}
+
+	p.tokens += uint64(window) * uint64(elapsed) * 1000 / uint64(rtt)
+	// An idle connection doesn't build up a burst larger than a quarter of the window.
+	max := uint64(window) * 1000 / 4
+	if max < 2000 {
+		max = 2000
+	}
+	if p.tokens > max {
+		p.tokens = max
+	}
+	return uint32(p.tokens / 1000)
+}
+
+// Consume takes the tokens of the segments sent.
+func (p *pacer) Consume(segments uint32) {
+	if cost := uint64(segments) * 1000; cost < p.tokens {
+		p.tokens -= cost
+	} else {
+		p.tokens = 0
+	}
+}
@@ -0,0 +1,33 @@
+package kcp
+
+import (
+	"testing"
+)
+
+func TestPacer(t *testing.T) {
+	p := new(pacer)
+
+	// No pacing before the round trip is measured.
+	if budget := p.Budget(0, 100, 0); budget != 100 {
+		t.Error("budget without rtt: ", budget)
+	}
+
+	// 100 segments per 100 ms.
+	if budget := p.Budget(10, 100, 100); budget != 10 {
+		t.Error("budget after 10 ms: ", budget)
+	}
+	p.Consume(4)
+	if budget := p.Budget(15, 100, 100); budget != 11 {
+		t.Error("budget after 15 ms: ", budget)
+	}
+	p.Consume(11)
+
+	// Idle time is capped at a quarter of the window.
+	if budget := p.Budget(5000, 100, 100); budget != 25 {
+		t.Error("budget after idle: ", budget)
+	}
+	p.Consume(100)
+	if budget := p.Budget(5000, 100, 100); budget != 0 {
+		t.Error("budget after consumed: ", budget)
+	}
+}
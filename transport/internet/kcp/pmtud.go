@@ -0,0 +1,154 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// The path MTU search starts from the minimum MTU, which is assumed to work on all paths.
+	pmtuBase = 576
+	// The search completes when the MTU is known within this number of bytes.
+	pmtuSearchGranularity = 16
+	// A probe size is considered too large after this number of lost probes.
+	pmtuMaxProbes = 3
+	// Time in milli-sec, after which a completed search starts again, to follow changes of the path.
+	pmtuSearchInterval = 600000
+)
+
+// pathMTUDiscovery finds the path MTU by probing in the style of DPLPMTUD (RFC 8899), and adjusts the MSS of the
+// connection by it. Probes are padded segments, so lost probes don't affect data segments.
+type pathMTUDiscovery struct {
+	sync.Mutex
+	conn *Connection
+	// Size of the packet header and security in each packet.
+	overhead uint32
+	// Size of FECSegments in excess of DataSegments.
+	fecOverhead uint32
+	max         uint32
+
+	// The MTU confirmed by probes, and the largest MTU not known to be too large.
+	low  uint32
+	high uint32
+
+	probing    uint32
+	probeTime  uint32
+	failures   uint32
+	nextSearch uint32
+	// Whether the probe revalidates the confirmed MTU.
+	revalidating bool
+}
+
+func newPathMTUDiscovery(conn *Connection, overhead uint32, fecOverhead uint32, max uint32) *pathMTUDiscovery {
+	return &pathMTUDiscovery{
+		conn:        conn,
+		overhead:    overhead,
+		fecOverhead: fecOverhead,
+		max:         max,
+		low:         pmtuBase,
+		high:        max,
+	}
+}
+
+// MSS returns the maximum payload size of DataSegments in packets of the MTU.
+func (p *pathMTUDiscovery) MSS(mtu uint32) uint32 {
+	return mtu - p.overhead - DataSegmentOverhead - p.fecOverhead
+}
+
+func (p *pathMTUDiscovery) timeout() uint32 {
+	timeout := 2 * p.conn.roundTrip.Timeout()
+	if timeout < 200 {
+		timeout = 200
+	}
+	return timeout
+}
+
+// Flush sends a probe if needed, and handles lost probes.
+func (p *pathMTUDiscovery) Flush(current uint32) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.probing != 0 {
+		if current-p.probeTime < p.timeout() {
+			return
+		}
+		p.failures++
+		if p.failures < pmtuMaxProbes {
+			p.probe(current, p.probing)
+			return
+		}
+
+		if p.revalidating {
+			// The path no longer delivers packets of the confirmed MTU. Fall back to the base MTU.
+			newError("#", p.conn.meta.Conversation, " path MTU ", p.low, " is no longer valid").AtWarning().WriteToLog()
+			p.revalidating = false
+			p.low = pmtuBase
+			p.setMTU(pmtuBase)
+		}
+		p.high = p.probing - 1
+		p.probing = 0
+		p.failures = 0
+	}
+
+	if p.high < p.low+pmtuSearchGranularity {
+		if p.nextSearch == 0 {
+			p.nextSearch = current + pmtuSearchInterval
+			newError("#", p.conn.meta.Conversation, " path MTU found: ", p.low).AtDebug().WriteToLog()
+		}
+		if current-p.nextSearch > 0x7FFFFFFF {
+			return
+		}
+		p.nextSearch = 0
+		p.high = p.max
+		if p.low > pmtuBase {
+			p.revalidating = true
+			p.probe(current, p.low)
+			return
+		}
+		if p.high < p.low+pmtuSearchGranularity {
+			return
+		}
+	}
+
+	p.probe(current, (p.low+p.high+1)/2)
+}
+
+func (p *pathMTUDiscovery) probe(current uint32, size uint32) {
+	if p.probing != size {
+		p.failures = 0
+	}
+	p.probing = size
+	p.probeTime = current
+
+	seg := NewProbeSegment()
+	seg.Conv = p.conn.meta.Conversation
+	seg.Cmd = CommandProbe
+	seg.Size = uint16(size)
+	seg.Padding = uint16(size - p.overhead - uint32(seg.ByteSize()))
+	p.conn.output.Write(seg)
+	seg.Release()
+}
+
+// OnAck handles the acknowledgement of a probe.
+func (p *pathMTUDiscovery) OnAck(size uint32) {
+	p.Lock()
+	defer p.Unlock()
+
+	if size != p.probing {
+		return
+	}
+	p.probing = 0
+	p.failures = 0
+	p.revalidating = false
+	if size > p.low {
+		p.low = size
+		p.setMTU(size)
+		newError("#", p.conn.meta.Conversation, " path MTU raised to ", size).AtDebug().WriteToLog()
+	}
+}
+
+func (p *pathMTUDiscovery) setMTU(mtu uint32) {
+	atomic.StoreUint32(&p.conn.mss, p.MSS(mtu))
+}
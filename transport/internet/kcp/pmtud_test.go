@@ -0,0 +1,93 @@
+package kcp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common/buf"
+)
+
+// pathSimulator delivers probes no larger than the path MTU.
+type pathSimulator struct {
+	pmtud   *pathMTUDiscovery
+	pathMTU uint32
+	pending []uint32
+}
+
+func (s *pathSimulator) Write(seg Segment) error {
+	if probe, ok := seg.(*ProbeSegment); ok && uint32(probe.Size) <= s.pathMTU {
+		if size := uint32(probe.ByteSize()) + s.pmtud.overhead; size != uint32(probe.Size) {
+			panic("probe size mismatch")
+		}
+		s.pending = append(s.pending, uint32(probe.Size))
+	}
+	return nil
+}
+
+func (s *pathSimulator) run(current uint32, duration uint32) uint32 {
+	for end := current + duration; current < end; current += 100 {
+		s.pmtud.Flush(current)
+		for _, size := range s.pending {
+			s.pmtud.OnAck(size)
+		}
+		s.pending = s.pending[:0]
+	}
+	return current
+}
+
+func TestPathMTUDiscovery(t *testing.T) {
+	simulator := &pathSimulator{pathMTU: 1000}
+	conn := &Connection{
+		roundTrip: &RoundTripInfo{},
+		output:    simulator,
+	}
+	simulator.pmtud = newPathMTUDiscovery(conn, 20, 0, 1350)
+	conn.mss = simulator.pmtud.MSS(pmtuBase)
+
+	checkMTU := func(min, max uint32) {
+		t.Helper()
+		if low := simulator.pmtud.low; low < min || low > max {
+			t.Error("path MTU: ", low)
+		}
+		if conn.mss != simulator.pmtud.MSS(simulator.pmtud.low) {
+			t.Error("mss: ", conn.mss)
+		}
+	}
+
+	current := simulator.run(0, 60000)
+	checkMTU(1000-pmtuSearchGranularity, 1000)
+
+	// The path MTU grows, and is found by the next search.
+	simulator.pathMTU = 1300
+	current = simulator.run(current, pmtuSearchInterval)
+	checkMTU(1300-pmtuSearchGranularity, 1300)
+
+	// The path MTU shrinks, and the revalidation fails.
+	simulator.pathMTU = 700
+	simulator.run(current, pmtuSearchInterval)
+	checkMTU(700-pmtuSearchGranularity, 700)
+}
+
+func TestSendingWorkerPushResegments(t *testing.T) {
+	conn := &Connection{
+		Config: &Config{},
+		mss:    10,
+	}
+	worker := NewSendingWorker(conn)
+
+	b := buf.New()
+	b.WriteString("0123456789abcdefghijklmno")
+	if !worker.Push(b) {
+		t.Fatal("failed to push")
+	}
+
+	var payloads []string
+	worker.window.Visit(func(seg *DataSegment) bool {
+		payloads = append(payloads, seg.Data().String())
+		return true
+	})
+	if r := cmp.Diff(payloads, []string{"0123456789", "abcdefghij", "klmno"}); r != "" {
+		t.Error(r)
+	}
+}
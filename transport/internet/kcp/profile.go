@@ -0,0 +1,66 @@
+package kcp
+
+// profiles are named sets of tuning settings, for users who don't tune each setting themselves.
+var profiles = map[string]*Config{
+	// Small TTI and quick resend, for interactive traffic. Without congestion control, so that losses don't slow it
+	// down.
+	"latency": {
+		Tti:              &TTI{Value: 10},
+		UplinkCapacity:   &UplinkCapacity{Value: 20},
+		DownlinkCapacity: &DownlinkCapacity{Value: 50},
+		FastResend:       2,
+		RtoMin:           30,
+	},
+	"balanced": {
+		Tti:               &TTI{Value: 20},
+		UplinkCapacity:    &UplinkCapacity{Value: 10},
+		DownlinkCapacity:  &DownlinkCapacity{Value: 40},
+		Congestion:        true,
+		CongestionControl: "loss",
+	},
+	// Large windows and buffers, with BBR to fill the link without flooding it.
+	"throughput": {
+		Tti:               &TTI{Value: 40},
+		UplinkCapacity:    &UplinkCapacity{Value: 100},
+		DownlinkCapacity:  &DownlinkCapacity{Value: 100},
+		Congestion:        true,
+		CongestionControl: "bbr",
+		ReadBuffer:        &ReadBuffer{Size: 8 * 1024 * 1024},
+		WriteBuffer:       &WriteBuffer{Size: 8 * 1024 * 1024},
+	},
+}
+
+// ApplyProfile fills the settings in config, which are not set yet, from the named profile. Congestion control is
+// enabled if the profile enables it.
+func ApplyProfile(config *Config, name string) error {
+	profile, found := profiles[name]
+	if !found {
+		return newError("unknown mKCP profile: ", name)
+	}
+	if config.Tti == nil && profile.Tti != nil {
+		config.Tti = &TTI{Value: profile.Tti.Value}
+	}
+	if config.UplinkCapacity == nil && profile.UplinkCapacity != nil {
+		config.UplinkCapacity = &UplinkCapacity{Value: profile.UplinkCapacity.Value}
+	}
+	if config.DownlinkCapacity == nil && profile.DownlinkCapacity != nil {
+		config.DownlinkCapacity = &DownlinkCapacity{Value: profile.DownlinkCapacity.Value}
+	}
+	if config.ReadBuffer == nil && profile.ReadBuffer != nil {
+		config.ReadBuffer = &ReadBuffer{Size: profile.ReadBuffer.Size}
+	}
+	if config.WriteBuffer == nil && profile.WriteBuffer != nil {
+		config.WriteBuffer = &WriteBuffer{Size: profile.WriteBuffer.Size}
+	}
+	if config.FastResend == 0 {
+		config.FastResend = profile.FastResend
+	}
+	if config.RtoMin == 0 {
+		config.RtoMin = profile.RtoMin
+	}
+	if config.CongestionControl == "" {
+		config.CongestionControl = profile.CongestionControl
+	}
+	config.Congestion = config.Congestion || profile.Congestion
+	return nil
+}
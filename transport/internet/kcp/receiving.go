@@ -27,6 +27,14 @@ func (w *ReceivingWindow) Set(id uint32, value *DataSegment) bool {
 	return true
 }
 
+// Release releases all segments in the window.
+func (w *ReceivingWindow) Release() {
+	for id, seg := range w.cache {
+		seg.Release()
+		delete(w.cache, id)
+	}
+}
+
 func (w *ReceivingWindow) Has(id uint32) bool {
 	_, f := w.cache[id]
 	return f
@@ -49,6 +57,16 @@ type AckList struct {
 
 	flushCandidates []uint32
 	dirty           bool
+	// Whether the numbers are acknowledged in ranges.
+	ranges bool
+
+	// Acknowledgements of new segments are held back for delay milli-sec, or until noDelayThreshold of them are
+	// pending, so that they are sent together.
+	delay            uint32
+	noDelayThreshold uint32
+	pending          uint32
+	pendingSince     uint32
+	pendingTimed     bool
 }
 
 func NewAckList(writer SegmentWriter) *AckList {
@@ -65,7 +83,11 @@ func (l *AckList) Add(number uint32, timestamp uint32) {
 	l.timestamps = append(l.timestamps, timestamp)
 	l.numbers = append(l.numbers, number)
 	l.nextFlush = append(l.nextFlush, 0)
-	l.dirty = true
+	if l.delay > 0 {
+		l.pending++
+	} else {
+		l.dirty = true
+	}
 }
 
 func (l *AckList) Clear(una uint32) {
@@ -89,18 +111,49 @@ func (l *AckList) Clear(una uint32) {
 	}
 }
 
+// UpdateWindow makes the next Flush send an AckSegment, even if there is nothing to acknowledge, so that the peer
+// learns the receiving window from it.
+func (l *AckList) UpdateWindow() {
+	l.dirty = true
+}
+
+func (l *AckList) put(seg *AckSegment, number uint32) {
+	if l.ranges {
+		seg.PutRange(number, number)
+	} else {
+		seg.PutNumber(number)
+	}
+}
+
 func (l *AckList) Flush(current uint32, rto uint32) {
 	l.flushCandidates = l.flushCandidates[:0]
 
+	hold := false
+	if l.pending > 0 {
+		if !l.pendingTimed {
+			l.pendingSince = current
+			l.pendingTimed = true
+		}
+		hold = current-l.pendingSince < l.delay && l.pending < l.noDelayThreshold
+	}
+	if !hold {
+		l.pending = 0
+		l.pendingTimed = false
+	}
+
 	seg := NewAckSegment()
 	for i := 0; i < len(l.numbers); i++ {
+		if hold && l.nextFlush[i] == 0 {
+			// Not acknowledged yet, and held back.
+			continue
+		}
 		if l.nextFlush[i] > current {
 			if len(l.flushCandidates) < cap(l.flushCandidates) {
 				l.flushCandidates = append(l.flushCandidates, l.numbers[i])
 			}
 			continue
 		}
-		seg.PutNumber(l.numbers[i])
+		l.put(seg, l.numbers[i])
 		seg.PutTimestamp(l.timestamps[i])
 		timeout := rto / 2
 		if timeout < 20 {
@@ -121,7 +174,7 @@ func (l *AckList) Flush(current uint32, rto uint32) {
 			if seg.IsFull() {
 				break
 			}
-			seg.PutNumber(number)
+			l.put(seg, number)
 		}
 		l.writer.Write(seg)
 		l.dirty = false
@@ -138,6 +191,10 @@ type ReceivingWorker struct {
 	acklist    *AckList
 	nextNumber uint32
 	windowSize uint32
+	// The receiving window in the last AckSegment.
+	advertisedWindow uint32
+	// Whether packets marked with Congestion Experienced are received since the last AckSegment.
+	congestionExperienced bool
 }
 
 func NewReceivingWorker(kcp *Connection) *ReceivingWorker {
@@ -146,7 +203,11 @@ func NewReceivingWorker(kcp *Connection) *ReceivingWorker {
 		window:     NewReceivingWindow(),
 		windowSize: kcp.Config.GetReceivingInFlightSize(),
 	}
+	worker.advertisedWindow = worker.windowSize
 	worker.acklist = NewAckList(worker)
+	worker.acklist.ranges = kcp.Config.AckRange
+	worker.acklist.delay = kcp.Config.GetAckDelay()
+	worker.acklist.noDelayThreshold = kcp.Config.GetAckNoDelayThresholdValue()
 	return worker
 }
 
@@ -154,6 +215,14 @@ func (w *ReceivingWorker) Release() {
 	w.Lock()
 	buf.ReleaseMulti(w.leftOver)
 	w.leftOver = nil
+	w.window.Release()
+	w.Unlock()
+}
+
+// OnCongestionExperienced echoes a Congestion Experienced mark to the peer in the next AckSegment.
+func (w *ReceivingWorker) OnCongestionExperienced() {
+	w.Lock()
+	w.congestionExperienced = true
 	w.Unlock()
 }
 
@@ -171,6 +240,7 @@ func (w *ReceivingWorker) ProcessSegment(seg *DataSegment) {
 	number := seg.Number
 	idx := number - w.nextNumber
 	if idx >= w.windowSize {
+		seg.Release()
 		return
 	}
 	w.acklist.Clear(seg.SendingNext)
@@ -182,6 +252,9 @@ func (w *ReceivingWorker) ProcessSegment(seg *DataSegment) {
 }
 
 func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
+	w.Lock()
+	defer w.Unlock()
+
 	if w.leftOver != nil {
 		mb := w.leftOver
 		w.leftOver = nil
@@ -189,9 +262,6 @@ func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
 	}
 
 	mb := make(buf.MultiBuffer, 0, 32)
-
-	w.Lock()
-	defer w.Unlock()
 	for {
 		seg := w.window.Remove(w.nextNumber)
 		if seg == nil {
@@ -202,6 +272,12 @@ func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
 		seg.Release()
 	}
 
+	// The peer doesn't send segments beyond the advertised window, and may be blocked by it when it is full. The window
+	// is updated once half of it is read, as no more segments may arrive to be acknowledged.
+	if w.nextNumber+w.windowSize-w.advertisedWindow >= w.windowSize/2 {
+		w.acklist.UpdateWindow()
+	}
+
 	return mb
 }
 
@@ -212,7 +288,9 @@ func (w *ReceivingWorker) Read(b []byte) int {
 	}
 	mb, nBytes := buf.SplitBytes(mb, b)
 	if !mb.IsEmpty() {
+		w.Lock()
 		w.leftOver = mb
+		w.Unlock()
 	}
 	return nBytes
 }
@@ -242,10 +320,15 @@ func (w *ReceivingWorker) Write(seg Segment) error {
 	ackSeg.Conv = w.conn.meta.Conversation
 	ackSeg.ReceivingNext = w.nextNumber
 	ackSeg.ReceivingWindow = w.nextNumber + w.windowSize
+	w.advertisedWindow = ackSeg.ReceivingWindow
 	ackSeg.Option = 0
 	if w.conn.State() == StateReadyToClose {
 		ackSeg.Option = SegmentOptionClose
 	}
+	if w.congestionExperienced {
+		ackSeg.Option |= SegmentOptionECE
+		w.congestionExperienced = false
+	}
 	return w.conn.output.Write(ackSeg)
 }
 
@@ -256,5 +339,5 @@ func (w *ReceivingWorker) UpdateNecessary() bool {
 	w.RLock()
 	defer w.RUnlock()
 
-	return len(w.acklist.numbers) > 0
+	return len(w.acklist.numbers) > 0 || w.acklist.dirty
 }
@@ -0,0 +1,146 @@
+package kcp
+
+import (
+	"testing"
+)
+
+func init() {
+	panicOnDoubleRelease = true
+}
+
+func TestReceivingWorkerReleasesSegments(t *testing.T) {
+	worker := NewReceivingWorker(&Connection{Config: &Config{}})
+
+	newSegment := func(number uint32) *DataSegment {
+		seg := NewDataSegment()
+		seg.Number = number
+		seg.Data().WriteString("a")
+		return seg
+	}
+
+	kept := newSegment(1)
+	worker.ProcessSegment(kept)
+	if kept.released {
+		t.Error("segment in window released")
+	}
+
+	duplicate := newSegment(1)
+	worker.ProcessSegment(duplicate)
+	if !duplicate.released {
+		t.Error("duplicate segment not released")
+	}
+
+	outOfWindow := newSegment(worker.windowSize)
+	worker.ProcessSegment(outOfWindow)
+	if !outOfWindow.released {
+		t.Error("segment out of window not released")
+	}
+
+	worker.Release()
+	if !kept.released {
+		t.Error("segment in window not released on close")
+	}
+}
+
+func TestDataSegmentReleaseTwice(t *testing.T) {
+	seg := NewDataSegment()
+	seg.Release()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic")
+		}
+	}()
+	seg.Release()
+}
+
+func TestDataSegmentReleaseTwiceLogged(t *testing.T) {
+	panicOnDoubleRelease = false
+	defer func() {
+		panicOnDoubleRelease = true
+	}()
+
+	seg := NewDataSegment()
+	seg.Release()
+	seg.Release()
+	if !seg.released {
+		t.Error("segment released twice is reset")
+	}
+}
+
+type ackRecorder struct {
+	acks []*AckSegment
+}
+
+func (r *ackRecorder) Write(seg Segment) error {
+	if ack, ok := seg.(*AckSegment); ok {
+		r.acks = append(r.acks, ack)
+	}
+	return nil
+}
+
+func TestCongestionExperienced(t *testing.T) {
+	recorder := new(ackRecorder)
+	receiver := &Connection{Config: &Config{}, output: recorder}
+	receiver.receivingWorker = NewReceivingWorker(receiver)
+
+	receiver.receivingWorker.OnCongestionExperienced()
+	receiver.receivingWorker.Write(NewAckSegment())
+	receiver.receivingWorker.Write(NewAckSegment())
+	if len(recorder.acks) != 2 {
+		t.Fatal("acks: ", len(recorder.acks))
+	}
+	if recorder.acks[0].Option&SegmentOptionECE == 0 {
+		t.Error("congestion not echoed")
+	}
+	if recorder.acks[1].Option&SegmentOptionECE != 0 {
+		t.Error("congestion echoed twice")
+	}
+
+	sender := &Connection{Config: &Config{Congestion: true}, roundTrip: &RoundTripInfo{}, output: recorder}
+	sender.sendingWorker = NewSendingWorker(sender)
+
+	window := sender.sendingWorker.congestion.Window()
+	sender.sendingWorker.ProcessSegment(0, recorder.acks[0], 100)
+	if w := sender.sendingWorker.congestion.Window(); w != 3*window/4 {
+		t.Error("window after congestion: ", w, ", before: ", window)
+	}
+}
+
+type ackCounter struct {
+	numbers []int
+}
+
+func (c *ackCounter) Write(seg Segment) error {
+	c.numbers = append(c.numbers, len(seg.(*AckSegment).NumberList))
+	return nil
+}
+
+func TestAckListDelay(t *testing.T) {
+	counter := new(ackCounter)
+	list := NewAckList(counter)
+	list.delay = 40
+	list.noDelayThreshold = 3
+
+	list.Add(1, 0)
+	list.Flush(0, 100)
+	list.Add(2, 0)
+	list.Flush(20, 100)
+	if len(counter.numbers) != 0 {
+		t.Fatal("acks sent before delay: ", counter.numbers)
+	}
+	list.Flush(40, 100)
+	if len(counter.numbers) != 1 || counter.numbers[0] != 2 {
+		t.Fatal("expect 2 numbers in 1 ack, but got ", counter.numbers)
+	}
+
+	counter.numbers = nil
+	list.Clear(3)
+	for i := uint32(3); i < 6; i++ {
+		list.Add(i, 40)
+	}
+	list.Flush(41, 100)
+	if len(counter.numbers) != 1 || counter.numbers[0] != 3 {
+		t.Error("expect 3 numbers acknowledged at threshold, but got ", counter.numbers)
+	}
+}
@@ -4,6 +4,7 @@ package kcp
 
 import (
 	"encoding/binary"
+	"sync"
 
 	"v2ray.com/core/common/buf"
 )
@@ -20,12 +21,26 @@ const (
 	CommandTerminate Command = 2
 	// CommandPing indicates a ping.
 	CommandPing Command = 3
+	// CommandFEC indicates a FECSegment.
+	CommandFEC Command = 4
+	// CommandProbe indicates a ProbeSegment that probes the path MTU.
+	CommandProbe Command = 5
+	// CommandProbeAck indicates a ProbeSegment that acknowledges a probe.
+	CommandProbeAck Command = 6
+	// CommandMigration indicates a MigrationSegment.
+	CommandMigration Command = 7
 )
 
 type SegmentOption byte
 
 const (
 	SegmentOptionClose SegmentOption = 1
+	// SegmentOptionAckRange indicates that an AckSegment carries ranges of numbers. It is set on the wire only, and
+	// not kept in AckSegment.Option.
+	SegmentOptionAckRange SegmentOption = 2
+	// SegmentOptionECE indicates that the sender of an AckSegment received packets marked with Congestion Experienced
+	// by ECN, since its last AckSegment.
+	SegmentOptionECE SegmentOption = 4
 )
 
 type Segment interface {
@@ -51,10 +66,24 @@ type DataSegment struct {
 	payload  *buf.Buffer
 	timeout  uint32
 	transmit uint32
+	released bool
 }
 
+// panicOnDoubleRelease makes a DataSegment released twice panic, instead of logging an error. It is set in tests.
+var panicOnDoubleRelease = false
+
+var dataSegmentPool = sync.Pool{
+	New: func() interface{} {
+		return new(DataSegment)
+	},
+}
+
+// NewDataSegment returns an empty DataSegment from the pool shared by the sending and receiving paths. The caller
+// owns it until it is passed on or released.
 func NewDataSegment() *DataSegment {
-	return new(DataSegment)
+	seg := dataSegmentPool.Get().(*DataSegment)
+	seg.released = false
+	return seg
 }
 
 func (s *DataSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
@@ -121,11 +150,28 @@ func (s *DataSegment) ByteSize() int32 {
 	return 2 + 1 + 1 + 4 + 4 + 4 + 2 + s.payload.Len()
 }
 
+// Release releases the payload, and recycles the segment into the pool. The segment must not be used afterwards.
 func (s *DataSegment) Release() {
+	if s.released {
+		// A segment in the pool twice would be shared by two owners, so it is not put back again.
+		if panicOnDoubleRelease {
+			panic("kcp: DataSegment released twice")
+		}
+		newError("DataSegment released twice").AtError().WriteToLog()
+		return
+	}
 	s.payload.Release()
-	s.payload = nil
+	*s = DataSegment{released: true}
+	dataSegmentPool.Put(s)
+}
+
+// AckRange is a range of acknowledged numbers, from Begin to End inclusively.
+type AckRange struct {
+	Begin uint32
+	End   uint32
 }
 
+// AckSegment acknowledges either a list of numbers, or ranges of numbers if Ranges is not empty.
 type AckSegment struct {
 	Conv            uint16
 	Option          SegmentOption
@@ -133,9 +179,13 @@ type AckSegment struct {
 	ReceivingNext   uint32
 	Timestamp       uint32
 	NumberList      []uint32
+	Ranges          []AckRange
 }
 
-const ackNumberLimit = 128
+const (
+	ackNumberLimit = 128
+	ackRangeLimit  = 64
+)
 
 func NewAckSegment() *AckSegment {
 	return new(AckSegment)
@@ -143,7 +193,7 @@ func NewAckSegment() *AckSegment {
 
 func (s *AckSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
 	s.Conv = conv
-	s.Option = opt
+	s.Option = opt &^ SegmentOptionAckRange
 	if len(buf) < 13 {
 		return false, nil
 	}
@@ -160,6 +210,22 @@ func (s *AckSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []by
 	count := int(buf[0])
 	buf = buf[1:]
 
+	if opt&SegmentOptionAckRange == SegmentOptionAckRange {
+		if len(buf) < count*8 {
+			return false, nil
+		}
+		for i := 0; i < count; i++ {
+			begin := binary.BigEndian.Uint32(buf)
+			end := binary.BigEndian.Uint32(buf[4:])
+			if end < begin {
+				return false, nil
+			}
+			s.Ranges = append(s.Ranges, AckRange{Begin: begin, End: end})
+			buf = buf[8:]
+		}
+		return true, buf
+	}
+
 	if len(buf) < count*4 {
 		return false, nil
 	}
@@ -189,15 +255,34 @@ func (s *AckSegment) PutNumber(number uint32) {
 	s.NumberList = append(s.NumberList, number)
 }
 
+// PutRange acknowledges the numbers from begin to end, merging them into the last range if adjacent.
+func (s *AckSegment) PutRange(begin uint32, end uint32) {
+	if n := len(s.Ranges); n > 0 {
+		last := &s.Ranges[n-1]
+		if begin == last.End+1 {
+			last.End = end
+			return
+		}
+		if end+1 == last.Begin {
+			last.Begin = begin
+			return
+		}
+	}
+	s.Ranges = append(s.Ranges, AckRange{Begin: begin, End: end})
+}
+
 func (s *AckSegment) IsFull() bool {
-	return len(s.NumberList) == ackNumberLimit
+	return len(s.NumberList) == ackNumberLimit || len(s.Ranges) == ackRangeLimit
 }
 
 func (s *AckSegment) IsEmpty() bool {
-	return len(s.NumberList) == 0
+	return len(s.NumberList) == 0 && len(s.Ranges) == 0
 }
 
 func (s *AckSegment) ByteSize() int32 {
+	if len(s.Ranges) > 0 {
+		return 2 + 1 + 1 + 4 + 4 + 4 + 1 + int32(len(s.Ranges)*8)
+	}
 	return 2 + 1 + 1 + 4 + 4 + 4 + 1 + int32(len(s.NumberList)*4)
 }
 
@@ -208,8 +293,18 @@ func (s *AckSegment) Serialize(b []byte) {
 	binary.BigEndian.PutUint32(b[4:], s.ReceivingWindow)
 	binary.BigEndian.PutUint32(b[8:], s.ReceivingNext)
 	binary.BigEndian.PutUint32(b[12:], s.Timestamp)
-	b[16] = byte(len(s.NumberList))
 	n := 17
+	if len(s.Ranges) > 0 {
+		b[3] |= byte(SegmentOptionAckRange)
+		b[16] = byte(len(s.Ranges))
+		for _, r := range s.Ranges {
+			binary.BigEndian.PutUint32(b[n:], r.Begin)
+			binary.BigEndian.PutUint32(b[n+4:], r.End)
+			n += 8
+		}
+		return
+	}
+	b[16] = byte(len(s.NumberList))
 	for _, number := range s.NumberList {
 		binary.BigEndian.PutUint32(b[n:], number)
 		n += 4
@@ -218,6 +313,201 @@ func (s *AckSegment) Serialize(b []byte) {
 
 func (s *AckSegment) Release() {}
 
+// FECShardID identifies a DataSegment in an FEC block. A resent DataSegment has a different timestamp.
+type FECShardID struct {
+	Number    uint32
+	Timestamp uint32
+}
+
+// FECSegment carries a parity shard of an FEC block, which recovers lost DataSegments of the block.
+type FECSegment struct {
+	Conv         uint16
+	Option       SegmentOption
+	Block        uint32
+	Index        byte
+	ParityShards byte
+	DataShards   []FECShardID
+	Parity       []byte
+}
+
+func NewFECSegment() *FECSegment {
+	return new(FECSegment)
+}
+
+func (s *FECSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
+	s.Conv = conv
+	s.Option = opt
+	if len(buf) < 7 {
+		return false, nil
+	}
+
+	s.Block = binary.BigEndian.Uint32(buf)
+	buf = buf[4:]
+
+	s.Index = buf[0]
+	s.ParityShards = buf[1]
+	count := int(buf[2])
+	buf = buf[3:]
+	if s.Index >= s.ParityShards || count == 0 {
+		return false, nil
+	}
+
+	if len(buf) < count*8+2 {
+		return false, nil
+	}
+	s.DataShards = make([]FECShardID, count)
+	for i := range s.DataShards {
+		s.DataShards[i].Number = binary.BigEndian.Uint32(buf)
+		s.DataShards[i].Timestamp = binary.BigEndian.Uint32(buf[4:])
+		buf = buf[8:]
+	}
+
+	parityLen := int(binary.BigEndian.Uint16(buf))
+	buf = buf[2:]
+	if len(buf) < parityLen {
+		return false, nil
+	}
+	s.Parity = append([]byte(nil), buf[:parityLen]...)
+	buf = buf[parityLen:]
+
+	return true, buf
+}
+
+func (s *FECSegment) Conversation() uint16 {
+	return s.Conv
+}
+
+func (*FECSegment) Command() Command {
+	return CommandFEC
+}
+
+func (s *FECSegment) ByteSize() int32 {
+	return 2 + 1 + 1 + 4 + 1 + 1 + 1 + int32(len(s.DataShards)*8) + 2 + int32(len(s.Parity))
+}
+
+func (s *FECSegment) Serialize(b []byte) {
+	binary.BigEndian.PutUint16(b, s.Conv)
+	b[2] = byte(CommandFEC)
+	b[3] = byte(s.Option)
+	binary.BigEndian.PutUint32(b[4:], s.Block)
+	b[8] = s.Index
+	b[9] = s.ParityShards
+	b[10] = byte(len(s.DataShards))
+	n := 11
+	for _, id := range s.DataShards {
+		binary.BigEndian.PutUint32(b[n:], id.Number)
+		binary.BigEndian.PutUint32(b[n+4:], id.Timestamp)
+		n += 8
+	}
+	binary.BigEndian.PutUint16(b[n:], uint16(len(s.Parity)))
+	copy(b[n+2:], s.Parity)
+}
+
+func (*FECSegment) Release() {}
+
+// ProbeSegment probes the path MTU. A probe is padded to fill a packet of Size bytes, and the acknowledgement of it
+// carries the same Size without padding.
+type ProbeSegment struct {
+	Conv    uint16
+	Cmd     Command
+	Option  SegmentOption
+	Size    uint16
+	Padding uint16
+}
+
+func NewProbeSegment() *ProbeSegment {
+	return new(ProbeSegment)
+}
+
+func (s *ProbeSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
+	s.Conv = conv
+	s.Cmd = cmd
+	s.Option = opt
+	if len(buf) < 4 {
+		return false, nil
+	}
+
+	s.Size = binary.BigEndian.Uint16(buf)
+	s.Padding = binary.BigEndian.Uint16(buf[2:])
+	buf = buf[4:]
+	if len(buf) < int(s.Padding) {
+		return false, nil
+	}
+	buf = buf[s.Padding:]
+
+	return true, buf
+}
+
+func (s *ProbeSegment) Conversation() uint16 {
+	return s.Conv
+}
+
+func (s *ProbeSegment) Command() Command {
+	return s.Cmd
+}
+
+func (s *ProbeSegment) ByteSize() int32 {
+	return 2 + 1 + 1 + 2 + 2 + int32(s.Padding)
+}
+
+func (s *ProbeSegment) Serialize(b []byte) {
+	binary.BigEndian.PutUint16(b, s.Conv)
+	b[2] = byte(s.Cmd)
+	b[3] = byte(s.Option)
+	binary.BigEndian.PutUint16(b[4:], s.Size)
+	binary.BigEndian.PutUint16(b[6:], s.Padding)
+	padding := b[8 : 8+int(s.Padding)]
+	for i := range padding {
+		padding[i] = 0
+	}
+}
+
+func (*ProbeSegment) Release() {}
+
+// MigrationSegmentSize is the size of a MigrationSegment.
+const MigrationSegmentSize = 12
+
+// MigrationSegment carries the token of a connection, by which the listener follows the dialer to a new address. It
+// is written before the other segments in each packet of the dialer.
+type MigrationSegment struct {
+	Conv  uint16
+	Token uint64
+}
+
+func NewMigrationSegment() *MigrationSegment {
+	return new(MigrationSegment)
+}
+
+func (s *MigrationSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
+	s.Conv = conv
+	if len(buf) < 8 {
+		return false, nil
+	}
+	s.Token = binary.BigEndian.Uint64(buf)
+	return true, buf[8:]
+}
+
+func (s *MigrationSegment) Conversation() uint16 {
+	return s.Conv
+}
+
+func (*MigrationSegment) Command() Command {
+	return CommandMigration
+}
+
+func (*MigrationSegment) ByteSize() int32 {
+	return MigrationSegmentSize
+}
+
+func (s *MigrationSegment) Serialize(b []byte) {
+	binary.BigEndian.PutUint16(b, s.Conv)
+	b[2] = byte(CommandMigration)
+	b[3] = 0
+	binary.BigEndian.PutUint64(b[4:], s.Token)
+}
+
+func (*MigrationSegment) Release() {}
+
 type CmdOnlySegment struct {
 	Conv          uint16
 	Cmd           Command
@@ -293,12 +583,19 @@ func ReadSegment(buf []byte) (Segment, []byte) {
 		seg = NewDataSegment()
 	case CommandACK:
 		seg = NewAckSegment()
+	case CommandFEC:
+		seg = NewFECSegment()
+	case CommandProbe, CommandProbeAck:
+		seg = NewProbeSegment()
+	case CommandMigration:
+		seg = NewMigrationSegment()
 	default:
 		seg = NewCmdOnlySegment()
 	}
 
 	valid, extra := seg.parse(conv, cmd, opt, buf)
 	if !valid {
+		seg.Release()
 		return nil, nil
 	}
 	return seg, extra
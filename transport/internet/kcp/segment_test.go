@@ -19,6 +19,26 @@ func TestBadSegment(t *testing.T) {
 	}
 }
 
+func TestDataSegmentPool(t *testing.T) {
+	seg := NewDataSegment()
+	seg.Number = 1
+	seg.Data().WriteString("abcd")
+	seg.Release()
+
+	seg = NewDataSegment()
+	defer seg.Release()
+	if seg.Number != 0 || seg.Data().Len() != 0 {
+		t.Error("segment from pool not empty: ", seg.Number, " ", seg.Data().Len())
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		NewDataSegment().Release()
+	})
+	if allocs > 0 {
+		t.Error("expect no allocation, but got ", allocs)
+	}
+}
+
 func TestDataSegment(t *testing.T) {
 	seg := &DataSegment{
 		Conv:        1,
@@ -85,6 +105,103 @@ func TestACKSegment(t *testing.T) {
 	}
 }
 
+func TestACKSegmentRanges(t *testing.T) {
+	seg := &AckSegment{
+		Conv:            1,
+		Option:          SegmentOptionClose,
+		ReceivingWindow: 2,
+		ReceivingNext:   3,
+		Timestamp:       10,
+	}
+	for _, number := range []uint32{5, 6, 7, 3, 4, 10} {
+		seg.PutRange(number, number)
+	}
+	if r := cmp.Diff(seg.Ranges, []AckRange{{Begin: 5, End: 7}, {Begin: 3, End: 4}, {Begin: 10, End: 10}}); r != "" {
+		t.Error(r)
+	}
+
+	nBytes := seg.ByteSize()
+	if nBytes != 17+3*8 {
+		t.Error("byte size: ", nBytes)
+	}
+	bytes := make([]byte, nBytes)
+	seg.Serialize(bytes)
+
+	iseg, _ := ReadSegment(bytes)
+	seg2 := iseg.(*AckSegment)
+	if r := cmp.Diff(seg2, seg); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestFECSegment(t *testing.T) {
+	seg := &FECSegment{
+		Conv:         1,
+		Block:        2,
+		Index:        1,
+		ParityShards: 3,
+		DataShards:   []FECShardID{{Number: 4, Timestamp: 5}, {Number: 6, Timestamp: 7}},
+		Parity:       []byte{'a', 'b', 'c', 'd'},
+	}
+
+	nBytes := seg.ByteSize()
+	bytes := make([]byte, nBytes)
+	seg.Serialize(bytes)
+
+	iseg, _ := ReadSegment(bytes)
+	seg2 := iseg.(*FECSegment)
+	if r := cmp.Diff(seg2, seg); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestProbeSegment(t *testing.T) {
+	seg := &ProbeSegment{
+		Conv:    1,
+		Cmd:     CommandProbe,
+		Size:    1200,
+		Padding: 1000,
+	}
+
+	nBytes := seg.ByteSize()
+	bytes := make([]byte, nBytes)
+	seg.Serialize(bytes)
+
+	iseg, extra := ReadSegment(bytes)
+	if len(extra) != 0 {
+		t.Error("extra bytes: ", len(extra))
+	}
+	seg2 := iseg.(*ProbeSegment)
+	if r := cmp.Diff(seg2, seg); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestMigrationSegment(t *testing.T) {
+	seg := &MigrationSegment{
+		Conv:  1,
+		Token: 0x0102030405060708,
+	}
+	data := &CmdOnlySegment{
+		Conv: 1,
+		Cmd:  CommandPing,
+	}
+
+	// The segment precedes the other segments of a packet.
+	bytes := make([]byte, seg.ByteSize()+data.ByteSize())
+	seg.Serialize(bytes)
+	data.Serialize(bytes[seg.ByteSize():])
+
+	iseg, extra := ReadSegment(bytes)
+	seg2 := iseg.(*MigrationSegment)
+	if r := cmp.Diff(seg2, seg); r != "" {
+		t.Error(r)
+	}
+	if len(extra) != int(data.ByteSize()) {
+		t.Error("extra bytes: ", len(extra))
+	}
+}
+
 func TestCmdSegment(t *testing.T) {
 	seg := &CmdOnlySegment{
 		Conv:          1,
@@ -5,15 +5,30 @@ package kcp
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 
+	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
 )
 
+// Congestion Experienced marks are reported to the congestion controller as this loss rate, which is high enough to
+// shrink the window.
+const ecnLossRate = 20
+
 type SendingWindow struct {
 	cache             *list.List
 	totalInFlightSize uint32
 	writer            SegmentWriter
 	onPacketLoss      func(uint32)
+	// Total number of retransmitted segments, and the loss rate in percent of the last flush.
+	retransmits uint32
+	lossRate    uint32
+	// Number of retransmissions of a segment, after which the peer is considered dead. 0 for no limit.
+	maxRetransmit uint32
+	deadLink      bool
+	// Percentage, by which the timeout of a segment grows on each transmission, and its upper bound. 0 for no backoff.
+	backoffFactor     uint32
+	backoffMaxTimeout uint32
 }
 
 func NewSendingWindow(writer SegmentWriter, onPacketLoss func(uint32)) *SendingWindow {
@@ -67,7 +82,9 @@ func (sw *SendingWindow) Clear(una uint32) {
 	}
 }
 
-func (sw *SendingWindow) HandleFastAck(number uint32, rto uint32) {
+// HandleFastAck shortens the timeout of the segments before number by decay, as they are likely lost. Segments
+// transmitted more than limit times are skipped, unless limit is 0.
+func (sw *SendingWindow) HandleFastAck(number uint32, decay uint32, limit uint32) {
 	if sw.IsEmpty() {
 		return
 	}
@@ -77,13 +94,25 @@ func (sw *SendingWindow) HandleFastAck(number uint32, rto uint32) {
 			return false
 		}
 
-		if seg.transmit > 0 && seg.timeout > rto/3 {
-			seg.timeout -= rto / 3
+		if seg.transmit > 0 && (limit == 0 || seg.transmit <= limit) && seg.timeout > decay {
+			seg.timeout -= decay
 		}
 		return true
 	})
 }
 
+// transmits returns the number of transmissions of the segment, or 0 if it is not in the window.
+func (sw *SendingWindow) transmits(number uint32) uint32 {
+	var transmit uint32
+	sw.Visit(func(seg *DataSegment) bool {
+		if seg.Number == number {
+			transmit = seg.transmit
+		}
+		return seg.Number < number
+	})
+	return transmit
+}
+
 func (sw *SendingWindow) Visit(visitor func(seg *DataSegment) bool) {
 	if sw.IsEmpty() {
 		return
@@ -97,9 +126,32 @@ func (sw *SendingWindow) Visit(visitor func(seg *DataSegment) bool) {
 	}
 }
 
-func (sw *SendingWindow) Flush(current uint32, rto uint32, maxInFlightSize uint32) {
+// SetBackoff sets the percentage, by which the timeout of a segment grows on each transmission, and its upper bound.
+func (sw *SendingWindow) SetBackoff(factor uint32, maxTimeout uint32) {
+	sw.backoffFactor = factor
+	sw.backoffMaxTimeout = maxTimeout
+}
+
+// timeout returns the timeout of a segment, which has been transmitted the given number of times.
+func (sw *SendingWindow) timeout(rto uint32, transmit uint32) uint32 {
+	if sw.backoffFactor == 0 || rto >= sw.backoffMaxTimeout {
+		return rto
+	}
+	timeout := uint64(rto)
+	for i := uint32(0); i < transmit && timeout < uint64(sw.backoffMaxTimeout); i++ {
+		timeout = timeout * uint64(sw.backoffFactor) / 100
+	}
+	if timeout > uint64(sw.backoffMaxTimeout) {
+		timeout = uint64(sw.backoffMaxTimeout)
+	}
+	return uint32(timeout)
+}
+
+// Flush sends the segments due for transmission, and returns the number of them. Segments from number end on are beyond
+// the receiving window of the peer, and are not sent for the first time.
+func (sw *SendingWindow) Flush(current uint32, rto uint32, end uint32, maxInFlightSize uint32) uint32 {
 	if sw.IsEmpty() {
-		return
+		return 0
 	}
 
 	var lost uint32
@@ -109,13 +161,21 @@ func (sw *SendingWindow) Flush(current uint32, rto uint32, maxInFlightSize uint3
 		if current-segment.timeout >= 0x7FFFFFFF {
 			return true
 		}
+		if segment.transmit == 0 && segment.Number-end < 0x7FFFFFFF {
+			// The segments after it are not sent yet either.
+			return false
+		}
+		if sw.maxRetransmit > 0 && segment.transmit > sw.maxRetransmit {
+			sw.deadLink = true
+			return false
+		}
 		if segment.transmit == 0 {
 			// First time
 			sw.totalInFlightSize++
 		} else {
 			lost++
 		}
-		segment.timeout = current + rto
+		segment.timeout = current + sw.timeout(rto, segment.transmit)
 
 		segment.Timestamp = current
 		segment.transmit++
@@ -124,10 +184,14 @@ func (sw *SendingWindow) Flush(current uint32, rto uint32, maxInFlightSize uint3
 		return inFlightSize < maxInFlightSize
 	})
 
-	if sw.onPacketLoss != nil && inFlightSize > 0 && sw.totalInFlightSize != 0 {
-		rate := lost * 100 / sw.totalInFlightSize
-		sw.onPacketLoss(rate)
+	sw.retransmits += lost
+	if inFlightSize > 0 && sw.totalInFlightSize != 0 {
+		sw.lossRate = lost * 100 / sw.totalInFlightSize
+		if sw.onPacketLoss != nil {
+			sw.onPacketLoss(sw.lossRate)
+		}
 	}
+	return inFlightSize
 }
 
 func (sw *SendingWindow) Remove(number uint32) bool {
@@ -152,16 +216,45 @@ func (sw *SendingWindow) Remove(number uint32) bool {
 	return false
 }
 
+// RemoveRange removes the segments with numbers from begin to end in one pass, and returns the number of removed
+// segments.
+func (sw *SendingWindow) RemoveRange(begin uint32, end uint32) uint32 {
+	var removed uint32
+	for e := sw.cache.Front(); e != nil; {
+		seg := e.Value.(*DataSegment)
+		if seg.Number > end {
+			break
+		}
+		next := e.Next()
+		if seg.Number >= begin {
+			if sw.totalInFlightSize > 0 {
+				sw.totalInFlightSize--
+			}
+			seg.Release()
+			sw.cache.Remove(e)
+			removed++
+		}
+		e = next
+	}
+	return removed
+}
+
 type SendingWorker struct {
 	sync.RWMutex
-	conn                       *Connection
-	window                     *SendingWindow
-	firstUnacknowledged        uint32
-	nextNumber                 uint32
-	remoteNextNumber           uint32
-	controlWindow              uint32
-	fastResend                 uint32
-	windowSize                 uint32
+	conn                *Connection
+	window              *SendingWindow
+	firstUnacknowledged uint32
+	nextNumber          uint32
+	remoteNextNumber    uint32
+	congestion          CongestionController
+	fec                 *fecEncoder
+	pacer               *pacer
+	tuner               *windowTuner
+	fastResend          uint32
+	fastAckLimit        uint32
+	windowSize          uint32
+	// Number of segments allowed in flight by the last flush.
+	sendingWindow              uint32
 	firstUnacknowledgedUpdated bool
 	closed                     bool
 }
@@ -169,12 +262,32 @@ type SendingWorker struct {
 func NewSendingWorker(kcp *Connection) *SendingWorker {
 	worker := &SendingWorker{
 		conn:             kcp,
-		fastResend:       2,
+		fastResend:       kcp.Config.GetFastResendValue(),
+		fastAckLimit:     kcp.Config.FastAckLimit,
 		remoteNextNumber: 32,
-		controlWindow:    kcp.Config.GetSendingInFlightSize(),
 		windowSize:       kcp.Config.GetSendingBufferSize(),
 	}
+	if kcp.Config.Congestion {
+		congestion, err := NewCongestionController(kcp.Config)
+		if err != nil {
+			newError("falling back to default congestion controller").Base(err).AtWarning().WriteToLog()
+			congestion = newLossController(kcp.Config)
+		}
+		worker.congestion = congestion
+	}
+	if kcp.Config.Pacing {
+		worker.pacer = new(pacer)
+	}
+	if kcp.Config.AutoTuneWindow {
+		worker.tuner = newWindowTuner(kcp.Config.GetSendingInFlightSize(), kcp.Config.GetSendingBufferSize())
+		worker.windowSize = worker.tuner.BufferSize()
+	}
+	if dataShards, parityShards := kcp.Config.GetFECShards(); dataShards > 0 {
+		worker.fec = newFECEncoder(kcp.output, kcp.meta.Conversation, dataShards, parityShards)
+	}
 	worker.window = NewSendingWindow(worker, worker.OnPacketLoss)
+	worker.window.maxRetransmit = kcp.Config.MaxRetransmit
+	worker.window.SetBackoff(kcp.Config.GetRetransmitBackoffValue())
 	return worker
 }
 
@@ -222,6 +335,27 @@ func (w *SendingWorker) processAck(number uint32) bool {
 	return removed
 }
 
+// processAckRange removes the segments acknowledged by the range, and returns the number of removed segments.
+func (w *SendingWorker) processAckRange(r AckRange) uint32 {
+	begin, end := r.Begin, r.End
+	// Only numbers in [w.firstUnacknowledged, w.nextNumber) are in flight.
+	if begin-w.firstUnacknowledged > 0x7FFFFFFF {
+		begin = w.firstUnacknowledged
+	}
+	if end-w.nextNumber < 0x7FFFFFFF {
+		end = w.nextNumber - 1
+	}
+	if end-begin > 0x7FFFFFFF {
+		return 0
+	}
+
+	removed := w.window.RemoveRange(begin, end)
+	if removed > 0 {
+		w.FindFirstUnacknowledged()
+	}
+	return removed
+}
+
 func (w *SendingWorker) ProcessSegment(current uint32, seg *AckSegment, rto uint32) {
 	defer seg.Release()
 
@@ -237,26 +371,64 @@ func (w *SendingWorker) ProcessSegment(current uint32, seg *AckSegment, rto uint
 	}
 	w.ProcessReceivingNextWithoutLock(seg.ReceivingNext)
 
+	if w.congestion != nil && seg.Option&SegmentOptionECE == SegmentOptionECE {
+		// The congestion is signaled before any packet is dropped.
+		w.congestion.OnPacketLoss(ecnLossRate)
+	}
+
 	if seg.IsEmpty() {
 		return
 	}
 
 	var maxack uint32
-	var maxackRemoved bool
 	for _, number := range seg.NumberList {
-		removed := w.processAck(number)
 		if maxack < number {
 			maxack = number
-			maxackRemoved = removed
+		}
+	}
+	for _, r := range seg.Ranges {
+		if maxack < r.End {
+			maxack = r.End
+		}
+	}
+	// By Karn's algorithm, the RTT is not measured from retransmitted segments, as it is unknown which transmission
+	// is acknowledged.
+	retransmitted := w.window.transmits(maxack) > 1
+
+	var maxackRemoved bool
+	var acked uint32
+	for _, number := range seg.NumberList {
+		removed := w.processAck(number)
+		if removed {
+			acked++
+		}
+		if number == maxack && removed {
+			maxackRemoved = true
+		}
+	}
+	for _, r := range seg.Ranges {
+		removed := w.processAckRange(r)
+		acked += removed
+		if r.End == maxack && removed > 0 {
+			maxackRemoved = true
 		}
 	}
 
+	var rtt uint32
 	if maxackRemoved {
-		w.window.HandleFastAck(maxack, rto)
-		if current-seg.Timestamp < 10000 {
-			w.conn.roundTrip.Update(current-seg.Timestamp, current)
+		w.window.HandleFastAck(maxack, rto/w.fastResend, w.fastAckLimit)
+		if !retransmitted && current-seg.Timestamp < 10000 {
+			rtt = current - seg.Timestamp
+			w.conn.roundTrip.Update(rtt, current)
 		}
 	}
+	if w.congestion != nil && acked > 0 {
+		w.congestion.OnAck(current, acked, rtt)
+	}
+	if w.tuner != nil && acked > 0 {
+		w.tuner.OnAck(current, acked, w.conn.roundTrip.SmoothedTime())
+		w.windowSize = w.tuner.BufferSize()
+	}
 }
 
 func (w *SendingWorker) Push(b *buf.Buffer) bool {
@@ -271,6 +443,18 @@ func (w *SendingWorker) Push(b *buf.Buffer) bool {
 		return false
 	}
 
+	// b is larger than the MSS if it is written by WriteMultiBuffer, or if the MSS has been lowered by path MTU
+	// discovery after b is read. Only the remainder after the MSS is copied, while b is sent as is.
+	mss := int32(atomic.LoadUint32(&w.conn.mss))
+	for b.Len() > mss {
+		rest := buf.New()
+		common.Must2(rest.Write(b.BytesFrom(mss)))
+		b.Resize(0, mss)
+		w.window.Push(w.nextNumber, b)
+		w.nextNumber++
+		b = rest
+	}
+
 	w.window.Push(w.nextNumber, b)
 	w.nextNumber++
 	return true
@@ -286,25 +470,21 @@ func (w *SendingWorker) Write(seg Segment) error {
 		dataSeg.Option = SegmentOptionClose
 	}
 
-	return w.conn.output.Write(dataSeg)
+	if err := w.conn.output.Write(dataSeg); err != nil {
+		return err
+	}
+	if w.fec != nil {
+		w.fec.Add(dataSeg)
+	}
+	return nil
 }
 
 func (w *SendingWorker) OnPacketLoss(lossRate uint32) {
-	if !w.conn.Config.Congestion || w.conn.roundTrip.Timeout() == 0 {
+	if w.congestion == nil || w.conn.roundTrip.Timeout() == 0 {
 		return
 	}
 
-	if lossRate >= 15 {
-		w.controlWindow = 3 * w.controlWindow / 4
-	} else if lossRate <= 5 {
-		w.controlWindow += w.controlWindow / 4
-	}
-	if w.controlWindow < 16 {
-		w.controlWindow = 16
-	}
-	if w.controlWindow > 2*w.conn.Config.GetSendingInFlightSize() {
-		w.controlWindow = 2 * w.conn.Config.GetSendingInFlightSize()
-	}
+	w.congestion.OnPacketLoss(lossRate)
 }
 
 func (w *SendingWorker) Flush(current uint32) {
@@ -315,24 +495,53 @@ func (w *SendingWorker) Flush(current uint32) {
 		return
 	}
 
-	cwnd := w.firstUnacknowledged + w.conn.Config.GetSendingInFlightSize()
+	inFlightSize := w.conn.Config.GetSendingInFlightSize()
+	if w.tuner != nil {
+		inFlightSize = w.tuner.Window()
+	}
+	cwnd := w.firstUnacknowledged + inFlightSize
 	if cwnd > w.remoteNextNumber {
 		cwnd = w.remoteNextNumber
 	}
-	if w.conn.Config.Congestion && cwnd > w.firstUnacknowledged+w.controlWindow {
-		cwnd = w.firstUnacknowledged + w.controlWindow
+	if w.congestion != nil && cwnd > w.firstUnacknowledged+w.congestion.Window() {
+		cwnd = w.firstUnacknowledged + w.congestion.Window()
 	}
 
+	w.sendingWindow = cwnd - w.firstUnacknowledged
+
 	if !w.window.IsEmpty() {
-		w.window.Flush(current, w.conn.roundTrip.Timeout(), cwnd)
+		// The first segment is retransmitted on timeout, rather than by fast acknowledgements.
+		first := w.window.cache.Front().Value.(*DataSegment)
+		if first.transmit > 0 && current-first.timeout < 0x7FFFFFFF && current-first.Timestamp >= w.conn.roundTrip.Timeout() {
+			w.conn.roundTrip.Backoff(current)
+		}
+
+		limit := cwnd
+		if w.pacer != nil {
+			if budget := w.pacer.Budget(current, cwnd-w.firstUnacknowledged, w.conn.roundTrip.SmoothedTime()); budget < limit {
+				limit = budget
+			}
+		}
+		sent := w.window.Flush(current, w.conn.roundTrip.Timeout(), w.remoteNextNumber, limit)
+		if w.pacer != nil {
+			w.pacer.Consume(sent)
+		}
 		w.firstUnacknowledgedUpdated = false
 	}
+	if w.fec != nil {
+		w.fec.Flush()
+	}
 
 	updated := w.firstUnacknowledgedUpdated
 	w.firstUnacknowledgedUpdated = false
+	deadLink := w.window.deadLink
 
 	w.Unlock()
 
+	if deadLink {
+		w.conn.fail(ErrDeadLink)
+		return
+	}
 	if updated {
 		w.conn.Ping(current, CommandPing)
 	}
@@ -356,6 +565,20 @@ func (w *SendingWorker) UpdateNecessary() bool {
 	return !w.IsEmpty()
 }
 
+// fillMetrics sets the sending metrics of the connection in m.
+func (w *SendingWorker) fillMetrics(m *Metrics) {
+	w.RLock()
+	defer w.RUnlock()
+
+	m.Retransmits = w.window.retransmits
+	m.LossRate = w.window.lossRate
+	m.InFlight = w.window.totalInFlightSize
+	m.Window = w.sendingWindow
+	if w.windowSize > 0 {
+		m.WindowOccupancy = w.window.Len() * 100 / w.windowSize
+	}
+}
+
 func (w *SendingWorker) FirstUnacknowledged() uint32 {
 	w.RLock()
 	defer w.RUnlock()
@@ -0,0 +1,103 @@
+package kcp_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/buf"
+	. "v2ray.com/core/transport/internet/kcp"
+)
+
+func TestSendingWindowRemoveRange(t *testing.T) {
+	window := NewSendingWindow(nil, nil)
+	for i := uint32(0); i < 10; i++ {
+		window.Push(i, buf.New())
+	}
+
+	if n := window.RemoveRange(2, 4); n != 3 {
+		t.Error("removed: ", n)
+	}
+	if n := window.RemoveRange(3, 6); n != 2 {
+		t.Error("removed: ", n)
+	}
+	if n := window.RemoveRange(20, 30); n != 0 {
+		t.Error("removed: ", n)
+	}
+
+	var numbers []uint32
+	window.Visit(func(seg *DataSegment) bool {
+		numbers = append(numbers, seg.Number)
+		return true
+	})
+	if len(numbers) != 5 || numbers[0] != 0 || numbers[1] != 1 || numbers[2] != 7 || numbers[4] != 9 {
+		t.Error("remaining: ", numbers)
+	}
+}
+
+type noOpSegmentWriter struct{}
+
+func (noOpSegmentWriter) Write(Segment) error {
+	return nil
+}
+
+func TestSendingWindowHandleFastAck(t *testing.T) {
+	window := NewSendingWindow(noOpSegmentWriter{}, nil)
+	for i := uint32(0); i < 3; i++ {
+		window.Push(i, buf.New())
+	}
+	window.Flush(0, 300, 100, 10)
+
+	// Segments 0 and 1 are skipped twice by acknowledgements of segment 2.
+	window.HandleFastAck(2, 100, 0)
+	window.HandleFastAck(2, 100, 1)
+	if n := window.Flush(100, 300, 100, 10); n != 2 {
+		t.Error("flushed: ", n)
+	}
+
+	// Segments 0 and 1 have been transmitted twice, which exceeds the limit.
+	window.HandleFastAck(2, 100, 1)
+	if n := window.Flush(399, 300, 100, 10); n != 1 {
+		t.Error("flushed: ", n)
+	}
+
+	window.HandleFastAck(2, 100, 0)
+	if n := window.Flush(300, 300, 100, 10); n != 2 {
+		t.Error("flushed: ", n)
+	}
+}
+
+func TestSendingWindowBackoff(t *testing.T) {
+	window := NewSendingWindow(noOpSegmentWriter{}, nil)
+	window.SetBackoff(150, 1000)
+	window.Push(0, buf.New())
+
+	// The timeout grows from the RTO of 200 by half on each transmission, up to 1000.
+	current := uint32(0)
+	for _, timeout := range []uint32{200, 300, 450, 675, 1000, 1000} {
+		if n := window.Flush(current, 200, 100, 10); n != 1 {
+			t.Fatal("flushed at ", current, ": ", n)
+		}
+		if n := window.Flush(current+timeout-1, 200, 100, 10); n != 0 {
+			t.Error("flushed before timeout ", timeout, ": ", n)
+		}
+		current += timeout
+	}
+}
+
+func TestSendingWindowEnd(t *testing.T) {
+	window := NewSendingWindow(noOpSegmentWriter{}, nil)
+	for i := uint32(0); i < 5; i++ {
+		window.Push(i, buf.New())
+	}
+
+	// Segments 3 and 4 are beyond the receiving window of the peer.
+	if n := window.Flush(0, 300, 3, 10); n != 3 {
+		t.Error("flushed: ", n)
+	}
+	// Sent segments are resent, even if the window shrinks.
+	if n := window.Flush(300, 300, 2, 10); n != 3 {
+		t.Error("flushed: ", n)
+	}
+	if n := window.Flush(300, 300, 5, 10); n != 2 {
+		t.Error("flushed: ", n)
+	}
+}
@@ -0,0 +1,224 @@
+package kcp
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+)
+
+// simClock is the clock of a simulation, which only advances when the simulation steps.
+type simClock struct {
+	now int64
+}
+
+func (c *simClock) Now() int64 {
+	return c.now
+}
+
+// simLinkConfig describes one direction of a simulated link.
+type simLinkConfig struct {
+	// Percentage of packets dropped.
+	Loss int
+	// One-way delay in milli-sec, and a random extra delay up to Jitter, by which packets are reordered.
+	Latency int64
+	Jitter  int64
+}
+
+type simPacket struct {
+	at      int64
+	seq     int
+	payload []byte
+}
+
+// simLink delivers the packets written to it after their delay, unless they are dropped.
+type simLink struct {
+	config  simLinkConfig
+	clock   *simClock
+	rand    *rand.Rand
+	seq     int
+	packets []simPacket
+}
+
+func (l *simLink) Write(b []byte) (int, error) {
+	if l.rand.Intn(100) < l.config.Loss {
+		return len(b), nil
+	}
+	at := l.clock.now + l.config.Latency
+	if l.config.Jitter > 0 {
+		at += l.rand.Int63n(l.config.Jitter + 1)
+	}
+	l.seq++
+	l.packets = append(l.packets, simPacket{
+		at:      at,
+		seq:     l.seq,
+		payload: append([]byte(nil), b...),
+	})
+	return len(b), nil
+}
+
+// deliver passes the packets due by now to conn.
+func (l *simLink) deliver(conn *Connection) {
+	sort.Slice(l.packets, func(i, j int) bool {
+		if l.packets[i].at != l.packets[j].at {
+			return l.packets[i].at < l.packets[j].at
+		}
+		return l.packets[i].seq < l.packets[j].seq
+	})
+	reader := new(KCPPacketReader)
+	n := 0
+	for n < len(l.packets) && l.packets[n].at <= l.clock.now {
+		if segments := reader.Read(l.packets[n].payload); len(segments) > 0 {
+			conn.Input(segments)
+		}
+		n++
+	}
+	l.packets = l.packets[n:]
+}
+
+type simCloser struct{}
+
+func (simCloser) Close() error {
+	return nil
+}
+
+// simulation runs a client and a server connection over a simulated link, with a simulated clock. Connections are
+// flushed by the simulation instead of their updaters, so that a simulation with the same seed always runs the same.
+type simulation struct {
+	clock    *simClock
+	client   *Connection
+	server   *Connection
+	toServer *simLink
+	toClient *simLink
+}
+
+func newSimulation(config *Config, link simLinkConfig, seed int64) *simulation {
+	clock := new(simClock)
+	random := rand.New(rand.NewSource(seed))
+	s := &simulation{
+		clock:    clock,
+		toServer: &simLink{config: link, clock: clock, rand: random},
+		toClient: &simLink{config: link, clock: clock, rand: random},
+	}
+	newConn := func(link *simLink) *Connection {
+		conn := newConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{Writer: link}, simCloser{}, config, clock.Now)
+		inert := func() *Updater {
+			return NewUpdater(0, func() bool { return false }, func() bool { return true }, nil)
+		}
+		conn.dataUpdater = inert()
+		conn.pingUpdater = inert()
+		return conn
+	}
+	s.client = newConn(s.toServer)
+	s.server = newConn(s.toClient)
+	return s
+}
+
+// transfer sends size bytes from the client to the server, and returns the simulated time in milli-sec it takes, or -1
+// if it doesn't complete within limit milli-sec.
+func (s *simulation) transfer(t testing.TB, size int, limit int64) int64 {
+	defer s.client.Terminate()
+	defer s.server.Terminate()
+
+	tti := int64(s.client.Config.GetTTIValue())
+	start := s.clock.now
+	sent := 0
+	received := 0
+	for s.clock.now-start < limit {
+		for sent < size {
+			b := buf.New()
+			chunk := int(s.client.mss)
+			if chunk > size-sent {
+				chunk = size - sent
+			}
+			for i := 0; i < chunk; i++ {
+				common.Must(b.WriteByte(byte((sent + i) % 251)))
+			}
+			if !s.client.sendingWorker.Push(b) {
+				b.Release()
+				break
+			}
+			sent += chunk
+		}
+
+		s.toServer.deliver(s.server)
+		s.toClient.deliver(s.client)
+
+		mb := s.server.receivingWorker.ReadMultiBuffer()
+		for _, b := range mb {
+			for _, c := range b.Bytes() {
+				if c != byte(received%251) {
+					t.Fatal("corrupted byte at ", received)
+				}
+				received++
+			}
+		}
+		buf.ReleaseMulti(mb)
+		if received == size {
+			return s.clock.now - start
+		}
+
+		if (s.clock.now-start)%tti == 0 {
+			s.client.flush()
+			s.server.flush()
+		}
+		s.clock.now++
+	}
+	return -1
+}
+
+func TestSimulation(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+		link   simLinkConfig
+	}{
+		{name: "lossless", config: &Config{}, link: simLinkConfig{Latency: 20}},
+		{name: "lossy", config: &Config{}, link: simLinkConfig{Loss: 10, Latency: 20, Jitter: 10}},
+		{name: "loss control", config: &Config{Congestion: true}, link: simLinkConfig{Loss: 5, Latency: 50, Jitter: 5}},
+		{name: "bbr", config: &Config{Congestion: true, CongestionControl: "bbr"}, link: simLinkConfig{Loss: 5, Latency: 50}},
+		{name: "backoff", config: &Config{RetransmitBackoff: &RetransmitBackoff{}}, link: simLinkConfig{Loss: 20, Latency: 20}},
+	}
+	for _, c := range cases {
+		elapsed := newSimulation(c.config, c.link, 1).transfer(t, 1024*1024, 60000)
+		if elapsed < 0 {
+			t.Error(c.name, ": transfer not completed")
+			continue
+		}
+		if elapsed < 2*c.link.Latency {
+			t.Error(c.name, ": transfer completed in ", elapsed, "ms")
+		}
+		// The same seed always gives the same result.
+		if again := newSimulation(c.config, c.link, 1).transfer(t, 1024*1024, 60000); again != elapsed {
+			t.Error(c.name, ": transfer completed in ", elapsed, "ms and ", again, "ms")
+		}
+	}
+}
+
+func TestSimulationRetransmits(t *testing.T) {
+	s := newSimulation(&Config{}, simLinkConfig{Latency: 20}, 1)
+	if s.transfer(t, 256*1024, 60000) < 0 {
+		t.Fatal("transfer not completed")
+	}
+	if n := s.client.Metrics().Retransmits; n != 0 {
+		t.Error("retransmits over a lossless link: ", n)
+	}
+
+	s = newSimulation(&Config{}, simLinkConfig{Loss: 10, Latency: 20}, 1)
+	if s.transfer(t, 256*1024, 60000) < 0 {
+		t.Fatal("transfer not completed")
+	}
+	if n := s.client.Metrics().Retransmits; n == 0 {
+		t.Error("no retransmits over a lossy link")
+	}
+}
+
+func BenchmarkSimulation(b *testing.B) {
+	var elapsed int64
+	for i := 0; i < b.N; i++ {
+		elapsed += newSimulation(&Config{}, simLinkConfig{Loss: 5, Latency: 30, Jitter: 10}, int64(i)).transfer(b, 256*1024, 60000)
+	}
+	b.ReportMetric(float64(elapsed)/float64(b.N), "sim-ms/op")
+}
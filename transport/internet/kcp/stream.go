@@ -0,0 +1,403 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/signal/done"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/pipe"
+)
+
+// Commands of stream frames. Each frame in a StreamSession is a header of the stream ID (4 bytes), the command
+// (1 byte) and the length of the payload (2 bytes), followed by the payload. The payload of a window frame is the
+// number of bytes (4 bytes) returned to the window of the stream.
+const (
+	streamCmdOpen byte = iota
+	streamCmdData
+	streamCmdClose
+	streamCmdWindow
+)
+
+const (
+	streamFrameHeaderSize = 7
+	// The payload of a frame fits in one Buffer together with its header.
+	maxStreamFramePayload = buf.Size - streamFrameHeaderSize
+	// Size of the data that may be sent in a stream before it is read by the peer. The receiver buffers up to this
+	// size for each stream, so that a stream not being read never blocks the other streams of the session.
+	streamWindowSize = 512 * 1024
+	// Read data is returned to the window of the peer in updates of at least this size.
+	streamWindowUpdateSize = streamWindowSize / 4
+	// Maximum number of streams opened by the peer at the same time. Streams opened beyond it are closed at once.
+	maxAcceptedStreams = 1024
+)
+
+// StreamSession multiplexes streams over a single connection, so that they share its handshake and its congestion
+// state. Streams are only opened by the client side. Each stream has its own flow control window.
+type StreamSession struct {
+	sync.Mutex
+	conn      internet.Connection
+	writeLock sync.Mutex
+	streams   map[uint32]*stream
+	nextID    uint32
+	// Called with each stream opened by the peer. nil on the client side, which closes the session when all its
+	// streams are closed.
+	onAccept func(internet.Connection)
+	onClose  func()
+	done     *done.Instance
+}
+
+// NewStreamSession creates a StreamSession over conn. If onAccept is not nil, the session accepts streams opened by
+// the peer, and passes them to onAccept.
+func NewStreamSession(conn internet.Connection, onAccept func(internet.Connection)) *StreamSession {
+	s := &StreamSession{
+		conn:     conn,
+		streams:  make(map[uint32]*stream),
+		onAccept: onAccept,
+		done:     done.New(),
+	}
+	go s.run()
+	return s
+}
+
+func putStreamFrameHeader(b []byte, id uint32, cmd byte, length int) {
+	binary.BigEndian.PutUint32(b, id)
+	b[4] = cmd
+	binary.BigEndian.PutUint16(b[5:], uint16(length))
+}
+
+func (s *StreamSession) writeFrame(b []byte) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	_, err := s.conn.Write(b)
+	return err
+}
+
+func (s *StreamSession) writeCommand(id uint32, cmd byte) error {
+	var frame [streamFrameHeaderSize]byte
+	putStreamFrameHeader(frame[:], id, cmd, 0)
+	return s.writeFrame(frame[:])
+}
+
+func (s *StreamSession) writeWindow(id uint32, n int32) error {
+	var frame [streamFrameHeaderSize + 4]byte
+	putStreamFrameHeader(frame[:], id, streamCmdWindow, 4)
+	binary.BigEndian.PutUint32(frame[streamFrameHeaderSize:], uint32(n))
+	return s.writeFrame(frame[:])
+}
+
+func (s *StreamSession) newStream(id uint32) *stream {
+	// The pipe is not limited, as the peer sends no more than the window.
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	st := &stream{
+		id:         id,
+		session:    s,
+		input:      writer,
+		output:     reader,
+		sendWindow: streamWindowSize,
+		recvWindow: streamWindowSize,
+	}
+	st.cond = sync.NewCond(&st.access)
+	st.conn = net.NewConnection(
+		net.ConnectionInputMulti(st),
+		net.ConnectionOutputMulti(st),
+		net.ConnectionLocalAddr(s.conn.LocalAddr()),
+		net.ConnectionRemoteAddr(s.conn.RemoteAddr()),
+	)
+	s.streams[id] = st
+	return st
+}
+
+// OpenStream opens a new stream in the session.
+func (s *StreamSession) OpenStream() (internet.Connection, error) {
+	s.Lock()
+	if s.done.Done() {
+		s.Unlock()
+		return nil, newError("stream session closed")
+	}
+	s.nextID++
+	st := s.newStream(s.nextID)
+	s.Unlock()
+
+	if err := s.writeCommand(st.id, streamCmdOpen); err != nil {
+		// The stream is dropped without closing the session, which is closed by its reading loop on the broken
+		// connection.
+		s.Lock()
+		delete(s.streams, st.id)
+		s.Unlock()
+		st.shutdown()
+		return nil, newError("failed to open stream").Base(err)
+	}
+	return st.conn, nil
+}
+
+func (s *StreamSession) remove(id uint32) {
+	s.Lock()
+	if _, found := s.streams[id]; !found {
+		s.Unlock()
+		return
+	}
+	delete(s.streams, id)
+	idle := s.onAccept == nil && len(s.streams) == 0
+	s.Unlock()
+
+	if idle {
+		s.Close()
+	}
+}
+
+func (s *StreamSession) run() {
+	defer s.Close()
+
+	reader := &buf.BufferedReader{Reader: buf.NewReader(s.conn)}
+	var header [streamFrameHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		id := binary.BigEndian.Uint32(header[:])
+		length := int32(binary.BigEndian.Uint16(header[5:]))
+		if length > maxStreamFramePayload {
+			newError("invalid stream frame of ", length, " bytes").AtWarning().WriteToLog()
+			return
+		}
+
+		switch header[4] {
+		case streamCmdOpen:
+			s.Lock()
+			_, found := s.streams[id]
+			var st *stream
+			refused := false
+			if !found && s.onAccept != nil && !s.done.Done() {
+				if len(s.streams) < maxAcceptedStreams {
+					st = s.newStream(id)
+				} else {
+					refused = true
+				}
+			}
+			s.Unlock()
+			if st != nil {
+				s.onAccept(st.conn)
+			}
+			if refused {
+				newError("refusing stream ", id, ", as ", maxAcceptedStreams, " streams are open").AtWarning().WriteToLog()
+				if err := s.writeCommand(id, streamCmdClose); err != nil {
+					return
+				}
+			}
+		case streamCmdData:
+			payload := buf.New()
+			if _, err := payload.ReadFullFrom(reader, length); err != nil {
+				payload.Release()
+				return
+			}
+			s.Lock()
+			st := s.streams[id]
+			s.Unlock()
+			if st == nil {
+				payload.Release()
+				continue
+			}
+			if !st.receive(length) {
+				payload.Release()
+				newError("closing stream ", id, " for data beyond its window").AtWarning().WriteToLog()
+				st.Close()
+				continue
+			}
+			if err := st.input.WriteMultiBuffer(buf.MultiBuffer{payload}); err != nil {
+				newError("failed to deliver data of stream ", id).Base(err).AtDebug().WriteToLog()
+			}
+		case streamCmdClose:
+			s.Lock()
+			st := s.streams[id]
+			s.Unlock()
+			if st != nil {
+				st.shutdown()
+				s.remove(id)
+			}
+		case streamCmdWindow:
+			var increment [4]byte
+			if length != int32(len(increment)) {
+				newError("invalid window frame of ", length, " bytes").AtWarning().WriteToLog()
+				return
+			}
+			if _, err := io.ReadFull(reader, increment[:]); err != nil {
+				return
+			}
+			s.Lock()
+			st := s.streams[id]
+			s.Unlock()
+			if st != nil {
+				st.grant(binary.BigEndian.Uint32(increment[:]))
+			}
+		default:
+			newError("unknown stream command: ", header[4]).AtWarning().WriteToLog()
+			return
+		}
+	}
+}
+
+// Close closes the session, all its streams, and the underlying connection.
+func (s *StreamSession) Close() error {
+	s.Lock()
+	if s.done.Done() {
+		s.Unlock()
+		return nil
+	}
+	s.done.Close()
+	streams := s.streams
+	s.streams = make(map[uint32]*stream)
+	s.Unlock()
+
+	for _, st := range streams {
+		st.shutdown()
+	}
+	if s.onClose != nil {
+		s.onClose()
+	}
+	return s.conn.Close()
+}
+
+// stream is a stream in a StreamSession.
+type stream struct {
+	id      uint32
+	session *StreamSession
+	input   *pipe.Writer
+	output  *pipe.Reader
+	conn    net.Conn
+	closed  sync.Once
+
+	access sync.Mutex
+	cond   *sync.Cond
+	// Bytes that may be sent before the peer returns more to the window.
+	sendWindow int64
+	// Bytes that the peer may send, and bytes read but not returned to the peer yet.
+	recvWindow int32
+	consumed   int32
+	done       bool
+}
+
+// reserve waits until the window is open, and takes at most max bytes from it.
+func (s *stream) reserve(max int32) (int32, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	for s.sendWindow <= 0 && !s.done {
+		s.cond.Wait()
+	}
+	if s.done {
+		return 0, io.ErrClosedPipe
+	}
+	n := max
+	if int64(n) > s.sendWindow {
+		n = int32(s.sendWindow)
+	}
+	s.sendWindow -= int64(n)
+	return n, nil
+}
+
+// grant returns n bytes to the window of the stream, after they are read by the peer.
+func (s *stream) grant(n uint32) {
+	s.access.Lock()
+	s.sendWindow += int64(n)
+	s.cond.Broadcast()
+	s.access.Unlock()
+}
+
+// receive takes n bytes received from the peer from the window. It returns false if the peer sent beyond the window.
+func (s *stream) receive(n int32) bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if n > s.recvWindow {
+		return false
+	}
+	s.recvWindow -= n
+	return true
+}
+
+// shutdown closes the input of the stream, and wakes up the writers waiting for the window.
+func (s *stream) shutdown() {
+	s.access.Lock()
+	s.done = true
+	s.cond.Broadcast()
+	s.access.Unlock()
+
+	s.input.Close()
+}
+
+// ReadMultiBuffer implements buf.Reader. The data read is returned to the window of the peer.
+func (s *stream) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := s.output.ReadMultiBuffer()
+	if n := mb.Len(); n > 0 {
+		s.access.Lock()
+		s.consumed += n
+		var increment int32
+		if s.consumed >= streamWindowUpdateSize && !s.done {
+			increment = s.consumed
+			s.consumed = 0
+			s.recvWindow += increment
+		}
+		s.access.Unlock()
+
+		if increment > 0 {
+			if err := s.session.writeWindow(s.id, increment); err != nil {
+				newError("failed to update window of stream ", s.id).Base(err).AtDebug().WriteToLog()
+			}
+		}
+	}
+	return mb, err
+}
+
+// Interrupt implements common.Interruptible. The data not read yet is discarded.
+func (s *stream) Interrupt() {
+	s.output.Interrupt()
+}
+
+// WriteMultiBuffer implements buf.Writer. It blocks while the window is closed.
+func (s *stream) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	frame := buf.New()
+	defer frame.Release()
+
+	for !mb.IsEmpty() {
+		size := mb.Len()
+		if size > maxStreamFramePayload {
+			size = maxStreamFramePayload
+		}
+		size, err := s.reserve(size)
+		if err != nil {
+			buf.ReleaseMulti(mb)
+			return err
+		}
+
+		frame.Clear()
+		header := frame.Extend(streamFrameHeaderSize)
+		var n int
+		mb, n = buf.SplitBytes(mb, frame.Extend(size))
+		frame.Resize(0, int32(streamFrameHeaderSize+n))
+		putStreamFrameHeader(header, s.id, streamCmdData, n)
+		if err := s.session.writeFrame(frame.Bytes()); err != nil {
+			buf.ReleaseMulti(mb)
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements io.Closer. It is called when the stream is closed locally.
+func (s *stream) Close() error {
+	s.closed.Do(func() {
+		s.shutdown()
+		if err := s.session.writeCommand(s.id, streamCmdClose); err != nil {
+			newError("failed to close stream ", s.id).Base(err).AtDebug().WriteToLog()
+		}
+		s.session.remove(s.id)
+	})
+	return nil
+}
@@ -0,0 +1,154 @@
+package kcp_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/kcp"
+)
+
+func TestStreamSession(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	accepted := make(chan internet.Connection, 2)
+	server := NewStreamSession(serverConn, func(conn internet.Connection) {
+		accepted <- conn
+	})
+	defer server.Close()
+	client := NewStreamSession(clientConn, nil)
+
+	stream1, err := client.OpenStream()
+	common.Must(err)
+	stream2, err := client.OpenStream()
+	common.Must(err)
+	common.Must2(stream1.Write([]byte("stream1")))
+	common.Must2(stream2.Write([]byte("stream2")))
+
+	for _, expected := range []string{"stream1", "stream2"} {
+		conn := <-accepted
+		b := make([]byte, len(expected))
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != expected {
+			t.Error("expect ", expected, ", but got ", string(b))
+		}
+		common.Must2(conn.Write(b))
+		defer conn.Close()
+	}
+
+	b := make([]byte, 7)
+	common.Must2(io.ReadFull(stream2, b))
+	if string(b) != "stream2" {
+		t.Error("expect stream2, but got ", string(b))
+	}
+	common.Must2(io.ReadFull(stream1, b))
+	if string(b) != "stream1" {
+		t.Error("expect stream1, but got ", string(b))
+	}
+
+	common.Must(stream1.Close())
+	common.Must(stream2.Close())
+	// The client session is closed with its last stream.
+	if _, err := client.OpenStream(); err == nil {
+		t.Error("expect client session to be closed")
+	}
+	if _, err := ioutil.ReadAll(clientConn); err == nil {
+		t.Error("expect closed connection")
+	}
+}
+
+func TestStreamSessionWindow(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	accepted := make(chan internet.Connection, 2)
+	server := NewStreamSession(serverConn, func(conn internet.Connection) {
+		accepted <- conn
+	})
+	defer server.Close()
+	client := NewStreamSession(clientConn, nil)
+	defer client.Close()
+
+	bulk, err := client.OpenStream()
+	common.Must(err)
+	bulkServer := <-accepted
+
+	// Twice the window of a stream, which is not read for now.
+	payload := make([]byte, 1024*1024)
+	common.Must2(rand.Read(payload))
+	written := make(chan error, 1)
+	go func() {
+		_, err := bulk.Write(payload)
+		written <- err
+	}()
+
+	// Other streams are not blocked.
+	echo, err := client.OpenStream()
+	common.Must(err)
+	echoServer := <-accepted
+	common.Must2(echo.Write([]byte("ping")))
+	b := make([]byte, 4)
+	common.Must2(io.ReadFull(echoServer, b))
+	common.Must2(echoServer.Write(b))
+	common.Must2(io.ReadFull(echo, b))
+	if string(b) != "ping" {
+		t.Error("unexpected echo ", string(b))
+	}
+
+	select {
+	case err := <-written:
+		t.Fatal("write beyond the window is not blocked: ", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	received := make([]byte, len(payload))
+	common.Must2(io.ReadFull(bulkServer, received))
+	if !bytes.Equal(received, payload) {
+		t.Error("unexpected payload")
+	}
+	common.Must(<-written)
+}
+
+func TestStreamSessionLimit(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	// The session accepts at most 1024 streams from the peer.
+	const limit = 1024
+	accepted := make(chan internet.Connection, limit)
+	server := NewStreamSession(serverConn, func(conn internet.Connection) {
+		accepted <- conn
+	})
+	defer server.Close()
+	client := NewStreamSession(clientConn, nil)
+	defer client.Close()
+
+	for i := 0; i < limit; i++ {
+		_, err := client.OpenStream()
+		common.Must(err)
+	}
+	refused, err := client.OpenStream()
+	common.Must(err)
+	if _, err := refused.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("expect EOF of refused stream, but got ", err)
+	}
+
+	// Streams may be opened again after one is closed.
+	common.Must((<-accepted).Close())
+	stream, err := client.OpenStream()
+	common.Must(err)
+	common.Must2(stream.Write([]byte("ok")))
+	for i := 1; i < limit; i++ {
+		<-accepted
+	}
+	conn := <-accepted
+	b := make([]byte, 2)
+	common.Must2(io.ReadFull(conn, b))
+	if string(b) != "ok" {
+		t.Error("unexpected data ", string(b))
+	}
+}
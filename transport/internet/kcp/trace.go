@@ -0,0 +1,88 @@
+// +build !confonly
+
+package kcp
+
+import (
+	"v2ray.com/core/transport/internet/trace"
+)
+
+// tracingWriter records segments before they are sent.
+type tracingWriter struct {
+	writer  SegmentWriter
+	session *trace.Session
+}
+
+func (w *tracingWriter) Write(seg Segment) error {
+	traceSegment(w.session, trace.Send, seg)
+	return w.writer.Write(seg)
+}
+
+func traceSegment(s *trace.Session, direction string, seg Segment) {
+	if s == nil {
+		return
+	}
+
+	switch seg := seg.(type) {
+	case *DataSegment:
+		attributes := map[string]interface{}{
+			"conv":  seg.Conv,
+			"seq":   seg.Number,
+			"una":   seg.SendingNext,
+			"ts":    seg.Timestamp,
+			"len":   seg.payload.Len(),
+			"close": seg.Option == SegmentOptionClose,
+		}
+		if direction == trace.Send {
+			// Retransmissions are only known to the sender.
+			attributes["transmit"] = seg.transmit
+			attributes["rtx"] = seg.transmit > 1
+		}
+		s.Record("kcp", direction, "data", attributes)
+	case *AckSegment:
+		attributes := map[string]interface{}{
+			"conv":   seg.Conv,
+			"window": seg.ReceivingWindow,
+			"next":   seg.ReceivingNext,
+			"ts":     seg.Timestamp,
+			"acks":   seg.NumberList,
+		}
+		if len(seg.Ranges) > 0 {
+			attributes["ranges"] = seg.Ranges
+		}
+		if seg.Option&SegmentOptionECE == SegmentOptionECE {
+			attributes["ece"] = true
+		}
+		s.Record("kcp", direction, "ack", attributes)
+	case *CmdOnlySegment:
+		eventType := "cmd"
+		switch seg.Cmd {
+		case CommandPing:
+			eventType = "ping"
+		case CommandTerminate:
+			eventType = "terminate"
+		}
+		s.Record("kcp", direction, eventType, map[string]interface{}{
+			"conv": seg.Conv,
+			"una":  seg.SendingNext,
+			"next": seg.ReceivingNext,
+			"rto":  seg.PeerRTO,
+		})
+	case *ProbeSegment:
+		eventType := "probe"
+		if seg.Cmd == CommandProbeAck {
+			eventType = "probe_ack"
+		}
+		s.Record("kcp", direction, eventType, map[string]interface{}{
+			"conv": seg.Conv,
+			"size": seg.Size,
+		})
+	case *FECSegment:
+		s.Record("kcp", direction, "fec", map[string]interface{}{
+			"conv":   seg.Conv,
+			"block":  seg.Block,
+			"index":  seg.Index,
+			"shards": len(seg.DataShards),
+			"len":    len(seg.Parity),
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package internet
 
+import "v2ray.com/core/transport/internet/trace"
+
 // MemoryStreamConfig is a parsed form of StreamConfig. This is used to reduce number of Protobuf parsing.
 type MemoryStreamConfig struct {
 	ProtocolName     string
@@ -7,6 +9,7 @@ type MemoryStreamConfig struct {
 	SecurityType     string
 	SecuritySettings interface{}
 	SocketSettings   *SocketConfig
+	Tracer           *trace.Tracer
 }
 
 // ToMemoryStreamConfig converts a StreamConfig to MemoryStreamConfig. It returns a default non-nil MemoryStreamConfig for nil input.
@@ -25,6 +28,10 @@ func ToMemoryStreamConfig(s *StreamConfig) (*MemoryStreamConfig, error) {
 		mss.SocketSettings = s.SocketSettings
 	}
 
+	if t := s.GetTraceSettings(); t != nil && t.Path != "" {
+		mss.Tracer = trace.Open(t.Path, t.Sample)
+	}
+
 	if s != nil && s.HasSecuritySettings() {
 		ess, err := s.GetEffectiveSecuritySettings()
 		if err != nil {
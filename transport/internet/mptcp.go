@@ -0,0 +1,17 @@
+// +build go1.21
+
+package internet
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func setMultipathTCP(dialer *net.Dialer) error {
+	dialer.SetMultipathTCP(true)
+	return nil
+}
+
+func setListenerMultipathTCP(lc *net.ListenConfig) error {
+	lc.SetMultipathTCP(true)
+	return nil
+}
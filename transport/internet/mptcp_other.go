@@ -0,0 +1,15 @@
+// +build !go1.21
+
+package internet
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func setMultipathTCP(dialer *net.Dialer) error {
+	return newError("MPTCP requires Go 1.21 or later")
+}
+
+func setListenerMultipathTCP(lc *net.ListenConfig) error {
+	return newError("MPTCP requires Go 1.21 or later")
+}
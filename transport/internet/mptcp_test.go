@@ -0,0 +1,45 @@
+// +build go1.21
+
+package internet_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/transport/internet"
+)
+
+func TestMultipathTCP(t *testing.T) {
+	sockopt := &SocketConfig{Mptcp: true}
+	listener, err := ListenSystem(context.Background(), &net.TCPAddr{IP: net.IP{127, 0, 0, 1}}, sockopt)
+	common.Must(err)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		accepted <- conn
+	}()
+
+	dest := net.DestinationFromAddr(listener.Addr())
+	conn, err := DialSystem(context.Background(), dest, sockopt)
+	common.Must(err)
+	defer conn.Close()
+	server := <-accepted
+	defer server.Close()
+
+	// Without MPTCP support of the kernel, the connection falls back to TCP.
+	common.Must2(conn.Write([]byte("test")))
+	b := make([]byte, 4)
+	common.Must2(io.ReadFull(server, b))
+	if string(b) != "test" {
+		t.Error("received ", string(b))
+	}
+	if mptcp, err := conn.(*net.TCPConn).MultipathTCP(); err == nil {
+		t.Log("MPTCP in use: ", mptcp)
+	}
+}
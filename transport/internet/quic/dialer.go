@@ -116,6 +116,20 @@ func (s *clientSessions) cleanSessions() error {
 	return nil
 }
 
+// reset closes all sessions.
+func (s *clientSessions) reset() {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	for _, sessions := range s.sessions {
+		for _, c := range sessions {
+			c.session.Close() // nolint: errcheck
+			c.rawConn.Close() // nolint: errcheck
+		}
+	}
+	s.sessions = make(map[net.Destination][]*sessionContext)
+}
+
 func (s *clientSessions) openConnection(destAddr net.Addr, config *Config, tlsConfig *tls.Config, sockopt *internet.SocketConfig) (internet.Connection, error) {
 	s.access.Lock()
 	defer s.access.Unlock()
@@ -140,10 +154,10 @@ func (s *clientSessions) openConnection(destAddr net.Addr, config *Config, tlsCo
 
 	sessions = removeInactiveSessions(sessions)
 
-	rawConn, err := internet.ListenSystemPacket(context.Background(), &net.UDPAddr{
+	rawConn, err := internet.DialSystemPacket(context.Background(), &net.UDPAddr{
 		IP:   []byte{0, 0, 0, 0},
 		Port: 0,
-	}, sockopt)
+	}, dest, sockopt)
 	if err != nil {
 		return nil, err
 	}
@@ -183,6 +197,7 @@ func init() {
 		Execute:  client.cleanSessions,
 	}
 	common.Must(client.cleanup.Start())
+	internet.RegisterConnectionPool(client.reset)
 }
 
 func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
@@ -0,0 +1,73 @@
+// +build linux
+
+package sockmap
+
+import (
+	"golang.org/x/sys/unix"
+
+	"v2ray.com/core/common/net"
+)
+
+// Max number of bytes moved by a splice(2) call, which is the default capacity of a pipe.
+const pipeSize = 64 * 1024
+
+func pipeAvailable() bool {
+	return true
+}
+
+// spliceStream moves bytes from src to dst with splice(2) through a pipe, until src reaches EOF. onData is called with
+// the number of bytes moved by each splice(2) call.
+func spliceStream(dst, src *net.TCPConn, onData func(int)) error {
+	srcConn, err := src.SyscallConn()
+	if err != nil {
+		return err
+	}
+	dstConn, err := dst.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return newError("failed to create pipe").Base(err)
+	}
+	defer unix.Close(p[0]) // nolint: errcheck
+	defer unix.Close(p[1]) // nolint: errcheck
+
+	const flags = unix.SPLICE_F_MOVE | unix.SPLICE_F_NONBLOCK
+	for {
+		// The pipe is empty here, so EAGAIN means that src has nothing to read yet.
+		var n int64
+		var serr error
+		if err := srcConn.Read(func(fd uintptr) bool {
+			// The type of the result of splice(2) differs among platforms.
+			r, e := unix.Splice(int(fd), nil, p[1], nil, pipeSize, flags)
+			n, serr = int64(r), e
+			return serr != unix.EAGAIN
+		}); err != nil {
+			return err
+		}
+		if serr != nil {
+			return newError("failed to splice from socket").Base(serr)
+		}
+		if n == 0 {
+			return nil
+		}
+
+		for pending := n; pending > 0; {
+			var m int64
+			if err := dstConn.Write(func(fd uintptr) bool {
+				r, e := unix.Splice(p[0], nil, int(fd), nil, int(pending), flags)
+				m, serr = int64(r), e
+				return serr != unix.EAGAIN
+			}); err != nil {
+				return err
+			}
+			if serr != nil {
+				return newError("failed to splice to socket").Base(serr)
+			}
+			pending -= m
+		}
+		onData(int(n))
+	}
+}
@@ -0,0 +1,121 @@
+// +build linux
+
+package sockmap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/pipe"
+)
+
+func tcpPair() (*net.TCPConn, *net.TCPConn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	server, err := listener.Accept()
+	common.Must(err)
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+func TestSpliceStream(t *testing.T) {
+	client, src := tcpPair()
+	dst, server := tcpPair()
+	defer client.Close()
+	defer src.Close()
+	defer dst.Close()
+	defer server.Close()
+
+	payload := make([]byte, 4*1024*1024)
+	common.Must2(rand.Read(payload))
+	go func() {
+		common.Must2(client.Write(payload))
+		common.Must(client.CloseWrite())
+	}()
+
+	var relayed int
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- spliceStream(dst, src, func(n int) {
+			relayed += n
+		})
+	}()
+
+	received := make([]byte, len(payload))
+	common.Must2(io.ReadFull(server, received))
+	common.Must(<-errCh)
+	if !bytes.Equal(received, payload) {
+		t.Error("payload is not relayed in order")
+	}
+	if relayed != len(payload) {
+		t.Error("relayed ", relayed, " bytes of ", len(payload))
+	}
+}
+
+func TestRelayWithPipes(t *testing.T) {
+	client, inbound := tcpPair()
+	outbound, server := tcpPair()
+	defer client.Close()
+	defer server.Close()
+
+	relay, err := NewRelay(inbound)
+	common.Must(err)
+	relay.useSockmap = false
+
+	reader, writer := pipe.New()
+	inboundDone := make(chan error, 1)
+	go func() {
+		inboundDone <- buf.Copy(buf.NewReader(relay), writer)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	common.Must(relay.Splice(ctx, outbound, reader))
+
+	request := make([]byte, 1024*1024)
+	common.Must2(rand.Read(request))
+	go func() {
+		common.Must2(client.Write(request))
+		common.Must(client.CloseWrite())
+	}()
+	received := make([]byte, len(request))
+	common.Must2(io.ReadFull(server, received))
+	if !bytes.Equal(received, request) {
+		t.Fatal("request is not relayed in order")
+	}
+
+	response := []byte("response")
+	common.Must2(server.Write(response))
+	common.Must(server.CloseWrite())
+	b, err := ioutil.ReadAll(client)
+	common.Must(err)
+	if !bytes.Equal(b, response) {
+		t.Error("unexpected response: ", string(b))
+	}
+
+	// Both the inbound reader and the outbound reader observe EOF once the relay finishes.
+	select {
+	case err := <-inboundDone:
+		common.Must(err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("inbound reader does not finish")
+	}
+	if n, err := relay.Outbound().Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Error("outbound read: ", n, err)
+	}
+
+	relay.Drain()
+	inbound.Close()
+	outbound.Close()
+}
@@ -0,0 +1,15 @@
+// +build !linux
+
+package sockmap
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func pipeAvailable() bool {
+	return false
+}
+
+func spliceStream(dst, src *net.TCPConn, onData func(int)) error {
+	return newError("splice is not supported on this platform")
+}
@@ -0,0 +1,338 @@
+// Package sockmap relays bytes between two TCP connections entirely in kernel, with eBPF sockmap programs on Linux.
+// If sockmap is not available, such as without the privilege of loading eBPF programs, the bytes are moved with
+// splice(2) through a pipe, which saves copying them to user space, though not the system calls.
+//
+// An inbound proxy that relays a connection as is creates a Relay and passes it to the outbound through context.
+// Once the outbound has connected to the destination, it hands both connections over to the kernel with Splice.
+// After that, reading the connections in user space only observes EOF.
+package sockmap
+
+//go:generate errorgen
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/signal/done"
+)
+
+const (
+	keepAliveInterval = time.Millisecond * 250
+	drainInterval     = time.Millisecond * 10
+	drainTimeout      = time.Second * 5
+)
+
+// Available returns true if the system supports relaying in kernel, with sockmap or with splice(2).
+func Available() bool {
+	return available() || pipeAvailable()
+}
+
+// Relay coordinates the handover of a relay session to the kernel.
+type Relay struct {
+	inbound    *net.TCPConn
+	useSockmap bool
+
+	access     sync.Mutex
+	pause      *done.Instance
+	resume     *done.Instance
+	readerDone bool
+
+	outbound *net.TCPConn
+	spliced  *done.Instance
+
+	// Both directions of the relay, if spliced through pipes instead of sockmap.
+	upstream   *pipeRelay
+	downstream *pipeRelay
+}
+
+// pipeRelay is one direction of a relay through a pipe.
+type pipeRelay struct {
+	done *done.Instance
+	err  error
+	// Number of bytes relayed.
+	relayed uint64
+}
+
+func newPipeRelay() *pipeRelay {
+	return &pipeRelay{
+		done: done.New(),
+	}
+}
+
+func (p *pipeRelay) run(dst, src *net.TCPConn) {
+	p.err = spliceStream(dst, src, func(n int) {
+		atomic.AddUint64(&p.relayed, uint64(n))
+	})
+	if p.err == nil {
+		p.err = dst.CloseWrite()
+	}
+	p.done.Close() // nolint: errcheck
+}
+
+// Read waits until the relay finishes, as the source connection must not be read elsewhere meanwhile.
+func (p *pipeRelay) Read(b []byte) (int, error) {
+	<-p.done.Wait()
+	if p.err != nil {
+		return 0, p.err
+	}
+	return 0, io.EOF
+}
+
+// NewRelay creates a Relay for the given inbound connection.
+// It returns an error if the connection is not a plain TCP connection, or if sockmap is not available.
+func NewRelay(conn net.Conn) (*Relay, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil, newError("not a TCP connection")
+	}
+	if !Available() {
+		return nil, newError("relaying in kernel is not available")
+	}
+	return &Relay{
+		inbound:    tcpConn,
+		useSockmap: available(),
+		spliced:    done.New(),
+	}, nil
+}
+
+// Read reads from the inbound connection. The inbound proxy must read the connection only through this method,
+// so that it can be paused while the connections are handed over.
+func (r *Relay) Read(b []byte) (int, error) {
+	for {
+		r.waitForResume()
+
+		r.access.Lock()
+		upstream := r.upstream
+		r.access.Unlock()
+		if upstream != nil {
+			return upstream.Read(b)
+		}
+
+		n, err := r.inbound.Read(b)
+		if err != nil && n == 0 && r.pauseRequested() {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				continue
+			}
+		}
+		if err != nil {
+			r.access.Lock()
+			r.readerDone = true
+			if r.pause != nil {
+				r.pause.Close() // nolint: errcheck
+			}
+			r.access.Unlock()
+		}
+		return n, err
+	}
+}
+
+func (r *Relay) pauseRequested() bool {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	return r.pause != nil
+}
+
+func (r *Relay) waitForResume() {
+	r.access.Lock()
+	pause, resume := r.pause, r.resume
+	r.access.Unlock()
+
+	if pause == nil {
+		return
+	}
+	pause.Close() // nolint: errcheck
+	<-resume.Wait()
+}
+
+// Splice hands the inbound connection and the given outbound connection over to the kernel. Payload already read from
+// the inbound connection is drained from input and written to the outbound connection first.
+// If Splice returns an error, the connections are left as they were, and the caller should continue relaying in user space.
+func (r *Relay) Splice(ctx context.Context, conn net.Conn, input buf.Reader) error {
+	outbound, ok := conn.(*net.TCPConn)
+	if !ok {
+		return newError("outbound is not a TCP connection")
+	}
+
+	r.access.Lock()
+	if r.readerDone || r.pause != nil || r.spliced.Done() {
+		r.access.Unlock()
+		return newError("inbound connection is not available")
+	}
+	r.pause = done.New()
+	r.resume = done.New()
+	pause, resume := r.pause, r.resume
+	r.access.Unlock()
+
+	defer func() {
+		r.access.Lock()
+		r.pause = nil
+		r.resume = nil
+		r.access.Unlock()
+
+		r.inbound.SetReadDeadline(time.Time{}) // nolint: errcheck
+		resume.Close()                         // nolint: errcheck
+	}()
+
+	// Interrupt pending read on the inbound connection, and keep draining input until the reader is paused,
+	// as it may be blocked on writing to input.
+	if err := r.inbound.SetReadDeadline(time.Now()); err != nil {
+		return newError("failed to interrupt inbound reader").Base(err)
+	}
+	writer := buf.NewWriter(outbound)
+	for !pause.Done() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := buf.CopyOnceTimeout(input, writer, drainInterval); err != nil && err != buf.ErrReadTimeout {
+			return newError("failed to drain input").Base(err)
+		}
+	}
+	for {
+		err := buf.CopyOnceTimeout(input, writer, 0)
+		if err == buf.ErrReadTimeout {
+			break
+		}
+		if err != nil {
+			return newError("failed to drain input").Base(err)
+		}
+	}
+
+	r.access.Lock()
+	readerDone := r.readerDone
+	r.access.Unlock()
+	if readerDone {
+		return newError("inbound connection is closed")
+	}
+
+	if r.useSockmap {
+		if err := splice(r.inbound, outbound); err != nil {
+			return err
+		}
+	} else {
+		if err := r.inbound.SetReadDeadline(time.Time{}); err != nil {
+			return newError("failed to reset inbound read deadline").Base(err)
+		}
+		upstream, downstream := newPipeRelay(), newPipeRelay()
+		r.access.Lock()
+		r.upstream = upstream
+		r.downstream = downstream
+		r.access.Unlock()
+		go upstream.run(outbound, r.inbound)
+		go downstream.run(r.inbound, outbound)
+	}
+	r.outbound = outbound
+	r.spliced.Close() // nolint: errcheck
+	return nil
+}
+
+// Outbound returns the reader of the outbound connection after Splice. The outbound proxy must read the connection
+// only through it, which observes EOF when the peer closes the connection.
+func (r *Relay) Outbound() io.Reader {
+	if r.downstream != nil {
+		return r.downstream
+	}
+	return r.outbound
+}
+
+// received returns the total number of bytes received on the spliced connections.
+func (r *Relay) received() (uint64, error) {
+	if r.upstream != nil {
+		return atomic.LoadUint64(&r.upstream.relayed) + atomic.LoadUint64(&r.downstream.relayed), nil
+	}
+	a, err := readCounters(r.inbound)
+	if err != nil {
+		return 0, err
+	}
+	b, err := readCounters(r.outbound)
+	if err != nil {
+		return 0, err
+	}
+	return a.received + b.received, nil
+}
+
+// KeepAlive updates the timer as long as there is traffic on the spliced connections, as the traffic is invisible
+// to user space. It returns when ctx is done.
+func (r *Relay) KeepAlive(ctx context.Context, timer signal.ActivityUpdater) {
+	select {
+	case <-r.spliced.Wait():
+	case <-ctx.Done():
+		return
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	var last uint64
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		current, err := r.received()
+		if err != nil {
+			return
+		}
+		if current != last {
+			last = current
+			timer.Update()
+		}
+	}
+}
+
+// Drain waits until all payload received on the spliced connections has been written to their counterparts.
+// It must be called before closing either of the connections, as the kernel discards pending payload on close.
+// Connections spliced through pipes need no draining, as their payload is written before the relay observes EOF.
+func (r *Relay) Drain() {
+	if !r.spliced.Done() || r.upstream != nil {
+		return
+	}
+
+	deadline := time.Now().Add(drainTimeout)
+	for time.Now().Before(deadline) {
+		a, err1 := readCounters(r.inbound)
+		b, err2 := readCounters(r.outbound)
+		if err1 != nil || err2 != nil {
+			return
+		}
+		if a.received == b.written && b.received == a.written {
+			return
+		}
+		time.Sleep(drainInterval)
+	}
+	newError("timeout waiting for spliced connections to drain").AtDebug().WriteToLog()
+}
+
+// counters are the byte counters of a TCP connection.
+type counters struct {
+	// received is the number of bytes received from the peer.
+	received uint64
+	// written is the number of bytes written to the connection, sent or not.
+	written uint64
+}
+
+type relayKey int
+
+const relayContextKey relayKey = iota
+
+// ContextWithRelay returns a new context with the given Relay.
+func ContextWithRelay(ctx context.Context, relay *Relay) context.Context {
+	return context.WithValue(ctx, relayContextKey, relay)
+}
+
+// RelayFromContext returns the Relay in ctx, or nil if there is none.
+func RelayFromContext(ctx context.Context) *Relay {
+	if relay, ok := ctx.Value(relayContextKey).(*Relay); ok {
+		return relay
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+// +build linux,amd64 linux,arm64 linux,arm linux,riscv64 linux,ppc64le linux,mipsle linux,mips64le
+
+package sockmap
+
+import (
+	"encoding/binary"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"v2ray.com/core/common/net"
+)
+
+// Constants from linux/bpf.h. Instructions and attributes are encoded in little endian, so only little endian
+// architectures are supported.
+const (
+	bpfMapCreate          = 0
+	bpfMapUpdate          = 2
+	bpfProgLoad           = 5
+	bpfProgAttach         = 8
+	bpfMapTypeSockHash    = 18
+	bpfProgTypeSkSkb      = 14
+	bpfSkSkbStreamVerdict = 5
+
+	bpfFuncGetSocketCookie = 46
+	bpfFuncSkRedirectHash  = 72
+
+	maxSessions = 65536
+
+	soCookie = 57
+)
+
+type bpfMapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+type bpfMapUpdateAttr struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+type bpfProgLoadAttr struct {
+	progType    uint32
+	insnCount   uint32
+	insns       uint64
+	license     uint64
+	logLevel    uint32
+	logSize     uint32
+	logBuf      uint64
+	kernVersion uint32
+	progFlags   uint32
+}
+
+type bpfProgAttachAttr struct {
+	targetFD    uint32
+	attachBpfFD uint32
+	attachType  uint32
+	attachFlags uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	r, _, errno := syscall.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r), nil
+}
+
+// instruction encodes an eBPF instruction.
+func instruction(code uint8, dst, src uint8, off int16, imm int32) []byte {
+	b := make([]byte, 8)
+	b[0] = code
+	b[1] = src<<4 | dst
+	binary.LittleEndian.PutUint16(b[2:], uint16(off))
+	binary.LittleEndian.PutUint32(b[4:], uint32(imm))
+	return b
+}
+
+// verdictProgram redirects each incoming skb to the egress of the socket stored in the target map,
+// under the cookie of the receiving socket.
+func verdictProgram(targets int) []byte {
+	const (
+		r0, r1, r2, r3, r4, r6, r10 = 0, 1, 2, 3, 4, 6, 10
+		pseudoMapFD                 = 1
+	)
+	var prog []byte
+	for _, insn := range [][]byte{
+		instruction(0xbf, r6, r1, 0, 0),                       // r6 = r1
+		instruction(0x85, 0, 0, 0, bpfFuncGetSocketCookie),    // r0 = bpf_get_socket_cookie(skb)
+		instruction(0x7b, r10, r0, -8, 0),                     // *(u64 *)(r10 - 8) = r0
+		instruction(0xbf, r1, r6, 0, 0),                       // r1 = r6
+		instruction(0x18, r2, pseudoMapFD, 0, int32(targets)), // r2 = targets
+		instruction(0x00, 0, 0, 0, 0),
+		instruction(0xbf, r3, r10, 0, 0),                  // r3 = r10
+		instruction(0x07, r3, 0, 0, -8),                   // r3 += -8
+		instruction(0xb7, r4, 0, 0, 0),                    // r4 = 0
+		instruction(0x85, 0, 0, 0, bpfFuncSkRedirectHash), // r0 = bpf_sk_redirect_hash(skb, targets, &cookie, 0)
+		instruction(0x95, 0, 0, 0, 0),                     // return r0
+	} {
+		prog = append(prog, insn...)
+	}
+	return prog
+}
+
+// maps holds the two socket maps for all spliced connections. Sockets in targets are the destinations of redirection,
+// keyed by the cookie of their counterparts. Sockets in sources, keyed by their own cookies, have the verdict program
+// attached, and their incoming payload is redirected. A socket in targets only is not affected, so both sockets of
+// a session can be made targets before either of them starts redirecting.
+type maps struct {
+	targets int
+	sources int
+}
+
+var (
+	loadAccess sync.Mutex
+	loaded     *maps
+	loadFailed bool
+)
+
+func createMap() (int, error) {
+	attr := bpfMapCreateAttr{
+		mapType:    bpfMapTypeSockHash,
+		keySize:    8,
+		valueSize:  4,
+		maxEntries: maxSessions,
+	}
+	fd, err := bpf(bpfMapCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return 0, newError("failed to create sockhash").Base(err)
+	}
+	return fd, nil
+}
+
+func loadMaps() (*maps, error) {
+	loadAccess.Lock()
+	defer loadAccess.Unlock()
+
+	if loaded != nil {
+		return loaded, nil
+	}
+	if loadFailed {
+		return nil, newError("sockmap is not supported")
+	}
+
+	m, err := createMaps()
+	if err != nil {
+		loadFailed = true
+		return nil, err
+	}
+	loaded = m
+	return m, nil
+}
+
+func createMaps() (*maps, error) {
+	targets, err := createMap()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := createMap()
+	if err != nil {
+		syscall.Close(targets) // nolint: errcheck
+		return nil, err
+	}
+
+	prog := verdictProgram(targets)
+	license := []byte("GPL\x00")
+	attr := bpfProgLoadAttr{
+		progType:  bpfProgTypeSkSkb,
+		insnCount: uint32(len(prog) / 8),
+		insns:     uint64(uintptr(unsafe.Pointer(&prog[0]))),
+		license:   uint64(uintptr(unsafe.Pointer(&license[0]))),
+	}
+	progFD, err := bpf(bpfProgLoad, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		syscall.Close(targets) // nolint: errcheck
+		syscall.Close(sources) // nolint: errcheck
+		return nil, newError("failed to load verdict program").Base(err)
+	}
+	defer syscall.Close(progFD) // nolint: errcheck
+
+	attach := bpfProgAttachAttr{
+		targetFD:    uint32(sources),
+		attachBpfFD: uint32(progFD),
+		attachType:  bpfSkSkbStreamVerdict,
+	}
+	if _, err := bpf(bpfProgAttach, unsafe.Pointer(&attach), unsafe.Sizeof(attach)); err != nil {
+		syscall.Close(targets) // nolint: errcheck
+		syscall.Close(sources) // nolint: errcheck
+		return nil, newError("failed to attach verdict program").Base(err)
+	}
+
+	return &maps{
+		targets: targets,
+		sources: sources,
+	}, nil
+}
+
+func updateMap(mapFD int, key uint64, fd int) error {
+	value := uint32(fd)
+	attr := bpfMapUpdateAttr{
+		mapFD: uint32(mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key))),
+		value: uint64(uintptr(unsafe.Pointer(&value))),
+	}
+	_, err := bpf(bpfMapUpdate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+func available() bool {
+	_, err := loadMaps()
+	return err == nil
+}
+
+// control runs f on the file descriptor of conn.
+func control(conn *net.TCPConn, f func(fd int) error) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rawConn.Control(func(fd uintptr) {
+		ferr = f(int(fd))
+	}); err != nil {
+		return err
+	}
+	return ferr
+}
+
+func getsockopt(fd, level, name int, b []byte) (int, error) {
+	size := uint32(len(b))
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&size)), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(size), nil
+}
+
+func socketCookie(fd int) (uint64, error) {
+	var b [8]byte
+	if _, err := getsockopt(fd, syscall.SOL_SOCKET, soCookie, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+type socket struct {
+	fd     int
+	cookie uint64
+}
+
+func splice(a, b *net.TCPConn) error {
+	m, err := loadMaps()
+	if err != nil {
+		return err
+	}
+
+	var sockets [2]socket
+	for i, conn := range []*net.TCPConn{a, b} {
+		s := &sockets[i]
+		if err := control(conn, func(fd int) error {
+			s.fd = fd
+			cookie, err := socketCookie(fd)
+			s.cookie = cookie
+			return err
+		}); err != nil {
+			return newError("failed to get socket cookie").Base(err)
+		}
+	}
+
+	// The file descriptors remain valid, as the connections are not closed until the relay finishes.
+	if err := updateMap(m.targets, sockets[0].cookie, sockets[1].fd); err != nil {
+		return newError("failed to add socket to sockhash").Base(err)
+	}
+	if err := updateMap(m.targets, sockets[1].cookie, sockets[0].fd); err != nil {
+		return newError("failed to add socket to sockhash").Base(err)
+	}
+	for _, s := range sockets {
+		if err := updateMap(m.sources, s.cookie, s.fd); err != nil {
+			return newError("failed to add socket to sockhash").Base(err)
+		}
+	}
+
+	// Payload queued before the verdict program is attached is only processed on the next notification of
+	// incoming data. Setting SO_RCVLOWAT triggers the notification immediately.
+	for _, s := range sockets {
+		if err := syscall.SetsockoptInt(s.fd, syscall.SOL_SOCKET, syscall.SO_RCVLOWAT, 1); err != nil {
+			return newError("failed to set SO_RCVLOWAT").Base(err)
+		}
+	}
+
+	return nil
+}
+
+// Offsets of fields in struct tcp_info.
+const (
+	tcpInfoState         = 0
+	tcpInfoBytesReceived = 128
+	tcpInfoNotSentBytes  = 144
+	tcpInfoBytesSent     = 200
+	tcpInfoBytesRetrans  = 208
+	tcpInfoMinSize       = 216
+)
+
+// TCP states, in which FIN has been received.
+const (
+	tcpTimeWait  = 6
+	tcpCloseWait = 8
+	tcpLastAck   = 9
+	tcpClosing   = 11
+)
+
+func readCounters(conn *net.TCPConn) (counters, error) {
+	var c counters
+	err := control(conn, func(fd int) error {
+		var info [256]byte
+		n, err := getsockopt(fd, syscall.SOL_TCP, syscall.TCP_INFO, info[:])
+		if err != nil {
+			return err
+		}
+		if n < tcpInfoMinSize {
+			return newError("TCP_INFO is too short")
+		}
+		c.received = binary.LittleEndian.Uint64(info[tcpInfoBytesReceived:])
+		// FIN is counted in bytes received.
+		switch info[tcpInfoState] {
+		case tcpTimeWait, tcpCloseWait, tcpLastAck, tcpClosing:
+			c.received--
+		}
+		c.written = binary.LittleEndian.Uint64(info[tcpInfoBytesSent:]) -
+			binary.LittleEndian.Uint64(info[tcpInfoBytesRetrans:]) +
+			uint64(binary.LittleEndian.Uint32(info[tcpInfoNotSentBytes:]))
+		return nil
+	})
+	return c, err
+}
@@ -0,0 +1,124 @@
+// +build linux,amd64 linux,arm64
+
+package sockmap_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/transport/internet/sockmap"
+	"v2ray.com/core/transport/pipe"
+)
+
+// connPair returns both ends of a loopback TCP connection.
+func connPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	server, err := listener.Accept()
+	common.Must(err)
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+func TestSplice(t *testing.T) {
+	if !Available() {
+		t.Skip("sockmap is not available")
+	}
+
+	client, inbound := connPair(t)
+	outbound, server := connPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	relay, err := NewRelay(inbound)
+	common.Must(err)
+
+	// Inbound side, reading through the relay as an inbound proxy does.
+	reader, writer := pipe.New()
+	inboundDone := make(chan error, 1)
+	go func() {
+		inboundDone <- buf.Copy(buf.NewReader(relay), writer)
+	}()
+
+	// Payload before splicing goes through user space.
+	head := []byte("before splice")
+	_, err = client.Write(head)
+	common.Must(err)
+	time.Sleep(time.Millisecond * 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	common.Must(relay.Splice(ctx, outbound, reader))
+
+	// Payload after splicing is relayed in kernel, in both directions.
+	request := make([]byte, 1024*1024)
+	common.Must2(rand.Read(request))
+	go func() {
+		common.Must2(client.Write(request))
+		common.Must(client.CloseWrite())
+	}()
+
+	received := make([]byte, len(head)+len(request))
+	common.Must2(io.ReadFull(server, received))
+	if !bytes.Equal(received[:len(head)], head) || !bytes.Equal(received[len(head):], request) {
+		t.Fatal("request is not relayed in order")
+	}
+
+	response := []byte("response")
+	common.Must2(server.Write(response))
+	b := make([]byte, len(response))
+	common.Must2(io.ReadFull(client, b))
+	if !bytes.Equal(b, response) {
+		t.Error("unexpected response: ", string(b))
+	}
+
+	// The inbound reader observes EOF once the client closes.
+	select {
+	case err := <-inboundDone:
+		common.Must(err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("inbound reader does not finish")
+	}
+
+	relay.Drain()
+	inbound.Close()
+	outbound.Close()
+}
+
+func TestSpliceAfterClose(t *testing.T) {
+	if !Available() {
+		t.Skip("sockmap is not available")
+	}
+
+	client, inbound := connPair(t)
+	outbound, server := connPair(t)
+	defer inbound.Close()
+	defer outbound.Close()
+	defer server.Close()
+
+	relay, err := NewRelay(inbound)
+	common.Must(err)
+
+	reader, writer := pipe.New()
+	go func() {
+		buf.Copy(buf.NewReader(relay), writer) // nolint: errcheck
+		writer.Close()
+	}()
+	client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := relay.Splice(ctx, outbound, reader); err == nil {
+		t.Error("expect error splicing a closed connection")
+	}
+}
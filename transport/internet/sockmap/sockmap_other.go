@@ -0,0 +1,19 @@
+// +build !linux linux,!amd64,!arm64,!arm,!riscv64,!ppc64le,!mipsle,!mips64le
+
+package sockmap
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func available() bool {
+	return false
+}
+
+func splice(a, b *net.TCPConn) error {
+	return newError("sockmap is not supported on this platform")
+}
+
+func readCounters(conn *net.TCPConn) (counters, error) {
+	return counters{}, newError("sockmap is not supported on this platform")
+}
@@ -1,5 +1,11 @@
 package internet
 
+// ReusePortBalanced returns true if the system distributes incoming connections and datagrams among
+// sockets listening on the same address with SO_REUSEPORT.
+func ReusePortBalanced() bool {
+	return reusePortBalanced
+}
+
 func isTCPSocket(network string) bool {
 	switch network {
 	case "tcp", "tcp4", "tcp6":
@@ -0,0 +1,30 @@
+// +build linux freebsd darwin openbsd
+
+package internet
+
+import (
+	"syscall"
+)
+
+func setSocketBuffers(fd int, config *SocketConfig) error {
+	if config.ReceiveBufferSize > 0 {
+		if err := setSocketBuffer(fd, syscall.SO_RCVBUF, soRcvBufForce, int(config.ReceiveBufferSize)); err != nil {
+			return newError("failed to set SO_RCVBUF").Base(err)
+		}
+	}
+	if config.SendBufferSize > 0 {
+		if err := setSocketBuffer(fd, syscall.SO_SNDBUF, soSndBufForce, int(config.SendBufferSize)); err != nil {
+			return newError("failed to set SO_SNDBUF").Base(err)
+		}
+	}
+	return nil
+}
+
+// setSocketBuffer tries the forced variant of the option first, if the system has one (non-zero force). It is not
+// limited by the system maximum, but requires privileges.
+func setSocketBuffer(fd int, opt int, force int, size int) error {
+	if force != 0 && syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, force, size) == nil {
+		return nil
+	}
+	return syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, opt, size)
+}
@@ -1,7 +1,11 @@
 package internet
 
 import (
+	"net"
+	"strings"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -11,9 +15,39 @@ const (
 	TCP_FASTOPEN_SERVER = 0x01
 	// TCP_FASTOPEN_CLIENT is the value to enable TCP fast open on darwin for client connections.
 	TCP_FASTOPEN_CLIENT = 0x02
+
+	tcpKeepIdle = unix.TCP_KEEPALIVE
+
+	soRcvBufForce = 0
+	soSndBufForce = 0
 )
 
+func bindInterface(network string, fd uintptr, name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(network, "6") {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, unix.IPV6_BOUND_IF, iface.Index)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, unix.IP_BOUND_IF, iface.Index)
+}
+
 func applyOutboundSocketOptions(network string, address string, fd uintptr, config *SocketConfig) error {
+	if config.Interface != "" {
+		if err := bindInterface(network, fd, config.Interface); err != nil {
+			return newError("failed to bind to interface ", config.Interface).Base(err)
+		}
+	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
@@ -31,6 +65,20 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 }
 
 func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig) error {
+	if config.Interface != "" {
+		if err := bindInterface(network, fd, config.Interface); err != nil {
+			return newError("failed to bind to interface ", config.Interface).Base(err)
+		}
+	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
@@ -55,6 +103,8 @@ func setReuseAddr(fd uintptr) error {
 	return nil
 }
 
+const reusePortBalanced = false
+
 func setReusePort(fd uintptr) error {
 	return nil
 }
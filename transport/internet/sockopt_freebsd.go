@@ -16,6 +16,11 @@ const (
 	sysPFOUT       = 0x2
 	sysPFFWD       = 0x3
 	sysDIOCNATLOOK = 0xc04c4417
+
+	tcpKeepIdle = unix.TCP_KEEPIDLE
+
+	soRcvBufForce = 0
+	soSndBufForce = 0
 )
 
 type pfiocNatlook struct {
@@ -129,6 +134,15 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		}
 	}
 
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
@@ -163,6 +177,15 @@ func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig)
 			return newError("failed to set SO_USER_COOKIE").Base(err)
 		}
 	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
@@ -220,6 +243,8 @@ func setReuseAddr(fd uintptr) error {
 	return nil
 }
 
+const reusePortBalanced = true
+
 func setReusePort(fd uintptr) error {
 	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReUsePortLB, 1); err != nil {
 		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReUsePort, 1); err != nil {
@@ -0,0 +1,31 @@
+// +build linux freebsd darwin
+
+package internet
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func setTCPKeepAlive(fd int, config *SocketConfig_TCPKeepAlive) error {
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1); err != nil {
+		return newError("failed to set SO_KEEPALIVE").Base(err)
+	}
+	if config.Idle > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpKeepIdle, int(config.Idle)); err != nil {
+			return newError("failed to set TCP keep-alive idle time").Base(err)
+		}
+	}
+	if config.Interval > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, unix.TCP_KEEPINTVL, int(config.Interval)); err != nil {
+			return newError("failed to set TCP_KEEPINTVL").Base(err)
+		}
+	}
+	if config.Count > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, unix.TCP_KEEPCNT, int(config.Count)); err != nil {
+			return newError("failed to set TCP_KEEPCNT").Base(err)
+		}
+	}
+	return nil
+}
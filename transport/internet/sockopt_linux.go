@@ -12,6 +12,11 @@ const (
 	TCP_FASTOPEN = 23
 	// For out-going connections.
 	TCP_FASTOPEN_CONNECT = 30
+
+	tcpKeepIdle = unix.TCP_KEEPIDLE
+
+	soRcvBufForce = unix.SO_RCVBUFFORCE
+	soSndBufForce = unix.SO_SNDBUFFORCE
 )
 
 func bindAddr(fd uintptr, ip []byte, port uint32) error {
@@ -46,6 +51,20 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 			return newError("failed to set SO_MARK").Base(err)
 		}
 	}
+	if config.Interface != "" {
+		if err := unix.BindToDevice(int(fd), config.Interface); err != nil {
+			return newError("failed to set SO_BINDTODEVICE").Base(err)
+		}
+	}
+
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
 
 	if isTCPSocket(network) {
 		switch config.Tfo {
@@ -75,6 +94,20 @@ func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig)
 			return newError("failed to set SO_MARK").Base(err)
 		}
 	}
+	if config.Interface != "" {
+		if err := unix.BindToDevice(int(fd), config.Interface); err != nil {
+			return newError("failed to set SO_BINDTODEVICE").Base(err)
+		}
+	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(int(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
@@ -112,6 +145,8 @@ func setReuseAddr(fd uintptr) error {
 	return nil
 }
 
+const reusePortBalanced = true
+
 func setReusePort(fd uintptr) error {
 	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
 		return newError("failed to set SO_REUSEPORT").Base(err).AtWarning()
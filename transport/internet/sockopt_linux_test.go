@@ -5,6 +5,8 @@ import (
 	"syscall"
 	"testing"
 
+	"golang.org/x/sys/unix"
+
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/testing/servers/tcp"
@@ -40,3 +42,104 @@ func TestSockOptMark(t *testing.T) {
 	})
 	common.Must(err)
 }
+
+func TestSockOptInterface(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{Interface: "lo"})
+	if err != nil {
+		t.Skip("unable to bind to lo: ", err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	common.Must(err)
+	err = rawConn.Control(func(fd uintptr) {
+		name, err := unix.GetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE)
+		common.Must(err)
+		if name != "lo" {
+			t.Error("bound to interface ", name)
+		}
+	})
+	common.Must(err)
+}
+
+func TestSockOptTCPKeepAlive(t *testing.T) {
+	sockopt := &SocketConfig{
+		TcpKeepAlive: &SocketConfig_TCPKeepAlive{
+			Idle:     30,
+			Interval: 5,
+			Count:    3,
+		},
+	}
+	listener, err := ListenSystem(context.Background(), &net.TCPAddr{IP: net.IP{127, 0, 0, 1}}, sockopt)
+	common.Must(err)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		accepted <- conn
+	}()
+
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, net.DestinationFromAddr(listener.Addr()), sockopt)
+	common.Must(err)
+	defer conn.Close()
+	server := <-accepted
+	defer server.Close()
+
+	for _, c := range []net.Conn{conn, server} {
+		rawConn, err := c.(*net.TCPConn).SyscallConn()
+		common.Must(err)
+		common.Must(rawConn.Control(func(fd uintptr) {
+			for _, opt := range []struct {
+				level, name, value int
+			}{
+				{syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1},
+				{syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, 30},
+				{syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, 5},
+				{syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, 3},
+			} {
+				v, err := syscall.GetsockoptInt(int(fd), opt.level, opt.name)
+				common.Must(err)
+				if v != opt.value {
+					t.Error("option ", opt.name, ": ", v, " want ", opt.value)
+				}
+			}
+		}))
+	}
+}
+
+func TestSockOptBufferSize(t *testing.T) {
+	const size = 1 << 16
+	sockopt := &SocketConfig{
+		ReceiveBufferSize: size,
+		SendBufferSize:    size,
+	}
+	conn, err := ListenSystemPacket(context.Background(), &net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, sockopt)
+	common.Must(err)
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.UDPConn).SyscallConn()
+	common.Must(err)
+	common.Must(rawConn.Control(func(fd uintptr) {
+		for _, opt := range []int{syscall.SO_RCVBUF, syscall.SO_SNDBUF} {
+			v, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+			common.Must(err)
+			// Linux doubles the value for bookkeeping overhead.
+			if v != 2*size {
+				t.Error("option ", opt, ": ", v, " want ", 2*size)
+			}
+		}
+	}))
+}
@@ -0,0 +1,93 @@
+package internet
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Connections diverted by pf with divert-to rules are delivered to the local socket as is, so the original
+// destination of TCP connections is the local address of the accepted socket. For UDP, the original
+// destination is received in control messages.
+
+const (
+	soRcvBufForce = 0
+	soSndBufForce = 0
+)
+
+func applyOutboundSocketOptions(network string, address string, fd uintptr, config *SocketConfig) error {
+	if config.Tproxy.IsEnabled() {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, unix.SO_BINDANY, 1); err != nil {
+			return newError("failed to set outbound SO_BINDANY").Base(err)
+		}
+	}
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+	return nil
+}
+
+func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig) error {
+	if err := setSocketBuffers(int(fd), config); err != nil {
+		return err
+	}
+	if config.ReceiveOriginalDestAddress && isUDPSocket(network) {
+		err1 := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, unix.IP_RECVDSTADDR, 1)
+		if err1 == nil {
+			err1 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, unix.IP_RECVDSTPORT, 1)
+		}
+		err2 := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, unix.IPV6_RECVPKTINFO, 1)
+		if err2 == nil {
+			err2 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, unix.IPV6_RECVDSTPORT, 1)
+		}
+		if err1 != nil && err2 != nil {
+			return newError("failed to set IP_RECVDSTPORT").Base(err1)
+		}
+	}
+
+	return nil
+}
+
+func bindAddr(fd uintptr, ip []byte, port uint32) error {
+	setReuseAddr(fd)
+	setReusePort(fd)
+
+	var sockaddr syscall.Sockaddr
+
+	switch len(ip) {
+	case net.IPv4len:
+		a4 := &syscall.SockaddrInet4{
+			Port: int(port),
+		}
+		copy(a4.Addr[:], ip)
+		sockaddr = a4
+	case net.IPv6len:
+		a6 := &syscall.SockaddrInet6{
+			Port: int(port),
+		}
+		copy(a6.Addr[:], ip)
+		sockaddr = a6
+	default:
+		return newError("unexpected length of ip")
+	}
+
+	return syscall.Bind(int(fd), sockaddr)
+}
+
+func setReuseAddr(fd uintptr) error {
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+		return newError("failed to set SO_REUSEADDR").Base(err).AtWarning()
+	}
+	return nil
+}
+
+// OpenBSD does not balance load among sockets with SO_REUSEPORT.
+const reusePortBalanced = false
+
+func setReusePort(fd uintptr) error {
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEPORT, 1); err != nil {
+		return newError("failed to set SO_REUSEPORT").Base(err).AtWarning()
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-// +build js dragonfly netbsd openbsd solaris
+// +build js dragonfly netbsd solaris
 
 package internet
 
@@ -18,6 +18,8 @@ func setReuseAddr(fd uintptr) error {
 	return nil
 }
 
+const reusePortBalanced = false
+
 func setReusePort(fd uintptr) error {
 	return nil
 }
@@ -1,13 +1,70 @@
 package internet
 
 import (
+	"math/bits"
+	"net"
+	"strings"
 	"syscall"
 )
 
 const (
-	TCP_FASTOPEN = 15
+	TCP_FASTOPEN    = 15
+	IP_UNICAST_IF   = 31
+	IPV6_UNICAST_IF = 31
+	TCP_KEEPIDLE    = 3
+	TCP_KEEPCNT     = 16
+	TCP_KEEPINTVL   = 17
 )
 
+// setTCPKeepAlive sets the parameters of keep-alive, which are supported since Windows 10 version 1709.
+func setTCPKeepAlive(fd syscall.Handle, config *SocketConfig_TCPKeepAlive) error {
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1); err != nil {
+		return newError("failed to set SO_KEEPALIVE").Base(err)
+	}
+	if config.Idle > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, TCP_KEEPIDLE, int(config.Idle)); err != nil {
+			return newError("failed to set TCP_KEEPIDLE").Base(err)
+		}
+	}
+	if config.Interval > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, TCP_KEEPINTVL, int(config.Interval)); err != nil {
+			return newError("failed to set TCP_KEEPINTVL").Base(err)
+		}
+	}
+	if config.Count > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, TCP_KEEPCNT, int(config.Count)); err != nil {
+			return newError("failed to set TCP_KEEPCNT").Base(err)
+		}
+	}
+	return nil
+}
+
+func setSocketBuffers(fd syscall.Handle, config *SocketConfig) error {
+	if config.ReceiveBufferSize > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, int(config.ReceiveBufferSize)); err != nil {
+			return newError("failed to set SO_RCVBUF").Base(err)
+		}
+	}
+	if config.SendBufferSize > 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, int(config.SendBufferSize)); err != nil {
+			return newError("failed to set SO_SNDBUF").Base(err)
+		}
+	}
+	return nil
+}
+
+func bindInterface(network string, fd syscall.Handle, name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(network, "6") {
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, IPV6_UNICAST_IF, iface.Index)
+	}
+	// The index is in network byte order for IPv4.
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, IP_UNICAST_IF, int(bits.ReverseBytes32(uint32(iface.Index))))
+}
+
 func setTFO(fd syscall.Handle, settings SocketConfig_TCPFastOpenState) error {
 	switch settings {
 	case SocketConfig_Enable:
@@ -23,6 +80,20 @@ func setTFO(fd syscall.Handle, settings SocketConfig_TCPFastOpenState) error {
 }
 
 func applyOutboundSocketOptions(network string, address string, fd uintptr, config *SocketConfig) error {
+	if config.Interface != "" {
+		if err := bindInterface(network, syscall.Handle(fd), config.Interface); err != nil {
+			return newError("failed to bind to interface ", config.Interface).Base(err)
+		}
+	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(syscall.Handle(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(syscall.Handle(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		if err := setTFO(syscall.Handle(fd), config.Tfo); err != nil {
 			return err
@@ -34,6 +105,20 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 }
 
 func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig) error {
+	if config.Interface != "" {
+		if err := bindInterface(network, syscall.Handle(fd), config.Interface); err != nil {
+			return newError("failed to bind to interface ", config.Interface).Base(err)
+		}
+	}
+	if config.TcpKeepAlive != nil && isTCPSocket(network) {
+		if err := setTCPKeepAlive(syscall.Handle(fd), config.TcpKeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := setSocketBuffers(syscall.Handle(fd), config); err != nil {
+		return err
+	}
+
 	if isTCPSocket(network) {
 		if err := setTFO(syscall.Handle(fd), config.Tfo); err != nil {
 			return err
@@ -51,6 +136,8 @@ func setReuseAddr(fd uintptr) error {
 	return nil
 }
 
+const reusePortBalanced = false
+
 func setReusePort(fd uintptr) error {
 	return nil
 }
@@ -56,8 +56,10 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		if err != nil {
 			return nil, err
 		}
+		d.applyControllers(ctx, dest.Network.SystemString(), dest.NetAddr(), packetConn)
 		destAddr, err := net.ResolveUDPAddr("udp", dest.NetAddr())
 		if err != nil {
+			packetConn.Close()
 			return nil, err
 		}
 		return &packetConnWrapper{
@@ -72,9 +74,25 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		LocalAddr: resolveSrcAddr(dest.Network, src),
 	}
 
-	if sockopt != nil || len(d.controllers) > 0 {
+	if sockopt != nil && sockopt.TcpKeepAlive != nil {
+		// Keep-alive is set on the socket instead.
+		dialer.KeepAlive = -1
+	}
+	if sockopt != nil && sockopt.Mptcp && dest.Network == net.Network_TCP {
+		if err := setMultipathTCP(dialer); err != nil {
+			newError("failed to enable MPTCP").Base(err).WriteToLog(session.ExportIDToError(ctx))
+		}
+	}
+
+	records := RedirectRecordsFromContext(ctx)
+	if sockopt != nil || len(d.controllers) > 0 || len(records) > 0 {
 		dialer.Control = func(network, address string, c syscall.RawConn) error {
 			return c.Control(func(fd uintptr) {
+				if len(records) > 0 {
+					if err := setRedirectRecords(fd, records); err != nil {
+						newError("failed to apply redirect records").Base(err).WriteToLog(session.ExportIDToError(ctx))
+					}
+				}
 				if sockopt != nil {
 					if err := applyOutboundSocketOptions(network, address, fd, sockopt); err != nil {
 						newError("failed to apply socket options").Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -95,9 +113,50 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		}
 	}
 
+	if sockopt != nil && sockopt.HappyEyeballs != nil && dest.Network == net.Network_TCP && dest.Address.Family().IsDomain() {
+		return dialHappyEyeballs(ctx, dialer, dest, sockopt.HappyEyeballs)
+	}
 	return dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
 }
 
+// applyControllers runs all controllers on the socket of a connection that was not created by Dial.
+func (d *DefaultSystemDialer) applyControllers(ctx context.Context, network, address string, conn interface{}) {
+	if len(d.controllers) == 0 {
+		return
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		newError("failed to get raw connection").Base(err).WriteToLog(session.ExportIDToError(ctx))
+		return
+	}
+	rawConn.Control(func(fd uintptr) {
+		for _, ctl := range d.controllers {
+			if err := ctl(network, address, fd); err != nil {
+				newError("failed to apply external controller").Base(err).WriteToLog(session.ExportIDToError(ctx))
+			}
+		}
+	})
+}
+
+// DialSystemPacket creates a packet connection for sending packets to the given destination.
+// Unlike ListenSystemPacket, controllers of the system dialer are applied to the socket.
+//
+// v2ray:api:beta
+func DialSystemPacket(ctx context.Context, addr net.Addr, dest net.Destination, sockopt *SocketConfig) (net.PacketConn, error) {
+	conn, err := ListenSystemPacket(ctx, addr, sockopt)
+	if err != nil {
+		return nil, err
+	}
+	if dialer, ok := effectiveSystemDialer.(*DefaultSystemDialer); ok {
+		dialer.applyControllers(ctx, dest.Network.SystemString(), dest.NetAddr(), conn)
+	}
+	return conn, nil
+}
+
 type packetConnWrapper struct {
 	conn net.PacketConn
 	dest net.Addr
@@ -167,6 +226,8 @@ func UseAlternativeSystemDialer(dialer SystemDialer) {
 
 // RegisterDialerController adds a controller to the effective system dialer.
 // The controller can be used to operate on file descriptors before they are put into use.
+// It is called for every outgoing socket before any packet is sent, so it is the place to protect sockets
+// from being routed back into a VPN, e.g., by VpnService.protect() on Android.
 // It only works when effective dialer is the default dialer.
 //
 // v2ray:api:beta
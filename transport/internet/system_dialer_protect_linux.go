@@ -0,0 +1,49 @@
+package internet
+
+import (
+	"syscall"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/platform"
+)
+
+// protectSocket sends fd to the protector listening on the given unix socket, and waits for its answer.
+// The protocol is compatible with the one used by shadowsocks-android: the fd is sent as SCM_RIGHTS along with one byte,
+// and the protector replies one byte, which is 0 on success.
+// A path starting with '@' refers to an abstract unix socket.
+func protectSocket(path string, fd uintptr) error {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return newError("failed to connect to protector at ", path).Base(err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(3 * time.Second)); err != nil {
+		return err
+	}
+
+	if _, _, err := conn.WriteMsgUnix([]byte{0}, syscall.UnixRights(int(fd)), nil); err != nil {
+		return newError("failed to send fd to protector").Base(err)
+	}
+
+	var result [1]byte
+	if _, err := conn.Read(result[:]); err != nil {
+		return newError("failed to read result from protector").Base(err)
+	}
+	if result[0] != 0 {
+		return newError("protector failed to protect fd ", fd, " with code ", result[0])
+	}
+	return nil
+}
+
+func init() {
+	path := platform.NewEnvFlag("v2ray.protect.path").GetValue(func() string { return "" })
+	if len(path) == 0 {
+		return
+	}
+	common.Must(RegisterDialerController(func(network, address string, fd uintptr) error {
+		return protectSocket(path, fd)
+	}))
+}
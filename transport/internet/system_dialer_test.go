@@ -0,0 +1,46 @@
+package internet
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+)
+
+// useDefaultSystemDialer replaces the system dialer with a new default one for the duration of the test, so that
+// controllers registered by the test are removed afterwards.
+func useDefaultSystemDialer(t *testing.T) {
+	previous := effectiveSystemDialer
+	effectiveSystemDialer = &DefaultSystemDialer{}
+	t.Cleanup(func() {
+		effectiveSystemDialer = previous
+	})
+}
+
+func TestDialerControllerOnUDP(t *testing.T) {
+	useDefaultSystemDialer(t)
+
+	var access sync.Mutex
+	var protected []string
+	common.Must(RegisterDialerController(func(network, address string, fd uintptr) error {
+		access.Lock()
+		defer access.Unlock()
+		protected = append(protected, network+"://"+address)
+		return nil
+	}))
+
+	dest := net.UDPDestination(net.LocalHostIP, net.Port(53))
+	conn, err := DialSystem(context.Background(), dest, nil)
+	common.Must(err)
+	conn.Close()
+
+	access.Lock()
+	defer access.Unlock()
+	if r := cmp.Diff(protected, []string{"udp://127.0.0.1:53"}); r != "" {
+		t.Error(r)
+	}
+}
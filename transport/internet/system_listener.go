@@ -39,14 +39,36 @@ func getControlFunc(ctx context.Context, sockopt *SocketConfig, controllers []co
 }
 
 func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *SocketConfig) (net.Listener, error) {
+	if socket := takeActivatedSocket(ctx, addr, false); socket != nil {
+		newError("listening on activated socket ", socket.addr(), " for ", addr).WriteToLog(session.ExportIDToError(ctx))
+		return socket.listener, nil
+	}
+	if addr, ok := addr.(*net.UnixAddr); ok {
+		return listenUnix(ctx, addr, sockopt)
+	}
+
 	var lc net.ListenConfig
 
 	lc.Control = getControlFunc(ctx, sockopt, dl.controllers)
+	if sockopt != nil && sockopt.TcpKeepAlive != nil {
+		// Accepted connections inherit keep-alive from the socket of the listener.
+		lc.KeepAlive = -1
+	}
+	if sockopt != nil && sockopt.Mptcp {
+		if err := setListenerMultipathTCP(&lc); err != nil {
+			newError("failed to enable MPTCP").Base(err).WriteToLog(session.ExportIDToError(ctx))
+		}
+	}
 
 	return lc.Listen(ctx, addr.Network(), addr.String())
 }
 
 func (dl *DefaultListener) ListenPacket(ctx context.Context, addr net.Addr, sockopt *SocketConfig) (net.PacketConn, error) {
+	if socket := takeActivatedSocket(ctx, addr, true); socket != nil {
+		newError("listening on activated socket ", socket.addr(), " for ", addr).WriteToLog(session.ExportIDToError(ctx))
+		return socket.conn, nil
+	}
+
 	var lc net.ListenConfig
 
 	lc.Control = getControlFunc(ctx, sockopt, dl.controllers)
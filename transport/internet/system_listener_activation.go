@@ -0,0 +1,201 @@
+package internet
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+)
+
+// The first file descriptor passed by socket activation. See sd_listen_fds(3).
+const listenFdsStart = 3
+
+// activatedSocket is a socket passed in by systemd socket activation. It is kept open for the lifetime of the process,
+// and each listener works on a duplicate of it, so that the socket is used again after a reload.
+type activatedSocket struct {
+	name     string
+	listener net.Listener
+	conn     net.PacketConn
+
+	// The duplicate that is handed out last.
+	duplicate syscall.Conn
+}
+
+func (s *activatedSocket) addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return s.conn.LocalAddr()
+}
+
+var activatedSockets struct {
+	sync.Mutex
+	once    sync.Once
+	sockets []*activatedSocket
+}
+
+// loadActivatedSockets reads sockets passed in by systemd, as sd_listen_fds(3) does.
+// The environment variables are unset afterwards, so that they are not inherited by child processes.
+func loadActivatedSockets() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	for i, name := range listenFdNames() {
+		fd := listenFdsStart + i
+		socket, err := newActivatedSocket(os.NewFile(uintptr(fd), name), name)
+		if err != nil {
+			newError("failed to use activated socket ", fd, " (", name, ")").Base(err).AtWarning().WriteToLog()
+			continue
+		}
+		newError("activated socket ", fd, " (", name, ") on ", socket.addr()).AtInfo().WriteToLog()
+		activatedSockets.sockets = append(activatedSockets.sockets, socket)
+	}
+}
+
+// listenFdNames returns the names of the sockets passed in to this process, one for each socket, or nil if there is
+// none. Sockets without a name in LISTEN_FDNAMES have an empty one.
+func listenFdNames() []string {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds <= 0 {
+		return nil
+	}
+	names := make([]string, nfds)
+	if env := os.Getenv("LISTEN_FDNAMES"); len(env) > 0 {
+		copy(names, strings.Split(env, ":"))
+	}
+	return names
+}
+
+// newActivatedSocket returns the socket of file, which is closed afterwards.
+func newActivatedSocket(file *os.File, name string) (*activatedSocket, error) {
+	// FileListener and FilePacketConn work on a duplicate of the file descriptor.
+	defer file.Close()
+
+	socket := &activatedSocket{name: name}
+	if l, err := net.FileListener(file); err == nil {
+		socket.listener = l
+	} else if c, err := net.FilePacketConn(file); err == nil {
+		socket.conn = c
+	} else {
+		return nil, err
+	}
+	return socket, nil
+}
+
+// inUse returns whether the socket has a duplicate that is not closed yet.
+func (s *activatedSocket) inUse() bool {
+	if s.duplicate == nil {
+		return false
+	}
+	rawConn, err := s.duplicate.SyscallConn()
+	if err != nil {
+		return false
+	}
+	// Control fails once the duplicate is closed.
+	return rawConn.Control(func(uintptr) {}) == nil
+}
+
+// dup returns a new activatedSocket on a duplicate of the file descriptor of s.
+func (s *activatedSocket) dup() (*activatedSocket, error) {
+	var source interface{} = s.listener
+	if s.listener == nil {
+		source = s.conn
+	}
+	filer, ok := source.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, newError("activated socket ", s.name, " can't be duplicated")
+	}
+
+	file, err := filer.File()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	socket := &activatedSocket{name: s.name}
+	if s.listener != nil {
+		socket.listener, err = net.FileListener(file)
+	} else {
+		socket.conn, err = net.FilePacketConn(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return socket, nil
+}
+
+func splitAddr(addr net.Addr) (net.IP, int) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP, a.Port
+	case *net.UDPAddr:
+		return a.IP, a.Port
+	default:
+		return nil, 0
+	}
+}
+
+func matchAddr(socketAddr net.Addr, addr net.Addr) bool {
+	socketIP, socketPort := splitAddr(socketAddr)
+	ip, port := splitAddr(addr)
+	if socketPort != port {
+		return false
+	}
+	return len(ip) == 0 || ip.IsUnspecified() || socketIP.Equal(ip)
+}
+
+// takeActivatedSocket returns a socket passed in by systemd for the given address, or nil if there is none.
+// A socket whose name (FileDescriptorName= in systemd.socket) equals to the tag of the inbound is preferred.
+// Otherwise the socket is matched by port. Each socket is used by one listener at a time.
+func takeActivatedSocket(ctx context.Context, addr net.Addr, isPacket bool) *activatedSocket {
+	activatedSockets.once.Do(loadActivatedSockets)
+
+	activatedSockets.Lock()
+	defer activatedSockets.Unlock()
+
+	// Sockets with random ports, such as the ones for outgoing UDP, are never activated.
+	if _, port := splitAddr(addr); port == 0 || len(activatedSockets.sockets) == 0 {
+		return nil
+	}
+
+	take := func(socket *activatedSocket) *activatedSocket {
+		dup, err := socket.dup()
+		if err != nil {
+			newError("failed to duplicate activated socket on ", socket.addr()).Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+			return nil
+		}
+		var handed interface{} = dup.listener
+		if dup.listener == nil {
+			handed = dup.conn
+		}
+		socket.duplicate, _ = handed.(syscall.Conn)
+		return dup
+	}
+
+	if inbound := session.InboundFromContext(ctx); inbound != nil && len(inbound.Tag) > 0 {
+		for _, socket := range activatedSockets.sockets {
+			if socket.name == inbound.Tag && (socket.conn != nil) == isPacket && !socket.inUse() {
+				return take(socket)
+			}
+		}
+	}
+
+	for _, socket := range activatedSockets.sockets {
+		if (socket.conn != nil) == isPacket && matchAddr(socket.addr(), addr) && !socket.inUse() {
+			return take(socket)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package internet
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/common/session"
+)
+
+// setEnv sets the environment variable for the duration of the test, or unsets it if value is empty.
+func setEnv(t *testing.T, key string, value string) {
+	previous, found := os.LookupEnv(key)
+	if len(value) > 0 {
+		common.Must(os.Setenv(key, value))
+	} else {
+		common.Must(os.Unsetenv(key))
+	}
+	t.Cleanup(func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// setActivatedSockets replaces the activated sockets of the process for the duration of the test.
+func setActivatedSockets(t *testing.T, sockets ...*activatedSocket) {
+	activatedSockets.once.Do(func() {})
+	activatedSockets.Lock()
+	previous := activatedSockets.sockets
+	activatedSockets.sockets = sockets
+	activatedSockets.Unlock()
+
+	t.Cleanup(func() {
+		activatedSockets.Lock()
+		activatedSockets.sockets = previous
+		activatedSockets.Unlock()
+	})
+}
+
+func TestActivatedSocketReuse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sockets can't be duplicated")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	setActivatedSockets(t, &activatedSocket{listener: listener})
+
+	addr := listener.Addr()
+	first := takeActivatedSocket(context.Background(), addr, false)
+	if first == nil {
+		t.Fatal("activated socket is not used")
+	}
+	if second := takeActivatedSocket(context.Background(), addr, false); second != nil {
+		t.Fatal("activated socket is used twice")
+	}
+
+	// Listeners of a reloaded instance use the socket again, once the previous ones are closed.
+	common.Must(first.listener.Close())
+	reloaded := takeActivatedSocket(context.Background(), addr, false)
+	if reloaded == nil {
+		t.Fatal("activated socket is not used after close")
+	}
+	defer reloaded.listener.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", addr.String())
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := reloaded.listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestListenFdNames(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	cases := []struct {
+		pid   string
+		fds   string
+		names string
+		want  []string
+	}{
+		{pid: pid, fds: "2", names: "http:dns", want: []string{"http", "dns"}},
+		{pid: pid, fds: "2", want: []string{"", ""}},
+		{pid: pid, fds: "3", names: "http", want: []string{"http", "", ""}},
+		{pid: pid, fds: "1", names: "http:dns", want: []string{"http"}},
+		{pid: "1", fds: "2", names: "http:dns"},
+		{fds: "2", names: "http:dns"},
+		{pid: "self", fds: "2"},
+		{pid: pid, fds: "0"},
+		{pid: pid, fds: "two"},
+		{pid: pid},
+	}
+
+	for _, c := range cases {
+		setEnv(t, "LISTEN_PID", c.pid)
+		setEnv(t, "LISTEN_FDS", c.fds)
+		setEnv(t, "LISTEN_FDNAMES", c.names)
+		if r := cmp.Diff(listenFdNames(), c.want); r != "" {
+			t.Error(c.pid, " ", c.fds, " ", c.names, ": ", r)
+		}
+	}
+}
+
+func TestNewActivatedSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sockets can't be duplicated")
+	}
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer listener.Close()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer conn.Close()
+
+	listenerFile, err := listener.File()
+	common.Must(err)
+	socket, err := newActivatedSocket(listenerFile, "http")
+	common.Must(err)
+	if socket.listener == nil || socket.name != "http" || socket.addr().String() != listener.Addr().String() {
+		t.Error("unexpected socket of listener: ", socket.name, " ", socket.addr())
+	}
+	socket.listener.Close()
+
+	connFile, err := conn.File()
+	common.Must(err)
+	socket, err = newActivatedSocket(connFile, "dns")
+	common.Must(err)
+	if socket.conn == nil || socket.name != "dns" || socket.addr().String() != conn.LocalAddr().String() {
+		t.Error("unexpected socket of packet conn: ", socket.name, " ", socket.addr())
+	}
+	socket.conn.Close()
+
+	reader, writer, err := os.Pipe()
+	common.Must(err)
+	defer writer.Close()
+	if _, err := newActivatedSocket(reader, "pipe"); err == nil {
+		t.Error("pipe is used as a socket")
+	}
+}
+
+func TestTakeActivatedSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sockets can't be duplicated")
+	}
+
+	named, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer named.Close()
+	unnamed, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer unnamed.Close()
+	packet, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer packet.Close()
+
+	setActivatedSockets(t,
+		&activatedSocket{name: "http", listener: named},
+		&activatedSocket{listener: unnamed},
+		&activatedSocket{name: "dns", conn: packet},
+	)
+
+	// Addresses on the port of a socket.
+	tcpAddr := func(ip net.IP, socket net.Addr) net.Addr {
+		_, port := splitAddr(socket)
+		return &net.TCPAddr{IP: ip, Port: port}
+	}
+	udpAddr := func(ip net.IP, socket net.Addr) net.Addr {
+		_, port := splitAddr(socket)
+		return &net.UDPAddr{IP: ip, Port: port}
+	}
+	localhost := net.LocalHostIP.IP()
+	unspecified := net.AnyIP.IP()
+
+	cases := []struct {
+		tag      string
+		addr     net.Addr
+		isPacket bool
+		want     net.Addr
+	}{
+		// The name is preferred to the address.
+		{tag: "http", addr: tcpAddr(localhost, unnamed.Addr()), want: named.Addr()},
+		{tag: "socks", addr: tcpAddr(localhost, unnamed.Addr()), want: unnamed.Addr()},
+		{addr: tcpAddr(unspecified, unnamed.Addr()), want: unnamed.Addr()},
+		{addr: tcpAddr(net.ParseIP("10.0.0.1"), unnamed.Addr())},
+		// A socket of the other kind is never used, even with the same name.
+		{tag: "dns", addr: tcpAddr(localhost, named.Addr()), want: named.Addr()},
+		{tag: "dns", addr: tcpAddr(localhost, packet.LocalAddr())},
+		{tag: "dns", addr: udpAddr(localhost, named.Addr()), isPacket: true, want: packet.LocalAddr()},
+		{addr: udpAddr(localhost, packet.LocalAddr()), isPacket: true, want: packet.LocalAddr()},
+		{addr: udpAddr(localhost, unnamed.Addr()), isPacket: true},
+		// Sockets with random ports are never activated.
+		{tag: "http", addr: &net.TCPAddr{IP: localhost}},
+	}
+
+	for _, c := range cases {
+		ctx := context.Background()
+		if len(c.tag) > 0 {
+			ctx = session.ContextWithInbound(ctx, &session.Inbound{Tag: c.tag})
+		}
+		socket := takeActivatedSocket(ctx, c.addr, c.isPacket)
+		if c.want == nil {
+			if socket != nil {
+				t.Error("activated socket on ", socket.addr(), " is used for ", c.tag, " ", c.addr)
+				closeActivatedSocket(socket)
+			}
+			continue
+		}
+		if socket == nil {
+			t.Error("no activated socket for ", c.tag, " ", c.addr)
+			continue
+		}
+		if socket.addr().String() != c.want.String() {
+			t.Error("activated socket on ", socket.addr(), " is used for ", c.tag, " ", c.addr, ", expected ", c.want)
+		}
+		closeActivatedSocket(socket)
+	}
+}
+
+func closeActivatedSocket(socket *activatedSocket) {
+	if socket.listener != nil {
+		socket.listener.Close()
+	} else {
+		socket.conn.Close()
+	}
+}
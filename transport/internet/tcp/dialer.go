@@ -20,6 +20,11 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		return nil, err
 	}
 
+	tcpSettings := streamSettings.ProtocolSettings.(*Config)
+	if tcpSettings.Fragment != nil {
+		conn = newFragmentConn(conn, tcpSettings.Fragment)
+	}
+
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
 		/*
@@ -32,7 +37,6 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		conn = tls.Client(conn, tlsConfig)
 	}
 
-	tcpSettings := streamSettings.ProtocolSettings.(*Config)
 	if tcpSettings.HeaderSettings != nil {
 		headerConfig, err := tcpSettings.HeaderSettings.GetInstance()
 		if err != nil {
@@ -0,0 +1,91 @@
+// +build !confonly
+
+package tcp
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/net"
+)
+
+const (
+	recordTypeHandshake   = 0x16
+	defaultFragmentLength = 32
+)
+
+func (f *Fragment) length() int {
+	min, max := int(f.MinLength), int(f.MaxLength)
+	if min == 0 {
+		min = 1
+	}
+	if max == 0 {
+		max = defaultFragmentLength
+	}
+	if max <= min {
+		return min
+	}
+	return min + dice.Roll(max-min+1)
+}
+
+func (f *Fragment) interval() time.Duration {
+	min, max := int(f.MinInterval), int(f.MaxInterval)
+	if max > min {
+		min += dice.Roll(max - min + 1)
+	}
+	return time.Duration(min) * time.Millisecond
+}
+
+// fragmentConn writes the first TLS record, usually a ClientHello, in fragments, so that the server name in it is
+// not found in any single TCP segment. It relies on TCP_NODELAY, which is on by default. Other writes go through as is.
+type fragmentConn struct {
+	net.Conn
+	config *Fragment
+	once   sync.Once
+}
+
+func newFragmentConn(conn net.Conn, config *Fragment) *fragmentConn {
+	return &fragmentConn{
+		Conn:   conn,
+		config: config,
+	}
+}
+
+func (c *fragmentConn) Write(b []byte) (int, error) {
+	first := false
+	c.once.Do(func() {
+		first = true
+	})
+	if !first || len(b) < 5 || b[0] != recordTypeHandshake {
+		return c.Conn.Write(b)
+	}
+
+	record := 5 + int(binary.BigEndian.Uint16(b[3:]))
+	if record > len(b) {
+		record = len(b)
+	}
+	written := 0
+	for written < record {
+		if written > 0 {
+			if interval := c.config.interval(); interval > 0 {
+				time.Sleep(interval)
+			}
+		}
+		end := written + c.config.length()
+		if end > record {
+			end = record
+		}
+		n, err := c.Conn.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	if written < len(b) {
+		n, err := c.Conn.Write(b[written:])
+		return written + n, err
+	}
+	return written, nil
+}
@@ -0,0 +1,68 @@
+package tcp
+
+import (
+	"bytes"
+	"testing"
+
+	"v2ray.com/core/common/net"
+)
+
+// recordingConn records the payload of each Write.
+type recordingConn struct {
+	net.Conn
+	writes [][]byte
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.writes = append(c.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestFragmentConn(t *testing.T) {
+	record := make([]byte, 5+200)
+	record[0] = recordTypeHandshake
+	record[4] = 200
+	for i := 5; i < len(record); i++ {
+		record[i] = byte(i)
+	}
+	extra := []byte("extra")
+
+	recorder := new(recordingConn)
+	conn := newFragmentConn(recorder, &Fragment{MinLength: 10, MaxLength: 20})
+	if n, err := conn.Write(append(append([]byte(nil), record...), extra...)); err != nil || n != len(record)+len(extra) {
+		t.Fatal("write: ", n, " ", err)
+	}
+	if _, err := conn.Write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := recorder.writes
+	if !bytes.Equal(writes[len(writes)-1], record) {
+		t.Error("later record fragmented")
+	}
+	if !bytes.Equal(writes[len(writes)-2], extra) {
+		t.Error("data after the first record: ", writes[len(writes)-2])
+	}
+	var fragments []byte
+	for i, w := range writes[:len(writes)-2] {
+		if len(w) > 20 || (len(w) < 10 && i != len(writes)-3) {
+			t.Error("fragment of ", len(w), " bytes")
+		}
+		fragments = append(fragments, w...)
+	}
+	if !bytes.Equal(fragments, record) {
+		t.Error("fragments don't make up the record")
+	}
+}
+
+func TestFragmentConnNonTLS(t *testing.T) {
+	recorder := new(recordingConn)
+	conn := newFragmentConn(recorder, &Fragment{})
+	payload := bytes.Repeat([]byte{'a'}, 100)
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.writes) != 1 {
+		t.Error("non-TLS data fragmented into ", len(recorder.writes), " writes")
+	}
+}
@@ -21,6 +21,7 @@ import (
 type Listener struct {
 	listener   net.Listener
 	tlsConfig  *gotls.Config
+	camouflage *tls.CamouflageServer
 	authConfig internet.ConnectionAuthenticator
 	config     *Config
 	addConn    internet.ConnHandler
@@ -28,10 +29,7 @@ type Listener struct {
 
 // ListenTCP creates a new Listener based on configurations.
 func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, handler internet.ConnHandler) (internet.Listener, error) {
-	listener, err := internet.ListenSystem(ctx, &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	}, streamSettings.SocketSettings)
+	listener, err := internet.ListenSystem(ctx, internet.StreamListenAddr(address, port), streamSettings.SocketSettings)
 	if err != nil {
 		return nil, newError("failed to listen TCP on", address, ":", port).Base(err)
 	}
@@ -58,6 +56,14 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.tlsConfig = config.GetTLSConfig(tls.WithNextProto("h2"))
+		if config.Camouflage != nil {
+			camouflage, err := tls.NewCamouflageServer(config.Camouflage, l.tlsConfig)
+			if err != nil {
+				listener.Close()
+				return nil, newError("invalid camouflage settings").Base(err).AtError()
+			}
+			l.camouflage = camouflage
+		}
 	}
 
 	if tcpSettings.HeaderSettings != nil {
@@ -91,6 +97,10 @@ func (v *Listener) keepAccepting() {
 			continue
 		}
 
+		if v.camouflage != nil {
+			go v.serveCamouflage(conn)
+			continue
+		}
 		if v.tlsConfig != nil {
 			conn = tls.Server(conn, v.tlsConfig)
 		}
@@ -102,6 +112,19 @@ func (v *Listener) keepAccepting() {
 	}
 }
 
+// serveCamouflage hands conn over if its client is authenticated. Other connections are relayed to the fronted site.
+func (v *Listener) serveCamouflage(conn net.Conn) {
+	conn, err := v.camouflage.Server(context.Background(), conn)
+	if err != nil {
+		newError("rejected camouflage connection").Base(err).AtInfo().WriteToLog()
+		return
+	}
+	if v.authConfig != nil {
+		conn = v.authConfig.Server(conn)
+	}
+	v.addConn(internet.Connection(conn))
+}
+
 // Addr implements internet.Listener.Addr.
 func (v *Listener) Addr() net.Addr {
 	return v.listener.Addr()
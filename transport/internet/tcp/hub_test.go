@@ -0,0 +1,59 @@
+package tcp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestAcceptProxyProtocol(t *testing.T) {
+	accepted := make(chan internet.Connection, 1)
+	listener, err := ListenTCP(context.Background(), net.LocalHostIP, 0, &internet.MemoryStreamConfig{
+		ProtocolName:     "tcp",
+		ProtocolSettings: &Config{AcceptProxyProtocol: true},
+	}, func(conn internet.Connection) {
+		accepted <- conn
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	for _, version := range []byte{1, 2} {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		common.Must(err)
+		header := &proxyproto.Header{
+			Version:            version,
+			Command:            proxyproto.PROXY,
+			TransportProtocol:  proxyproto.TCPv4,
+			SourceAddress:      net.IP{192, 0, 2, 1},
+			SourcePort:         12345,
+			DestinationAddress: net.IP{192, 0, 2, 2},
+			DestinationPort:    443,
+		}
+		_, err = header.WriteTo(conn)
+		common.Must(err)
+		_, err = conn.Write([]byte("data"))
+		common.Must(err)
+
+		select {
+		case server := <-accepted:
+			// The header is parsed on the first read.
+			b := make([]byte, 4)
+			_, err := server.Read(b)
+			common.Must(err)
+			if source := net.DestinationFromAddr(server.RemoteAddr()); source != net.TCPDestination(net.ParseAddress("192.0.2.1"), 12345) {
+				t.Error("version ", version, ": unexpected source ", source)
+			}
+			server.Close()
+		case <-time.After(time.Second * 5):
+			t.Fatal("version ", version, ": connection not accepted")
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,18 @@
+// +build openbsd
+// +build !confonly
+
+package tcp
+
+import (
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+// GetOriginalDestination returns the destination of a connection diverted by pf, which is the local address of the connection.
+func GetOriginalDestination(conn internet.Connection) (net.Destination, error) {
+	dest := net.DestinationFromAddr(conn.LocalAddr())
+	if !dest.IsValid() {
+		return net.Destination{}, newError("failed to parse destination.")
+	}
+	return dest, nil
+}
@@ -1,4 +1,4 @@
-// +build !linux,!freebsd
+// +build !linux,!freebsd,!openbsd,!windows
 // +build !confonly
 
 package tcp
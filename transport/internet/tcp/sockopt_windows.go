@@ -0,0 +1,22 @@
+// +build windows
+// +build !confonly
+
+package tcp
+
+import (
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+// GetOriginalDestination returns the destination of a connection redirected by a WFP callout. The callout is expected
+// to store the original remote address as a SOCKADDR_STORAGE in the redirect context of the connection.
+//
+// V2Ray neither installs the filters nor the callout, which runs in a kernel-mode driver. They must be provided
+// separately, along with the selection of the processes and ports to redirect.
+func GetOriginalDestination(conn internet.Connection) (net.Destination, error) {
+	b, err := internet.RedirectContext(conn)
+	if err != nil {
+		return net.Destination{}, err
+	}
+	return parseRedirectContext(b)
+}
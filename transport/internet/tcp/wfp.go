@@ -0,0 +1,38 @@
+// +build !confonly
+
+package tcp
+
+import (
+	"encoding/binary"
+
+	"v2ray.com/core/common/net"
+)
+
+// Address families of SOCKADDR_STORAGE on Windows, from ws2def.h.
+const (
+	wfpAFInet  = 2
+	wfpAFInet6 = 23
+)
+
+// parseRedirectContext returns the destination in the redirect context of a WFP callout, which is a SOCKADDR_STORAGE.
+func parseRedirectContext(b []byte) (net.Destination, error) {
+	if len(b) < 2 {
+		return net.Destination{}, newError("connection is not redirected")
+	}
+
+	var dest net.Destination
+	switch binary.LittleEndian.Uint16(b) {
+	case wfpAFInet:
+		if len(b) >= 8 {
+			dest = net.TCPDestination(net.IPAddress(b[4:8]), net.PortFromBytes(b[2:4]))
+		}
+	case wfpAFInet6:
+		if len(b) >= 24 {
+			dest = net.TCPDestination(net.IPAddress(b[8:24]), net.PortFromBytes(b[2:4]))
+		}
+	}
+	if !dest.IsValid() {
+		return net.Destination{}, newError("invalid redirect context")
+	}
+	return dest, nil
+}
@@ -0,0 +1,57 @@
+package tcp
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/net"
+)
+
+func TestParseRedirectContext(t *testing.T) {
+	ipv6 := []byte{23, 0, 0x01, 0xbb, 0, 0, 0, 0, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+
+	cases := []struct {
+		input  []byte
+		output net.Destination
+		err    bool
+	}{
+		{
+			input:  []byte{2, 0, 0x00, 0x50, 1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0},
+			output: net.TCPDestination(net.IPAddress([]byte{1, 2, 3, 4}), 80),
+		},
+		{
+			input:  ipv6,
+			output: net.TCPDestination(net.ParseAddress("2001:db8::1"), 443),
+		},
+		{
+			input: nil,
+			err:   true,
+		},
+		{
+			// Truncated SOCKADDR_IN6.
+			input: ipv6[:20],
+			err:   true,
+		},
+		{
+			// AF_INET6 on Linux is not a family on Windows.
+			input: []byte{10, 0, 0x00, 0x50, 1, 2, 3, 4},
+			err:   true,
+		},
+	}
+
+	for _, c := range cases {
+		dest, err := parseRedirectContext(c.input)
+		if c.err {
+			if err == nil {
+				t.Error("expected error for ", c.input, ", but got ", dest)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if dest != c.output {
+			t.Error("expected ", c.output, ", but got ", dest)
+		}
+	}
+}
@@ -40,7 +40,7 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, settings
 		address = net.LocalHostIP
 	}
 
-	if address.Family().IsDomain() {
+	if address.Family().IsDomain() && !IsUnixSocketPath(address) {
 		return nil, newError("domain address is not allowed for listening: ", address.Domain())
 	}
 
@@ -0,0 +1,373 @@
+// +build !confonly
+
+package tls
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/buf"
+	"v2ray.com/core/common/net"
+	tls_proto "v2ray.com/core/common/protocol/tls"
+	"v2ray.com/core/common/session"
+	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/task"
+	"v2ray.com/core/transport/internet"
+)
+
+// A credential takes the place of the random of a ClientHello. It is a random nonce of 16 bytes, followed by the time
+// of the client and a tag, which are masked to look random as well.
+const (
+	credentialSize      = 32
+	credentialNonceSize = 16
+	credentialTagSize   = 8
+
+	defaultMaxTimeDiff       = 120
+	clientHelloTimeout       = time.Second * 10
+	camouflageRelayIdleLimit = time.Minute * 5
+)
+
+func (c *Camouflage) maxTimeDiff() int64 {
+	if c.MaxTimeDiff == 0 {
+		return defaultMaxTimeDiff
+	}
+	return int64(c.MaxTimeDiff)
+}
+
+func credentialHMAC(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, d := range data {
+		common.Must2(h.Write(d))
+	}
+	return h.Sum(nil)
+}
+
+// putCredential fills b with a credential for serverName at the given time.
+func putCredential(b []byte, key []byte, serverName string, now time.Time) {
+	nonce := b[:credentialNonceSize]
+	common.Must2(rand.Read(nonce))
+
+	body := make([]byte, credentialSize-credentialNonceSize)
+	binary.BigEndian.PutUint64(body, uint64(now.Unix()))
+	copy(body[8:], credentialHMAC(key, nonce, body[:8], []byte(serverName))[:credentialTagSize])
+
+	mask := credentialHMAC(key, nonce)
+	for i := range body {
+		b[credentialNonceSize+i] = body[i] ^ mask[i]
+	}
+}
+
+// verifyCredential returns the time in the credential, or false if the credential is not valid for serverName.
+func verifyCredential(b []byte, key []byte, serverName string) (int64, bool) {
+	if len(b) != credentialSize {
+		return 0, false
+	}
+	nonce := b[:credentialNonceSize]
+	mask := credentialHMAC(key, nonce)
+	body := make([]byte, credentialSize-credentialNonceSize)
+	for i := range body {
+		body[i] = b[credentialNonceSize+i] ^ mask[i]
+	}
+	tag := credentialHMAC(key, nonce, body[:8], []byte(serverName))[:credentialTagSize]
+	if !hmac.Equal(tag, body[8:]) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(body)), true
+}
+
+// credentialReader is the Rand of a client tls.Config with camouflage. Its first read, which crypto/tls uses for the
+// random of the ClientHello, returns a credential. Later reads are random. So the tls.Config must not be reused for
+// another connection.
+type credentialReader struct {
+	sync.Mutex
+	key        []byte
+	serverName string
+	issued     bool
+}
+
+func (r *credentialReader) Read(b []byte) (int, error) {
+	r.Lock()
+	issued := r.issued
+	r.issued = true
+	r.Unlock()
+
+	if issued || len(b) != credentialSize {
+		return rand.Read(b)
+	}
+	putCredential(b, r.key, r.serverName, time.Now())
+	return len(b), nil
+}
+
+// certificateSignature returns the signature that replaces the one of a camouflage certificate. It is the HMAC of
+// the public key, which only the owners of the key can produce.
+func certificateSignature(key []byte, pub ed25519.PublicKey) []byte {
+	h := hmac.New(sha512.New, key)
+	common.Must2(h.Write(pub))
+	return h.Sum(nil)
+}
+
+// camouflageCertificate creates a certificate for serverName, which clients verify with certificateSignature.
+func camouflageCertificate(key []byte, serverName string) (tls.Certificate, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: serverName},
+		DNSNames:     []string{serverName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour * 24 * 365),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	// The signature is at the end of the certificate.
+	copy(der[len(der)-ed25519.SignatureSize:], certificateSignature(key, pub))
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  priv,
+	}, nil
+}
+
+func verifyCamouflageCertificate(key []byte) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return newError("no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return newError("invalid certificate").Base(err)
+		}
+		pub, ok := cert.PublicKey.(ed25519.PublicKey)
+		if !ok || !hmac.Equal(cert.Signature, certificateSignature(key, pub)) {
+			return newError("certificate is not authenticated by the camouflage key")
+		}
+		return nil
+	}
+}
+
+// applyClient sets up a client tls.Config to present a credential, and to verify the server with the key.
+func (c *Camouflage) applyClient(config *tls.Config) {
+	config.Rand = &credentialReader{
+		key:        c.Key,
+		serverName: config.ServerName,
+	}
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = verifyCamouflageCertificate(c.Key)
+	// The certificate is only encrypted in TLS 1.3.
+	config.MinVersion = tls.VersionTLS13
+	// Resumed sessions would present no credential.
+	config.ClientSessionCache = nil
+}
+
+// replayFilter rejects credentials that have been seen within their lifetime.
+type replayFilter struct {
+	sync.Mutex
+	seen      map[[credentialNonceSize]byte]int64
+	lastPrune int64
+}
+
+// check returns false if nonce has been seen. Nonces are remembered until expire.
+func (f *replayFilter) check(nonce []byte, now int64, expire int64) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.seen == nil {
+		f.seen = make(map[[credentialNonceSize]byte]int64)
+	}
+	if now-f.lastPrune > expire-now {
+		for k, e := range f.seen {
+			if e < now {
+				delete(f.seen, k)
+			}
+		}
+		f.lastPrune = now
+	}
+
+	var key [credentialNonceSize]byte
+	copy(key[:], nonce)
+	if e, found := f.seen[key]; found && e >= now {
+		return false
+	}
+	f.seen[key] = expire
+	return true
+}
+
+// CamouflageServer accepts TLS connections from clients with valid credentials, and relays the other connections to
+// the fronted site.
+type CamouflageServer struct {
+	config    *Camouflage
+	tlsConfig *tls.Config
+	dest      net.Destination
+	replay    replayFilter
+}
+
+// NewCamouflageServer creates a CamouflageServer, which serves clients with a copy of tlsConfig.
+func NewCamouflageServer(config *Camouflage, tlsConfig *tls.Config) (*CamouflageServer, error) {
+	if len(config.Key) != 32 {
+		return nil, newError("invalid camouflage key of ", len(config.Key), " bytes")
+	}
+	dest, err := net.ParseDestination("tcp:" + config.Dest)
+	if err != nil {
+		return nil, newError("invalid camouflage destination ", config.Dest).Base(err)
+	}
+	serverName := dest.Address.String()
+	if len(config.ServerNames) > 0 {
+		serverName = config.ServerNames[0]
+	}
+	certificate, err := camouflageCertificate(config.Key, serverName)
+	if err != nil {
+		return nil, newError("failed to create camouflage certificate").Base(err)
+	}
+
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+	tlsConfig.NameToCertificate = nil // nolint: staticcheck
+	tlsConfig.GetCertificate = nil
+	tlsConfig.MinVersion = tls.VersionTLS13
+	return &CamouflageServer{
+		config:    config,
+		tlsConfig: tlsConfig,
+		dest:      dest,
+	}, nil
+}
+
+func (s *CamouflageServer) isServerNameAllowed(serverName string) bool {
+	if len(s.config.ServerNames) == 0 {
+		return true
+	}
+	for _, name := range s.config.ServerNames {
+		if name == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns true if the ClientHello in b carries a valid credential.
+func (s *CamouflageServer) authenticate(b []byte, serverName string) bool {
+	// The random follows the record header (5 bytes), the handshake header (4 bytes) and the version (2 bytes).
+	const randomOffset = 11
+	if len(b) < randomOffset+credentialSize || int(binary.BigEndian.Uint16(b[3:]))+5 < randomOffset+credentialSize {
+		return false
+	}
+	if !s.isServerNameAllowed(serverName) {
+		return false
+	}
+	random := b[randomOffset : randomOffset+credentialSize]
+	t, ok := verifyCredential(random, s.config.Key, serverName)
+	if !ok {
+		return false
+	}
+	now := time.Now().Unix()
+	diff := s.config.maxTimeDiff()
+	if t < now-diff || t > now+diff {
+		return false
+	}
+	return s.replay.check(random[:credentialNonceSize], now, t+diff)
+}
+
+// readClientHello reads from conn until the end of the ClientHello, and returns the bytes read and the server name.
+func readClientHello(conn net.Conn) ([]byte, string, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(clientHelloTimeout)); err != nil {
+		return nil, "", err
+	}
+	defer conn.SetReadDeadline(time.Time{}) // nolint: errcheck
+
+	b := make([]byte, 0, buf.Size)
+	for {
+		if len(b) == cap(b) {
+			if len(b) >= 64*1024 {
+				return b, "", newError("ClientHello too large")
+			}
+			b = append(b, make([]byte, len(b))...)[:len(b)]
+		}
+		n, err := conn.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if err != nil {
+			return b, "", err
+		}
+		header, err := tls_proto.SniffTLS(b)
+		if err == common.ErrNoClue {
+			continue
+		}
+		if err != nil {
+			return b, "", err
+		}
+		return b, header.Domain(), nil
+	}
+}
+
+// Server reads the ClientHello from conn. If it carries a valid credential, Server returns a TLS connection on conn.
+// Otherwise Server relays conn to the fronted site until either side closes it, and returns an error.
+func (s *CamouflageServer) Server(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	head, serverName, err := readClientHello(conn)
+	if err == nil && s.authenticate(head, serverName) {
+		return Server(&peekedConn{Conn: conn, head: head}, s.tlsConfig), nil
+	}
+
+	defer conn.Close()
+	s.relay(ctx, conn, head)
+	if err != nil {
+		return nil, newError("relayed invalid ClientHello to ", s.dest).Base(err)
+	}
+	return nil, newError("relayed unauthenticated connection to ", s.dest)
+}
+
+func (s *CamouflageServer) relay(ctx context.Context, conn net.Conn, head []byte) {
+	target, err := internet.DialSystem(ctx, s.dest, nil)
+	if err != nil {
+		newError("failed to dial camouflage destination ", s.dest).Base(err).WriteToLog(session.ExportIDToError(ctx))
+		return
+	}
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, camouflageRelayIdleLimit)
+
+	requestDone := func() error {
+		if _, err := target.Write(head); err != nil {
+			return err
+		}
+		return buf.Copy(buf.NewReader(conn), buf.NewWriter(target), buf.UpdateActivity(timer))
+	}
+	responseDone := func() error {
+		return buf.Copy(buf.NewReader(target), buf.NewWriter(conn), buf.UpdateActivity(timer))
+	}
+	if err := task.Run(ctx, requestDone, responseDone); err != nil {
+		newError("camouflage relay ends").Base(err).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+	}
+}
+
+// peekedConn is a connection, whose bytes already read are read again first.
+type peekedConn struct {
+	net.Conn
+	head []byte
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	if len(c.head) > 0 {
+		n := copy(b, c.head)
+		c.head = c.head[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
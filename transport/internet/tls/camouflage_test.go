@@ -0,0 +1,112 @@
+package tls_test
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/protocol/tls/cert"
+	. "v2ray.com/core/transport/internet/tls"
+)
+
+// serveText accepts connections on listener, and writes text to each after handshake.
+func serveText(listener net.Listener, text string, handshake func(net.Conn) (net.Conn, error)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			c, err := handshake(conn)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.Write([]byte(text))
+		}()
+	}
+}
+
+func readText(addr string, config *gotls.Config) (string, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	b, err := ioutil.ReadAll(gotls.Client(conn, config))
+	return string(b), err
+}
+
+func TestCamouflage(t *testing.T) {
+	siteConfig := &Config{
+		Certificate: []*Certificate{ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.example.com")))},
+	}
+	site, err := gotls.Listen("tcp", "127.0.0.1:0", siteConfig.GetTLSConfig())
+	common.Must(err)
+	defer site.Close()
+	go serveText(site, "site", func(conn net.Conn) (net.Conn, error) {
+		return conn, nil
+	})
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	camouflage := &Camouflage{
+		Key:         key,
+		Dest:        site.Addr().String(),
+		ServerNames: []string{"www.example.com"},
+	}
+	server, err := NewCamouflageServer(camouflage, (&Config{}).GetTLSConfig())
+	common.Must(err)
+
+	front, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer front.Close()
+	go serveText(front, "proxy", func(conn net.Conn) (net.Conn, error) {
+		return server.Server(context.Background(), conn)
+	})
+
+	clientConfig := &Config{
+		ServerName: "www.example.com",
+		Camouflage: camouflage,
+	}
+	if text, err := readText(front.Addr().String(), clientConfig.GetTLSConfig()); err != nil || text != "proxy" {
+		t.Error("client with key: ", text, " ", err)
+	}
+
+	// The credential is only used once.
+	config := clientConfig.GetTLSConfig()
+	if text, err := readText(front.Addr().String(), config); err != nil || text != "proxy" {
+		t.Error("client with key: ", text, " ", err)
+	}
+	if text, err := readText(front.Addr().String(), config); err == nil {
+		t.Error("client with used credential: ", text)
+	}
+
+	if text, err := readText(front.Addr().String(), &gotls.Config{
+		ServerName:         "www.example.com",
+		InsecureSkipVerify: true,
+	}); err != nil || text != "site" {
+		t.Error("client without key: ", text, " ", err)
+	}
+
+	wrongKey := make([]byte, 32)
+	if text, err := readText(front.Addr().String(), (&Config{
+		ServerName: "www.example.com",
+		Camouflage: &Camouflage{Key: wrongKey},
+	}).GetTLSConfig()); err == nil {
+		t.Error("client with wrong key: ", text)
+	}
+
+	if text, err := readText(front.Addr().String(), (&Config{
+		ServerName: "www.example.org",
+		Camouflage: camouflage,
+	}).GetTLSConfig()); err == nil {
+		t.Error("client with unknown server name: ", text)
+	}
+}
@@ -3,6 +3,8 @@
 package tls
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"strings"
@@ -14,10 +16,6 @@ import (
 	"v2ray.com/core/transport/internet"
 )
 
-var (
-	globalSessionCache = tls.NewLRUClientSessionCache(128)
-)
-
 const exp8357 = "experiment:8357"
 
 // ParseCertificate converts a cert.Certificate to Certificate.
@@ -36,6 +34,9 @@ func (c *Config) loadSelfCertPool() (*x509.CertPool, error) {
 			return nil, newError("failed to append cert").AtWarning()
 		}
 	}
+	if len(c.RootCa) > 0 && !root.AppendCertsFromPEM(c.RootCa) {
+		return nil, newError("failed to append root CA").AtWarning()
+	}
 	return root, nil
 }
 
@@ -154,6 +155,23 @@ func getGetCertificateFunc(c *tls.Config, ca []*Certificate) func(hello *tls.Cli
 	}
 }
 
+// verifyPinnedCertificate returns a function for tls.Config.VerifyConnection, which accepts a connection only if one
+// of the peer certificates has one of the SHA-256 hashes. Unlike VerifyPeerCertificate, it also runs on resumed
+// sessions.
+func verifyPinnedCertificate(hashes [][]byte) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		for _, cert := range state.PeerCertificates {
+			hash := sha256.Sum256(cert.Raw)
+			for _, pinned := range hashes {
+				if bytes.Equal(hash[:], pinned) {
+					return nil
+				}
+			}
+		}
+		return newError("peer certificate is not pinned")
+	}
+}
+
 func (c *Config) IsExperiment8357() bool {
 	return strings.HasPrefix(c.ServerName, exp8357)
 }
@@ -174,7 +192,7 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 	}
 
 	config := &tls.Config{
-		ClientSessionCache:     globalSessionCache,
+		ClientSessionCache:     c.sessionCache(),
 		RootCAs:                root,
 		InsecureSkipVerify:     c.AllowInsecure,
 		NextProtos:             c.NextProtocol,
@@ -204,6 +222,20 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		config.NextProtos = []string{"h2", "http/1.1"}
 	}
 
+	c.applyECH(config)
+
+	if w := c.keyLogWriter(); w != nil {
+		config.KeyLogWriter = w
+	}
+
+	if len(c.PinnedPeerCertificateSha256) > 0 {
+		config.VerifyConnection = verifyPinnedCertificate(c.PinnedPeerCertificateSha256)
+	}
+
+	if c.Camouflage != nil {
+		c.Camouflage.applyClient(config)
+	}
+
 	return config
 }
 
@@ -32,7 +32,7 @@ func (c *rootCertsCache) load() (*x509.CertPool, error) {
 var rootCerts rootCertsCache
 
 func (c *Config) getCertPool() (*x509.CertPool, error) {
-	if c.DisableSystemRoot {
+	if c.DisableSystemRoot || len(c.RootCa) > 0 {
 		return c.loadSelfCertPool()
 	}
 
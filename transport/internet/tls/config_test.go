@@ -1,8 +1,14 @@
 package tls_test
 
 import (
+	"crypto/sha256"
 	gotls "crypto/tls"
 	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,6 +80,129 @@ func TestInsecureCertificates(t *testing.T) {
 	}
 }
 
+// listenTLS serves "ok" over TLS with a certificate for www.v2ray.com issued by caCert.
+func listenTLS(caCert *cert.Certificate) (net.Listener, *x509.Certificate) {
+	serverCert := cert.MustGenerate(caCert, cert.CommonName("www.v2ray.com"), cert.DNSNames("www.v2ray.com"))
+	listener, err := gotls.Listen("tcp", "127.0.0.1:0", (&Config{
+		Certificate: []*Certificate{ParseCertificate(serverCert)},
+	}).GetTLSConfig())
+	common.Must(err)
+	go serveText(listener, "ok", func(conn net.Conn) (net.Conn, error) {
+		return conn, nil
+	})
+	x509Cert, err := x509.ParseCertificate(serverCert.Certificate)
+	common.Must(err)
+	return listener, x509Cert
+}
+
+func TestPinnedPeerCertificate(t *testing.T) {
+	listener, serverCert := listenTLS(nil)
+	defer listener.Close()
+
+	hash := sha256.Sum256(serverCert.Raw)
+	if text, err := readText(listener.Addr().String(), (&Config{
+		ServerName:                  "www.v2ray.com",
+		AllowInsecure:               true,
+		PinnedPeerCertificateSha256: [][]byte{hash[:]},
+	}).GetTLSConfig()); err != nil || text != "ok" {
+		t.Error("pinned certificate: ", text, " ", err)
+	}
+
+	otherHash := sha256.Sum256([]byte("other"))
+	if text, err := readText(listener.Addr().String(), (&Config{
+		ServerName:                  "www.v2ray.com",
+		AllowInsecure:               true,
+		PinnedPeerCertificateSha256: [][]byte{otherHash[:]},
+	}).GetTLSConfig()); err == nil {
+		t.Error("certificate not pinned: ", text)
+	}
+}
+
+func TestRootCA(t *testing.T) {
+	caCert := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	listener, _ := listenTLS(caCert)
+	defer listener.Close()
+
+	if text, err := readText(listener.Addr().String(), (&Config{
+		ServerName: "www.v2ray.com",
+		RootCa:     ParseCertificate(caCert).Certificate,
+	}).GetTLSConfig()); err != nil || text != "ok" {
+		t.Error("trusted CA: ", text, " ", err)
+	}
+
+	otherCA := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	if text, err := readText(listener.Addr().String(), (&Config{
+		ServerName: "www.v2ray.com",
+		RootCa:     ParseCertificate(otherCA).Certificate,
+	}).GetTLSConfig()); err == nil {
+		t.Error("untrusted CA: ", text)
+	}
+}
+
+func TestSessionResumption(t *testing.T) {
+	caCert := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	listener, _ := listenTLS(caCert)
+	defer listener.Close()
+
+	dial := func(c *Config) (bool, error) {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		tlsConn := gotls.Client(conn, c.GetTLSConfig())
+		// Session tickets arrive after the handshake.
+		if _, err := ioutil.ReadAll(tlsConn); err != nil {
+			return false, err
+		}
+		return tlsConn.ConnectionState().DidResume, nil
+	}
+
+	trusted := &Config{
+		ServerName: "www.v2ray.com",
+		RootCa:     ParseCertificate(caCert).Certificate,
+	}
+	if _, err := dial(trusted); err != nil {
+		t.Fatal(err)
+	}
+	if resumed, err := dial(trusted); err != nil || !resumed {
+		t.Error("session not resumed: ", err)
+	}
+
+	// A session is not resumed by a config that trusts other certificates.
+	otherCA := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	if _, err := dial(&Config{
+		ServerName: "www.v2ray.com",
+		RootCa:     ParseCertificate(otherCA).Certificate,
+	}); err == nil {
+		t.Error("untrusted server accepted by resumption")
+	}
+}
+
+func TestKeyLogFile(t *testing.T) {
+	listener, _ := listenTLS(nil)
+	defer listener.Close()
+
+	dir, err := ioutil.TempDir("", "v2ray-keylog")
+	common.Must(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.log")
+
+	if text, err := readText(listener.Addr().String(), (&Config{
+		ServerName:    "www.v2ray.com",
+		AllowInsecure: true,
+		KeyLogFile:    path,
+	}).GetTLSConfig()); err != nil || text != "ok" {
+		t.Fatal("handshake: ", text, " ", err)
+	}
+
+	keys, err := ioutil.ReadFile(path)
+	common.Must(err)
+	if !strings.Contains(string(keys), "CLIENT_TRAFFIC_SECRET_0 ") {
+		t.Error("key log: ", string(keys))
+	}
+}
+
 func BenchmarkCertificateIssuing(b *testing.B) {
 	certificate := ParseCertificate(cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign)))
 	certificate.Usage = Certificate_AUTHORITY_ISSUE
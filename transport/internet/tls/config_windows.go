@@ -6,7 +6,7 @@ package tls
 import "crypto/x509"
 
 func (c *Config) getCertPool() (*x509.CertPool, error) {
-	if c.DisableSystemRoot {
+	if c.DisableSystemRoot || len(c.RootCa) > 0 {
 		return c.loadSelfCertPool()
 	}
 
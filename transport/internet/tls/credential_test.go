@@ -0,0 +1,41 @@
+package tls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCredential(t *testing.T) {
+	key := make([]byte, 32)
+	now := time.Now()
+
+	b := make([]byte, credentialSize)
+	putCredential(b, key, "www.example.com", now)
+	if ts, ok := verifyCredential(b, key, "www.example.com"); !ok || ts != now.Unix() {
+		t.Error("credential: ", ts, " ", ok)
+	}
+	if _, ok := verifyCredential(b, key, "www.example.org"); ok {
+		t.Error("credential accepted for another server name")
+	}
+	if _, ok := verifyCredential(b, []byte("another key"), "www.example.com"); ok {
+		t.Error("credential accepted with another key")
+	}
+	b[credentialSize-1] ^= 1
+	if _, ok := verifyCredential(b, key, "www.example.com"); ok {
+		t.Error("tampered credential accepted")
+	}
+}
+
+func TestReplayFilter(t *testing.T) {
+	var filter replayFilter
+	nonce := make([]byte, credentialNonceSize)
+	if !filter.check(nonce, 100, 200) {
+		t.Fatal("new nonce rejected")
+	}
+	if filter.check(nonce, 150, 250) {
+		t.Error("replayed nonce accepted")
+	}
+	if !filter.check(nonce, 201, 301) {
+		t.Error("nonce rejected after expiry")
+	}
+}
@@ -0,0 +1,38 @@
+// +build go1.24
+// +build !confonly
+
+package tls
+
+import (
+	"crypto/tls"
+)
+
+// applyECH sets up ECH in config. A client without an ECHConfigList fetches one from the DNS server if configured,
+// and connects without ECH if that fails.
+func (c *Config) applyECH(config *tls.Config) {
+	configList := c.EchConfigList
+	if len(configList) == 0 && len(c.EchDnsServer) > 0 && len(config.ServerName) > 0 {
+		fetched, err := echConfigCache.get(c.EchDnsServer, config.ServerName)
+		if err != nil {
+			newError("failed to fetch ECH config of ", config.ServerName).Base(err).AtWarning().WriteToLog()
+		}
+		configList = fetched
+	}
+	if len(configList) > 0 {
+		config.EncryptedClientHelloConfigList = configList
+		config.MinVersion = tls.VersionTLS13
+	}
+
+	if len(c.EchKey) > 0 {
+		keys := make([]tls.EncryptedClientHelloKey, 0, len(c.EchKey))
+		for _, key := range c.EchKey {
+			keys = append(keys, tls.EncryptedClientHelloKey{
+				Config:      key.Config,
+				PrivateKey:  key.PrivateKey,
+				SendAsRetry: true,
+			})
+		}
+		config.EncryptedClientHelloKeys = keys
+		config.MinVersion = tls.VersionTLS13
+	}
+}
@@ -0,0 +1,197 @@
+// +build !confonly
+
+package tls
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	typeHTTPS    = 65
+	svcParamECH  = 5
+	echQueryTime = time.Second * 5
+	minECHTTL    = 60
+)
+
+type echRecord struct {
+	configList []byte
+	expire     time.Time
+}
+
+// echCache caches the ECHConfigLists fetched from DNS servers, for the TTL of their records.
+type echCache struct {
+	sync.Mutex
+	records map[string]echRecord
+}
+
+var echConfigCache = echCache{
+	records: make(map[string]echRecord),
+}
+
+// get returns the ECHConfigList of domain, from the cache or the DNS server.
+func (c *echCache) get(server string, domain string) ([]byte, error) {
+	key := server + " " + domain
+	c.Lock()
+	record, found := c.records[key]
+	c.Unlock()
+	if found && time.Now().Before(record.expire) {
+		return record.configList, nil
+	}
+
+	configList, ttl, err := lookupECHConfigList(server, domain)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < minECHTTL {
+		ttl = minECHTTL
+	}
+
+	c.Lock()
+	c.records[key] = echRecord{
+		configList: configList,
+		expire:     time.Now().Add(time.Duration(ttl) * time.Second),
+	}
+	c.Unlock()
+	return configList, nil
+}
+
+// lookupECHConfigList queries the HTTPS record of domain from the DNS server over UDP.
+func lookupECHConfigList(server string, domain string) ([]byte, uint32, error) {
+	dest, err := net.ParseDestination("udp:" + server)
+	if err != nil {
+		return nil, 0, newError("invalid DNS server ", server).Base(err)
+	}
+	conn, err := internet.DialSystem(context.Background(), dest, nil)
+	if err != nil {
+		return nil, 0, newError("failed to dial DNS server ", server).Base(err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(echQueryTime)); err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.Uint32())
+	if _, err := conn.Write(buildHTTPSQuery(id, domain)); err != nil {
+		return nil, 0, newError("failed to query DNS server ", server).Base(err)
+	}
+	b := make([]byte, 4096)
+	n, err := conn.Read(b)
+	if err != nil {
+		return nil, 0, newError("failed to read from DNS server ", server).Base(err)
+	}
+	return parseHTTPSResponse(b[:n], id)
+}
+
+// buildHTTPSQuery builds a DNS query for the HTTPS record of domain, with an EDNS(0) OPT record for responses larger
+// than 512 bytes.
+func buildHTTPSQuery(id uint16, domain string) []byte {
+	b := make([]byte, 12, 12+len(domain)+2+4+11)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], 0x0100) // Recursion desired
+	binary.BigEndian.PutUint16(b[4:], 1)      // Questions
+	binary.BigEndian.PutUint16(b[10:], 1)     // Additional records
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	b = append(b, 0, 0, typeHTTPS, 0, 1)
+	// OPT record: root name, type 41, UDP payload size 1232, no extended flags or options.
+	b = append(b, 0, 0, 41, 0x04, 0xd0, 0, 0, 0, 0, 0, 0)
+	return b
+}
+
+func skipName(b []byte, off int) (int, error) {
+	for off < len(b) {
+		l := int(b[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+	return 0, newError("invalid name in DNS message")
+}
+
+// parseHTTPSResponse returns the ECHConfigList in the first HTTPS record of the response, and the TTL of the record.
+func parseHTTPSResponse(b []byte, id uint16) ([]byte, uint32, error) {
+	if len(b) < 12 || binary.BigEndian.Uint16(b) != id {
+		return nil, 0, newError("invalid DNS response")
+	}
+	if b[2]&0x02 != 0 {
+		return nil, 0, newError("DNS response truncated")
+	}
+	if rcode := b[3] & 0x0f; rcode != 0 {
+		return nil, 0, newError("DNS response code ", rcode)
+	}
+	questions := int(binary.BigEndian.Uint16(b[4:]))
+	answers := int(binary.BigEndian.Uint16(b[6:]))
+
+	off := 12
+	var err error
+	for i := 0; i < questions; i++ {
+		if off, err = skipName(b, off); err != nil {
+			return nil, 0, err
+		}
+		off += 4
+	}
+	for i := 0; i < answers; i++ {
+		if off, err = skipName(b, off); err != nil {
+			return nil, 0, err
+		}
+		if off+10 > len(b) {
+			return nil, 0, newError("invalid DNS answer")
+		}
+		rtype := binary.BigEndian.Uint16(b[off:])
+		ttl := binary.BigEndian.Uint32(b[off+4:])
+		length := int(binary.BigEndian.Uint16(b[off+8:]))
+		off += 10
+		if off+length > len(b) {
+			return nil, 0, newError("invalid DNS answer")
+		}
+		if rtype == typeHTTPS {
+			if configList := parseSVCBRecord(b[off : off+length]); configList != nil {
+				return configList, ttl, nil
+			}
+		}
+		off += length
+	}
+	return nil, 0, newError("no ECH config in DNS response")
+}
+
+// parseSVCBRecord returns the value of the ech parameter in the data of an SVCB or HTTPS record, or nil if there
+// isn't one.
+func parseSVCBRecord(b []byte) []byte {
+	// Records of priority 0 are aliases, without parameters.
+	if len(b) < 2 || binary.BigEndian.Uint16(b) == 0 {
+		return nil
+	}
+	// The target name is not compressed.
+	off, err := skipName(b, 2)
+	if err != nil {
+		return nil
+	}
+	for off+4 <= len(b) {
+		key := binary.BigEndian.Uint16(b[off:])
+		length := int(binary.BigEndian.Uint16(b[off+2:]))
+		off += 4
+		if off+length > len(b) {
+			return nil
+		}
+		if key == svcParamECH {
+			return b[off : off+length]
+		}
+		off += length
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// +build !go1.24
+// +build !confonly
+
+package tls
+
+import (
+	"crypto/tls"
+)
+
+func (c *Config) applyECH(config *tls.Config) {
+	if len(c.EchConfigList) > 0 || len(c.EchDnsServer) > 0 || len(c.EchKey) > 0 {
+		newError("ECH requires Go 1.24 or later").AtError().WriteToLog()
+	}
+}
@@ -0,0 +1,120 @@
+// +build go1.24
+
+package tls_test
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"net"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/protocol/tls/cert"
+	. "v2ray.com/core/transport/internet/tls"
+)
+
+func appendUint16(b []byte, v int) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// generateECHKey generates an ECHConfig of DHKEM(X25519, HKDF-SHA256), HKDF-SHA256 and AES-128-GCM.
+func generateECHKey(publicName string) *ECHKey {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	common.Must(err)
+
+	var contents []byte
+	contents = append(contents, 1)          // Config ID
+	contents = appendUint16(contents, 0x20) // KEM
+	contents = appendUint16(contents, len(key.PublicKey().Bytes()))
+	contents = append(contents, key.PublicKey().Bytes()...)
+	contents = appendUint16(contents, 4)
+	contents = appendUint16(contents, 1) // KDF
+	contents = appendUint16(contents, 1) // AEAD
+	contents = append(contents, 0)       // Maximum name length
+	contents = append(contents, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = appendUint16(contents, 0) // Extensions
+
+	config := appendUint16(nil, 0xfe0d)
+	config = appendUint16(config, len(contents))
+	config = append(config, contents...)
+	return &ECHKey{
+		Config:     config,
+		PrivateKey: key.Bytes(),
+	}
+}
+
+func echHandshake(t *testing.T, clientConfig *Config, serverConfig *Config) gotls.ConnectionState {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	client := Client(clientConn, clientConfig.GetTLSConfig()).(*Conn)
+	server := Server(serverConn, serverConfig.GetTLSConfig()).(*Conn)
+	go server.Handshake()
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	return client.ConnectionState()
+}
+
+// serveHTTPSRecord answers DNS queries on conn with an HTTPS record of the ECHConfigList.
+func serveHTTPSRecord(conn net.PacketConn, configList []byte) {
+	b := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		// The query is followed by an OPT record of 11 bytes.
+		question := b[12 : n-11]
+
+		response := append([]byte(nil), b[0], b[1], 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0)
+		response = append(response, question...)
+		var data []byte
+		data = appendUint16(data, 1) // Priority
+		data = append(data, 0)       // Target name
+		data = appendUint16(data, 5) // ech
+		data = appendUint16(data, len(configList))
+		data = append(data, configList...)
+		response = append(response, 0xc0, 12, 0, 65, 0, 1, 0, 0, 1, 0)
+		response = appendUint16(response, len(data))
+		response = append(response, data...)
+		conn.WriteTo(response, addr)
+	}
+}
+
+func TestECH(t *testing.T) {
+	key := generateECHKey("public.v2ray.com")
+	configList := appendUint16(nil, len(key.Config))
+	configList = append(configList, key.Config...)
+
+	serverConfig := &Config{
+		Certificate: []*Certificate{ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.v2ray.com", "public.v2ray.com")))},
+		EchKey:      []*ECHKey{key},
+	}
+
+	state := echHandshake(t, &Config{
+		ServerName:    "www.v2ray.com",
+		AllowInsecure: true,
+		EchConfigList: configList,
+	}, serverConfig)
+	if !state.ECHAccepted {
+		t.Error("ECH not accepted with static config")
+	}
+
+	dns, err := net.ListenPacket("udp", "127.0.0.1:0")
+	common.Must(err)
+	defer dns.Close()
+	go serveHTTPSRecord(dns, configList)
+
+	state = echHandshake(t, &Config{
+		ServerName:    "www.v2ray.com",
+		AllowInsecure: true,
+		EchDnsServer:  dns.LocalAddr().String(),
+	}, serverConfig)
+	if !state.ECHAccepted {
+		t.Error("ECH not accepted with config from DNS")
+	}
+}
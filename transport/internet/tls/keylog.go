@@ -0,0 +1,42 @@
+// +build !confonly
+
+package tls
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"v2ray.com/core/common/platform"
+)
+
+// keyLogFiles are the opened key log files by path, shared by all configs.
+var keyLogFiles = struct {
+	sync.Mutex
+	files map[string]*os.File
+}{
+	files: make(map[string]*os.File),
+}
+
+// keyLogWriter returns the writer of the key log file, or nil if key logging is disabled.
+func (c *Config) keyLogWriter() io.Writer {
+	path := platform.NewEnvFlag("v2ray.tls.keylogfile").GetValue(func() string { return c.KeyLogFile })
+	if path == "" {
+		return nil
+	}
+
+	keyLogFiles.Lock()
+	defer keyLogFiles.Unlock()
+
+	if file, found := keyLogFiles.files[path]; found {
+		return file
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		newError("failed to open TLS key log file ", path).Base(err).AtError().WriteToLog()
+		return nil
+	}
+	newError("TLS secrets are written to ", path, ". Connections are no longer secret to anyone reading it.").AtWarning().WriteToLog()
+	keyLogFiles.files[path] = file
+	return file
+}
@@ -0,0 +1,54 @@
+// +build !confonly
+
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+)
+
+var globalSessionCache = tls.NewLRUClientSessionCache(128)
+
+// sessionCache is a view of globalSessionCache, shared by all configs of the same verification settings. Sessions are
+// cached by server name, and resumed without verifying the server again. So a session verified under one config must
+// not be resumed under another config, which trusts different certificates.
+type sessionCache struct {
+	prefix string
+}
+
+// Get implements tls.ClientSessionCache.
+func (c *sessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	return globalSessionCache.Get(c.prefix + sessionKey)
+}
+
+// Put implements tls.ClientSessionCache.
+func (c *sessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	globalSessionCache.Put(c.prefix+sessionKey, cs)
+}
+
+func (c *Config) sessionCache() tls.ClientSessionCache {
+	if c == nil {
+		return globalSessionCache
+	}
+
+	h := sha256.New()
+	flags := []byte{0, 0}
+	if c.AllowInsecure {
+		flags[0] = 1
+	}
+	if c.DisableSystemRoot {
+		flags[1] = 1
+	}
+	h.Write(flags)
+	for _, cert := range c.Certificate {
+		h.Write(cert.Certificate)
+	}
+	h.Write(c.RootCa)
+	for _, hash := range c.PinnedPeerCertificateSha256 {
+		h.Write(hash)
+	}
+	return &sessionCache{
+		prefix: hex.EncodeToString(h.Sum(nil)[:8]) + ":",
+	}
+}
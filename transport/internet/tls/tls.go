@@ -37,6 +37,14 @@ func (c *Conn) HandshakeAddress() net.Address {
 	return net.ParseAddress(state.ServerName)
 }
 
+// NegotiatedProtocol returns the application protocol negotiated by ALPN, or empty if none is.
+func (c *Conn) NegotiatedProtocol() string {
+	if err := c.Handshake(); err != nil {
+		return ""
+	}
+	return c.ConnectionState().NegotiatedProtocol
+}
+
 // Client initiates a TLS client handshake on the given connection.
 func Client(c net.Conn, config *tls.Config) net.Conn {
 	tlsConn := tls.Client(c, config)
@@ -0,0 +1,38 @@
+package tls_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/common/protocol/tls/cert"
+	. "v2ray.com/core/transport/internet/tls"
+)
+
+func TestNegotiatedProtocol(t *testing.T) {
+	serverConfig := &Config{
+		Certificate:  []*Certificate{ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.v2ray.com")))},
+		NextProtocol: []string{"h2", "http/1.1"},
+	}
+	clientConfig := &Config{
+		ServerName:    "www.v2ray.com",
+		AllowInsecure: true,
+		NextProtocol:  []string{"http/1.1"},
+	}
+
+	clientConn, serverConn := net.Pipe()
+	client := Client(clientConn, clientConfig.GetTLSConfig()).(*Conn)
+	server := Server(serverConn, serverConfig.GetTLSConfig()).(*Conn)
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	done := make(chan string)
+	go func() {
+		done <- client.NegotiatedProtocol()
+	}()
+	if protocol := server.NegotiatedProtocol(); protocol != "http/1.1" {
+		t.Error("server protocol: ", protocol)
+	}
+	if protocol := <-done; protocol != "http/1.1" {
+		t.Error("client protocol: ", protocol)
+	}
+}
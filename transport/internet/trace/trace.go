@@ -0,0 +1,152 @@
+// Package trace records frame level events of transports and protocols for debugging.
+//
+// Events are written as JSON lines into a file. Only a sampled subset of connections is traced, and all layers of a
+// traced connection, for example mKCP segments and VMess chunks, share the same Session.
+package trace
+
+//go:generate errorgen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Direction of traced frames.
+const (
+	Send    = "send"
+	Receive = "recv"
+)
+
+// Event is a single line in the trace file.
+type Event struct {
+	Time       time.Time              `json:"time"`
+	Session    uint32                 `json:"session"`
+	Layer      string                 `json:"layer"`
+	Direction  string                 `json:"dir"`
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// Tracer writes events of sampled connections into a file.
+type Tracer struct {
+	path   string
+	sample uint32
+
+	connections uint32
+	sessions    uint32
+
+	access  sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+var (
+	tracerAccess sync.Mutex
+	tracers      = make(map[string]*Tracer)
+)
+
+// Open returns the Tracer for the given file. Transports configured with the same file share a Tracer, so that the
+// session IDs in the file are unique. One in every sample connections is traced. All connections are traced if sample
+// is 0 or 1. The file is created when the first event is recorded.
+func Open(path string, sample uint32) *Tracer {
+	tracerAccess.Lock()
+	defer tracerAccess.Unlock()
+
+	if t, found := tracers[path]; found {
+		return t
+	}
+	if sample == 0 {
+		sample = 1
+	}
+	t := &Tracer{
+		path:   path,
+		sample: sample,
+	}
+	tracers[path] = t
+	return t
+}
+
+// Sample returns a new Session if the next connection is to be traced, or nil otherwise.
+func (t *Tracer) Sample() *Session {
+	if t == nil {
+		return nil
+	}
+	if (atomic.AddUint32(&t.connections, 1)-1)%t.sample != 0 {
+		return nil
+	}
+	return &Session{
+		id:     atomic.AddUint32(&t.sessions, 1),
+		tracer: t,
+	}
+}
+
+func (t *Tracer) write(event *Event) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	if t.encoder == nil {
+		file, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			newError("failed to open trace file ", t.path).Base(err).AtWarning().WriteToLog()
+			return
+		}
+		t.file = file
+		t.encoder = json.NewEncoder(file)
+	}
+	if err := t.encoder.Encode(event); err != nil {
+		newError("failed to write trace").Base(err).AtWarning().WriteToLog()
+	}
+}
+
+// Session is a traced connection. A nil Session records nothing, so callers don't need to check it.
+type Session struct {
+	id     uint32
+	tracer *Tracer
+}
+
+// ID returns the ID of the session in the trace file.
+func (s *Session) ID() uint32 {
+	if s == nil {
+		return 0
+	}
+	return s.id
+}
+
+// Record writes an event of the given layer, for example "kcp", into the trace file.
+func (s *Session) Record(layer string, direction string, eventType string, attributes map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.tracer.write(&Event{
+		Time:       time.Now(),
+		Session:    s.id,
+		Layer:      layer,
+		Direction:  direction,
+		Type:       eventType,
+		Attributes: attributes,
+	})
+}
+
+// Carrier is an optional interface for connections whose frames are traced, so that upper layers may join the Session.
+type Carrier interface {
+	TraceSession() *Session
+}
+
+type sessionKey int
+
+// ContextWithSession returns a new context with the given Session.
+func ContextWithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey(0), s)
+}
+
+// SessionFromContext returns the Session in the context, or nil if the connection is not traced.
+func SessionFromContext(ctx context.Context) *Session {
+	if s, ok := ctx.Value(sessionKey(0)).(*Session); ok {
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package trace_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"v2ray.com/core/common"
+	. "v2ray.com/core/transport/internet/trace"
+)
+
+func readEvents(t *testing.T, path string) []*Event {
+	t.Helper()
+
+	file, err := os.Open(path)
+	common.Must(err)
+	defer file.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := new(Event)
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			t.Fatal("invalid line: ", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestSample(t *testing.T) {
+	tracer := Open(filepath.Join(t.TempDir(), "sample.jsonl"), 3)
+
+	var sessions []*Session
+	for i := 0; i < 9; i++ {
+		if s := tracer.Sample(); s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	if len(sessions) != 3 {
+		t.Fatal("expect 3 sampled sessions, but got ", len(sessions))
+	}
+	for i, s := range sessions {
+		if s.ID() != uint32(i+1) {
+			t.Error("unexpected session id: ", s.ID())
+		}
+	}
+
+	var nilTracer *Tracer
+	if s := nilTracer.Sample(); s != nil {
+		t.Error("nil tracer samples a session")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	if Open(path, 0) != Open(path, 0) {
+		t.Error("tracers of the same file are not shared")
+	}
+
+	s := Open(path, 0).Sample()
+	s.Record("kcp", Send, "data", map[string]interface{}{"seq": 1})
+	s.Record("kcp", Receive, "ack", nil)
+
+	var nilSession *Session
+	nilSession.Record("kcp", Send, "data", nil)
+
+	events := readEvents(t, path)
+	if len(events) != 2 {
+		t.Fatal("expect 2 events, but got ", len(events))
+	}
+	if e := events[0]; e.Session != s.ID() || e.Layer != "kcp" || e.Direction != Send || e.Type != "data" || e.Attributes["seq"] != float64(1) {
+		t.Error("unexpected event: ", e)
+	}
+	if e := events[1]; e.Direction != Receive || e.Type != "ack" || e.Attributes != nil {
+		t.Error("unexpected event: ", e)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if s := SessionFromContext(context.Background()); s != nil {
+		t.Error("unexpected session in empty context")
+	}
+
+	s := Open(filepath.Join(t.TempDir(), "context.jsonl"), 0).Sample()
+	if SessionFromContext(ContextWithSession(context.Background(), s)) != s {
+		t.Error("session is not in context")
+	}
+}
@@ -0,0 +1,112 @@
+// +build linux
+
+package udp
+
+import (
+	"sync"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"v2ray.com/core/common/net"
+)
+
+type batchConn interface {
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// BatchWriter writes packets to a UDP socket in batches, each in one sendmmsg call. With GSO, consecutive packets of
+// the same size are sent as one message, which the kernel or the NIC segments.
+type BatchWriter struct {
+	sync.Mutex
+	conn batchConn
+	gso  bool
+	msgs []ipv4.Message
+	oob  []byte
+}
+
+// NewBatchWriter creates a BatchWriter on conn. GSO must be supported on conn if enabled, as by EnableOffload.
+func NewBatchWriter(conn *net.UDPConn, gso bool) *BatchWriter {
+	w := &BatchWriter{gso: gso}
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		w.conn = ipv4.NewPacketConn(conn)
+	} else {
+		w.conn = ipv6.NewPacketConn(conn)
+	}
+	return w
+}
+
+// gsoEnd returns the end of the packets from start, which can be sent as one GSO message.
+func gsoEnd(packets [][]byte, start int) int {
+	size := len(packets[start])
+	total := size
+	end := start + 1
+	for end < len(packets) && end-start < maxGSOSegments {
+		next := len(packets[end])
+		if next > size || total+next > maxGSOSize {
+			break
+		}
+		total += next
+		end++
+		if next < size {
+			// Only the last segment may be shorter.
+			break
+		}
+	}
+	return end
+}
+
+// WriteBatch writes the packets to addr, or to the connected peer if addr is nil.
+func (w *BatchWriter) WriteBatch(packets [][]byte, addr *net.UDPAddr) error {
+	w.Lock()
+	defer w.Unlock()
+
+	err := w.writeBatch(packets, addr)
+	if err != nil && w.gso {
+		// GSO fails with EIO if the device doesn't support checksum offload.
+		newError("failed to write with UDP GSO, disabling it").Base(err).AtWarning().WriteToLog()
+		w.gso = false
+		err = w.writeBatch(packets, addr)
+	}
+	return err
+}
+
+func (w *BatchWriter) writeBatch(packets [][]byte, addr *net.UDPAddr) error {
+	msgs := w.msgs[:0]
+	oobSize := syscall.CmsgSpace(2)
+	if w.gso && len(w.oob) < len(packets)*oobSize {
+		w.oob = make([]byte, len(packets)*oobSize)
+	}
+	for start := 0; start < len(packets); {
+		end := start + 1
+		if w.gso {
+			end = gsoEnd(packets, start)
+		}
+		msg := ipv4.Message{Buffers: packets[start:end]}
+		// A nil *UDPAddr in the interface is not a nil Addr.
+		if addr != nil {
+			msg.Addr = addr
+		}
+		if end-start > 1 {
+			msg.OOB = putGSOControlMessage(w.oob[len(msgs)*oobSize:], len(packets[start]))
+		}
+		msgs = append(msgs, msg)
+		start = end
+	}
+	defer func() {
+		for i := range msgs {
+			msgs[i] = ipv4.Message{}
+		}
+		w.msgs = msgs[:0]
+	}()
+
+	for pending := msgs; len(pending) > 0; {
+		n, err := w.conn.WriteBatch(pending, 0)
+		if err != nil {
+			return err
+		}
+		pending = pending[n:]
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+// +build !linux
+
+package udp
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+// BatchWriter writes packets to a UDP socket in batches. On this platform, the packets are written one by one.
+type BatchWriter struct {
+	conn *net.UDPConn
+}
+
+// NewBatchWriter creates a BatchWriter on conn. GSO is not supported on this platform, so gso is ignored.
+func NewBatchWriter(conn *net.UDPConn, gso bool) *BatchWriter {
+	return &BatchWriter{conn: conn}
+}
+
+// WriteBatch writes the packets to addr, or to the connected peer if addr is nil.
+func (w *BatchWriter) WriteBatch(packets [][]byte, addr *net.UDPAddr) error {
+	for _, packet := range packets {
+		var err error
+		if addr != nil {
+			_, err = w.conn.WriteToUDP(packet, addr)
+		} else {
+			_, err = w.conn.Write(packet)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
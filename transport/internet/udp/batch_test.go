@@ -0,0 +1,37 @@
+package udp_test
+
+import (
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestBatchWriter(t *testing.T) {
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	defer receiver.Close()
+	addr := receiver.LocalAddr().(*net.UDPAddr)
+
+	connected, err := net.DialUDP("udp", nil, addr)
+	common.Must(err)
+	defer connected.Close()
+	unconnected, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	defer unconnected.Close()
+
+	common.Must(NewBatchWriter(connected, false).WriteBatch([][]byte{[]byte("a"), []byte("bb")}, nil))
+	common.Must(NewBatchWriter(unconnected, false).WriteBatch([][]byte{[]byte("ccc")}, addr))
+
+	common.Must(receiver.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	b := make([]byte, 16)
+	for _, expected := range []string{"a", "bb", "ccc"} {
+		n, err := receiver.Read(b)
+		common.Must(err)
+		if string(b[:n]) != expected {
+			t.Error("expect ", expected, ", but got ", string(b[:n]))
+		}
+	}
+}
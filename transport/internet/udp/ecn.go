@@ -0,0 +1,9 @@
+package udp
+
+// ECN codepoints in the IP header, as in RFC 3168.
+const (
+	ECNNotECT byte = 0
+	ECNECT1   byte = 1
+	ECNECT0   byte = 2
+	ECNCE     byte = 3
+)
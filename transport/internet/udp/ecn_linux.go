@@ -0,0 +1,56 @@
+// +build linux
+
+package udp
+
+import (
+	"syscall"
+	"unsafe"
+
+	"v2ray.com/core/common/net"
+)
+
+// EnableECN marks the packets sent on conn as ECN-capable, and requests the ECN codepoints of received packets in
+// control messages.
+func EnableECN(conn *net.UDPConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var v4Err, v6Err error
+	if err := rawConn.Control(func(fd uintptr) {
+		if v4Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, int(ECNECT0)); v4Err == nil {
+			v4Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1)
+		}
+		if v6Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, int(ECNECT0)); v6Err == nil {
+			v6Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVTCLASS, 1)
+		}
+	}); err != nil {
+		return err
+	}
+	// Only one of the protocols is available on sockets that are not dual-stack.
+	if v4Err != nil && v6Err != nil {
+		return newError("failed to enable ECN").Base(v4Err)
+	}
+	return nil
+}
+
+// RetrieveECN returns the ECN codepoint of a received packet from its control messages.
+func RetrieveECN(oob []byte) byte {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return ECNNotECT
+	}
+	for _, msg := range msgs {
+		if len(msg.Data) == 0 {
+			continue
+		}
+		if msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TOS {
+			return msg.Data[0] & 0x03
+		}
+		if msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_TCLASS && len(msg.Data) >= 4 {
+			// The traffic class is an int in host byte order.
+			return byte(*(*int32)(unsafe.Pointer(&msg.Data[0]))) & 0x03
+		}
+	}
+	return ECNNotECT
+}
@@ -0,0 +1,35 @@
+package udp_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestECN(t *testing.T) {
+	listen := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+		common.Must(err)
+		common.Must(EnableECN(conn))
+		return conn
+	}
+	sender := listen()
+	defer sender.Close()
+	receiver := listen()
+	defer receiver.Close()
+
+	common.Must2(sender.WriteToUDP([]byte("test"), receiver.LocalAddr().(*net.UDPAddr)))
+
+	payload := make([]byte, 16)
+	oob := make([]byte, 64)
+	n, noob, _, _, err := ReadUDPMsg(receiver, payload, oob)
+	common.Must(err)
+	if n != 4 {
+		t.Error("payload length: ", n)
+	}
+	if ecn := RetrieveECN(oob[:noob]); ecn != ECNECT0 {
+		t.Error("ECN codepoint: ", ecn)
+	}
+}
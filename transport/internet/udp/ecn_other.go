@@ -0,0 +1,15 @@
+// +build !linux
+
+package udp
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func EnableECN(conn *net.UDPConn) error {
+	return newError("ECN is not supported on this platform")
+}
+
+func RetrieveECN(oob []byte) byte {
+	return ECNNotECT
+}
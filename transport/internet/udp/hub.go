@@ -3,6 +3,7 @@ package udp
 import (
 	"context"
 
+	"v2ray.com/core/common"
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol/udp"
@@ -17,6 +18,13 @@ func HubCapacity(capacity int) HubOption {
 	}
 }
 
+// HubECN enables ECN on the socket of the Hub, so that ECN codepoints of received packets are reported.
+func HubECN(enabled bool) HubOption {
+	return func(h *Hub) {
+		h.ecn = enabled
+	}
+}
+
 func HubReceiveOriginalDestination(r bool) HubOption {
 	return func(h *Hub) {
 		h.recvOrigDest = r
@@ -25,9 +33,12 @@ func HubReceiveOriginalDestination(r bool) HubOption {
 
 type Hub struct {
 	conn         *net.UDPConn
+	batch        *BatchWriter
 	cache        chan *udp.Packet
 	capacity     int
 	recvOrigDest bool
+	ecn          bool
+	offload      bool
 }
 
 func ListenUDP(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, options ...HubOption) (*Hub, error) {
@@ -46,6 +57,9 @@ func ListenUDP(ctx context.Context, address net.Address, port net.Port, streamSe
 	if sockopt != nil && sockopt.ReceiveOriginalDestAddress {
 		hub.recvOrigDest = true
 	}
+	if sockopt != nil && sockopt.UdpOffload {
+		hub.offload = true
+	}
 
 	udpConn, err := internet.ListenSystemPacket(ctx, &net.UDPAddr{
 		IP:   address.IP(),
@@ -56,6 +70,19 @@ func ListenUDP(ctx context.Context, address net.Address, port net.Port, streamSe
 	}
 	newError("listening UDP on ", address, ":", port).WriteToLog()
 	hub.conn = udpConn.(*net.UDPConn)
+	if hub.offload {
+		if err := EnableOffload(hub.conn); err != nil {
+			newError("failed to enable UDP offload on ", address, ":", port).Base(err).AtWarning().WriteToLog()
+			hub.offload = false
+		}
+	}
+	hub.batch = NewBatchWriter(hub.conn, hub.offload)
+	if hub.ecn {
+		if err := EnableECN(hub.conn); err != nil {
+			newError("failed to enable ECN on ", address, ":", port).Base(err).AtWarning().WriteToLog()
+			hub.ecn = false
+		}
+	}
 	hub.cache = make(chan *udp.Packet, hub.capacity)
 
 	go hub.start()
@@ -75,17 +102,33 @@ func (h *Hub) WriteTo(payload []byte, dest net.Destination) (int, error) {
 	})
 }
 
+// WriteBatch writes the payloads to dest in a batch.
+func (h *Hub) WriteBatch(payloads [][]byte, dest net.Destination) error {
+	return h.batch.WriteBatch(payloads, &net.UDPAddr{
+		IP:   dest.Address.IP(),
+		Port: int(dest.Port),
+	})
+}
+
 func (h *Hub) start() {
 	c := h.cache
 	defer close(c)
 
 	oobBytes := make([]byte, 256)
+	// Packets coalesced by GRO are read into a large buffer, and then split.
+	var large []byte
+	if h.offload {
+		large = make([]byte, 65536)
+	}
 
 	for {
 		buffer := buf.New()
 		var noob int
 		var addr *net.UDPAddr
 		rawBytes := buffer.Extend(buf.Size)
+		if large != nil {
+			rawBytes = large
+		}
 
 		n, noob, _, addr, err := ReadUDPMsg(h.conn, rawBytes, oobBytes)
 		if err != nil {
@@ -93,33 +136,56 @@ func (h *Hub) start() {
 			buffer.Release()
 			break
 		}
-		buffer.Resize(0, int32(n))
 
-		if buffer.IsEmpty() {
+		if large != nil {
 			buffer.Release()
+			groSize := 0
+			if noob > 0 {
+				groSize = RetrieveGROSize(oobBytes[:noob])
+			}
+			for _, datagram := range SplitGRO(large[:n], groSize) {
+				if len(datagram) == 0 || len(datagram) > buf.Size {
+					continue
+				}
+				buffer := buf.New()
+				common.Must2(buffer.Write(datagram))
+				h.deliver(buffer, addr, oobBytes[:noob])
+			}
 			continue
 		}
 
-		payload := &udp.Packet{
-			Payload: buffer,
-			Source:  net.UDPDestination(net.IPAddress(addr.IP), net.Port(addr.Port)),
-		}
-		if h.recvOrigDest && noob > 0 {
-			payload.Target = RetrieveOriginalDest(oobBytes[:noob])
-			if payload.Target.IsValid() {
-				newError("UDP original destination: ", payload.Target).AtDebug().WriteToLog()
-			} else {
-				newError("failed to read UDP original destination").WriteToLog()
-			}
+		buffer.Resize(0, int32(n))
+		if buffer.IsEmpty() {
+			buffer.Release()
+			continue
 		}
+		h.deliver(buffer, addr, oobBytes[:noob])
+	}
+}
 
-		select {
-		case c <- payload:
-		default:
-			buffer.Release()
-			payload.Payload = nil
+// deliver delivers a received packet with its control messages in oob.
+func (h *Hub) deliver(buffer *buf.Buffer, addr *net.UDPAddr, oob []byte) {
+	payload := &udp.Packet{
+		Payload: buffer,
+		Source:  net.UDPDestination(net.IPAddress(addr.IP), net.Port(addr.Port)),
+	}
+	if h.ecn && len(oob) > 0 {
+		payload.ECN = RetrieveECN(oob)
+	}
+	if h.recvOrigDest && len(oob) > 0 {
+		payload.Target = RetrieveOriginalDest(oob)
+		if payload.Target.IsValid() {
+			newError("UDP original destination: ", payload.Target).AtDebug().WriteToLog()
+		} else {
+			newError("failed to read UDP original destination").WriteToLog()
 		}
+	}
 
+	select {
+	case h.cache <- payload:
+	default:
+		buffer.Release()
+		payload.Payload = nil
 	}
 }
 
@@ -0,0 +1,40 @@
+// +build openbsd
+
+package udp
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"v2ray.com/core/common/net"
+)
+
+// RetrieveOriginalDest returns the destination of a datagram diverted by pf, from its IP_RECVDSTADDR and
+// IP_RECVDSTPORT control messages, or their IPv6 counterparts.
+func RetrieveOriginalDest(oob []byte) net.Destination {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return net.Destination{}
+	}
+	var address net.Address
+	var port net.Port
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == unix.IP_RECVDSTADDR && len(msg.Data) >= 4:
+			address = net.IPAddress(msg.Data[:4])
+		case msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_PKTINFO && len(msg.Data) >= 16:
+			address = net.IPAddress(msg.Data[:16])
+		case (msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == unix.IP_RECVDSTPORT ||
+			msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_RECVDSTPORT) && len(msg.Data) >= 2:
+			port = net.PortFromBytes(msg.Data[:2])
+		}
+	}
+	if address == nil || port == 0 {
+		return net.Destination{}
+	}
+	return net.UDPDestination(address, port)
+}
+
+func ReadUDPMsg(conn *net.UDPConn, payload []byte, oob []byte) (int, int, int, *net.UDPAddr, error) {
+	return conn.ReadMsgUDP(payload, oob)
+}
@@ -0,0 +1,15 @@
+package udp
+
+// SplitGRO splits a packet, which is coalesced by GRO from datagrams of size, into the datagrams. The last datagram may
+// be shorter. The packet is returned as is if size is 0.
+func SplitGRO(packet []byte, size int) [][]byte {
+	if size <= 0 || len(packet) <= size {
+		return [][]byte{packet}
+	}
+	datagrams := make([][]byte, 0, (len(packet)+size-1)/size)
+	for len(packet) > size {
+		datagrams = append(datagrams, packet[:size])
+		packet = packet[size:]
+	}
+	return append(datagrams, packet)
+}
@@ -0,0 +1,70 @@
+// +build linux
+
+package udp
+
+import (
+	"syscall"
+	"unsafe"
+
+	"v2ray.com/core/common/net"
+)
+
+const (
+	solUDP     = 17
+	udpSegment = 103
+	udpGRO     = 104
+	// Limits of a GSO packet in the kernel.
+	maxGSOSegments = 64
+	maxGSOSize     = 65000
+)
+
+// EnableOffload enables UDP generic receive offload (GRO) on conn, and checks that generic segmentation offload (GSO)
+// is supported. With GRO, a packet read from conn may be coalesced from multiple datagrams, whose size is reported in
+// control messages.
+func EnableOffload(conn *net.UDPConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var gsoErr, groErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		_, gsoErr = syscall.GetsockoptInt(int(fd), solUDP, udpSegment)
+		groErr = syscall.SetsockoptInt(int(fd), solUDP, udpGRO, 1)
+	}); err != nil {
+		return err
+	}
+	if gsoErr != nil {
+		return newError("UDP GSO is not supported").Base(gsoErr)
+	}
+	if groErr != nil {
+		return newError("UDP GRO is not supported").Base(groErr)
+	}
+	return nil
+}
+
+// RetrieveGROSize returns the size of the datagrams coalesced in a packet by GRO from its control messages, or 0 if the
+// packet is not coalesced.
+func RetrieveGROSize(oob []byte) int {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level == solUDP && msg.Header.Type == udpGRO && len(msg.Data) >= 4 {
+			// The size is an int in host byte order.
+			return int(*(*int32)(unsafe.Pointer(&msg.Data[0])))
+		}
+	}
+	return 0
+}
+
+// putGSOControlMessage writes the control message of the segment size of GSO into b, and returns it.
+func putGSOControlMessage(b []byte, size int) []byte {
+	b = b[:syscall.CmsgSpace(2)]
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = solUDP
+	h.Type = udpSegment
+	h.SetLen(syscall.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = uint16(size)
+	return b
+}
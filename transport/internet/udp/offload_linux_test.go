@@ -0,0 +1,54 @@
+package udp_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestOffload(t *testing.T) {
+	listen := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+		common.Must(err)
+		return conn
+	}
+	sender := listen()
+	defer sender.Close()
+	receiver := listen()
+	defer receiver.Close()
+	if err := EnableOffload(sender); err != nil {
+		t.Skip("UDP offload is not supported: ", err)
+	}
+	if err := EnableOffload(receiver); err != nil {
+		t.Skip("UDP offload is not supported: ", err)
+	}
+
+	packets := [][]byte{
+		bytes.Repeat([]byte{'a'}, 100),
+		bytes.Repeat([]byte{'b'}, 100),
+		bytes.Repeat([]byte{'c'}, 100),
+		bytes.Repeat([]byte{'d'}, 40),
+	}
+	common.Must(NewBatchWriter(sender, true).WriteBatch(packets, receiver.LocalAddr().(*net.UDPAddr)))
+
+	common.Must(receiver.SetReadDeadline(time.Now().Add(time.Second)))
+	var datagrams [][]byte
+	large := make([]byte, 65536)
+	oob := make([]byte, 64)
+	for len(datagrams) < len(packets) {
+		n, noob, _, _, err := ReadUDPMsg(receiver, large, oob)
+		common.Must(err)
+		for _, datagram := range SplitGRO(large[:n], RetrieveGROSize(oob[:noob])) {
+			datagrams = append(datagrams, append([]byte(nil), datagram...))
+		}
+	}
+	for i := range packets {
+		if !bytes.Equal(datagrams[i], packets[i]) {
+			t.Error("datagram ", i, ": ", string(datagrams[i]))
+		}
+	}
+}
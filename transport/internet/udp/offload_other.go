@@ -0,0 +1,15 @@
+// +build !linux
+
+package udp
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+func EnableOffload(conn *net.UDPConn) error {
+	return newError("UDP offload is not supported on this platform")
+}
+
+func RetrieveGROSize(oob []byte) int {
+	return 0
+}
@@ -0,0 +1,27 @@
+package udp_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestSplitGRO(t *testing.T) {
+	packet := []byte("abcdefgh")
+	cases := []struct {
+		size      int
+		datagrams [][]byte
+	}{
+		{size: 0, datagrams: [][]byte{[]byte("abcdefgh")}},
+		{size: 8, datagrams: [][]byte{[]byte("abcdefgh")}},
+		{size: 4, datagrams: [][]byte{[]byte("abcd"), []byte("efgh")}},
+		{size: 3, datagrams: [][]byte{[]byte("abc"), []byte("def"), []byte("gh")}},
+	}
+	for _, c := range cases {
+		if r := cmp.Diff(SplitGRO(packet, c.size), c.datagrams); r != "" {
+			t.Error("size ", c.size, ": ", r)
+		}
+	}
+}
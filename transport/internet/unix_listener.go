@@ -0,0 +1,129 @@
+package internet
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/net"
+)
+
+// IsUnixSocketPath returns true if the address is the path of a unix domain socket to listen on. The path starts with
+// "/", or "@" for an abstract socket on Linux.
+func IsUnixSocketPath(address net.Address) bool {
+	if !address.Family().IsDomain() {
+		return false
+	}
+	path := address.Domain()
+	return strings.HasPrefix(path, "/") || strings.HasPrefix(path, "@")
+}
+
+// StreamListenAddr returns the address that stream transports listen on, either a TCP address or a unix domain socket.
+func StreamListenAddr(address net.Address, port net.Port) net.Addr {
+	if IsUnixSocketPath(address) {
+		return &net.UnixAddr{
+			Name: address.Domain(),
+			Net:  "unix",
+		}
+	}
+	return &net.TCPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	}
+}
+
+// lookupOwner returns the user ID and group ID of an owner in the form of "user" or "user:group". The primary group of
+// the user is used if the group is omitted.
+func lookupOwner(owner string) (int, int, error) {
+	parts := strings.SplitN(owner, ":", 2)
+	u, err := user.Lookup(parts[0])
+	if err != nil {
+		if u, err = user.LookupId(parts[0]); err != nil {
+			return 0, 0, newError("unknown user ", parts[0]).Base(err)
+		}
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, 0, newError("user ", parts[0], " has no numeric ID")
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		gid = -1
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		g, err := user.LookupGroup(parts[1])
+		if err != nil {
+			if g, err = user.LookupGroupId(parts[1]); err != nil {
+				return 0, 0, newError("unknown group ", parts[1]).Base(err)
+			}
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, newError("group ", parts[1], " has no numeric ID")
+		}
+	}
+	return uid, gid, nil
+}
+
+// removeStaleSocket removes the socket file at path, if no one listens on it. Such a file is left by a process that
+// exits without closing its listener.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	dialer := &net.Dialer{Timeout: time.Second}
+	if conn, err := dialer.Dial("unix", path); err == nil {
+		conn.Close()
+		return newError("unix domain socket ", path, " is in use")
+	}
+	return os.Remove(path)
+}
+
+func listenUnix(ctx context.Context, addr *net.UnixAddr, sockopt *SocketConfig) (net.Listener, error) {
+	path := addr.Name
+	abstract := strings.HasPrefix(path, "@")
+	if !abstract {
+		if err := removeStaleSocket(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if abstract {
+		return listener, nil
+	}
+	if err := ApplyUnixSocketOptions(path, sockopt); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ApplyUnixSocketOptions sets the owner and the permission bits of the unix domain socket file at path, as in sockopt.
+func ApplyUnixSocketOptions(path string, sockopt *SocketConfig) error {
+	if sockopt == nil {
+		return nil
+	}
+	if sockopt.UnixSocketOwner != "" {
+		uid, gid, err := lookupOwner(sockopt.UnixSocketOwner)
+		if err == nil {
+			err = os.Chown(path, uid, gid)
+		}
+		if err != nil {
+			return newError("failed to set owner of ", path).Base(err)
+		}
+	}
+	if sockopt.UnixSocketMode != 0 {
+		if err := os.Chmod(path, os.FileMode(sockopt.UnixSocketMode)); err != nil {
+			return newError("failed to set mode of ", path).Base(err)
+		}
+	}
+	return nil
+}
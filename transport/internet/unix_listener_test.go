@@ -0,0 +1,61 @@
+// +build !windows
+
+package internet_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+func TestStreamListenAddr(t *testing.T) {
+	if addr := internet.StreamListenAddr(net.ParseAddress("/run/v2ray.sock"), 0); addr.Network() != "unix" || addr.String() != "/run/v2ray.sock" {
+		t.Error("unexpected address ", addr.Network(), " ", addr)
+	}
+	if addr := internet.StreamListenAddr(net.ParseAddress("@v2ray"), 0); addr.Network() != "unix" || addr.String() != "@v2ray" {
+		t.Error("unexpected address ", addr.Network(), " ", addr)
+	}
+	if addr := internet.StreamListenAddr(net.ParseAddress("127.0.0.1"), 1080); addr.Network() != "tcp" || addr.String() != "127.0.0.1:1080" {
+		t.Error("unexpected address ", addr.Network(), " ", addr)
+	}
+	if internet.IsUnixSocketPath(net.ParseAddress("v2fly.org")) {
+		t.Error("expect domain not to be a unix socket path")
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "v2ray-unix")
+	common.Must(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "v2ray.sock")
+	addr := internet.StreamListenAddr(net.ParseAddress(path), 0)
+	sockopt := &internet.SocketConfig{UnixSocketMode: 0600}
+
+	listener, err := internet.ListenSystem(context.Background(), addr, sockopt)
+	common.Must(err)
+	info, err := os.Stat(path)
+	common.Must(err)
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Error("expect mode 0600, but got ", mode)
+	}
+
+	if _, err := internet.ListenSystem(context.Background(), addr, sockopt); err == nil {
+		t.Error("expect error for socket in use")
+	}
+
+	// A socket file left without a listener is replaced.
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	common.Must(listener.Close())
+	listener, err = internet.ListenSystem(context.Background(), addr, sockopt)
+	common.Must(err)
+	common.Must(listener.Close())
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expect socket file to be removed on close, but got ", err)
+	}
+}
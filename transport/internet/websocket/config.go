@@ -3,9 +3,12 @@
 package websocket
 
 import (
+	"compress/flate"
 	"net/http"
+	"strings"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
 )
 
@@ -27,9 +30,39 @@ func (c *Config) GetRequestHeader() http.Header {
 	for _, h := range c.Header {
 		header.Add(h.Key, h.Value)
 	}
+	if c.Host != "" {
+		header.Set("Host", c.Host)
+	}
 	return header
 }
 
+// GetCompressionLevelValue returns the flate level of compressing messages.
+func (c *Config) GetCompressionLevelValue() int {
+	if c.CompressionLevel == 0 {
+		return flate.BestSpeed
+	}
+	return int(c.CompressionLevel)
+}
+
+// GetMaxMessageSizeValue returns the max size of a message received when compression is enabled.
+func (c *Config) GetMaxMessageSizeValue() int {
+	if c.MaxMessageSize == 0 {
+		return 1024 * 1024
+	}
+	return int(c.MaxMessageSize)
+}
+
+// IsHostAllowed returns whether requests to host are accepted. The port in host is ignored.
+func (c *Config) IsHostAllowed(host string) bool {
+	if c.Host == "" {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.EqualFold(host, c.Host)
+}
+
 func init() {
 	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
 		return new(Config)
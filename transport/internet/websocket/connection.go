@@ -11,6 +11,7 @@ import (
 	"v2ray.com/core/common/buf"
 	"v2ray.com/core/common/errors"
 	"v2ray.com/core/common/serial"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 var (
@@ -22,13 +23,42 @@ type connection struct {
 	conn       *websocket.Conn
 	reader     io.Reader
 	remoteAddr net.Addr
+
+	trace       *trace.Session
+	messageType int
+	messageLen  int
+	// Max size of a message received, or 0 for no limit.
+	maxMessageLen int
 }
 
-func newConnection(conn *websocket.Conn, remoteAddr net.Addr) *connection {
-	return &connection{
+func newConnection(conn *websocket.Conn, remoteAddr net.Addr, config *Config, session *trace.Session) *connection {
+	c := &connection{
 		conn:       conn,
 		remoteAddr: remoteAddr,
+		trace:      session,
+	}
+	if config.Compression {
+		// The flate writer is only held while writing a message, and the reader while reading one, so that the memory
+		// of compression on each connection is bounded.
+		if err := conn.SetCompressionLevel(config.GetCompressionLevelValue()); err != nil {
+			newError("invalid compression level").Base(err).AtWarning().WriteToLog()
+		}
+		c.maxMessageLen = config.GetMaxMessageSizeValue()
+		conn.SetReadLimit(int64(c.maxMessageLen))
 	}
+	return c
+}
+
+func (c *connection) traceMessage(direction string, messageType int, length int) {
+	c.trace.Record("ws", direction, "message", map[string]interface{}{
+		"opcode": messageType,
+		"len":    length,
+	})
+}
+
+// TraceSession implements trace.Carrier.
+func (c *connection) TraceSession() *trace.Session {
+	return c.trace
 }
 
 // Read implements net.Conn.Read()
@@ -40,7 +70,13 @@ func (c *connection) Read(b []byte) (int, error) {
 		}
 
 		nBytes, err := reader.Read(b)
+		c.messageLen += nBytes
+		if c.maxMessageLen > 0 && c.messageLen > c.maxMessageLen {
+			c.conn.Close()
+			return 0, newError("message larger than ", c.maxMessageLen, " bytes")
+		}
 		if errors.Cause(err) == io.EOF {
+			c.traceMessage(trace.Receive, c.messageType, c.messageLen)
 			c.reader = nil
 			continue
 		}
@@ -53,11 +89,13 @@ func (c *connection) getReader() (io.Reader, error) {
 		return c.reader, nil
 	}
 
-	_, reader, err := c.conn.NextReader()
+	messageType, reader, err := c.conn.NextReader()
 	if err != nil {
 		return nil, err
 	}
 	c.reader = reader
+	c.messageType = messageType
+	c.messageLen = 0
 	return reader, nil
 }
 
@@ -66,6 +104,7 @@ func (c *connection) Write(b []byte) (int, error) {
 	if err := c.conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
 		return 0, err
 	}
+	c.traceMessage(trace.Send, websocket.BinaryMessage, len(b))
 	return len(b), nil
 }
 
@@ -80,6 +119,8 @@ func (c *connection) Close() error {
 	var errors []interface{}
 	if err := c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second*5)); err != nil {
 		errors = append(errors, err)
+	} else {
+		c.traceMessage(trace.Send, websocket.CloseMessage, 2)
 	}
 	if err := c.conn.Close(); err != nil {
 		errors = append(errors, err)
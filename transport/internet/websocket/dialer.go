@@ -12,6 +12,7 @@ import (
 	"v2ray.com/core/common/session"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 // Dial dials a WebSocket connection to the given destination.
@@ -36,9 +37,10 @@ func dialWebsocket(ctx context.Context, dest net.Destination, streamSettings *in
 		NetDial: func(network, addr string) (net.Conn, error) {
 			return internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
 		},
-		ReadBufferSize:   4 * 1024,
-		WriteBufferSize:  4 * 1024,
-		HandshakeTimeout: time.Second * 8,
+		ReadBufferSize:    4 * 1024,
+		WriteBufferSize:   4 * 1024,
+		HandshakeTimeout:  time.Second * 8,
+		EnableCompression: wsSettings.Compression,
 	}
 
 	protocol := "ws"
@@ -63,5 +65,5 @@ func dialWebsocket(ctx context.Context, dest net.Destination, streamSettings *in
 		return nil, newError("failed to dial to (", uri, "): ", reason).Base(err)
 	}
 
-	return newConnection(conn, conn.RemoteAddr()), nil
+	return newConnection(conn, conn.RemoteAddr(), wsSettings, trace.SessionFromContext(ctx)), nil
 }
@@ -18,20 +18,26 @@ import (
 	"v2ray.com/core/common/session"
 	"v2ray.com/core/transport/internet"
 	v2tls "v2ray.com/core/transport/internet/tls"
+	"v2ray.com/core/transport/internet/trace"
 )
 
 type requestHandler struct {
-	path string
-	ln   *Listener
+	path     string
+	config   *Config
+	upgrader *websocket.Upgrader
+	ln       *Listener
 }
 
-var upgrader = &websocket.Upgrader{
-	ReadBufferSize:   4 * 1024,
-	WriteBufferSize:  4 * 1024,
-	HandshakeTimeout: time.Second * 4,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+func newUpgrader(config *Config) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:    4 * 1024,
+		WriteBufferSize:   4 * 1024,
+		HandshakeTimeout:  time.Second * 4,
+		EnableCompression: config.Compression,
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
 }
 
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
@@ -39,7 +45,12 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		writer.WriteHeader(http.StatusNotFound)
 		return
 	}
-	conn, err := upgrader.Upgrade(writer, request, nil)
+	if !h.config.IsHostAllowed(request.Host) {
+		newError("rejected request to unexpected host: ", request.Host).AtInfo().WriteToLog()
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(writer, request, nil)
 	if err != nil {
 		newError("failed to convert to WebSocket connection").Base(err).WriteToLog()
 		return
@@ -51,7 +62,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		remoteAddr.(*net.TCPAddr).IP = forwardedAddrs[0].IP()
 	}
 
-	h.ln.addConn(newConnection(conn, remoteAddr))
+	h.ln.addConn(newConnection(conn, remoteAddr, h.config, h.ln.tracer.Sample()))
 }
 
 type Listener struct {
@@ -60,13 +71,11 @@ type Listener struct {
 	listener net.Listener
 	config   *Config
 	addConn  internet.ConnHandler
+	tracer   *trace.Tracer
 }
 
 func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
-	listener, err := internet.ListenSystem(ctx, &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	}, streamSettings.SocketSettings)
+	listener, err := internet.ListenSystem(ctx, internet.StreamListenAddr(address, port), streamSettings.SocketSettings)
 	if err != nil {
 		return nil, newError("failed to listen TCP(for WS) on", address, ":", port).Base(err)
 	}
@@ -90,12 +99,15 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 		config:   wsSettings,
 		addConn:  addConn,
 		listener: listener,
+		tracer:   streamSettings.Tracer,
 	}
 
 	l.server = http.Server{
 		Handler: &requestHandler{
-			path: wsSettings.GetNormalizedPath(),
-			ln:   l,
+			path:     wsSettings.GetNormalizedPath(),
+			config:   wsSettings,
+			upgrader: newUpgrader(wsSettings),
+			ln:       l,
 		},
 		ReadHeaderTimeout: time.Second * 4,
 		MaxHeaderBytes:    2048,
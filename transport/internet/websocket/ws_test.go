@@ -1,7 +1,9 @@
 package websocket_test
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"runtime"
 	"testing"
 	"time"
@@ -146,3 +148,103 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func TestDialWithHost(t *testing.T) {
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, 13149, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path: "ws",
+			Host: "www.v2ray.com",
+		},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			n, err := c.Read(b[:])
+			if err != nil {
+				return
+			}
+
+			common.Must2(c.Write(b[:n]))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), 13149), &internet.MemoryStreamConfig{
+		ProtocolName:     "websocket",
+		ProtocolSettings: &Config{Path: "ws", Host: "www.v2ray.com"},
+	})
+	common.Must(err)
+	common.Must2(conn.Write([]byte("Test connection")))
+
+	var b [1024]byte
+	n, err := conn.Read(b[:])
+	common.Must(err)
+	if string(b[:n]) != "Test connection" {
+		t.Error("response: ", string(b[:n]))
+	}
+	common.Must(conn.Close())
+
+	if _, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), 13149), &internet.MemoryStreamConfig{
+		ProtocolName:     "websocket",
+		ProtocolSettings: &Config{Path: "ws", Host: "www.example.com"},
+	}); err == nil {
+		t.Error("dialed with a mismatched host")
+	}
+}
+
+func TestDialWithCompression(t *testing.T) {
+	received := make(chan error, 1)
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, 13150, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:           "ws",
+			Compression:    true,
+			MaxMessageSize: 8192,
+		},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+
+			b := make([]byte, 16384)
+			for {
+				n, err := c.Read(b)
+				if err != nil {
+					received <- err
+					return
+				}
+				common.Must2(c.Write(b[:n]))
+			}
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), 13150), &internet.MemoryStreamConfig{
+		ProtocolName:     "websocket",
+		ProtocolSettings: &Config{Path: "ws", Compression: true, CompressionLevel: 9},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	payload := bytes.Repeat([]byte("compressible "), 500)
+	common.Must2(conn.Write(payload))
+	response := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, response))
+	if !bytes.Equal(response, payload) {
+		t.Error("response mismatch")
+	}
+
+	// A message larger than the max message size of the server closes the connection, though it is small compressed.
+	common.Must2(conn.Write(make([]byte, 16384)))
+	select {
+	case err := <-received:
+		if err == nil {
+			t.Error("nil error")
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("large message accepted")
+	}
+}
@@ -0,0 +1,23 @@
+package internet
+
+import (
+	"context"
+)
+
+type redirectRecordsKey int
+
+const redirectRecordsContextKey redirectRecordsKey = iota
+
+// ContextWithRedirectRecords returns a new context with the WFP redirect records of an inbound connection. Outbound
+// connections dialed with the context carry the records, so that the redirection on Windows is not applied to them again.
+func ContextWithRedirectRecords(ctx context.Context, records []byte) context.Context {
+	return context.WithValue(ctx, redirectRecordsContextKey, records)
+}
+
+// RedirectRecordsFromContext returns the WFP redirect records in ctx, or nil if there is none.
+func RedirectRecordsFromContext(ctx context.Context) []byte {
+	if records, ok := ctx.Value(redirectRecordsContextKey).([]byte); ok {
+		return records
+	}
+	return nil
+}
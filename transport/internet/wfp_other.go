@@ -0,0 +1,16 @@
+// +build !windows
+
+package internet
+
+import (
+	"v2ray.com/core/common/net"
+)
+
+// RedirectRecords returns nil, as WFP is only available on Windows.
+func RedirectRecords(conn net.Conn) []byte {
+	return nil
+}
+
+func setRedirectRecords(fd uintptr, records []byte) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package internet
+
+import (
+	"syscall"
+
+	"v2ray.com/core/common/net"
+)
+
+// Socket IOCTLs of WFP connection redirection, from mstcpip.h.
+const (
+	sioQueryWfpConnectionRedirectRecords = 0xd80000dc
+	sioQueryWfpConnectionRedirectContext = 0xd80000dd
+	sioSetWfpConnectionRedirectRecords   = 0x980000de
+
+	wsaEFault = syscall.Errno(10014)
+)
+
+func wsaIoctlQuery(fd syscall.Handle, code uint32) ([]byte, error) {
+	b := make([]byte, 512)
+	for {
+		var n uint32
+		err := syscall.WSAIoctl(fd, code, nil, 0, &b[0], uint32(len(b)), &n, nil, 0)
+		if err == wsaEFault && int(n) > len(b) {
+			b = make([]byte, n)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+
+func queryConn(conn net.Conn, code uint32) ([]byte, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, newError("unable to get syscall.Conn")
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return nil, newError("failed to get sys fd").Base(err)
+	}
+	var b []byte
+	var qerr error
+	if err := rawConn.Control(func(fd uintptr) {
+		b, qerr = wsaIoctlQuery(syscall.Handle(fd), code)
+	}); err != nil {
+		return nil, err
+	}
+	return b, qerr
+}
+
+// RedirectContext returns the context that a WFP callout attached to an inbound connection when redirecting it.
+func RedirectContext(conn net.Conn) ([]byte, error) {
+	b, err := queryConn(conn, sioQueryWfpConnectionRedirectContext)
+	if err != nil {
+		return nil, newError("failed to query WFP redirect context").Base(err)
+	}
+	return b, nil
+}
+
+// RedirectRecords returns the WFP redirect records of an inbound connection, or nil if it is not redirected.
+func RedirectRecords(conn net.Conn) []byte {
+	b, err := queryConn(conn, sioQueryWfpConnectionRedirectRecords)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func setRedirectRecords(fd uintptr, records []byte) error {
+	var n uint32
+	if err := syscall.WSAIoctl(syscall.Handle(fd), sioSetWfpConnectionRedirectRecords, &records[0], uint32(len(records)), nil, 0, &n, nil, 0); err != nil {
+		return newError("failed to set WFP redirect records").Base(err)
+	}
+	return nil
+}
+
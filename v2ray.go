@@ -261,6 +261,36 @@ func (s *Instance) Close() error {
 	return nil
 }
 
+// Shutdown gracefully shuts down the V2Ray instance. It stops all features from accepting new connections,
+// waits for existing connections to finish until ctx is done, and then closes the instance.
+func (s *Instance) Shutdown(ctx context.Context) error {
+	s.access.Lock()
+	all := append([]features.Feature(nil), s.features...)
+	s.access.Unlock()
+
+	// Features are closed only after all of them are drained, even if some fail.
+	var wg sync.WaitGroup
+	var errorsAccess sync.Mutex
+	var errors []interface{}
+	for _, f := range all {
+		wg.Add(1)
+		go func(f features.Feature) {
+			defer wg.Done()
+			if err := common.Drain(ctx, f); err != nil {
+				errorsAccess.Lock()
+				errors = append(errors, err)
+				errorsAccess.Unlock()
+			}
+		}(f)
+	}
+	wg.Wait()
+	if len(errors) > 0 {
+		newError("not all connections are finished before shutdown").Base(newError(serial.Concat(errors...))).AtWarning().WriteToLog()
+	}
+
+	return s.Close()
+}
+
 // RequireFeatures registers a callback, which will be called when all dependent features are registered.
 // The callback must be a func(). All its parameters must be features.Feature.
 func (s *Instance) RequireFeatures(callback interface{}) error {
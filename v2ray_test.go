@@ -1,7 +1,11 @@
 package core_test
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	proto "github.com/golang/protobuf/proto"
 	. "v2ray.com/core"
@@ -88,3 +92,41 @@ func TestV2RayClose(t *testing.T) {
 	common.Must(err)
 	server.Close()
 }
+
+type drainFeature struct {
+	drain func(context.Context) error
+	close func() error
+}
+
+func (f *drainFeature) Type() interface{}               { return f }
+func (f *drainFeature) Start() error                    { return nil }
+func (f *drainFeature) Close() error                    { return f.close() }
+func (f *drainFeature) Drain(ctx context.Context) error { return f.drain(ctx) }
+
+func TestV2RayShutdown(t *testing.T) {
+	var drained int32
+	closeFeature := func() error {
+		if atomic.LoadInt32(&drained) == 0 {
+			t.Error("feature is closed while another one is draining")
+		}
+		return nil
+	}
+
+	instance := new(Instance)
+	common.Must(instance.AddFeature(&drainFeature{
+		drain: func(context.Context) error {
+			return errors.New("failed to drain")
+		},
+		close: closeFeature,
+	}))
+	common.Must(instance.AddFeature(&drainFeature{
+		drain: func(context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt32(&drained, 1)
+			return nil
+		},
+		close: closeFeature,
+	}))
+
+	common.Must(instance.Shutdown(context.Background()))
+}